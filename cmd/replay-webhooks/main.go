@@ -0,0 +1,140 @@
+// replay-webhooks vuelve a normalizar los webhooks guardados en inbound_messages y reenvía sus
+// mensajes al servicio de mensajería, marcados con el ID de la reproducción. Sirve para recuperar
+// mensajes después de corregir un error de normalización o de una caída del servicio de mensajería.
+//
+// Uso:
+//
+//	go run ./cmd/replay-webhooks [-platform whatsapp] [-ids id1,id2] [-from 2026-01-01T00:00:00Z]
+//		[-to 2026-01-02T00:00:00Z] [-processed false] [-limit 1000] [-rate 10] [-dry-run] [-report report.json]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/repository"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+)
+
+func main() {
+	cfg := config.Load()
+
+	platform := flag.String("platform", "", "plataforma de los mensajes entrantes")
+	ids := flag.String("ids", "", "IDs de mensajes entrantes separados por coma")
+	from := flag.String("from", "", "recibidos desde (RFC3339)")
+	to := flag.String("to", "", "recibidos hasta, sin incluir (RFC3339)")
+	processed := flag.String("processed", "", "solo procesados (true) o sin procesar (false)")
+	limit := flag.Int("limit", cfg.Replay.MaxMessages, "máximo de mensajes entrantes a reproducir")
+	ratePerSecond := flag.Float64("rate", cfg.Replay.RatePerSecond, "máximo de mensajes reenviados por segundo")
+	dryRun := flag.Bool("dry-run", false, "solo normalizar, sin reenviar")
+	reportPath := flag.String("report", "", "archivo donde guardar el reporte JSON (- para stdout)")
+	flag.Parse()
+
+	logger := logger.NewLogger(cfg.LogLevel)
+
+	req := domain.WebhookReplayRequest{
+		Platform:      domain.Platform(*platform),
+		Limit:         *limit,
+		RatePerSecond: *ratePerSecond,
+		DryRun:        *dryRun,
+	}
+	if *ids != "" {
+		req.IDs = strings.Split(*ids, ",")
+	}
+	var err error
+	if req.From, err = parseTime(*from); err != nil {
+		logger.Fatal("Invalid -from", err)
+	}
+	if req.To, err = parseTime(*to); err != nil {
+		logger.Fatal("Invalid -to", err)
+	}
+	if *processed != "" {
+		value, err := strconv.ParseBool(*processed)
+		if err != nil {
+			logger.Fatal("Invalid -processed", err)
+		}
+		req.Processed = &value
+	}
+
+	db, err := repository.NewPostgresDB(
+		cfg.Database.Host,
+		cfg.Database.Port,
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Name,
+		cfg.Database.SSLMode,
+	)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	replayService := services.NewWebhookReplayService(
+		repository.NewInboundMessageRepository(db),
+		services.NewWebhookService(cfg.Integration.MessagingServiceURL, logger),
+		cfg.Replay,
+		logger,
+	)
+
+	report, err := replayService.Run(ctx, req, func(report *domain.WebhookReplayReport) {
+		if report.Selected > 0 && report.Selected%100 == 0 {
+			logger.Info("Webhook replay progress", map[string]interface{}{
+				"selected": report.Selected,
+				"replayed": report.Replayed,
+				"failed":   report.Failed,
+			})
+		}
+	})
+	if err != nil {
+		logger.Fatal("Invalid replay request", err)
+	}
+
+	if *reportPath != "" {
+		if err := writeReport(*reportPath, report); err != nil {
+			logger.Error("Failed to write replay report", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if report.State == domain.WebhookReplayFailed || report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func writeReport(path string, report *domain.WebhookReplayReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if path == "-" {
+		_, err = fmt.Println(string(data))
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,108 @@
+// seal-credentials sella en el lugar, con la clave activa del keyring, los access tokens y
+// secretos de config de channel_integrations que quedaron en texto plano o sellados con una
+// clave anterior, y los tokens de google_calendar_integrations.
+//
+// Uso:
+//
+//	go run ./cmd/seal-credentials [-dry-run] [-batch-size 200]
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/repository"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+	"it-integration-service/pkg/vault"
+)
+
+func main() {
+	cfg := config.Load()
+
+	dryRun := flag.Bool("dry-run", false, "solo contar las filas que se sellarían")
+	batchSize := flag.Int("batch-size", cfg.Encryption.ReencryptBatchSize, "filas leídas por lote")
+	flag.Parse()
+
+	logger := logger.NewLogger(cfg.LogLevel)
+
+	var vaultClient vault.Client
+	if cfg.Encryption.KeySource == "vault" {
+		client, err := vault.NewClient(cfg.VaultConfig)
+		if err != nil {
+			logger.Fatal("Failed to initialize Vault client", err)
+		}
+		vaultClient = client
+	}
+
+	keyring, err := services.LoadEncryptionKeyring(cfg.Encryption, cfg.Integration.EncryptionKey, vaultClient)
+	if err != nil {
+		logger.Fatal("Invalid encryption configuration", err)
+	}
+
+	db, err := repository.NewPostgresDB(
+		cfg.Database.Host,
+		cfg.Database.Port,
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Name,
+		cfg.Database.SSLMode,
+	)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := repository.SealOptions{
+		BatchSize: *batchSize,
+		DryRun:    *dryRun,
+		OnBatch: func(report repository.SealReport) {
+			logger.Info("Credential sealing progress", map[string]interface{}{
+				"table":   report.Table,
+				"scanned": report.Scanned,
+				"sealed":  report.Sealed,
+				"failed":  report.Failed,
+			})
+		},
+	}
+
+	failed := false
+	for _, seal := range []func() (*repository.SealReport, error){
+		func() (*repository.SealReport, error) {
+			return repository.SealChannelCredentials(ctx, db, keyring, opts)
+		},
+		func() (*repository.SealReport, error) {
+			return repository.SealCalendarCredentials(ctx, db, keyring, opts)
+		},
+	} {
+		report, err := seal()
+		if err != nil {
+			logger.Error("Credential sealing stopped", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+
+		logger.Info("Credential sealing completed", map[string]interface{}{
+			"table":         report.Table,
+			"dry_run":       *dryRun,
+			"active_key_id": keyring.ActiveKeyID(),
+			"scanned":       report.Scanned,
+			"sealed":        report.Sealed,
+			"failed":        report.Failed,
+			"last_error":    report.LastError,
+		})
+		failed = failed || report.Failed > 0
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -1,9 +1,16 @@
 package config
 
 import (
+	"context"
+	"fmt"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"it-integration-service/pkg/secrets"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +23,15 @@ type Config struct {
 	Database    DatabaseConfig
 	ExternalAPI ExternalAPIConfig
 	Integration IntegrationConfig
+	Outbox      OutboxConfig
+	Dedup       DedupConfig
+	Replay      ReplayConfig
+	Broadcast   BroadcastConfig
+	Encryption  EncryptionConfig
+	Secrets     SecretsConfig
+	Notifications NotificationConfig
+	Health      HealthConfig
+	ChannelProbe ChannelProbeConfig
 	MercadoPago MercadoPagoConfig
 	TawkTo      TawkToConfig
 	Mailchimp   MailchimpConfig
@@ -50,6 +66,122 @@ type IntegrationConfig struct {
 	RateLimitBurst      int
 	WebhookSecrets      map[string]string
 	WebhookVerifyTokens map[string]string
+	// ChannelCacheTTL es el tiempo que se cachea la resolución identificador -> canal
+	ChannelCacheTTL time.Duration
+	// TemplateCacheTTL es el tiempo que se cachean las plantillas de WhatsApp de cada canal
+	TemplateCacheTTL time.Duration
+	// SessionWindow es la duración de la ventana de atención que abre cada mensaje del contacto
+	SessionWindow time.Duration
+	// UnresolvedChannelPolicy define qué hacer con webhooks sin canal: "reject" o "quarantine"
+	UnresolvedChannelPolicy string
+}
+
+// OutboxConfig configura el dispatcher del outbox hacia el servicio de mensajería
+type OutboxConfig struct {
+	Enabled        bool
+	PollInterval   time.Duration
+	BatchSize      int
+	MaxAttempts    int
+	BaseBackoff    time.Duration
+	MaxBackoff     time.Duration
+	LeaseDuration  time.Duration
+	RequestTimeout time.Duration
+}
+
+// DedupConfig configura la deduplicación de webhooks reenviados por los proveedores
+type DedupConfig struct {
+	// Retention es el tiempo durante el que un evento repetido se descarta
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
+
+// ReplayConfig configura la reproducción de webhooks guardados en inbound_messages
+type ReplayConfig struct {
+	// RatePerSecond es el máximo de mensajes reenviados por segundo cuando la solicitud no lo indica
+	RatePerSecond float64
+	// MaxMessages es la cantidad máxima de mensajes entrantes de una reproducción
+	MaxMessages int
+}
+
+// BroadcastConfig configura el motor de envíos masivos
+type BroadcastConfig struct {
+	Enabled       bool
+	Workers       int
+	BatchSize     int
+	PollInterval  time.Duration
+	LeaseDuration time.Duration
+	// RateLimits es el máximo de mensajes por segundo por canal de cada plataforma; cada canal lo
+	// atiende una réplica a la vez, así que el límite vale para todo el servicio
+	RateLimits map[string]int
+}
+
+// EncryptionConfig configura el keyring con el que se sellan las credenciales
+type EncryptionConfig struct {
+	// KeySource es el origen de las claves: "env", "file" o "vault"
+	KeySource string
+	// ActiveKeyID es el ID de la clave que sella los valores nuevos
+	ActiveKeyID string
+	// Keys son las claves anteriores del origen env por ID, además de ENCRYPTION_KEY
+	Keys map[string]string
+	// KeysFile es el archivo JSON con las claves del origen file
+	KeysFile string
+	// VaultPath es la ruta del secreto con las claves del origen vault
+	VaultPath string
+	// ReencryptBatchSize es la cantidad de filas por lote al volver a sellar con la clave activa
+	ReencryptBatchSize int
+	// AllowPlaintext permite arrancar sin keyring y guardar las credenciales en texto plano
+	// cuando las claves no se pueden cargar; sin él el servidor no arranca
+	AllowPlaintext bool
+}
+
+// SecretsConfig configura de dónde se resuelven los secretos en cada request
+type SecretsConfig struct {
+	// Source es el origen principal: "env", "file" o "vault"; las variables de entorno
+	// siempre quedan como respaldo
+	Source string
+	// FileDir es el directorio con un archivo por secreto del origen file
+	FileDir string
+	// VaultMount y VaultPath ubican el secreto KV v2 con una clave por secreto
+	VaultMount string
+	VaultPath  string
+	// CacheTTL es el tiempo que se reutiliza un secreto antes de volver a leerlo
+	CacheTTL time.Duration
+}
+
+// NotificationConfig configura la entrega de los avisos de vencimiento de tokens
+type NotificationConfig struct {
+	// SMTPHost vacío deshabilita los destinos email
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	// SMTPPassword es el respaldo del secreto smtp_password
+	SMTPPassword string
+	SMTPFrom     string
+	// WebhookTimeout es el tiempo máximo de cada envío a un destino webhook
+	WebhookTimeout time.Duration
+}
+
+// HealthConfig configura las verificaciones de /health y /ready
+type HealthConfig struct {
+	// Timeout es el tiempo máximo de cada verificación de una dependencia externa
+	Timeout time.Duration
+	// PlatformCacheTTL es el tiempo que se reutiliza la verificación de la API de cada plataforma
+	PlatformCacheTTL time.Duration
+	// Criticality es el nivel de cada dependencia, "critical" u "optional"; una plataforma sin
+	// nivel propio usa el de "platforms"
+	Criticality map[string]string
+}
+
+// ChannelProbeConfig configura la verificación periódica de conectividad de cada canal
+type ChannelProbeConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	// FailureThreshold es la cantidad de fallos seguidos que pasa un canal activo a error
+	FailureThreshold int
+	// Timeout es el tiempo máximo de cada verificación contra la plataforma
+	Timeout time.Duration
+	// Retention es el tiempo que se conserva el historial de verificaciones
+	Retention time.Duration
 }
 
 type TawkToConfig struct {
@@ -70,6 +202,10 @@ type MailchimpConfig struct {
 }
 
 type GoogleCalendarConfig struct {
+	// Enabled monta el módulo de Google Calendar en el servidor
+	Enabled bool `envconfig:"GOOGLE_CALENDAR_ENABLED" default:"false"`
+	// AutoMigrate aplica al iniciar las migraciones de las tablas de Google Calendar pendientes
+	AutoMigrate bool `envconfig:"GOOGLE_CALENDAR_AUTO_MIGRATE" default:"true"`
 	ClientID     string   `envconfig:"GOOGLE_CLIENT_ID" required:"true"`
 	ClientSecret string   `envconfig:"GOOGLE_CLIENT_SECRET" required:"true"`
 	RedirectURL  string   `envconfig:"GOOGLE_REDIRECT_URL" required:"true"`
@@ -80,6 +216,110 @@ type GoogleCalendarConfig struct {
 	WebhookSecret string  `envconfig:"GOOGLE_WEBHOOK_SECRET"`
 	WebhookURL   string   `envconfig:"GOOGLE_WEBHOOK_URL"`
 	DefaultTimeZone string `envconfig:"GOOGLE_DEFAULT_TIMEZONE" default:"America/Mexico_City"`
+	// OAuthStateTTL es la vigencia del state de un flujo OAuth2 iniciado
+	OAuthStateTTL time.Duration `envconfig:"GOOGLE_OAUTH_STATE_TTL_MINUTES" default:"10"`
+	// ReturnURLHosts son los hosts a los que se puede volver al terminar el flujo OAuth2
+	ReturnURLHosts []string `envconfig:"GOOGLE_OAUTH_RETURN_URL_HOSTS"`
+	// WatchChannelTTL es la vigencia pedida para cada canal push; Google puede acortarla
+	WatchChannelTTL time.Duration `envconfig:"GOOGLE_WATCH_CHANNEL_TTL_HOURS" default:"168"`
+	// WatchRenewBefore es la anticipación con la que se renueva un canal push antes de vencer
+	WatchRenewBefore time.Duration `envconfig:"GOOGLE_WATCH_RENEW_BEFORE_HOURS" default:"24"`
+	// WatchRenewInterval es cada cuánto se buscan canales push próximos a vencer
+	WatchRenewInterval time.Duration `envconfig:"GOOGLE_WATCH_RENEW_INTERVAL_MINUTES" default:"60"`
+	// ReminderPollInterval es cada cuánto se buscan recordatorios de eventos vencidos
+	ReminderPollInterval time.Duration `envconfig:"GOOGLE_REMINDER_POLL_INTERVAL_MS" default:"5000"`
+	ReminderBatchSize    int           `envconfig:"GOOGLE_REMINDER_BATCH_SIZE" default:"100"`
+	// ReminderLease es el tiempo que una réplica retiene un recordatorio reclamado
+	ReminderLease        time.Duration `envconfig:"GOOGLE_REMINDER_LEASE_MS" default:"60000"`
+	// ReminderSendTimeout limita cada envío; el lote se achica para terminar dentro de ReminderLease
+	ReminderSendTimeout  time.Duration `envconfig:"GOOGLE_REMINDER_SEND_TIMEOUT_MS" default:"10000"`
+	ReminderMaxAttempts  int           `envconfig:"GOOGLE_REMINDER_MAX_ATTEMPTS" default:"5"`
+	ReminderRetryBackoff time.Duration `envconfig:"GOOGLE_REMINDER_RETRY_BACKOFF_MS" default:"30000"`
+	// ReminderMisfireGrace es el atraso tolerado antes de aplicar ReminderMisfirePolicy
+	ReminderMisfireGrace time.Duration `envconfig:"GOOGLE_REMINDER_MISFIRE_GRACE_MINUTES" default:"5"`
+	// ReminderMisfirePolicy define qué hacer con un recordatorio vencido durante una caída:
+	// "fire" (enviarlo tarde), "skip" (descartarlo) o "fire_before_start" (enviarlo si el evento no empezó)
+	ReminderMisfirePolicy string `envconfig:"GOOGLE_REMINDER_MISFIRE_POLICY" default:"fire_before_start"`
+	// Secrets resuelve las credenciales OAuth en cada uso; sin él se usan ClientID y ClientSecret
+	Secrets secrets.Provider `envconfig:"-"`
+}
+
+// OAuthClientCredentials retorna el client ID y secret de Google vigentes
+func (c *GoogleCalendarConfig) OAuthClientCredentials(ctx context.Context) (string, string, error) {
+	clientID, err := secrets.Resolve(ctx, c.Secrets, "google_client_id", c.ClientID)
+	if err != nil {
+		return "", "", err
+	}
+	clientSecret, err := secrets.Resolve(ctx, c.Secrets, "google_client_secret", c.ClientSecret)
+	if err != nil {
+		return "", "", err
+	}
+	return clientID, clientSecret, nil
+}
+
+// Validate verifica que la configuración alcance para montar el módulo de Google Calendar y
+// retorna todos los problemas encontrados juntos
+func (c *GoogleCalendarConfig) Validate(ctx context.Context) error {
+	var problems []string
+
+	clientID, clientSecret, err := c.OAuthClientCredentials(ctx)
+	switch {
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("no se pudieron resolver las credenciales OAuth: %v", err))
+	case clientID == "" || clientSecret == "":
+		problems = append(problems, "GOOGLE_CLIENT_ID y GOOGLE_CLIENT_SECRET son requeridos")
+	}
+
+	for name, value := range map[string]string{
+		"GOOGLE_REDIRECT_URL": c.RedirectURL,
+		"GOOGLE_API_BASE_URL": c.APIBaseURL,
+		"GOOGLE_TOKEN_URL":    c.TokenURL,
+		"GOOGLE_AUTH_URL":     c.AuthURL,
+	} {
+		if !isAbsoluteURL(value) {
+			problems = append(problems, name+" debe ser una URL http(s) absoluta")
+		}
+	}
+	if len(c.Scopes) == 0 {
+		problems = append(problems, "GOOGLE_SCOPES no puede estar vacío")
+	}
+	if _, err := time.LoadLocation(c.DefaultTimeZone); err != nil {
+		problems = append(problems, fmt.Sprintf("GOOGLE_DEFAULT_TIMEZONE inválida: %v", err))
+	}
+	if c.OAuthStateTTL <= 0 {
+		problems = append(problems, "GOOGLE_OAUTH_STATE_TTL_MINUTES debe ser mayor que cero")
+	}
+
+	// Sin WebhookURL no se abren canales push y no hace falta revisar su vigencia
+	if c.WebhookURL != "" {
+		if !isAbsoluteURL(c.WebhookURL) || !strings.HasPrefix(c.WebhookURL, "https://") {
+			problems = append(problems, "GOOGLE_WEBHOOK_URL debe ser una URL https absoluta")
+		}
+		if c.WatchChannelTTL <= c.WatchRenewBefore {
+			problems = append(problems, "GOOGLE_WATCH_CHANNEL_TTL_HOURS debe ser mayor que GOOGLE_WATCH_RENEW_BEFORE_HOURS")
+		}
+	}
+
+	if c.ReminderPollInterval <= 0 || c.ReminderBatchSize <= 0 || c.ReminderLease <= 0 || c.ReminderMaxAttempts <= 0 {
+		problems = append(problems, "GOOGLE_REMINDER_POLL_INTERVAL_MS, GOOGLE_REMINDER_BATCH_SIZE, GOOGLE_REMINDER_LEASE_MS y GOOGLE_REMINDER_MAX_ATTEMPTS deben ser mayores que cero")
+	}
+	switch c.ReminderMisfirePolicy {
+	case "fire", "skip", "fire_before_start":
+	default:
+		problems = append(problems, "GOOGLE_REMINDER_MISFIRE_POLICY debe ser fire, skip o fire_before_start")
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s", ErrIncompleteGoogleCalendarConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// isAbsoluteURL indica si value es una URL http(s) con host
+func isAbsoluteURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
 }
 
 func Load() *Config {
@@ -133,6 +373,78 @@ func Load() *Config {
 				"mailchimp": getEnv("MAILCHIMP_VERIFY_TOKEN", ""),
 				"google_calendar": getEnv("GOOGLE_VERIFY_TOKEN", ""),
 			},
+			ChannelCacheTTL:         time.Duration(getEnvAsInt("CHANNEL_CACHE_TTL_SECONDS", 60)) * time.Second,
+			TemplateCacheTTL:        time.Duration(getEnvAsInt("WHATSAPP_TEMPLATE_CACHE_TTL_MINUTES", 15)) * time.Minute,
+			SessionWindow:           time.Duration(getEnvAsInt("SESSION_WINDOW_HOURS", 24)) * time.Hour,
+			UnresolvedChannelPolicy: getEnv("UNRESOLVED_CHANNEL_POLICY", "reject"),
+		},
+		Outbox: OutboxConfig{
+			Enabled:        getEnvAsBool("OUTBOX_ENABLED", true),
+			PollInterval:   time.Duration(getEnvAsInt("OUTBOX_POLL_INTERVAL_MS", 1000)) * time.Millisecond,
+			BatchSize:      getEnvAsInt("OUTBOX_BATCH_SIZE", 50),
+			MaxAttempts:    getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 10),
+			BaseBackoff:    time.Duration(getEnvAsInt("OUTBOX_BASE_BACKOFF_MS", 2000)) * time.Millisecond,
+			MaxBackoff:     time.Duration(getEnvAsInt("OUTBOX_MAX_BACKOFF_MS", 900000)) * time.Millisecond,
+			LeaseDuration:  time.Duration(getEnvAsInt("OUTBOX_LEASE_MS", 60000)) * time.Millisecond,
+			RequestTimeout: time.Duration(getEnvAsInt("OUTBOX_REQUEST_TIMEOUT_MS", 10000)) * time.Millisecond,
+		},
+		Dedup: DedupConfig{
+			Retention:       time.Duration(getEnvAsInt("WEBHOOK_DEDUP_RETENTION_HOURS", 72)) * time.Hour,
+			CleanupInterval: time.Duration(getEnvAsInt("WEBHOOK_DEDUP_CLEANUP_INTERVAL_MINUTES", 60)) * time.Minute,
+		},
+		Replay: ReplayConfig{
+			RatePerSecond: float64(getEnvAsInt("WEBHOOK_REPLAY_RATE_PER_SECOND", 10)),
+			MaxMessages:   getEnvAsInt("WEBHOOK_REPLAY_MAX_MESSAGES", 1000),
+		},
+		Encryption: EncryptionConfig{
+			KeySource:          getEnv("ENCRYPTION_KEY_SOURCE", "env"),
+			ActiveKeyID:        getEnv("ENCRYPTION_KEY_ID", "default"),
+			Keys:               getEnvAsMap("ENCRYPTION_PREVIOUS_KEYS"),
+			KeysFile:           getEnv("ENCRYPTION_KEYS_FILE", ""),
+			VaultPath:          getEnv("ENCRYPTION_VAULT_PATH", "secret/data/integration-service/encryption"),
+			ReencryptBatchSize: getEnvAsInt("ENCRYPTION_REENCRYPT_BATCH_SIZE", 200),
+			AllowPlaintext:     getEnvAsBool("ENCRYPTION_ALLOW_PLAINTEXT", false),
+		},
+		Secrets: SecretsConfig{
+			Source:     getEnv("SECRETS_SOURCE", "env"),
+			FileDir:    getEnv("SECRETS_DIR", "/run/secrets"),
+			VaultMount: getEnv("SECRETS_VAULT_MOUNT", "secret"),
+			VaultPath:  getEnv("SECRETS_VAULT_PATH", "integration-service"),
+			CacheTTL:   time.Duration(getEnvAsInt("SECRETS_CACHE_TTL_SECONDS", 300)) * time.Second,
+		},
+		Notifications: NotificationConfig{
+			SMTPHost:       getEnv("SMTP_HOST", ""),
+			SMTPPort:       getEnvAsInt("SMTP_PORT", 587),
+			SMTPUsername:   getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+			SMTPFrom:       getEnv("SMTP_FROM", ""),
+			WebhookTimeout: time.Duration(getEnvAsInt("NOTIFICATION_WEBHOOK_TIMEOUT_MS", 10000)) * time.Millisecond,
+		},
+		Health: HealthConfig{
+			Timeout:          time.Duration(getEnvAsInt("HEALTH_CHECK_TIMEOUT_MS", 3000)) * time.Millisecond,
+			PlatformCacheTTL: time.Duration(getEnvAsInt("HEALTH_PLATFORM_CACHE_TTL_SECONDS", 60)) * time.Second,
+			Criticality:      getEnvAsMap("HEALTH_CRITICALITY"),
+		},
+		ChannelProbe: ChannelProbeConfig{
+			Enabled:          getEnvAsBool("CHANNEL_PROBE_ENABLED", true),
+			Interval:         time.Duration(getEnvAsInt("CHANNEL_PROBE_INTERVAL_MINUTES", 15)) * time.Minute,
+			FailureThreshold: getEnvAsInt("CHANNEL_PROBE_FAILURE_THRESHOLD", 3),
+			Timeout:          time.Duration(getEnvAsInt("CHANNEL_PROBE_TIMEOUT_MS", 10000)) * time.Millisecond,
+			Retention:        time.Duration(getEnvAsInt("CHANNEL_PROBE_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		},
+		Broadcast: BroadcastConfig{
+			Enabled:       getEnvAsBool("BROADCAST_ENABLED", true),
+			Workers:       getEnvAsInt("BROADCAST_WORKERS", 10),
+			BatchSize:     getEnvAsInt("BROADCAST_BATCH_SIZE", 200),
+			PollInterval:  time.Duration(getEnvAsInt("BROADCAST_POLL_INTERVAL_MS", 2000)) * time.Millisecond,
+			LeaseDuration: time.Duration(getEnvAsInt("BROADCAST_LEASE_MS", 300000)) * time.Millisecond,
+			RateLimits: map[string]int{
+				"whatsapp":  getEnvAsInt("BROADCAST_RATE_WHATSAPP", 80),
+				"messenger": getEnvAsInt("BROADCAST_RATE_MESSENGER", 40),
+				"instagram": getEnvAsInt("BROADCAST_RATE_INSTAGRAM", 20),
+				"telegram":  getEnvAsInt("BROADCAST_RATE_TELEGRAM", 30),
+				"webchat":   getEnvAsInt("BROADCAST_RATE_WEBCHAT", 100),
+			},
 		},
 		MercadoPago: MercadoPagoConfig{
 			AccessToken:  getEnv("MP_ACCESS_TOKEN", ""),
@@ -158,6 +470,8 @@ func Load() *Config {
 			DataCenter:    getEnv("MAILCHIMP_DATA_CENTER", ""),
 		},
 		GoogleCalendar: GoogleCalendarConfig{
+			Enabled:      getEnvAsBool("GOOGLE_CALENDAR_ENABLED", false),
+			AutoMigrate:  getEnvAsBool("GOOGLE_CALENDAR_AUTO_MIGRATE", true),
 			ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
 			ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
 			RedirectURL:  getEnv("GOOGLE_REDIRECT_URL", ""),
@@ -172,6 +486,19 @@ func Load() *Config {
 			WebhookSecret: getEnv("GOOGLE_WEBHOOK_SECRET", ""),
 			WebhookURL:   getEnv("GOOGLE_WEBHOOK_URL", ""),
 			DefaultTimeZone: getEnv("GOOGLE_DEFAULT_TIMEZONE", "America/Mexico_City"),
+			OAuthStateTTL: time.Duration(getEnvAsInt("GOOGLE_OAUTH_STATE_TTL_MINUTES", 10)) * time.Minute,
+			ReturnURLHosts: getEnvAsSlice("GOOGLE_OAUTH_RETURN_URL_HOSTS", nil),
+			WatchChannelTTL:    time.Duration(getEnvAsInt("GOOGLE_WATCH_CHANNEL_TTL_HOURS", 168)) * time.Hour,
+			WatchRenewBefore:   time.Duration(getEnvAsInt("GOOGLE_WATCH_RENEW_BEFORE_HOURS", 24)) * time.Hour,
+			WatchRenewInterval: time.Duration(getEnvAsInt("GOOGLE_WATCH_RENEW_INTERVAL_MINUTES", 60)) * time.Minute,
+			ReminderPollInterval:  time.Duration(getEnvAsInt("GOOGLE_REMINDER_POLL_INTERVAL_MS", 5000)) * time.Millisecond,
+			ReminderBatchSize:     getEnvAsInt("GOOGLE_REMINDER_BATCH_SIZE", 100),
+			ReminderLease:         time.Duration(getEnvAsInt("GOOGLE_REMINDER_LEASE_MS", 60000)) * time.Millisecond,
+			ReminderSendTimeout:   time.Duration(getEnvAsInt("GOOGLE_REMINDER_SEND_TIMEOUT_MS", 10000)) * time.Millisecond,
+			ReminderMaxAttempts:   getEnvAsInt("GOOGLE_REMINDER_MAX_ATTEMPTS", 5),
+			ReminderRetryBackoff:  time.Duration(getEnvAsInt("GOOGLE_REMINDER_RETRY_BACKOFF_MS", 30000)) * time.Millisecond,
+			ReminderMisfireGrace:  time.Duration(getEnvAsInt("GOOGLE_REMINDER_MISFIRE_GRACE_MINUTES", 5)) * time.Minute,
+			ReminderMisfirePolicy: getEnv("GOOGLE_REMINDER_MISFIRE_POLICY", "fire_before_start"),
 		},
 	}
 }
@@ -192,6 +519,27 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsMap lee pares id:valor separados por coma, p. ej. "v1:clave1,v2:clave2"
+func getEnvAsMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range getEnvAsSlice(key, nil) {
+		id, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && id != "" {
+			result[id] = value
+		}
+	}
+	return result
+}
+
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return strings.Split(value, ",")
@@ -7,4 +7,6 @@ var (
 	ErrMissingAccessToken = errors.New("access token de Mercado Pago es requerido")
 	ErrInvalidCredentials = errors.New("credenciales de Mercado Pago inválidas")
 	ErrSDKInitialization  = errors.New("error al inicializar el SDK de Mercado Pago")
+
+	ErrIncompleteGoogleCalendarConfig = errors.New("configuración de Google Calendar incompleta")
 )
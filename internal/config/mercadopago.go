@@ -1,7 +1,10 @@
 package config
 
 import (
+	"context"
 	"os"
+
+	"it-integration-service/pkg/secrets"
 )
 
 // MercadoPagoConfig contiene la configuración para Mercado Pago
@@ -13,11 +16,18 @@ type MercadoPagoConfig struct {
 	WebhookURL   string
 	SecretKey    string // Clave secreta para validar webhooks
 	SDK          interface{}
+	// Secrets resuelve el access token y la clave de webhooks en cada uso, para rotarlos sin redeploy
+	Secrets secrets.Provider
 }
 
-// NewMercadoPagoConfig crea una nueva instancia de configuración de Mercado Pago
-func NewMercadoPagoConfig() (*MercadoPagoConfig, error) {
-	accessToken := os.Getenv("MP_ACCESS_TOKEN")
+// NewMercadoPagoConfig crea una nueva instancia de configuración de Mercado Pago. Con provider
+// el access token y la clave de webhooks se resuelven en cada uso; los valores de entorno
+// quedan como respaldo.
+func NewMercadoPagoConfig(provider secrets.Provider) (*MercadoPagoConfig, error) {
+	accessToken, err := secrets.Resolve(context.Background(), provider, "mp_access_token", os.Getenv("MP_ACCESS_TOKEN"))
+	if err != nil {
+		return nil, err
+	}
 	if accessToken == "" {
 		return nil, ErrMissingAccessToken
 	}
@@ -43,9 +53,20 @@ func NewMercadoPagoConfig() (*MercadoPagoConfig, error) {
 		WebhookURL:   webhookURL,
 		SecretKey:    secretKey,
 		SDK:          sdk,
+		Secrets:      provider,
 	}, nil
 }
 
+// ResolveAccessToken retorna el access token vigente
+func (c *MercadoPagoConfig) ResolveAccessToken(ctx context.Context) (string, error) {
+	return secrets.Resolve(ctx, c.Secrets, "mp_access_token", c.AccessToken)
+}
+
+// ResolveWebhookSecret retorna la clave vigente para validar webhooks
+func (c *MercadoPagoConfig) ResolveWebhookSecret(ctx context.Context) (string, error) {
+	return secrets.Resolve(ctx, c.Secrets, "mp_webhook_secret", c.SecretKey)
+}
+
 // IsProduction verifica si está en modo producción
 func (c *MercadoPagoConfig) IsProduction() bool {
 	return c.Environment == "production"
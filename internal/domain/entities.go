@@ -15,28 +15,178 @@ type ChannelIntegration struct {
 	WebhookURL  string            `json:"webhook_url" db:"webhook_url"`
 	Status      IntegrationStatus `json:"status" db:"status"`
 	Config      json.RawMessage   `json:"config" db:"config"`
-	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time         `json:"updated_at" db:"updated_at"`
+	// Ciclo de vida del access token; nil cuando la plataforma no lo informa o aún no se consultó
+	TokenExpiresAt       *time.Time `json:"token_expires_at,omitempty" db:"token_expires_at"`
+	TokenIssuedAt        *time.Time `json:"token_issued_at,omitempty" db:"token_issued_at"`
+	TokenLastValidatedAt *time.Time `json:"token_last_validated_at,omitempty" db:"token_last_validated_at"`
+	TokenLastRotatedAt   *time.Time `json:"token_last_rotated_at,omitempty" db:"token_last_rotated_at"`
+	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ChannelIdentifier asocia un identificador de plataforma (phone_number_id, page_id, etc.) con un canal
+type ChannelIdentifier struct {
+	Platform       Platform  `json:"platform" db:"platform"`
+	IdentifierType string    `json:"identifier_type" db:"identifier_type"`
+	Identifier     string    `json:"identifier" db:"identifier"`
+	ChannelID      string    `json:"channel_id" db:"channel_id"`
+	TenantID       string    `json:"tenant_id" db:"tenant_id"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 }
 
 // InboundMessage representa un mensaje entrante para logs/debug
 type InboundMessage struct {
-	ID         string          `json:"id" db:"id"`
-	Platform   Platform        `json:"platform" db:"platform"`
-	Payload    json.RawMessage `json:"payload" db:"payload"`
-	ReceivedAt time.Time       `json:"received_at" db:"received_at"`
-	Processed  bool            `json:"processed" db:"processed"`
+	ID               string          `json:"id" db:"id"`
+	Platform         Platform        `json:"platform" db:"platform"`
+	TenantID         string          `json:"tenant_id,omitempty" db:"tenant_id"`
+	ChannelID        string          `json:"channel_id,omitempty" db:"channel_id"`
+	Payload          json.RawMessage `json:"payload" db:"payload"`
+	ReceivedAt       time.Time       `json:"received_at" db:"received_at"`
+	Processed        bool            `json:"processed" db:"processed"`
+	QuarantineReason string          `json:"quarantine_reason,omitempty" db:"quarantine_reason"`
+	Results          []InboundResult `json:"results,omitempty" db:"results"`
+}
+
+// InboundResult registra el resultado de entrega de cada mensaje contenido en un webhook. TenantID
+// y ChannelID son los del canal de la entrada que trajo el evento: un webhook de Meta puede agrupar
+// eventos de varios canales.
+type InboundResult struct {
+	Index          int                 `json:"index"`
+	EventType      EventType           `json:"event_type"`
+	MessageID      string              `json:"message_id"`
+	Sender         string              `json:"sender,omitempty"`
+	IdempotencyKey string              `json:"idempotency_key"`
+	TenantID       string              `json:"tenant_id,omitempty"`
+	ChannelID      string              `json:"channel_id,omitempty"`
+	Status         InboundResultStatus `json:"status"`
+	Error          string              `json:"error,omitempty"`
+	Attempts       int                 `json:"attempts"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// EventType distingue los mensajes de usuario de los recibos de estado (entregado, leído, fallido)
+type EventType string
+
+const (
+	EventTypeMessage EventType = "message"
+	EventTypeStatus  EventType = "status"
+)
+
+// InboundResultStatus representa el estado de entrega de un mensaje de un webhook
+type InboundResultStatus string
+
+const (
+	InboundResultPending    InboundResultStatus = "pending"
+	InboundResultDelivered  InboundResultStatus = "delivered"
+	InboundResultFailed     InboundResultStatus = "failed"
+	InboundResultDeadLetter InboundResultStatus = "dead_letter"
+	InboundResultDuplicate  InboundResultStatus = "duplicate"
+	// InboundResultUnresolved indica que el canal de la entrada no está registrado o está deshabilitado
+	InboundResultUnresolved InboundResultStatus = "unresolved"
+)
+
+// InboundMessageFilter selecciona mensajes entrantes; los campos vacíos no filtran
+type InboundMessageFilter struct {
+	Platform  Platform
+	TenantID  string
+	ChannelID string
+	IDs       []string
+	// Sender selecciona los webhooks con al menos un mensaje de ese remitente
+	Sender    string
+	From      *time.Time
+	To        *time.Time
+	Processed *bool
+	// AfterReceivedAt y AfterID continúan la lectura después del último mensaje leído
+	AfterReceivedAt *time.Time
+	AfterID         string
+	// Descending retorna primero los más recientes; el cursor avanza hacia los más antiguos
+	Descending bool
+	Limit      int
+}
+
+// OutboundMessageFilter selecciona mensajes salientes, los más recientes primero; los campos
+// vacíos no filtran. Tenant y plataforma se toman del canal.
+type OutboundMessageFilter struct {
+	TenantID  string
+	ChannelID string
+	Platform  Platform
+	Status    MessageStatus
+	Recipient string
+	From      *time.Time
+	To        *time.Time
+	// BeforeTimestamp y BeforeID continúan la lectura después del último mensaje leído
+	BeforeTimestamp *time.Time
+	BeforeID        string
+	Limit           int
+}
+
+// ProcessedWebhookEvent registra un evento ya aceptado para descartar los reenvíos del proveedor.
+// EventKey es el ID del evento en la plataforma (wamid, mid, update_id de Telegram).
+type ProcessedWebhookEvent struct {
+	Platform         Platform  `json:"platform" db:"platform"`
+	ChannelID        string    `json:"channel_id" db:"channel_id"`
+	EventKey         string    `json:"event_key" db:"event_key"`
+	InboundMessageID string    `json:"inbound_message_id" db:"inbound_message_id"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt        time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// ConversationSession es la ventana de atención de un contacto en un canal. Meta solo acepta
+// mensajes libres mientras la ventana abierta por el último mensaje del contacto siga vigente.
+type ConversationSession struct {
+	ChannelID       string    `json:"channel_id" db:"channel_id"`
+	ContactID       string    `json:"contact_id" db:"contact_id"`
+	TenantID        string    `json:"tenant_id" db:"tenant_id"`
+	Platform        Platform  `json:"platform" db:"platform"`
+	LastInboundAt   time.Time `json:"last_inbound_at" db:"last_inbound_at"`
+	WindowExpiresAt time.Time `json:"window_expires_at" db:"window_expires_at"`
+	Category        string    `json:"category" db:"category"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ConversationCategoryService es la categoría de una conversación iniciada por el contacto
+const ConversationCategoryService = "service"
+
+// IsOpen indica si la ventana sigue vigente en el momento dado
+func (s *ConversationSession) IsOpen(now time.Time) bool {
+	return now.Before(s.WindowExpiresAt)
+}
+
+// OutboxMessage representa un mensaje normalizado pendiente de entrega al servicio de mensajería
+type OutboxMessage struct {
+	ID               string          `json:"id" db:"id"`
+	InboundMessageID string          `json:"inbound_message_id" db:"inbound_message_id"`
+	ItemIndex        int             `json:"item_index" db:"item_index"`
+	EventType        EventType       `json:"event_type" db:"event_type"`
+	Platform         Platform        `json:"platform" db:"platform"`
+	MessageID        string          `json:"message_id" db:"message_id"`
+	IdempotencyKey   string          `json:"idempotency_key" db:"idempotency_key"`
+	Payload          json.RawMessage `json:"payload" db:"payload"`
+	Status           OutboxStatus    `json:"status" db:"status"`
+	Attempts         int             `json:"attempts" db:"attempts"`
+	NextAttemptAt    time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError        string          `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at" db:"updated_at"`
+	DeliveredAt      *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+	// DedupEvent es el registro de deduplicación del evento, que se guarda en la misma transacción
+	// que la entrada; nil si el evento no se deduplica
+	DedupEvent *ProcessedWebhookEvent `json:"-" db:"-"`
+	// Duplicate indica que el evento ya estaba registrado y la entrada no se encoló
+	Duplicate bool `json:"-" db:"-"`
 }
 
 // OutboundMessageLog representa el log de mensajes salientes
 type OutboundMessageLog struct {
-	ID        string          `json:"id" db:"id"`
-	ChannelID string          `json:"channel_id" db:"channel_id"`
-	Recipient string          `json:"recipient" db:"recipient"`
-	Content   json.RawMessage `json:"content" db:"content"`
-	Status    MessageStatus   `json:"status" db:"status"`
-	Response  json.RawMessage `json:"response" db:"response"`
-	Timestamp time.Time       `json:"timestamp" db:"timestamp"`
+	ID                string          `json:"id" db:"id"`
+	ChannelID         string          `json:"channel_id" db:"channel_id"`
+	Recipient         string          `json:"recipient" db:"recipient"`
+	Content           json.RawMessage `json:"content" db:"content"`
+	Status            MessageStatus   `json:"status" db:"status"`
+	Response          json.RawMessage `json:"response" db:"response"`
+	Timestamp         time.Time       `json:"timestamp" db:"timestamp"`
+	PlatformMessageID string          `json:"platform_message_id,omitempty" db:"platform_message_id"`
 }
 
 // SendMessageRequest representa una solicitud de envío de mensaje
@@ -51,14 +201,102 @@ type MessageContent struct {
 	Type string `json:"type" binding:"required"`
 	Text string `json:"text,omitempty"`
 	// Otros campos para diferentes tipos de contenido
-	Media *MediaContent `json:"media,omitempty"`
+	Media       *MediaContent       `json:"media,omitempty"`
+	Attachments []MediaContent      `json:"attachments,omitempty"`
+	Location    *LocationContent    `json:"location,omitempty"`
+	Contacts    []ContactCard       `json:"contacts,omitempty"`
+	Interactive *InteractiveContent `json:"interactive,omitempty"`
+	Reaction    *ReactionContent    `json:"reaction,omitempty"`
+	ReplyTo     *ReplyReference     `json:"reply_to,omitempty"`
+	// Template solo aplica a envíos de WhatsApp fuera de la ventana de 24 horas
+	Template *TemplateContent `json:"template,omitempty"`
 }
 
+// Tipos de contenido normalizados
+const (
+	ContentTypeText        = "text"
+	ContentTypeImage       = "image"
+	ContentTypeAudio       = "audio"
+	ContentTypeVideo       = "video"
+	ContentTypeDocument    = "document"
+	ContentTypeSticker     = "sticker"
+	ContentTypeLocation    = "location"
+	ContentTypeContacts    = "contacts"
+	ContentTypeInteractive = "interactive"
+	ContentTypeReaction    = "reaction"
+	ContentTypeTemplate    = "template"
+	ContentTypeUnsupported = "unsupported"
+)
+
 // MediaContent representa contenido multimedia
 type MediaContent struct {
-	URL      string `json:"url"`
+	// ID es el identificador del archivo en la plataforma (media_id de WhatsApp, file_id de Telegram)
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	URL      string `json:"url,omitempty"`
 	Caption  string `json:"caption,omitempty"`
 	MimeType string `json:"mime_type,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// TemplateContent representa el envío de una plantilla aprobada de WhatsApp con sus parámetros
+type TemplateContent struct {
+	Name     string `json:"name"`
+	Language string `json:"language"`
+	// HeaderParams completa los placeholders de un encabezado de texto
+	HeaderParams []string `json:"header_params,omitempty"`
+	// HeaderMedia es el archivo de un encabezado de imagen, video o documento
+	HeaderMedia *MediaContent         `json:"header_media,omitempty"`
+	BodyParams  []string              `json:"body_params,omitempty"`
+	Buttons     []TemplateButtonParam `json:"buttons,omitempty"`
+}
+
+// TemplateButtonParam es el parámetro de un botón de la plantilla
+type TemplateButtonParam struct {
+	// Index es la posición del botón en la plantilla, empezando en 0
+	Index int `json:"index"`
+	// SubType es quick_reply (Param es el payload) o url (Param completa el sufijo de la URL)
+	SubType string `json:"sub_type"`
+	Param   string `json:"param"`
+}
+
+// LocationContent representa una ubicación compartida
+type LocationContent struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+}
+
+// ContactCard representa una tarjeta de contacto compartida
+type ContactCard struct {
+	Name         string   `json:"name"`
+	Phones       []string `json:"phones,omitempty"`
+	Emails       []string `json:"emails,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	// PlatformUserID es el usuario del contacto en la plataforma (wa_id, user_id de Telegram)
+	PlatformUserID string `json:"platform_user_id,omitempty"`
+}
+
+// InteractiveContent representa la respuesta a un botón, lista, quick reply o postback
+type InteractiveContent struct {
+	// Type indica el origen: button_reply, list_reply, button, quick_reply, postback o callback_query
+	Type        string `json:"type"`
+	ID          string `json:"id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ReactionContent representa una reacción a un mensaje; Emoji vacío indica que se retiró
+type ReactionContent struct {
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji,omitempty"`
+}
+
+// ReplyReference identifica el mensaje al que responde el usuario
+type ReplyReference struct {
+	MessageID string `json:"message_id"`
+	Sender    string `json:"sender,omitempty"`
 }
 
 // Platform enum para plataformas de mensajería
@@ -95,13 +333,77 @@ const (
 	StatusError    IntegrationStatus = "error"
 )
 
+// IntegrationCount es la cantidad de integraciones de una plataforma en un estado
+type IntegrationCount struct {
+	Platform Platform          `json:"platform"`
+	Status   IntegrationStatus `json:"status"`
+	Count    int               `json:"count"`
+}
+
+// ChannelHealthCheck registra una verificación de conectividad de un canal contra su plataforma
+type ChannelHealthCheck struct {
+	ID        int64    `json:"id" db:"id"`
+	ChannelID string   `json:"channel_id" db:"channel_id"`
+	TenantID  string   `json:"tenant_id" db:"tenant_id"`
+	Platform  Platform `json:"platform" db:"platform"`
+	Healthy   bool     `json:"healthy" db:"healthy"`
+	Error     string   `json:"error,omitempty" db:"error"`
+	// Details es lo informado por la plataforma (webhook de Telegram, número de WhatsApp, página)
+	Details   json.RawMessage `json:"details,omitempty" db:"details"`
+	LatencyMs int64           `json:"latency_ms" db:"latency_ms"`
+	// ConsecutiveFailures cuenta los fallos seguidos incluyendo esta verificación
+	ConsecutiveFailures int       `json:"consecutive_failures" db:"consecutive_failures"`
+	CheckedAt           time.Time `json:"checked_at" db:"checked_at"`
+}
+
+// ChannelHealth resume la conectividad de un canal con sus últimas verificaciones
+type ChannelHealth struct {
+	ChannelID string            `json:"channel_id"`
+	TenantID  string            `json:"tenant_id"`
+	Platform  Platform          `json:"platform"`
+	Status    IntegrationStatus `json:"status"`
+	// Healthy refleja la última verificación; es false si el canal nunca se verificó
+	Healthy             bool                  `json:"healthy"`
+	ConsecutiveFailures int                   `json:"consecutive_failures"`
+	LastError           string                `json:"last_error,omitempty"`
+	LastCheckedAt       *time.Time            `json:"last_checked_at,omitempty"`
+	LastSuccessAt       *time.Time            `json:"last_success_at,omitempty"`
+	History             []*ChannelHealthCheck `json:"history"`
+}
+
 // MessageStatus enum para estado de mensajes
 type MessageStatus string
 
 const (
-	MessageStatusSent   MessageStatus = "sent"
-	MessageStatusFailed MessageStatus = "failed"
-	MessageStatusQueued MessageStatus = "queued"
+	MessageStatusSent      MessageStatus = "sent"
+	MessageStatusFailed    MessageStatus = "failed"
+	MessageStatusQueued    MessageStatus = "queued"
+	MessageStatusDelivered MessageStatus = "delivered"
+	MessageStatusRead      MessageStatus = "read"
+)
+
+// OutboxStatus enum para estado de entrega de mensajes en el outbox
+type OutboxStatus string
+
+const (
+	OutboxStatusPending    OutboxStatus = "pending"
+	OutboxStatusProcessing OutboxStatus = "processing"
+	OutboxStatusDelivered  OutboxStatus = "delivered"
+	OutboxStatusDeadLetter OutboxStatus = "dead_letter"
+)
+
+// ScheduledNotificationStatus enum para estado de los recordatorios programados
+type ScheduledNotificationStatus string
+
+const (
+	ScheduledNotificationPending    ScheduledNotificationStatus = "pending"
+	ScheduledNotificationProcessing ScheduledNotificationStatus = "processing"
+	ScheduledNotificationSent       ScheduledNotificationStatus = "sent"
+	// ScheduledNotificationCancelled es un recordatorio de un evento eliminado o movido a otra hora
+	ScheduledNotificationCancelled ScheduledNotificationStatus = "cancelled"
+	// ScheduledNotificationSkipped es un recordatorio vencido durante una caída que la política de misfire descartó
+	ScheduledNotificationSkipped ScheduledNotificationStatus = "skipped"
+	ScheduledNotificationFailed  ScheduledNotificationStatus = "failed"
 )
 
 // CalendarType enum para tipos de calendario de Google
@@ -204,11 +506,72 @@ type GoogleCalendarIntegration struct {
 	WebhookResource string                 `json:"webhook_resource"`
 	Status          IntegrationStatus      `json:"status"`
 	Config          map[string]interface{} `json:"config"`
+	SyncToken       string                 `json:"-"` // nextSyncToken de Google; vacío fuerza una sincronización completa
+	LastSyncAt      *time.Time             `json:"last_sync_at,omitempty"`
 	CreatedAt       time.Time              `json:"created_at"`
 	UpdatedAt       time.Time              `json:"updated_at"`
 	DeletedAt       *time.Time             `json:"deleted_at,omitempty"` // Soft delete
 }
 
+// OAuthState vincula el parámetro state de un flujo OAuth2 con la integración que lo inició.
+// Es de un solo uso: ConsumedAt se marca al recibir el callback.
+type OAuthState struct {
+	StateToken   string       `json:"state_token" db:"state_token"`
+	TenantID     string       `json:"tenant_id" db:"tenant_id"`
+	ChannelID    string       `json:"channel_id" db:"channel_id"`
+	CalendarType CalendarType `json:"calendar_type" db:"calendar_type"`
+	// CodeVerifier es el verificador PKCE que se envía al intercambiar el código
+	CodeVerifier string `json:"-" db:"code_verifier"`
+	// ReturnURL es la URL del tenant a la que vuelve el navegador al terminar el flujo
+	ReturnURL  string     `json:"return_url,omitempty" db:"return_url"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty" db:"consumed_at"`
+}
+
+// CalendarWatchChannel es un canal de notificaciones push de Google Calendar abierto para una integración.
+// ID es el que Google envía en X-Goog-Channel-ID y ChannelID el canal de la integración.
+type CalendarWatchChannel struct {
+	ID         string `json:"id" db:"id"`
+	ChannelID  string `json:"channel_id" db:"channel_id"`
+	CalendarID string `json:"calendar_id" db:"calendar_id"`
+	ResourceID string `json:"resource_id" db:"resource_id"`
+	// Token se envía a Google al abrir el canal y vuelve en X-Goog-Channel-Token en cada notificación
+	Token      string     `json:"-" db:"token"`
+	Expiration time.Time  `json:"expiration" db:"expiration"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	StoppedAt  *time.Time `json:"stopped_at,omitempty" db:"stopped_at"`
+}
+
+// WebhookNotification es una notificación push de Google Calendar, leída de los headers X-Goog-*
+type WebhookNotification struct {
+	WatchChannelID string `json:"watch_channel_id"`
+	ResourceID     string `json:"resource_id"`
+	ResourceURI    string `json:"resource_uri"`
+	// State es sync al abrir el canal, exists si cambió el recurso y not_exists si dejó de existir
+	State         string `json:"state"`
+	MessageNumber string `json:"message_number"`
+	Token         string `json:"-"`
+}
+
+// ScheduledNotification es un recordatorio de un evento de calendario programado para enviarse en FireAt
+type ScheduledNotification struct {
+	ID              string                      `json:"id" db:"id"`
+	EventID         string                      `json:"event_id" db:"event_id"`
+	TenantID        string                      `json:"tenant_id" db:"tenant_id"`
+	ChannelID       string                      `json:"channel_id" db:"channel_id"`
+	ReminderMinutes int                         `json:"reminder_minutes" db:"reminder_minutes"`
+	FireAt          time.Time                   `json:"fire_at" db:"fire_at"`
+	Status          ScheduledNotificationStatus `json:"status" db:"status"`
+	Attempts        int                         `json:"attempts" db:"attempts"`
+	// NextAttemptAt es FireAt hasta el primer fallo y luego la hora del siguiente reintento
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+}
+
 // CreateEventRequest representa una solicitud de creación de evento
 type CreateEventRequest struct {
 	TenantID    string             `json:"tenant_id" binding:"required"`
@@ -301,6 +664,58 @@ type ChatMessage struct {
 	Status    string    `json:"status,omitempty"` // Para mensajes outbound
 }
 
+// PayloadMode indica cómo se retorna el contenido de los mensajes en las consultas
+type PayloadMode string
+
+const (
+	PayloadModeFull PayloadMode = "full"
+	// PayloadModeRedacted conserva la estructura y los campos técnicos y oculta los demás valores
+	PayloadModeRedacted PayloadMode = "redacted"
+	PayloadModeNone     PayloadMode = "none"
+)
+
+// InboundMessageQuery representa una consulta de mensajes entrantes, los más recientes primero.
+// From es inclusivo y To exclusivo; Cursor es el next_cursor de la página anterior.
+type InboundMessageQuery struct {
+	TenantID  string
+	ChannelID string
+	Platform  Platform
+	Processed *bool
+	Sender    string
+	From      *time.Time
+	To        *time.Time
+	Cursor    string
+	Limit     int
+	Payload   PayloadMode
+}
+
+// OutboundMessageQuery representa una consulta del log de mensajes salientes, los más recientes primero
+type OutboundMessageQuery struct {
+	TenantID  string
+	ChannelID string
+	Platform  Platform
+	Status    MessageStatus
+	Recipient string
+	From      *time.Time
+	To        *time.Time
+	Cursor    string
+	Limit     int
+	Payload   PayloadMode
+}
+
+// InboundMessagePage es una página de mensajes entrantes
+type InboundMessagePage struct {
+	Messages []*InboundMessage `json:"messages"`
+	// NextCursor pide la siguiente página; vacío cuando no hay más resultados
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// OutboundMessagePage es una página del log de mensajes salientes
+type OutboundMessagePage struct {
+	Messages   []*OutboundMessageLog `json:"messages"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
 // ChatHistory representa el historial de conversación con un usuario
 type ChatHistory struct {
 	Platform   Platform      `json:"platform"`
@@ -322,6 +737,8 @@ type BroadcastResult struct {
 	TotalSent   int                   `json:"total_sent"`
 	TotalFailed int                   `json:"total_failed"`
 	Results     []BroadcastItemResult `json:"results"`
+	// NextAfterID es el cursor para pedir la siguiente página de resultados
+	NextAfterID int64 `json:"next_after_id,omitempty"`
 }
 
 // BroadcastItemResult representa el resultado de un envío individual
@@ -332,3 +749,230 @@ type BroadcastItemResult struct {
 	Error     string   `json:"error,omitempty"`
 	MessageID string   `json:"message_id,omitempty"`
 }
+
+// BroadcastJobStatus representa el estado de un envío masivo
+type BroadcastJobStatus string
+
+const (
+	// BroadcastJobLoading indica que los destinatarios se están cargando
+	BroadcastJobLoading   BroadcastJobStatus = "loading"
+	BroadcastJobQueued    BroadcastJobStatus = "queued"
+	BroadcastJobRunning   BroadcastJobStatus = "running"
+	BroadcastJobPaused    BroadcastJobStatus = "paused"
+	BroadcastJobCompleted BroadcastJobStatus = "completed"
+	BroadcastJobCancelled BroadcastJobStatus = "cancelled"
+	BroadcastJobFailed    BroadcastJobStatus = "failed"
+)
+
+// BroadcastJob representa un envío masivo procesado en segundo plano
+type BroadcastJob struct {
+	ID                 string              `json:"id" db:"id"`
+	TenantID           string              `json:"tenant_id" db:"tenant_id"`
+	Platforms          []Platform          `json:"platforms" db:"platforms"`
+	Channels           map[Platform]string `json:"channels" db:"channels"`
+	Content            MessageContent      `json:"content" db:"content"`
+	Status             BroadcastJobStatus  `json:"status" db:"status"`
+	TotalRecipients    int                 `json:"total_recipients" db:"total_recipients"`
+	RejectedRecipients int                 `json:"rejected_recipients" db:"rejected_recipients"`
+	Error              string              `json:"error,omitempty" db:"error"`
+	CreatedAt          time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time           `json:"updated_at" db:"updated_at"`
+	StartedAt          *time.Time          `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt        *time.Time          `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// BroadcastRecipientStatus representa el estado del envío a un destinatario
+type BroadcastRecipientStatus string
+
+const (
+	BroadcastRecipientPending    BroadcastRecipientStatus = "pending"
+	BroadcastRecipientProcessing BroadcastRecipientStatus = "processing"
+	BroadcastRecipientSent       BroadcastRecipientStatus = "sent"
+	BroadcastRecipientFailed     BroadcastRecipientStatus = "failed"
+	BroadcastRecipientCancelled  BroadcastRecipientStatus = "cancelled"
+)
+
+// BroadcastRecipient representa un destinatario de un envío masivo en una plataforma
+type BroadcastRecipient struct {
+	ID            int64                    `json:"id" db:"id"`
+	JobID         string                   `json:"job_id" db:"job_id"`
+	Platform      Platform                 `json:"platform" db:"platform"`
+	ChannelID     string                   `json:"channel_id" db:"channel_id"`
+	Recipient     string                   `json:"recipient" db:"recipient"`
+	Status        BroadcastRecipientStatus `json:"status" db:"status"`
+	MessageID     string                   `json:"message_id,omitempty" db:"message_id"`
+	OutboundLogID string                   `json:"outbound_log_id,omitempty" db:"outbound_log_id"`
+	Error         string                   `json:"error,omitempty" db:"error"`
+	UpdatedAt     time.Time                `json:"updated_at" db:"updated_at"`
+}
+
+// BroadcastProgress resume el avance de un envío masivo
+type BroadcastProgress struct {
+	JobID      string             `json:"job_id"`
+	Status     BroadcastJobStatus `json:"status"`
+	Total      int                `json:"total"`
+	Pending    int                `json:"pending"`
+	Processing int                `json:"processing"`
+	Sent       int                `json:"sent"`
+	Failed     int                `json:"failed"`
+	Cancelled  int                `json:"cancelled"`
+	Percent    float64            `json:"percent"`
+}
+
+// CredentialReencryptionState representa el estado de la re-encriptación de credenciales
+type CredentialReencryptionState string
+
+const (
+	CredentialReencryptionIdle      CredentialReencryptionState = "idle"
+	CredentialReencryptionRunning   CredentialReencryptionState = "running"
+	CredentialReencryptionCompleted CredentialReencryptionState = "completed"
+	CredentialReencryptionFailed    CredentialReencryptionState = "failed"
+)
+
+// CredentialReencryptionTable es el avance de la re-encriptación de una tabla
+type CredentialReencryptionTable struct {
+	Table     string `json:"table"`
+	Scanned   int    `json:"scanned"`
+	Resealed  int    `json:"resealed"`
+	Failed    int    `json:"failed"`
+	LastError string `json:"last_error,omitempty"`
+	Done      bool   `json:"done"`
+}
+
+// CredentialReencryptionStatus resume la última re-encriptación de credenciales con la clave activa
+type CredentialReencryptionStatus struct {
+	State       CredentialReencryptionState   `json:"state"`
+	ActiveKeyID string                        `json:"active_key_id"`
+	DryRun      bool                          `json:"dry_run"`
+	Tables      []CredentialReencryptionTable `json:"tables"`
+	Error       string                        `json:"error,omitempty"`
+	StartedAt   *time.Time                    `json:"started_at,omitempty"`
+	CompletedAt *time.Time                    `json:"completed_at,omitempty"`
+}
+
+// NotificationDestinationType es el medio por el que se avisa a un tenant
+type NotificationDestinationType string
+
+const (
+	NotificationDestinationWebhook NotificationDestinationType = "webhook"
+	NotificationDestinationEmail   NotificationDestinationType = "email"
+	NotificationDestinationEvent   NotificationDestinationType = "event"
+)
+
+// NotificationDestination es un destino de los avisos de un tenant
+type NotificationDestination struct {
+	Type NotificationDestinationType `json:"type"`
+	// URL es el endpoint HTTPS de un destino webhook
+	URL string `json:"url,omitempty"`
+	// Secret firma con HMAC-SHA256 el cuerpo enviado al webhook
+	Secret string `json:"secret,omitempty"`
+	// Emails son los destinatarios de un destino email
+	Emails []string `json:"emails,omitempty"`
+}
+
+// TenantNotificationSettings son los destinos a los que se avisa el vencimiento de los tokens de un tenant
+type TenantNotificationSettings struct {
+	TenantID     string                    `json:"tenant_id" db:"tenant_id"`
+	Enabled      bool                      `json:"enabled" db:"enabled"`
+	Destinations []NotificationDestination `json:"destinations" db:"destinations"`
+	CreatedAt    time.Time                 `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at" db:"updated_at"`
+}
+
+// NotificationEventType es el motivo de un aviso
+type NotificationEventType string
+
+const (
+	NotificationTokenExpiring NotificationEventType = "token_expiring"
+	NotificationTokenExpired  NotificationEventType = "token_expired"
+)
+
+// NotificationStatus es el resultado de la entrega de un aviso
+type NotificationStatus string
+
+const (
+	NotificationStatusSent   NotificationStatus = "sent"
+	NotificationStatusFailed NotificationStatus = "failed"
+)
+
+// NotificationRecord registra la entrega de un aviso a un destino del tenant
+type NotificationRecord struct {
+	ID              string                      `json:"id" db:"id"`
+	TenantID        string                      `json:"tenant_id" db:"tenant_id"`
+	ChannelID       string                      `json:"channel_id" db:"channel_id"`
+	Platform        Platform                    `json:"platform" db:"platform"`
+	EventType       NotificationEventType       `json:"event_type" db:"event_type"`
+	DestinationType NotificationDestinationType `json:"destination_type" db:"destination_type"`
+	// Destination identifica el destino: la URL, los emails o el tipo de evento
+	Destination string             `json:"destination" db:"destination"`
+	Status      NotificationStatus `json:"status" db:"status"`
+	Error       string             `json:"error,omitempty" db:"error"`
+	// DedupKey evita repetir el aviso del mismo vencimiento al mismo destino
+	DedupKey       string     `json:"-" db:"dedup_key"`
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty" db:"token_expires_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// WebhookReplayState representa el estado de una reproducción de webhooks
+type WebhookReplayState string
+
+const (
+	WebhookReplayIdle      WebhookReplayState = "idle"
+	WebhookReplayRunning   WebhookReplayState = "running"
+	WebhookReplayCompleted WebhookReplayState = "completed"
+	WebhookReplayFailed    WebhookReplayState = "failed"
+)
+
+// WebhookReplayRequest selecciona los mensajes entrantes a reproducir; hace falta al menos un filtro
+type WebhookReplayRequest struct {
+	Platform  Platform   `json:"platform,omitempty"`
+	IDs       []string   `json:"ids,omitempty"`
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+	Processed *bool      `json:"processed,omitempty"`
+	// Limit es la cantidad máxima de mensajes entrantes a reproducir
+	Limit int `json:"limit,omitempty"`
+	// RatePerSecond es el máximo de mensajes reenviados por segundo al servicio de mensajería
+	RatePerSecond float64 `json:"rate_per_second,omitempty"`
+	// DryRun normaliza los mensajes sin reenviarlos
+	DryRun bool `json:"dry_run"`
+}
+
+// WebhookReplayItemStatus es el resultado de reproducir un mensaje entrante
+type WebhookReplayItemStatus string
+
+const (
+	WebhookReplayItemReplayed    WebhookReplayItemStatus = "replayed"
+	WebhookReplayItemWouldReplay WebhookReplayItemStatus = "would_replay"
+	WebhookReplayItemFailed      WebhookReplayItemStatus = "failed"
+	WebhookReplayItemSkipped     WebhookReplayItemStatus = "skipped"
+)
+
+// WebhookReplayItem es el resultado de reproducir un mensaje entrante
+type WebhookReplayItem struct {
+	InboundMessageID string                  `json:"inbound_message_id"`
+	Platform         Platform                `json:"platform"`
+	ReceivedAt       time.Time               `json:"received_at"`
+	Status           WebhookReplayItemStatus `json:"status"`
+	// Messages es la cantidad de mensajes normalizados del webhook y Forwarded los reenviados
+	Messages  int    `json:"messages"`
+	Forwarded int    `json:"forwarded"`
+	Error     string `json:"error,omitempty"`
+}
+
+// WebhookReplayReport resume una reproducción de mensajes entrantes
+type WebhookReplayReport struct {
+	ReplayID string               `json:"replay_id,omitempty"`
+	State    WebhookReplayState   `json:"state"`
+	Request  WebhookReplayRequest `json:"request"`
+	// Selected es la cantidad de mensajes entrantes leídos
+	Selected          int                 `json:"selected"`
+	Replayed          int                 `json:"replayed"`
+	Skipped           int                 `json:"skipped"`
+	Failed            int                 `json:"failed"`
+	MessagesForwarded int                 `json:"messages_forwarded"`
+	Items             []WebhookReplayItem `json:"items"`
+	Error             string              `json:"error,omitempty"`
+	StartedAt         *time.Time          `json:"started_at,omitempty"`
+	CompletedAt       *time.Time          `json:"completed_at,omitempty"`
+}
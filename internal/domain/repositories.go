@@ -16,6 +16,13 @@ type ChannelIntegrationRepository interface {
 	Update(ctx context.Context, integration *ChannelIntegration) error
 	Delete(ctx context.Context, id string) error
 	GetByPlatformAndTenant(ctx context.Context, platform Platform, tenantID string) (*ChannelIntegration, error)
+	GetByPlatformIdentifier(ctx context.Context, platform Platform, identifier string) (*ChannelIntegration, error)
+	GetByTokenExpiry(ctx context.Context, before time.Time) ([]*ChannelIntegration, error)
+	UpdateTokenState(ctx context.Context, integration *ChannelIntegration) error
+	// CountByPlatformAndStatus cuenta las integraciones agrupadas por plataforma y estado
+	CountByPlatformAndStatus(ctx context.Context) ([]*IntegrationCount, error)
+	// UpdateStatus cambia el estado solo si la integración sigue en from; retorna si lo cambió
+	UpdateStatus(ctx context.Context, id string, from, to IntegrationStatus) (bool, error)
 	DB() *sql.DB // Para consultas directas
 }
 
@@ -24,6 +31,96 @@ type InboundMessageRepository interface {
 	Create(ctx context.Context, message *InboundMessage) error
 	GetUnprocessed(ctx context.Context, limit int) ([]*InboundMessage, error)
 	MarkAsProcessed(ctx context.Context, id string) error
+	UpdateResults(ctx context.Context, id string, results []InboundResult, processed bool) error
+	// List retorna los mensajes que cumplen el filtro, los más antiguos primero salvo con Descending
+	List(ctx context.Context, filter InboundMessageFilter) ([]*InboundMessage, error)
+}
+
+// MessageOutboxRepository define las operaciones del outbox de entrega al servicio de mensajería
+type MessageOutboxRepository interface {
+	// EnqueueWithInbound guarda el mensaje entrante y sus entradas de outbox en una misma transacción,
+	// junto con el DedupEvent de cada entrada. Las entradas cuyo evento ya estaba registrado no se
+	// encolan: quedan con Duplicate y su resultado como duplicado. Si todas son duplicadas no se
+	// guarda nada.
+	EnqueueWithInbound(ctx context.Context, inbound *InboundMessage, messages []*OutboxMessage) error
+	// ClaimDue reserva hasta limit mensajes listos para entrega durante el tiempo de lease y
+	// cuenta el reclamo como un intento
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*OutboxMessage, error)
+	MarkDelivered(ctx context.Context, id string) error
+	MarkRetry(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error
+	MarkDeadLetter(ctx context.Context, id string, lastError string) error
+}
+
+// ProcessedWebhookEventRepository define las operaciones del registro de deduplicación de webhooks
+type ProcessedWebhookEventRepository interface {
+	// Claim registra el evento si no existe o si su retención venció; retorna false si es un duplicado
+	Claim(ctx context.Context, event *ProcessedWebhookEvent) (bool, error)
+	// Release elimina el registro para que un reenvío del proveedor pueda procesarse
+	Release(ctx context.Context, event *ProcessedWebhookEvent) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// ConversationSessionRepository define las operaciones de persistencia de las ventanas de atención
+type ConversationSessionRepository interface {
+	// RecordInbound abre o extiende la ventana; un mensaje anterior al último registrado no la acorta
+	RecordInbound(ctx context.Context, session *ConversationSession) error
+	Get(ctx context.Context, channelID, contactID string) (*ConversationSession, error)
+	UpdateCategory(ctx context.Context, channelID, contactID, category string) error
+	// ListOpenByTenant retorna las ventanas vigentes del tenant, las que vencen antes primero
+	ListOpenByTenant(ctx context.Context, tenantID string, platform Platform, limit, offset int) ([]*ConversationSession, error)
+}
+
+// BroadcastRepository define las operaciones de persistencia de envíos masivos
+type BroadcastRepository interface {
+	CreateJob(ctx context.Context, job *BroadcastJob) error
+	GetJob(ctx context.Context, id string) (*BroadcastJob, error)
+	// GetActiveJobs retorna los trabajos en cola o en ejecución, los más antiguos primero
+	GetActiveJobs(ctx context.Context, limit int) ([]*BroadcastJob, error)
+	// TransitionJob cambia el estado solo si el actual es uno de from; retorna false si no aplicó
+	TransitionJob(ctx context.Context, id string, from []BroadcastJobStatus, to BroadcastJobStatus) (bool, error)
+	// FailJob marca el trabajo como fallido con el motivo
+	FailJob(ctx context.Context, id string, reason string) error
+	// FinishLoading registra los totales de la carga y deja el trabajo en cola
+	FinishLoading(ctx context.Context, id string, total, rejected int) error
+	// CompleteJobIfDone marca el trabajo como completado cuando no quedan destinatarios por enviar
+	CompleteJobIfDone(ctx context.Context, id string) (bool, error)
+
+	// AddRecipients inserta un lote de destinatarios ignorando repetidos; retorna cuántos se insertaron
+	AddRecipients(ctx context.Context, recipients []*BroadcastRecipient) (int, error)
+	// ClaimRecipients reserva hasta limit destinatarios pendientes de los canales indicados durante el tiempo de lease
+	ClaimRecipients(ctx context.Context, jobID string, channelIDs []string, limit int, lease time.Duration) ([]*BroadcastRecipient, error)
+	// AcquireChannels toma o renueva para holder los canales libres, vencidos o ya suyos; retorna los que tiene
+	AcquireChannels(ctx context.Context, holder string, channelIDs []string, lease time.Duration) ([]string, error)
+	// ReleaseChannels libera los canales de holder para que otra réplica pueda tomarlos
+	ReleaseChannels(ctx context.Context, holder string, channelIDs []string) error
+	MarkRecipientResult(ctx context.Context, recipient *BroadcastRecipient) error
+	// ReleaseRecipients devuelve a pendiente los destinatarios reclamados que no se enviaron
+	ReleaseRecipients(ctx context.Context, ids []int64) error
+	// CancelPendingRecipients marca como cancelados los destinatarios aún no enviados
+	CancelPendingRecipients(ctx context.Context, jobID string) (int64, error)
+	GetProgress(ctx context.Context, jobID string) (*BroadcastProgress, error)
+	ListRecipients(ctx context.Context, jobID string, status BroadcastRecipientStatus, afterID int64, limit int) ([]*BroadcastRecipient, error)
+}
+
+// NotificationRepository define las operaciones de los avisos de vencimiento de tokens por tenant
+type NotificationRepository interface {
+	GetSettings(ctx context.Context, tenantID string) (*TenantNotificationSettings, error)
+	UpsertSettings(ctx context.Context, settings *TenantNotificationSettings) error
+	// WasSent indica si ya se entregó un aviso con la clave de deduplicación
+	WasSent(ctx context.Context, dedupKey string) (bool, error)
+	CreateRecord(ctx context.Context, record *NotificationRecord) error
+	// ListRecords retorna el historial del tenant, los más recientes primero; channelID vacío no filtra
+	ListRecords(ctx context.Context, tenantID, channelID string, limit, offset int) ([]*NotificationRecord, error)
+}
+
+// ChannelHealthCheckRepository define las operaciones del historial de verificaciones de conectividad
+type ChannelHealthCheckRepository interface {
+	Create(ctx context.Context, check *ChannelHealthCheck) error
+	// ListByChannel retorna las verificaciones del canal, las más recientes primero
+	ListByChannel(ctx context.Context, channelID string, limit int) ([]*ChannelHealthCheck, error)
+	// GetLastSuccess retorna la última verificación exitosa del canal
+	GetLastSuccess(ctx context.Context, channelID string) (*ChannelHealthCheck, error)
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
 
 // OutboundMessageLogRepository define las operaciones para logs de mensajes salientes
@@ -32,6 +129,12 @@ type OutboundMessageLogRepository interface {
 	GetByChannelID(ctx context.Context, channelID string, limit, offset int) ([]*OutboundMessageLog, error)
 	GetByStatus(ctx context.Context, status MessageStatus, limit int) ([]*OutboundMessageLog, error)
 	UpdateStatus(ctx context.Context, id string, status MessageStatus, response []byte) error
+	// UpdateSendResult registra la respuesta de la plataforma al enviar, incluido el ID del mensaje
+	UpdateSendResult(ctx context.Context, id string, status MessageStatus, platformMessageID string, response []byte) error
+	GetByPlatformMessageID(ctx context.Context, platformMessageID string) (*OutboundMessageLog, error)
+	GetUnreadByRecipient(ctx context.Context, channelID, recipient string, before time.Time) ([]*OutboundMessageLog, error)
+	// List retorna los mensajes que cumplen el filtro, los más recientes primero
+	List(ctx context.Context, filter OutboundMessageFilter) ([]*OutboundMessageLog, error)
 }
 
 // UserRepository define las operaciones de persistencia para usuarios
@@ -64,14 +167,51 @@ type GoogleCalendarRepository interface {
 	GetIntegration(ctx context.Context, channelID string) (*GoogleCalendarIntegration, error)
 	GetIntegrationsByTenant(ctx context.Context, tenantID string) ([]*GoogleCalendarIntegration, error)
 	UpdateIntegration(ctx context.Context, integration *GoogleCalendarIntegration) error
+	// UpdateSyncState guarda el token de sincronización y la fecha de la última sincronización exitosa
+	UpdateSyncState(ctx context.Context, channelID, syncToken string, lastSyncAt time.Time) error
 	DeleteIntegration(ctx context.Context, channelID string) error
 
 	// Operaciones de eventos
 	CreateEvent(ctx context.Context, event *CalendarEvent) error
 	GetEvent(ctx context.Context, eventID string) (*CalendarEvent, error)
-	GetEventsByChannel(ctx context.Context, channelID string) ([]*CalendarEvent, error)
+	GetEventByGoogleID(ctx context.Context, channelID, googleID string) (*CalendarEvent, error)
+	GetEventsByChannel(ctx context.Context, channelID string, limit, offset int) ([]*CalendarEvent, error)
 	GetEventsByTenant(ctx context.Context, tenantID string, limit, offset int) ([]*CalendarEvent, error)
-	UpdateEvent(ctx context.Context, event *CalendarEvent) error
+	UpdateEvent(ctx context.Context, eventID string, event *CalendarEvent) error
 	DeleteEvent(ctx context.Context, eventID string) error
 	GetEventsByDateRange(ctx context.Context, channelID string, startTime, endTime time.Time) ([]*CalendarEvent, error)
 }
+
+// CalendarWatchChannelRepository define la persistencia de los canales push de Google Calendar
+type CalendarWatchChannelRepository interface {
+	Create(ctx context.Context, channel *CalendarWatchChannel) error
+	GetByID(ctx context.Context, id string) (*CalendarWatchChannel, error)
+	// ListActiveByChannel retorna los canales push sin detener de una integración
+	ListActiveByChannel(ctx context.Context, channelID string) ([]*CalendarWatchChannel, error)
+	// ListExpiring retorna los canales push sin detener que vencen antes de la fecha indicada
+	ListExpiring(ctx context.Context, before time.Time) ([]*CalendarWatchChannel, error)
+	MarkStopped(ctx context.Context, id string, stoppedAt time.Time) error
+}
+
+// ScheduledNotificationRepository define la persistencia de los recordatorios programados de eventos
+type ScheduledNotificationRepository interface {
+	// Reschedule cancela los recordatorios pendientes del evento y programa los indicados en una misma
+	// transacción; un recordatorio ya enviado o en envío para la misma hora no se vuelve a programar
+	Reschedule(ctx context.Context, eventID string, notifications []*ScheduledNotification) error
+	// CancelByEvent cancela los recordatorios pendientes del evento y retorna cuántos canceló
+	CancelByEvent(ctx context.Context, eventID string) (int64, error)
+	// ClaimDue reserva hasta limit recordatorios vencidos durante el tiempo de lease
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*ScheduledNotification, error)
+	MarkSent(ctx context.Context, id string, sentAt time.Time) error
+	MarkRetry(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error
+	// MarkFinished cierra un recordatorio reservado sin enviarlo: cancelled, skipped o failed
+	MarkFinished(ctx context.Context, id string, status ScheduledNotificationStatus, reason string) error
+}
+
+// OAuthStateRepository define la persistencia de los estados de los flujos OAuth2
+type OAuthStateRepository interface {
+	Create(ctx context.Context, state *OAuthState) error
+	// Consume marca el estado como usado y lo retorna; falla si no existe o ya se usó
+	Consume(ctx context.Context, stateToken string) (*OAuthState, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
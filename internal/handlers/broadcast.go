@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultBroadcastResultsLimit = 100
+	maxBroadcastResultsLimit     = 1000
+)
+
+type BroadcastHandler struct {
+	broadcastService services.BroadcastService
+	logger           logger.Logger
+}
+
+func NewBroadcastHandler(broadcastService services.BroadcastService, logger logger.Logger) *BroadcastHandler {
+	return &BroadcastHandler{
+		broadcastService: broadcastService,
+		logger:           logger,
+	}
+}
+
+// CreateBroadcast godoc
+// @Summary Crear envío masivo
+// @Description Encola un envío masivo; los mensajes se envían en segundo plano respetando el límite de cada canal
+// @Tags broadcasts
+// @Accept json
+// @Produce json
+// @Param request body domain.BroadcastMessageRequest true "Tenant, plataformas, destinatarios y contenido"
+// @Success 202 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Router /integrations/broadcasts [post]
+func (h *BroadcastHandler) CreateBroadcast(c *gin.Context) {
+	var req domain.BroadcastMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	job, err := h.broadcastService.CreateBroadcast(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Broadcast queued",
+		Data:    job,
+	})
+}
+
+// CreateBroadcastFromCSV godoc
+// @Summary Crear envío masivo desde CSV
+// @Description Encola un envío masivo leyendo los destinatarios de un CSV (columnas recipient y opcionalmente platform). Los campos tenant_id, platforms y content deben enviarse antes del archivo.
+// @Tags broadcasts
+// @Accept multipart/form-data
+// @Produce json
+// @Param tenant_id formData string true "ID del tenant"
+// @Param platforms formData string true "Plataformas separadas por coma"
+// @Param content formData string true "Contenido del mensaje en JSON"
+// @Param file formData file true "CSV de destinatarios"
+// @Success 202 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Router /integrations/broadcasts/csv [post]
+func (h *BroadcastHandler) CreateBroadcastFromCSV(c *gin.Context) {
+	// Se lee el multipart en streaming para no guardar el archivo completo en memoria ni en disco
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		h.invalidRequest(c, "Expected multipart/form-data body: "+err.Error())
+		return
+	}
+
+	var tenantID string
+	var platforms []domain.Platform
+	var content *domain.MessageContent
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			h.invalidRequest(c, "Missing file part")
+			return
+		}
+		if err != nil {
+			h.invalidRequest(c, "Invalid multipart body: "+err.Error())
+			return
+		}
+
+		switch part.FormName() {
+		case "tenant_id":
+			value, err := io.ReadAll(io.LimitReader(part, 1024))
+			if err != nil {
+				h.invalidRequest(c, "Invalid tenant_id")
+				return
+			}
+			tenantID = strings.TrimSpace(string(value))
+		case "platforms":
+			value, err := io.ReadAll(io.LimitReader(part, 1024))
+			if err != nil {
+				h.invalidRequest(c, "Invalid platforms")
+				return
+			}
+			platforms = parsePlatforms(string(value))
+		case "content":
+			content = &domain.MessageContent{}
+			if err := json.NewDecoder(io.LimitReader(part, 64*1024)).Decode(content); err != nil {
+				h.invalidRequest(c, "Invalid content: "+err.Error())
+				return
+			}
+		case "file":
+			if content == nil {
+				h.invalidRequest(c, "Fields tenant_id, platforms and content must be sent before the file")
+				return
+			}
+
+			job, err := h.broadcastService.CreateBroadcastFromCSV(c.Request.Context(), tenantID, platforms, *content, part)
+			if err != nil {
+				h.respondError(c, err)
+				return
+			}
+
+			c.JSON(http.StatusAccepted, domain.APIResponse{
+				Code:    "SUCCESS",
+				Message: "Broadcast queued",
+				Data:    job,
+			})
+			return
+		}
+	}
+}
+
+// GetBroadcast godoc
+// @Summary Obtener envío masivo
+// @Tags broadcasts
+// @Produce json
+// @Param id path string true "ID del envío masivo"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Router /integrations/broadcasts/{id} [get]
+func (h *BroadcastHandler) GetBroadcast(c *gin.Context) {
+	job, err := h.broadcastService.GetBroadcast(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Broadcast retrieved successfully",
+		Data:    job,
+	})
+}
+
+// GetBroadcastProgress godoc
+// @Summary Progreso de envío masivo
+// @Description Retorna cuántos destinatarios quedan pendientes, cuántos se enviaron y cuántos fallaron
+// @Tags broadcasts
+// @Produce json
+// @Param id path string true "ID del envío masivo"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Router /integrations/broadcasts/{id}/progress [get]
+func (h *BroadcastHandler) GetBroadcastProgress(c *gin.Context) {
+	progress, err := h.broadcastService.GetProgress(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Broadcast progress retrieved successfully",
+		Data:    progress,
+	})
+}
+
+// GetBroadcastResults godoc
+// @Summary Resultados de envío masivo
+// @Description Lista el resultado por destinatario, paginado con el cursor next_after_id
+// @Tags broadcasts
+// @Produce json
+// @Param id path string true "ID del envío masivo"
+// @Param status query string false "Filtrar por estado (pending, processing, sent, failed, cancelled)"
+// @Param after_id query int false "Cursor de la página anterior"
+// @Param limit query int false "Cantidad de resultados (máximo 1000)"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Router /integrations/broadcasts/{id}/results [get]
+func (h *BroadcastHandler) GetBroadcastResults(c *gin.Context) {
+	afterID, err := strconv.ParseInt(c.DefaultQuery("after_id", "0"), 10, 64)
+	if err != nil || afterID < 0 {
+		h.invalidRequest(c, "Invalid after_id")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultBroadcastResultsLimit)))
+	if err != nil || limit <= 0 {
+		h.invalidRequest(c, "Invalid limit")
+		return
+	}
+	if limit > maxBroadcastResultsLimit {
+		limit = maxBroadcastResultsLimit
+	}
+
+	status := domain.BroadcastRecipientStatus(c.Query("status"))
+	result, err := h.broadcastService.GetResults(c.Request.Context(), c.Param("id"), status, afterID, limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Broadcast results retrieved successfully",
+		Data:    result,
+	})
+}
+
+// PauseBroadcast godoc
+// @Summary Pausar envío masivo
+// @Tags broadcasts
+// @Produce json
+// @Param id path string true "ID del envío masivo"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Router /integrations/broadcasts/{id}/pause [post]
+func (h *BroadcastHandler) PauseBroadcast(c *gin.Context) {
+	job, err := h.broadcastService.PauseBroadcast(c.Request.Context(), c.Param("id"))
+	h.respondTransition(c, job, err, "Broadcast paused")
+}
+
+// ResumeBroadcast godoc
+// @Summary Reanudar envío masivo
+// @Tags broadcasts
+// @Produce json
+// @Param id path string true "ID del envío masivo"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Router /integrations/broadcasts/{id}/resume [post]
+func (h *BroadcastHandler) ResumeBroadcast(c *gin.Context) {
+	job, err := h.broadcastService.ResumeBroadcast(c.Request.Context(), c.Param("id"))
+	h.respondTransition(c, job, err, "Broadcast resumed")
+}
+
+// CancelBroadcast godoc
+// @Summary Cancelar envío masivo
+// @Tags broadcasts
+// @Produce json
+// @Param id path string true "ID del envío masivo"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Router /integrations/broadcasts/{id}/cancel [post]
+func (h *BroadcastHandler) CancelBroadcast(c *gin.Context) {
+	job, err := h.broadcastService.CancelBroadcast(c.Request.Context(), c.Param("id"))
+	h.respondTransition(c, job, err, "Broadcast cancelled")
+}
+
+func (h *BroadcastHandler) respondTransition(c *gin.Context, job *domain.BroadcastJob, err error, message string) {
+	if err != nil {
+		if errors.Is(err, services.ErrBroadcastStateConflict) {
+			c.JSON(http.StatusConflict, domain.APIResponse{
+				Code:    "INVALID_STATE",
+				Message: err.Error(),
+				Data:    job,
+			})
+			return
+		}
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: message,
+		Data:    job,
+	})
+}
+
+// respondError traduce los errores del servicio de envíos masivos a la respuesta HTTP
+func (h *BroadcastHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrBroadcastNotFound):
+		c.JSON(http.StatusNotFound, domain.APIResponse{
+			Code:    "BROADCAST_NOT_FOUND",
+			Message: "Broadcast not found",
+		})
+	case errors.Is(err, services.ErrBroadcastStateConflict):
+		c.JSON(http.StatusConflict, domain.APIResponse{
+			Code:    "INVALID_STATE",
+			Message: err.Error(),
+		})
+	case errors.Is(err, services.ErrInvalidBroadcast), errors.Is(err, services.ErrInvalidMessageContent):
+		h.invalidRequest(c, err.Error())
+	default:
+		h.logger.Error("Broadcast request failed", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "BROADCAST_ERROR",
+			Message: "Broadcast request failed: " + err.Error(),
+		})
+	}
+}
+
+func (h *BroadcastHandler) invalidRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, domain.APIResponse{
+		Code:    "INVALID_REQUEST",
+		Message: message,
+	})
+}
+
+// parsePlatforms convierte una lista separada por comas en plataformas
+func parsePlatforms(value string) []domain.Platform {
+	var platforms []domain.Platform
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			platforms = append(platforms, domain.Platform(item))
+		}
+	}
+	return platforms
+}
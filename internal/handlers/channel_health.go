@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxChannelHealthHistoryLimit = 200
+
+type ChannelHealthHandler struct {
+	channelProber *services.ChannelProber
+	logger        logger.Logger
+}
+
+func NewChannelHealthHandler(channelProber *services.ChannelProber, logger logger.Logger) *ChannelHealthHandler {
+	return &ChannelHealthHandler{
+		channelProber: channelProber,
+		logger:        logger,
+	}
+}
+
+// GetChannelHealth godoc
+// @Summary Conectividad de un canal
+// @Description Retorna el resultado de la última verificación de conectividad del canal contra su plataforma, el último éxito, los fallos seguidos y el historial de verificaciones, las más recientes primero
+// @Tags channels
+// @Produce json
+// @Param id path string true "ID del canal"
+// @Param limit query int false "Cantidad de verificaciones del historial" default(20)
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Router /integrations/channels/{id}/health [get]
+func (h *ChannelHealthHandler) GetChannelHealth(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > maxChannelHealthHistoryLimit {
+		limit = maxChannelHealthHistoryLimit
+	}
+
+	health, err := h.channelProber.GetChannelHealth(c.Request.Context(), c.Param("id"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrChannelNotFound) {
+			c.JSON(http.StatusNotFound, domain.APIResponse{
+				Code:    "CHANNEL_NOT_FOUND",
+				Message: "Channel not found",
+			})
+			return
+		}
+		h.logger.Error("Failed to get channel health", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "CHANNEL_HEALTH_ERROR",
+			Message: "Failed to get channel health",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Channel health retrieved successfully",
+		Data:    health,
+	})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CredentialHandler struct {
+	reencryptionJob *services.CredentialReencryptionJob
+	logger          logger.Logger
+}
+
+// NewCredentialHandler crea el handler de credenciales; sin reencryptionJob el cifrado no está
+// configurado y los endpoints responden 503
+func NewCredentialHandler(reencryptionJob *services.CredentialReencryptionJob, logger logger.Logger) *CredentialHandler {
+	return &CredentialHandler{
+		reencryptionJob: reencryptionJob,
+		logger:          logger,
+	}
+}
+
+// StartReencryption godoc
+// @Summary Re-encriptar credenciales con la clave activa
+// @Description Vuelve a sellar en segundo plano los tokens y secretos de canales e integraciones de Google Calendar guardados con claves anteriores
+// @Tags credentials
+// @Produce json
+// @Param dry_run query bool false "Solo contar las credenciales que se volverían a sellar"
+// @Success 202 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 503 {object} domain.APIResponse
+// @Router /integrations/credentials/reencrypt [post]
+func (h *CredentialHandler) StartReencryption(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	status, err := h.reencryptionJob.Trigger(dryRun)
+	if err != nil {
+		if errors.Is(err, services.ErrReencryptionRunning) {
+			c.JSON(http.StatusConflict, domain.APIResponse{
+				Code:    "REENCRYPTION_RUNNING",
+				Message: err.Error(),
+				Data:    status,
+			})
+			return
+		}
+		h.logger.Error("Failed to start credential re-encryption", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "REENCRYPTION_ERROR",
+			Message: "Failed to start credential re-encryption",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Credential re-encryption started",
+		Data:    status,
+	})
+}
+
+// GetReencryptionStatus godoc
+// @Summary Avance de la re-encriptación de credenciales
+// @Description Retorna el avance por tabla de la última re-encriptación
+// @Tags credentials
+// @Produce json
+// @Success 200 {object} domain.APIResponse
+// @Failure 503 {object} domain.APIResponse
+// @Router /integrations/credentials/reencrypt [get]
+func (h *CredentialHandler) GetReencryptionStatus(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Credential re-encryption status retrieved successfully",
+		Data:    h.reencryptionJob.Status(),
+	})
+}
+
+func (h *CredentialHandler) available(c *gin.Context) bool {
+	if h.reencryptionJob != nil {
+		return true
+	}
+	c.JSON(http.StatusServiceUnavailable, domain.APIResponse{
+		Code:    "ENCRYPTION_NOT_CONFIGURED",
+		Message: "Credential encryption is not configured",
+	})
+	return false
+}
@@ -48,14 +48,6 @@ type SyncEventsRequest struct {
 	ChannelID string `json:"channel_id" binding:"required"`
 }
 
-// WebhookNotification representa una notificación de webhook de Google Calendar
-type WebhookNotification struct {
-	State       string `json:"state"`
-	ResourceID  string `json:"resourceId"`
-	ResourceURI string `json:"resourceUri"`
-	Expiration  string `json:"expiration"`
-}
-
 // ListEvents lista eventos de Google Calendar con filtros y paginación
 // @Summary Listar eventos
 // @Description Lista eventos de Google Calendar con filtros opcionales y paginación
@@ -447,51 +439,6 @@ func (h *GoogleCalendarEventsHandler) SyncEvents(c *gin.Context) {
 	})
 }
 
-// HandleWebhook maneja las notificaciones de webhook de Google Calendar
-// @Summary Manejar webhook
-// @Description Maneja las notificaciones de webhook de Google Calendar
-// @Tags Google Calendar Events
-// @Accept json
-// @Produce json
-// @Param notification body WebhookNotification true "Notificación de webhook"
-// @Success 200 {object} domain.APIResponse
-// @Failure 400 {object} domain.APIResponse
-// @Failure 500 {object} domain.APIResponse
-// @Router /webhooks/google-calendar [post]
-func (h *GoogleCalendarEventsHandler) HandleWebhook(c *gin.Context) {
-	var notification WebhookNotification
-	if err := c.ShouldBindJSON(&notification); err != nil {
-		h.logger.Error("Error al validar notificación de webhook", err, nil)
-		c.JSON(http.StatusBadRequest, domain.APIResponse{
-			Code:    "INVALID_WEBHOOK",
-			Message: "Notificación de webhook inválida",
-			Data:    err.Error(),
-		})
-		return
-	}
-
-	h.logger.Info("Webhook recibido de Google Calendar", map[string]interface{}{
-		"state":        notification.State,
-		"resource_id":  notification.ResourceID,
-		"resource_uri": notification.ResourceURI,
-		"expiration":   notification.Expiration,
-	})
-
-	// TODO: Implementar procesamiento de webhook
-	// - Extraer channel_id del resource_uri
-	// - Sincronizar eventos del canal específico
-	// - Enviar notificaciones si es necesario
-
-	c.JSON(http.StatusOK, domain.APIResponse{
-		Code:    "WEBHOOK_PROCESSED",
-		Message: "Webhook procesado exitosamente",
-		Data: map[string]interface{}{
-			"state":       notification.State,
-			"resource_id": notification.ResourceID,
-		},
-	})
-}
-
 // GetEventsByDateRange obtiene eventos en un rango de fechas específico
 // @Summary Obtener eventos por rango de fechas
 // @Description Obtiene eventos en un rango de fechas específico
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 
 	"it-integration-service/internal/config"
 	"it-integration-service/internal/domain"
@@ -31,6 +33,8 @@ func NewGoogleCalendarSetupHandler(setupService *services.GoogleCalendarSetupSer
 type InitiateAuthRequest struct {
 	TenantID     string              `json:"tenant_id" binding:"required"`
 	CalendarType domain.CalendarType `json:"calendar_type" binding:"required"`
+	// ReturnURL es la URL a la que vuelve el navegador al terminar; su host debe estar permitido
+	ReturnURL string `json:"return_url"`
 }
 
 // SetupWebhookRequest representa la solicitud de configuración de webhook
@@ -82,7 +86,15 @@ func (h *GoogleCalendarSetupHandler) InitiateAuth(c *gin.Context) {
 	}
 
 	// Iniciar autenticación
-	response, err := h.setupService.InitiateAuth(c.Request.Context(), req.TenantID, req.CalendarType)
+	response, err := h.setupService.InitiateAuth(c.Request.Context(), req.TenantID, req.CalendarType, req.ReturnURL)
+	if errors.Is(err, services.ErrInvalidReturnURL) {
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_RETURN_URL",
+			Message: "URL de retorno inválida",
+			Data:    err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		h.logger.Error("Error al iniciar autenticación OAuth2", err, map[string]interface{}{
 			"tenant_id":     req.TenantID,
@@ -105,21 +117,24 @@ func (h *GoogleCalendarSetupHandler) InitiateAuth(c *gin.Context) {
 
 // HandleCallback maneja el callback de OAuth2
 // @Summary Callback de autenticación OAuth2
-// @Description Maneja el callback de Google OAuth2 y completa la autenticación
+// @Description Valida y consume el state, completa la autenticación de la integración que inició el flujo y, si se indicó return_url al iniciarlo, redirige el navegador con status, channel_id y error en la query
 // @Tags Google Calendar Setup
 // @Accept json
 // @Produce json
-// @Param code query string true "Código de autorización"
+// @Param code query string false "Código de autorización"
 // @Param state query string true "Token de estado"
+// @Param error query string false "Error informado por Google cuando no se autoriza el acceso"
 // @Success 200 {object} domain.APIResponse
+// @Success 302 "Redirección a la URL de retorno"
 // @Failure 400 {object} domain.APIResponse
 // @Failure 500 {object} domain.APIResponse
 // @Router /integrations/google-calendar/callback [get]
 func (h *GoogleCalendarSetupHandler) HandleCallback(c *gin.Context) {
 	code := c.Query("code")
 	state := c.Query("state")
+	authError := c.Query("error")
 
-	if code == "" || state == "" {
+	if state == "" || (code == "" && authError == "") {
 		c.JSON(http.StatusBadRequest, domain.APIResponse{
 			Code:    "MISSING_PARAMETERS",
 			Message: "Faltan parámetros requeridos: code y state",
@@ -129,12 +144,35 @@ func (h *GoogleCalendarSetupHandler) HandleCallback(c *gin.Context) {
 	}
 
 	// Procesar callback
-	err := h.setupService.HandleCallback(c.Request.Context(), code, state)
+	result, err := h.setupService.HandleCallback(c.Request.Context(), code, state, authError)
 	if err != nil {
-		h.logger.Error("Error al procesar callback OAuth2", err, map[string]interface{}{
-			"state": state,
-		})
-		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+		h.logger.Error("Error al procesar callback OAuth2", err, nil)
+
+		status, errorCode := http.StatusInternalServerError, "authorization_failed"
+		switch {
+		case errors.Is(err, services.ErrInvalidOAuthState):
+			c.JSON(http.StatusBadRequest, domain.APIResponse{
+				Code:    "INVALID_STATE",
+				Message: "Token de estado inválido o ya utilizado",
+				Data:    nil,
+			})
+			return
+		case errors.Is(err, services.ErrOAuthStateExpired):
+			status, errorCode = http.StatusBadRequest, "state_expired"
+		case authError != "":
+			status, errorCode = http.StatusBadRequest, authError
+		}
+
+		if result != nil && result.ReturnURL != "" {
+			c.Redirect(http.StatusFound, callbackRedirectURL(result.ReturnURL, url.Values{
+				"status":     {"error"},
+				"channel_id": {result.ChannelID},
+				"error":      {errorCode},
+			}))
+			return
+		}
+
+		c.JSON(status, domain.APIResponse{
 			Code:    "CALLBACK_ERROR",
 			Message: "Error al procesar callback de autenticación",
 			Data:    err.Error(),
@@ -142,16 +180,40 @@ func (h *GoogleCalendarSetupHandler) HandleCallback(c *gin.Context) {
 		return
 	}
 
+	if result.ReturnURL != "" {
+		c.Redirect(http.StatusFound, callbackRedirectURL(result.ReturnURL, url.Values{
+			"status":     {"success"},
+			"channel_id": {result.ChannelID},
+		}))
+		return
+	}
+
 	c.JSON(http.StatusOK, domain.APIResponse{
 		Code:    "AUTH_SUCCESS",
 		Message: "Autenticación completada exitosamente",
 		Data: map[string]interface{}{
-			"channel_id": state,
-			"status":     "authenticated",
+			"tenant_id":     result.TenantID,
+			"channel_id":    result.ChannelID,
+			"calendar_name": result.CalendarName,
+			"status":        "authenticated",
 		},
 	})
 }
 
+// callbackRedirectURL agrega el resultado del flujo a la query de la URL de retorno
+func callbackRedirectURL(returnURL string, params url.Values) string {
+	parsed, err := url.Parse(returnURL)
+	if err != nil {
+		return returnURL
+	}
+	query := parsed.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
 // GetIntegrationStatus obtiene el estado de una integración
 // @Summary Obtener estado de integración
 // @Description Obtiene el estado actual de una integración de Google Calendar
@@ -197,7 +259,7 @@ func (h *GoogleCalendarSetupHandler) GetIntegrationStatus(c *gin.Context) {
 
 // SetupWebhook configura webhooks para sincronización automática
 // @Summary Configurar webhook
-// @Description Configura webhooks para recibir notificaciones de cambios en Google Calendar
+// @Description Abre un canal push sobre el calendario de la integración y detiene los anteriores. El canal se renueva solo antes de vencer.
 // @Tags Google Calendar Setup
 // @Accept json
 // @Produce json
@@ -219,7 +281,7 @@ func (h *GoogleCalendarSetupHandler) SetupWebhook(c *gin.Context) {
 	}
 
 	// Configurar webhook
-	err := h.setupService.SetupWebhook(c.Request.Context(), req.ChannelID)
+	watchChannel, err := h.setupService.SetupWebhook(c.Request.Context(), req.ChannelID)
 	if err != nil {
 		h.logger.Error("Error al configurar webhook", err, map[string]interface{}{
 			"channel_id":  req.ChannelID,
@@ -237,9 +299,11 @@ func (h *GoogleCalendarSetupHandler) SetupWebhook(c *gin.Context) {
 		Code:    "WEBHOOK_SETUP_SUCCESS",
 		Message: "Webhook configurado exitosamente",
 		Data: map[string]interface{}{
-			"channel_id":  req.ChannelID,
-			"calendar_id": req.CalendarID,
-			"webhook_url": h.config.WebhookURL,
+			"channel_id":       req.ChannelID,
+			"calendar_id":      watchChannel.CalendarID,
+			"watch_channel_id": watchChannel.ID,
+			"expiration":       watchChannel.Expiration,
+			"webhook_url":      h.config.WebhookURL,
 		},
 	})
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -36,15 +37,6 @@ func NewGoogleCalendarWebhookHandler(
 	}
 }
 
-// WebhookPayload representa el payload de webhook de Google Calendar
-type WebhookPayload struct {
-	State       string `json:"state"`
-	ResourceID  string `json:"resourceId"`
-	ResourceURI string `json:"resourceUri"`
-	Expiration  string `json:"expiration"`
-	Token       string `json:"token"`
-}
-
 // WebhookSyncRequest representa una solicitud de sincronización desde webhook
 type WebhookSyncRequest struct {
 	ChannelID   string `json:"channel_id"`
@@ -54,58 +46,85 @@ type WebhookSyncRequest struct {
 	Action      string `json:"action"` // created, updated, deleted
 }
 
-// HandleWebhook maneja las notificaciones de webhook de Google Calendar
+// HandleWebhook maneja las notificaciones push de Google Calendar
 // @Summary Manejar webhook de Google Calendar
-// @Description Procesa notificaciones de webhook de Google Calendar y envía notificaciones automáticas
+// @Description Recibe las notificaciones push de Google Calendar. El canal se identifica por los headers X-Goog-Channel-ID y X-Goog-Resource-ID, se valida con X-Goog-Channel-Token y dispara una sincronización incremental de su integración.
 // @Tags Google Calendar Webhooks
-// @Accept json
 // @Produce json
-// @Param payload body WebhookPayload true "Payload del webhook"
+// @Param X-Goog-Channel-ID header string true "ID del canal push"
+// @Param X-Goog-Resource-ID header string true "ID del recurso observado"
+// @Param X-Goog-Resource-State header string true "sync, exists o not_exists"
+// @Param X-Goog-Channel-Token header string true "Token del canal push"
 // @Success 200 {object} domain.APIResponse
-// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
 // @Failure 500 {object} domain.APIResponse
 // @Router /webhooks/google-calendar [post]
 func (h *GoogleCalendarWebhookHandler) HandleWebhook(c *gin.Context) {
-	var payload WebhookPayload
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		h.logger.Error("Error al validar payload de webhook", err, nil)
-		c.JSON(http.StatusBadRequest, domain.APIResponse{
-			Code:    "INVALID_WEBHOOK_PAYLOAD",
-			Message: "Payload de webhook inválido",
-			Data:    err.Error(),
-		})
-		return
+	notification := &domain.WebhookNotification{
+		WatchChannelID: c.GetHeader("X-Goog-Channel-ID"),
+		ResourceID:     c.GetHeader("X-Goog-Resource-ID"),
+		ResourceURI:    c.GetHeader("X-Goog-Resource-URI"),
+		State:          c.GetHeader("X-Goog-Resource-State"),
+		MessageNumber:  c.GetHeader("X-Goog-Message-Number"),
+		Token:          c.GetHeader("X-Goog-Channel-Token"),
 	}
 
 	h.logger.Info("Webhook recibido de Google Calendar", map[string]interface{}{
-		"state":        payload.State,
-		"resource_id":  payload.ResourceID,
-		"resource_uri": payload.ResourceURI,
-		"expiration":   payload.Expiration,
+		"watch_channel_id": notification.WatchChannelID,
+		"resource_id":      notification.ResourceID,
+		"state":            notification.State,
+		"message_number":   notification.MessageNumber,
 	})
 
-	// Validar token de webhook si es necesario
-	if !h.validateWebhookToken(payload.Token) {
-		h.logger.Warn("Token de webhook inválido", map[string]interface{}{
-			"token": payload.Token,
-		})
-		c.JSON(http.StatusUnauthorized, domain.APIResponse{
-			Code:    "INVALID_WEBHOOK_TOKEN",
-			Message: "Token de webhook inválido",
-			Data:    nil,
-		})
+	watchChannel, err := h.eventService.HandlePushNotification(c.Request.Context(), notification)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownWatchChannel):
+			// Google deja de reintentar ante un 404; el canal vence solo
+			h.logger.Warn("Notificación de un canal push desconocido", map[string]interface{}{
+				"watch_channel_id": notification.WatchChannelID,
+			})
+			c.JSON(http.StatusNotFound, domain.APIResponse{
+				Code:    "UNKNOWN_WATCH_CHANNEL",
+				Message: "Canal push desconocido",
+				Data:    nil,
+			})
+		case errors.Is(err, services.ErrInvalidWatchToken):
+			h.logger.Warn("Token de webhook inválido", map[string]interface{}{
+				"watch_channel_id": notification.WatchChannelID,
+			})
+			c.JSON(http.StatusUnauthorized, domain.APIResponse{
+				Code:    "INVALID_WEBHOOK_TOKEN",
+				Message: "Token de webhook inválido",
+				Data:    nil,
+			})
+		default:
+			h.logger.Error("Error procesando webhook", err, map[string]interface{}{
+				"watch_channel_id": notification.WatchChannelID,
+			})
+			c.JSON(http.StatusInternalServerError, domain.APIResponse{
+				Code:    "WEBHOOK_PROCESSING_ERROR",
+				Message: "Error procesando webhook",
+				Data:    err.Error(),
+			})
+		}
 		return
 	}
 
-	// Procesar webhook en background
-	go h.processWebhookAsync(c.Request.Context(), &payload)
+	if err := h.notificationService.ProcessWebhookNotification(c.Request.Context(), notification); err != nil {
+		h.logger.Error("Error procesando notificación de webhook", err, map[string]interface{}{
+			"channel_id": watchChannel.ChannelID,
+		})
+	}
 
 	c.JSON(http.StatusOK, domain.APIResponse{
 		Code:    "WEBHOOK_PROCESSED",
 		Message: "Webhook procesado exitosamente",
 		Data: map[string]interface{}{
-			"state":       payload.State,
-			"resource_id": payload.ResourceID,
+			"channel_id":   watchChannel.ChannelID,
+			"state":        notification.State,
+			"resource_id":  notification.ResourceID,
 			"processed_at": time.Now(),
 		},
 	})
@@ -291,96 +310,6 @@ func (h *GoogleCalendarWebhookHandler) HandleNotificationRequest(c *gin.Context)
 
 // Helper methods
 
-// validateWebhookToken valida el token del webhook
-func (h *GoogleCalendarWebhookHandler) validateWebhookToken(token string) bool {
-	// TODO: Implementar validación real del token
-	// Por ahora, aceptar cualquier token no vacío
-	return token != ""
-}
-
-// processWebhookAsync procesa el webhook de forma asíncrona
-func (h *GoogleCalendarWebhookHandler) processWebhookAsync(ctx context.Context, payload *WebhookPayload) {
-	h.logger.Info("Procesando webhook de forma asíncrona", map[string]interface{}{
-		"resource_id": payload.ResourceID,
-		"state":       payload.State,
-	})
-
-	// Extraer información del resource_uri
-	channelID, calendarID, err := h.extractInfoFromResourceURI(payload.ResourceURI)
-	if err != nil {
-		h.logger.Error("Error extrayendo información del resource URI", err, map[string]interface{}{
-			"resource_uri": payload.ResourceURI,
-		})
-		return
-	}
-
-	// Procesar según el estado
-	switch payload.State {
-	case "sync":
-		err = h.handleSyncState(ctx, channelID, calendarID, payload.ResourceID)
-	case "exists":
-		err = h.handleExistsState(ctx, channelID, calendarID, payload.ResourceID)
-	default:
-		h.logger.Warn("Estado de webhook no reconocido", map[string]interface{}{
-			"state": payload.State,
-		})
-		return
-	}
-
-	if err != nil {
-		h.logger.Error("Error procesando webhook", err, map[string]interface{}{
-			"resource_id": payload.ResourceID,
-			"state":       payload.State,
-		})
-	}
-}
-
-// extractInfoFromResourceURI extrae información del resource URI
-func (h *GoogleCalendarWebhookHandler) extractInfoFromResourceURI(resourceURI string) (string, string, error) {
-	// TODO: Implementar parsing del resource URI de Google Calendar
-	// Por ahora, retornar valores por defecto
-	return "default-channel", "primary", nil
-}
-
-// handleSyncState maneja el estado "sync" del webhook
-func (h *GoogleCalendarWebhookHandler) handleSyncState(ctx context.Context, channelID, calendarID, resourceID string) error {
-	h.logger.Info("Procesando estado sync", map[string]interface{}{
-		"channel_id":  channelID,
-		"calendar_id": calendarID,
-		"resource_id": resourceID,
-	})
-
-	// Sincronizar eventos del canal
-	_, err := h.eventService.SyncEvents(ctx, channelID)
-	if err != nil {
-		return err
-	}
-
-	// Procesar notificación de webhook
-	notification := &domain.WebhookNotification{
-		State:       "sync",
-		ResourceID:  resourceID,
-		ResourceURI: "",
-		Expiration:  "",
-	}
-
-	return h.notificationService.ProcessWebhookNotification(ctx, notification)
-}
-
-// handleExistsState maneja el estado "exists" del webhook
-func (h *GoogleCalendarWebhookHandler) handleExistsState(ctx context.Context, channelID, calendarID, resourceID string) error {
-	h.logger.Info("Procesando estado exists", map[string]interface{}{
-		"channel_id":  channelID,
-		"calendar_id": calendarID,
-		"resource_id": resourceID,
-	})
-
-	// Verificar que el recurso existe
-	// TODO: Implementar verificación específica
-
-	return nil
-}
-
 // handleEventCreated maneja eventos creados
 func (h *GoogleCalendarWebhookHandler) handleEventCreated(ctx context.Context, req *WebhookSyncRequest) error {
 	h.logger.Info("Manejando evento creado", map[string]interface{}{
@@ -465,6 +394,14 @@ func (h *GoogleCalendarWebhookHandler) handleEventUpdated(ctx context.Context, r
 		})
 	}
 
+	// Reprogramar recordatorios con el horario actualizado
+	err = h.notificationService.ScheduleEventReminders(ctx, event)
+	if err != nil {
+		h.logger.Error("Error reprogramando recordatorios", err, map[string]interface{}{
+			"event_id": req.EventID,
+		})
+	}
+
 	return nil
 }
 
@@ -474,6 +411,13 @@ func (h *GoogleCalendarWebhookHandler) handleEventDeleted(ctx context.Context, r
 		"event_id": req.EventID,
 	})
 
+	// Cancelar recordatorios pendientes
+	if err := h.notificationService.CancelReminders(ctx, req.EventID); err != nil {
+		h.logger.Error("Error cancelando recordatorios", err, map[string]interface{}{
+			"event_id": req.EventID,
+		})
+	}
+
 	// TODO: Obtener información del evento antes de eliminarlo para las notificaciones
 	// Por ahora, enviar notificación genérica
 
@@ -6,9 +6,9 @@ import (
 	"it-integration-service/internal/config"
 	"it-integration-service/internal/domain"
 	"it-integration-service/internal/middleware"
-	"it-integration-service/internal/repository"
 	"it-integration-service/internal/services"
 	"it-integration-service/pkg/logger"
+	"it-integration-service/pkg/secrets"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -16,11 +16,11 @@ import (
 )
 
 type Handler struct {
-	healthService services.HealthService
+	healthService *services.HealthService
 	logger        logger.Logger
 }
 
-func SetupRoutes(router *gin.Engine, healthService services.HealthService, integrationService services.IntegrationService, logger logger.Logger, cfg *config.Config, db *repository.PostgresDB) {
+func SetupRoutes(router *gin.Engine, healthService *services.HealthService, integrationService services.IntegrationService, queryService services.QueryService, messageSendService services.MessageSendService, broadcastService services.BroadcastService, templateService *services.WhatsAppTemplateService, sessionService services.ConversationSessionService, notificationService *services.TokenNotificationService, channelProber *services.ChannelProber, replayService *services.WebhookReplayService, reencryptionJob *services.CredentialReencryptionJob, secretProvider secrets.Provider, logger logger.Logger, cfg *config.Config, channelRepo domain.ChannelIntegrationRepository) {
 	h := &Handler{
 		healthService: healthService,
 		logger:        logger,
@@ -28,6 +28,14 @@ func SetupRoutes(router *gin.Engine, healthService services.HealthService, integ
 
 	// Integration handler
 	integrationHandler := NewIntegrationHandler(integrationService, logger)
+	messageHandler := NewMessageHandler(messageSendService, logger)
+	messageQueryHandler := NewMessageQueryHandler(queryService, logger)
+	broadcastHandler := NewBroadcastHandler(broadcastService, logger)
+	sessionHandler := NewSessionHandler(sessionService, logger)
+	credentialHandler := NewCredentialHandler(reencryptionJob, logger)
+	notificationHandler := NewNotificationHandler(notificationService, logger)
+	channelHealthHandler := NewChannelHealthHandler(channelProber, logger)
+	replayHandler := NewWebhookReplayHandler(replayService, logger)
 
 	// Setup handlers para configuración específica de plataformas
 	telegramSetupService := services.NewTelegramSetupService(logger)
@@ -35,6 +43,7 @@ func SetupRoutes(router *gin.Engine, healthService services.HealthService, integ
 
 	whatsappSetupService := services.NewWhatsAppSetupService(logger)
 	whatsappSetupHandler := NewWhatsAppSetupHandler(whatsappSetupService, integrationService, logger)
+	whatsappTemplateHandler := NewWhatsAppTemplateHandler(templateService, logger)
 
 	messengerSetupService := services.NewMessengerSetupService(logger)
 	messengerSetupHandler := NewMessengerSetupHandler(messengerSetupService, integrationService, logger)
@@ -46,7 +55,6 @@ func SetupRoutes(router *gin.Engine, healthService services.HealthService, integ
 	webchatSetupHandler := NewWebchatSetupHandler(webchatSetupService, integrationService, logger)
 
 	// Tawk.to service (usando el repositorio directamente)
-	channelRepo := repository.NewChannelIntegrationRepository(db)
 	tawkToSetupService := services.NewTawkToService(&cfg.TawkTo, channelRepo, logger)
 	tawkToSetupHandler := NewTawkToHandler(tawkToSetupService, logger)
 
@@ -55,7 +63,7 @@ func SetupRoutes(router *gin.Engine, healthService services.HealthService, integ
 	mailchimpSetupHandler := NewMailchimpSetupHandler(mailchimpSetupService, integrationService, logger)
 
 	// Webhook validation middleware
-	webhookValidation := middleware.NewWebhookValidationMiddleware(cfg, logger)
+	webhookValidation := middleware.NewWebhookValidationMiddleware(cfg, secretProvider, integrationService, logger)
 
 	// Swagger documentation (protegido en producción)
 	router.GET("/swagger/*any", middleware.SwaggerAuth(), ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -79,9 +87,42 @@ func SetupRoutes(router *gin.Engine, healthService services.HealthService, integ
 			integrations.POST("/channels", integrationHandler.CreateChannel)
 			integrations.PATCH("/channels/:id", integrationHandler.UpdateChannel)
 			integrations.DELETE("/channels/:id", integrationHandler.DeleteChannel)
+			integrations.GET("/channels/:id/health", channelHealthHandler.GetChannelHealth)
 
-			// Message validation (solo para validar integraciones)
-			integrations.GET("/messages/inbound", integrationHandler.GetInboundMessages)
+			// Consulta de mensajes entrantes y salientes
+			integrations.GET("/messages/inbound", messageQueryHandler.GetInboundMessages)
+			integrations.GET("/messages/outbound", messageQueryHandler.GetOutboundMessages)
+			integrations.POST("/messages/inbound/replay", replayHandler.StartReplay)
+			integrations.GET("/messages/inbound/replay", replayHandler.GetReplayStatus)
+
+			// Envío de mensajes salientes
+			integrations.POST("/messages/send", messageHandler.SendMessage)
+
+			// Ventanas de atención por contacto
+			integrations.GET("/sessions", sessionHandler.ListOpenSessions)
+			integrations.GET("/sessions/:channel_id/:contact_id", sessionHandler.GetSession)
+
+			// Re-encriptación de credenciales con la clave activa
+			integrations.POST("/credentials/reencrypt", credentialHandler.StartReencryption)
+			integrations.GET("/credentials/reencrypt", credentialHandler.GetReencryptionStatus)
+
+			// Avisos de vencimiento de tokens por tenant
+			integrations.GET("/notifications/settings/:tenant_id", notificationHandler.GetNotificationSettings)
+			integrations.PUT("/notifications/settings/:tenant_id", notificationHandler.UpdateNotificationSettings)
+			integrations.GET("/notifications/history", notificationHandler.ListNotificationHistory)
+
+			// Envíos masivos
+			broadcasts := integrations.Group("/broadcasts")
+			{
+				broadcasts.POST("", broadcastHandler.CreateBroadcast)
+				broadcasts.POST("/csv", broadcastHandler.CreateBroadcastFromCSV)
+				broadcasts.GET("/:id", broadcastHandler.GetBroadcast)
+				broadcasts.GET("/:id/progress", broadcastHandler.GetBroadcastProgress)
+				broadcasts.GET("/:id/results", broadcastHandler.GetBroadcastResults)
+				broadcasts.POST("/:id/pause", broadcastHandler.PauseBroadcast)
+				broadcasts.POST("/:id/resume", broadcastHandler.ResumeBroadcast)
+				broadcasts.POST("/:id/cancel", broadcastHandler.CancelBroadcast)
+			}
 
 			// Platform-specific setup routes
 			telegram := integrations.Group("/telegram")
@@ -101,6 +142,11 @@ func SetupRoutes(router *gin.Engine, healthService services.HealthService, integ
 				whatsapp.POST("/setup", whatsappSetupHandler.SetupWhatsAppIntegration)
 				whatsapp.POST("/test-message", whatsappSetupHandler.TestMessage)
 				whatsapp.GET("/webhook-verify", whatsappSetupHandler.ValidateWebhook)
+
+				// Plantillas de mensaje de la WABA del canal
+				whatsapp.GET("/channels/:channel_id/templates", whatsappTemplateHandler.ListTemplates)
+				whatsapp.POST("/channels/:channel_id/templates", whatsappTemplateHandler.CreateTemplate)
+				whatsapp.DELETE("/channels/:channel_id/templates/:name", whatsappTemplateHandler.DeleteTemplate)
 			}
 
 			messenger := integrations.Group("/messenger")
@@ -154,20 +200,27 @@ func SetupRoutes(router *gin.Engine, healthService services.HealthService, integ
 			// Webhooks
 			webhooks := integrations.Group("/webhooks")
 			{
-				// WhatsApp webhooks con validación
+				// WhatsApp webhooks con validación; la ruta con channel_id usa los secretos del canal
 				webhooks.GET("/whatsapp", webhookValidation.ValidateWebhookVerification("whatsapp"), integrationHandler.WhatsAppWebhook)
 				webhooks.POST("/whatsapp", webhookValidation.ValidateWebhookSignature("whatsapp"), integrationHandler.WhatsAppWebhook)
+				webhooks.GET("/whatsapp/:channel_id", webhookValidation.ValidateWebhookVerification("whatsapp"), integrationHandler.WhatsAppWebhook)
+				webhooks.POST("/whatsapp/:channel_id", webhookValidation.ValidateWebhookSignature("whatsapp"), integrationHandler.WhatsAppWebhook)
 
 				// Messenger webhooks con validación
 				webhooks.GET("/messenger", webhookValidation.ValidateWebhookVerification("messenger"), integrationHandler.MessengerWebhook)
 				webhooks.POST("/messenger", webhookValidation.ValidateWebhookSignature("messenger"), integrationHandler.MessengerWebhook)
+				webhooks.GET("/messenger/:channel_id", webhookValidation.ValidateWebhookVerification("messenger"), integrationHandler.MessengerWebhook)
+				webhooks.POST("/messenger/:channel_id", webhookValidation.ValidateWebhookSignature("messenger"), integrationHandler.MessengerWebhook)
 
 				// Instagram webhooks con validación
 				webhooks.GET("/instagram", webhookValidation.ValidateWebhookVerification("instagram"), integrationHandler.InstagramWebhook)
 				webhooks.POST("/instagram", webhookValidation.ValidateWebhookSignature("instagram"), integrationHandler.InstagramWebhook)
+				webhooks.GET("/instagram/:channel_id", webhookValidation.ValidateWebhookVerification("instagram"), integrationHandler.InstagramWebhook)
+				webhooks.POST("/instagram/:channel_id", webhookValidation.ValidateWebhookSignature("instagram"), integrationHandler.InstagramWebhook)
 
 				// Telegram webhooks con validación
 				webhooks.POST("/telegram", webhookValidation.ValidateTelegramWebhook(), integrationHandler.TelegramWebhook)
+				webhooks.POST("/telegram/:webhook_key", webhookValidation.ValidateTelegramWebhook(), integrationHandler.TelegramWebhook)
 
 				// Webchat webhooks (sin validación específica por ahora)
 				webhooks.POST("/webchat", integrationHandler.WebchatWebhook)
@@ -184,18 +237,18 @@ func SetupRoutes(router *gin.Engine, healthService services.HealthService, integ
 
 // HealthCheck godoc
 // @Summary Health check endpoint
-// @Description Verifica el estado del servicio
+// @Description Verifica el estado del servicio y de sus dependencias: base de datos, servicio de mensajería, Vault y APIs de las plataformas con integraciones activas
 // @Tags health
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Router /health [get]
 func (h *Handler) HealthCheck(c *gin.Context) {
-	status := h.healthService.CheckHealth()
+	status := h.healthService.CheckHealth(c.Request.Context())
 
 	response := domain.APIResponse{
 		Code:    "SUCCESS",
-		Message: "Service is healthy",
+		Message: "Service is " + status.Status,
 		Data:    status,
 	}
 
@@ -204,19 +257,20 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 
 // ReadinessCheck godoc
 // @Summary Readiness check endpoint
-// @Description Verifica si el servicio está listo para recibir tráfico
+// @Description Verifica si el servicio está listo para recibir tráfico. Una dependencia opcional caída responde 200 con estado degraded; una crítica responde 503.
 // @Tags health
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
 // @Router /ready [get]
 func (h *Handler) ReadinessCheck(c *gin.Context) {
-	status := h.healthService.CheckReadiness()
+	status := h.healthService.CheckReadiness(c.Request.Context())
 
-	if status.Status == "ready" {
+	if status.Status == "ready" || status.Status == services.HealthStatusDegraded {
 		response := domain.APIResponse{
 			Code:    "SUCCESS",
-			Message: "Service is ready",
+			Message: "Service is " + status.Status,
 			Data:    status,
 		}
 		c.JSON(http.StatusOK, response)
@@ -1,8 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"it-integration-service/internal/domain"
 	"it-integration-service/internal/services"
@@ -212,51 +213,6 @@ func (h *IntegrationHandler) DeleteChannel(c *gin.Context) {
 	})
 }
 
-// Message History (solo para validación)
-
-// GetInboundMessages godoc
-// @Summary Obtener mensajes entrantes
-// @Description Obtiene mensajes entrantes para validación de integración
-// @Tags integrations
-// @Accept json
-// @Produce json
-// @Param platform query string true "Plataforma"
-// @Param limit query int false "Límite de resultados" default(10)
-// @Success 200 {object} domain.APIResponse
-// @Router /integrations/messages/inbound [get]
-func (h *IntegrationHandler) GetInboundMessages(c *gin.Context) {
-	platform := c.Query("platform")
-	if platform == "" {
-		c.JSON(http.StatusBadRequest, domain.APIResponse{
-			Code:    "INVALID_REQUEST",
-			Message: "Platform is required",
-		})
-		return
-	}
-
-	limitStr := c.DefaultQuery("limit", "10")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		limit = 10
-	}
-
-	messages, err := h.integrationService.GetInboundMessages(c.Request.Context(), platform, limit, 0)
-	if err != nil {
-		h.logger.Error("Failed to get inbound messages", err)
-		c.JSON(http.StatusInternalServerError, domain.APIResponse{
-			Code:    "FETCH_ERROR",
-			Message: "Failed to get inbound messages: " + err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, domain.APIResponse{
-		Code:    "SUCCESS",
-		Message: "Messages retrieved successfully",
-		Data:    messages,
-	})
-}
-
 // Webhook Handlers
 
 // WhatsAppWebhook godoc
@@ -266,7 +222,9 @@ func (h *IntegrationHandler) GetInboundMessages(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} domain.APIResponse
+// @Param channel_id path string false "ID del canal, para validar con sus propios secretos"
 // @Router /integrations/webhooks/whatsapp [post]
+// @Router /integrations/webhooks/whatsapp/{channel_id} [post]
 func (h *IntegrationHandler) WhatsAppWebhook(c *gin.Context) {
 	// La validación de firma y verificación se maneja en el middleware
 	// Solo procesar el webhook si es un POST request
@@ -286,12 +244,9 @@ func (h *IntegrationHandler) WhatsAppWebhook(c *gin.Context) {
 	}
 
 	// La firma ya fue validada por el middleware
-	if err := h.integrationService.ProcessWhatsAppWebhook(c.Request.Context(), payload, ""); err != nil {
+	if err := h.processChannelWebhook(c, domain.PlatformWhatsApp, payload, h.integrationService.ProcessWhatsAppWebhook); err != nil {
 		h.logger.Error("Failed to process WhatsApp webhook", err)
-		c.JSON(http.StatusInternalServerError, domain.APIResponse{
-			Code:    "PROCESSING_ERROR",
-			Message: "Failed to process webhook",
-		})
+		h.respondWebhookError(c, err)
 		return
 	}
 
@@ -308,7 +263,9 @@ func (h *IntegrationHandler) WhatsAppWebhook(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} domain.APIResponse
+// @Param channel_id path string false "ID del canal, para validar con sus propios secretos"
 // @Router /integrations/webhooks/messenger [post]
+// @Router /integrations/webhooks/messenger/{channel_id} [post]
 func (h *IntegrationHandler) MessengerWebhook(c *gin.Context) {
 	// La validación de firma y verificación se maneja en el middleware
 	// Solo procesar el webhook si es un POST request
@@ -327,12 +284,9 @@ func (h *IntegrationHandler) MessengerWebhook(c *gin.Context) {
 	}
 
 	// La firma ya fue validada por el middleware
-	if err := h.integrationService.ProcessMessengerWebhook(c.Request.Context(), payload, ""); err != nil {
+	if err := h.processChannelWebhook(c, domain.PlatformMessenger, payload, h.integrationService.ProcessMessengerWebhook); err != nil {
 		h.logger.Error("Failed to process Messenger webhook", err)
-		c.JSON(http.StatusInternalServerError, domain.APIResponse{
-			Code:    "PROCESSING_ERROR",
-			Message: "Failed to process webhook",
-		})
+		h.respondWebhookError(c, err)
 		return
 	}
 
@@ -349,7 +303,9 @@ func (h *IntegrationHandler) MessengerWebhook(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} domain.APIResponse
+// @Param channel_id path string false "ID del canal, para validar con sus propios secretos"
 // @Router /integrations/webhooks/instagram [post]
+// @Router /integrations/webhooks/instagram/{channel_id} [post]
 func (h *IntegrationHandler) InstagramWebhook(c *gin.Context) {
 	// La validación de firma y verificación se maneja en el middleware
 	// Solo procesar el webhook si es un POST request
@@ -368,12 +324,9 @@ func (h *IntegrationHandler) InstagramWebhook(c *gin.Context) {
 	}
 
 	// La firma ya fue validada por el middleware
-	if err := h.integrationService.ProcessInstagramWebhook(c.Request.Context(), payload, ""); err != nil {
+	if err := h.processChannelWebhook(c, domain.PlatformInstagram, payload, h.integrationService.ProcessInstagramWebhook); err != nil {
 		h.logger.Error("Failed to process Instagram webhook", err)
-		c.JSON(http.StatusInternalServerError, domain.APIResponse{
-			Code:    "PROCESSING_ERROR",
-			Message: "Failed to process webhook",
-		})
+		h.respondWebhookError(c, err)
 		return
 	}
 
@@ -385,12 +338,15 @@ func (h *IntegrationHandler) InstagramWebhook(c *gin.Context) {
 
 // TelegramWebhook godoc
 // @Summary Webhook de Telegram
-// @Description Procesa webhooks de Telegram
+// @Description Procesa webhooks de Telegram. La ruta sin webhook_key es legacy: solo resuelve el canal
+// @Description si hay un único bot activo y se mantiene para los bots registrados antes de las claves por canal.
 // @Tags webhooks
 // @Accept json
 // @Produce json
 // @Success 200 {object} domain.APIResponse
+// @Param webhook_key path string false "Clave del canal generada al configurar el bot"
 // @Router /integrations/webhooks/telegram [post]
+// @Router /integrations/webhooks/telegram/{webhook_key} [post]
 func (h *IntegrationHandler) TelegramWebhook(c *gin.Context) {
 	payload, err := c.GetRawData()
 	if err != nil {
@@ -402,12 +358,9 @@ func (h *IntegrationHandler) TelegramWebhook(c *gin.Context) {
 		return
 	}
 
-	if err := h.integrationService.ProcessTelegramWebhook(c.Request.Context(), payload); err != nil {
+	if err := h.integrationService.ProcessTelegramWebhook(c.Request.Context(), payload, c.Param("webhook_key")); err != nil {
 		h.logger.Error("Failed to process Telegram webhook", err)
-		c.JSON(http.StatusInternalServerError, domain.APIResponse{
-			Code:    "PROCESSING_ERROR",
-			Message: "Failed to process webhook",
-		})
+		h.respondWebhookError(c, err)
 		return
 	}
 
@@ -438,10 +391,7 @@ func (h *IntegrationHandler) WebchatWebhook(c *gin.Context) {
 
 	if err := h.integrationService.ProcessWebchatWebhook(c.Request.Context(), payload); err != nil {
 		h.logger.Error("Failed to process Webchat webhook", err)
-		c.JSON(http.StatusInternalServerError, domain.APIResponse{
-			Code:    "PROCESSING_ERROR",
-			Message: "Failed to process webhook",
-		})
+		h.respondWebhookError(c, err)
 		return
 	}
 
@@ -474,10 +424,7 @@ func (h *IntegrationHandler) MailchimpWebhook(c *gin.Context) {
 
 	if err := h.integrationService.ProcessMailchimpWebhook(c.Request.Context(), payload, signature); err != nil {
 		h.logger.Error("Failed to process Mailchimp webhook", err)
-		c.JSON(http.StatusInternalServerError, domain.APIResponse{
-			Code:    "PROCESSING_ERROR",
-			Message: "Failed to process webhook",
-		})
+		h.respondWebhookError(c, err)
 		return
 	}
 
@@ -486,3 +433,38 @@ func (h *IntegrationHandler) MailchimpWebhook(c *gin.Context) {
 		Message: "Webhook processed successfully",
 	})
 }
+
+// processChannelWebhook procesa el webhook por el canal de la ruta si la trae, o con process
+// resolviendo el canal desde el payload
+func (h *IntegrationHandler) processChannelWebhook(c *gin.Context, platform domain.Platform, payload []byte, process func(ctx context.Context, payload []byte, signature string) error) error {
+	if channelID := c.Param("channel_id"); channelID != "" {
+		return h.integrationService.ProcessChannelWebhook(c.Request.Context(), platform, channelID, payload)
+	}
+	return process(c.Request.Context(), payload, "")
+}
+
+// respondWebhookError traduce los errores de procesamiento de webhooks a la respuesta HTTP
+func (h *IntegrationHandler) respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrChannelNotFound):
+		c.JSON(http.StatusNotFound, domain.APIResponse{
+			Code:    "CHANNEL_NOT_FOUND",
+			Message: "No channel registered for this webhook",
+		})
+	case errors.Is(err, services.ErrChannelMismatch):
+		c.JSON(http.StatusForbidden, domain.APIResponse{
+			Code:    "CHANNEL_MISMATCH",
+			Message: "Webhook payload does not belong to this channel",
+		})
+	case errors.Is(err, services.ErrChannelDisabled):
+		c.JSON(http.StatusForbidden, domain.APIResponse{
+			Code:    "CHANNEL_DISABLED",
+			Message: "Channel is not active",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "PROCESSING_ERROR",
+			Message: "Failed to process webhook",
+		})
+	}
+}
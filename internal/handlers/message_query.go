@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MessageQueryHandler struct {
+	queryService services.QueryService
+	logger       logger.Logger
+}
+
+func NewMessageQueryHandler(queryService services.QueryService, logger logger.Logger) *MessageQueryHandler {
+	return &MessageQueryHandler{
+		queryService: queryService,
+		logger:       logger,
+	}
+}
+
+// GetInboundMessages godoc
+// @Summary Consultar mensajes entrantes
+// @Description Lista los webhooks recibidos que cumplen los filtros, los más recientes primero, paginados con el cursor next_cursor. El filtro por remitente solo encuentra webhooks guardados desde que se registra el remitente de cada mensaje.
+// @Tags integrations
+// @Produce json
+// @Param tenant_id query string false "Filtrar por tenant"
+// @Param channel_id query string false "Filtrar por canal"
+// @Param platform query string false "Filtrar por plataforma"
+// @Param processed query bool false "Solo procesados (true) o sin procesar (false)"
+// @Param sender query string false "Filtrar por remitente de alguno de los mensajes del webhook"
+// @Param from query string false "Recibidos desde (RFC3339)"
+// @Param to query string false "Recibidos hasta, sin incluir (RFC3339)"
+// @Param cursor query string false "Cursor de la página anterior"
+// @Param limit query int false "Cantidad de resultados (máximo 500)" default(50)
+// @Param payload query string false "Contenido del webhook: full, redacted o none" default(full)
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Router /integrations/messages/inbound [get]
+func (h *MessageQueryHandler) GetInboundMessages(c *gin.Context) {
+	query := domain.InboundMessageQuery{
+		TenantID:  c.Query("tenant_id"),
+		ChannelID: c.Query("channel_id"),
+		Platform:  domain.Platform(c.Query("platform")),
+		Sender:    c.Query("sender"),
+		Cursor:    c.Query("cursor"),
+		Payload:   domain.PayloadMode(c.Query("payload")),
+	}
+
+	var err error
+	if query.From, query.To, query.Limit, err = parseMessageQueryParams(c); err != nil {
+		h.invalidRequest(c, err.Error())
+		return
+	}
+	if processed := c.Query("processed"); processed != "" {
+		value, err := strconv.ParseBool(processed)
+		if err != nil {
+			h.invalidRequest(c, "Invalid processed")
+			return
+		}
+		query.Processed = &value
+	}
+
+	page, err := h.queryService.GetInboundMessages(c.Request.Context(), query)
+	if err != nil {
+		h.respondError(c, err, "Failed to get inbound messages")
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Messages retrieved successfully",
+		Data:    page,
+	})
+}
+
+// GetOutboundMessages godoc
+// @Summary Consultar mensajes salientes
+// @Description Lista el log de mensajes enviados que cumplen los filtros, los más recientes primero, paginado con el cursor next_cursor. Tenant y plataforma se filtran por el canal del envío.
+// @Tags integrations
+// @Produce json
+// @Param tenant_id query string false "Filtrar por tenant"
+// @Param channel_id query string false "Filtrar por canal"
+// @Param platform query string false "Filtrar por plataforma"
+// @Param status query string false "Filtrar por estado (queued, sent, delivered, read, failed)"
+// @Param recipient query string false "Filtrar por destinatario"
+// @Param from query string false "Enviados desde (RFC3339)"
+// @Param to query string false "Enviados hasta, sin incluir (RFC3339)"
+// @Param cursor query string false "Cursor de la página anterior"
+// @Param limit query int false "Cantidad de resultados (máximo 500)" default(50)
+// @Param payload query string false "Contenido y respuesta de la plataforma: full, redacted o none" default(full)
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Router /integrations/messages/outbound [get]
+func (h *MessageQueryHandler) GetOutboundMessages(c *gin.Context) {
+	query := domain.OutboundMessageQuery{
+		TenantID:  c.Query("tenant_id"),
+		ChannelID: c.Query("channel_id"),
+		Platform:  domain.Platform(c.Query("platform")),
+		Status:    domain.MessageStatus(c.Query("status")),
+		Recipient: c.Query("recipient"),
+		Cursor:    c.Query("cursor"),
+		Payload:   domain.PayloadMode(c.Query("payload")),
+	}
+
+	var err error
+	if query.From, query.To, query.Limit, err = parseMessageQueryParams(c); err != nil {
+		h.invalidRequest(c, err.Error())
+		return
+	}
+
+	page, err := h.queryService.GetOutboundMessages(c.Request.Context(), query)
+	if err != nil {
+		h.respondError(c, err, "Failed to get outbound messages")
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Messages retrieved successfully",
+		Data:    page,
+	})
+}
+
+// parseMessageQueryParams lee el rango de fechas y el límite comunes a ambas consultas
+func parseMessageQueryParams(c *gin.Context) (*time.Time, *time.Time, int, error) {
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, nil, 0, errors.New("Invalid from, expected RFC3339")
+		}
+		from = &parsed
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, nil, 0, errors.New("Invalid to, expected RFC3339")
+		}
+		to = &parsed
+	}
+
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return nil, nil, 0, errors.New("Invalid limit")
+		}
+		limit = parsed
+	}
+
+	return from, to, limit, nil
+}
+
+func (h *MessageQueryHandler) invalidRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, domain.APIResponse{
+		Code:    "INVALID_REQUEST",
+		Message: message,
+	})
+}
+
+func (h *MessageQueryHandler) respondError(c *gin.Context, err error, message string) {
+	if errors.Is(err, services.ErrInvalidMessageQuery) {
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_MESSAGE_QUERY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logger.Error(message, err)
+	c.JSON(http.StatusInternalServerError, domain.APIResponse{
+		Code:    "FETCH_ERROR",
+		Message: message,
+	})
+}
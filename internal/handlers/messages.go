@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MessageHandler struct {
+	sendService services.MessageSendService
+	logger      logger.Logger
+}
+
+func NewMessageHandler(sendService services.MessageSendService, logger logger.Logger) *MessageHandler {
+	return &MessageHandler{
+		sendService: sendService,
+		logger:      logger,
+	}
+}
+
+// SendMessage godoc
+// @Summary Enviar mensaje
+// @Description Envía un mensaje de texto o multimedia por el canal indicado y lo registra en el log de salida
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body domain.SendMessageRequest true "Canal, destinatario y contenido"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 502 {object} domain.APIResponse
+// @Router /integrations/messages/send [post]
+func (h *MessageHandler) SendMessage(c *gin.Context) {
+	var req domain.SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	log, err := h.sendService.SendMessage(c.Request.Context(), &req)
+	if err != nil {
+		h.respondSendError(c, log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Message sent successfully",
+		Data:    log,
+	})
+}
+
+// respondSendError traduce los errores de envío a la respuesta HTTP
+func (h *MessageHandler) respondSendError(c *gin.Context, log *domain.OutboundMessageLog, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMessageContent), errors.Is(err, services.ErrUnsupportedPlatform),
+		errors.Is(err, services.ErrTemplateNotFound), errors.Is(err, services.ErrMissingBusinessAccount):
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_CONTENT",
+			Message: err.Error(),
+			Data:    log,
+		})
+	case errors.Is(err, services.ErrOutsideSessionWindow):
+		c.JSON(http.StatusUnprocessableEntity, domain.APIResponse{
+			Code:    "OUTSIDE_SESSION_WINDOW",
+			Message: err.Error(),
+		})
+	case errors.Is(err, services.ErrChannelNotFound):
+		c.JSON(http.StatusNotFound, domain.APIResponse{
+			Code:    "CHANNEL_NOT_FOUND",
+			Message: "Channel not found",
+		})
+	case errors.Is(err, services.ErrChannelDisabled):
+		c.JSON(http.StatusForbidden, domain.APIResponse{
+			Code:    "CHANNEL_DISABLED",
+			Message: "Channel is not active",
+		})
+	case errors.Is(err, services.ErrMessageSendFailed), errors.Is(err, services.ErrTemplateRequestFailed):
+		c.JSON(http.StatusBadGateway, domain.APIResponse{
+			Code:    "SEND_FAILED",
+			Message: "Platform rejected the message: " + err.Error(),
+			Data:    log,
+		})
+	default:
+		h.logger.Error("Failed to send message", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "SEND_ERROR",
+			Message: "Failed to send message: " + err.Error(),
+			Data:    log,
+		})
+	}
+}
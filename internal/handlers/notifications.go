@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxNotificationHistoryLimit = 500
+
+type NotificationHandler struct {
+	notificationService *services.TokenNotificationService
+	logger              logger.Logger
+}
+
+func NewNotificationHandler(notificationService *services.TokenNotificationService, logger logger.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// NotificationSettingsRequest es el cuerpo para reemplazar los destinos de aviso de un tenant
+type NotificationSettingsRequest struct {
+	Enabled      *bool                            `json:"enabled"`
+	Destinations []domain.NotificationDestination `json:"destinations"`
+}
+
+// GetNotificationSettings godoc
+// @Summary Obtener destinos de aviso
+// @Description Retorna los destinos a los que se avisa el vencimiento de los tokens del tenant, sin los secretos de los webhooks
+// @Tags notifications
+// @Produce json
+// @Param tenant_id path string true "ID del tenant"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Router /integrations/notifications/settings/{tenant_id} [get]
+func (h *NotificationHandler) GetNotificationSettings(c *gin.Context) {
+	settings, err := h.notificationService.GetSettings(c.Request.Context(), c.Param("tenant_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrNotificationSettingsNotFound) {
+			c.JSON(http.StatusNotFound, domain.APIResponse{
+				Code:    "NOTIFICATION_SETTINGS_NOT_FOUND",
+				Message: "Tenant has no notification settings",
+			})
+			return
+		}
+		h.logger.Error("Failed to get notification settings", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "NOTIFICATIONS_ERROR",
+			Message: "Failed to get notification settings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Notification settings retrieved successfully",
+		Data:    settings,
+	})
+}
+
+// UpdateNotificationSettings godoc
+// @Summary Configurar destinos de aviso
+// @Description Reemplaza los destinos de aviso de vencimiento de tokens del tenant: webhook HTTPS firmado con HMAC-SHA256, email o evento. Un webhook sin secreto conserva el guardado para la misma URL.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param tenant_id path string true "ID del tenant"
+// @Param request body NotificationSettingsRequest true "Destinos de aviso"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Router /integrations/notifications/settings/{tenant_id} [put]
+func (h *NotificationHandler) UpdateNotificationSettings(c *gin.Context) {
+	var req NotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	settings := &domain.TenantNotificationSettings{
+		TenantID:     c.Param("tenant_id"),
+		Enabled:      req.Enabled == nil || *req.Enabled,
+		Destinations: req.Destinations,
+	}
+
+	updated, err := h.notificationService.UpdateSettings(c.Request.Context(), settings)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidNotificationSettings) {
+			c.JSON(http.StatusBadRequest, domain.APIResponse{
+				Code:    "INVALID_NOTIFICATION_SETTINGS",
+				Message: err.Error(),
+			})
+			return
+		}
+		h.logger.Error("Failed to update notification settings", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "NOTIFICATIONS_ERROR",
+			Message: "Failed to update notification settings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Notification settings updated successfully",
+		Data:    updated,
+	})
+}
+
+// ListNotificationHistory godoc
+// @Summary Historial de avisos
+// @Description Lista los avisos de vencimiento de tokens entregados o fallidos del tenant, los más recientes primero
+// @Tags notifications
+// @Produce json
+// @Param tenant_id query string true "ID del tenant"
+// @Param channel_id query string false "Filtrar por canal"
+// @Param limit query int false "Límite de resultados" default(100)
+// @Param offset query int false "Desplazamiento" default(0)
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Router /integrations/notifications/history [get]
+func (h *NotificationHandler) ListNotificationHistory(c *gin.Context) {
+	tenantID := c.Query("tenant_id")
+	if tenantID == "" {
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "tenant_id is required",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > maxNotificationHistoryLimit {
+		limit = maxNotificationHistoryLimit
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	records, err := h.notificationService.ListHistory(c.Request.Context(), tenantID, c.Query("channel_id"), limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list notification history", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "NOTIFICATIONS_ERROR",
+			Message: "Failed to list notification history",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Notification history retrieved successfully",
+		Data:    records,
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxSessionsLimit = 500
+
+type SessionHandler struct {
+	sessionService services.ConversationSessionService
+	logger         logger.Logger
+}
+
+func NewSessionHandler(sessionService services.ConversationSessionService, logger logger.Logger) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		logger:         logger,
+	}
+}
+
+// ListOpenSessions godoc
+// @Summary Listar ventanas de atención abiertas
+// @Description Lista los contactos del tenant con la ventana de 24 horas vigente, los que vencen antes primero
+// @Tags sessions
+// @Produce json
+// @Param tenant_id query string true "ID del tenant"
+// @Param platform query string false "Filtrar por plataforma"
+// @Param limit query int false "Límite de resultados" default(100)
+// @Param offset query int false "Desplazamiento" default(0)
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Router /integrations/sessions [get]
+func (h *SessionHandler) ListOpenSessions(c *gin.Context) {
+	tenantID := c.Query("tenant_id")
+	if tenantID == "" {
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "tenant_id is required",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > maxSessionsLimit {
+		limit = maxSessionsLimit
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	platform := domain.Platform(c.Query("platform"))
+	sessions, err := h.sessionService.ListOpenSessions(c.Request.Context(), tenantID, platform, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list conversation sessions", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "SESSIONS_ERROR",
+			Message: "Failed to list conversation sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Open sessions retrieved successfully",
+		Data:    sessions,
+	})
+}
+
+// GetSession godoc
+// @Summary Obtener ventana de atención
+// @Description Retorna la ventana de atención de un contacto en un canal, abierta o vencida
+// @Tags sessions
+// @Produce json
+// @Param channel_id path string true "ID del canal"
+// @Param contact_id path string true "ID del contacto en la plataforma"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Router /integrations/sessions/{channel_id}/{contact_id} [get]
+func (h *SessionHandler) GetSession(c *gin.Context) {
+	session, err := h.sessionService.GetSession(c.Request.Context(), c.Param("channel_id"), c.Param("contact_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, domain.APIResponse{
+				Code:    "SESSION_NOT_FOUND",
+				Message: "Contact has not messaged this channel",
+			})
+			return
+		}
+		h.logger.Error("Failed to get conversation session", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "SESSIONS_ERROR",
+			Message: "Failed to get conversation session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Session retrieved successfully",
+		Data:    session,
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookReplayHandler struct {
+	replayService *services.WebhookReplayService
+	logger        logger.Logger
+}
+
+func NewWebhookReplayHandler(replayService *services.WebhookReplayService, logger logger.Logger) *WebhookReplayHandler {
+	return &WebhookReplayHandler{
+		replayService: replayService,
+		logger:        logger,
+	}
+}
+
+// StartReplay godoc
+// @Summary Reproducir webhooks recibidos
+// @Description Vuelve a normalizar en segundo plano los mensajes entrantes guardados que cumplen los filtros (plataforma, IDs, rango de recepción, procesados) y los reenvía al servicio de mensajería con la marca de la reproducción. Con dry_run solo informa qué se reenviaría.
+// @Tags integrations
+// @Accept json
+// @Produce json
+// @Param request body domain.WebhookReplayRequest true "Filtros de la reproducción"
+// @Success 202 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Router /integrations/messages/inbound/replay [post]
+func (h *WebhookReplayHandler) StartReplay(c *gin.Context) {
+	var req domain.WebhookReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	report, err := h.replayService.Trigger(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidReplayRequest):
+			c.JSON(http.StatusBadRequest, domain.APIResponse{
+				Code:    "INVALID_REPLAY_REQUEST",
+				Message: err.Error(),
+			})
+		case errors.Is(err, services.ErrReplayRunning):
+			c.JSON(http.StatusConflict, domain.APIResponse{
+				Code:    "REPLAY_RUNNING",
+				Message: err.Error(),
+				Data:    report,
+			})
+		default:
+			h.logger.Error("Failed to start webhook replay", err)
+			c.JSON(http.StatusInternalServerError, domain.APIResponse{
+				Code:    "REPLAY_ERROR",
+				Message: "Failed to start webhook replay",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Webhook replay started",
+		Data:    report,
+	})
+}
+
+// GetReplayStatus godoc
+// @Summary Reporte de la reproducción de webhooks
+// @Description Retorna el avance y el resultado por mensaje entrante de la última reproducción
+// @Tags integrations
+// @Produce json
+// @Success 200 {object} domain.APIResponse
+// @Router /integrations/messages/inbound/replay [get]
+func (h *WebhookReplayHandler) GetReplayStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Webhook replay status retrieved successfully",
+		Data:    h.replayService.Status(),
+	})
+}
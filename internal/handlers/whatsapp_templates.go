@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/services"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WhatsAppTemplateHandler struct {
+	templateService *services.WhatsAppTemplateService
+	logger          logger.Logger
+}
+
+func NewWhatsAppTemplateHandler(templateService *services.WhatsAppTemplateService, logger logger.Logger) *WhatsAppTemplateHandler {
+	return &WhatsAppTemplateHandler{
+		templateService: templateService,
+		logger:          logger,
+	}
+}
+
+// ListTemplates godoc
+// @Summary Listar plantillas de WhatsApp
+// @Description Lista las plantillas de mensaje de la cuenta de WhatsApp Business del canal
+// @Tags whatsapp
+// @Produce json
+// @Param channel_id path string true "ID del canal"
+// @Param refresh query bool false "Ignorar la cache y consultar a Meta"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Router /integrations/whatsapp/channels/{channel_id}/templates [get]
+func (h *WhatsAppTemplateHandler) ListTemplates(c *gin.Context) {
+	refresh := c.Query("refresh") == "true"
+
+	templates, err := h.templateService.ListTemplates(c.Request.Context(), c.Param("channel_id"), refresh)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Templates retrieved successfully",
+		Data:    templates,
+	})
+}
+
+// CreateTemplate godoc
+// @Summary Crear plantilla de WhatsApp
+// @Description Envía una plantilla de mensaje a revisión de Meta
+// @Tags whatsapp
+// @Accept json
+// @Produce json
+// @Param channel_id path string true "ID del canal"
+// @Param request body services.WhatsAppTemplateDefinition true "Nombre, idioma, categoría y componentes"
+// @Success 201 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 502 {object} domain.APIResponse
+// @Router /integrations/whatsapp/channels/{channel_id}/templates [post]
+func (h *WhatsAppTemplateHandler) CreateTemplate(c *gin.Context) {
+	var req services.WhatsAppTemplateDefinition
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	template, err := h.templateService.CreateTemplate(c.Request.Context(), c.Param("channel_id"), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Template submitted for review",
+		Data:    template,
+	})
+}
+
+// DeleteTemplate godoc
+// @Summary Eliminar plantilla de WhatsApp
+// @Description Elimina la plantilla en todos sus idiomas
+// @Tags whatsapp
+// @Produce json
+// @Param channel_id path string true "ID del canal"
+// @Param name path string true "Nombre de la plantilla"
+// @Success 200 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 502 {object} domain.APIResponse
+// @Router /integrations/whatsapp/channels/{channel_id}/templates/{name} [delete]
+func (h *WhatsAppTemplateHandler) DeleteTemplate(c *gin.Context) {
+	if err := h.templateService.DeleteTemplate(c.Request.Context(), c.Param("channel_id"), c.Param("name")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.APIResponse{
+		Code:    "SUCCESS",
+		Message: "Template deleted successfully",
+	})
+}
+
+// respondError traduce los errores de plantillas a la respuesta HTTP
+func (h *WhatsAppTemplateHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrChannelNotFound):
+		c.JSON(http.StatusNotFound, domain.APIResponse{
+			Code:    "CHANNEL_NOT_FOUND",
+			Message: "Channel not found",
+		})
+	case errors.Is(err, services.ErrUnsupportedPlatform), errors.Is(err, services.ErrMissingBusinessAccount):
+		c.JSON(http.StatusBadRequest, domain.APIResponse{
+			Code:    "INVALID_CHANNEL",
+			Message: err.Error(),
+		})
+	case errors.Is(err, services.ErrTemplateRequestFailed):
+		c.JSON(http.StatusBadGateway, domain.APIResponse{
+			Code:    "TEMPLATE_REQUEST_FAILED",
+			Message: err.Error(),
+		})
+	default:
+		h.logger.Error("WhatsApp template request failed", err)
+		c.JSON(http.StatusInternalServerError, domain.APIResponse{
+			Code:    "TEMPLATE_ERROR",
+			Message: "Template request failed: " + err.Error(),
+		})
+	}
+}
@@ -0,0 +1,22 @@
+// Package metrics contiene las métricas de Prometheus que registran los servicios, separadas
+// del middleware HTTP para que la capa de servicios no dependa de él.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var webhookDuplicatesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "webhook_duplicates_total",
+		Help: "Total number of redelivered webhook events discarded as duplicates",
+	},
+	[]string{"platform", "event_type"},
+)
+
+func init() {
+	prometheus.MustRegister(webhookDuplicatesTotal)
+}
+
+// WebhookDuplicate registra un evento de webhook descartado por duplicado
+func WebhookDuplicate(platform, eventType string) {
+	webhookDuplicatesTotal.WithLabelValues(platform, eventType).Inc()
+}
@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -11,20 +14,173 @@ import (
 	"it-integration-service/internal/config"
 	"it-integration-service/internal/domain"
 	"it-integration-service/pkg/logger"
+	"it-integration-service/pkg/secrets"
 
 	"github.com/gin-gonic/gin"
 )
 
+// WebhookChannelResolver identifica los canales destino de un webhook, por el ID de la ruta o por
+// el identificador de cada entrada del payload
+type WebhookChannelResolver interface {
+	ResolveWebhookChannels(ctx context.Context, platform domain.Platform, channelID string, payload []byte) ([]*domain.ChannelIntegration, error)
+}
+
 type WebhookValidationMiddleware struct {
-	config *config.Config
-	logger logger.Logger
+	config   *config.Config
+	secrets  secrets.Provider
+	channels WebhookChannelResolver
+	logger   logger.Logger
 }
 
-func NewWebhookValidationMiddleware(cfg *config.Config, logger logger.Logger) *WebhookValidationMiddleware {
+// NewWebhookValidationMiddleware crea el middleware. Cada canal puede traer su propio app_secret y
+// verify_token en el config; si no los tiene, o sin channels, se usan los de la plataforma, que se
+// resuelven con secretProvider en cada request o, sin él, desde la configuración.
+func NewWebhookValidationMiddleware(cfg *config.Config, secretProvider secrets.Provider, channels WebhookChannelResolver, logger logger.Logger) *WebhookValidationMiddleware {
 	return &WebhookValidationMiddleware{
-		config: cfg,
-		logger: logger,
+		config:   cfg,
+		secrets:  secretProvider,
+		channels: channels,
+		logger:   logger,
+	}
+}
+
+// webhookChannels resuelve los canales del webhook: el de la ruta o el de cada entrada del payload.
+// Si ninguno está registrado se valida con los secretos de la plataforma; el procesamiento aplica
+// la política de canales no resueltos. Retorna false si ya respondió el request.
+func (m *WebhookValidationMiddleware) webhookChannels(c *gin.Context, platform string, payload []byte) ([]*domain.ChannelIntegration, bool) {
+	channelID := c.Param("channel_id")
+	if m.channels == nil {
+		if channelID == "" {
+			return nil, true
+		}
+		m.abortChannelNotFound(c)
+		return nil, false
+	}
+
+	// Un canal deshabilitado se retorna con error y se valida igual con sus propios secretos
+	channels, err := m.channels.ResolveWebhookChannels(c.Request.Context(), domain.Platform(platform), channelID, payload)
+	if len(channels) > 0 || (channelID == "" && err == nil) {
+		return channels, true
+	}
+
+	m.logger.Warn("Webhook channel not resolved", map[string]interface{}{
+		"platform":   platform,
+		"channel_id": channelID,
+		"error":      fmt.Sprint(err),
+	})
+	if channelID == "" {
+		// Sin saber a qué canales pertenecen las entradas no se puede elegir el secreto de la firma
+		c.JSON(http.StatusServiceUnavailable, domain.APIResponse{
+			Code:    "CHANNEL_LOOKUP_FAILED",
+			Message: "Webhook channel could not be resolved",
+		})
+		c.Abort()
+		return nil, false
+	}
+	m.abortChannelNotFound(c)
+	return nil, false
+}
+
+// signingSecret retorna el app secret con que debe venir firmado el webhook. El de cada canal tiene
+// prioridad sobre el de la plataforma, y todas las entradas deben ser de canales con el mismo
+// secreto: una sola firma no puede autenticar entradas de apps distintas. Retorna false si ya
+// respondió el request.
+func (m *WebhookValidationMiddleware) signingSecret(c *gin.Context, platform string, channels []*domain.ChannelIntegration) (string, bool) {
+	var platformSecret *string
+	resolvePlatform := func() (string, bool) {
+		if platformSecret == nil {
+			secret, err := m.webhookSecret(c.Request.Context(), platform)
+			if err != nil {
+				m.abortSecretError(c, platform, err)
+				return "", false
+			}
+			platformSecret = &secret
+		}
+		return *platformSecret, true
+	}
+
+	if len(channels) == 0 {
+		return resolvePlatform()
+	}
+
+	secret := ""
+	for i, channel := range channels {
+		candidate := channelSecret(channel, "app_secret")
+		if candidate == "" {
+			var ok bool
+			if candidate, ok = resolvePlatform(); !ok {
+				return "", false
+			}
+		}
+		if i > 0 && candidate != secret {
+			m.logger.Error("Webhook entries belong to channels with different app secrets", map[string]interface{}{
+				"platform":    platform,
+				"channel_ids": channelIDs(channels),
+			})
+			c.JSON(http.StatusUnauthorized, domain.APIResponse{
+				Code:    "UNAUTHORIZED",
+				Message: "Webhook entries belong to channels with different app secrets",
+			})
+			c.Abort()
+			return "", false
+		}
+		secret = candidate
+	}
+	return secret, true
+}
+
+func channelIDs(channels []*domain.ChannelIntegration) []string {
+	ids := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		ids = append(ids, channel.ID)
+	}
+	return ids
+}
+
+// abortChannelNotFound responde cuando la ruta del webhook apunta a un canal inexistente
+func (m *WebhookValidationMiddleware) abortChannelNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, domain.APIResponse{
+		Code:    "CHANNEL_NOT_FOUND",
+		Message: "No channel registered for this webhook",
+	})
+	c.Abort()
+}
+
+// channelSecret retorna un secreto del config del canal, ya desencriptado por el repositorio
+func channelSecret(channel *domain.ChannelIntegration, key string) string {
+	if channel == nil || len(channel.Config) == 0 {
+		return ""
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(channel.Config, &config); err != nil {
+		return ""
 	}
+	value, _ := config[key].(string)
+	return value
+}
+
+// webhookSecret resuelve el secreto de firma vigente de la plataforma
+func (m *WebhookValidationMiddleware) webhookSecret(ctx context.Context, platform string) (string, error) {
+	return secrets.Resolve(ctx, m.secrets, platform+"_webhook_secret", m.config.Integration.WebhookSecrets[platform])
+}
+
+// verifyToken resuelve el token de verificación vigente de la plataforma
+func (m *WebhookValidationMiddleware) verifyToken(ctx context.Context, platform string) (string, error) {
+	return secrets.Resolve(ctx, m.secrets, platform+"_verify_token", m.config.Integration.WebhookVerifyTokens[platform])
+}
+
+// abortSecretError responde cuando el proveedor de secretos no está disponible
+func (m *WebhookValidationMiddleware) abortSecretError(c *gin.Context, platform string, err error) {
+	m.logger.Error("Failed to resolve webhook secret", map[string]interface{}{
+		"platform": platform,
+		"error":    err.Error(),
+	})
+	c.JSON(http.StatusServiceUnavailable, domain.APIResponse{
+		Code:    "SECRETS_UNAVAILABLE",
+		Message: "Webhook secret could not be resolved",
+	})
+	c.Abort()
 }
 
 // ValidateWebhookSignature valida la firma HMAC de los webhooks de Meta (WhatsApp, Messenger, Instagram)
@@ -36,20 +192,6 @@ func (m *WebhookValidationMiddleware) ValidateWebhookSignature(platform string)
 			return
 		}
 
-		// Obtener el secret para la plataforma
-		secret, exists := m.config.Integration.WebhookSecrets[platform]
-		if !exists || secret == "" {
-			m.logger.Error("Webhook secret not configured for platform", map[string]interface{}{
-				"platform": platform,
-			})
-			c.JSON(http.StatusInternalServerError, domain.APIResponse{
-				Code:    "CONFIGURATION_ERROR",
-				Message: "Webhook secret not configured",
-			})
-			c.Abort()
-			return
-		}
-
 		// Leer el body completo
 		body, err := io.ReadAll(c.Request.Body)
 		if err != nil {
@@ -68,6 +210,27 @@ func (m *WebhookValidationMiddleware) ValidateWebhookSignature(platform string)
 		// Restaurar el body para que otros handlers puedan leerlo
 		c.Request.Body = io.NopCloser(strings.NewReader(string(body)))
 
+		channels, ok := m.webhookChannels(c, platform, body)
+		if !ok {
+			return
+		}
+
+		secret, ok := m.signingSecret(c, platform, channels)
+		if !ok {
+			return
+		}
+		if secret == "" {
+			m.logger.Error("Webhook secret not configured for platform", map[string]interface{}{
+				"platform": platform,
+			})
+			c.JSON(http.StatusInternalServerError, domain.APIResponse{
+				Code:    "CONFIGURATION_ERROR",
+				Message: "Webhook secret not configured",
+			})
+			c.Abort()
+			return
+		}
+
 		// Obtener la firma del header
 		signature := c.GetHeader("X-Hub-Signature-256")
 		if signature == "" {
@@ -132,9 +295,27 @@ func (m *WebhookValidationMiddleware) ValidateWebhookVerification(platform strin
 			return
 		}
 
-		// Obtener el token de verificación configurado
-		expectedToken, exists := m.config.Integration.WebhookVerifyTokens[platform]
-		if !exists || expectedToken == "" {
+		// En la verificación solo la ruta identifica al canal
+		channels, ok := m.webhookChannels(c, platform, nil)
+		if !ok {
+			return
+		}
+		var channel *domain.ChannelIntegration
+		if len(channels) > 0 {
+			channel = channels[0]
+		}
+
+		// Obtener el token de verificación vigente, el del canal tiene prioridad
+		expectedToken := channelSecret(channel, "verify_token")
+		if expectedToken == "" {
+			var err error
+			expectedToken, err = m.verifyToken(c.Request.Context(), platform)
+			if err != nil {
+				m.abortSecretError(c, platform, err)
+				return
+			}
+		}
+		if expectedToken == "" {
 			m.logger.Error("Webhook verify token not configured for platform", map[string]interface{}{
 				"platform": platform,
 			})
@@ -184,8 +365,12 @@ func (m *WebhookValidationMiddleware) ValidateTelegramWebhook() gin.HandlerFunc
 		// Telegram no usa HMAC, pero puede usar un secret token
 		secretToken := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
 		if secretToken != "" {
-			expectedToken, exists := m.config.Integration.WebhookSecrets["telegram"]
-			if exists && expectedToken != "" && secretToken != expectedToken {
+			expectedToken, err := m.webhookSecret(c.Request.Context(), "telegram")
+			if err != nil {
+				m.abortSecretError(c, "telegram", err)
+				return
+			}
+			if expectedToken != "" && secretToken != expectedToken {
 				m.logger.Error("Invalid Telegram secret token")
 				c.JSON(http.StatusUnauthorized, domain.APIResponse{
 					Code:    "UNAUTHORIZED",
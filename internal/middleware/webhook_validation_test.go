@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticChannels resuelve canales por ID de ruta o, sin ruta, por el id de cada entrada
+type staticChannels map[string]*domain.ChannelIntegration
+
+func (s staticChannels) ResolveWebhookChannels(ctx context.Context, platform domain.Platform, channelID string, payload []byte) ([]*domain.ChannelIntegration, error) {
+	if channelID != "" {
+		if channel, ok := s[channelID]; ok {
+			return []*domain.ChannelIntegration{channel}, nil
+		}
+		return nil, errors.New("channel not found")
+	}
+
+	var webhook struct {
+		Entry []struct {
+			ID string `json:"id"`
+		} `json:"entry"`
+	}
+	_ = json.Unmarshal(payload, &webhook)
+	var channels []*domain.ChannelIntegration
+	for _, entry := range webhook.Entry {
+		if entry.ID == "broken" {
+			return nil, errors.New("database unavailable")
+		}
+		if channel, ok := s[entry.ID]; ok {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{}
+	cfg.Integration.WebhookSecrets = map[string]string{"whatsapp": "global-secret"}
+	cfg.Integration.WebhookVerifyTokens = map[string]string{"whatsapp": "global-token"}
+
+	channels := staticChannels{
+		"tenant-a": {ID: "tenant-a", Config: []byte(`{"app_secret":"secret-a","verify_token":"token-a"}`)},
+		"tenant-b": {ID: "tenant-b", Config: []byte(`{}`)},
+		"tenant-c": {ID: "tenant-c", Config: []byte(`{"app_secret":"secret-a"}`)},
+	}
+	m := NewWebhookValidationMiddleware(cfg, nil, channels, logger.NewLogger("error"))
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := gin.New()
+	router.GET("/whatsapp", m.ValidateWebhookVerification("whatsapp"), ok)
+	router.GET("/whatsapp/:channel_id", m.ValidateWebhookVerification("whatsapp"), ok)
+	router.POST("/whatsapp", m.ValidateWebhookSignature("whatsapp"), ok)
+	router.POST("/whatsapp/:channel_id", m.ValidateWebhookSignature("whatsapp"), ok)
+	return router
+}
+
+func TestValidateWebhookSignaturePerChannel(t *testing.T) {
+	router := newWebhookRouter()
+	body := `{"entry":[]}`
+
+	cases := []struct {
+		name   string
+		path   string
+		secret string
+		status int
+	}{
+		{"channel secret", "/whatsapp/tenant-a", "secret-a", http.StatusOK},
+		{"global secret rejected for channel with its own", "/whatsapp/tenant-a", "global-secret", http.StatusUnauthorized},
+		{"channel without secret falls back to global", "/whatsapp/tenant-b", "global-secret", http.StatusOK},
+		{"unknown channel", "/whatsapp/missing", "global-secret", http.StatusNotFound},
+		{"route without channel uses global", "/whatsapp", "global-secret", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(body))
+			req.Header.Set("X-Hub-Signature-256", sign(tc.secret, body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestValidateWebhookSignatureAcrossEntries(t *testing.T) {
+	router := newWebhookRouter()
+
+	cases := []struct {
+		name    string
+		entries []string
+		secret  string
+		status  int
+	}{
+		{"single channel", []string{"tenant-a"}, "secret-a", http.StatusOK},
+		{"channels sharing the app secret", []string{"tenant-a", "tenant-c"}, "secret-a", http.StatusOK},
+		{"channel secret does not cover another channel", []string{"tenant-a", "tenant-b"}, "secret-a", http.StatusUnauthorized},
+		{"global secret does not cover a channel with its own", []string{"tenant-a", "tenant-b"}, "global-secret", http.StatusUnauthorized},
+		{"unknown entries left to processing", []string{"tenant-a", "unknown"}, "secret-a", http.StatusOK},
+		{"only unknown entries use global", []string{"unknown"}, "global-secret", http.StatusOK},
+		{"channel lookup failure", []string{"tenant-a", "broken"}, "secret-a", http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries := make([]string, 0, len(tc.entries))
+			for _, id := range tc.entries {
+				entries = append(entries, `{"id":"`+id+`"}`)
+			}
+			body := `{"entry":[` + strings.Join(entries, ",") + `]}`
+
+			req := httptest.NewRequest(http.MethodPost, "/whatsapp", strings.NewReader(body))
+			req.Header.Set("X-Hub-Signature-256", sign(tc.secret, body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestValidateWebhookVerificationPerChannel(t *testing.T) {
+	router := newWebhookRouter()
+
+	verify := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path+"?hub.mode=subscribe&hub.challenge=42&hub.verify_token="+token, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := verify("/whatsapp/tenant-a", "token-a")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "42", rec.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, verify("/whatsapp/tenant-a", "global-token").Code)
+	assert.Equal(t, http.StatusOK, verify("/whatsapp/tenant-b", "global-token").Code)
+	assert.Equal(t, http.StatusOK, verify("/whatsapp", "global-token").Code)
+}
@@ -0,0 +1,498 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"it-integration-service/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+type broadcastRepository struct {
+	db *PostgresDB
+}
+
+// NewBroadcastRepository creates a new broadcast repository
+func NewBroadcastRepository(db *PostgresDB) domain.BroadcastRepository {
+	return &broadcastRepository{db: db}
+}
+
+const broadcastJobColumns = `id, tenant_id, platforms, channels, content, status, total_recipients, rejected_recipients,
+	COALESCE(error, ''), created_at, updated_at, started_at, completed_at`
+
+func (r *broadcastRepository) CreateJob(ctx context.Context, job *domain.BroadcastJob) error {
+	platformsJSON, err := json.Marshal(job.Platforms)
+	if err != nil {
+		return fmt.Errorf("failed to marshal platforms: %w", err)
+	}
+	channelsJSON, err := json.Marshal(job.Channels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal channels: %w", err)
+	}
+	contentJSON, err := json.Marshal(job.Content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal content: %w", err)
+	}
+
+	query := `
+		INSERT INTO broadcast_jobs (id, tenant_id, platforms, channels, content, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = r.db.DB.ExecContext(ctx, query,
+		job.ID,
+		job.TenantID,
+		platformsJSON,
+		channelsJSON,
+		contentJSON,
+		job.Status,
+		job.CreatedAt,
+		job.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create broadcast job: %w", err)
+	}
+
+	return nil
+}
+
+func (r *broadcastRepository) GetJob(ctx context.Context, id string) (*domain.BroadcastJob, error) {
+	query := `SELECT ` + broadcastJobColumns + ` FROM broadcast_jobs WHERE id = $1`
+
+	job, err := scanBroadcastJob(r.db.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("broadcast job not found: %w", sql.ErrNoRows)
+		}
+		return nil, fmt.Errorf("failed to get broadcast job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (r *broadcastRepository) GetActiveJobs(ctx context.Context, limit int) ([]*domain.BroadcastJob, error) {
+	query := `
+		SELECT ` + broadcastJobColumns + `
+		FROM broadcast_jobs
+		WHERE status IN ('queued', 'running')
+		ORDER BY created_at ASC
+		LIMIT $1`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active broadcast jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*domain.BroadcastJob
+
+	for rows.Next() {
+		job, err := scanBroadcastJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (r *broadcastRepository) TransitionJob(ctx context.Context, id string, from []domain.BroadcastJobStatus, to domain.BroadcastJobStatus) (bool, error) {
+	fromStatuses := make([]string, len(from))
+	for i, status := range from {
+		fromStatuses[i] = string(status)
+	}
+
+	query := `
+		UPDATE broadcast_jobs
+		SET status = $3,
+			updated_at = NOW(),
+			started_at = CASE WHEN $3 = 'running' THEN COALESCE(started_at, NOW()) ELSE started_at END,
+			completed_at = CASE WHEN $3 IN ('completed', 'cancelled', 'failed') THEN NOW() ELSE completed_at END
+		WHERE id = $1 AND status = ANY($2)`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id, pq.Array(fromStatuses), to)
+	if err != nil {
+		return false, fmt.Errorf("failed to update broadcast job status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *broadcastRepository) FailJob(ctx context.Context, id string, reason string) error {
+	query := `
+		UPDATE broadcast_jobs
+		SET status = 'failed', error = $2, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1`
+
+	_, err := r.db.DB.ExecContext(ctx, query, id, reason)
+	if err != nil {
+		return fmt.Errorf("failed to mark broadcast job as failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *broadcastRepository) FinishLoading(ctx context.Context, id string, total, rejected int) error {
+	query := `
+		UPDATE broadcast_jobs
+		SET status = 'queued', total_recipients = $2, rejected_recipients = $3, updated_at = NOW()
+		WHERE id = $1 AND status = 'loading'`
+
+	_, err := r.db.DB.ExecContext(ctx, query, id, total, rejected)
+	if err != nil {
+		return fmt.Errorf("failed to finish broadcast loading: %w", err)
+	}
+
+	return nil
+}
+
+func (r *broadcastRepository) CompleteJobIfDone(ctx context.Context, id string) (bool, error) {
+	query := `
+		UPDATE broadcast_jobs
+		SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+		  AND NOT EXISTS (
+			SELECT 1 FROM broadcast_recipients
+			WHERE job_id = $1 AND status IN ('pending', 'processing')
+		  )`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete broadcast job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *broadcastRepository) AddRecipients(ctx context.Context, recipients []*domain.BroadcastRecipient) (int, error) {
+	if len(recipients) == 0 {
+		return 0, nil
+	}
+
+	// Un único INSERT por lote; las repeticiones dentro de la carga se descartan por la restricción única
+	placeholders := make([]string, 0, len(recipients))
+	args := make([]interface{}, 0, len(recipients)*4)
+	for i, recipient := range recipients {
+		n := i * 4
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4))
+		args = append(args, recipient.JobID, recipient.Platform, recipient.ChannelID, recipient.Recipient)
+	}
+
+	query := `
+		INSERT INTO broadcast_recipients (job_id, platform, channel_id, recipient)
+		VALUES ` + strings.Join(placeholders, ", ") + `
+		ON CONFLICT (job_id, platform, recipient) DO NOTHING`
+
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add broadcast recipients: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+func (r *broadcastRepository) ClaimRecipients(ctx context.Context, jobID string, channelIDs []string, limit int, lease time.Duration) ([]*domain.BroadcastRecipient, error) {
+	// Igual que el outbox: los destinatarios en "processing" con lease vencido se vuelven a reclamar
+	query := `
+		UPDATE broadcast_recipients
+		SET status = 'processing', locked_until = NOW() + ($4 * INTERVAL '1 millisecond'), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM broadcast_recipients
+			WHERE job_id = $1
+			  AND channel_id = ANY($2)
+			  AND (status = 'pending' OR (status = 'processing' AND locked_until < NOW()))
+			ORDER BY id ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, job_id, platform, channel_id, recipient, status, COALESCE(message_id, ''),
+			COALESCE(outbound_log_id, ''), COALESCE(error, ''), updated_at`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, jobID, pq.Array(channelIDs), limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim broadcast recipients: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBroadcastRecipients(rows)
+}
+
+func (r *broadcastRepository) AcquireChannels(ctx context.Context, holder string, channelIDs []string, lease time.Duration) ([]string, error) {
+	// Un canal libre, vencido o ya de holder se toma (o renueva); el de otra réplica vigente no cambia
+	query := `
+		INSERT INTO broadcast_channel_leases (channel_id, holder, locked_until)
+		SELECT channel_id, $2, NOW() + ($3 * INTERVAL '1 millisecond')
+		FROM UNNEST($1::text[]) AS channel_id
+		ON CONFLICT (channel_id) DO UPDATE
+		SET holder = EXCLUDED.holder, locked_until = EXCLUDED.locked_until
+		WHERE broadcast_channel_leases.holder = EXCLUDED.holder
+		   OR broadcast_channel_leases.locked_until < NOW()
+		RETURNING channel_id`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, pq.Array(channelIDs), holder, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire broadcast channels: %w", err)
+	}
+	defer rows.Close()
+
+	var acquired []string
+	for rows.Next() {
+		var channelID string
+		if err := rows.Scan(&channelID); err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast channel lease: %w", err)
+		}
+		acquired = append(acquired, channelID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate broadcast channel leases: %w", err)
+	}
+
+	return acquired, nil
+}
+
+func (r *broadcastRepository) ReleaseChannels(ctx context.Context, holder string, channelIDs []string) error {
+	query := `DELETE FROM broadcast_channel_leases WHERE holder = $1 AND channel_id = ANY($2)`
+
+	if _, err := r.db.DB.ExecContext(ctx, query, holder, pq.Array(channelIDs)); err != nil {
+		return fmt.Errorf("failed to release broadcast channels: %w", err)
+	}
+
+	return nil
+}
+
+func (r *broadcastRepository) MarkRecipientResult(ctx context.Context, recipient *domain.BroadcastRecipient) error {
+	query := `
+		UPDATE broadcast_recipients
+		SET status = $2, message_id = NULLIF($3, ''), outbound_log_id = NULLIF($4, ''), error = NULLIF($5, ''),
+			locked_until = NULL, updated_at = NOW()
+		WHERE id = $1`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		recipient.ID,
+		recipient.Status,
+		recipient.MessageID,
+		recipient.OutboundLogID,
+		recipient.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update broadcast recipient: %w", err)
+	}
+
+	return nil
+}
+
+func (r *broadcastRepository) ReleaseRecipients(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE broadcast_recipients
+		SET status = 'pending', locked_until = NULL, updated_at = NOW()
+		WHERE id = ANY($1) AND status = 'processing'`
+
+	_, err := r.db.DB.ExecContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to release broadcast recipients: %w", err)
+	}
+
+	return nil
+}
+
+func (r *broadcastRepository) CancelPendingRecipients(ctx context.Context, jobID string) (int64, error) {
+	query := `
+		UPDATE broadcast_recipients
+		SET status = 'cancelled', locked_until = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND (status = 'pending' OR (status = 'processing' AND locked_until < NOW()))`
+
+	result, err := r.db.DB.ExecContext(ctx, query, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel broadcast recipients: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (r *broadcastRepository) GetProgress(ctx context.Context, jobID string) (*domain.BroadcastProgress, error) {
+	job, err := r.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT status, COUNT(*)
+		FROM broadcast_recipients
+		WHERE job_id = $1
+		GROUP BY status`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query broadcast progress: %w", err)
+	}
+	defer rows.Close()
+
+	progress := &domain.BroadcastProgress{
+		JobID:  job.ID,
+		Status: job.Status,
+		Total:  job.TotalRecipients,
+	}
+
+	counted := 0
+	for rows.Next() {
+		var status domain.BroadcastRecipientStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast progress: %w", err)
+		}
+
+		counted += count
+		switch status {
+		case domain.BroadcastRecipientPending:
+			progress.Pending = count
+		case domain.BroadcastRecipientProcessing:
+			progress.Processing = count
+		case domain.BroadcastRecipientSent:
+			progress.Sent = count
+		case domain.BroadcastRecipientFailed:
+			progress.Failed = count
+		case domain.BroadcastRecipientCancelled:
+			progress.Cancelled = count
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	// Mientras se carga el CSV el total aún no está registrado en el trabajo
+	if progress.Total < counted {
+		progress.Total = counted
+	}
+	if progress.Total > 0 {
+		done := progress.Sent + progress.Failed + progress.Cancelled
+		progress.Percent = float64(done) * 100 / float64(progress.Total)
+	}
+
+	return progress, nil
+}
+
+func (r *broadcastRepository) ListRecipients(ctx context.Context, jobID string, status domain.BroadcastRecipientStatus, afterID int64, limit int) ([]*domain.BroadcastRecipient, error) {
+	query := `
+		SELECT id, job_id, platform, channel_id, recipient, status, COALESCE(message_id, ''),
+			COALESCE(outbound_log_id, ''), COALESCE(error, ''), updated_at
+		FROM broadcast_recipients
+		WHERE job_id = $1 AND id > $2 AND ($3 = '' OR status = $3)
+		ORDER BY id ASC
+		LIMIT $4`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, jobID, afterID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query broadcast recipients: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBroadcastRecipients(rows)
+}
+
+// rowScanner abstrae sql.Row y sql.Rows para compartir el escaneo
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBroadcastJob(row rowScanner) (*domain.BroadcastJob, error) {
+	var job domain.BroadcastJob
+	var platformsJSON, channelsJSON, contentJSON []byte
+
+	err := row.Scan(
+		&job.ID,
+		&job.TenantID,
+		&platformsJSON,
+		&channelsJSON,
+		&contentJSON,
+		&job.Status,
+		&job.TotalRecipients,
+		&job.RejectedRecipients,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.StartedAt,
+		&job.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(platformsJSON, &job.Platforms); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal platforms: %w", err)
+	}
+	if err := json.Unmarshal(channelsJSON, &job.Channels); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal channels: %w", err)
+	}
+	if err := json.Unmarshal(contentJSON, &job.Content); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal content: %w", err)
+	}
+
+	return &job, nil
+}
+
+func scanBroadcastRecipients(rows *sql.Rows) ([]*domain.BroadcastRecipient, error) {
+	var recipients []*domain.BroadcastRecipient
+
+	for rows.Next() {
+		var recipient domain.BroadcastRecipient
+
+		err := rows.Scan(
+			&recipient.ID,
+			&recipient.JobID,
+			&recipient.Platform,
+			&recipient.ChannelID,
+			&recipient.Recipient,
+			&recipient.Status,
+			&recipient.MessageID,
+			&recipient.OutboundLogID,
+			&recipient.Error,
+			&recipient.UpdatedAt,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast recipient: %w", err)
+		}
+
+		recipients = append(recipients, &recipient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return recipients, nil
+}
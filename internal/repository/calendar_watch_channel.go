@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/domain"
+)
+
+type calendarWatchChannelRepository struct {
+	db *PostgresDB
+}
+
+// NewCalendarWatchChannelRepository creates a new Google Calendar push channel repository
+func NewCalendarWatchChannelRepository(db *PostgresDB) domain.CalendarWatchChannelRepository {
+	return &calendarWatchChannelRepository{db: db}
+}
+
+const calendarWatchChannelColumns = `id, channel_id, calendar_id, resource_id, token, expiration, created_at, stopped_at`
+
+func (r *calendarWatchChannelRepository) Create(ctx context.Context, channel *domain.CalendarWatchChannel) error {
+	query := `
+		INSERT INTO calendar_watch_channels (` + calendarWatchChannelColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		channel.ID,
+		channel.ChannelID,
+		channel.CalendarID,
+		channel.ResourceID,
+		channel.Token,
+		channel.Expiration,
+		channel.CreatedAt,
+		channel.StoppedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create calendar watch channel: %w", err)
+	}
+
+	return nil
+}
+
+func (r *calendarWatchChannelRepository) GetByID(ctx context.Context, id string) (*domain.CalendarWatchChannel, error) {
+	query := `SELECT ` + calendarWatchChannelColumns + ` FROM calendar_watch_channels WHERE id = $1`
+
+	channel, err := scanCalendarWatchChannel(r.db.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("calendar watch channel not found: %w", sql.ErrNoRows)
+		}
+		return nil, fmt.Errorf("failed to get calendar watch channel: %w", err)
+	}
+
+	return channel, nil
+}
+
+func (r *calendarWatchChannelRepository) ListActiveByChannel(ctx context.Context, channelID string) ([]*domain.CalendarWatchChannel, error) {
+	query := `
+		SELECT ` + calendarWatchChannelColumns + `
+		FROM calendar_watch_channels
+		WHERE channel_id = $1 AND stopped_at IS NULL
+		ORDER BY created_at`
+
+	return r.list(ctx, query, channelID)
+}
+
+func (r *calendarWatchChannelRepository) ListExpiring(ctx context.Context, before time.Time) ([]*domain.CalendarWatchChannel, error) {
+	query := `
+		SELECT ` + calendarWatchChannelColumns + `
+		FROM calendar_watch_channels
+		WHERE stopped_at IS NULL AND expiration < $1
+		ORDER BY expiration`
+
+	return r.list(ctx, query, before)
+}
+
+func (r *calendarWatchChannelRepository) MarkStopped(ctx context.Context, id string, stoppedAt time.Time) error {
+	query := `UPDATE calendar_watch_channels SET stopped_at = $1 WHERE id = $2 AND stopped_at IS NULL`
+
+	if _, err := r.db.DB.ExecContext(ctx, query, stoppedAt, id); err != nil {
+		return fmt.Errorf("failed to mark calendar watch channel as stopped: %w", err)
+	}
+
+	return nil
+}
+
+func (r *calendarWatchChannelRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.CalendarWatchChannel, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendar watch channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []*domain.CalendarWatchChannel
+	for rows.Next() {
+		channel, err := scanCalendarWatchChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar watch channel: %w", err)
+		}
+		channels = append(channels, channel)
+	}
+
+	return channels, rows.Err()
+}
+
+func scanCalendarWatchChannel(row rowScanner) (*domain.CalendarWatchChannel, error) {
+	var channel domain.CalendarWatchChannel
+	err := row.Scan(
+		&channel.ID,
+		&channel.ChannelID,
+		&channel.CalendarID,
+		&channel.ResourceID,
+		&channel.Token,
+		&channel.Expiration,
+		&channel.CreatedAt,
+		&channel.StoppedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
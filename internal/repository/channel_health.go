@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/domain"
+)
+
+type channelHealthCheckRepository struct {
+	db *PostgresDB
+}
+
+// NewChannelHealthCheckRepository creates a new channel health check repository
+func NewChannelHealthCheckRepository(db *PostgresDB) domain.ChannelHealthCheckRepository {
+	return &channelHealthCheckRepository{db: db}
+}
+
+const channelHealthCheckColumns = `id, channel_id, tenant_id, platform, healthy, error, details,
+	latency_ms, consecutive_failures, checked_at`
+
+func (r *channelHealthCheckRepository) Create(ctx context.Context, check *domain.ChannelHealthCheck) error {
+	query := `
+		INSERT INTO channel_health_checks (channel_id, tenant_id, platform, healthy, error, details, latency_ms, consecutive_failures, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+
+	var details interface{}
+	if len(check.Details) > 0 {
+		details = []byte(check.Details)
+	}
+
+	err := r.db.DB.QueryRowContext(ctx, query,
+		check.ChannelID,
+		check.TenantID,
+		check.Platform,
+		check.Healthy,
+		sql.NullString{String: check.Error, Valid: check.Error != ""},
+		details,
+		check.LatencyMs,
+		check.ConsecutiveFailures,
+		check.CheckedAt,
+	).Scan(&check.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create channel health check: %w", err)
+	}
+
+	return nil
+}
+
+func (r *channelHealthCheckRepository) ListByChannel(ctx context.Context, channelID string, limit int) ([]*domain.ChannelHealthCheck, error) {
+	query := `SELECT ` + channelHealthCheckColumns + `
+		FROM channel_health_checks
+		WHERE channel_id = $1
+		ORDER BY checked_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, channelID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channel health checks: %w", err)
+	}
+	defer rows.Close()
+
+	var checks []*domain.ChannelHealthCheck
+	for rows.Next() {
+		check, err := scanChannelHealthCheck(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan channel health check: %w", err)
+		}
+		checks = append(checks, check)
+	}
+
+	return checks, rows.Err()
+}
+
+func (r *channelHealthCheckRepository) GetLastSuccess(ctx context.Context, channelID string) (*domain.ChannelHealthCheck, error) {
+	query := `SELECT ` + channelHealthCheckColumns + `
+		FROM channel_health_checks
+		WHERE channel_id = $1 AND healthy
+		ORDER BY checked_at DESC, id DESC
+		LIMIT 1`
+
+	check, err := scanChannelHealthCheck(r.db.DB.QueryRowContext(ctx, query, channelID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("channel health check not found: %w", sql.ErrNoRows)
+		}
+		return nil, fmt.Errorf("failed to get last successful health check: %w", err)
+	}
+
+	return check, nil
+}
+
+func (r *channelHealthCheckRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM channel_health_checks WHERE checked_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old channel health checks: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func scanChannelHealthCheck(row rowScanner) (*domain.ChannelHealthCheck, error) {
+	var check domain.ChannelHealthCheck
+	var errorMessage sql.NullString
+	var details []byte
+	err := row.Scan(
+		&check.ID,
+		&check.ChannelID,
+		&check.TenantID,
+		&check.Platform,
+		&check.Healthy,
+		&errorMessage,
+		&details,
+		&check.LatencyMs,
+		&check.ConsecutiveFailures,
+		&check.CheckedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	check.Error = errorMessage.String
+	check.Details = details
+
+	return &check, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/domain"
+)
+
+// channelIdentifierKeys indica qué claves del config de cada plataforma identifican al canal en sus webhooks
+var channelIdentifierKeys = map[domain.Platform][]string{
+	domain.PlatformWhatsApp:  {"phone_number_id"},
+	domain.PlatformMessenger: {"page_id"},
+	domain.PlatformInstagram: {"instagram_id", "page_id"},
+	domain.PlatformTelegram:  {"webhook_key"},
+	domain.PlatformWebchat:   {"webchat_id"},
+	domain.PlatformMailchimp: {"list_id", "audience_id"},
+}
+
+// channelIdentifiersFor extrae los identificadores de plataforma del config de una integración
+func channelIdentifiersFor(integration *domain.ChannelIntegration) []domain.ChannelIdentifier {
+	keys, ok := channelIdentifierKeys[integration.Platform]
+	if !ok || len(integration.Config) == 0 {
+		return nil
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(integration.Config, &config); err != nil {
+		return nil
+	}
+
+	// El webchat guarda su ID dentro de webchat_config
+	if webchatConfig, ok := config["webchat_config"].(map[string]interface{}); ok {
+		if id, ok := webchatConfig["id"]; ok {
+			config["webchat_id"] = id
+		}
+	}
+
+	identifiers := make([]domain.ChannelIdentifier, 0, len(keys))
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		value := identifierValue(config[key])
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+
+		// list_id y audience_id son el mismo identificador en Mailchimp
+		identifierType := key
+		if key == "audience_id" {
+			identifierType = "list_id"
+		}
+
+		identifiers = append(identifiers, domain.ChannelIdentifier{
+			Platform:       integration.Platform,
+			IdentifierType: identifierType,
+			Identifier:     value,
+			ChannelID:      integration.ID,
+			TenantID:       integration.TenantID,
+			CreatedAt:      time.Now(),
+		})
+	}
+
+	return identifiers
+}
+
+// identifierValue convierte un valor del config (string o número) en identificador
+func identifierValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
+
+// replaceChannelIdentifiers sincroniza la tabla de lookup con el config actual de la integración
+func replaceChannelIdentifiers(ctx context.Context, tx *sql.Tx, integration *domain.ChannelIntegration) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM channel_identifiers WHERE channel_id = $1`, integration.ID); err != nil {
+		return fmt.Errorf("failed to clear channel identifiers: %w", err)
+	}
+
+	query := `
+		INSERT INTO channel_identifiers (platform, identifier_type, identifier, channel_id, tenant_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	for _, identifier := range channelIdentifiersFor(integration) {
+		_, err := tx.ExecContext(ctx, query,
+			identifier.Platform,
+			identifier.IdentifierType,
+			identifier.Identifier,
+			identifier.ChannelID,
+			identifier.TenantID,
+			identifier.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to register %s %s for channel (already used by another channel?): %w",
+				identifier.IdentifierType, identifier.Identifier, err)
+		}
+	}
+
+	return nil
+}
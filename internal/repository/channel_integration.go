@@ -4,39 +4,62 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"it-integration-service/internal/domain"
 )
 
+// channelIntegrationColumns son las columnas que lee scanChannelIntegration, en orden
+const channelIntegrationColumns = `id, tenant_id, platform, provider, access_token, webhook_url, status, config,
+			token_expires_at, token_issued_at, token_last_validated_at, token_last_rotated_at, created_at, updated_at`
+
+// ErrNoActiveChannel indica que el tenant no tiene un canal activo de la plataforma
+var ErrNoActiveChannel = errors.New("no active channel integration found")
+
 type channelIntegrationRepository struct {
-	db *PostgresDB
+	db     *PostgresDB
+	sealer CredentialSealer
 }
 
-// NewChannelIntegrationRepository creates a new channel integration repository
-func NewChannelIntegrationRepository(db *PostgresDB) domain.ChannelIntegrationRepository {
-	return &channelIntegrationRepository{db: db}
+// NewChannelIntegrationRepository creates a new channel integration repository.
+// Con sealer, el access token y los secretos del config se guardan cifrados y se
+// descifran al leer; sin sealer se guardan tal como llegan.
+func NewChannelIntegrationRepository(db *PostgresDB, sealer CredentialSealer) domain.ChannelIntegrationRepository {
+	return &channelIntegrationRepository{db: db, sealer: sealer}
 }
 
 func (r *channelIntegrationRepository) Create(ctx context.Context, integration *domain.ChannelIntegration) error {
 	query := `
-		INSERT INTO channel_integrations (id, tenant_id, platform, provider, access_token, webhook_url, status, config, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+		INSERT INTO channel_integrations (id, tenant_id, platform, provider, access_token, webhook_url, status, config,
+			token_expires_at, token_issued_at, token_last_validated_at, token_last_rotated_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+
+	accessToken, configJSON, err := sealChannel(r.sealer, integration)
+	if err != nil {
+		return err
+	}
 
-	configJSON, err := json.Marshal(integration.Config)
+	tx, err := r.db.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	_, err = r.db.DB.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		integration.ID,
 		integration.TenantID,
 		string(integration.Platform),
 		string(integration.Provider),
-		integration.AccessToken,
+		accessToken,
 		integration.WebhookURL,
 		string(integration.Status),
 		configJSON,
+		integration.TokenExpiresAt,
+		integration.TokenIssuedAt,
+		integration.TokenLastValidatedAt,
+		integration.TokenLastRotatedAt,
 		integration.CreatedAt,
 		integration.UpdatedAt,
 	)
@@ -45,48 +68,42 @@ func (r *channelIntegrationRepository) Create(ctx context.Context, integration *
 		return fmt.Errorf("failed to create channel integration (query: %s): %w", query, err)
 	}
 
+	if err := replaceChannelIdentifiers(ctx, tx, integration); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
 func (r *channelIntegrationRepository) GetByID(ctx context.Context, id string) (*domain.ChannelIntegration, error) {
 	query := `
-		SELECT id, tenant_id, platform, provider, access_token, webhook_url, status, config, created_at, updated_at
+		SELECT ` + channelIntegrationColumns + `
 		FROM channel_integrations
 		WHERE id = $1`
 
-	var integration domain.ChannelIntegration
-	var configJSON []byte
-
-	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
-		&integration.ID,
-		&integration.TenantID,
-		&integration.Platform,
-		&integration.Provider,
-		&integration.AccessToken,
-		&integration.WebhookURL,
-		&integration.Status,
-		&configJSON,
-		&integration.CreatedAt,
-		&integration.UpdatedAt,
-	)
+	integration, err := scanChannelIntegration(r.db.DB.QueryRowContext(ctx, query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("channel integration not found")
+			return nil, fmt.Errorf("channel integration not found: %w", sql.ErrNoRows)
 		}
 		return nil, fmt.Errorf("failed to get channel integration: %w", err)
 	}
 
-	if err := json.Unmarshal(configJSON, &integration.Config); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	if err := openChannel(r.sealer, integration); err != nil {
+		return nil, err
 	}
 
-	return &integration, nil
+	return integration, nil
 }
 
 func (r *channelIntegrationRepository) GetByTenantID(ctx context.Context, tenantID string) ([]*domain.ChannelIntegration, error) {
 	query := `
-		SELECT id, tenant_id, platform, provider, access_token, webhook_url, status, config, created_at, updated_at
+		SELECT ` + channelIntegrationColumns + `
 		FROM channel_integrations
 		WHERE tenant_id = $1
 		ORDER BY created_at DESC`
@@ -100,31 +117,92 @@ func (r *channelIntegrationRepository) GetByTenantID(ctx context.Context, tenant
 	var integrations []*domain.ChannelIntegration
 
 	for rows.Next() {
-		var integration domain.ChannelIntegration
-		var configJSON []byte
-
-		err := rows.Scan(
-			&integration.ID,
-			&integration.TenantID,
-			&integration.Platform,
-			&integration.Provider,
-			&integration.AccessToken,
-			&integration.WebhookURL,
-			&integration.Status,
-			&configJSON,
-			&integration.CreatedAt,
-			&integration.UpdatedAt,
-		)
+		integration, err := scanChannelIntegration(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan channel integration: %w", err)
+		}
+
+		if err := openChannel(r.sealer, integration); err != nil {
+			return nil, err
+		}
+
+		integrations = append(integrations, integration)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return integrations, nil
+}
+
+// UpdateTokenState guarda el access token, el estado y la metadata del token. Update no modifica
+// la metadata, que solo mantiene el ciclo de vida de tokens.
+func (r *channelIntegrationRepository) UpdateTokenState(ctx context.Context, integration *domain.ChannelIntegration) error {
+	query := `
+		UPDATE channel_integrations
+		SET access_token = $2, status = $3, token_expires_at = $4, token_issued_at = $5,
+			token_last_validated_at = $6, token_last_rotated_at = $7, updated_at = $8
+		WHERE id = $1`
+
+	accessToken, _, err := sealChannel(r.sealer, integration)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		integration.ID,
+		accessToken,
+		integration.Status,
+		integration.TokenExpiresAt,
+		integration.TokenIssuedAt,
+		integration.TokenLastValidatedAt,
+		integration.TokenLastRotatedAt,
+		integration.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update channel token state: %w", err)
+	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("channel integration not found")
+	}
+
+	return nil
+}
+
+// GetByTokenExpiry obtiene las integraciones no deshabilitadas cuyo token vence antes de before
+func (r *channelIntegrationRepository) GetByTokenExpiry(ctx context.Context, before time.Time) ([]*domain.ChannelIntegration, error) {
+	query := `
+		SELECT ` + channelIntegrationColumns + `
+		FROM channel_integrations
+		WHERE token_expires_at IS NOT NULL AND token_expires_at <= $1 AND status <> 'disabled'
+		ORDER BY token_expires_at`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channel integrations by token expiry: %w", err)
+	}
+	defer rows.Close()
+
+	var integrations []*domain.ChannelIntegration
+
+	for rows.Next() {
+		integration, err := scanChannelIntegration(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan channel integration: %w", err)
 		}
 
-		if err := json.Unmarshal(configJSON, &integration.Config); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+		if err := openChannel(r.sealer, integration); err != nil {
+			return nil, err
 		}
 
-		integrations = append(integrations, &integration)
+		integrations = append(integrations, integration)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -134,23 +212,75 @@ func (r *channelIntegrationRepository) GetByTenantID(ctx context.Context, tenant
 	return integrations, nil
 }
 
+func (r *channelIntegrationRepository) CountByPlatformAndStatus(ctx context.Context) ([]*domain.IntegrationCount, error) {
+	query := `
+		SELECT platform, status, COUNT(*)
+		FROM channel_integrations
+		GROUP BY platform, status
+		ORDER BY platform, status`
+
+	rows, err := r.db.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count channel integrations: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []*domain.IntegrationCount
+	for rows.Next() {
+		var count domain.IntegrationCount
+		if err := rows.Scan(&count.Platform, &count.Status, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan integration count: %w", err)
+		}
+		counts = append(counts, &count)
+	}
+
+	return counts, rows.Err()
+}
+
+// UpdateStatus cambia el estado de la integración sin tocar sus credenciales; la condición sobre
+// from evita pisar un cambio hecho mientras tanto (por ejemplo, un canal deshabilitado a mano)
+func (r *channelIntegrationRepository) UpdateStatus(ctx context.Context, id string, from, to domain.IntegrationStatus) (bool, error) {
+	query := `
+		UPDATE channel_integrations
+		SET status = $3, updated_at = NOW()
+		WHERE id = $1 AND status = $2`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id, from, to)
+	if err != nil {
+		return false, fmt.Errorf("failed to update channel integration status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
 func (r *channelIntegrationRepository) Update(ctx context.Context, integration *domain.ChannelIntegration) error {
 	query := `
 		UPDATE channel_integrations
 		SET tenant_id = $2, platform = $3, provider = $4, access_token = $5, webhook_url = $6, status = $7, config = $8, updated_at = $9
 		WHERE id = $1`
 
-	configJSON, err := json.Marshal(integration.Config)
+	accessToken, configJSON, err := sealChannel(r.sealer, integration)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
 
-	result, err := r.db.DB.ExecContext(ctx, query,
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query,
 		integration.ID,
 		integration.TenantID,
 		integration.Platform,
 		integration.Provider,
-		integration.AccessToken,
+		accessToken,
 		integration.WebhookURL,
 		integration.Status,
 		configJSON,
@@ -170,13 +300,27 @@ func (r *channelIntegrationRepository) Update(ctx context.Context, integration *
 		return fmt.Errorf("channel integration not found")
 	}
 
+	if err := replaceChannelIdentifiers(ctx, tx, integration); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
 func (r *channelIntegrationRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM channel_integrations WHERE id = $1`
 
-	result, err := r.db.DB.ExecContext(ctx, query, id)
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete channel integration: %w", err)
 	}
@@ -190,51 +334,71 @@ func (r *channelIntegrationRepository) Delete(ctx context.Context, id string) er
 		return fmt.Errorf("channel integration not found")
 	}
 
+	if _, err := tx.ExecContext(ctx, `DELETE FROM channel_identifiers WHERE channel_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete channel identifiers: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
 func (r *channelIntegrationRepository) GetByPlatformAndTenant(ctx context.Context, platform domain.Platform, tenantID string) (*domain.ChannelIntegration, error) {
 	query := `
-		SELECT id, tenant_id, platform, provider, access_token, webhook_url, status, config, created_at, updated_at
+		SELECT ` + channelIntegrationColumns + `
 		FROM channel_integrations
 		WHERE platform = $1 AND tenant_id = $2 AND status = 'active'
 		ORDER BY created_at DESC
 		LIMIT 1`
 
-	var integration domain.ChannelIntegration
-	var configJSON []byte
-
-	err := r.db.DB.QueryRowContext(ctx, query, platform, tenantID).Scan(
-		&integration.ID,
-		&integration.TenantID,
-		&integration.Platform,
-		&integration.Provider,
-		&integration.AccessToken,
-		&integration.WebhookURL,
-		&integration.Status,
-		&configJSON,
-		&integration.CreatedAt,
-		&integration.UpdatedAt,
-	)
+	integration, err := scanChannelIntegration(r.db.DB.QueryRowContext(ctx, query, platform, tenantID))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("no active channel integration found for platform %s and tenant %s", platform, tenantID)
+			return nil, fmt.Errorf("%w for platform %s and tenant %s", ErrNoActiveChannel, platform, tenantID)
 		}
 		return nil, fmt.Errorf("failed to get channel integration by platform and tenant: %w", err)
 	}
 
-	if err := json.Unmarshal(configJSON, &integration.Config); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	if err := openChannel(r.sealer, integration); err != nil {
+		return nil, err
 	}
 
-	return &integration, nil
+	return integration, nil
+}
+
+// GetByPlatformIdentifier obtiene la integración asociada a un identificador de plataforma
+// (phone_number_id de WhatsApp, page_id de Messenger, list_id de Mailchimp, etc.)
+func (r *channelIntegrationRepository) GetByPlatformIdentifier(ctx context.Context, platform domain.Platform, identifier string) (*domain.ChannelIntegration, error) {
+	query := `
+		SELECT ci.id, ci.tenant_id, ci.platform, ci.provider, ci.access_token, ci.webhook_url, ci.status, ci.config,
+			ci.token_expires_at, ci.token_issued_at, ci.token_last_validated_at, ci.token_last_rotated_at, ci.created_at, ci.updated_at
+		FROM channel_identifiers cid
+		JOIN channel_integrations ci ON ci.id::text = cid.channel_id
+		WHERE cid.platform = $1 AND cid.identifier = $2`
+
+	integration, err := scanChannelIntegration(r.db.DB.QueryRowContext(ctx, query, platform, identifier))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("channel integration not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get channel integration by identifier: %w", err)
+	}
+
+	if err := openChannel(r.sealer, integration); err != nil {
+		return nil, err
+	}
+
+	return integration, nil
 }
 
 // GetByPlatform obtiene todas las integraciones de una plataforma específica
 func (r *channelIntegrationRepository) GetByPlatform(ctx context.Context, platform domain.Platform) ([]*domain.ChannelIntegration, error) {
 	query := `
-		SELECT id, tenant_id, platform, provider, access_token, webhook_url, status, config, created_at, updated_at
+		SELECT ` + channelIntegrationColumns + `
 		FROM channel_integrations
 		WHERE platform = $1
 		ORDER BY created_at DESC`
@@ -248,31 +412,17 @@ func (r *channelIntegrationRepository) GetByPlatform(ctx context.Context, platfo
 	var integrations []*domain.ChannelIntegration
 
 	for rows.Next() {
-		var integration domain.ChannelIntegration
-		var configJSON []byte
-
-		err := rows.Scan(
-			&integration.ID,
-			&integration.TenantID,
-			&integration.Platform,
-			&integration.Provider,
-			&integration.AccessToken,
-			&integration.WebhookURL,
-			&integration.Status,
-			&configJSON,
-			&integration.CreatedAt,
-			&integration.UpdatedAt,
-		)
+		integration, err := scanChannelIntegration(rows)
 
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan channel integration: %w", err)
 		}
 
-		if err := json.Unmarshal(configJSON, &integration.Config); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+		if err := openChannel(r.sealer, integration); err != nil {
+			return nil, err
 		}
 
-		integrations = append(integrations, &integration)
+		integrations = append(integrations, integration)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -285,7 +435,7 @@ func (r *channelIntegrationRepository) GetByPlatform(ctx context.Context, platfo
 // GetActiveByTenant obtiene todas las integraciones activas de un tenant
 func (r *channelIntegrationRepository) GetActiveByTenant(ctx context.Context, tenantID string) ([]*domain.ChannelIntegration, error) {
 	query := `
-		SELECT id, tenant_id, platform, provider, access_token, webhook_url, status, config, created_at, updated_at
+		SELECT ` + channelIntegrationColumns + `
 		FROM channel_integrations
 		WHERE tenant_id = $1 AND status = 'active'
 		ORDER BY platform, created_at DESC`
@@ -299,31 +449,17 @@ func (r *channelIntegrationRepository) GetActiveByTenant(ctx context.Context, te
 	var integrations []*domain.ChannelIntegration
 
 	for rows.Next() {
-		var integration domain.ChannelIntegration
-		var configJSON []byte
-
-		err := rows.Scan(
-			&integration.ID,
-			&integration.TenantID,
-			&integration.Platform,
-			&integration.Provider,
-			&integration.AccessToken,
-			&integration.WebhookURL,
-			&integration.Status,
-			&configJSON,
-			&integration.CreatedAt,
-			&integration.UpdatedAt,
-		)
+		integration, err := scanChannelIntegration(rows)
 
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan channel integration: %w", err)
 		}
 
-		if err := json.Unmarshal(configJSON, &integration.Config); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+		if err := openChannel(r.sealer, integration); err != nil {
+			return nil, err
 		}
 
-		integrations = append(integrations, &integration)
+		integrations = append(integrations, integration)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -333,7 +469,39 @@ func (r *channelIntegrationRepository) GetActiveByTenant(ctx context.Context, te
 	return integrations, nil
 }
 
+// scanChannelIntegration lee una fila con las columnas de channelIntegrationColumns
+func scanChannelIntegration(row rowScanner) (*domain.ChannelIntegration, error) {
+	var integration domain.ChannelIntegration
+	var configJSON []byte
+
+	err := row.Scan(
+		&integration.ID,
+		&integration.TenantID,
+		&integration.Platform,
+		&integration.Provider,
+		&integration.AccessToken,
+		&integration.WebhookURL,
+		&integration.Status,
+		&configJSON,
+		&integration.TokenExpiresAt,
+		&integration.TokenIssuedAt,
+		&integration.TokenLastValidatedAt,
+		&integration.TokenLastRotatedAt,
+		&integration.CreatedAt,
+		&integration.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(configJSON, &integration.Config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	return &integration, nil
+}
+
 // DB returns the database connection for direct queries
 func (r *channelIntegrationRepository) DB() *sql.DB {
 	return r.db.DB
-}
\ No newline at end of file
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"it-integration-service/internal/domain"
+)
+
+type conversationSessionRepository struct {
+	db *PostgresDB
+}
+
+// NewConversationSessionRepository creates a new conversation session repository
+func NewConversationSessionRepository(db *PostgresDB) domain.ConversationSessionRepository {
+	return &conversationSessionRepository{db: db}
+}
+
+const conversationSessionColumns = `channel_id, contact_id, tenant_id, platform, last_inbound_at,
+	window_expires_at, category, created_at, updated_at`
+
+func (r *conversationSessionRepository) RecordInbound(ctx context.Context, session *domain.ConversationSession) error {
+	// Una ventana vencida que se reabre vuelve a la categoría del contacto
+	query := `
+		INSERT INTO conversation_sessions (channel_id, contact_id, tenant_id, platform, last_inbound_at, window_expires_at, category, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (channel_id, contact_id) DO UPDATE
+		SET tenant_id = EXCLUDED.tenant_id,
+			category = CASE
+				WHEN conversation_sessions.window_expires_at <= EXCLUDED.last_inbound_at THEN EXCLUDED.category
+				ELSE conversation_sessions.category
+			END,
+			last_inbound_at = GREATEST(conversation_sessions.last_inbound_at, EXCLUDED.last_inbound_at),
+			window_expires_at = GREATEST(conversation_sessions.window_expires_at, EXCLUDED.window_expires_at),
+			updated_at = NOW()`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		session.ChannelID,
+		session.ContactID,
+		session.TenantID,
+		session.Platform,
+		session.LastInboundAt,
+		session.WindowExpiresAt,
+		session.Category,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record conversation session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *conversationSessionRepository) Get(ctx context.Context, channelID, contactID string) (*domain.ConversationSession, error) {
+	query := `SELECT ` + conversationSessionColumns + `
+		FROM conversation_sessions
+		WHERE channel_id = $1 AND contact_id = $2`
+
+	session, err := scanConversationSession(r.db.DB.QueryRowContext(ctx, query, channelID, contactID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("conversation session not found: %w", sql.ErrNoRows)
+		}
+		return nil, fmt.Errorf("failed to get conversation session: %w", err)
+	}
+
+	return session, nil
+}
+
+func (r *conversationSessionRepository) UpdateCategory(ctx context.Context, channelID, contactID, category string) error {
+	query := `
+		UPDATE conversation_sessions
+		SET category = $3, updated_at = NOW()
+		WHERE channel_id = $1 AND contact_id = $2`
+
+	if _, err := r.db.DB.ExecContext(ctx, query, channelID, contactID, category); err != nil {
+		return fmt.Errorf("failed to update conversation category: %w", err)
+	}
+
+	return nil
+}
+
+func (r *conversationSessionRepository) ListOpenByTenant(ctx context.Context, tenantID string, platform domain.Platform, limit, offset int) ([]*domain.ConversationSession, error) {
+	query := `SELECT ` + conversationSessionColumns + `
+		FROM conversation_sessions
+		WHERE tenant_id = $1 AND window_expires_at > NOW() AND ($2 = '' OR platform = $2)
+		ORDER BY window_expires_at ASC, channel_id, contact_id
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, tenantID, string(platform), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversation sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*domain.ConversationSession
+	for rows.Next() {
+		session, err := scanConversationSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversation session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func scanConversationSession(row rowScanner) (*domain.ConversationSession, error) {
+	var session domain.ConversationSession
+	err := row.Scan(
+		&session.ChannelID,
+		&session.ContactID,
+		&session.TenantID,
+		&session.Platform,
+		&session.LastInboundAt,
+		&session.WindowExpiresAt,
+		&session.Category,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
@@ -0,0 +1,368 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"it-integration-service/internal/domain"
+)
+
+// CredentialSealer cifra las credenciales antes de guardarlas y las descifra al leerlas.
+// Open debe retornar sin cambios los valores que no estén sellados, y Reseal sella el texto
+// plano y vuelve a sellar con la clave activa lo sellado con una clave anterior.
+type CredentialSealer interface {
+	Seal(plaintext string) (string, error)
+	Open(value string) (string, error)
+	Reseal(value string) (string, bool, error)
+	IsSealed(value string) bool
+}
+
+// CredentialKeyring agrega al sellador el descifrado de valores cifrados sin sobre, como los
+// tokens de Google Calendar guardados antes de versionar las claves
+type CredentialKeyring interface {
+	CredentialSealer
+	Decrypt(value string) (string, error)
+}
+
+// secretConfigFields son las claves de primer nivel del config de un canal que guardan secretos
+var secretConfigFields = map[string]bool{
+	"access_token":      true,
+	"api_key":           true,
+	"app_secret":        true,
+	"bot_token":         true,
+	"client_secret":     true,
+	"page_access_token": true,
+	"refresh_token":     true,
+	"verify_token":      true,
+	"webhook_secret":    true,
+}
+
+// sealChannel retorna el token y el config listos para guardar. No modifica la integración
+// para que quien llama siga trabajando con los valores en claro.
+func sealChannel(sealer CredentialSealer, integration *domain.ChannelIntegration) (string, []byte, error) {
+	configJSON, err := json.Marshal(integration.Config)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if sealer == nil {
+		return integration.AccessToken, configJSON, nil
+	}
+
+	token, _, err := sealToken(sealer, integration.AccessToken)
+	if err != nil {
+		return "", nil, err
+	}
+	configJSON, _, err = sealConfig(sealer, configJSON)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, configJSON, nil
+}
+
+// sealToken sella el token con la clave activa si aún no lo está
+func sealToken(sealer CredentialSealer, token string) (string, bool, error) {
+	if token == "" {
+		return token, false, nil
+	}
+	sealed, changed, err := sealer.Reseal(token)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to seal access token: %w", err)
+	}
+	return sealed, changed, nil
+}
+
+// sealConfig sella con la clave activa los campos secretos del config que aún no lo estén
+func sealConfig(sealer CredentialSealer, configJSON []byte) ([]byte, bool, error) {
+	sealed, changed, err := transformSecretFields(configJSON, sealer.Reseal)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to seal config secrets: %w", err)
+	}
+	return sealed, changed, nil
+}
+
+// openChannel descifra en el lugar el token y los secretos del config leídos de la base
+func openChannel(sealer CredentialSealer, integration *domain.ChannelIntegration) error {
+	if sealer == nil {
+		return nil
+	}
+
+	token, err := sealer.Open(integration.AccessToken)
+	if err != nil {
+		return fmt.Errorf("failed to open access token of channel %s: %w", integration.ID, err)
+	}
+	integration.AccessToken = token
+
+	configJSON, changed, err := transformSecretFields(integration.Config, func(value string) (string, bool, error) {
+		if !sealer.IsSealed(value) {
+			return value, false, nil
+		}
+		opened, err := sealer.Open(value)
+		return opened, true, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open config secrets of channel %s: %w", integration.ID, err)
+	}
+	if changed {
+		integration.Config = configJSON
+	}
+
+	return nil
+}
+
+// transformSecretFields aplica fn a los campos secretos de texto del config. Un config que no
+// es un objeto JSON se retorna sin cambios.
+func transformSecretFields(configJSON []byte, fn func(value string) (string, bool, error)) ([]byte, bool, error) {
+	if len(configJSON) == 0 {
+		return configJSON, false, nil
+	}
+
+	// UseNumber conserva los números grandes (IDs de páginas y cuentas) sin pasar por float64
+	var config map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(configJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&config); err != nil || config == nil {
+		return configJSON, false, nil
+	}
+
+	changed := false
+	for key, raw := range config {
+		value, ok := raw.(string)
+		if !ok || value == "" || !secretConfigFields[key] {
+			continue
+		}
+
+		transformed, fieldChanged, err := fn(value)
+		if err != nil {
+			return nil, false, fmt.Errorf("field %s: %w", key, err)
+		}
+		if fieldChanged {
+			config[key] = transformed
+			changed = true
+		}
+	}
+
+	if !changed {
+		return configJSON, false, nil
+	}
+
+	result, err := json.Marshal(config)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return result, true, nil
+}
+
+// SealReport resume una pasada de sellado sobre una tabla de credenciales
+type SealReport struct {
+	Table   string `json:"table"`
+	Scanned int    `json:"scanned"`
+	Sealed  int    `json:"sealed"`
+	Failed  int    `json:"failed"`
+	// LastError es el último error de una fila que no se pudo sellar
+	LastError string `json:"last_error,omitempty"`
+}
+
+// SealOptions configura una pasada de sellado
+type SealOptions struct {
+	BatchSize int
+	// DryRun solo cuenta las filas que se sellarían
+	DryRun bool
+	// OnBatch recibe el avance acumulado después de cada lote
+	OnBatch func(SealReport)
+}
+
+// storedCredentials son las columnas con credenciales de una fila, tal como están guardadas
+type storedCredentials struct {
+	id      string
+	columns []sql.NullString
+}
+
+// credentialTable describe una tabla con credenciales selladas
+type credentialTable struct {
+	name    string
+	columns []string
+	// jsonColumn es la columna con secretos dentro de un objeto JSON; vacía si no tiene
+	jsonColumn string
+}
+
+var (
+	channelCredentialTable = credentialTable{
+		name:       "channel_integrations",
+		columns:    []string{"access_token", "config"},
+		jsonColumn: "config",
+	}
+	calendarCredentialTable = credentialTable{
+		name:    "google_calendar_integrations",
+		columns: []string{"access_token", "refresh_token"},
+	}
+)
+
+// SealChannelCredentials cifra en el lugar las credenciales de channel_integrations que están en
+// texto plano o selladas con una clave anterior. Es idempotente: las filas ya selladas con la
+// clave activa no se modifican.
+func SealChannelCredentials(ctx context.Context, db *PostgresDB, sealer CredentialSealer, opts SealOptions) (*SealReport, error) {
+	if sealer == nil {
+		return nil, fmt.Errorf("credential sealer is required")
+	}
+
+	return sealTable(ctx, db, channelCredentialTable, opts, func(column, value string) (string, bool, error) {
+		if column == channelCredentialTable.jsonColumn {
+			sealed, changed, err := sealConfig(sealer, []byte(value))
+			return string(sealed), changed, err
+		}
+		return sealToken(sealer, value)
+	})
+}
+
+// SealCalendarCredentials vuelve a sellar con la clave activa los tokens de
+// google_calendar_integrations. Los tokens cifrados sin sobre se descifran con el keyring.
+func SealCalendarCredentials(ctx context.Context, db *PostgresDB, keyring CredentialKeyring, opts SealOptions) (*SealReport, error) {
+	if keyring == nil {
+		return nil, fmt.Errorf("credential keyring is required")
+	}
+
+	return sealTable(ctx, db, calendarCredentialTable, opts, func(column, value string) (string, bool, error) {
+		if value == "" {
+			return value, false, nil
+		}
+		if keyring.IsSealed(value) {
+			return keyring.Reseal(value)
+		}
+
+		// Estos tokens siempre se guardaron cifrados, nunca en texto plano
+		plaintext, err := keyring.Decrypt(value)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decrypt %s: %w", column, err)
+		}
+		sealed, err := keyring.Seal(plaintext)
+		return sealed, err == nil, err
+	})
+}
+
+// sealTable recorre la tabla por lotes ordenados por ID aplicando seal a cada columna no nula
+func sealTable(ctx context.Context, db *PostgresDB, table credentialTable, opts SealOptions, seal func(column, value string) (string, bool, error)) (*SealReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 200
+	}
+
+	report := &SealReport{Table: table.name}
+	lastID := ""
+
+	selectColumns := ""
+	setColumns := ""
+	unchangedConditions := ""
+	for i, column := range table.columns {
+		selectColumns += fmt.Sprintf(", %s::text", column)
+		if i > 0 {
+			setColumns += ", "
+		}
+		setColumns += fmt.Sprintf("%s = $%d", column, i+2)
+		unchangedConditions += fmt.Sprintf(" AND %s::text IS NOT DISTINCT FROM $%d", column, i+2+len(table.columns))
+	}
+
+	for {
+		rows, err := db.DB.QueryContext(ctx, fmt.Sprintf(`
+			SELECT id::text%s
+			FROM %s
+			WHERE id::text > $1
+			ORDER BY id::text
+			LIMIT $2`, selectColumns, table.name), lastID, opts.BatchSize)
+		if err != nil {
+			return report, fmt.Errorf("failed to query %s: %w", table.name, err)
+		}
+
+		var batch []storedCredentials
+		for rows.Next() {
+			stored := storedCredentials{columns: make([]sql.NullString, len(table.columns))}
+			dest := []interface{}{&stored.id}
+			for i := range stored.columns {
+				dest = append(dest, &stored.columns[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return report, fmt.Errorf("failed to scan %s row: %w", table.name, err)
+			}
+			batch = append(batch, stored)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return report, fmt.Errorf("error iterating rows: %w", err)
+		}
+
+		for _, stored := range batch {
+			report.Scanned++
+			lastID = stored.id
+
+			sealed, changed, err := sealRow(table, stored, seal)
+			if err != nil {
+				report.Failed++
+				report.LastError = fmt.Sprintf("%s %s: %v", table.name, stored.id, err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+
+			if opts.DryRun {
+				report.Sealed++
+				continue
+			}
+
+			// Solo se actualiza si la fila no cambió desde la lectura; si cambió, quien la
+			// escribió ya pasó por el repositorio y quedó sellada
+			args := []interface{}{stored.id}
+			for _, value := range sealed {
+				args = append(args, value)
+			}
+			for _, value := range stored.columns {
+				args = append(args, value)
+			}
+			result, err := db.DB.ExecContext(ctx, fmt.Sprintf(`
+				UPDATE %s
+				SET %s
+				WHERE id::text = $1%s`, table.name, setColumns, unchangedConditions), args...)
+			if err != nil {
+				return report, fmt.Errorf("failed to seal %s %s: %w", table.name, stored.id, err)
+			}
+			if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+				report.Sealed++
+			}
+		}
+
+		if opts.OnBatch != nil {
+			opts.OnBatch(*report)
+		}
+
+		if len(batch) < opts.BatchSize {
+			return report, nil
+		}
+	}
+}
+
+// sealRow aplica seal a las columnas de la fila; las columnas nulas se conservan nulas
+func sealRow(table credentialTable, stored storedCredentials, seal func(column, value string) (string, bool, error)) ([]sql.NullString, bool, error) {
+	sealed := make([]sql.NullString, len(stored.columns))
+	changed := false
+
+	for i, value := range stored.columns {
+		sealed[i] = value
+		if !value.Valid {
+			continue
+		}
+
+		result, columnChanged, err := seal(table.columns[i], value.String)
+		if err != nil {
+			return nil, false, err
+		}
+		if columnChanged {
+			sealed[i] = sql.NullString{String: result, Valid: true}
+			changed = true
+		}
+	}
+
+	return sealed, changed, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"it-integration-service/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseSealer sella invirtiendo el texto, suficiente para seguir los valores en las pruebas
+type reverseSealer struct{}
+
+func (reverseSealer) Seal(plaintext string) (string, error) {
+	return "enc:test:" + reverse(plaintext), nil
+}
+
+func (s reverseSealer) Open(value string) (string, error) {
+	if !s.IsSealed(value) {
+		return value, nil
+	}
+	return reverse(strings.TrimPrefix(value, "enc:test:")), nil
+}
+
+func (s reverseSealer) Reseal(value string) (string, bool, error) {
+	if s.IsSealed(value) {
+		return value, false, nil
+	}
+	sealed, err := s.Seal(value)
+	return sealed, true, err
+}
+
+func (reverseSealer) IsSealed(value string) bool {
+	return strings.HasPrefix(value, "enc:test:")
+}
+
+func reverse(value string) string {
+	runes := []rune(value)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+func TestSealChannelEncryptsTokenAndSecretFields(t *testing.T) {
+	integration := &domain.ChannelIntegration{
+		ID:          "channel-1",
+		AccessToken: "EAAG-token",
+		Config:      json.RawMessage(`{"api_key":"mc-key-us21","list_id":"a1b2c3","page_id":102290129340398}`),
+	}
+
+	token, configJSON, err := sealChannel(reverseSealer{}, integration)
+	require.NoError(t, err)
+
+	assert.Equal(t, "enc:test:nekot-GAAE", token)
+	assert.Equal(t, "EAAG-token", integration.AccessToken, "the caller keeps the plaintext token")
+
+	var stored map[string]interface{}
+	require.NoError(t, json.Unmarshal(configJSON, &stored))
+	assert.Equal(t, "enc:test:12su-yek-cm", stored["api_key"])
+	assert.Equal(t, "a1b2c3", stored["list_id"])
+	assert.Contains(t, string(configJSON), `"page_id":102290129340398`)
+
+	// Releer la fila devuelve los valores originales
+	read := &domain.ChannelIntegration{ID: "channel-1", AccessToken: token, Config: configJSON}
+	require.NoError(t, openChannel(reverseSealer{}, read))
+	assert.Equal(t, "EAAG-token", read.AccessToken)
+	assert.JSONEq(t, string(integration.Config), string(read.Config))
+}
+
+func TestSealChannelDoesNotSealTwice(t *testing.T) {
+	integration := &domain.ChannelIntegration{
+		AccessToken: "enc:test:nekot",
+		Config:      json.RawMessage(`{"bot_token":"enc:test:321"}`),
+	}
+
+	token, configJSON, err := sealChannel(reverseSealer{}, integration)
+	require.NoError(t, err)
+	assert.Equal(t, "enc:test:nekot", token)
+	assert.JSONEq(t, `{"bot_token":"enc:test:321"}`, string(configJSON))
+}
+
+func TestOpenChannelKeepsPlaintextRows(t *testing.T) {
+	integration := &domain.ChannelIntegration{
+		AccessToken: "legacy-token",
+		Config:      json.RawMessage(`{"api_key":"legacy-key"}`),
+	}
+
+	require.NoError(t, openChannel(reverseSealer{}, integration))
+	assert.Equal(t, "legacy-token", integration.AccessToken)
+	assert.JSONEq(t, `{"api_key":"legacy-key"}`, string(integration.Config))
+}
+
+func TestSealRowKeepsNullColumns(t *testing.T) {
+	stored := storedCredentials{
+		id: "integration-1",
+		columns: []sql.NullString{
+			{String: "ya29.token", Valid: true},
+			{},
+		},
+	}
+
+	sealed, changed, err := sealRow(calendarCredentialTable, stored, func(column, value string) (string, bool, error) {
+		return reverseSealer{}.Reseal(value)
+	})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, sql.NullString{String: "enc:test:nekot.92ay", Valid: true}, sealed[0])
+	assert.False(t, sealed[1].Valid)
+}
@@ -81,7 +81,7 @@ func (r *GoogleCalendarRepository) GetIntegration(ctx context.Context, channelID
 	query := `
 		SELECT id, tenant_id, channel_id, calendar_type, calendar_id, calendar_name,
 			   access_token, refresh_token, token_expiry, webhook_channel, webhook_resource,
-			   status, config, created_at, updated_at
+			   status, config, COALESCE(sync_token, ''), last_sync_at, created_at, updated_at
 		FROM google_calendar_integrations
 		WHERE channel_id = $1 AND deleted_at IS NULL
 	`
@@ -103,6 +103,8 @@ func (r *GoogleCalendarRepository) GetIntegration(ctx context.Context, channelID
 		&integration.WebhookResource,
 		&integration.Status,
 		&configJSON,
+		&integration.SyncToken,
+		&integration.LastSyncAt,
 		&integration.CreatedAt,
 		&integration.UpdatedAt,
 	)
@@ -130,7 +132,7 @@ func (r *GoogleCalendarRepository) GetIntegrationsByTenant(ctx context.Context,
 	query := `
 		SELECT id, tenant_id, channel_id, calendar_type, calendar_id, calendar_name,
 			   access_token, refresh_token, token_expiry, webhook_channel, webhook_resource,
-			   status, config, created_at, updated_at
+			   status, config, COALESCE(sync_token, ''), last_sync_at, created_at, updated_at
 		FROM google_calendar_integrations
 		WHERE tenant_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
@@ -162,6 +164,8 @@ func (r *GoogleCalendarRepository) GetIntegrationsByTenant(ctx context.Context,
 			&integration.WebhookResource,
 			&integration.Status,
 			&configJSON,
+			&integration.SyncToken,
+			&integration.LastSyncAt,
 			&integration.CreatedAt,
 			&integration.UpdatedAt,
 		)
@@ -240,6 +244,31 @@ func (r *GoogleCalendarRepository) UpdateIntegration(ctx context.Context, integr
 	return nil
 }
 
+// UpdateSyncState guarda el estado de sincronización sin tocar los tokens ni el resto de la integración
+func (r *GoogleCalendarRepository) UpdateSyncState(ctx context.Context, channelID, syncToken string, lastSyncAt time.Time) error {
+	query := `
+		UPDATE google_calendar_integrations
+		SET sync_token = NULLIF($1, ''), last_sync_at = $2
+		WHERE channel_id = $3 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, syncToken, lastSyncAt, channelID)
+	if err != nil {
+		return fmt.Errorf("error updating sync state: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("integration not found: %s", channelID)
+	}
+
+	return nil
+}
+
 // DeleteIntegration elimina una integración (soft delete)
 func (r *GoogleCalendarRepository) DeleteIntegration(ctx context.Context, channelID string) error {
 	query := `
@@ -377,7 +406,7 @@ func (r *GoogleCalendarRepository) GetEvent(ctx context.Context, eventID string)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("event not found: %s", eventID)
+			return nil, fmt.Errorf("event not found: %s: %w", eventID, sql.ErrNoRows)
 		}
 		return nil, fmt.Errorf("error getting event: %w", err)
 	}
@@ -390,6 +419,33 @@ func (r *GoogleCalendarRepository) GetEvent(ctx context.Context, eventID string)
 	return &event, nil
 }
 
+// GetEventByGoogleID obtiene el evento vigente de un canal por su ID en Google Calendar
+func (r *GoogleCalendarRepository) GetEventByGoogleID(ctx context.Context, channelID, googleID string) (*domain.CalendarEvent, error) {
+	query := `
+		SELECT id, tenant_id, channel_id, google_id, calendar_id, summary, description,
+			   location, start_time, end_time, all_day, attendees, recurrence, status,
+			   visibility, reminders, created_at, updated_at
+		FROM calendar_events
+		WHERE channel_id = $1 AND google_id = $2 AND deleted_at IS NULL
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, channelID, googleID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying event: %w", err)
+	}
+	defer rows.Close()
+
+	events, err := r.scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("event not found: %w", sql.ErrNoRows)
+	}
+
+	return events[0], nil
+}
+
 // GetEventsByChannel obtiene eventos por channel_id con paginación
 func (r *GoogleCalendarRepository) GetEventsByChannel(ctx context.Context, channelID string, limit, offset int) ([]*domain.CalendarEvent, error) {
 	query := `
@@ -2,9 +2,14 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"it-integration-service/internal/domain"
+
+	"github.com/lib/pq"
 )
 
 type inboundMessageRepository struct {
@@ -18,15 +23,24 @@ func NewInboundMessageRepository(db *PostgresDB) domain.InboundMessageRepository
 
 func (r *inboundMessageRepository) Create(ctx context.Context, message *domain.InboundMessage) error {
 	query := `
-		INSERT INTO inbound_messages (id, platform, payload, received_at, processed)
-		VALUES ($1, $2, $3, $4, $5)`
+		INSERT INTO inbound_messages (id, platform, tenant_id, channel_id, quarantine_reason, payload, received_at, processed, results)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9)`
+
+	resultsJSON, err := marshalInboundResults(message.Results)
+	if err != nil {
+		return err
+	}
 
-	_, err := r.db.DB.ExecContext(ctx, query,
+	_, err = r.db.DB.ExecContext(ctx, query,
 		message.ID,
 		message.Platform,
+		message.TenantID,
+		message.ChannelID,
+		message.QuarantineReason,
 		message.Payload,
 		message.ReceivedAt,
 		message.Processed,
+		resultsJSON,
 	)
 
 	if err != nil {
@@ -38,7 +52,7 @@ func (r *inboundMessageRepository) Create(ctx context.Context, message *domain.I
 
 func (r *inboundMessageRepository) GetUnprocessed(ctx context.Context, limit int) ([]*domain.InboundMessage, error) {
 	query := `
-		SELECT id, platform, payload, received_at, processed
+		SELECT ` + inboundMessageColumns + `
 		FROM inbound_messages
 		WHERE processed = false
 		ORDER BY received_at ASC
@@ -50,23 +64,148 @@ func (r *inboundMessageRepository) GetUnprocessed(ctx context.Context, limit int
 	}
 	defer rows.Close()
 
+	return scanInboundMessages(rows)
+}
+
+// List arma la consulta con los filtros informados; el orden por received_at e id permite
+// continuar la lectura desde el último mensaje con AfterReceivedAt y AfterID
+func (r *inboundMessageRepository) List(ctx context.Context, filter domain.InboundMessageFilter) ([]*domain.InboundMessage, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Platform != "" {
+		addCondition("platform = $%d", filter.Platform)
+	}
+	if filter.TenantID != "" {
+		addCondition("tenant_id = $%d", filter.TenantID)
+	}
+	if filter.ChannelID != "" {
+		addCondition("channel_id = $%d", filter.ChannelID)
+	}
+	if len(filter.IDs) > 0 {
+		addCondition("id = ANY($%d)", pq.Array(filter.IDs))
+	}
+	if filter.Sender != "" {
+		sender, err := json.Marshal([]map[string]string{{"sender": filter.Sender}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal sender filter: %w", err)
+		}
+		addCondition("results @> $%d::jsonb", string(sender))
+	}
+	if filter.From != nil {
+		addCondition("received_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("received_at < $%d", *filter.To)
+	}
+	if filter.Processed != nil {
+		addCondition("processed = $%d", *filter.Processed)
+	}
+
+	order, cursor := "ASC", ">"
+	if filter.Descending {
+		order, cursor = "DESC", "<"
+	}
+	if filter.AfterReceivedAt != nil {
+		args = append(args, *filter.AfterReceivedAt, filter.AfterID)
+		conditions = append(conditions, fmt.Sprintf("(received_at, id) %s ($%d, $%d)", cursor, len(args)-1, len(args)))
+	}
+
+	query := `SELECT ` + inboundMessageColumns + ` FROM inbound_messages`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY received_at %s, id %s LIMIT $%d`, order, order, len(args))
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inbound messages: %w", err)
+	}
+	defer rows.Close()
+
+	return scanInboundMessages(rows)
+}
+
+func (r *inboundMessageRepository) MarkAsProcessed(ctx context.Context, id string) error {
+	query := `UPDATE inbound_messages SET processed = true WHERE id = $1`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark message as processed: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("inbound message not found")
+	}
+
+	return nil
+}
+
+func (r *inboundMessageRepository) UpdateResults(ctx context.Context, id string, results []domain.InboundResult, processed bool) error {
+	query := `UPDATE inbound_messages SET results = $2, processed = $3 WHERE id = $1`
+
+	resultsJSON, err := marshalInboundResults(results)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, query, id, resultsJSON, processed)
+	if err != nil {
+		return fmt.Errorf("failed to update inbound message results: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("inbound message not found")
+	}
+
+	return nil
+}
+
+const inboundMessageColumns = `id, platform, COALESCE(tenant_id, ''), COALESCE(channel_id, ''),
+	COALESCE(quarantine_reason, ''), payload, received_at, processed, results`
+
+func scanInboundMessages(rows *sql.Rows) ([]*domain.InboundMessage, error) {
 	var messages []*domain.InboundMessage
 
 	for rows.Next() {
 		var message domain.InboundMessage
+		var resultsJSON []byte
 
 		err := rows.Scan(
 			&message.ID,
 			&message.Platform,
+			&message.TenantID,
+			&message.ChannelID,
+			&message.QuarantineReason,
 			&message.Payload,
 			&message.ReceivedAt,
 			&message.Processed,
+			&resultsJSON,
 		)
 
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan inbound message: %w", err)
 		}
 
+		if err := json.Unmarshal(resultsJSON, &message.Results); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal inbound message results: %w", err)
+		}
+
 		messages = append(messages, &message)
 	}
 
@@ -77,22 +216,14 @@ func (r *inboundMessageRepository) GetUnprocessed(ctx context.Context, limit int
 	return messages, nil
 }
 
-func (r *inboundMessageRepository) MarkAsProcessed(ctx context.Context, id string) error {
-	query := `UPDATE inbound_messages SET processed = true WHERE id = $1`
-
-	result, err := r.db.DB.ExecContext(ctx, query, id)
-	if err != nil {
-		return fmt.Errorf("failed to mark message as processed: %w", err)
+// marshalInboundResults serializa los resultados por mensaje; sin resultados se guarda un arreglo vacío
+func marshalInboundResults(results []domain.InboundResult) ([]byte, error) {
+	if results == nil {
+		results = []domain.InboundResult{}
 	}
-
-	rowsAffected, err := result.RowsAffected()
+	resultsJSON, err := json.Marshal(results)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, fmt.Errorf("failed to marshal inbound message results: %w", err)
 	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("inbound message not found")
-	}
-
-	return nil
-}
\ No newline at end of file
+	return resultsJSON, nil
+}
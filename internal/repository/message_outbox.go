@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/domain"
+)
+
+type messageOutboxRepository struct {
+	db *PostgresDB
+}
+
+// NewMessageOutboxRepository creates a new message outbox repository
+func NewMessageOutboxRepository(db *PostgresDB) domain.MessageOutboxRepository {
+	return &messageOutboxRepository{db: db}
+}
+
+func (r *messageOutboxRepository) EnqueueWithInbound(ctx context.Context, inbound *domain.InboundMessage, messages []*domain.OutboxMessage) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Los eventos se registran en la misma transacción que su entrega: si no se confirma, un
+	// reenvío del proveedor no se descarta como duplicado
+	enqueue := make([]*domain.OutboxMessage, 0, len(messages))
+	for _, message := range messages {
+		if message.DedupEvent != nil {
+			claimed, err := claimWebhookEvent(ctx, tx, message.DedupEvent)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				message.Duplicate = true
+				if message.ItemIndex < len(inbound.Results) {
+					inbound.Results[message.ItemIndex].Status = domain.InboundResultDuplicate
+				}
+				continue
+			}
+		}
+		enqueue = append(enqueue, message)
+	}
+	if len(messages) > 0 && len(enqueue) == 0 {
+		return nil
+	}
+
+	inboundQuery := `
+		INSERT INTO inbound_messages (id, platform, tenant_id, channel_id, payload, received_at, processed, results)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8)`
+
+	resultsJSON, err := marshalInboundResults(inbound.Results)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, inboundQuery,
+		inbound.ID,
+		inbound.Platform,
+		inbound.TenantID,
+		inbound.ChannelID,
+		inbound.Payload,
+		inbound.ReceivedAt,
+		inbound.Processed,
+		resultsJSON,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create inbound message: %w", err)
+	}
+
+	outboxQuery := `
+		INSERT INTO message_outbox (id, inbound_message_id, item_index, event_type, platform, message_id, idempotency_key, payload, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	for _, message := range enqueue {
+		_, err = tx.ExecContext(ctx, outboxQuery,
+			message.ID,
+			message.InboundMessageID,
+			message.ItemIndex,
+			message.EventType,
+			message.Platform,
+			message.MessageID,
+			message.IdempotencyKey,
+			message.Payload,
+			message.Status,
+			message.Attempts,
+			message.NextAttemptAt,
+			message.CreatedAt,
+			message.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue outbox message: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *messageOutboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	// Los mensajes en "processing" cuyo lease expiró se consideran abandonados
+	// (p. ej. la réplica que los tomó se reinició) y vuelven a reclamarse. Cada reclamo
+	// cuenta como intento, así un mensaje que tumba al worker llega igual a dead_letter.
+	query := `
+		UPDATE message_outbox
+		SET status = 'processing', attempts = attempts + 1, locked_until = NOW() + ($2 * INTERVAL '1 millisecond'), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM message_outbox
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'processing' AND locked_until < NOW())
+			ORDER BY next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, inbound_message_id, item_index, event_type, platform, message_id, COALESCE(idempotency_key, ''),
+			payload, status, attempts, next_attempt_at, COALESCE(last_error, ''), created_at, updated_at`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*domain.OutboxMessage
+
+	for rows.Next() {
+		var message domain.OutboxMessage
+
+		err := rows.Scan(
+			&message.ID,
+			&message.InboundMessageID,
+			&message.ItemIndex,
+			&message.EventType,
+			&message.Platform,
+			&message.MessageID,
+			&message.IdempotencyKey,
+			&message.Payload,
+			&message.Status,
+			&message.Attempts,
+			&message.NextAttemptAt,
+			&message.LastError,
+			&message.CreatedAt,
+			&message.UpdatedAt,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+
+		messages = append(messages, &message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *messageOutboxRepository) MarkDelivered(ctx context.Context, id string) error {
+	query := `
+		UPDATE message_outbox
+		SET status = 'delivered', locked_until = NULL, delivered_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'`
+
+	return r.transition(ctx, id, domain.InboundResultDelivered, query, id)
+}
+
+func (r *messageOutboxRepository) MarkRetry(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE message_outbox
+		SET status = 'pending', next_attempt_at = $2, last_error = $3, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'`
+
+	return r.transition(ctx, id, domain.InboundResultPending, query, id, nextAttemptAt, lastError)
+}
+
+func (r *messageOutboxRepository) MarkDeadLetter(ctx context.Context, id string, lastError string) error {
+	query := `
+		UPDATE message_outbox
+		SET status = 'dead_letter', last_error = $2, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'`
+
+	return r.transition(ctx, id, domain.InboundResultDeadLetter, query, id, lastError)
+}
+
+// transition aplica el cambio de estado a la entrada del outbox y refleja el resultado
+// en el registro del mensaje entrante dentro de la misma transacción
+func (r *messageOutboxRepository) transition(ctx context.Context, id string, resultStatus domain.InboundResultStatus, query string, args ...interface{}) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	// Sin filas el mensaje no existe o ya no está reclamado (otra réplica lo tomó tras vencer el lease)
+	if rowsAffected == 0 {
+		return fmt.Errorf("outbox message not claimed: %w", sql.ErrNoRows)
+	}
+
+	if err := updateInboundResult(ctx, tx, id, resultStatus); err != nil {
+		return err
+	}
+
+	// El mensaje entrante solo se marca como procesado cuando todas sus entradas fueron entregadas
+	if resultStatus == domain.InboundResultDelivered {
+		processedQuery := `
+			UPDATE inbound_messages SET processed = true
+			WHERE id = (SELECT inbound_message_id FROM message_outbox WHERE id = $1)
+			  AND NOT EXISTS (
+				SELECT 1 FROM message_outbox
+				WHERE inbound_message_id = inbound_messages.id AND status <> 'delivered'
+			  )`
+
+		if _, err := tx.ExecContext(ctx, processedQuery, id); err != nil {
+			return fmt.Errorf("failed to mark inbound message as processed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// updateInboundResult actualiza el resultado del mensaje en inbound_messages.results
+// a partir del estado actual de su entrada en el outbox
+func updateInboundResult(ctx context.Context, tx *sql.Tx, outboxID string, status domain.InboundResultStatus) error {
+	query := `
+		UPDATE inbound_messages im
+		SET results = jsonb_set(
+			im.results,
+			ARRAY[o.item_index::text],
+			((im.results -> o.item_index) - 'error') || jsonb_strip_nulls(jsonb_build_object(
+				'status', $2::text,
+				'attempts', o.attempts,
+				'error', CASE WHEN $2::text = 'delivered' THEN NULL ELSE o.last_error END,
+				'updated_at', NOW()
+			))
+		)
+		FROM message_outbox o
+		WHERE o.id = $1
+		  AND im.id = o.inbound_message_id
+		  AND jsonb_array_length(im.results) > o.item_index`
+
+	if _, err := tx.ExecContext(ctx, query, outboxID, string(status)); err != nil {
+		return fmt.Errorf("failed to update inbound message result: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+)
+
+// GoogleCalendarMigrations son las migraciones que crean y actualizan las tablas de Google Calendar, en orden
+var GoogleCalendarMigrations = []string{
+	"001_create_google_calendar_tables.sql",
+	"013_create_oauth_states.sql",
+	"014_add_google_calendar_sync_state.sql",
+	"015_create_calendar_watch_channels.sql",
+	"016_create_scheduled_notifications.sql",
+}
+
+// migrationLockID es la clave del advisory lock que serializa las migraciones entre réplicas
+const migrationLockID = 7314560021
+
+// ApplyMigrations ejecuta cada archivo de names que todavía no figura en schema_migrations. Cada
+// archivo corre en su propia transacción junto con su registro, así que uno que falla se reintenta
+// en el siguiente arranque. Retorna los nombres de los archivos aplicados en esta llamada.
+func ApplyMigrations(ctx context.Context, db *PostgresDB, migrations fs.FS, names []string) ([]string, error) {
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			name VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var applied []string
+	for _, name := range names {
+		var exists bool
+		err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE name = $1)`, name).Scan(&exists)
+		if err != nil {
+			return applied, fmt.Errorf("failed to check migration %s: %w", name, err)
+		}
+		if exists {
+			continue
+		}
+
+		script, err := fs.ReadFile(migrations, name)
+		if err != nil {
+			return applied, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		if err := applyMigration(ctx, conn, name, string(script)); err != nil {
+			return applied, err
+		}
+		applied = append(applied, name)
+	}
+
+	return applied, nil
+}
+
+// applyMigration ejecuta un script de migración y lo registra en la misma transacción
+func applyMigration(ctx context.Context, conn *sql.Conn, name, script string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", name, err)
+	}
+	defer tx.Rollback()
+
+	// Sin argumentos lib/pq usa el protocolo de consulta simple, que acepta varias sentencias
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("failed to apply migration %s: %w", name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (name) VALUES ($1)`, name); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", name, err)
+	}
+	return nil
+}
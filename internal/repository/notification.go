@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"it-integration-service/internal/domain"
+)
+
+type notificationRepository struct {
+	db     *PostgresDB
+	sealer CredentialSealer
+}
+
+// NewNotificationRepository creates a new notification repository. The webhook secrets of the
+// destinations are sealed with sealer; a nil sealer stores them as plaintext.
+func NewNotificationRepository(db *PostgresDB, sealer CredentialSealer) domain.NotificationRepository {
+	return &notificationRepository{db: db, sealer: sealer}
+}
+
+const notificationRecordColumns = `id, tenant_id, channel_id, platform, event_type, destination_type,
+	destination, status, error, dedup_key, token_expires_at, created_at`
+
+func (r *notificationRepository) GetSettings(ctx context.Context, tenantID string) (*domain.TenantNotificationSettings, error) {
+	query := `
+		SELECT tenant_id, enabled, destinations, created_at, updated_at
+		FROM tenant_notification_settings
+		WHERE tenant_id = $1`
+
+	var settings domain.TenantNotificationSettings
+	var destinationsJSON []byte
+	err := r.db.DB.QueryRowContext(ctx, query, tenantID).Scan(
+		&settings.TenantID,
+		&settings.Enabled,
+		&destinationsJSON,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("notification settings not found: %w", sql.ErrNoRows)
+		}
+		return nil, fmt.Errorf("failed to get notification settings: %w", err)
+	}
+
+	if err := json.Unmarshal(destinationsJSON, &settings.Destinations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notification destinations: %w", err)
+	}
+	if r.sealer != nil {
+		for i := range settings.Destinations {
+			secret, err := r.sealer.Open(settings.Destinations[i].Secret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open webhook secret of tenant %s: %w", tenantID, err)
+			}
+			settings.Destinations[i].Secret = secret
+		}
+	}
+
+	return &settings, nil
+}
+
+func (r *notificationRepository) UpsertSettings(ctx context.Context, settings *domain.TenantNotificationSettings) error {
+	// Se sella una copia para que quien llama siga trabajando con los secretos en claro
+	destinations := append([]domain.NotificationDestination(nil), settings.Destinations...)
+	if r.sealer != nil {
+		for i := range destinations {
+			if destinations[i].Secret == "" {
+				continue
+			}
+			sealed, _, err := r.sealer.Reseal(destinations[i].Secret)
+			if err != nil {
+				return fmt.Errorf("failed to seal webhook secret: %w", err)
+			}
+			destinations[i].Secret = sealed
+		}
+	}
+	if destinations == nil {
+		destinations = []domain.NotificationDestination{}
+	}
+
+	destinationsJSON, err := json.Marshal(destinations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification destinations: %w", err)
+	}
+
+	query := `
+		INSERT INTO tenant_notification_settings (tenant_id, enabled, destinations, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+			destinations = EXCLUDED.destinations,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at`
+
+	err = r.db.DB.QueryRowContext(ctx, query,
+		settings.TenantID,
+		settings.Enabled,
+		destinationsJSON,
+		settings.CreatedAt,
+		settings.UpdatedAt,
+	).Scan(&settings.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save notification settings: %w", err)
+	}
+
+	return nil
+}
+
+func (r *notificationRepository) WasSent(ctx context.Context, dedupKey string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM notification_history WHERE dedup_key = $1 AND status = 'sent')`
+
+	var sent bool
+	if err := r.db.DB.QueryRowContext(ctx, query, dedupKey).Scan(&sent); err != nil {
+		return false, fmt.Errorf("failed to check notification history: %w", err)
+	}
+
+	return sent, nil
+}
+
+func (r *notificationRepository) CreateRecord(ctx context.Context, record *domain.NotificationRecord) error {
+	query := `
+		INSERT INTO notification_history (` + notificationRecordColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		record.ID,
+		record.TenantID,
+		record.ChannelID,
+		record.Platform,
+		record.EventType,
+		record.DestinationType,
+		record.Destination,
+		record.Status,
+		sql.NullString{String: record.Error, Valid: record.Error != ""},
+		record.DedupKey,
+		record.TokenExpiresAt,
+		record.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create notification record: %w", err)
+	}
+
+	return nil
+}
+
+func (r *notificationRepository) ListRecords(ctx context.Context, tenantID, channelID string, limit, offset int) ([]*domain.NotificationRecord, error) {
+	query := `SELECT ` + notificationRecordColumns + `
+		FROM notification_history
+		WHERE tenant_id = $1 AND ($2 = '' OR channel_id = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, tenantID, channelID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification history: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*domain.NotificationRecord
+	for rows.Next() {
+		record, err := scanNotificationRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+func scanNotificationRecord(row rowScanner) (*domain.NotificationRecord, error) {
+	var record domain.NotificationRecord
+	var errorMessage sql.NullString
+	err := row.Scan(
+		&record.ID,
+		&record.TenantID,
+		&record.ChannelID,
+		&record.Platform,
+		&record.EventType,
+		&record.DestinationType,
+		&record.Destination,
+		&record.Status,
+		&errorMessage,
+		&record.DedupKey,
+		&record.TokenExpiresAt,
+		&record.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	record.Error = errorMessage.String
+	return &record, nil
+}
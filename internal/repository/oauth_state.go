@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/domain"
+)
+
+type oauthStateRepository struct {
+	db *PostgresDB
+}
+
+// NewOAuthStateRepository creates a new OAuth2 state repository
+func NewOAuthStateRepository(db *PostgresDB) domain.OAuthStateRepository {
+	return &oauthStateRepository{db: db}
+}
+
+func (r *oauthStateRepository) Create(ctx context.Context, state *domain.OAuthState) error {
+	query := `
+		INSERT INTO oauth_states (state_token, tenant_id, channel_id, calendar_type, code_verifier, return_url, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		state.StateToken,
+		state.TenantID,
+		state.ChannelID,
+		state.CalendarType,
+		state.CodeVerifier,
+		state.ReturnURL,
+		state.CreatedAt,
+		state.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth state: %w", err)
+	}
+
+	return nil
+}
+
+// Consume marca el state como usado en la misma sentencia que lo lee, de modo que dos callbacks
+// con el mismo state no puedan usarlo a la vez
+func (r *oauthStateRepository) Consume(ctx context.Context, stateToken string) (*domain.OAuthState, error) {
+	query := `
+		UPDATE oauth_states
+		SET consumed_at = NOW()
+		WHERE state_token = $1 AND consumed_at IS NULL
+		RETURNING state_token, tenant_id, channel_id, calendar_type, code_verifier,
+			COALESCE(return_url, ''), created_at, expires_at, consumed_at`
+
+	var state domain.OAuthState
+	err := r.db.DB.QueryRowContext(ctx, query, stateToken).Scan(
+		&state.StateToken,
+		&state.TenantID,
+		&state.ChannelID,
+		&state.CalendarType,
+		&state.CodeVerifier,
+		&state.ReturnURL,
+		&state.CreatedAt,
+		&state.ExpiresAt,
+		&state.ConsumedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("oauth state not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to consume oauth state: %w", err)
+	}
+
+	return &state, nil
+}
+
+func (r *oauthStateRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM oauth_states WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired oauth states: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"it-integration-service/internal/domain"
 )
@@ -18,8 +21,8 @@ func NewOutboundMessageLogRepository(db *PostgresDB) domain.OutboundMessageLogRe
 
 func (r *outboundMessageLogRepository) Create(ctx context.Context, log *domain.OutboundMessageLog) error {
 	query := `
-		INSERT INTO outbound_message_logs (id, channel_id, recipient, content, status, response, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		INSERT INTO outbound_message_logs (id, channel_id, recipient, content, status, response, timestamp, platform_message_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))`
 
 	_, err := r.db.DB.ExecContext(ctx, query,
 		log.ID,
@@ -29,6 +32,7 @@ func (r *outboundMessageLogRepository) Create(ctx context.Context, log *domain.O
 		log.Status,
 		log.Response,
 		log.Timestamp,
+		log.PlatformMessageID,
 	)
 
 	if err != nil {
@@ -40,7 +44,7 @@ func (r *outboundMessageLogRepository) Create(ctx context.Context, log *domain.O
 
 func (r *outboundMessageLogRepository) GetByChannelID(ctx context.Context, channelID string, limit, offset int) ([]*domain.OutboundMessageLog, error) {
 	query := `
-		SELECT id, channel_id, recipient, content, status, response, timestamp
+		SELECT id, channel_id, recipient, content, status, response, timestamp, COALESCE(platform_message_id, '')
 		FROM outbound_message_logs
 		WHERE channel_id = $1
 		ORDER BY timestamp DESC
@@ -65,6 +69,7 @@ func (r *outboundMessageLogRepository) GetByChannelID(ctx context.Context, chann
 			&log.Status,
 			&log.Response,
 			&log.Timestamp,
+			&log.PlatformMessageID,
 		)
 
 		if err != nil {
@@ -83,7 +88,7 @@ func (r *outboundMessageLogRepository) GetByChannelID(ctx context.Context, chann
 
 func (r *outboundMessageLogRepository) GetByStatus(ctx context.Context, status domain.MessageStatus, limit int) ([]*domain.OutboundMessageLog, error) {
 	query := `
-		SELECT id, channel_id, recipient, content, status, response, timestamp
+		SELECT id, channel_id, recipient, content, status, response, timestamp, COALESCE(platform_message_id, '')
 		FROM outbound_message_logs
 		WHERE status = $1
 		ORDER BY timestamp ASC
@@ -108,6 +113,7 @@ func (r *outboundMessageLogRepository) GetByStatus(ctx context.Context, status d
 			&log.Status,
 			&log.Response,
 			&log.Timestamp,
+			&log.PlatformMessageID,
 		)
 
 		if err != nil {
@@ -127,7 +133,7 @@ func (r *outboundMessageLogRepository) GetByStatus(ctx context.Context, status d
 func (r *outboundMessageLogRepository) UpdateStatus(ctx context.Context, id string, status domain.MessageStatus, response []byte) error {
 	query := `
 		UPDATE outbound_message_logs
-		SET status = $2, response = $3
+		SET status = $2, response = COALESCE($3, response)
 		WHERE id = $1`
 
 	result, err := r.db.DB.ExecContext(ctx, query, id, status, response)
@@ -145,4 +151,184 @@ func (r *outboundMessageLogRepository) UpdateStatus(ctx context.Context, id stri
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *outboundMessageLogRepository) UpdateSendResult(ctx context.Context, id string, status domain.MessageStatus, platformMessageID string, response []byte) error {
+	query := `
+		UPDATE outbound_message_logs
+		SET status = $2, platform_message_id = NULLIF($3, ''), response = COALESCE($4, response)
+		WHERE id = $1`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id, status, platformMessageID, response)
+	if err != nil {
+		return fmt.Errorf("failed to update outbound message log send result: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("outbound message log not found: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (r *outboundMessageLogRepository) GetByPlatformMessageID(ctx context.Context, platformMessageID string) (*domain.OutboundMessageLog, error) {
+	query := `
+		SELECT id, channel_id, recipient, content, status, response, timestamp, COALESCE(platform_message_id, '')
+		FROM outbound_message_logs
+		WHERE platform_message_id = $1
+		ORDER BY timestamp DESC
+		LIMIT 1`
+
+	var log domain.OutboundMessageLog
+
+	err := r.db.DB.QueryRowContext(ctx, query, platformMessageID).Scan(
+		&log.ID,
+		&log.ChannelID,
+		&log.Recipient,
+		&log.Content,
+		&log.Status,
+		&log.Response,
+		&log.Timestamp,
+		&log.PlatformMessageID,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("outbound message log not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get outbound message log: %w", err)
+	}
+
+	return &log, nil
+}
+
+// GetUnreadByRecipient obtiene los mensajes enviados a un destinatario hasta un instante que aún no
+// fueron leídos; Messenger confirma lecturas y entregas con un watermark en lugar de IDs
+func (r *outboundMessageLogRepository) GetUnreadByRecipient(ctx context.Context, channelID, recipient string, before time.Time) ([]*domain.OutboundMessageLog, error) {
+	query := `
+		SELECT id, channel_id, recipient, content, status, response, timestamp, COALESCE(platform_message_id, '')
+		FROM outbound_message_logs
+		WHERE channel_id = $1 AND recipient = $2 AND timestamp <= $3 AND status IN ('queued', 'sent', 'delivered')
+		ORDER BY timestamp ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, channelID, recipient, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unread outbound message logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*domain.OutboundMessageLog
+
+	for rows.Next() {
+		var log domain.OutboundMessageLog
+
+		err := rows.Scan(
+			&log.ID,
+			&log.ChannelID,
+			&log.Recipient,
+			&log.Content,
+			&log.Status,
+			&log.Response,
+			&log.Timestamp,
+			&log.PlatformMessageID,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbound message log: %w", err)
+		}
+
+		logs = append(logs, &log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return logs, nil
+}
+
+// List arma la consulta con los filtros informados; tenant y plataforma se filtran por el canal y
+// el orden por timestamp e id permite continuar la lectura con BeforeTimestamp y BeforeID
+func (r *outboundMessageLogRepository) List(ctx context.Context, filter domain.OutboundMessageFilter) ([]*domain.OutboundMessageLog, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ChannelID != "" {
+		addCondition("channel_id = $%d", filter.ChannelID)
+	}
+	if filter.TenantID != "" {
+		addCondition("channel_id IN (SELECT id FROM channel_integrations WHERE tenant_id = $%d)", filter.TenantID)
+	}
+	if filter.Platform != "" {
+		addCondition("channel_id IN (SELECT id FROM channel_integrations WHERE platform = $%d)", filter.Platform)
+	}
+	if filter.Status != "" {
+		addCondition("status = $%d", filter.Status)
+	}
+	if filter.Recipient != "" {
+		addCondition("recipient = $%d", filter.Recipient)
+	}
+	if filter.From != nil {
+		addCondition("timestamp >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("timestamp < $%d", *filter.To)
+	}
+	if filter.BeforeTimestamp != nil {
+		args = append(args, *filter.BeforeTimestamp, filter.BeforeID)
+		conditions = append(conditions, fmt.Sprintf("(timestamp, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := `
+		SELECT id, channel_id, recipient, content, status, response, timestamp, COALESCE(platform_message_id, '')
+		FROM outbound_message_logs`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY timestamp DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbound message logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*domain.OutboundMessageLog
+
+	for rows.Next() {
+		var log domain.OutboundMessageLog
+
+		err := rows.Scan(
+			&log.ID,
+			&log.ChannelID,
+			&log.Recipient,
+			&log.Content,
+			&log.Status,
+			&log.Response,
+			&log.Timestamp,
+			&log.PlatformMessageID,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbound message log: %w", err)
+		}
+
+		logs = append(logs, &log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return logs, nil
+}
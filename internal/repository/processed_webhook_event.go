@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"it-integration-service/internal/domain"
+)
+
+type processedWebhookEventRepository struct {
+	db *PostgresDB
+}
+
+// NewProcessedWebhookEventRepository creates a new processed webhook event repository
+func NewProcessedWebhookEventRepository(db *PostgresDB) domain.ProcessedWebhookEventRepository {
+	return &processedWebhookEventRepository{db: db}
+}
+
+// sqlExecer es lo que comparten *sql.DB y *sql.Tx para ejecutar una sentencia
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (r *processedWebhookEventRepository) Claim(ctx context.Context, event *domain.ProcessedWebhookEvent) (bool, error) {
+	return claimWebhookEvent(ctx, r.db.DB, event)
+}
+
+// claimWebhookEvent registra el evento si no existe o si su retención venció; retorna false si es
+// un duplicado. Dentro de una transacción, un reenvío concurrente espera a que esta termine.
+func claimWebhookEvent(ctx context.Context, db sqlExecer, event *domain.ProcessedWebhookEvent) (bool, error) {
+	// La restricción única serializa reenvíos concurrentes; un registro vencido se reutiliza
+	query := `
+		INSERT INTO processed_webhook_events (platform, channel_id, event_key, inbound_message_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (platform, channel_id, event_key) DO UPDATE
+		SET inbound_message_id = EXCLUDED.inbound_message_id,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE processed_webhook_events.expires_at <= NOW()`
+
+	result, err := db.ExecContext(ctx, query,
+		event.Platform,
+		event.ChannelID,
+		event.EventKey,
+		event.InboundMessageID,
+		event.CreatedAt,
+		event.ExpiresAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *processedWebhookEventRepository) Release(ctx context.Context, event *domain.ProcessedWebhookEvent) error {
+	query := `
+		DELETE FROM processed_webhook_events
+		WHERE platform = $1 AND channel_id = $2 AND event_key = $3 AND inbound_message_id = $4`
+
+	_, err := r.db.DB.ExecContext(ctx, query, event.Platform, event.ChannelID, event.EventKey, event.InboundMessageID)
+	if err != nil {
+		return fmt.Errorf("failed to release webhook event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *processedWebhookEventRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM processed_webhook_events WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired webhook events: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/domain"
+)
+
+type scheduledNotificationRepository struct {
+	db *PostgresDB
+}
+
+// NewScheduledNotificationRepository crea el repositorio de recordatorios programados de eventos
+func NewScheduledNotificationRepository(db *PostgresDB) domain.ScheduledNotificationRepository {
+	return &scheduledNotificationRepository{db: db}
+}
+
+const scheduledNotificationColumns = `id, event_id, tenant_id, channel_id, reminder_minutes, fire_at, status, attempts,
+	next_attempt_at, COALESCE(last_error, ''), created_at, updated_at, sent_at`
+
+func (r *scheduledNotificationRepository) Reschedule(ctx context.Context, eventID string, notifications []*domain.ScheduledNotification) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, cancelPendingQuery, eventID); err != nil {
+		return fmt.Errorf("failed to cancel scheduled notifications: %w", err)
+	}
+
+	// Un recordatorio cancelado arriba (o por un cambio anterior) vuelve a pendiente cuando se pide
+	// de nuevo su horario; uno ya enviado o en envío conserva su estado para dispararse una sola vez
+	query := `
+		INSERT INTO scheduled_notifications (id, event_id, tenant_id, channel_id, reminder_minutes, fire_at, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'pending', 0, $6, $7, $7)
+		ON CONFLICT (event_id, reminder_minutes, fire_at) DO UPDATE
+		SET status = 'pending', attempts = 0, next_attempt_at = EXCLUDED.next_attempt_at, locked_until = NULL,
+			last_error = NULL, updated_at = EXCLUDED.updated_at
+		WHERE scheduled_notifications.status = 'cancelled'`
+
+	for _, notification := range notifications {
+		_, err := tx.ExecContext(ctx, query,
+			notification.ID,
+			eventID,
+			notification.TenantID,
+			notification.ChannelID,
+			notification.ReminderMinutes,
+			notification.FireAt,
+			notification.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to schedule notification: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+const cancelPendingQuery = `
+	UPDATE scheduled_notifications
+	SET status = 'cancelled', updated_at = NOW()
+	WHERE event_id = $1 AND status = 'pending'`
+
+func (r *scheduledNotificationRepository) CancelByEvent(ctx context.Context, eventID string) (int64, error) {
+	result, err := r.db.DB.ExecContext(ctx, cancelPendingQuery, eventID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel scheduled notifications: %w", err)
+	}
+
+	cancelled, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return cancelled, nil
+}
+
+func (r *scheduledNotificationRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*domain.ScheduledNotification, error) {
+	// Los recordatorios en "processing" con la concesión vencida quedaron abandonados (por ejemplo,
+	// se reinició la réplica que los tomó) y se vuelven a tomar.
+	query := `
+		UPDATE scheduled_notifications
+		SET status = 'processing', attempts = attempts + 1, locked_until = NOW() + ($2 * INTERVAL '1 millisecond'), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM scheduled_notifications
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'processing' AND locked_until < NOW())
+			ORDER BY next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + scheduledNotificationColumns
+
+	rows, err := r.db.DB.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim scheduled notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*domain.ScheduledNotification
+	for rows.Next() {
+		notification, err := scanScheduledNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return notifications, nil
+}
+
+func (r *scheduledNotificationRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	query := `
+		UPDATE scheduled_notifications
+		SET status = 'sent', sent_at = $2, locked_until = NULL, last_error = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'`
+
+	return r.transition(ctx, query, id, sentAt)
+}
+
+func (r *scheduledNotificationRepository) MarkRetry(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE scheduled_notifications
+		SET status = 'pending', next_attempt_at = $2, last_error = $3, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'`
+
+	return r.transition(ctx, query, id, nextAttemptAt, lastError)
+}
+
+func (r *scheduledNotificationRepository) MarkFinished(ctx context.Context, id string, status domain.ScheduledNotificationStatus, reason string) error {
+	query := `
+		UPDATE scheduled_notifications
+		SET status = $2, last_error = NULLIF($3, ''), locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'`
+
+	return r.transition(ctx, query, id, string(status), reason)
+}
+
+// transition aplica un cambio de estado a un recordatorio ya tomado
+func (r *scheduledNotificationRepository) transition(ctx context.Context, query string, id string, args ...interface{}) error {
+	result, err := r.db.DB.ExecContext(ctx, query, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled notification: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("scheduled notification not claimed: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func scanScheduledNotification(row rowScanner) (*domain.ScheduledNotification, error) {
+	var notification domain.ScheduledNotification
+	err := row.Scan(
+		&notification.ID,
+		&notification.EventID,
+		&notification.TenantID,
+		&notification.ChannelID,
+		&notification.ReminderMinutes,
+		&notification.FireAt,
+		&notification.Status,
+		&notification.Attempts,
+		&notification.NextAttemptAt,
+		&notification.LastError,
+		&notification.CreatedAt,
+		&notification.UpdatedAt,
+		&notification.SentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
@@ -3,8 +3,6 @@ package routes
 import (
 	"it-integration-service/internal/config"
 	"it-integration-service/internal/handlers"
-	"it-integration-service/internal/middleware"
-	"it-integration-service/internal/repository"
 	"it-integration-service/internal/services"
 	"it-integration-service/pkg/logger"
 
@@ -16,28 +14,14 @@ func SetupGoogleCalendarRoutes(
 	router *gin.Engine,
 	cfg *config.Config,
 	logger logger.Logger,
-	googleCalendarRepo repository.GoogleCalendarRepository,
-	encryptionService *services.EncryptionService,
+	setupService *services.GoogleCalendarSetupService,
+	eventService *services.GoogleCalendarService,
+	notificationService *services.NotificationService,
 ) {
-	// Crear servicios
-	setupService := services.NewGoogleCalendarSetupService(
-		&cfg.GoogleCalendar,
-		googleCalendarRepo,
-		logger,
-		encryptionService,
-	)
-
-	eventService := services.NewGoogleCalendarService(
-		&cfg.GoogleCalendar,
-		setupService,
-		googleCalendarRepo,
-		logger,
-		encryptionService,
-	)
-
 	// Crear handlers
 	setupHandler := handlers.NewGoogleCalendarSetupHandler(setupService, &cfg.GoogleCalendar, logger)
 	eventsHandler := handlers.NewGoogleCalendarEventsHandler(eventService, &cfg.GoogleCalendar, logger)
+	webhookHandler := handlers.NewGoogleCalendarWebhookHandler(notificationService, eventService, &cfg.GoogleCalendar, logger)
 
 	// Grupo de rutas para Google Calendar
 	googleCalendar := router.Group("/api/v1/integrations/google-calendar")
@@ -69,7 +53,8 @@ func SetupGoogleCalendarRoutes(
 	// Webhook endpoint (fuera del grupo de integraciones)
 	webhooks := router.Group("/api/v1/webhooks")
 	{
-		webhooks.POST("/google-calendar", middleware.WebhookValidation(), eventsHandler.HandleWebhook)
+		// Google valida cada notificación con el token de su canal push
+		webhooks.POST("/google-calendar", webhookHandler.HandleWebhook)
 	}
 
 	logger.Info("Rutas de Google Calendar configuradas", map[string]interface{}{
@@ -83,29 +68,15 @@ func SetupGoogleCalendarRoutesWithAuth(
 	router *gin.Engine,
 	cfg *config.Config,
 	logger logger.Logger,
-	googleCalendarRepo repository.GoogleCalendarRepository,
-	encryptionService *services.EncryptionService,
+	setupService *services.GoogleCalendarSetupService,
+	eventService *services.GoogleCalendarService,
+	notificationService *services.NotificationService,
 	authMiddleware gin.HandlerFunc,
 ) {
-	// Crear servicios
-	setupService := services.NewGoogleCalendarSetupService(
-		&cfg.GoogleCalendar,
-		googleCalendarRepo,
-		logger,
-		encryptionService,
-	)
-
-	eventService := services.NewGoogleCalendarService(
-		&cfg.GoogleCalendar,
-		setupService,
-		googleCalendarRepo,
-		logger,
-		encryptionService,
-	)
-
 	// Crear handlers
 	setupHandler := handlers.NewGoogleCalendarSetupHandler(setupService, &cfg.GoogleCalendar, logger)
 	eventsHandler := handlers.NewGoogleCalendarEventsHandler(eventService, &cfg.GoogleCalendar, logger)
+	webhookHandler := handlers.NewGoogleCalendarWebhookHandler(notificationService, eventService, &cfg.GoogleCalendar, logger)
 
 	// Grupo de rutas para Google Calendar con autenticación
 	googleCalendar := router.Group("/api/v1/integrations/google-calendar")
@@ -138,7 +109,8 @@ func SetupGoogleCalendarRoutesWithAuth(
 	// Webhook endpoint (sin autenticación, solo validación de webhook)
 	webhooks := router.Group("/api/v1/webhooks")
 	{
-		webhooks.POST("/google-calendar", middleware.WebhookValidation(), eventsHandler.HandleWebhook)
+		// Google valida cada notificación con el token de su canal push
+		webhooks.POST("/google-calendar", webhookHandler.HandleWebhook)
 	}
 
 	logger.Info("Rutas de Google Calendar configuradas con autenticación", map[string]interface{}{
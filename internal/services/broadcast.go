@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/repository"
+	"it-integration-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrBroadcastNotFound indica que el envío masivo no existe
+	ErrBroadcastNotFound = errors.New("broadcast job not found")
+	// ErrBroadcastStateConflict indica que la operación no aplica al estado actual del envío
+	ErrBroadcastStateConflict = errors.New("broadcast job state does not allow this operation")
+	// ErrInvalidBroadcast indica que la solicitud de envío masivo no es válida
+	ErrInvalidBroadcast = errors.New("invalid broadcast request")
+)
+
+// broadcastInsertBatch es la cantidad de destinatarios insertados por consulta durante la carga
+const broadcastInsertBatch = 1000
+
+// BroadcastService gestiona los envíos masivos; el envío lo realiza BroadcastEngine en segundo plano
+type BroadcastService interface {
+	CreateBroadcast(ctx context.Context, req *domain.BroadcastMessageRequest) (*domain.BroadcastJob, error)
+	// CreateBroadcastFromCSV carga los destinatarios leyendo el CSV fila por fila, sin cargarlo en memoria
+	CreateBroadcastFromCSV(ctx context.Context, tenantID string, platforms []domain.Platform, content domain.MessageContent, recipients io.Reader) (*domain.BroadcastJob, error)
+	GetBroadcast(ctx context.Context, id string) (*domain.BroadcastJob, error)
+	GetProgress(ctx context.Context, id string) (*domain.BroadcastProgress, error)
+	GetResults(ctx context.Context, id string, status domain.BroadcastRecipientStatus, afterID int64, limit int) (*domain.BroadcastResult, error)
+	PauseBroadcast(ctx context.Context, id string) (*domain.BroadcastJob, error)
+	ResumeBroadcast(ctx context.Context, id string) (*domain.BroadcastJob, error)
+	CancelBroadcast(ctx context.Context, id string) (*domain.BroadcastJob, error)
+}
+
+type broadcastService struct {
+	broadcastRepo domain.BroadcastRepository
+	channelRepo   domain.ChannelIntegrationRepository
+	logger        logger.Logger
+}
+
+// NewBroadcastService crea una nueva instancia del servicio de envíos masivos
+func NewBroadcastService(broadcastRepo domain.BroadcastRepository, channelRepo domain.ChannelIntegrationRepository, logger logger.Logger) BroadcastService {
+	return &broadcastService{
+		broadcastRepo: broadcastRepo,
+		channelRepo:   channelRepo,
+		logger:        logger,
+	}
+}
+
+// broadcastRow es un destinatario leído de la carga; Platform vacío significa todas las plataformas del envío
+type broadcastRow struct {
+	Recipient string
+	Platform  string
+}
+
+func (s *broadcastService) CreateBroadcast(ctx context.Context, req *domain.BroadcastMessageRequest) (*domain.BroadcastJob, error) {
+	next := 0
+	return s.createBroadcast(ctx, req.TenantID, req.Platforms, req.Content, func() (*broadcastRow, error) {
+		if next >= len(req.Recipients) {
+			return nil, io.EOF
+		}
+		next++
+		return &broadcastRow{Recipient: req.Recipients[next-1]}, nil
+	})
+}
+
+func (s *broadcastService) CreateBroadcastFromCSV(ctx context.Context, tenantID string, platforms []domain.Platform, content domain.MessageContent, recipients io.Reader) (*domain.BroadcastJob, error) {
+	reader := csv.NewReader(recipients)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	// Columnas: recipient y, opcionalmente, platform. La fila de encabezado es opcional.
+	recipientCol, platformCol := 0, 1
+	first := true
+
+	return s.createBroadcast(ctx, tenantID, platforms, content, func() (*broadcastRow, error) {
+		for {
+			record, err := reader.Read()
+			if err != nil {
+				return nil, err
+			}
+
+			if first {
+				first = false
+				if header, ok := csvHeader(record); ok {
+					recipientCol, platformCol = header[0], header[1]
+					continue
+				}
+			}
+
+			row := &broadcastRow{}
+			if recipientCol < len(record) {
+				row.Recipient = record[recipientCol]
+			}
+			if platformCol >= 0 && platformCol < len(record) {
+				row.Platform = strings.ToLower(strings.TrimSpace(record[platformCol]))
+			}
+			return row, nil
+		}
+	})
+}
+
+// csvHeader detecta la fila de encabezado y retorna las posiciones de recipient y platform (-1 si falta)
+func csvHeader(record []string) ([2]int, bool) {
+	columns := [2]int{-1, -1}
+	for i, field := range record {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "recipient":
+			columns[0] = i
+		case "platform":
+			columns[1] = i
+		}
+	}
+	return columns, columns[0] >= 0
+}
+
+func (s *broadcastService) createBroadcast(ctx context.Context, tenantID string, platforms []domain.Platform, content domain.MessageContent, next func() (*broadcastRow, error)) (*domain.BroadcastJob, error) {
+	if tenantID == "" {
+		return nil, fmt.Errorf("%w: tenant_id is required", ErrInvalidBroadcast)
+	}
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("%w: at least one platform is required", ErrInvalidBroadcast)
+	}
+	if err := validateContent(&content); err != nil {
+		return nil, err
+	}
+
+	channels, err := s.resolveChannels(ctx, tenantID, platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &domain.BroadcastJob{
+		ID:        uuid.New().String(),
+		TenantID:  tenantID,
+		Platforms: platforms,
+		Channels:  channels,
+		Content:   content,
+		Status:    domain.BroadcastJobLoading,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.broadcastRepo.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	total, rejected, err := s.loadRecipients(ctx, job, next)
+	if err != nil {
+		// La carga incompleta no debe enviarse; se conserva el trabajo para diagnóstico
+		if failErr := s.broadcastRepo.FailJob(context.Background(), job.ID, err.Error()); failErr != nil {
+			s.logger.Error("Failed to mark broadcast job as failed", failErr)
+		}
+		return nil, err
+	}
+
+	if err := s.broadcastRepo.FinishLoading(ctx, job.ID, total, rejected); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Broadcast job queued", map[string]interface{}{
+		"job_id":     job.ID,
+		"tenant_id":  tenantID,
+		"platforms":  platforms,
+		"recipients": total,
+		"rejected":   rejected,
+	})
+
+	return s.broadcastRepo.GetJob(ctx, job.ID)
+}
+
+// resolveChannels elige el canal activo del tenant para cada plataforma destino
+func (s *broadcastService) resolveChannels(ctx context.Context, tenantID string, platforms []domain.Platform) (map[domain.Platform]string, error) {
+	channels := make(map[domain.Platform]string, len(platforms))
+	for _, platform := range platforms {
+		if _, ok := channels[platform]; ok {
+			continue
+		}
+
+		channel, err := s.channelRepo.GetByPlatformAndTenant(ctx, platform, tenantID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNoActiveChannel) {
+				return nil, fmt.Errorf("%w: tenant has no active %s channel", ErrInvalidBroadcast, platform)
+			}
+			return nil, fmt.Errorf("failed to get %s channel: %w", platform, err)
+		}
+		channels[platform] = channel.ID
+	}
+	return channels, nil
+}
+
+// loadRecipients inserta los destinatarios en lotes y retorna cuántos se agregaron y cuántos se descartaron
+func (s *broadcastService) loadRecipients(ctx context.Context, job *domain.BroadcastJob, next func() (*broadcastRow, error)) (int, int, error) {
+	total, rejected := 0, 0
+	batch := make([]*domain.BroadcastRecipient, 0, broadcastInsertBatch)
+
+	flush := func() error {
+		inserted, err := s.broadcastRepo.AddRecipients(ctx, batch)
+		if err != nil {
+			return err
+		}
+		total += inserted
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: failed to read recipients: %v", ErrInvalidBroadcast, err)
+		}
+
+		recipient := strings.TrimSpace(row.Recipient)
+		if recipient == "" {
+			rejected++
+			continue
+		}
+
+		platforms := job.Platforms
+		if row.Platform != "" {
+			platforms = []domain.Platform{domain.Platform(row.Platform)}
+		}
+
+		for _, platform := range platforms {
+			channelID, ok := job.Channels[platform]
+			if !ok {
+				rejected++
+				continue
+			}
+
+			batch = append(batch, &domain.BroadcastRecipient{
+				JobID:     job.ID,
+				Platform:  platform,
+				ChannelID: channelID,
+				Recipient: recipient,
+			})
+			if len(batch) == broadcastInsertBatch {
+				if err := flush(); err != nil {
+					return 0, 0, err
+				}
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return 0, 0, err
+	}
+
+	return total, rejected, nil
+}
+
+func (s *broadcastService) GetBroadcast(ctx context.Context, id string) (*domain.BroadcastJob, error) {
+	job, err := s.broadcastRepo.GetJob(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBroadcastNotFound
+	}
+	return job, err
+}
+
+func (s *broadcastService) GetProgress(ctx context.Context, id string) (*domain.BroadcastProgress, error) {
+	progress, err := s.broadcastRepo.GetProgress(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBroadcastNotFound
+	}
+	return progress, err
+}
+
+func (s *broadcastService) GetResults(ctx context.Context, id string, status domain.BroadcastRecipientStatus, afterID int64, limit int) (*domain.BroadcastResult, error) {
+	progress, err := s.GetProgress(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients, err := s.broadcastRepo.ListRecipients(ctx, id, status, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.BroadcastResult{
+		TotalSent:   progress.Sent,
+		TotalFailed: progress.Failed,
+		Results:     make([]domain.BroadcastItemResult, 0, len(recipients)),
+	}
+	for _, recipient := range recipients {
+		result.Results = append(result.Results, domain.BroadcastItemResult{
+			Platform:  recipient.Platform,
+			Recipient: recipient.Recipient,
+			Success:   recipient.Status == domain.BroadcastRecipientSent,
+			Error:     recipient.Error,
+			MessageID: recipient.MessageID,
+		})
+	}
+	if len(recipients) == limit {
+		result.NextAfterID = recipients[len(recipients)-1].ID
+	}
+
+	return result, nil
+}
+
+func (s *broadcastService) PauseBroadcast(ctx context.Context, id string) (*domain.BroadcastJob, error) {
+	return s.transition(ctx, id, []domain.BroadcastJobStatus{domain.BroadcastJobQueued, domain.BroadcastJobRunning}, domain.BroadcastJobPaused)
+}
+
+func (s *broadcastService) ResumeBroadcast(ctx context.Context, id string) (*domain.BroadcastJob, error) {
+	return s.transition(ctx, id, []domain.BroadcastJobStatus{domain.BroadcastJobPaused}, domain.BroadcastJobRunning)
+}
+
+func (s *broadcastService) CancelBroadcast(ctx context.Context, id string) (*domain.BroadcastJob, error) {
+	job, err := s.transition(ctx, id, []domain.BroadcastJobStatus{
+		domain.BroadcastJobQueued,
+		domain.BroadcastJobRunning,
+		domain.BroadcastJobPaused,
+	}, domain.BroadcastJobCancelled)
+	if err != nil {
+		return nil, err
+	}
+
+	// Los destinatarios del lote en curso terminan de enviarse; el resto no sale
+	cancelled, err := s.broadcastRepo.CancelPendingRecipients(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Broadcast job cancelled", map[string]interface{}{
+		"job_id":    id,
+		"cancelled": cancelled,
+	})
+
+	return job, nil
+}
+
+// transition aplica un cambio de estado solicitado por el usuario
+func (s *broadcastService) transition(ctx context.Context, id string, from []domain.BroadcastJobStatus, to domain.BroadcastJobStatus) (*domain.BroadcastJob, error) {
+	applied, err := s.broadcastRepo.TransitionJob(ctx, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.GetBroadcast(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		return job, fmt.Errorf("%w: job is %s", ErrBroadcastStateConflict, job.Status)
+	}
+
+	s.logger.Info("Broadcast job status changed", map[string]interface{}{
+		"job_id": id,
+		"status": to,
+	})
+
+	return job, nil
+}
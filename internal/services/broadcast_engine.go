@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// broadcastActiveJobsLimit es la cantidad de trabajos atendidos por ronda
+	broadcastActiveJobsLimit = 50
+	// broadcastStatusCheckEvery indica cada cuántos envíos se revisa si el trabajo fue pausado o cancelado
+	broadcastStatusCheckEvery = 50
+)
+
+// BroadcastEngine envía en segundo plano los destinatarios de los envíos masivos, respetando
+// el límite de mensajes por segundo de cada canal. Cada canal lo atiende una sola réplica a la
+// vez, la que tiene su lease, así el límite vale para todo el servicio.
+type BroadcastEngine struct {
+	broadcastRepo domain.BroadcastRepository
+	sendService   MessageSendService
+	config        config.BroadcastConfig
+	logger        logger.Logger
+	// holder identifica a esta réplica en los leases de canales
+	holder string
+
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter
+}
+
+// NewBroadcastEngine crea una nueva instancia del motor de envíos masivos
+func NewBroadcastEngine(broadcastRepo domain.BroadcastRepository, sendService MessageSendService, cfg config.BroadcastConfig, logger logger.Logger) *BroadcastEngine {
+	return &BroadcastEngine{
+		broadcastRepo: broadcastRepo,
+		sendService:   sendService,
+		config:        cfg,
+		logger:        logger,
+		holder:        uuid.New().String(),
+		limiters:      make(map[string]*rate.Limiter),
+	}
+}
+
+// Start inicia el loop de envío hasta que se cancele el contexto
+func (e *BroadcastEngine) Start(ctx context.Context) {
+	if !e.config.Enabled {
+		e.logger.Info("Broadcast engine is disabled")
+		return
+	}
+
+	go e.run(ctx)
+
+	e.logger.Info("Broadcast engine started", map[string]interface{}{
+		"poll_interval": e.config.PollInterval,
+		"batch_size":    e.config.BatchSize,
+		"workers":       e.config.Workers,
+	})
+}
+
+// run ejecuta el polling de trabajos activos
+func (e *BroadcastEngine) run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.logger.Info("Broadcast engine stopped")
+			return
+		case <-ticker.C:
+			// Se atiende un lote por trabajo en cada ronda para que un envío grande no bloquee a los demás
+			for {
+				claimed, err := e.ProcessRound(ctx)
+				if err != nil {
+					e.logger.Error("Failed to process broadcast round", err)
+					break
+				}
+				if claimed == 0 || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// ProcessRound procesa un lote de cada trabajo activo, retornando cuántos destinatarios se reclamaron
+func (e *BroadcastEngine) ProcessRound(ctx context.Context) (int, error) {
+	jobs, err := e.broadcastRepo.GetActiveJobs(ctx, broadcastActiveJobsLimit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get active broadcast jobs: %w", err)
+	}
+
+	total := 0
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		claimed, err := e.ProcessBatch(ctx, job)
+		if err != nil {
+			e.logger.Error("Failed to process broadcast batch", map[string]interface{}{
+				"job_id": job.ID,
+				"error":  err.Error(),
+			})
+			continue
+		}
+		total += claimed
+	}
+
+	return total, nil
+}
+
+// ProcessBatch reclama y envía un lote de destinatarios del trabajo
+func (e *BroadcastEngine) ProcessBatch(ctx context.Context, job *domain.BroadcastJob) (int, error) {
+	if job.Status == domain.BroadcastJobQueued {
+		started, err := e.broadcastRepo.TransitionJob(ctx, job.ID, []domain.BroadcastJobStatus{domain.BroadcastJobQueued}, domain.BroadcastJobRunning)
+		if err != nil {
+			return 0, err
+		}
+		if !started {
+			// Pausado o cancelado entre la consulta y el inicio
+			return 0, nil
+		}
+		e.logger.Info("Broadcast job started", map[string]interface{}{"job_id": job.ID})
+	}
+
+	// Solo se envía por los canales que esta réplica tiene; los de otra se saltean hasta que los libere
+	channels, err := e.broadcastRepo.AcquireChannels(ctx, e.holder, jobChannelIDs(job), e.config.LeaseDuration)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire broadcast channels: %w", err)
+	}
+	if len(channels) == 0 {
+		return 0, nil
+	}
+
+	recipients, err := e.broadcastRepo.ClaimRecipients(ctx, job.ID, channels, e.config.BatchSize, e.config.LeaseDuration)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim broadcast recipients: %w", err)
+	}
+
+	if len(recipients) == 0 {
+		// Sin destinatarios del trabajo en estos canales otra réplica puede tomarlos para sus trabajos
+		if err := e.broadcastRepo.ReleaseChannels(ctx, e.holder, channels); err != nil {
+			e.logger.Error("Failed to release broadcast channels", map[string]interface{}{
+				"job_id": job.ID,
+				"error":  err.Error(),
+			})
+		}
+
+		completed, err := e.broadcastRepo.CompleteJobIfDone(ctx, job.ID)
+		if err != nil {
+			return 0, err
+		}
+		if completed {
+			e.logger.Info("Broadcast job completed", map[string]interface{}{"job_id": job.ID})
+		}
+		return 0, nil
+	}
+
+	e.sendBatch(ctx, job, recipients)
+	return len(recipients), nil
+}
+
+// sendBatch reparte el lote entre los workers. Si el trabajo deja de estar en ejecución,
+// los destinatarios que aún no salieron vuelven a pendiente.
+func (e *BroadcastEngine) sendBatch(ctx context.Context, job *domain.BroadcastJob, recipients []*domain.BroadcastRecipient) {
+	queue := make(chan *domain.BroadcastRecipient)
+	var unsentMu sync.Mutex
+	var unsent []int64
+
+	workers := e.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for recipient := range queue {
+				if !e.send(ctx, job, recipient) {
+					unsentMu.Lock()
+					unsent = append(unsent, recipient.ID)
+					unsentMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	fed := 0
+	for i, recipient := range recipients {
+		if i > 0 && i%broadcastStatusCheckEvery == 0 && !e.stillRunning(ctx, job.ID) {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- recipient
+		fed++
+	}
+	close(queue)
+	wg.Wait()
+
+	for _, recipient := range recipients[fed:] {
+		unsent = append(unsent, recipient.ID)
+	}
+
+	if len(unsent) > 0 {
+		// Con el contexto cancelado se usa uno nuevo para no dejar los destinatarios reservados hasta que venza el lease
+		if err := e.broadcastRepo.ReleaseRecipients(context.Background(), unsent); err != nil {
+			e.logger.Error("Failed to release broadcast recipients", map[string]interface{}{
+				"job_id": job.ID,
+				"count":  len(unsent),
+				"error":  err.Error(),
+			})
+		}
+	}
+}
+
+// stillRunning indica si el trabajo sigue en ejecución
+func (e *BroadcastEngine) stillRunning(ctx context.Context, jobID string) bool {
+	job, err := e.broadcastRepo.GetJob(ctx, jobID)
+	if err != nil {
+		e.logger.Error("Failed to check broadcast job status", map[string]interface{}{
+			"job_id": jobID,
+			"error":  err.Error(),
+		})
+		return false
+	}
+	return job.Status == domain.BroadcastJobRunning
+}
+
+// send envía un destinatario y registra el resultado; retorna false si no llegó a enviarse
+func (e *BroadcastEngine) send(ctx context.Context, job *domain.BroadcastJob, recipient *domain.BroadcastRecipient) bool {
+	if err := e.limiter(recipient).Wait(ctx); err != nil {
+		return false
+	}
+
+	log, err := e.sendService.SendMessage(ctx, &domain.SendMessageRequest{
+		ChannelID: recipient.ChannelID,
+		Recipient: recipient.Recipient,
+		Content:   job.Content,
+	})
+	if err != nil && log == nil && ctx.Err() != nil {
+		return false
+	}
+
+	if log != nil {
+		recipient.OutboundLogID = log.ID
+		recipient.MessageID = log.PlatformMessageID
+	}
+	if err != nil {
+		recipient.Status = domain.BroadcastRecipientFailed
+		recipient.Error = err.Error()
+	} else {
+		recipient.Status = domain.BroadcastRecipientSent
+		recipient.Error = ""
+	}
+
+	if err := e.broadcastRepo.MarkRecipientResult(context.Background(), recipient); err != nil {
+		e.logger.Error("Failed to record broadcast result", map[string]interface{}{
+			"job_id":       job.ID,
+			"recipient_id": recipient.ID,
+			"error":        err.Error(),
+		})
+	}
+	return true
+}
+
+// jobChannelIDs retorna los canales del trabajo sin repetir
+func jobChannelIDs(job *domain.BroadcastJob) []string {
+	seen := make(map[string]bool, len(job.Channels))
+	channelIDs := make([]string, 0, len(job.Channels))
+	for _, channelID := range job.Channels {
+		if !seen[channelID] {
+			seen[channelID] = true
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	return channelIDs
+}
+
+// limiter retorna el limitador del canal del destinatario, compartido entre workers y trabajos.
+// Vive en memoria: alcanza porque el lease del canal impide que otra réplica envíe a la vez.
+func (e *BroadcastEngine) limiter(recipient *domain.BroadcastRecipient) *rate.Limiter {
+	e.limitersMu.Lock()
+	defer e.limitersMu.Unlock()
+
+	if limiter, ok := e.limiters[recipient.ChannelID]; ok {
+		return limiter
+	}
+
+	perSecond := e.config.RateLimits[string(recipient.Platform)]
+	limit := rate.Inf
+	burst := 1
+	if perSecond > 0 {
+		limit = rate.Limit(perSecond)
+		burst = perSecond
+	}
+
+	limiter := rate.NewLimiter(limit, burst)
+	// Arranca sin ráfaga: la réplica que tenía el canal antes pudo haberla usado en el último segundo
+	limiter.AllowN(time.Now(), burst)
+	e.limiters[recipient.ChannelID] = limiter
+	return limiter
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/repository"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBroadcastRepository guarda en memoria lo necesario para probar la carga de destinatarios
+type memoryBroadcastRepository struct {
+	domain.BroadcastRepository
+	jobs       map[string]*domain.BroadcastJob
+	recipients []*domain.BroadcastRecipient
+	seen       map[string]bool
+}
+
+func newMemoryBroadcastRepository() *memoryBroadcastRepository {
+	return &memoryBroadcastRepository{
+		jobs: make(map[string]*domain.BroadcastJob),
+		seen: make(map[string]bool),
+	}
+}
+
+func (r *memoryBroadcastRepository) CreateJob(ctx context.Context, job *domain.BroadcastJob) error {
+	r.jobs[job.ID] = job
+	return nil
+}
+
+func (r *memoryBroadcastRepository) GetJob(ctx context.Context, id string) (*domain.BroadcastJob, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return job, nil
+}
+
+func (r *memoryBroadcastRepository) AddRecipients(ctx context.Context, recipients []*domain.BroadcastRecipient) (int, error) {
+	inserted := 0
+	for _, recipient := range recipients {
+		key := recipient.JobID + ":" + string(recipient.Platform) + ":" + recipient.Recipient
+		if r.seen[key] {
+			continue
+		}
+		r.seen[key] = true
+		r.recipients = append(r.recipients, recipient)
+		inserted++
+	}
+	return inserted, nil
+}
+
+func (r *memoryBroadcastRepository) FinishLoading(ctx context.Context, id string, total, rejected int) error {
+	job := r.jobs[id]
+	job.Status = domain.BroadcastJobQueued
+	job.TotalRecipients = total
+	job.RejectedRecipients = rejected
+	return nil
+}
+
+func (r *memoryBroadcastRepository) FailJob(ctx context.Context, id string, reason string) error {
+	r.jobs[id].Status = domain.BroadcastJobFailed
+	r.jobs[id].Error = reason
+	return nil
+}
+
+// tenantChannels resuelve un canal fijo por plataforma
+type tenantChannels struct {
+	domain.ChannelIntegrationRepository
+	channels map[domain.Platform]string
+}
+
+func (r *tenantChannels) GetByPlatformAndTenant(ctx context.Context, platform domain.Platform, tenantID string) (*domain.ChannelIntegration, error) {
+	id, ok := r.channels[platform]
+	if !ok {
+		return nil, fmt.Errorf("%w for platform %s and tenant %s", repository.ErrNoActiveChannel, platform, tenantID)
+	}
+	return &domain.ChannelIntegration{ID: id, Platform: platform, TenantID: tenantID, Status: domain.StatusActive}, nil
+}
+
+func TestCreateBroadcastFromCSV(t *testing.T) {
+	repo := newMemoryBroadcastRepository()
+	channels := &tenantChannels{channels: map[domain.Platform]string{
+		domain.PlatformWhatsApp: "channel-wa",
+		domain.PlatformTelegram: "channel-tg",
+	}}
+	service := NewBroadcastService(repo, channels, logger.NewLogger("error"))
+
+	csvData := strings.Join([]string{
+		"platform,recipient",
+		"whatsapp,5491155550000",
+		"telegram,99",
+		",5491155550001",
+		"whatsapp,5491155550000",
+		"messenger,1146720579373962",
+		"whatsapp,",
+	}, "\n")
+
+	job, err := service.CreateBroadcastFromCSV(context.Background(), "tenant-1",
+		[]domain.Platform{domain.PlatformWhatsApp, domain.PlatformTelegram},
+		domain.MessageContent{Type: domain.ContentTypeText, Text: "Promo"},
+		strings.NewReader(csvData))
+	require.NoError(t, err)
+
+	assert.Equal(t, domain.BroadcastJobQueued, job.Status)
+	// La fila sin plataforma va a ambas; el repetido se ignora
+	assert.Equal(t, 4, job.TotalRecipients)
+	// messenger no es destino y la fila sin destinatario se descarta
+	assert.Equal(t, 2, job.RejectedRecipients)
+
+	var got []string
+	for _, recipient := range repo.recipients {
+		got = append(got, recipient.ChannelID+":"+recipient.Recipient)
+	}
+	assert.Equal(t, []string{
+		"channel-wa:5491155550000",
+		"channel-tg:99",
+		"channel-wa:5491155550001",
+		"channel-tg:5491155550001",
+	}, got)
+}
+
+func TestCreateBroadcastRequiresChannelPerPlatform(t *testing.T) {
+	repo := newMemoryBroadcastRepository()
+	channels := &tenantChannels{channels: map[domain.Platform]string{domain.PlatformWhatsApp: "channel-wa"}}
+	service := NewBroadcastService(repo, channels, logger.NewLogger("error"))
+
+	_, err := service.CreateBroadcast(context.Background(), &domain.BroadcastMessageRequest{
+		TenantID:   "tenant-1",
+		Platforms:  []domain.Platform{domain.PlatformWhatsApp, domain.PlatformInstagram},
+		Recipients: []string{"5491155550000"},
+		Content:    domain.MessageContent{Type: domain.ContentTypeText, Text: "Promo"},
+	})
+
+	assert.True(t, errors.Is(err, ErrInvalidBroadcast))
+	assert.Empty(t, repo.jobs)
+}
+
+// leasedBroadcastRepository emula los leases de canales compartidos entre réplicas
+type leasedBroadcastRepository struct {
+	domain.BroadcastRepository
+	leases  map[string]string
+	claimed [][]string
+}
+
+func (r *leasedBroadcastRepository) AcquireChannels(ctx context.Context, holder string, channelIDs []string, lease time.Duration) ([]string, error) {
+	var acquired []string
+	for _, channelID := range channelIDs {
+		if current, ok := r.leases[channelID]; ok && current != holder {
+			continue
+		}
+		r.leases[channelID] = holder
+		acquired = append(acquired, channelID)
+	}
+	return acquired, nil
+}
+
+func (r *leasedBroadcastRepository) ReleaseChannels(ctx context.Context, holder string, channelIDs []string) error {
+	for _, channelID := range channelIDs {
+		if r.leases[channelID] == holder {
+			delete(r.leases, channelID)
+		}
+	}
+	return nil
+}
+
+func (r *leasedBroadcastRepository) ClaimRecipients(ctx context.Context, jobID string, channelIDs []string, limit int, lease time.Duration) ([]*domain.BroadcastRecipient, error) {
+	r.claimed = append(r.claimed, channelIDs)
+	return nil, nil
+}
+
+func (r *leasedBroadcastRepository) CompleteJobIfDone(ctx context.Context, id string) (bool, error) {
+	return false, nil
+}
+
+func TestBroadcastChannelIsSentByOneReplicaAtATime(t *testing.T) {
+	repo := &leasedBroadcastRepository{leases: make(map[string]string)}
+	cfg := config.BroadcastConfig{BatchSize: 10, LeaseDuration: time.Minute}
+	log := logger.NewLogger("error")
+	first := NewBroadcastEngine(repo, nil, cfg, log)
+	second := NewBroadcastEngine(repo, nil, cfg, log)
+
+	job := &domain.BroadcastJob{
+		ID:       "job-1",
+		Status:   domain.BroadcastJobRunning,
+		Channels: map[domain.Platform]string{domain.PlatformWhatsApp: "channel-1", domain.PlatformTelegram: "channel-2"},
+	}
+
+	// Mientras la primera réplica tiene el canal, la segunda no reclama destinatarios en él
+	repo.leases["channel-1"] = first.holder
+	_, err := second.ProcessBatch(context.Background(), job)
+	require.NoError(t, err)
+	require.Len(t, repo.claimed, 1)
+	assert.Equal(t, []string{"channel-2"}, repo.claimed[0])
+
+	// Sin destinatarios pendientes la réplica libera sus canales y otra puede tomarlos
+	_, err = first.ProcessBatch(context.Background(), job)
+	require.NoError(t, err)
+	assert.NotContains(t, repo.leases, "channel-1")
+
+	_, err = second.ProcessBatch(context.Background(), job)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"channel-1", "channel-2"}, repo.claimed[len(repo.claimed)-1])
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+)
+
+// defaultChannelHealthHistory es la cantidad de verificaciones que retorna el historial de un canal
+const defaultChannelHealthHistory = 20
+
+// ChannelProber verifica periódicamente la conectividad de los canales activos, guarda cada
+// resultado y pasa a error los canales que fallan FailureThreshold veces seguidas
+type ChannelProber struct {
+	channelRepo domain.ChannelIntegrationRepository
+	healthRepo  domain.ChannelHealthCheckRepository
+	probes      map[domain.Platform]ChannelProbe
+	config      config.ChannelProbeConfig
+	logger      logger.Logger
+	now         func() time.Time
+}
+
+// NewChannelProber crea una nueva instancia del verificador de canales
+func NewChannelProber(channelRepo domain.ChannelIntegrationRepository, healthRepo domain.ChannelHealthCheckRepository, probes []ChannelProbe, cfg config.ChannelProbeConfig, logger logger.Logger) *ChannelProber {
+	byPlatform := make(map[domain.Platform]ChannelProbe)
+	for _, probe := range probes {
+		for _, platform := range probe.Platforms() {
+			byPlatform[platform] = probe
+		}
+	}
+
+	return &ChannelProber{
+		channelRepo: channelRepo,
+		healthRepo:  healthRepo,
+		probes:      byPlatform,
+		config:      cfg,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Start inicia las verificaciones periódicas hasta que se cancele el contexto
+func (p *ChannelProber) Start(ctx context.Context) {
+	if !p.config.Enabled || p.config.Interval <= 0 {
+		p.logger.Info("Channel prober is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := p.ProbeAll(ctx); err != nil {
+					p.logger.Error("Failed to probe channels", err)
+				}
+				p.pruneHistory(ctx)
+			}
+		}
+	}()
+
+	p.logger.Info("Channel prober started", map[string]interface{}{
+		"interval":          p.config.Interval,
+		"failure_threshold": p.config.FailureThreshold,
+	})
+}
+
+// ProbeAll verifica los canales activos de las plataformas con verificación y los que el propio
+// verificador pasó a error, para reactivarlos cuando se recuperan. Retorna cuántos verificó.
+func (p *ChannelProber) ProbeAll(ctx context.Context) (int, error) {
+	probed := 0
+	for platform := range p.probes {
+		channels, err := p.channelRepo.GetByPlatform(ctx, platform)
+		if err != nil {
+			return probed, fmt.Errorf("failed to get %s channels: %w", platform, err)
+		}
+
+		for _, channel := range channels {
+			if ctx.Err() != nil {
+				return probed, ctx.Err()
+			}
+			if channel.Status != domain.StatusActive && channel.Status != domain.StatusError {
+				continue
+			}
+
+			previous, err := p.latestCheck(ctx, channel.ID)
+			if err != nil {
+				p.logger.Error("Failed to get last channel health check", map[string]interface{}{
+					"channel_id": channel.ID,
+					"error":      err.Error(),
+				})
+				continue
+			}
+			if channel.Status == domain.StatusError && !p.deactivatedByProber(previous) {
+				continue
+			}
+
+			if _, err := p.probeChannel(ctx, channel, previous); err != nil {
+				p.logger.Error("Failed to record channel health check", map[string]interface{}{
+					"channel_id": channel.ID,
+					"error":      err.Error(),
+				})
+				continue
+			}
+			probed++
+		}
+	}
+
+	return probed, nil
+}
+
+// deactivatedByProber indica si la última verificación alcanzó el umbral de fallos; los canales
+// en error por otros motivos (por ejemplo, un token vencido) no se reactivan desde aquí
+func (p *ChannelProber) deactivatedByProber(previous *domain.ChannelHealthCheck) bool {
+	return previous != nil && !previous.Healthy && previous.ConsecutiveFailures >= p.failureThreshold()
+}
+
+// probeChannel verifica un canal, guarda el resultado y actualiza su estado según el umbral
+func (p *ChannelProber) probeChannel(ctx context.Context, channel *domain.ChannelIntegration, previous *domain.ChannelHealthCheck) (*domain.ChannelHealthCheck, error) {
+	probeCtx := ctx
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		probeCtx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+
+	started := p.now()
+	details, probeErr := p.probes[channel.Platform].Probe(probeCtx, channel)
+
+	check := &domain.ChannelHealthCheck{
+		ChannelID: channel.ID,
+		TenantID:  channel.TenantID,
+		Platform:  channel.Platform,
+		Healthy:   probeErr == nil,
+		LatencyMs: p.now().Sub(started).Milliseconds(),
+		CheckedAt: p.now().UTC(),
+	}
+	if details != nil {
+		if raw, err := json.Marshal(details); err == nil {
+			check.Details = raw
+		}
+	}
+	if probeErr != nil {
+		check.Error = probeErr.Error()
+		check.ConsecutiveFailures = 1
+		if previous != nil && !previous.Healthy {
+			check.ConsecutiveFailures = previous.ConsecutiveFailures + 1
+		}
+	}
+
+	if err := p.healthRepo.Create(ctx, check); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !check.Healthy && check.ConsecutiveFailures >= p.failureThreshold():
+		p.updateStatus(ctx, channel, domain.StatusActive, domain.StatusError, check)
+	case check.Healthy && p.deactivatedByProber(previous):
+		p.updateStatus(ctx, channel, domain.StatusError, domain.StatusActive, check)
+	case !check.Healthy:
+		p.logger.Warn("Channel health check failed", map[string]interface{}{
+			"channel_id":           channel.ID,
+			"platform":             channel.Platform,
+			"consecutive_failures": check.ConsecutiveFailures,
+			"error":                check.Error,
+		})
+	}
+
+	return check, nil
+}
+
+// updateStatus cambia el estado del canal si sigue en from
+func (p *ChannelProber) updateStatus(ctx context.Context, channel *domain.ChannelIntegration, from, to domain.IntegrationStatus, check *domain.ChannelHealthCheck) {
+	if channel.Status != from {
+		return
+	}
+
+	updated, err := p.channelRepo.UpdateStatus(ctx, channel.ID, from, to)
+	if err != nil {
+		p.logger.Error("Failed to update channel status", map[string]interface{}{
+			"channel_id": channel.ID,
+			"status":     to,
+			"error":      err.Error(),
+		})
+		return
+	}
+	if !updated {
+		return
+	}
+	channel.Status = to
+
+	fields := map[string]interface{}{
+		"channel_id": channel.ID,
+		"platform":   channel.Platform,
+		"tenant_id":  channel.TenantID,
+	}
+	if to == domain.StatusError {
+		fields["consecutive_failures"] = check.ConsecutiveFailures
+		fields["error"] = check.Error
+		p.logger.Warn("Channel deactivated after consecutive failed health checks", fields)
+		return
+	}
+	p.logger.Info("Channel reactivated after successful health check", fields)
+}
+
+// pruneHistory elimina las verificaciones fuera del período de retención
+func (p *ChannelProber) pruneHistory(ctx context.Context) {
+	if p.config.Retention <= 0 {
+		return
+	}
+
+	deleted, err := p.healthRepo.DeleteOlderThan(ctx, p.now().Add(-p.config.Retention))
+	if err != nil {
+		p.logger.Error("Failed to delete old channel health checks", err)
+		return
+	}
+	if deleted > 0 {
+		p.logger.Info("Old channel health checks deleted", map[string]interface{}{
+			"deleted": deleted,
+		})
+	}
+}
+
+// GetChannelHealth retorna el estado de conectividad del canal con sus últimas limit verificaciones
+func (p *ChannelProber) GetChannelHealth(ctx context.Context, channelID string, limit int) (*domain.ChannelHealth, error) {
+	channel, err := p.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, fmt.Errorf("failed to get channel: %w", err)
+	}
+
+	if limit <= 0 {
+		limit = defaultChannelHealthHistory
+	}
+	history, err := p.healthRepo.ListByChannel(ctx, channelID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &domain.ChannelHealth{
+		ChannelID: channel.ID,
+		TenantID:  channel.TenantID,
+		Platform:  channel.Platform,
+		Status:    channel.Status,
+		History:   history,
+	}
+	if health.History == nil {
+		health.History = []*domain.ChannelHealthCheck{}
+	}
+	if len(history) == 0 {
+		return health, nil
+	}
+
+	latest := history[0]
+	health.Healthy = latest.Healthy
+	health.ConsecutiveFailures = latest.ConsecutiveFailures
+	health.LastError = latest.Error
+	health.LastCheckedAt = &latest.CheckedAt
+	if latest.Healthy {
+		health.LastSuccessAt = &latest.CheckedAt
+		return health, nil
+	}
+
+	lastSuccess, err := p.healthRepo.GetLastSuccess(ctx, channelID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if lastSuccess != nil {
+		health.LastSuccessAt = &lastSuccess.CheckedAt
+	}
+
+	return health, nil
+}
+
+// latestCheck retorna la última verificación del canal o nil si nunca se verificó
+func (p *ChannelProber) latestCheck(ctx context.Context, channelID string) (*domain.ChannelHealthCheck, error) {
+	checks, err := p.healthRepo.ListByChannel(ctx, channelID, 1)
+	if err != nil || len(checks) == 0 {
+		return nil, err
+	}
+	return checks[0], nil
+}
+
+func (p *ChannelProber) failureThreshold() int {
+	if p.config.FailureThreshold <= 0 {
+		return 1
+	}
+	return p.config.FailureThreshold
+}
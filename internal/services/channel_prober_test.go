@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryProbeChannelRepository guarda los canales por ID y aplica los cambios de estado
+type memoryProbeChannelRepository struct {
+	domain.ChannelIntegrationRepository
+	channels map[string]*domain.ChannelIntegration
+}
+
+func (r *memoryProbeChannelRepository) GetByID(ctx context.Context, id string) (*domain.ChannelIntegration, error) {
+	channel, ok := r.channels[id]
+	if !ok {
+		return nil, fmt.Errorf("channel integration not found: %w", sql.ErrNoRows)
+	}
+	copied := *channel
+	return &copied, nil
+}
+
+func (r *memoryProbeChannelRepository) GetByPlatform(ctx context.Context, platform domain.Platform) ([]*domain.ChannelIntegration, error) {
+	var result []*domain.ChannelIntegration
+	for _, channel := range r.channels {
+		if channel.Platform == platform {
+			copied := *channel
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryProbeChannelRepository) UpdateStatus(ctx context.Context, id string, from, to domain.IntegrationStatus) (bool, error) {
+	channel, ok := r.channels[id]
+	if !ok || channel.Status != from {
+		return false, nil
+	}
+	channel.Status = to
+	return true, nil
+}
+
+// memoryChannelHealthRepository guarda las verificaciones en orden de creación
+type memoryChannelHealthRepository struct {
+	checks []*domain.ChannelHealthCheck
+}
+
+func (r *memoryChannelHealthRepository) Create(ctx context.Context, check *domain.ChannelHealthCheck) error {
+	check.ID = int64(len(r.checks) + 1)
+	r.checks = append(r.checks, check)
+	return nil
+}
+
+func (r *memoryChannelHealthRepository) ListByChannel(ctx context.Context, channelID string, limit int) ([]*domain.ChannelHealthCheck, error) {
+	var result []*domain.ChannelHealthCheck
+	for _, check := range r.checks {
+		if check.ChannelID == channelID {
+			result = append(result, check)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].ID > result[j].ID })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *memoryChannelHealthRepository) GetLastSuccess(ctx context.Context, channelID string) (*domain.ChannelHealthCheck, error) {
+	checks, _ := r.ListByChannel(ctx, channelID, len(r.checks))
+	for _, check := range checks {
+		if check.Healthy {
+			return check, nil
+		}
+	}
+	return nil, fmt.Errorf("channel health check not found: %w", sql.ErrNoRows)
+}
+
+func (r *memoryChannelHealthRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	var kept []*domain.ChannelHealthCheck
+	for _, check := range r.checks {
+		if !check.CheckedAt.Before(before) {
+			kept = append(kept, check)
+		}
+	}
+	deleted := int64(len(r.checks) - len(kept))
+	r.checks = kept
+	return deleted, nil
+}
+
+// fakeChannelProbe falla para los canales marcados como caídos
+type fakeChannelProbe struct {
+	down  map[string]bool
+	calls map[string]int
+}
+
+func (p *fakeChannelProbe) Platforms() []domain.Platform {
+	return []domain.Platform{domain.PlatformTelegram}
+}
+
+func (p *fakeChannelProbe) Probe(ctx context.Context, channel *domain.ChannelIntegration) (interface{}, error) {
+	p.calls[channel.ID]++
+	if p.down[channel.ID] {
+		return map[string]string{"url": ""}, errors.New("telegram webhook is not set")
+	}
+	return map[string]string{"url": "https://example.com/webhook"}, nil
+}
+
+func newTestChannelProber(channels ...*domain.ChannelIntegration) (*ChannelProber, *memoryProbeChannelRepository, *memoryChannelHealthRepository, *fakeChannelProbe) {
+	channelRepo := &memoryProbeChannelRepository{channels: make(map[string]*domain.ChannelIntegration)}
+	for _, channel := range channels {
+		channelRepo.channels[channel.ID] = channel
+	}
+	healthRepo := &memoryChannelHealthRepository{}
+	probe := &fakeChannelProbe{down: make(map[string]bool), calls: make(map[string]int)}
+
+	prober := NewChannelProber(channelRepo, healthRepo, []ChannelProbe{probe}, config.ChannelProbeConfig{
+		Enabled:          true,
+		Interval:         15 * time.Minute,
+		FailureThreshold: 3,
+		Retention:        24 * time.Hour,
+	}, logger.NewLogger("error"))
+	return prober, channelRepo, healthRepo, probe
+}
+
+func TestChannelProberDeactivatesAfterConsecutiveFailures(t *testing.T) {
+	prober, channelRepo, healthRepo, probe := newTestChannelProber(
+		&domain.ChannelIntegration{ID: "tg-1", TenantID: "tenant-1", Platform: domain.PlatformTelegram, Status: domain.StatusActive},
+		&domain.ChannelIntegration{ID: "tg-2", TenantID: "tenant-1", Platform: domain.PlatformTelegram, Status: domain.StatusDisabled},
+	)
+	probe.down["tg-1"] = true
+
+	for i := 1; i <= 2; i++ {
+		probed, err := prober.ProbeAll(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, probed)
+		assert.Equal(t, domain.StatusActive, channelRepo.channels["tg-1"].Status)
+	}
+
+	_, err := prober.ProbeAll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusError, channelRepo.channels["tg-1"].Status)
+
+	// Los canales deshabilitados no se verifican
+	assert.Zero(t, probe.calls["tg-2"])
+
+	require.Len(t, healthRepo.checks, 3)
+	last := healthRepo.checks[2]
+	assert.False(t, last.Healthy)
+	assert.Equal(t, 3, last.ConsecutiveFailures)
+	assert.Equal(t, "telegram webhook is not set", last.Error)
+	assert.JSONEq(t, `{"url":""}`, string(last.Details))
+}
+
+func TestChannelProberReactivatesRecoveredChannel(t *testing.T) {
+	prober, channelRepo, _, probe := newTestChannelProber(
+		&domain.ChannelIntegration{ID: "tg-1", TenantID: "tenant-1", Platform: domain.PlatformTelegram, Status: domain.StatusActive},
+	)
+	probe.down["tg-1"] = true
+	for i := 0; i < 3; i++ {
+		_, err := prober.ProbeAll(context.Background())
+		require.NoError(t, err)
+	}
+	require.Equal(t, domain.StatusError, channelRepo.channels["tg-1"].Status)
+
+	// El canal en error sigue verificándose y vuelve a activarse al recuperarse
+	probe.down["tg-1"] = false
+	_, err := prober.ProbeAll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusActive, channelRepo.channels["tg-1"].Status)
+	assert.Equal(t, 4, probe.calls["tg-1"])
+}
+
+func TestChannelProberSkipsChannelsInErrorForOtherReasons(t *testing.T) {
+	// Un canal pasado a error por la rotación de tokens no lo reactiva el verificador
+	prober, channelRepo, healthRepo, probe := newTestChannelProber(
+		&domain.ChannelIntegration{ID: "tg-1", TenantID: "tenant-1", Platform: domain.PlatformTelegram, Status: domain.StatusError},
+	)
+
+	probed, err := prober.ProbeAll(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, probed)
+	assert.Zero(t, probe.calls["tg-1"])
+	assert.Empty(t, healthRepo.checks)
+	assert.Equal(t, domain.StatusError, channelRepo.channels["tg-1"].Status)
+}
+
+func TestGetChannelHealthSummarizesHistory(t *testing.T) {
+	prober, _, healthRepo, probe := newTestChannelProber(
+		&domain.ChannelIntegration{ID: "tg-1", TenantID: "tenant-1", Platform: domain.PlatformTelegram, Status: domain.StatusActive},
+	)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	prober.now = func() time.Time { return now }
+
+	_, err := prober.ProbeAll(context.Background())
+	require.NoError(t, err)
+	firstCheck := now
+
+	probe.down["tg-1"] = true
+	for i := 0; i < 2; i++ {
+		now = now.Add(15 * time.Minute)
+		_, err := prober.ProbeAll(context.Background())
+		require.NoError(t, err)
+	}
+
+	health, err := prober.GetChannelHealth(context.Background(), "tg-1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusActive, health.Status)
+	assert.False(t, health.Healthy)
+	assert.Equal(t, 2, health.ConsecutiveFailures)
+	assert.Equal(t, "telegram webhook is not set", health.LastError)
+	assert.Equal(t, now, *health.LastCheckedAt)
+	assert.Equal(t, firstCheck, *health.LastSuccessAt)
+	require.Len(t, health.History, 2)
+	assert.Equal(t, now, health.History[0].CheckedAt)
+
+	// La limpieza elimina las verificaciones fuera de la retención
+	now = firstCheck.Add(24*time.Hour + time.Minute)
+	prober.pruneHistory(context.Background())
+	assert.Len(t, healthRepo.checks, 2)
+
+	_, err = prober.GetChannelHealth(context.Background(), "missing", 0)
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+}
+
+func TestEvaluateTelegramWebhook(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-15 * time.Minute)
+
+	healthy := &TelegramWebhookInfo{URL: "https://example.com/webhook", PendingUpdateCount: 3}
+	assert.NoError(t, evaluateTelegramWebhook(healthy, since))
+
+	// Un error anterior a la última verificación ya no cuenta
+	oldError := &TelegramWebhookInfo{URL: "https://example.com/webhook", LastErrorDate: now.Add(-time.Hour).Unix(), LastErrorMessage: "Connection timed out"}
+	assert.NoError(t, evaluateTelegramWebhook(oldError, since))
+
+	recentError := &TelegramWebhookInfo{URL: "https://example.com/webhook", LastErrorDate: now.Add(-time.Minute).Unix(), LastErrorMessage: "Wrong response from the webhook: 502 Bad Gateway"}
+	assert.EqualError(t, evaluateTelegramWebhook(recentError, since), "telegram webhook delivery failed: Wrong response from the webhook: 502 Bad Gateway")
+
+	assert.EqualError(t, evaluateTelegramWebhook(&TelegramWebhookInfo{}, since), "telegram webhook is not set")
+
+	backlog := &TelegramWebhookInfo{URL: "https://example.com/webhook", PendingUpdateCount: 250}
+	assert.EqualError(t, evaluateTelegramWebhook(backlog, since), "telegram webhook has 250 pending updates")
+}
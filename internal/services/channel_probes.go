@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+)
+
+// telegramMaxPendingUpdates es la cantidad de updates sin entregar a partir de la cual el webhook
+// de Telegram se considera atascado
+const telegramMaxPendingUpdates = 100
+
+// ChannelProbe verifica la conectividad de los canales de las plataformas que atiende
+type ChannelProbe interface {
+	Platforms() []domain.Platform
+	// Probe consulta a la plataforma por el canal. Retorna lo informado por la plataforma, aun
+	// cuando el canal no funciona, y un error que describe el fallo.
+	Probe(ctx context.Context, channel *domain.ChannelIntegration) (interface{}, error)
+}
+
+// DefaultChannelProbes retorna las verificaciones de las plataformas soportadas, usando los
+// servicios de setup de cada una
+func DefaultChannelProbes(cfg config.ChannelProbeConfig, logger logger.Logger) []ChannelProbe {
+	return []ChannelProbe{
+		&TelegramChannelProbe{setup: NewTelegramSetupService(logger), errorWindow: cfg.Interval},
+		&WhatsAppChannelProbe{setup: NewWhatsAppSetupService(logger)},
+		&MessengerChannelProbe{setup: NewMessengerSetupService(logger)},
+	}
+}
+
+// TelegramChannelProbe verifica el webhook del bot con getWebhookInfo
+type TelegramChannelProbe struct {
+	setup *TelegramSetupService
+	// errorWindow es la antigüedad máxima de un error de entrega informado por Telegram para
+	// tomarlo como fallo; Telegram conserva el último error aunque las entregas se hayan recuperado
+	errorWindow time.Duration
+}
+
+func (p *TelegramChannelProbe) Platforms() []domain.Platform {
+	return []domain.Platform{domain.PlatformTelegram}
+}
+
+func (p *TelegramChannelProbe) Probe(ctx context.Context, channel *domain.ChannelIntegration) (interface{}, error) {
+	botToken := channelConfigValue(channel, "bot_token")
+	if botToken == "" {
+		botToken = channelToken(channel)
+	}
+	if botToken == "" {
+		return nil, fmt.Errorf("telegram channel %s has no bot token", channel.ID)
+	}
+
+	info, err := p.setup.GetWebhookInfo(ctx, botToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, evaluateTelegramWebhook(info, time.Now().Add(-p.errorWindow))
+}
+
+// evaluateTelegramWebhook detecta un webhook sin configurar, con errores de entrega posteriores
+// a since o con demasiados updates pendientes
+func evaluateTelegramWebhook(info *TelegramWebhookInfo, since time.Time) error {
+	if info.URL == "" {
+		return fmt.Errorf("telegram webhook is not set")
+	}
+	if info.LastErrorDate > 0 && info.LastErrorDate >= since.Unix() {
+		return fmt.Errorf("telegram webhook delivery failed: %s", info.LastErrorMessage)
+	}
+	if info.PendingUpdateCount > telegramMaxPendingUpdates {
+		return fmt.Errorf("telegram webhook has %d pending updates", info.PendingUpdateCount)
+	}
+	return nil
+}
+
+// WhatsAppChannelProbe verifica el número de teléfono del canal en Graph API
+type WhatsAppChannelProbe struct {
+	setup *WhatsAppSetupService
+}
+
+func (p *WhatsAppChannelProbe) Platforms() []domain.Platform {
+	return []domain.Platform{domain.PlatformWhatsApp}
+}
+
+func (p *WhatsAppChannelProbe) Probe(ctx context.Context, channel *domain.ChannelIntegration) (interface{}, error) {
+	phoneNumberID := channelConfigValue(channel, "phone_number_id")
+	if phoneNumberID == "" {
+		return nil, fmt.Errorf("whatsapp channel %s has no phone_number_id", channel.ID)
+	}
+	accessToken := channelToken(channel)
+	if accessToken == "" {
+		return nil, fmt.Errorf("whatsapp channel %s has no access token", channel.ID)
+	}
+
+	info, err := p.setup.GetPhoneNumberInfo(ctx, accessToken, phoneNumberID)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// MessengerChannelProbe verifica la página del canal en Graph API
+type MessengerChannelProbe struct {
+	setup *MessengerSetupService
+}
+
+func (p *MessengerChannelProbe) Platforms() []domain.Platform {
+	return []domain.Platform{domain.PlatformMessenger}
+}
+
+func (p *MessengerChannelProbe) Probe(ctx context.Context, channel *domain.ChannelIntegration) (interface{}, error) {
+	pageID := channelConfigValue(channel, "page_id")
+	if pageID == "" {
+		return nil, fmt.Errorf("messenger channel %s has no page_id", channel.ID)
+	}
+	pageAccessToken := channelConfigValue(channel, "page_access_token")
+	if pageAccessToken == "" {
+		pageAccessToken = channelToken(channel)
+	}
+	if pageAccessToken == "" {
+		return nil, fmt.Errorf("messenger channel %s has no page access token", channel.ID)
+	}
+
+	info, err := p.setup.GetPageInfo(ctx, pageAccessToken, pageID)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
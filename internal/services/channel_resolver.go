@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+)
+
+var (
+	// ErrChannelNotFound indica que ningún canal registrado coincide con el identificador del webhook
+	ErrChannelNotFound = errors.New("channel not found for webhook")
+	// ErrChannelDisabled indica que el canal existe pero no está activo
+	ErrChannelDisabled = errors.New("channel is disabled")
+	// ErrChannelMismatch indica que el payload pertenece a un canal distinto al de la ruta del webhook
+	ErrChannelMismatch = errors.New("webhook payload belongs to another channel")
+)
+
+// ChannelResolver resuelve el canal (y su tenant) a partir del identificador de plataforma de un webhook
+type ChannelResolver interface {
+	Resolve(ctx context.Context, platform domain.Platform, identifier string) (*domain.ChannelIntegration, error)
+	ResolveSole(ctx context.Context, platform domain.Platform) (*domain.ChannelIntegration, error)
+	InvalidateChannel(channelID string)
+}
+
+type cachedChannel struct {
+	integration *domain.ChannelIntegration
+	expiresAt   time.Time
+}
+
+type channelResolver struct {
+	channelRepo domain.ChannelIntegrationRepository
+	ttl         time.Duration
+	logger      logger.Logger
+	now         func() time.Time
+
+	mu    sync.RWMutex
+	cache map[string]cachedChannel
+}
+
+// NewChannelResolver crea un resolvedor de canales con cache en memoria
+func NewChannelResolver(channelRepo domain.ChannelIntegrationRepository, ttl time.Duration, logger logger.Logger) ChannelResolver {
+	return &channelResolver{
+		channelRepo: channelRepo,
+		ttl:         ttl,
+		logger:      logger,
+		now:         time.Now,
+		cache:       make(map[string]cachedChannel),
+	}
+}
+
+func (r *channelResolver) Resolve(ctx context.Context, platform domain.Platform, identifier string) (*domain.ChannelIntegration, error) {
+	if identifier == "" {
+		return nil, ErrChannelNotFound
+	}
+
+	key := string(platform) + ":" + identifier
+
+	r.mu.RLock()
+	entry, ok := r.cache[key]
+	r.mu.RUnlock()
+
+	integration := entry.integration
+	if !ok || r.now().After(entry.expiresAt) {
+		var err error
+		integration, err = r.channelRepo.GetByPlatformIdentifier(ctx, platform, identifier)
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.Warn("Channel not resolved for webhook", map[string]interface{}{
+				"platform":   platform,
+				"identifier": identifier,
+			})
+			return nil, ErrChannelNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if r.ttl > 0 {
+			r.mu.Lock()
+			r.cache[key] = cachedChannel{integration: integration, expiresAt: r.now().Add(r.ttl)}
+			r.mu.Unlock()
+		}
+	}
+
+	if integration.Status != domain.StatusActive {
+		return integration, ErrChannelDisabled
+	}
+
+	return integration, nil
+}
+
+// ResolveSole resuelve el canal de un webhook que no trae identificador, como los bots de Telegram
+// registrados en la ruta legacy sin webhook_key. Solo es posible si la plataforma tiene un único
+// canal activo; con varios no hay forma de saber a cuál pertenece y se trata como no encontrado.
+// No se cachea: un segundo canal creado mientras tanto dejaría la resolución apuntando al primero.
+func (r *channelResolver) ResolveSole(ctx context.Context, platform domain.Platform) (*domain.ChannelIntegration, error) {
+	integrations, err := r.channelRepo.GetByPlatform(ctx, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	var active []*domain.ChannelIntegration
+	for _, integration := range integrations {
+		if integration.Status == domain.StatusActive {
+			active = append(active, integration)
+		}
+	}
+
+	if len(active) != 1 {
+		r.logger.Warn("Channel not resolved for webhook without identifier", map[string]interface{}{
+			"platform":        platform,
+			"active_channels": len(active),
+		})
+		return nil, ErrChannelNotFound
+	}
+
+	return active[0], nil
+}
+
+// InvalidateChannel descarta las entradas cacheadas de un canal tras actualizarlo o eliminarlo
+func (r *channelResolver) InvalidateChannel(channelID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, entry := range r.cache {
+		if entry.integration.ID == channelID {
+			delete(r.cache, key)
+		}
+	}
+}
+
+// invalidatingChannelRepository descarta del cache del resolvedor cada canal que se escribe, así
+// los cambios de estado o credenciales hechos fuera de integrationService (rotación de tokens,
+// verificación de canales, servicios de configuración) se ven en el siguiente webhook. Las demás
+// réplicas los ven al vencer el TTL del cache.
+type invalidatingChannelRepository struct {
+	domain.ChannelIntegrationRepository
+	resolver ChannelResolver
+}
+
+// NewInvalidatingChannelRepository envuelve el repositorio de canales para invalidar el resolvedor en cada escritura
+func NewInvalidatingChannelRepository(channelRepo domain.ChannelIntegrationRepository, resolver ChannelResolver) domain.ChannelIntegrationRepository {
+	return &invalidatingChannelRepository{ChannelIntegrationRepository: channelRepo, resolver: resolver}
+}
+
+func (r *invalidatingChannelRepository) Update(ctx context.Context, integration *domain.ChannelIntegration) error {
+	defer r.resolver.InvalidateChannel(integration.ID)
+	return r.ChannelIntegrationRepository.Update(ctx, integration)
+}
+
+func (r *invalidatingChannelRepository) Delete(ctx context.Context, id string) error {
+	defer r.resolver.InvalidateChannel(id)
+	return r.ChannelIntegrationRepository.Delete(ctx, id)
+}
+
+func (r *invalidatingChannelRepository) UpdateTokenState(ctx context.Context, integration *domain.ChannelIntegration) error {
+	defer r.resolver.InvalidateChannel(integration.ID)
+	return r.ChannelIntegrationRepository.UpdateTokenState(ctx, integration)
+}
+
+func (r *invalidatingChannelRepository) UpdateStatus(ctx context.Context, id string, from, to domain.IntegrationStatus) (bool, error) {
+	defer r.resolver.InvalidateChannel(id)
+	return r.ChannelIntegrationRepository.UpdateStatus(ctx, id, from, to)
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingChannelRepository guarda los canales en memoria y cuenta las búsquedas por identificador
+type countingChannelRepository struct {
+	domain.ChannelIntegrationRepository
+	channels    map[string]*domain.ChannelIntegration
+	identifiers map[string]string
+	lookups     int
+}
+
+func (r *countingChannelRepository) GetByID(ctx context.Context, id string) (*domain.ChannelIntegration, error) {
+	channel, ok := r.channels[id]
+	if !ok {
+		return nil, fmt.Errorf("channel integration not found: %w", sql.ErrNoRows)
+	}
+	copied := *channel
+	return &copied, nil
+}
+
+func (r *countingChannelRepository) GetByPlatformIdentifier(ctx context.Context, platform domain.Platform, identifier string) (*domain.ChannelIntegration, error) {
+	r.lookups++
+	return r.GetByID(ctx, r.identifiers[identifier])
+}
+
+func (r *countingChannelRepository) GetByPlatform(ctx context.Context, platform domain.Platform) ([]*domain.ChannelIntegration, error) {
+	var channels []*domain.ChannelIntegration
+	for _, channel := range r.channels {
+		if channel.Platform == platform {
+			copied := *channel
+			channels = append(channels, &copied)
+		}
+	}
+	return channels, nil
+}
+
+func (r *countingChannelRepository) Update(ctx context.Context, integration *domain.ChannelIntegration) error {
+	copied := *integration
+	r.channels[integration.ID] = &copied
+	return nil
+}
+
+func (r *countingChannelRepository) Delete(ctx context.Context, id string) error {
+	delete(r.channels, id)
+	return nil
+}
+
+// savedInboundRepository registra los mensajes entrantes guardados
+type savedInboundRepository struct {
+	domain.InboundMessageRepository
+	saved []*domain.InboundMessage
+}
+
+func (r *savedInboundRepository) Create(ctx context.Context, message *domain.InboundMessage) error {
+	r.saved = append(r.saved, message)
+	return nil
+}
+
+func newCountingChannelRepository(channels ...*domain.ChannelIntegration) *countingChannelRepository {
+	repo := &countingChannelRepository{
+		channels:    make(map[string]*domain.ChannelIntegration),
+		identifiers: make(map[string]string),
+	}
+	for _, channel := range channels {
+		repo.channels[channel.ID] = channel
+	}
+	return repo
+}
+
+func TestChannelResolverCachesUntilTTL(t *testing.T) {
+	ctx := context.Background()
+	repo := newCountingChannelRepository(&domain.ChannelIntegration{ID: "channel-a", TenantID: "tenant-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive})
+	repo.identifiers["phone-a"] = "channel-a"
+
+	now := time.Now()
+	resolver := NewChannelResolver(repo, time.Minute, logger.NewLogger("error")).(*channelResolver)
+	resolver.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		channel, err := resolver.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+		require.NoError(t, err)
+		assert.Equal(t, "tenant-a", channel.TenantID)
+	}
+	assert.Equal(t, 1, repo.lookups)
+
+	// Al vencer el TTL se vuelve a consultar el repositorio
+	now = now.Add(time.Minute + time.Second)
+	_, err := resolver.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, repo.lookups)
+
+	// Los identificadores desconocidos no se cachean
+	for i := 0; i < 2; i++ {
+		_, err = resolver.Resolve(ctx, domain.PlatformWhatsApp, "phone-x")
+		assert.ErrorIs(t, err, ErrChannelNotFound)
+	}
+	assert.Equal(t, 4, repo.lookups)
+
+	// Sin TTL no hay cache
+	uncached := NewChannelResolver(repo, 0, logger.NewLogger("error"))
+	_, err = uncached.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+	require.NoError(t, err)
+	_, err = uncached.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+	require.NoError(t, err)
+	assert.Equal(t, 6, repo.lookups)
+}
+
+func TestChannelResolverWithoutIdentifier(t *testing.T) {
+	repo := newCountingChannelRepository()
+	resolver := NewChannelResolver(repo, time.Minute, logger.NewLogger("error"))
+
+	channel, err := resolver.Resolve(context.Background(), domain.PlatformWhatsApp, "")
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+	assert.Nil(t, channel)
+	assert.Zero(t, repo.lookups)
+}
+
+func TestChannelResolverInvalidatedOnUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewLogger("error")
+	repo := newCountingChannelRepository(&domain.ChannelIntegration{ID: "channel-a", TenantID: "tenant-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive})
+	repo.identifiers["phone-a"] = "channel-a"
+	resolver := NewChannelResolver(repo, time.Hour, log)
+	service := &integrationService{
+		channelService:  NewChannelService(repo, log),
+		channelResolver: resolver,
+		logger:          log,
+	}
+
+	_, err := resolver.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+	require.NoError(t, err)
+
+	// Deshabilitar el canal descarta la resolución cacheada
+	require.NoError(t, service.UpdateChannel(ctx, &domain.ChannelIntegration{ID: "channel-a", TenantID: "tenant-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusDisabled}))
+	channel, err := resolver.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+	assert.ErrorIs(t, err, ErrChannelDisabled)
+	assert.Equal(t, "channel-a", channel.ID)
+	assert.Equal(t, 2, repo.lookups)
+
+	require.NoError(t, service.DeleteChannel(ctx, "channel-a"))
+	_, err = resolver.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+	assert.Equal(t, 3, repo.lookups)
+}
+
+func (r *countingChannelRepository) UpdateStatus(ctx context.Context, id string, from, to domain.IntegrationStatus) (bool, error) {
+	channel, ok := r.channels[id]
+	if !ok || channel.Status != from {
+		return false, nil
+	}
+	channel.Status = to
+	return true, nil
+}
+
+func (r *countingChannelRepository) UpdateTokenState(ctx context.Context, integration *domain.ChannelIntegration) error {
+	return r.Update(ctx, integration)
+}
+
+func TestChannelResolverInvalidatedOnRepositoryWrites(t *testing.T) {
+	ctx := context.Background()
+	repo := newCountingChannelRepository(&domain.ChannelIntegration{ID: "channel-a", TenantID: "tenant-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive})
+	repo.identifiers["phone-a"] = "channel-a"
+	resolver := NewChannelResolver(repo, time.Hour, logger.NewLogger("error"))
+	channelRepo := NewInvalidatingChannelRepository(repo, resolver)
+
+	_, err := resolver.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+	require.NoError(t, err)
+
+	// El verificador de canales marca el canal con error sin pasar por integrationService
+	updated, err := channelRepo.UpdateStatus(ctx, "channel-a", domain.StatusActive, domain.StatusError)
+	require.NoError(t, err)
+	require.True(t, updated)
+	_, err = resolver.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+	assert.ErrorIs(t, err, ErrChannelDisabled)
+	assert.Equal(t, 2, repo.lookups)
+
+	// La rotación de tokens lo reactiva al renovar el token
+	require.NoError(t, channelRepo.UpdateTokenState(ctx, &domain.ChannelIntegration{ID: "channel-a", TenantID: "tenant-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive}))
+	channel, err := resolver.Resolve(ctx, domain.PlatformWhatsApp, "phone-a")
+	require.NoError(t, err)
+	assert.Equal(t, "channel-a", channel.ID)
+	assert.Equal(t, 3, repo.lookups)
+}
+
+func TestUnresolvedChannelPolicies(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		phoneNumberID  string
+		wantErr        error
+		wantSaved      bool
+		wantChannelID  string
+		wantQuarantine string
+	}{
+		{name: "reject con canal desconocido", policy: "reject", phoneNumberID: "phone-x", wantErr: ErrChannelNotFound},
+		{name: "reject con canal deshabilitado", policy: "reject", phoneNumberID: "phone-c", wantErr: ErrChannelDisabled},
+		{name: "quarantine con canal desconocido", policy: UnresolvedChannelQuarantine, phoneNumberID: "phone-x", wantSaved: true, wantQuarantine: ErrChannelNotFound.Error()},
+		{name: "quarantine con canal deshabilitado", policy: UnresolvedChannelQuarantine, phoneNumberID: "phone-c", wantSaved: true, wantChannelID: "channel-c", wantQuarantine: ErrChannelDisabled.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.NewLogger("error")
+			repo := newCountingChannelRepository(&domain.ChannelIntegration{ID: "channel-c", TenantID: "tenant-c", Platform: domain.PlatformWhatsApp, Status: domain.StatusDisabled})
+			repo.identifiers["phone-c"] = "channel-c"
+			inbound := &savedInboundRepository{}
+			forwarder := &scriptedForwarder{WebhookService: NewWebhookService("", log)}
+			service := &integrationService{
+				channelService:   NewChannelService(repo, log),
+				inboundRepo:      inbound,
+				webhookService:   forwarder,
+				channelResolver:  NewChannelResolver(repo, 0, log),
+				unresolvedPolicy: tt.policy,
+				logger:           log,
+			}
+
+			err := service.ProcessWhatsAppWebhook(context.Background(), whatsappPayload(tt.phoneNumberID), "")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Empty(t, forwarder.forwarded)
+
+			if !tt.wantSaved {
+				assert.Empty(t, inbound.saved)
+				return
+			}
+			require.Len(t, inbound.saved, 1)
+			assert.Equal(t, tt.wantQuarantine, inbound.saved[0].QuarantineReason)
+			assert.Equal(t, tt.wantChannelID, inbound.saved[0].ChannelID)
+		})
+	}
+}
+
+func TestLegacyTelegramWebhookResolvesSoleActiveBot(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewLogger("error")
+	repo := newCountingChannelRepository(
+		&domain.ChannelIntegration{ID: "bot-a", TenantID: "tenant-a", Platform: domain.PlatformTelegram, Status: domain.StatusActive},
+		&domain.ChannelIntegration{ID: "bot-old", TenantID: "tenant-b", Platform: domain.PlatformTelegram, Status: domain.StatusDisabled},
+		&domain.ChannelIntegration{ID: "channel-w", TenantID: "tenant-b", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive},
+	)
+	repo.identifiers["key-b"] = "bot-b"
+	service := &integrationService{
+		channelService:  NewChannelService(repo, log),
+		webhookService:  NewWebhookService("", log),
+		channelResolver: NewChannelResolver(repo, time.Hour, log),
+		logger:          log,
+	}
+	payload := []byte(telegramTextPayload(1, 1))
+
+	// Un único bot activo: la ruta sin clave sigue funcionando
+	channel, err := service.resolveChannel(ctx, domain.PlatformTelegram, payload, webhookRoute{})
+	require.NoError(t, err)
+	assert.Equal(t, "bot-a", channel.ID)
+
+	// Con un segundo bot activo ya no se puede saber a cuál pertenece el update
+	repo.channels["bot-b"] = &domain.ChannelIntegration{ID: "bot-b", TenantID: "tenant-b", Platform: domain.PlatformTelegram, Status: domain.StatusActive}
+	_, err = service.resolveChannel(ctx, domain.PlatformTelegram, payload, webhookRoute{})
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+
+	// Los bots con clave se resuelven por la URL
+	channel, err = service.resolveChannel(ctx, domain.PlatformTelegram, payload, webhookRoute{channelKey: "key-b"})
+	require.NoError(t, err)
+	assert.Equal(t, "bot-b", channel.ID)
+}
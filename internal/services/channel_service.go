@@ -38,6 +38,10 @@ func (s *channelService) CreateChannel(ctx context.Context, integration *domain.
 	integration.CreatedAt = time.Now()
 	integration.UpdatedAt = time.Now()
 	integration.Status = domain.StatusActive
+	if integration.AccessToken != "" && integration.TokenIssuedAt == nil {
+		issuedAt := integration.CreatedAt
+		integration.TokenIssuedAt = &issuedAt
+	}
 
 	if s.channelRepo != nil {
 		if err := s.channelRepo.Create(ctx, integration); err != nil {
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+)
+
+var (
+	// ErrOutsideSessionWindow indica que el contacto no escribió dentro de la ventana de atención
+	ErrOutsideSessionWindow = errors.New("contact is outside the messaging window")
+	// ErrSessionNotFound indica que el contacto nunca escribió por el canal
+	ErrSessionNotFound = errors.New("conversation session not found")
+)
+
+// sessionWindowPlatforms son las plataformas que solo aceptan mensajes libres dentro de la ventana
+var sessionWindowPlatforms = map[domain.Platform]bool{
+	domain.PlatformWhatsApp:  true,
+	domain.PlatformMessenger: true,
+	domain.PlatformInstagram: true,
+}
+
+// ConversationSessionService registra la ventana de atención de cada contacto y la consulta al enviar
+type ConversationSessionService interface {
+	// RecordInbound abre o extiende la ventana del remitente de un mensaje entrante
+	RecordInbound(ctx context.Context, message *NormalizedMessage) error
+	// RecordStatus guarda la categoría de conversación que WhatsApp informa en los recibos
+	RecordStatus(ctx context.Context, event *StatusEvent) error
+	// CheckWindow retorna ErrOutsideSessionWindow si el canal exige ventana y el contacto no la tiene abierta
+	CheckWindow(ctx context.Context, channel *domain.ChannelIntegration, contactID string) error
+	GetSession(ctx context.Context, channelID, contactID string) (*domain.ConversationSession, error)
+	ListOpenSessions(ctx context.Context, tenantID string, platform domain.Platform, limit, offset int) ([]*domain.ConversationSession, error)
+}
+
+type conversationSessionService struct {
+	sessionRepo domain.ConversationSessionRepository
+	window      time.Duration
+	logger      logger.Logger
+}
+
+// NewConversationSessionService crea una nueva instancia del servicio de ventanas de atención
+func NewConversationSessionService(sessionRepo domain.ConversationSessionRepository, window time.Duration, logger logger.Logger) ConversationSessionService {
+	return &conversationSessionService{
+		sessionRepo: sessionRepo,
+		window:      window,
+		logger:      logger,
+	}
+}
+
+func (s *conversationSessionService) RecordInbound(ctx context.Context, message *NormalizedMessage) error {
+	if message.ChannelID == "" || message.Sender == "" {
+		return nil
+	}
+
+	inboundAt := inboundTime(message.Timestamp, time.Now())
+	return s.sessionRepo.RecordInbound(ctx, &domain.ConversationSession{
+		ChannelID:       message.ChannelID,
+		ContactID:       message.Sender,
+		TenantID:        message.TenantID,
+		Platform:        message.Platform,
+		LastInboundAt:   inboundAt,
+		WindowExpiresAt: inboundAt.Add(s.window),
+		Category:        domain.ConversationCategoryService,
+	})
+}
+
+func (s *conversationSessionService) RecordStatus(ctx context.Context, event *StatusEvent) error {
+	if event.ChannelID == "" || event.Recipient == "" || event.ConversationCategory == "" {
+		return nil
+	}
+	return s.sessionRepo.UpdateCategory(ctx, event.ChannelID, event.Recipient, event.ConversationCategory)
+}
+
+func (s *conversationSessionService) CheckWindow(ctx context.Context, channel *domain.ChannelIntegration, contactID string) error {
+	if !sessionWindowPlatforms[channel.Platform] {
+		return nil
+	}
+
+	session, err := s.GetSession(ctx, channel.ID, contactID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return fmt.Errorf("%w: %s has not messaged this channel, send a template instead", ErrOutsideSessionWindow, contactID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !session.IsOpen(time.Now()) {
+		return fmt.Errorf("%w: window for %s closed at %s, send a template instead",
+			ErrOutsideSessionWindow, contactID, session.WindowExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (s *conversationSessionService) GetSession(ctx context.Context, channelID, contactID string) (*domain.ConversationSession, error) {
+	session, err := s.sessionRepo.Get(ctx, channelID, contactID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	return session, err
+}
+
+func (s *conversationSessionService) ListOpenSessions(ctx context.Context, tenantID string, platform domain.Platform, limit, offset int) ([]*domain.ConversationSession, error) {
+	return s.sessionRepo.ListOpenByTenant(ctx, tenantID, platform, limit, offset)
+}
+
+// inboundTime convierte el timestamp de la plataforma (segundos en WhatsApp y Telegram,
+// milisegundos en Messenger e Instagram). Un timestamp futuro o ausente se toma como now.
+func inboundTime(timestamp int64, now time.Time) time.Time {
+	if timestamp <= 0 {
+		return now
+	}
+
+	var t time.Time
+	if timestamp > 1e12 {
+		t = time.UnixMilli(timestamp)
+	} else {
+		t = time.Unix(timestamp, 0)
+	}
+	if t.After(now) {
+		return now
+	}
+	return t
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySessionRepository guarda las ventanas en memoria con la misma regla de extensión que Postgres
+type memorySessionRepository struct {
+	sessions map[string]*domain.ConversationSession
+}
+
+func (r *memorySessionRepository) RecordInbound(ctx context.Context, session *domain.ConversationSession) error {
+	key := session.ChannelID + ":" + session.ContactID
+	existing, ok := r.sessions[key]
+	if !ok {
+		copied := *session
+		r.sessions[key] = &copied
+		return nil
+	}
+	if session.LastInboundAt.After(existing.LastInboundAt) {
+		existing.LastInboundAt = session.LastInboundAt
+	}
+	if session.WindowExpiresAt.After(existing.WindowExpiresAt) {
+		existing.WindowExpiresAt = session.WindowExpiresAt
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) Get(ctx context.Context, channelID, contactID string) (*domain.ConversationSession, error) {
+	session, ok := r.sessions[channelID+":"+contactID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return session, nil
+}
+
+func (r *memorySessionRepository) UpdateCategory(ctx context.Context, channelID, contactID, category string) error {
+	if session, ok := r.sessions[channelID+":"+contactID]; ok {
+		session.Category = category
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) ListOpenByTenant(ctx context.Context, tenantID string, platform domain.Platform, limit, offset int) ([]*domain.ConversationSession, error) {
+	return nil, nil
+}
+
+func TestConversationSessionWindow(t *testing.T) {
+	repo := &memorySessionRepository{sessions: make(map[string]*domain.ConversationSession)}
+	service := NewConversationSessionService(repo, 24*time.Hour, logger.NewLogger("error"))
+	ctx := context.Background()
+
+	whatsapp := &domain.ChannelIntegration{ID: "channel-wa", Platform: domain.PlatformWhatsApp}
+	telegram := &domain.ChannelIntegration{ID: "channel-tg", Platform: domain.PlatformTelegram}
+
+	// Sin mensajes del contacto no hay ventana
+	err := service.CheckWindow(ctx, whatsapp, "5491155550000")
+	assert.True(t, errors.Is(err, ErrOutsideSessionWindow))
+
+	// Telegram no tiene ventana
+	assert.NoError(t, service.CheckWindow(ctx, telegram, "99"))
+
+	// Un mensaje de hace 30 horas ya no abre la ventana
+	stale := time.Now().Add(-30 * time.Hour).Unix()
+	require.NoError(t, service.RecordInbound(ctx, &NormalizedMessage{
+		Platform: domain.PlatformWhatsApp, ChannelID: "channel-wa", TenantID: "tenant-1",
+		Sender: "5491155550000", Timestamp: stale,
+	}))
+	err = service.CheckWindow(ctx, whatsapp, "5491155550000")
+	assert.True(t, errors.Is(err, ErrOutsideSessionWindow))
+
+	// Un mensaje reciente la reabre y un recibo atrasado no la acorta
+	recent := time.Now().Add(-time.Hour).Unix()
+	require.NoError(t, service.RecordInbound(ctx, &NormalizedMessage{
+		Platform: domain.PlatformWhatsApp, ChannelID: "channel-wa", TenantID: "tenant-1",
+		Sender: "5491155550000", Timestamp: recent,
+	}))
+	require.NoError(t, service.RecordInbound(ctx, &NormalizedMessage{
+		Platform: domain.PlatformWhatsApp, ChannelID: "channel-wa", TenantID: "tenant-1",
+		Sender: "5491155550000", Timestamp: stale,
+	}))
+	assert.NoError(t, service.CheckWindow(ctx, whatsapp, "5491155550000"))
+
+	session, err := service.GetSession(ctx, "channel-wa", "5491155550000")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(recent, 0).Add(24*time.Hour), session.WindowExpiresAt)
+
+	require.NoError(t, service.RecordStatus(ctx, &StatusEvent{
+		ChannelID: "channel-wa", Recipient: "5491155550000", ConversationCategory: "marketing",
+	}))
+	assert.Equal(t, "marketing", session.Category)
+}
+
+func TestInboundTime(t *testing.T) {
+	now := time.Unix(1749416800, 0)
+
+	assert.Equal(t, time.Unix(1749416700, 0), inboundTime(1749416700, now), "seconds")
+	assert.Equal(t, time.UnixMilli(1749416700123), inboundTime(1749416700123, now), "milliseconds")
+	assert.Equal(t, now, inboundTime(0, now), "missing")
+	assert.Equal(t, now, inboundTime(1749416900, now), "future")
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/repository"
+	"it-integration-service/pkg/logger"
+)
+
+// ErrReencryptionRunning indica que ya hay una re-encriptación en curso
+var ErrReencryptionRunning = errors.New("credential re-encryption is already running")
+
+// credentialSealStep vuelve a sellar una tabla de credenciales
+type credentialSealStep struct {
+	table string
+	run   func(ctx context.Context, opts repository.SealOptions) (*repository.SealReport, error)
+}
+
+// CredentialReencryptionJob vuelve a sellar en segundo plano las credenciales de
+// channel_integrations y google_calendar_integrations con la clave activa del keyring, para
+// poder retirar las claves anteriores una vez terminado
+type CredentialReencryptionJob struct {
+	keyring   *EncryptionService
+	steps     []credentialSealStep
+	batchSize int
+	logger    logger.Logger
+
+	mu      sync.Mutex
+	baseCtx context.Context
+	status  domain.CredentialReencryptionStatus
+}
+
+// NewCredentialReencryptionJob crea el trabajo de re-encriptación sobre las tablas de credenciales
+func NewCredentialReencryptionJob(db *repository.PostgresDB, keyring *EncryptionService, batchSize int, logger logger.Logger) *CredentialReencryptionJob {
+	steps := []credentialSealStep{
+		{
+			table: "channel_integrations",
+			run: func(ctx context.Context, opts repository.SealOptions) (*repository.SealReport, error) {
+				return repository.SealChannelCredentials(ctx, db, keyring, opts)
+			},
+		},
+		{
+			table: "google_calendar_integrations",
+			run: func(ctx context.Context, opts repository.SealOptions) (*repository.SealReport, error) {
+				return repository.SealCalendarCredentials(ctx, db, keyring, opts)
+			},
+		},
+	}
+	return newCredentialReencryptionJob(keyring, steps, batchSize, logger)
+}
+
+func newCredentialReencryptionJob(keyring *EncryptionService, steps []credentialSealStep, batchSize int, logger logger.Logger) *CredentialReencryptionJob {
+	return &CredentialReencryptionJob{
+		keyring:   keyring,
+		steps:     steps,
+		batchSize: batchSize,
+		logger:    logger,
+		baseCtx:   context.Background(),
+		status: domain.CredentialReencryptionStatus{
+			State:       domain.CredentialReencryptionIdle,
+			ActiveKeyID: keyring.ActiveKeyID(),
+		},
+	}
+}
+
+// Start asocia el trabajo al contexto de los procesos en segundo plano; al cancelarse se
+// detiene la re-encriptación en curso
+func (j *CredentialReencryptionJob) Start(ctx context.Context) {
+	j.mu.Lock()
+	j.baseCtx = ctx
+	j.mu.Unlock()
+
+	j.logger.Info("Credential re-encryption job ready", map[string]interface{}{
+		"active_key_id": j.keyring.ActiveKeyID(),
+		"key_ids":       j.keyring.KeyIDs(),
+	})
+}
+
+// Trigger inicia una re-encriptación en segundo plano y retorna su estado inicial. Con dryRun
+// solo cuenta las credenciales que se volverían a sellar.
+func (j *CredentialReencryptionJob) Trigger(dryRun bool) (domain.CredentialReencryptionStatus, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.status.State == domain.CredentialReencryptionRunning {
+		return j.snapshot(), ErrReencryptionRunning
+	}
+
+	now := time.Now()
+	tables := make([]domain.CredentialReencryptionTable, len(j.steps))
+	for i, step := range j.steps {
+		tables[i] = domain.CredentialReencryptionTable{Table: step.table}
+	}
+	j.status = domain.CredentialReencryptionStatus{
+		State:       domain.CredentialReencryptionRunning,
+		ActiveKeyID: j.keyring.ActiveKeyID(),
+		DryRun:      dryRun,
+		Tables:      tables,
+		StartedAt:   &now,
+	}
+
+	go j.run(j.baseCtx, dryRun)
+
+	return j.snapshot(), nil
+}
+
+// Status retorna el avance de la última re-encriptación
+func (j *CredentialReencryptionJob) Status() domain.CredentialReencryptionStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.snapshot()
+}
+
+// run recorre las tablas en orden, actualizando el avance después de cada lote
+func (j *CredentialReencryptionJob) run(ctx context.Context, dryRun bool) {
+	var runErr error
+
+	for i, step := range j.steps {
+		index := i
+		report, err := step.run(ctx, repository.SealOptions{
+			BatchSize: j.batchSize,
+			DryRun:    dryRun,
+			OnBatch: func(report repository.SealReport) {
+				j.updateTable(index, report, false)
+			},
+		})
+		if report != nil {
+			j.updateTable(index, *report, err == nil)
+		}
+		if err != nil {
+			runErr = err
+			break
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	j.status.CompletedAt = &now
+	if runErr != nil {
+		j.status.State = domain.CredentialReencryptionFailed
+		j.status.Error = runErr.Error()
+		j.logger.Error("Credential re-encryption failed", map[string]interface{}{
+			"active_key_id": j.status.ActiveKeyID,
+			"error":         runErr.Error(),
+		})
+		return
+	}
+
+	j.status.State = domain.CredentialReencryptionCompleted
+	j.logger.Info("Credential re-encryption completed", map[string]interface{}{
+		"active_key_id": j.status.ActiveKeyID,
+		"dry_run":       dryRun,
+		"tables":        j.status.Tables,
+	})
+}
+
+// updateTable registra el avance acumulado de una tabla
+func (j *CredentialReencryptionJob) updateTable(index int, report repository.SealReport, done bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	table := &j.status.Tables[index]
+	table.Scanned = report.Scanned
+	table.Resealed = report.Sealed
+	table.Failed = report.Failed
+	table.LastError = report.LastError
+	table.Done = done
+}
+
+// snapshot copia el estado para que quien llama no comparta el slice de tablas
+func (j *CredentialReencryptionJob) snapshot() domain.CredentialReencryptionStatus {
+	status := j.status
+	status.Tables = append([]domain.CredentialReencryptionTable(nil), j.status.Tables...)
+	return status
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/repository"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitForReencryption(t *testing.T, job *CredentialReencryptionJob) domain.CredentialReencryptionStatus {
+	t.Helper()
+	var status domain.CredentialReencryptionStatus
+	require.Eventually(t, func() bool {
+		status = job.Status()
+		return status.State != domain.CredentialReencryptionRunning
+	}, time.Second, 5*time.Millisecond)
+	return status
+}
+
+func TestCredentialReencryptionJobReportsProgressPerTable(t *testing.T) {
+	keyring, err := NewEncryptionServiceWithKeyID("2026-10", "fedcba9876543210fedcba9876543210")
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	steps := []credentialSealStep{
+		{
+			table: "channel_integrations",
+			run: func(ctx context.Context, opts repository.SealOptions) (*repository.SealReport, error) {
+				opts.OnBatch(repository.SealReport{Table: "channel_integrations", Scanned: 200, Sealed: 150})
+				<-release
+				return &repository.SealReport{Table: "channel_integrations", Scanned: 250, Sealed: 180, Failed: 1}, nil
+			},
+		},
+		{
+			table: "google_calendar_integrations",
+			run: func(ctx context.Context, opts repository.SealOptions) (*repository.SealReport, error) {
+				assert.Equal(t, 50, opts.BatchSize)
+				return &repository.SealReport{Table: "google_calendar_integrations", Scanned: 4, Sealed: 4}, nil
+			},
+		},
+	}
+	job := newCredentialReencryptionJob(keyring, steps, 50, logger.NewLogger("error"))
+
+	status, err := job.Trigger(false)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CredentialReencryptionRunning, status.State)
+	assert.Equal(t, "2026-10", status.ActiveKeyID)
+
+	require.Eventually(t, func() bool {
+		return job.Status().Tables[0].Scanned == 200
+	}, time.Second, 5*time.Millisecond)
+
+	_, err = job.Trigger(false)
+	assert.ErrorIs(t, err, ErrReencryptionRunning)
+
+	close(release)
+	status = waitForReencryption(t, job)
+
+	assert.Equal(t, domain.CredentialReencryptionCompleted, status.State)
+	require.Len(t, status.Tables, 2)
+	assert.Equal(t, domain.CredentialReencryptionTable{Table: "channel_integrations", Scanned: 250, Resealed: 180, Failed: 1, Done: true}, status.Tables[0])
+	assert.Equal(t, domain.CredentialReencryptionTable{Table: "google_calendar_integrations", Scanned: 4, Resealed: 4, Done: true}, status.Tables[1])
+	assert.NotNil(t, status.CompletedAt)
+}
+
+func TestCredentialReencryptionJobStopsOnTableError(t *testing.T) {
+	keyring, err := NewEncryptionServiceWithKeyID("2026-10", "fedcba9876543210fedcba9876543210")
+	require.NoError(t, err)
+
+	calendarCalled := false
+	steps := []credentialSealStep{
+		{
+			table: "channel_integrations",
+			run: func(ctx context.Context, opts repository.SealOptions) (*repository.SealReport, error) {
+				return &repository.SealReport{Table: "channel_integrations", Scanned: 10}, errors.New("connection reset")
+			},
+		},
+		{
+			table: "google_calendar_integrations",
+			run: func(ctx context.Context, opts repository.SealOptions) (*repository.SealReport, error) {
+				calendarCalled = true
+				return &repository.SealReport{}, nil
+			},
+		},
+	}
+	job := newCredentialReencryptionJob(keyring, steps, 50, logger.NewLogger("error"))
+
+	_, err = job.Trigger(true)
+	require.NoError(t, err)
+	status := waitForReencryption(t, job)
+
+	assert.Equal(t, domain.CredentialReencryptionFailed, status.State)
+	assert.Equal(t, "connection reset", status.Error)
+	assert.True(t, status.DryRun)
+	assert.Equal(t, 10, status.Tables[0].Scanned)
+	assert.False(t, status.Tables[0].Done)
+	assert.False(t, calendarCalled)
+}
@@ -7,28 +7,116 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 )
 
-// EncryptionService maneja la encriptación y desencriptación de datos sensibles
+// sealedPrefix marca los valores sellados: enc:<key_id>:<base64(nonce+ciphertext)>
+const sealedPrefix = "enc:"
+
+// defaultKeyID es el identificador de la clave cuando no se configura uno
+const defaultKeyID = "default"
+
+// EncryptionService maneja la encriptación y desencriptación de datos sensibles con un keyring
+// versionado: la clave activa cifra y cualquier clave conocida descifra según el ID del sobre
 type EncryptionService struct {
-	key []byte
+	keys     map[string][]byte
+	activeID string
 }
 
 // NewEncryptionService crea una nueva instancia del servicio de encriptación
 func NewEncryptionService(key string) (*EncryptionService, error) {
-	if len(key) != 32 {
-		return nil, fmt.Errorf("encryption key must be exactly 32 bytes")
+	return NewEncryptionServiceWithKeyID(defaultKeyID, key)
+}
+
+// NewEncryptionServiceWithKeyID crea el servicio identificando la clave en los valores sellados,
+// de modo que al rotarla se sepa con qué clave se selló cada credencial
+func NewEncryptionServiceWithKeyID(keyID, key string) (*EncryptionService, error) {
+	return NewEncryptionKeyring(keyID, map[string]string{keyID: key})
+}
+
+// NewEncryptionKeyring crea el servicio con varias claves por ID; activeKeyID es la que cifra
+func NewEncryptionKeyring(activeKeyID string, keys map[string]string) (*EncryptionService, error) {
+	keyring := make(map[string][]byte, len(keys))
+	for keyID, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be exactly 32 bytes", keyID)
+		}
+		if keyID == "" || strings.Contains(keyID, ":") {
+			return nil, fmt.Errorf("encryption key id must be non-empty and cannot contain ':'")
+		}
+		keyring[keyID] = []byte(key)
+	}
+
+	if _, ok := keyring[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active encryption key %q is not in the keyring", activeKeyID)
 	}
 
 	return &EncryptionService{
-		key: []byte(key),
+		keys:     keyring,
+		activeID: activeKeyID,
 	}, nil
 }
 
-// Encrypt encripta un texto plano
+// ActiveKeyID retorna el ID de la clave con la que se sellan los valores nuevos
+func (s *EncryptionService) ActiveKeyID() string {
+	return s.activeID
+}
+
+// KeyIDs retorna los IDs de las claves conocidas
+func (s *EncryptionService) KeyIDs() []string {
+	ids := make([]string, 0, len(s.keys))
+	for keyID := range s.keys {
+		ids = append(ids, keyID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt encripta un texto plano con la clave activa y lo envuelve con su ID
 func (s *EncryptionService) Encrypt(plaintext string) (string, error) {
+	ciphertext, err := encryptWithKey(s.keys[s.activeID], plaintext)
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + s.activeID + ":" + ciphertext, nil
+}
+
+// Decrypt desencripta un texto encriptado. Los valores cifrados antes del sobre con ID de
+// clave se prueban con todas las claves del keyring.
+func (s *EncryptionService) Decrypt(encryptedText string) (string, error) {
+	if s.IsSealed(encryptedText) {
+		keyID, ciphertext, err := splitSealed(encryptedText)
+		if err != nil {
+			return "", err
+		}
+		key, ok := s.keys[keyID]
+		if !ok {
+			return "", fmt.Errorf("value sealed with unknown key %q", keyID)
+		}
+		return decryptWithKey(key, ciphertext)
+	}
+
+	// Se prueba primero la clave activa; GCM rechaza las claves que no corresponden
+	plaintext, err := decryptWithKey(s.keys[s.activeID], encryptedText)
+	if err == nil {
+		return plaintext, nil
+	}
+	for keyID, key := range s.keys {
+		if keyID == s.activeID {
+			continue
+		}
+		if plaintext, keyErr := decryptWithKey(key, encryptedText); keyErr == nil {
+			return plaintext, nil
+		}
+	}
+	return "", err
+}
+
+// encryptWithKey cifra con AES-GCM y retorna base64(nonce+ciphertext)
+func encryptWithKey(key []byte, plaintext string) (string, error) {
 	// Crear cipher block
-	block, err := aes.NewCipher(s.key)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", fmt.Errorf("failed to create cipher block: %w", err)
 	}
@@ -52,8 +140,8 @@ func (s *EncryptionService) Encrypt(plaintext string) (string, error) {
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Decrypt desencripta un texto encriptado
-func (s *EncryptionService) Decrypt(encryptedText string) (string, error) {
+// decryptWithKey descifra un base64(nonce+ciphertext) producido por encryptWithKey
+func decryptWithKey(key []byte, encryptedText string) (string, error) {
 	// Decodificar de base64
 	ciphertext, err := base64.StdEncoding.DecodeString(encryptedText)
 	if err != nil {
@@ -61,7 +149,7 @@ func (s *EncryptionService) Decrypt(encryptedText string) (string, error) {
 	}
 
 	// Crear cipher block
-	block, err := aes.NewCipher(s.key)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", fmt.Errorf("failed to create cipher block: %w", err)
 	}
@@ -107,19 +195,67 @@ func (s *EncryptionService) DecryptAccessToken(encryptedToken string) (string, e
 	return s.Decrypt(encryptedToken)
 }
 
-// IsEncrypted verifica si un texto está encriptado
-func (s *EncryptionService) IsEncrypted(text string) bool {
-	if text == "" {
-		return false
+// Seal encripta el valor dentro de un sobre con el ID de la clave activa
+func (s *EncryptionService) Seal(plaintext string) (string, error) {
+	return s.Encrypt(plaintext)
+}
+
+// Open desencripta un valor sellado; los valores en texto plano se retornan sin cambios
+func (s *EncryptionService) Open(value string) (string, error) {
+	if !s.IsSealed(value) {
+		return value, nil
+	}
+	return s.Decrypt(value)
+}
+
+// Reseal retorna el valor sellado con la clave activa y si cambió. Los valores sin sobre se
+// consideran texto plano y los sellados con otra clave se vuelven a sellar.
+func (s *EncryptionService) Reseal(value string) (string, bool, error) {
+	if !s.IsSealed(value) {
+		sealed, err := s.Seal(value)
+		return sealed, err == nil, err
+	}
+
+	keyID, _, err := splitSealed(value)
+	if err != nil {
+		return "", false, err
+	}
+	if keyID == s.activeID {
+		return value, false, nil
 	}
 
-	// Intentar decodificar de base64
-	_, err := base64.StdEncoding.DecodeString(text)
+	plaintext, err := s.Decrypt(value)
 	if err != nil {
-		return false
+		return "", false, err
+	}
+	sealed, err := s.Seal(plaintext)
+	return sealed, err == nil, err
+}
+
+// SealedKeyID retorna el ID de la clave con la que se selló el valor
+func (s *EncryptionService) SealedKeyID(value string) (string, bool) {
+	if !s.IsSealed(value) {
+		return "", false
 	}
+	keyID, _, err := splitSealed(value)
+	return keyID, err == nil
+}
 
-	// Verificar que tenga el tamaño mínimo para un texto encriptado
-	// (nonce + al menos algunos bytes de datos)
-	return len(text) > 50
+// IsSealed indica si el valor fue sellado con Seal
+func (s *EncryptionService) IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// IsEncrypted verifica si un texto está encriptado, según el sobre con ID de clave
+func (s *EncryptionService) IsEncrypted(text string) bool {
+	return s.IsSealed(text)
+}
+
+// splitSealed separa un valor sellado en ID de clave y texto cifrado
+func splitSealed(value string) (string, string, error) {
+	keyID, ciphertext, ok := strings.Cut(strings.TrimPrefix(value, sealedPrefix), ":")
+	if !ok || keyID == "" {
+		return "", "", fmt.Errorf("malformed sealed value")
+	}
+	return keyID, ciphertext, nil
 }
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/pkg/vault"
+)
+
+// encryptionKeyFile es el formato del archivo y del secreto de Vault con las claves
+type encryptionKeyFile struct {
+	ActiveKeyID string            `json:"active_key_id"`
+	Keys        map[string]string `json:"keys"`
+}
+
+// LoadEncryptionKeyring carga el keyring desde el origen configurado. En el origen env la clave
+// activa es activeKey (ENCRYPTION_KEY) y las anteriores vienen de ENCRYPTION_PREVIOUS_KEYS; el
+// cliente de Vault solo se usa con el origen vault.
+func LoadEncryptionKeyring(cfg config.EncryptionConfig, activeKey string, vaultClient vault.Client) (*EncryptionService, error) {
+	switch cfg.KeySource {
+	case "", "env":
+		keys := make(map[string]string, len(cfg.Keys)+1)
+		for keyID, key := range cfg.Keys {
+			keys[keyID] = key
+		}
+		keys[cfg.ActiveKeyID] = activeKey
+		return NewEncryptionKeyring(cfg.ActiveKeyID, keys)
+
+	case "file":
+		if cfg.KeysFile == "" {
+			return nil, fmt.Errorf("encryption keys file is not configured")
+		}
+		data, err := os.ReadFile(cfg.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption keys file: %w", err)
+		}
+		var file encryptionKeyFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse encryption keys file: %w", err)
+		}
+		return keyringFromFile(cfg, file)
+
+	case "vault":
+		if vaultClient == nil {
+			return nil, fmt.Errorf("vault client is required for vault encryption keys")
+		}
+		secret, err := vaultClient.GetSecret(cfg.VaultPath)
+		if err != nil {
+			return nil, err
+		}
+		file, err := parseVaultKeys(secret)
+		if err != nil {
+			return nil, err
+		}
+		return keyringFromFile(cfg, file)
+
+	default:
+		return nil, fmt.Errorf("unknown encryption key source %q", cfg.KeySource)
+	}
+}
+
+// keyringFromFile arma el keyring; el ID activo del archivo tiene prioridad sobre la configuración
+func keyringFromFile(cfg config.EncryptionConfig, file encryptionKeyFile) (*EncryptionService, error) {
+	activeKeyID := file.ActiveKeyID
+	if activeKeyID == "" {
+		activeKeyID = cfg.ActiveKeyID
+	}
+	return NewEncryptionKeyring(activeKeyID, file.Keys)
+}
+
+// parseVaultKeys lee las claves de un secreto KV v1 o KV v2 (donde vienen anidadas en "data").
+// El secreto tiene "active_key_id" y una entrada por clave, o las claves dentro de "keys".
+func parseVaultKeys(secret map[string]interface{}) (encryptionKeyFile, error) {
+	if nested, ok := secret["data"].(map[string]interface{}); ok {
+		secret = nested
+	}
+
+	file := encryptionKeyFile{Keys: make(map[string]string)}
+	for name, raw := range secret {
+		switch value := raw.(type) {
+		case string:
+			if name == "active_key_id" {
+				file.ActiveKeyID = value
+			} else {
+				file.Keys[name] = value
+			}
+		case map[string]interface{}:
+			if name != "keys" {
+				continue
+			}
+			for keyID, key := range value {
+				keyValue, ok := key.(string)
+				if !ok {
+					return file, fmt.Errorf("encryption key %s in vault is not a string", keyID)
+				}
+				file.Keys[keyID] = keyValue
+			}
+		}
+	}
+
+	if len(file.Keys) == 0 {
+		return file, fmt.Errorf("no encryption keys found in vault secret")
+	}
+	return file, nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"it-integration-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealAndOpenCarryKeyID(t *testing.T) {
+	service, err := NewEncryptionServiceWithKeyID("2026-10", "0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+
+	sealed, err := service.Seal("EAAG-token")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "enc:2026-10:"))
+	assert.True(t, service.IsSealed(sealed))
+
+	opened, err := service.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "EAAG-token", opened)
+
+	// Los valores sin sobre se consideran texto plano
+	opened, err = service.Open("legacy-token")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy-token", opened)
+}
+
+func TestOpenRejectsUnknownKeyID(t *testing.T) {
+	previous, err := NewEncryptionServiceWithKeyID("2026-01", "0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+	current, err := NewEncryptionServiceWithKeyID("2026-10", "fedcba9876543210fedcba9876543210")
+	require.NoError(t, err)
+
+	sealed, err := previous.Seal("EAAG-token")
+	require.NoError(t, err)
+
+	_, err = current.Open(sealed)
+	assert.ErrorContains(t, err, `unknown key "2026-01"`)
+}
+
+func TestNewEncryptionServiceWithKeyIDValidatesInput(t *testing.T) {
+	_, err := NewEncryptionServiceWithKeyID("v1", "short")
+	assert.Error(t, err)
+
+	_, err = NewEncryptionServiceWithKeyID("v:1", "0123456789abcdef0123456789abcdef")
+	assert.Error(t, err)
+}
+
+func TestKeyringDecryptsWithAnyKnownKey(t *testing.T) {
+	previous, err := NewEncryptionServiceWithKeyID("2026-01", "0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+	sealed, err := previous.Seal("EAAG-token")
+	require.NoError(t, err)
+
+	keyring, err := NewEncryptionKeyring("2026-10", map[string]string{
+		"2026-01": "0123456789abcdef0123456789abcdef",
+		"2026-10": "fedcba9876543210fedcba9876543210",
+	})
+	require.NoError(t, err)
+
+	opened, err := keyring.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "EAAG-token", opened)
+
+	resealed, changed, err := keyring.Reseal(sealed)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	keyID, ok := keyring.SealedKeyID(resealed)
+	assert.True(t, ok)
+	assert.Equal(t, "2026-10", keyID)
+
+	// Lo sellado con la clave activa no se vuelve a sellar
+	_, changed, err = keyring.Reseal(resealed)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestKeyringDecryptsLegacyCiphertextWithoutEnvelope(t *testing.T) {
+	legacy, err := encryptWithKey([]byte("0123456789abcdef0123456789abcdef"), "1//refresh-token")
+	require.NoError(t, err)
+
+	keyring, err := NewEncryptionKeyring("2026-10", map[string]string{
+		"2026-01": "0123456789abcdef0123456789abcdef",
+		"2026-10": "fedcba9876543210fedcba9876543210",
+	})
+	require.NoError(t, err)
+
+	assert.False(t, keyring.IsEncrypted(legacy))
+	plaintext, err := keyring.Decrypt(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, "1//refresh-token", plaintext)
+}
+
+func TestNewEncryptionKeyringRequiresActiveKey(t *testing.T) {
+	_, err := NewEncryptionKeyring("2026-10", map[string]string{
+		"2026-01": "0123456789abcdef0123456789abcdef",
+	})
+	assert.ErrorContains(t, err, `active encryption key "2026-10"`)
+}
+
+func TestLoadEncryptionKeyringFromEnv(t *testing.T) {
+	keyring, err := LoadEncryptionKeyring(config.EncryptionConfig{
+		KeySource:   "env",
+		ActiveKeyID: "2026-10",
+		Keys:        map[string]string{"2026-01": "0123456789abcdef0123456789abcdef"},
+	}, "fedcba9876543210fedcba9876543210", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "2026-10", keyring.ActiveKeyID())
+	assert.Equal(t, []string{"2026-01", "2026-10"}, keyring.KeyIDs())
+}
+
+func TestLoadEncryptionKeyringFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"active_key_id": "2026-10",
+		"keys": {
+			"2026-01": "0123456789abcdef0123456789abcdef",
+			"2026-10": "fedcba9876543210fedcba9876543210"
+		}
+	}`), 0o600))
+
+	keyring, err := LoadEncryptionKeyring(config.EncryptionConfig{KeySource: "file", KeysFile: path, ActiveKeyID: "default"}, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10", keyring.ActiveKeyID())
+}
+
+func TestParseVaultKeysReadsKVv2Data(t *testing.T) {
+	file, err := parseVaultKeys(map[string]interface{}{
+		"data": map[string]interface{}{
+			"active_key_id": "2026-10",
+			"2026-01":       "0123456789abcdef0123456789abcdef",
+			"2026-10":       "fedcba9876543210fedcba9876543210",
+		},
+		"metadata": map[string]interface{}{"version": 3},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "2026-10", file.ActiveKeyID)
+	assert.Len(t, file.Keys, 2)
+}
@@ -7,11 +7,15 @@ import (
 
 	"it-integration-service/internal/domain"
 	"it-integration-service/pkg/logger"
+
+	"github.com/google/uuid"
 )
 
 // NotificationService maneja las notificaciones automáticas para eventos de Google Calendar
 type NotificationService struct {
-	logger logger.Logger
+	scheduleRepo domain.ScheduledNotificationRepository
+	logger       logger.Logger
+	now          func() time.Time
 	// TODO: Agregar clientes de servicios de mensajería existentes
 	// whatsappClient *WhatsAppClient
 	// telegramClient *TelegramClient
@@ -20,9 +24,11 @@ type NotificationService struct {
 }
 
 // NewNotificationService crea una nueva instancia del servicio de notificaciones
-func NewNotificationService(logger logger.Logger) *NotificationService {
+func NewNotificationService(scheduleRepo domain.ScheduledNotificationRepository, logger logger.Logger) *NotificationService {
 	return &NotificationService{
-		logger: logger,
+		scheduleRepo: scheduleRepo,
+		logger:       logger,
+		now:          time.Now,
 	}
 }
 
@@ -36,7 +42,7 @@ type NotificationRequest struct {
 	EventLocation     string                `json:"event_location"`
 	StartTime         time.Time             `json:"start_time"`
 	EndTime           time.Time             `json:"end_time"`
-	Attendees         []CalendarAttendee    `json:"attendees"`
+	Attendees         []domain.CalendarAttendee `json:"attendees"`
 	NotificationType  NotificationType      `json:"notification_type"`
 	ReminderMinutes   int                   `json:"reminder_minutes"`
 	CustomMessage     string                `json:"custom_message,omitempty"`
@@ -165,20 +171,71 @@ func (s *NotificationService) SendEventCancellation(ctx context.Context, req *No
 	return results, nil
 }
 
-// ScheduleReminders programa recordatorios automáticos para un evento
+// ScheduleReminders programa los recordatorios del evento y reemplaza los pendientes, así un evento
+// movido de hora no conserva los anteriores. Solo se programan los que aún no vencieron; el envío
+// queda a cargo del ReminderScheduler de cualquier réplica.
 func (s *NotificationService) ScheduleReminders(ctx context.Context, event *domain.CalendarEvent, reminderMinutes []int) error {
 	s.logger.Info("Programando recordatorios automáticos", map[string]interface{}{
 		"event_id":         event.ID,
 		"reminder_minutes": reminderMinutes,
 	})
 
+	now := s.now()
+	seen := make(map[int]bool)
+	var notifications []*domain.ScheduledNotification
 	for _, minutes := range reminderMinutes {
-		reminderTime := event.StartTime.Add(-time.Duration(minutes) * time.Minute)
-		
+		if seen[minutes] || event.Status == domain.EventStatusCancelled {
+			continue
+		}
+		seen[minutes] = true
+
 		// Solo programar si el recordatorio es en el futuro
-		if reminderTime.After(time.Now()) {
-			go s.scheduleReminder(ctx, event, minutes, reminderTime)
+		fireAt := reminderFireAt(event, minutes)
+		if !fireAt.After(now) {
+			continue
 		}
+		notifications = append(notifications, &domain.ScheduledNotification{
+			ID:              uuid.New().String(),
+			EventID:         event.ID,
+			TenantID:        event.TenantID,
+			ChannelID:       event.ChannelID,
+			ReminderMinutes: minutes,
+			FireAt:          fireAt,
+			Status:          domain.ScheduledNotificationPending,
+			NextAttemptAt:   fireAt,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		})
+	}
+
+	if err := s.scheduleRepo.Reschedule(ctx, event.ID, notifications); err != nil {
+		return fmt.Errorf("error al programar recordatorios: %w", err)
+	}
+
+	return nil
+}
+
+// ScheduleEventReminders programa los recordatorios configurados en el evento
+func (s *NotificationService) ScheduleEventReminders(ctx context.Context, event *domain.CalendarEvent) error {
+	reminderMinutes := make([]int, 0, len(event.Reminders))
+	for _, reminder := range event.Reminders {
+		reminderMinutes = append(reminderMinutes, reminder.Minutes)
+	}
+	return s.ScheduleReminders(ctx, event, reminderMinutes)
+}
+
+// CancelReminders cancela los recordatorios pendientes de un evento eliminado
+func (s *NotificationService) CancelReminders(ctx context.Context, eventID string) error {
+	cancelled, err := s.scheduleRepo.CancelByEvent(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("error al cancelar recordatorios: %w", err)
+	}
+
+	if cancelled > 0 {
+		s.logger.Info("Recordatorios cancelados", map[string]interface{}{
+			"event_id":  eventID,
+			"cancelled": cancelled,
+		})
 	}
 
 	return nil
@@ -454,39 +511,9 @@ func (s *NotificationService) sendSMSNotification(ctx context.Context, recipient
 	return result
 }
 
-// scheduleReminder programa un recordatorio para ejecutarse en el futuro
-func (s *NotificationService) scheduleReminder(ctx context.Context, event *domain.CalendarEvent, minutes int, reminderTime time.Time) {
-	// Calcular tiempo de espera
-	waitTime := time.Until(reminderTime)
-	if waitTime <= 0 {
-		return
-	}
-
-	// Esperar hasta el momento del recordatorio
-	time.Sleep(waitTime)
-
-	// Enviar recordatorio
-	req := &NotificationRequest{
-		EventID:          event.ID,
-		TenantID:         event.TenantID,
-		ChannelID:        event.ChannelID,
-		EventSummary:     event.Summary,
-		EventDescription: event.Description,
-		EventLocation:    event.Location,
-		StartTime:        event.StartTime,
-		EndTime:          event.EndTime,
-		Attendees:        event.Attendees,
-		NotificationType: NotificationTypeReminder,
-		ReminderMinutes:  minutes,
-	}
-
-	_, err := s.SendEventReminder(ctx, req)
-	if err != nil {
-		s.logger.Error("Error enviando recordatorio programado", err, map[string]interface{}{
-			"event_id": event.ID,
-			"minutes":  minutes,
-		})
-	}
+// reminderFireAt calcula la hora de envío de un recordatorio del evento
+func reminderFireAt(event *domain.CalendarEvent, minutes int) time.Time {
+	return event.StartTime.Add(-time.Duration(minutes) * time.Minute)
 }
 
 // countSuccessfulResults cuenta los resultados exitosos
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+)
+
+// Políticas de misfire para recordatorios que vencieron mientras ninguna réplica estaba activa
+const (
+	// ReminderMisfireFire envía el recordatorio aunque llegue tarde
+	ReminderMisfireFire = "fire"
+	// ReminderMisfireSkip descarta el recordatorio
+	ReminderMisfireSkip = "skip"
+	// ReminderMisfireFireBeforeStart envía el recordatorio solo si el evento todavía no empezó
+	ReminderMisfireFireBeforeStart = "fire_before_start"
+)
+
+// GoogleCalendarReminderScheduler envía los recordatorios programados de los eventos. Cada réplica
+// reclama los vencidos con SKIP LOCKED, así cada recordatorio se envía en una sola de ellas.
+type GoogleCalendarReminderScheduler struct {
+	scheduleRepo        domain.ScheduledNotificationRepository
+	calendarRepo        domain.GoogleCalendarRepository
+	notificationService *NotificationService
+	config              *config.GoogleCalendarConfig
+	logger              logger.Logger
+	now                 func() time.Time
+}
+
+// NewGoogleCalendarReminderScheduler crea una nueva instancia del scheduler de recordatorios
+func NewGoogleCalendarReminderScheduler(scheduleRepo domain.ScheduledNotificationRepository, calendarRepo domain.GoogleCalendarRepository, notificationService *NotificationService, cfg *config.GoogleCalendarConfig, logger logger.Logger) *GoogleCalendarReminderScheduler {
+	return &GoogleCalendarReminderScheduler{
+		scheduleRepo:        scheduleRepo,
+		calendarRepo:        calendarRepo,
+		notificationService: notificationService,
+		config:              cfg,
+		logger:              logger,
+		now:                 time.Now,
+	}
+}
+
+// Start inicia el polling de recordatorios vencidos hasta que se cancele el contexto
+func (s *GoogleCalendarReminderScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.config.ReminderPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Google Calendar reminder scheduler stopped")
+				return
+			case <-ticker.C:
+				// Muchos recordatorios vencen a la vez (eventos a la hora en punto, o los acumulados
+				// mientras el servicio estuvo caído); un lote completo indica que quedan más y se
+				// envían ya, para no atrasar cada lote un intervalo de polling más
+				for {
+					claimed, err := s.DispatchDue(ctx)
+					if err != nil {
+						s.logger.Error("Failed to dispatch Google Calendar reminders", err)
+						break
+					}
+					if claimed < s.claimLimit() || ctx.Err() != nil {
+						break
+					}
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Google Calendar reminder scheduler started", map[string]interface{}{
+		"poll_interval":  s.config.ReminderPollInterval,
+		"batch_size":     s.claimLimit(),
+		"misfire_policy": s.config.ReminderMisfirePolicy,
+		"misfire_grace":  s.config.ReminderMisfireGrace,
+	})
+}
+
+// claimLimit calcula cuántos recordatorios reclamar por lote. Igual que en el outbox, los envíos son
+// secuenciales y cada uno puede tardar hasta ReminderSendTimeout, así que el lote se limita para que
+// termine antes de que venza el lease; si no, otra réplica reclamaría y enviaría los que faltan.
+func (s *GoogleCalendarReminderScheduler) claimLimit() int {
+	limit := s.config.ReminderBatchSize
+	if s.config.ReminderSendTimeout > 0 {
+		if fit := int(s.config.ReminderLease/s.config.ReminderSendTimeout) - 1; fit < limit {
+			limit = fit
+		}
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
+// DispatchDue reclama y envía un lote de recordatorios vencidos, retornando cuántos se reclamaron
+func (s *GoogleCalendarReminderScheduler) DispatchDue(ctx context.Context) (int, error) {
+	notifications, err := s.scheduleRepo.ClaimDue(ctx, s.claimLimit(), s.config.ReminderLease)
+	if err != nil {
+		return 0, fmt.Errorf("error al reclamar recordatorios: %w", err)
+	}
+
+	for _, notification := range notifications {
+		s.fire(ctx, notification)
+	}
+
+	return len(notifications), nil
+}
+
+// fire envía un recordatorio reclamado con los datos actuales del evento y registra el resultado
+func (s *GoogleCalendarReminderScheduler) fire(ctx context.Context, notification *domain.ScheduledNotification) {
+	event, err := s.calendarRepo.GetEvent(ctx, notification.EventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.finish(ctx, notification, domain.ScheduledNotificationCancelled, "evento eliminado")
+			return
+		}
+		s.retry(ctx, notification, fmt.Errorf("error al obtener evento: %w", err))
+		return
+	}
+
+	// Un recordatorio que ya no corresponde al horario del evento quedó de una reprogramación fallida
+	if event.Status == domain.EventStatusCancelled || !reminderFireAt(event, notification.ReminderMinutes).Equal(notification.FireAt) {
+		s.finish(ctx, notification, domain.ScheduledNotificationCancelled, "el evento fue cancelado o cambió de horario")
+		return
+	}
+
+	now := s.now()
+	if reason := s.misfireReason(notification, event, now); reason != "" {
+		s.finish(ctx, notification, domain.ScheduledNotificationSkipped, reason)
+		return
+	}
+
+	req := &NotificationRequest{
+		EventID:          event.ID,
+		TenantID:         event.TenantID,
+		ChannelID:        event.ChannelID,
+		EventSummary:     event.Summary,
+		EventDescription: event.Description,
+		EventLocation:    event.Location,
+		StartTime:        event.StartTime,
+		EndTime:          event.EndTime,
+		Attendees:        event.Attendees,
+		NotificationType: NotificationTypeReminder,
+		ReminderMinutes:  notification.ReminderMinutes,
+	}
+	sendCtx := ctx
+	if s.config.ReminderSendTimeout > 0 {
+		var cancel context.CancelFunc
+		sendCtx, cancel = context.WithTimeout(ctx, s.config.ReminderSendTimeout)
+		defer cancel()
+	}
+	if _, err := s.notificationService.SendEventReminder(sendCtx, req); err != nil {
+		s.retry(ctx, notification, err)
+		return
+	}
+
+	if err := s.scheduleRepo.MarkSent(ctx, notification.ID, now); err != nil {
+		s.logger.Error("Error al marcar recordatorio como enviado", err, map[string]interface{}{
+			"scheduled_notification_id": notification.ID,
+		})
+	}
+}
+
+// misfireReason aplica la política de misfire a un recordatorio atrasado más que la tolerancia y
+// retorna el motivo para descartarlo, o vacío si debe enviarse. El atraso se mide desde FireAt: los
+// reintentos mueven NextAttemptAt y no deben ocultar cuánto lleva vencido el recordatorio.
+func (s *GoogleCalendarReminderScheduler) misfireReason(notification *domain.ScheduledNotification, event *domain.CalendarEvent, now time.Time) string {
+	late := now.Sub(notification.FireAt)
+	if late <= s.config.ReminderMisfireGrace {
+		return ""
+	}
+
+	switch s.config.ReminderMisfirePolicy {
+	case ReminderMisfireFire:
+		return ""
+	case ReminderMisfireFireBeforeStart:
+		if now.Before(event.StartTime) {
+			return ""
+		}
+		return fmt.Sprintf("vencido hace %s y el evento ya empezó", late.Round(time.Second))
+	default:
+		return fmt.Sprintf("vencido hace %s", late.Round(time.Second))
+	}
+}
+
+// retry reprograma el recordatorio tras un fallo o lo marca fallido al agotar los intentos
+func (s *GoogleCalendarReminderScheduler) retry(ctx context.Context, notification *domain.ScheduledNotification, cause error) {
+	if notification.Attempts >= s.config.ReminderMaxAttempts {
+		s.finish(ctx, notification, domain.ScheduledNotificationFailed, cause.Error())
+		return
+	}
+
+	nextAttemptAt := s.now().Add(s.config.ReminderRetryBackoff)
+	if err := s.scheduleRepo.MarkRetry(ctx, notification.ID, nextAttemptAt, cause.Error()); err != nil {
+		s.logger.Error("Error al reprogramar recordatorio", err, map[string]interface{}{
+			"scheduled_notification_id": notification.ID,
+		})
+		return
+	}
+
+	s.logger.Warn("Falló el envío del recordatorio, se reintentará", map[string]interface{}{
+		"scheduled_notification_id": notification.ID,
+		"event_id":                  notification.EventID,
+		"attempts":                  notification.Attempts,
+		"next_attempt_at":           nextAttemptAt,
+		"error":                     cause.Error(),
+	})
+}
+
+// finish cierra el recordatorio sin enviarlo
+func (s *GoogleCalendarReminderScheduler) finish(ctx context.Context, notification *domain.ScheduledNotification, status domain.ScheduledNotificationStatus, reason string) {
+	if err := s.scheduleRepo.MarkFinished(ctx, notification.ID, status, reason); err != nil {
+		s.logger.Error("Error al cerrar recordatorio", err, map[string]interface{}{
+			"scheduled_notification_id": notification.ID,
+		})
+		return
+	}
+
+	s.logger.Info("Recordatorio cerrado sin enviar", map[string]interface{}{
+		"scheduled_notification_id": notification.ID,
+		"event_id":                  notification.EventID,
+		"status":                    status,
+		"reason":                    reason,
+	})
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryScheduledNotificationRepository reproduce las transiciones de estado de la tabla scheduled_notifications
+type memoryScheduledNotificationRepository struct {
+	mu            sync.Mutex
+	notifications map[string]*domain.ScheduledNotification
+	now           func() time.Time
+}
+
+func newMemoryScheduledNotificationRepository() *memoryScheduledNotificationRepository {
+	return &memoryScheduledNotificationRepository{
+		notifications: make(map[string]*domain.ScheduledNotification),
+		now:           time.Now,
+	}
+}
+
+func (r *memoryScheduledNotificationRepository) Reschedule(ctx context.Context, eventID string, notifications []*domain.ScheduledNotification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancelPending(eventID)
+
+	for _, notification := range notifications {
+		existing := r.find(eventID, notification.ReminderMinutes, notification.FireAt)
+		if existing == nil {
+			copied := *notification
+			r.notifications[notification.ID] = &copied
+			continue
+		}
+		if existing.Status == domain.ScheduledNotificationCancelled {
+			existing.Status = domain.ScheduledNotificationPending
+			existing.Attempts = 0
+			existing.NextAttemptAt = notification.FireAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryScheduledNotificationRepository) CancelByEvent(ctx context.Context, eventID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cancelPending(eventID), nil
+}
+
+func (r *memoryScheduledNotificationRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*domain.ScheduledNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*domain.ScheduledNotification
+	for _, notification := range r.notifications {
+		if notification.Status == domain.ScheduledNotificationPending && !notification.NextAttemptAt.After(r.now()) {
+			due = append(due, notification)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*domain.ScheduledNotification, 0, len(due))
+	for _, notification := range due {
+		notification.Status = domain.ScheduledNotificationProcessing
+		notification.Attempts++
+		copied := *notification
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *memoryScheduledNotificationRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	return r.transition(id, func(notification *domain.ScheduledNotification) {
+		notification.Status = domain.ScheduledNotificationSent
+		notification.SentAt = &sentAt
+	})
+}
+
+func (r *memoryScheduledNotificationRepository) MarkRetry(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	return r.transition(id, func(notification *domain.ScheduledNotification) {
+		notification.Status = domain.ScheduledNotificationPending
+		notification.NextAttemptAt = nextAttemptAt
+		notification.LastError = lastError
+	})
+}
+
+func (r *memoryScheduledNotificationRepository) MarkFinished(ctx context.Context, id string, status domain.ScheduledNotificationStatus, reason string) error {
+	return r.transition(id, func(notification *domain.ScheduledNotification) {
+		notification.Status = status
+		notification.LastError = reason
+	})
+}
+
+func (r *memoryScheduledNotificationRepository) transition(id string, apply func(*domain.ScheduledNotification)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	notification, ok := r.notifications[id]
+	if !ok || notification.Status != domain.ScheduledNotificationProcessing {
+		return fmt.Errorf("scheduled notification not claimed: %w", sql.ErrNoRows)
+	}
+	apply(notification)
+	return nil
+}
+
+func (r *memoryScheduledNotificationRepository) cancelPending(eventID string) int64 {
+	var cancelled int64
+	for _, notification := range r.notifications {
+		if notification.EventID == eventID && notification.Status == domain.ScheduledNotificationPending {
+			notification.Status = domain.ScheduledNotificationCancelled
+			cancelled++
+		}
+	}
+	return cancelled
+}
+
+func (r *memoryScheduledNotificationRepository) find(eventID string, minutes int, fireAt time.Time) *domain.ScheduledNotification {
+	for _, notification := range r.notifications {
+		if notification.EventID == eventID && notification.ReminderMinutes == minutes && notification.FireAt.Equal(fireAt) {
+			return notification
+		}
+	}
+	return nil
+}
+
+// byStatus retorna los minutos de anticipación de los recordatorios del evento en el estado dado
+func (r *memoryScheduledNotificationRepository) byStatus(eventID string, status domain.ScheduledNotificationStatus) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var minutes []int
+	for _, notification := range r.notifications {
+		if notification.EventID == eventID && notification.Status == status {
+			minutes = append(minutes, notification.ReminderMinutes)
+		}
+	}
+	sort.Ints(minutes)
+	return minutes
+}
+
+func (r *memoryCalendarRepository) GetEvent(ctx context.Context, eventID string) (*domain.CalendarEvent, error) {
+	event, ok := r.events[eventID]
+	if !ok || event.DeletedAt != nil {
+		return nil, fmt.Errorf("event not found: %s: %w", eventID, sql.ErrNoRows)
+	}
+	copied := *event
+	return &copied, nil
+}
+
+func testReminderConfig(policy string) *config.GoogleCalendarConfig {
+	return &config.GoogleCalendarConfig{
+		ReminderBatchSize:     10,
+		ReminderLease:         time.Minute,
+		ReminderMaxAttempts:   3,
+		ReminderRetryBackoff:  time.Minute,
+		ReminderMisfireGrace:  5 * time.Minute,
+		ReminderMisfirePolicy: policy,
+	}
+}
+
+func TestScheduleRemindersReschedulesAndCancelsWithTheEvent(t *testing.T) {
+	scheduleRepo := newMemoryScheduledNotificationRepository()
+	service := NewNotificationService(scheduleRepo, logger.NewLogger("error"))
+	ctx := context.Background()
+
+	start := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	event := &domain.CalendarEvent{ID: "event-1", TenantID: "tenant-1", ChannelID: "channel-1", StartTime: start, Status: domain.EventStatusConfirmed}
+
+	// El recordatorio de 180 minutos ya venció y no se programa; los repetidos se programan una vez
+	require.NoError(t, service.ScheduleReminders(ctx, event, []int{10, 60, 60, 180}))
+	assert.Equal(t, []int{10, 60}, scheduleRepo.byStatus("event-1", domain.ScheduledNotificationPending))
+
+	// Mover el evento cancela los recordatorios del horario anterior
+	event.StartTime = start.Add(time.Hour)
+	require.NoError(t, service.ScheduleReminders(ctx, event, []int{10}))
+	assert.Equal(t, []int{10}, scheduleRepo.byStatus("event-1", domain.ScheduledNotificationPending))
+	assert.Equal(t, []int{10, 60}, scheduleRepo.byStatus("event-1", domain.ScheduledNotificationCancelled))
+
+	// Volver al horario original recupera el recordatorio cancelado en lugar de duplicarlo
+	event.StartTime = start
+	require.NoError(t, service.ScheduleReminders(ctx, event, []int{10}))
+	assert.Equal(t, []int{10}, scheduleRepo.byStatus("event-1", domain.ScheduledNotificationPending))
+	assert.Len(t, scheduleRepo.notifications, 3)
+
+	require.NoError(t, service.CancelReminders(ctx, "event-1"))
+	assert.Empty(t, scheduleRepo.byStatus("event-1", domain.ScheduledNotificationPending))
+}
+
+func TestReminderSchedulerFiresDueRemindersOnce(t *testing.T) {
+	calendarRepo := &memoryCalendarRepository{events: make(map[string]*domain.CalendarEvent)}
+	scheduleRepo := newMemoryScheduledNotificationRepository()
+	notificationService := NewNotificationService(scheduleRepo, logger.NewLogger("error"))
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	calendarRepo.events["event-1"] = &domain.CalendarEvent{ID: "event-1", StartTime: now.Add(time.Hour), Status: domain.EventStatusConfirmed}
+	calendarRepo.events["event-2"] = &domain.CalendarEvent{ID: "event-2", StartTime: now.Add(time.Hour), Status: domain.EventStatusConfirmed}
+	require.NoError(t, notificationService.ScheduleReminders(ctx, calendarRepo.events["event-1"], []int{30}))
+	require.NoError(t, notificationService.ScheduleReminders(ctx, calendarRepo.events["event-2"], []int{30}))
+
+	// Una réplica y otra comparten la tabla: cada recordatorio vencido se reclama una sola vez
+	clock := now.Add(31 * time.Minute)
+	scheduleRepo.now = func() time.Time { return clock }
+	replicas := []*GoogleCalendarReminderScheduler{
+		NewGoogleCalendarReminderScheduler(scheduleRepo, calendarRepo, notificationService, testReminderConfig(ReminderMisfireSkip), logger.NewLogger("error")),
+		NewGoogleCalendarReminderScheduler(scheduleRepo, calendarRepo, notificationService, testReminderConfig(ReminderMisfireSkip), logger.NewLogger("error")),
+	}
+
+	// El evento 2 se eliminó sin cancelar sus recordatorios
+	require.NoError(t, calendarRepo.DeleteEvent(ctx, "event-2"))
+
+	claimed := 0
+	for _, replica := range replicas {
+		replica.now = func() time.Time { return clock }
+		n, err := replica.DispatchDue(ctx)
+		require.NoError(t, err)
+		claimed += n
+	}
+	assert.Equal(t, 2, claimed)
+	assert.Equal(t, []int{30}, scheduleRepo.byStatus("event-1", domain.ScheduledNotificationSent))
+	assert.Equal(t, []int{30}, scheduleRepo.byStatus("event-2", domain.ScheduledNotificationCancelled))
+
+	n, err := replicas[0].DispatchDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestReminderSchedulerAppliesMisfirePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		late       time.Duration
+		wantStatus domain.ScheduledNotificationStatus
+	}{
+		{name: "dentro de la tolerancia se envía", policy: ReminderMisfireSkip, late: time.Minute, wantStatus: domain.ScheduledNotificationSent},
+		{name: "skip descarta", policy: ReminderMisfireSkip, late: 20 * time.Minute, wantStatus: domain.ScheduledNotificationSkipped},
+		{name: "fire envía tarde", policy: ReminderMisfireFire, late: 2 * time.Hour, wantStatus: domain.ScheduledNotificationSent},
+		{name: "fire_before_start envía si el evento no empezó", policy: ReminderMisfireFireBeforeStart, late: 20 * time.Minute, wantStatus: domain.ScheduledNotificationSent},
+		{name: "fire_before_start descarta si el evento empezó", policy: ReminderMisfireFireBeforeStart, late: 2 * time.Hour, wantStatus: domain.ScheduledNotificationSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendarRepo := &memoryCalendarRepository{events: make(map[string]*domain.CalendarEvent)}
+			scheduleRepo := newMemoryScheduledNotificationRepository()
+			notificationService := NewNotificationService(scheduleRepo, logger.NewLogger("error"))
+			ctx := context.Background()
+
+			// Recordatorio 60 minutos antes de un evento que empieza en una hora y media
+			now := time.Now().Truncate(time.Second)
+			event := &domain.CalendarEvent{ID: "event-1", StartTime: now.Add(90 * time.Minute), Status: domain.EventStatusConfirmed}
+			calendarRepo.events["event-1"] = event
+			require.NoError(t, notificationService.ScheduleReminders(ctx, event, []int{60}))
+
+			clock := now.Add(30*time.Minute + tt.late)
+			scheduleRepo.now = func() time.Time { return clock }
+			scheduler := NewGoogleCalendarReminderScheduler(scheduleRepo, calendarRepo, notificationService, testReminderConfig(tt.policy), logger.NewLogger("error"))
+			scheduler.now = func() time.Time { return clock }
+
+			n, err := scheduler.DispatchDue(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+			assert.Equal(t, []int{60}, scheduleRepo.byStatus("event-1", tt.wantStatus))
+		})
+	}
+}
+
+func TestSyncEventsReschedulesRemindersOfMovedAndCancelledEvents(t *testing.T) {
+	service, repo, google := newTestCalendarService(t, "")
+	scheduleRepo := service.notificationService.scheduleRepo.(*memoryScheduledNotificationRepository)
+
+	start := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	eventJSON := func(id, status string, start time.Time) string {
+		return fmt.Sprintf(`{"id":%q,"status":%q,"summary":"Demo","start":{"dateTime":%q},"end":{"dateTime":%q},"reminders":{"useDefault":false,"overrides":[{"method":"popup","minutes":15}]}}`,
+			id, status, start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+	}
+
+	google.events = func(query url.Values) (int, string) {
+		if query.Get("syncToken") == "" {
+			return 200, fmt.Sprintf(`{"items":[%s,%s],"nextSyncToken":"sync-1"}`, eventJSON("evt-1", "confirmed", start), eventJSON("evt-2", "confirmed", start))
+		}
+		return 200, fmt.Sprintf(`{"items":[%s,%s],"nextSyncToken":"sync-2"}`, eventJSON("evt-1", "confirmed", start.Add(time.Hour)), eventJSON("evt-2", "cancelled", start))
+	}
+
+	_, err := service.SyncEvents(context.Background(), "channel-1")
+	require.NoError(t, err)
+	moved, err := repo.GetEventByGoogleID(context.Background(), "channel-1", "evt-1")
+	require.NoError(t, err)
+	cancelled, err := repo.GetEventByGoogleID(context.Background(), "channel-1", "evt-2")
+	require.NoError(t, err)
+	assert.Equal(t, []int{15}, scheduleRepo.byStatus(moved.ID, domain.ScheduledNotificationPending))
+	assert.Equal(t, []int{15}, scheduleRepo.byStatus(cancelled.ID, domain.ScheduledNotificationPending))
+
+	// El evento movido reprograma su recordatorio y el cancelado en Google lo pierde
+	result, err := service.SyncEvents(context.Background(), "channel-1")
+	require.NoError(t, err)
+	assert.Zero(t, result.Errors)
+	assert.Equal(t, []int{15}, scheduleRepo.byStatus(moved.ID, domain.ScheduledNotificationPending))
+	assert.Equal(t, []int{15}, scheduleRepo.byStatus(moved.ID, domain.ScheduledNotificationCancelled))
+	assert.Empty(t, scheduleRepo.byStatus(cancelled.ID, domain.ScheduledNotificationPending))
+	assert.Equal(t, []int{15}, scheduleRepo.byStatus(cancelled.ID, domain.ScheduledNotificationCancelled))
+}
+
+func TestReminderMisfireIsMeasuredFromFireAt(t *testing.T) {
+	calendarRepo := &memoryCalendarRepository{events: make(map[string]*domain.CalendarEvent)}
+	scheduleRepo := newMemoryScheduledNotificationRepository()
+	notificationService := NewNotificationService(scheduleRepo, logger.NewLogger("error"))
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	event := &domain.CalendarEvent{ID: "event-1", StartTime: now.Add(90 * time.Minute), Status: domain.EventStatusConfirmed}
+	calendarRepo.events["event-1"] = event
+	require.NoError(t, notificationService.ScheduleReminders(ctx, event, []int{60}))
+
+	// Un reintento reciente no oculta que el recordatorio venció hace 20 minutos
+	clock := now.Add(50 * time.Minute)
+	for _, notification := range scheduleRepo.notifications {
+		notification.NextAttemptAt = clock.Add(-time.Minute)
+	}
+	scheduleRepo.now = func() time.Time { return clock }
+	scheduler := NewGoogleCalendarReminderScheduler(scheduleRepo, calendarRepo, notificationService, testReminderConfig(ReminderMisfireSkip), logger.NewLogger("error"))
+	scheduler.now = func() time.Time { return clock }
+
+	n, err := scheduler.DispatchDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []int{60}, scheduleRepo.byStatus("event-1", domain.ScheduledNotificationSkipped))
+}
+
+func TestReminderClaimLimitFitsTheLease(t *testing.T) {
+	cfg := testReminderConfig(ReminderMisfireSkip)
+	cfg.ReminderBatchSize = 100
+	cfg.ReminderLease = time.Minute
+	scheduler := NewGoogleCalendarReminderScheduler(nil, nil, nil, cfg, logger.NewLogger("error"))
+	assert.Equal(t, 100, scheduler.claimLimit())
+
+	// Cada envío puede tardar hasta el timeout: el lote completo tiene que caber en el lease
+	cfg.ReminderSendTimeout = 10 * time.Second
+	assert.Equal(t, 5, scheduler.claimLimit())
+
+	cfg.ReminderSendTimeout = 2 * time.Minute
+	assert.Equal(t, 1, scheduler.claimLimit())
+}
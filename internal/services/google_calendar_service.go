@@ -2,27 +2,43 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"it-integration-service/internal/config"
 	"it-integration-service/internal/domain"
-	"it-integration-service/internal/repository"
 	"it-integration-service/pkg/logger"
 
 	"github.com/google/uuid"
 	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// syncPageSize es la cantidad de eventos pedidos a Google por página al sincronizar
+	syncPageSize = 250
+	// localEventsPageSize es la cantidad de eventos locales leídos por página al buscar eventos eliminados
+	localEventsPageSize = 500
 )
 
 // GoogleCalendarService maneja las operaciones de eventos de Google Calendar
 type GoogleCalendarService struct {
 	config     *config.GoogleCalendarConfig
 	setupSvc   *GoogleCalendarSetupService
-	repo       repository.GoogleCalendarRepository
+	repo       domain.GoogleCalendarRepository
 	logger     logger.Logger
 	encryption *EncryptionService
+	// notificationService programa y cancela los recordatorios cuando los eventos cambian
+	notificationService *NotificationService
+
+	// syncing guarda los canales con una sincronización en curso y si llegó otra notificación mientras tanto
+	syncMu  sync.Mutex
+	syncing map[string]bool
 }
 
 // EventListResponse representa la respuesta de listado de eventos
@@ -39,6 +55,14 @@ type SyncResult struct {
 	Deleted   int      `json:"deleted"`
 	Errors    int      `json:"errors"`
 	ErrorList []string `json:"error_list,omitempty"`
+	FullSync  bool     `json:"full_sync"`
+
+	syncToken string
+}
+
+func (r *SyncResult) addError(format string, args ...interface{}) {
+	r.Errors++
+	r.ErrorList = append(r.ErrorList, fmt.Sprintf(format, args...))
 }
 
 // NotificationConfig configura las notificaciones para eventos
@@ -51,13 +75,15 @@ type NotificationConfig struct {
 }
 
 // NewGoogleCalendarService crea una nueva instancia del servicio
-func NewGoogleCalendarService(cfg *config.GoogleCalendarConfig, setupSvc *GoogleCalendarSetupService, repo repository.GoogleCalendarRepository, logger logger.Logger, encryption *EncryptionService) *GoogleCalendarService {
+func NewGoogleCalendarService(cfg *config.GoogleCalendarConfig, setupSvc *GoogleCalendarSetupService, repo domain.GoogleCalendarRepository, notificationService *NotificationService, logger logger.Logger, encryption *EncryptionService) *GoogleCalendarService {
 	return &GoogleCalendarService{
-		config:     cfg,
-		setupSvc:   setupSvc,
-		repo:       repo,
-		logger:     logger,
-		encryption: encryption,
+		config:              cfg,
+		setupSvc:            setupSvc,
+		repo:                repo,
+		logger:              logger,
+		encryption:          encryption,
+		notificationService: notificationService,
+		syncing:             make(map[string]bool),
 	}
 }
 
@@ -83,7 +109,7 @@ func (s *GoogleCalendarService) CreateEvent(ctx context.Context, req *domain.Cre
 	}
 
 	// Crear servicio de Google Calendar
-	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	calendarService, err := s.setupSvc.newCalendarService(ctx, client)
 	if err != nil {
 		return nil, fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
 	}
@@ -115,17 +141,11 @@ func (s *GoogleCalendarService) CreateEvent(ctx context.Context, req *domain.Cre
 			"event_id": event.ID,
 		})
 		// No fallar si no se puede guardar localmente
-	}
-
-	// Configurar notificaciones si se especifican
-	if len(req.Reminders) > 0 {
-		err = s.setupEventNotifications(ctx, event, req.Reminders)
-		if err != nil {
-			s.logger.Warn("Error al configurar notificaciones", map[string]interface{}{
-				"event_id": event.ID,
-				"error":    err.Error(),
-			})
-		}
+	} else if err := s.notificationService.ScheduleEventReminders(ctx, event); err != nil {
+		s.logger.Warn("Error al programar recordatorios", map[string]interface{}{
+			"event_id": event.ID,
+			"error":    err.Error(),
+		})
 	}
 
 	s.logger.Info("Evento creado exitosamente", map[string]interface{}{
@@ -163,7 +183,7 @@ func (s *GoogleCalendarService) UpdateEvent(ctx context.Context, eventID string,
 	}
 
 	// Crear servicio de Google Calendar
-	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	calendarService, err := s.setupSvc.newCalendarService(ctx, client)
 	if err != nil {
 		return nil, fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
 	}
@@ -192,7 +212,7 @@ func (s *GoogleCalendarService) UpdateEvent(ctx context.Context, eventID string,
 	updatedLocalEvent.ID = event.ID
 	updatedLocalEvent.UpdatedAt = time.Now()
 
-	err = s.repo.UpdateEvent(ctx, updatedLocalEvent)
+	err = s.repo.UpdateEvent(ctx, updatedLocalEvent.ID, updatedLocalEvent)
 	if err != nil {
 		s.logger.Error("Error al actualizar evento en base de datos", err, map[string]interface{}{
 			"event_id": eventID,
@@ -200,6 +220,15 @@ func (s *GoogleCalendarService) UpdateEvent(ctx context.Context, eventID string,
 		// No fallar si no se puede actualizar localmente
 	}
 
+	// Los recordatorios siguen al nuevo horario; si la reprogramación falla, el scheduler descarta
+	// los anteriores al enviarlos porque ya no coinciden con el evento
+	if err := s.notificationService.ScheduleEventReminders(ctx, updatedLocalEvent); err != nil {
+		s.logger.Warn("Error al reprogramar recordatorios", map[string]interface{}{
+			"event_id": eventID,
+			"error":    err.Error(),
+		})
+	}
+
 	s.logger.Info("Evento actualizado exitosamente", map[string]interface{}{
 		"event_id":  eventID,
 		"google_id": event.GoogleID,
@@ -234,7 +263,7 @@ func (s *GoogleCalendarService) DeleteEvent(ctx context.Context, eventID string)
 	}
 
 	// Crear servicio de Google Calendar
-	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	calendarService, err := s.setupSvc.newCalendarService(ctx, client)
 	if err != nil {
 		return fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
 	}
@@ -258,6 +287,13 @@ func (s *GoogleCalendarService) DeleteEvent(ctx context.Context, eventID string)
 		// No fallar si no se puede eliminar localmente
 	}
 
+	if err := s.notificationService.CancelReminders(ctx, eventID); err != nil {
+		s.logger.Warn("Error al cancelar recordatorios", map[string]interface{}{
+			"event_id": eventID,
+			"error":    err.Error(),
+		})
+	}
+
 	s.logger.Info("Evento eliminado exitosamente", map[string]interface{}{
 		"event_id":  eventID,
 		"google_id": event.GoogleID,
@@ -266,6 +302,33 @@ func (s *GoogleCalendarService) DeleteEvent(ctx context.Context, eventID string)
 	return nil
 }
 
+// GetEvent obtiene un evento de la base de datos local
+func (s *GoogleCalendarService) GetEvent(ctx context.Context, eventID string) (*domain.CalendarEvent, error) {
+	event, err := s.repo.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener evento: %w", err)
+	}
+	return event, nil
+}
+
+// GetEventsByTenant obtiene los eventos sincronizados de un tenant, del más reciente al más antiguo
+func (s *GoogleCalendarService) GetEventsByTenant(ctx context.Context, tenantID string, limit, offset int) ([]*domain.CalendarEvent, error) {
+	events, err := s.repo.GetEventsByTenant(ctx, tenantID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener eventos del tenant: %w", err)
+	}
+	return events, nil
+}
+
+// GetEventsByDateRange obtiene los eventos sincronizados de un canal dentro de un rango de fechas
+func (s *GoogleCalendarService) GetEventsByDateRange(ctx context.Context, channelID string, startTime, endTime time.Time) ([]*domain.CalendarEvent, error) {
+	events, err := s.repo.GetEventsByDateRange(ctx, channelID, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener eventos por rango de fechas: %w", err)
+	}
+	return events, nil
+}
+
 // ListEvents lista eventos de Google Calendar
 func (s *GoogleCalendarService) ListEvents(ctx context.Context, req *domain.ListEventsRequest) (*EventListResponse, error) {
 	s.logger.Info("Listando eventos de Google Calendar", map[string]interface{}{
@@ -287,7 +350,7 @@ func (s *GoogleCalendarService) ListEvents(ctx context.Context, req *domain.List
 	}
 
 	// Crear servicio de Google Calendar
-	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	calendarService, err := s.setupSvc.newCalendarService(ctx, client)
 	if err != nil {
 		return nil, fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
 	}
@@ -356,14 +419,14 @@ func (s *GoogleCalendarService) ListEvents(ctx context.Context, req *domain.List
 	}, nil
 }
 
-// SyncEvents sincroniza eventos entre Google Calendar y base de datos local
+// SyncEvents sincroniza eventos entre Google Calendar y base de datos local.
+// Con el token de la sincronización anterior solo pide a Google los cambios; sin token, o si Google
+// lo invalidó (410 Gone), recorre el calendario completo y da de baja los eventos locales que ya no existen.
 func (s *GoogleCalendarService) SyncEvents(ctx context.Context, channelID string) (*SyncResult, error) {
 	s.logger.Info("Iniciando sincronización de eventos", map[string]interface{}{
 		"channel_id": channelID,
 	})
 
-	result := &SyncResult{}
-
 	// Obtener integración
 	integration, err := s.repo.GetIntegration(ctx, channelID)
 	if err != nil {
@@ -377,89 +440,35 @@ func (s *GoogleCalendarService) SyncEvents(ctx context.Context, channelID string
 	}
 
 	// Crear servicio de Google Calendar
-	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	calendarService, err := s.setupSvc.newCalendarService(ctx, client)
 	if err != nil {
 		return nil, fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
 	}
 
-	// Obtener eventos de Google Calendar
-	googleEvents, err := calendarService.Events.List(integration.CalendarID).
-		ShowDeleted(false).
-		SingleEvents(true).
-		TimeMin(time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339)). // Últimos 30 días
-		TimeMax(time.Now().Add(365 * 24 * time.Hour).Format(time.RFC3339)). // Próximo año
-		Do()
-	if err != nil {
-		return nil, fmt.Errorf("error al obtener eventos de Google Calendar: %w", err)
+	result, err := s.syncEvents(ctx, calendarService, integration, integration.SyncToken)
+	if err != nil && integration.SyncToken != "" && isSyncTokenExpired(err) {
+		s.logger.Warn("Token de sincronización vencido, se hará una sincronización completa", map[string]interface{}{
+			"channel_id": channelID,
+		})
+		result, err = s.syncEvents(ctx, calendarService, integration, "")
 	}
-
-	// Obtener eventos locales
-	localEvents, err := s.repo.GetEventsByChannel(ctx, channelID)
 	if err != nil {
-		return nil, fmt.Errorf("error al obtener eventos locales: %w", err)
-	}
-
-	// Crear mapas para comparación
-	googleEventMap := make(map[string]*calendar.Event)
-	for _, event := range googleEvents.Items {
-		googleEventMap[event.Id] = event
-	}
-
-	localEventMap := make(map[string]*domain.CalendarEvent)
-	for _, event := range localEvents {
-		localEventMap[event.GoogleID] = event
-	}
-
-	// Sincronizar eventos
-	for googleID, googleEvent := range googleEventMap {
-		if localEvent, exists := localEventMap[googleID]; exists {
-			// Evento existe en ambos, verificar si necesita actualización
-			if s.needsUpdate(localEvent, googleEvent) {
-				updatedEvent := s.convertFromGoogleEvent(googleEvent, localEvent.TenantID, localEvent.ChannelID, localEvent.CalendarID)
-				updatedEvent.ID = localEvent.ID
-				updatedEvent.UpdatedAt = time.Now()
-
-				err := s.repo.UpdateEvent(ctx, updatedEvent)
-				if err != nil {
-					result.Errors++
-					result.ErrorList = append(result.ErrorList, fmt.Sprintf("Error actualizando evento %s: %v", googleID, err))
-				} else {
-					result.Updated++
-				}
-			}
-		} else {
-			// Evento nuevo en Google Calendar
-			newEvent := s.convertFromGoogleEvent(googleEvent, integration.TenantID, channelID, integration.CalendarID)
-			newEvent.ID = uuid.New().String()
-			newEvent.CreatedAt = time.Now()
-			newEvent.UpdatedAt = time.Now()
-
-			err := s.repo.CreateEvent(ctx, newEvent)
-			if err != nil {
-				result.Errors++
-				result.ErrorList = append(result.ErrorList, fmt.Sprintf("Error creando evento %s: %v", googleID, err))
-			} else {
-				result.Created++
-			}
-		}
+		s.logger.Error("Error al sincronizar eventos", err, map[string]interface{}{
+			"channel_id": channelID,
+		})
+		return nil, err
 	}
 
-	// Verificar eventos eliminados en Google Calendar
-	for googleID, localEvent := range localEventMap {
-		if _, exists := googleEventMap[googleID]; !exists {
-			// Evento eliminado en Google Calendar
-			err := s.repo.DeleteEvent(ctx, localEvent.ID)
-			if err != nil {
-				result.Errors++
-				result.ErrorList = append(result.ErrorList, fmt.Sprintf("Error eliminando evento %s: %v", googleID, err))
-			} else {
-				result.Deleted++
-			}
+	// Si algún evento falló el token no avanza, así la próxima sincronización vuelve a traer esos cambios
+	if result.Errors == 0 {
+		if err := s.repo.UpdateSyncState(ctx, channelID, result.syncToken, time.Now()); err != nil {
+			return nil, fmt.Errorf("error al guardar estado de sincronización: %w", err)
 		}
 	}
 
 	s.logger.Info("Sincronización completada", map[string]interface{}{
 		"channel_id": channelID,
+		"full_sync":  result.FullSync,
 		"created":    result.Created,
 		"updated":    result.Updated,
 		"deleted":    result.Deleted,
@@ -469,31 +478,144 @@ func (s *GoogleCalendarService) SyncEvents(ctx context.Context, channelID string
 	return result, nil
 }
 
-// setupEventNotifications configura notificaciones para un evento
-func (s *GoogleCalendarService) setupEventNotifications(ctx context.Context, event *domain.CalendarEvent, reminders []domain.EventReminder) error {
-	// TODO: Implementar integración con servicios de notificación
-	// - Email notifications
-	// - SMS notifications
-	// - WhatsApp notifications
-	// - Telegram notifications
-
-	s.logger.Info("Configurando notificaciones para evento", map[string]interface{}{
-		"event_id":  event.ID,
-		"reminders": reminders,
-	})
+// syncEvents recorre todas las páginas de eventos de Google y las aplica sobre la base de datos local.
+// Con syncToken vacío hace una sincronización completa.
+func (s *GoogleCalendarService) syncEvents(ctx context.Context, calendarService *calendar.Service, integration *domain.GoogleCalendarIntegration, syncToken string) (*SyncResult, error) {
+	result := &SyncResult{FullSync: syncToken == ""}
+	seen := make(map[string]bool)
+
+	pageToken := ""
+	for {
+		call := calendarService.Events.List(integration.CalendarID).
+			SingleEvents(true).
+			MaxResults(syncPageSize).
+			Context(ctx)
+		if syncToken != "" {
+			call = call.SyncToken(syncToken)
+		}
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
 
-	// Por ahora, solo logueamos las notificaciones
-	for _, reminder := range reminders {
-		s.logger.Info("Recordatorio configurado", map[string]interface{}{
-			"event_id": event.ID,
-			"method":   reminder.Method,
-			"minutes":  reminder.Minutes,
-		})
+		page, err := call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("error al obtener eventos de Google Calendar: %w", err)
+		}
+
+		for _, googleEvent := range page.Items {
+			seen[googleEvent.Id] = true
+			s.applyGoogleEvent(ctx, integration, googleEvent, result)
+		}
+
+		if page.NextPageToken == "" {
+			result.syncToken = page.NextSyncToken
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	if result.FullSync {
+		if err := s.deleteMissingEvents(ctx, integration.ChannelID, seen, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// applyGoogleEvent da de baja los eventos cancelados en Google y crea o actualiza el resto
+func (s *GoogleCalendarService) applyGoogleEvent(ctx context.Context, integration *domain.GoogleCalendarIntegration, googleEvent *calendar.Event, result *SyncResult) {
+	localEvent, err := s.repo.GetEventByGoogleID(ctx, integration.ChannelID, googleEvent.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		result.addError("Error obteniendo evento %s: %v", googleEvent.Id, err)
+		return
+	}
+
+	switch {
+	case googleEvent.Status == string(domain.EventStatusCancelled):
+		if localEvent == nil {
+			return
+		}
+		if err := s.repo.DeleteEvent(ctx, localEvent.ID); err != nil {
+			result.addError("Error eliminando evento %s: %v", googleEvent.Id, err)
+			return
+		}
+		if err := s.notificationService.CancelReminders(ctx, localEvent.ID); err != nil {
+			result.addError("Error cancelando recordatorios del evento %s: %v", googleEvent.Id, err)
+		}
+		result.Deleted++
+
+	case localEvent != nil:
+		if !s.needsUpdate(localEvent, googleEvent) {
+			return
+		}
+		updatedEvent := s.convertFromGoogleEvent(googleEvent, localEvent.TenantID, localEvent.ChannelID, localEvent.CalendarID)
+		updatedEvent.ID = localEvent.ID
+		updatedEvent.CreatedAt = localEvent.CreatedAt
+		updatedEvent.UpdatedAt = time.Now()
+		if err := s.repo.UpdateEvent(ctx, localEvent.ID, updatedEvent); err != nil {
+			result.addError("Error actualizando evento %s: %v", googleEvent.Id, err)
+			return
+		}
+		if err := s.notificationService.ScheduleEventReminders(ctx, updatedEvent); err != nil {
+			result.addError("Error reprogramando recordatorios del evento %s: %v", googleEvent.Id, err)
+		}
+		result.Updated++
+
+	default:
+		newEvent := s.convertFromGoogleEvent(googleEvent, integration.TenantID, integration.ChannelID, integration.CalendarID)
+		newEvent.ID = uuid.New().String()
+		newEvent.CreatedAt = time.Now()
+		newEvent.UpdatedAt = time.Now()
+		if err := s.repo.CreateEvent(ctx, newEvent); err != nil {
+			result.addError("Error creando evento %s: %v", googleEvent.Id, err)
+			return
+		}
+		if err := s.notificationService.ScheduleEventReminders(ctx, newEvent); err != nil {
+			result.addError("Error programando recordatorios del evento %s: %v", googleEvent.Id, err)
+		}
+		result.Created++
+	}
+}
+
+// deleteMissingEvents da de baja los eventos locales que no aparecieron en una sincronización completa
+func (s *GoogleCalendarService) deleteMissingEvents(ctx context.Context, channelID string, seen map[string]bool, result *SyncResult) error {
+	var missing []*domain.CalendarEvent
+	for offset := 0; ; offset += localEventsPageSize {
+		localEvents, err := s.repo.GetEventsByChannel(ctx, channelID, localEventsPageSize, offset)
+		if err != nil {
+			return fmt.Errorf("error al obtener eventos locales: %w", err)
+		}
+		for _, event := range localEvents {
+			if !seen[event.GoogleID] {
+				missing = append(missing, event)
+			}
+		}
+		if len(localEvents) < localEventsPageSize {
+			break
+		}
+	}
+
+	for _, event := range missing {
+		if err := s.repo.DeleteEvent(ctx, event.ID); err != nil {
+			result.addError("Error eliminando evento %s: %v", event.GoogleID, err)
+			continue
+		}
+		if err := s.notificationService.CancelReminders(ctx, event.ID); err != nil {
+			result.addError("Error cancelando recordatorios del evento %s: %v", event.GoogleID, err)
+		}
+		result.Deleted++
 	}
 
 	return nil
 }
 
+// isSyncTokenExpired indica si Google rechazó el token de sincronización y pide una sincronización completa
+func isSyncTokenExpired(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusGone
+}
+
 // convertToGoogleEvent convierte un request de dominio a evento de Google Calendar
 func (s *GoogleCalendarService) convertToGoogleEvent(req *domain.CreateEventRequest) *calendar.Event {
 	event := &calendar.Event{
@@ -527,8 +649,8 @@ func (s *GoogleCalendarService) convertToGoogleEvent(req *domain.CreateEventRequ
 		attendees := make([]*calendar.EventAttendee, 0, len(req.Attendees))
 		for _, attendee := range req.Attendees {
 			attendees = append(attendees, &calendar.EventAttendee{
-				Email: attendee.Email,
-				Name:  attendee.Name,
+				Email:       attendee.Email,
+				DisplayName: attendee.Name,
 			})
 		}
 		event.Attendees = attendees
@@ -744,5 +866,19 @@ func (s *GoogleCalendarService) needsUpdate(localEvent *domain.CalendarEvent, go
 		}
 	}
 
+	// Comparar recordatorios, de los que depende cuándo se envían los avisos
+	var googleReminders []*calendar.EventReminder
+	if googleEvent.Reminders != nil {
+		googleReminders = googleEvent.Reminders.Overrides
+	}
+	if len(localEvent.Reminders) != len(googleReminders) {
+		return true
+	}
+	for i, reminder := range googleReminders {
+		if localEvent.Reminders[i].Method != reminder.Method || localEvent.Reminders[i].Minutes != int(reminder.Minutes) {
+			return true
+		}
+	}
+
 	return false
 }
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (r *memoryCalendarRepository) UpdateSyncState(ctx context.Context, channelID, syncToken string, lastSyncAt time.Time) error {
+	integration, ok := r.integrations[channelID]
+	if !ok {
+		return fmt.Errorf("integration not found: %s", channelID)
+	}
+	integration.SyncToken = syncToken
+	integration.LastSyncAt = &lastSyncAt
+	return nil
+}
+
+func (r *memoryCalendarRepository) CreateEvent(ctx context.Context, event *domain.CalendarEvent) error {
+	if event.Summary == "falla" {
+		return errors.New("insert failed")
+	}
+	copied := *event
+	r.events[event.ID] = &copied
+	return nil
+}
+
+func (r *memoryCalendarRepository) GetEventByGoogleID(ctx context.Context, channelID, googleID string) (*domain.CalendarEvent, error) {
+	for _, event := range r.events {
+		if event.ChannelID == channelID && event.GoogleID == googleID && event.DeletedAt == nil {
+			copied := *event
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("event not found: %w", sql.ErrNoRows)
+}
+
+func (r *memoryCalendarRepository) GetEventsByChannel(ctx context.Context, channelID string, limit, offset int) ([]*domain.CalendarEvent, error) {
+	var events []*domain.CalendarEvent
+	for _, event := range r.events {
+		if event.ChannelID == channelID && event.DeletedAt == nil {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	if offset >= len(events) {
+		return nil, nil
+	}
+	events = events[offset:]
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r *memoryCalendarRepository) UpdateEvent(ctx context.Context, eventID string, event *domain.CalendarEvent) error {
+	if _, ok := r.events[eventID]; !ok {
+		return fmt.Errorf("event not found: %s", eventID)
+	}
+	copied := *event
+	r.events[eventID] = &copied
+	return nil
+}
+
+// DeleteEvent da de baja el evento como la consulta del repositorio, sin borrar la fila
+func (r *memoryCalendarRepository) DeleteEvent(ctx context.Context, eventID string) error {
+	event, ok := r.events[eventID]
+	if !ok || event.DeletedAt != nil {
+		return fmt.Errorf("event not found: %s", eventID)
+	}
+	now := time.Now()
+	event.DeletedAt = &now
+	event.Status = domain.EventStatusCancelled
+	return nil
+}
+
+func googleEventJSON(id, status, summary string) string {
+	return fmt.Sprintf(`{"id":%q,"status":%q,"summary":%q,"start":{"dateTime":"2026-03-02T10:00:00Z"},"end":{"dateTime":"2026-03-02T11:00:00Z"}}`, id, status, summary)
+}
+
+func newTestCalendarService(t *testing.T, syncToken string) (*GoogleCalendarService, *memoryCalendarRepository, *fakeGoogleServer) {
+	setupSvc, repo, _, google := newTestSetupService(t)
+
+	accessToken, err := setupSvc.encryption.Encrypt("access-1")
+	require.NoError(t, err)
+	repo.integrations["channel-1"] = &domain.GoogleCalendarIntegration{
+		ID:          "integration-1",
+		TenantID:    "tenant-1",
+		ChannelID:   "channel-1",
+		CalendarID:  "primary",
+		AccessToken: accessToken,
+		TokenExpiry: time.Now().Add(time.Hour),
+		Status:      domain.StatusActive,
+		SyncToken:   syncToken,
+	}
+
+	notificationService := NewNotificationService(newMemoryScheduledNotificationRepository(), logger.NewLogger("error"))
+	service := NewGoogleCalendarService(setupSvc.config, setupSvc, repo, notificationService, logger.NewLogger("error"), setupSvc.encryption)
+	return service, repo, google
+}
+
+func TestSyncEventsPagesFullSyncThenAppliesIncrementalChanges(t *testing.T) {
+	service, repo, google := newTestCalendarService(t, "")
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	repo.events["local-1"] = &domain.CalendarEvent{ID: "local-1", ChannelID: "channel-1", GoogleID: "evt-1", Summary: "Vieja", Status: domain.EventStatusConfirmed, StartTime: start, EndTime: start.Add(time.Hour)}
+	repo.events["local-gone"] = &domain.CalendarEvent{ID: "local-gone", ChannelID: "channel-1", GoogleID: "evt-gone", Summary: "Borrada", Status: domain.EventStatusConfirmed}
+
+	google.events = func(query url.Values) (int, string) {
+		switch {
+		case query.Get("syncToken") == "sync-1":
+			return http.StatusOK, fmt.Sprintf(`{"items":[%s,%s],"nextSyncToken":"sync-2"}`,
+				googleEventJSON("evt-2", "cancelled", ""), googleEventJSON("evt-3", "confirmed", "Nueva"))
+		case query.Get("pageToken") == "page-2":
+			return http.StatusOK, fmt.Sprintf(`{"items":[%s],"nextSyncToken":"sync-1"}`, googleEventJSON("evt-2", "confirmed", "Demo"))
+		default:
+			return http.StatusOK, fmt.Sprintf(`{"items":[%s],"nextPageToken":"page-2"}`, googleEventJSON("evt-1", "confirmed", "Revisión"))
+		}
+	}
+
+	result, err := service.SyncEvents(context.Background(), "channel-1")
+	require.NoError(t, err)
+	assert.True(t, result.FullSync)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Deleted)
+	assert.Equal(t, "Revisión", repo.events["local-1"].Summary)
+	assert.NotNil(t, repo.events["local-gone"].DeletedAt)
+
+	// La sincronización completa no acota fechas y guarda el token de la última página
+	require.Len(t, google.eventQueries, 2)
+	assert.Empty(t, google.eventQueries[0].Get("timeMin"))
+	assert.Equal(t, "page-2", google.eventQueries[1].Get("pageToken"))
+	integration := repo.integrations["channel-1"]
+	assert.Equal(t, "sync-1", integration.SyncToken)
+	require.NotNil(t, integration.LastSyncAt)
+
+	// La siguiente sincronización solo aplica los cambios; el cancelado queda dado de baja, no borrado
+	result, err = service.SyncEvents(context.Background(), "channel-1")
+	require.NoError(t, err)
+	assert.False(t, result.FullSync)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Deleted)
+	assert.Equal(t, "sync-2", integration.SyncToken)
+
+	_, err = repo.GetEventByGoogleID(context.Background(), "channel-1", "evt-2")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Len(t, repo.events, 4)
+}
+
+func TestSyncEventsFallsBackToFullSyncWhenTokenExpires(t *testing.T) {
+	service, repo, google := newTestCalendarService(t, "stale")
+	google.events = func(query url.Values) (int, string) {
+		if query.Get("syncToken") == "stale" {
+			return http.StatusGone, `{"error":{"code":410,"message":"Sync token is no longer valid, a full sync is required."}}`
+		}
+		return http.StatusOK, fmt.Sprintf(`{"items":[%s,%s],"nextSyncToken":"sync-fresh"}`,
+			googleEventJSON("evt-1", "confirmed", "Demo"), googleEventJSON("evt-2", "confirmed", "falla"))
+	}
+
+	result, err := service.SyncEvents(context.Background(), "channel-1")
+	require.NoError(t, err)
+	assert.True(t, result.FullSync)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Errors)
+	require.Len(t, google.eventQueries, 2)
+	assert.Empty(t, google.eventQueries[1].Get("syncToken"))
+
+	// Con un evento fallido el estado de sincronización no avanza
+	integration := repo.integrations["channel-1"]
+	assert.Equal(t, "stale", integration.SyncToken)
+	assert.Nil(t, integration.LastSyncAt)
+
+	// Cualquier otro error de Google no dispara la sincronización completa
+	google.events = func(query url.Values) (int, string) {
+		return http.StatusInternalServerError, `{"error":{"code":500,"message":"backend error"}}`
+	}
+	_, err = service.SyncEvents(context.Background(), "channel-1")
+	assert.Error(t, err)
+	assert.Len(t, google.eventQueries, 3)
+}
@@ -2,13 +2,16 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"it-integration-service/internal/config"
 	"it-integration-service/internal/domain"
-	"it-integration-service/internal/repository"
 	"it-integration-service/pkg/logger"
 
 	"github.com/google/uuid"
@@ -17,21 +20,36 @@ import (
 	"google.golang.org/api/option"
 )
 
+// defaultOAuthStateTTL es la vigencia del state cuando no se configura otra
+const defaultOAuthStateTTL = 10 * time.Minute
+
+var (
+	// ErrInvalidOAuthState indica que el state del callback no existe o ya se usó
+	ErrInvalidOAuthState = errors.New("invalid or already used oauth state")
+	// ErrOAuthStateExpired indica que el callback llegó después del vencimiento del state
+	ErrOAuthStateExpired = errors.New("oauth state expired")
+	// ErrInvalidReturnURL indica que la URL de retorno no es absoluta o su host no está permitido
+	ErrInvalidReturnURL = errors.New("invalid return url")
+)
+
 // GoogleCalendarSetupService maneja la configuración OAuth2 para Google Calendar
 type GoogleCalendarSetupService struct {
 	config     *config.GoogleCalendarConfig
-	repo       repository.GoogleCalendarRepository
+	repo       domain.GoogleCalendarRepository
+	stateRepo  domain.OAuthStateRepository
+	watchRepo  domain.CalendarWatchChannelRepository
 	logger     logger.Logger
 	encryption *EncryptionService
+	now        func() time.Time
 }
 
-// OAuth2State representa el estado del flujo OAuth2
-type OAuth2State struct {
-	TenantID     string              `json:"tenant_id"`
-	ChannelID    string              `json:"channel_id"`
-	CalendarType domain.CalendarType `json:"calendar_type"`
-	StateToken   string              `json:"state_token"`
-	ExpiresAt    time.Time           `json:"expires_at"`
+// OAuthCallbackResult identifica la integración que completó el flujo OAuth2. Se retorna también
+// junto con el error cuando el state era válido, para volver a ReturnURL informando el fallo.
+type OAuthCallbackResult struct {
+	TenantID     string `json:"tenant_id"`
+	ChannelID    string `json:"channel_id"`
+	CalendarName string `json:"calendar_name,omitempty"`
+	ReturnURL    string `json:"-"`
 }
 
 // AuthURLResponse representa la respuesta con URL de autenticación
@@ -54,26 +72,38 @@ type IntegrationStatusResponse struct {
 }
 
 // NewGoogleCalendarSetupService crea una nueva instancia del servicio
-func NewGoogleCalendarSetupService(cfg *config.GoogleCalendarConfig, repo repository.GoogleCalendarRepository, logger logger.Logger, encryption *EncryptionService) *GoogleCalendarSetupService {
+func NewGoogleCalendarSetupService(cfg *config.GoogleCalendarConfig, repo domain.GoogleCalendarRepository, stateRepo domain.OAuthStateRepository, watchRepo domain.CalendarWatchChannelRepository, logger logger.Logger, encryption *EncryptionService) *GoogleCalendarSetupService {
 	return &GoogleCalendarSetupService{
 		config:     cfg,
 		repo:       repo,
+		stateRepo:  stateRepo,
+		watchRepo:  watchRepo,
 		logger:     logger,
 		encryption: encryption,
+		now:        time.Now,
 	}
 }
 
-// InitiateAuth inicia el flujo de autenticación OAuth2
-func (s *GoogleCalendarSetupService) InitiateAuth(ctx context.Context, tenantID string, calendarType domain.CalendarType) (*AuthURLResponse, error) {
+// InitiateAuth inicia el flujo de autenticación OAuth2. El state queda guardado con el canal
+// creado, el verificador PKCE y la URL de retorno hasta que lo consuma el callback.
+func (s *GoogleCalendarSetupService) InitiateAuth(ctx context.Context, tenantID string, calendarType domain.CalendarType, returnURL string) (*AuthURLResponse, error) {
 	s.logger.Info("Iniciando autenticación OAuth2 para Google Calendar", map[string]interface{}{
 		"tenant_id":     tenantID,
 		"calendar_type": calendarType,
 	})
 
-	// Generar state token único
-	stateToken := uuid.New().String()
+	if err := s.validateReturnURL(returnURL); err != nil {
+		return nil, err
+	}
+
+	// Configurar OAuth2
+	oauth2Config, err := s.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	// Crear o actualizar integración
+	// Crear integración pendiente de autorización
+	now := s.now()
 	channelID := uuid.New().String()
 	integration := &domain.GoogleCalendarIntegration{
 		ID:           channelID,
@@ -81,12 +111,12 @@ func (s *GoogleCalendarSetupService) InitiateAuth(ctx context.Context, tenantID
 		ChannelID:    channelID,
 		CalendarType: calendarType,
 		Status:       domain.StatusDisabled,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}
 
 	// Guardar integración en base de datos
-	err := s.repo.CreateIntegration(ctx, integration)
+	err = s.repo.CreateIntegration(ctx, integration)
 	if err != nil {
 		s.logger.Error("Error al crear integración de Google Calendar", err, map[string]interface{}{
 			"tenant_id":     tenantID,
@@ -95,96 +125,112 @@ func (s *GoogleCalendarSetupService) InitiateAuth(ctx context.Context, tenantID
 		return nil, fmt.Errorf("error al crear integración: %w", err)
 	}
 
-	// Configurar OAuth2
-	oauth2Config := &oauth2.Config{
-		ClientID:     s.config.ClientID,
-		ClientSecret: s.config.ClientSecret,
-		RedirectURL:  s.config.RedirectURL,
-		Scopes:       s.config.Scopes,
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  s.config.AuthURL,
-			TokenURL: s.config.TokenURL,
-		},
+	// Guardar state de un solo uso con el verificador PKCE
+	ttl := s.config.OAuthStateTTL
+	if ttl <= 0 {
+		ttl = defaultOAuthStateTTL
 	}
-
-	// Generar URL de autenticación
-	authURL := oauth2Config.AuthCodeURL(stateToken, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
-
-	// Guardar state token temporalmente (en producción usar Redis)
-	state := &OAuth2State{
+	state := &domain.OAuthState{
+		StateToken:   uuid.New().String(),
 		TenantID:     tenantID,
 		ChannelID:    channelID,
 		CalendarType: calendarType,
-		StateToken:   stateToken,
-		ExpiresAt:    time.Now().Add(10 * time.Minute), // 10 minutos de expiración
+		CodeVerifier: oauth2.GenerateVerifier(),
+		ReturnURL:    returnURL,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+	}
+	if err := s.stateRepo.Create(ctx, state); err != nil {
+		s.logger.Error("Error al guardar state OAuth2", err, map[string]interface{}{
+			"tenant_id":  tenantID,
+			"channel_id": channelID,
+		})
+		return nil, fmt.Errorf("error al guardar state: %w", err)
 	}
+	s.deleteExpiredStates(ctx)
 
-	// En producción, guardar en Redis o base de datos temporal
-	s.logger.Info("State token generado", map[string]interface{}{
-		"state_token": stateToken,
-		"expires_at":  state.ExpiresAt,
-	})
+	// Generar URL de autenticación
+	authURL := oauth2Config.AuthCodeURL(state.StateToken, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(state.CodeVerifier))
 
 	return &AuthURLResponse{
 		AuthURL:    authURL,
-		StateToken: stateToken,
+		StateToken: state.StateToken,
 		ExpiresAt:  state.ExpiresAt.Format(time.RFC3339),
 	}, nil
 }
 
-// HandleCallback maneja el callback de OAuth2
-func (s *GoogleCalendarSetupService) HandleCallback(ctx context.Context, code, stateToken string) error {
+// HandleCallback maneja el callback de OAuth2: consume el state, intercambia el código con su
+// verificador PKCE y guarda los tokens en la integración que inició el flujo. authError es el
+// parámetro error que envía Google cuando el usuario no autoriza el acceso.
+func (s *GoogleCalendarSetupService) HandleCallback(ctx context.Context, code, stateToken, authError string) (*OAuthCallbackResult, error) {
+	state, err := s.stateRepo.Consume(ctx, stateToken)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidOAuthState
+		}
+		return nil, fmt.Errorf("error al obtener state: %w", err)
+	}
+
+	result := &OAuthCallbackResult{
+		TenantID:  state.TenantID,
+		ChannelID: state.ChannelID,
+		ReturnURL: state.ReturnURL,
+	}
+	if s.now().After(state.ExpiresAt) {
+		return result, ErrOAuthStateExpired
+	}
+	if authError != "" {
+		return result, fmt.Errorf("autorización rechazada: %s", authError)
+	}
+	if code == "" {
+		return result, fmt.Errorf("falta el código de autorización")
+	}
+
 	s.logger.Info("Procesando callback OAuth2", map[string]interface{}{
-		"state_token": stateToken,
+		"tenant_id":  state.TenantID,
+		"channel_id": state.ChannelID,
 	})
 
-	// En producción, recuperar state de Redis o base de datos
-	// Por ahora, asumimos que el state es válido
-	// TODO: Implementar validación de state token
+	integration, err := s.repo.GetIntegration(ctx, state.ChannelID)
+	if err != nil {
+		return result, fmt.Errorf("error al obtener integración: %w", err)
+	}
+	if integration.TenantID != state.TenantID {
+		return result, fmt.Errorf("la integración %s no pertenece al tenant %s", state.ChannelID, state.TenantID)
+	}
 
 	// Configurar OAuth2
-	oauth2Config := &oauth2.Config{
-		ClientID:     s.config.ClientID,
-		ClientSecret: s.config.ClientSecret,
-		RedirectURL:  s.config.RedirectURL,
-		Scopes:       s.config.Scopes,
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  s.config.AuthURL,
-			TokenURL: s.config.TokenURL,
-		},
+	oauth2Config, err := s.oauth2Config(ctx)
+	if err != nil {
+		return result, err
 	}
 
 	// Intercambiar código por token
-	token, err := oauth2Config.Exchange(ctx, code)
+	token, err := oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(state.CodeVerifier))
 	if err != nil {
 		s.logger.Error("Error al intercambiar código por token", err, map[string]interface{}{
-			"state_token": stateToken,
+			"channel_id": state.ChannelID,
 		})
-		return fmt.Errorf("error al intercambiar código por token: %w", err)
+		return result, fmt.Errorf("error al intercambiar código por token: %w", err)
 	}
 
-	// Crear cliente HTTP con token
-	client := oauth2Config.Client(ctx, token)
-
-	// Crear servicio de Google Calendar
-	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	// Obtener información del calendario principal
+	calendarService, err := s.newCalendarService(ctx, oauth2Config.Client(ctx, token))
 	if err != nil {
 		s.logger.Error("Error al crear servicio de Google Calendar", err, nil)
-		return fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
+		return result, fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
 	}
-
-	// Obtener información del calendario principal
-	calendarList, err := calendarService.CalendarList.Get("primary").Do()
+	calendarList, err := calendarService.CalendarList.Get("primary").Context(ctx).Do()
 	if err != nil {
 		s.logger.Error("Error al obtener información del calendario", err, nil)
-		return fmt.Errorf("error al obtener información del calendario: %w", err)
+		return result, fmt.Errorf("error al obtener información del calendario: %w", err)
 	}
 
 	// Encriptar tokens
 	encryptedAccessToken, err := s.encryption.Encrypt(token.AccessToken)
 	if err != nil {
 		s.logger.Error("Error al encriptar access token", err, nil)
-		return fmt.Errorf("error al encriptar access token: %w", err)
+		return result, fmt.Errorf("error al encriptar access token: %w", err)
 	}
 
 	encryptedRefreshToken := ""
@@ -192,30 +238,27 @@ func (s *GoogleCalendarSetupService) HandleCallback(ctx context.Context, code, s
 		encryptedRefreshToken, err = s.encryption.Encrypt(token.RefreshToken)
 		if err != nil {
 			s.logger.Error("Error al encriptar refresh token", err, nil)
-			return fmt.Errorf("error al encriptar refresh token: %w", err)
+			return result, fmt.Errorf("error al encriptar refresh token: %w", err)
 		}
 	}
 
-	// Actualizar integración con tokens
-	integration := &domain.GoogleCalendarIntegration{
-		ChannelID:    stateToken, // Usar stateToken como ChannelID temporal
-		CalendarID:   "primary",
-		CalendarName: calendarList.Summary,
-		AccessToken:  encryptedAccessToken,
-		RefreshToken: encryptedRefreshToken,
-		TokenExpiry:  token.Expiry,
-		Status:       domain.StatusActive,
-		UpdatedAt:    time.Now(),
-	}
+	// Actualizar la integración creada al iniciar el flujo
+	integration.CalendarID = "primary"
+	integration.CalendarName = calendarList.Summary
+	integration.AccessToken = encryptedAccessToken
+	integration.RefreshToken = encryptedRefreshToken
+	integration.TokenExpiry = token.Expiry
+	integration.Status = domain.StatusActive
+	integration.UpdatedAt = s.now()
 
-	// Guardar integración actualizada
 	err = s.repo.UpdateIntegration(ctx, integration)
 	if err != nil {
 		s.logger.Error("Error al actualizar integración con tokens", err, map[string]interface{}{
 			"channel_id": integration.ChannelID,
 		})
-		return fmt.Errorf("error al actualizar integración: %w", err)
+		return result, fmt.Errorf("error al actualizar integración: %w", err)
 	}
+	result.CalendarName = integration.CalendarName
 
 	s.logger.Info("Autenticación OAuth2 completada exitosamente", map[string]interface{}{
 		"channel_id":    integration.ChannelID,
@@ -223,7 +266,66 @@ func (s *GoogleCalendarSetupService) HandleCallback(ctx context.Context, code, s
 		"token_expiry":  integration.TokenExpiry,
 	})
 
-	return nil
+	return result, nil
+}
+
+// validateReturnURL acepta URLs http(s) absolutas cuyo host esté en ReturnURLHosts
+func (s *GoogleCalendarSetupService) validateReturnURL(returnURL string) error {
+	if returnURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(returnURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Hostname() == "" {
+		return fmt.Errorf("%w: must be an absolute http(s) url", ErrInvalidReturnURL)
+	}
+	for _, host := range s.config.ReturnURLHosts {
+		if strings.EqualFold(strings.TrimSpace(host), parsed.Hostname()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: host %s is not allowed", ErrInvalidReturnURL, parsed.Hostname())
+}
+
+// deleteExpiredStates elimina los states vencidos sin interrumpir el flujo si falla
+func (s *GoogleCalendarSetupService) deleteExpiredStates(ctx context.Context) {
+	deleted, err := s.stateRepo.DeleteExpired(ctx, s.now())
+	if err != nil {
+		s.logger.Warn("No se pudieron eliminar los states OAuth2 vencidos", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("States OAuth2 vencidos eliminados", map[string]interface{}{
+			"deleted": deleted,
+		})
+	}
+}
+
+// GetIntegrationsByTenant obtiene el estado de las integraciones de un tenant, sin sus tokens
+func (s *GoogleCalendarSetupService) GetIntegrationsByTenant(ctx context.Context, tenantID string) ([]*IntegrationStatusResponse, error) {
+	integrations, err := s.repo.GetIntegrationsByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener integraciones: %w", err)
+	}
+
+	statuses := make([]*IntegrationStatusResponse, 0, len(integrations))
+	for _, integration := range integrations {
+		tokenExpiry := integration.TokenExpiry
+		statuses = append(statuses, &IntegrationStatusResponse{
+			ChannelID:       integration.ChannelID,
+			CalendarType:    integration.CalendarType,
+			CalendarID:      integration.CalendarID,
+			CalendarName:    integration.CalendarName,
+			Status:          integration.Status,
+			IsAuthenticated: integration.Status == domain.StatusActive,
+			TokenExpiry:     &tokenExpiry,
+			LastSync:        integration.LastSyncAt,
+		})
+	}
+
+	return statuses, nil
 }
 
 // RefreshToken refresca el token de acceso automáticamente
@@ -251,15 +353,9 @@ func (s *GoogleCalendarSetupService) RefreshToken(ctx context.Context, channelID
 	}
 
 	// Configurar OAuth2
-	oauth2Config := &oauth2.Config{
-		ClientID:     s.config.ClientID,
-		ClientSecret: s.config.ClientSecret,
-		RedirectURL:  s.config.RedirectURL,
-		Scopes:       s.config.Scopes,
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  s.config.AuthURL,
-			TokenURL: s.config.TokenURL,
-		},
+	oauth2Config, err := s.oauth2Config(ctx)
+	if err != nil {
+		return err
 	}
 
 	// Crear token para refresh
@@ -344,32 +440,41 @@ func (s *GoogleCalendarSetupService) GetIntegrationStatus(ctx context.Context, c
 		Status:          integration.Status,
 		IsAuthenticated: isAuthenticated,
 		TokenExpiry:     tokenExpiry,
-		LastSync:        &integration.UpdatedAt,
+		LastSync:        integration.LastSyncAt,
 	}, nil
 }
 
-// SetupWebhook configura webhooks para sincronización automática
-func (s *GoogleCalendarSetupService) SetupWebhook(ctx context.Context, channelID string) error {
+// SetupWebhook abre un canal push sobre el calendario de la integración y lo registra para enrutar
+// sus notificaciones. Los canales que la integración tenía abiertos se detienen, así renovar un
+// canal es volver a llamar a SetupWebhook.
+func (s *GoogleCalendarSetupService) SetupWebhook(ctx context.Context, channelID string) (*domain.CalendarWatchChannel, error) {
 	s.logger.Info("Configurando webhook para Google Calendar", map[string]interface{}{
 		"channel_id": channelID,
 	})
 
+	if s.config.WebhookURL == "" {
+		return nil, fmt.Errorf("GOOGLE_WEBHOOK_URL no está configurada")
+	}
+
 	// Obtener integración
 	integration, err := s.repo.GetIntegration(ctx, channelID)
 	if err != nil {
-		return fmt.Errorf("error al obtener integración: %w", err)
+		return nil, fmt.Errorf("error al obtener integración: %w", err)
 	}
 
-	// Crear cliente OAuth2
-	client, err := s.createOAuth2Client(ctx, integration)
+	calendarService, err := s.calendarServiceFor(ctx, integration)
 	if err != nil {
-		return fmt.Errorf("error al crear cliente OAuth2: %w", err)
+		return nil, err
 	}
 
-	// Crear servicio de Google Calendar
-	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	previous, err := s.watchRepo.ListActiveByChannel(ctx, channelID)
 	if err != nil {
-		return fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
+		return nil, fmt.Errorf("error al obtener canales push: %w", err)
+	}
+
+	ttl := s.config.WatchChannelTTL
+	if ttl <= 0 {
+		ttl = defaultWatchChannelTTL
 	}
 
 	// Configurar webhook
@@ -377,22 +482,43 @@ func (s *GoogleCalendarSetupService) SetupWebhook(ctx context.Context, channelID
 		Id:         uuid.New().String(),
 		Type:       "web_hook",
 		Address:    s.config.WebhookURL,
-		Token:      s.config.WebhookSecret,
-		Expiration: time.Now().Add(24*time.Hour).UnixNano() / 1e6, // 24 horas en milisegundos
+		Token:      uuid.New().String(),
+		Expiration: s.now().Add(ttl).UnixMilli(),
 	}
 
 	// Registrar webhook
-	_, err = calendarService.Events.Watch("primary", webhook).Do()
+	created, err := calendarService.Events.Watch(integration.CalendarID, webhook).Context(ctx).Do()
 	if err != nil {
 		s.logger.Error("Error al configurar webhook", err, map[string]interface{}{
 			"channel_id": channelID,
 		})
-		return fmt.Errorf("error al configurar webhook: %w", err)
+		return nil, fmt.Errorf("error al configurar webhook: %w", err)
+	}
+
+	// Google puede acortar la vigencia pedida
+	expiration := webhook.Expiration
+	if created.Expiration > 0 {
+		expiration = created.Expiration
+	}
+
+	watchChannel := &domain.CalendarWatchChannel{
+		ID:         webhook.Id,
+		ChannelID:  channelID,
+		CalendarID: integration.CalendarID,
+		ResourceID: created.ResourceId,
+		Token:      webhook.Token,
+		Expiration: time.UnixMilli(expiration),
+		CreatedAt:  s.now(),
+	}
+	if err := s.watchRepo.Create(ctx, watchChannel); err != nil {
+		// Sin registro las notificaciones no se podrían enrutar, así que se cierra el canal
+		s.stopWatchChannel(ctx, calendarService, watchChannel)
+		return nil, fmt.Errorf("error al registrar canal push: %w", err)
 	}
 
 	// Actualizar integración con información del webhook
-	integration.WebhookChannel = webhook.Id
-	integration.WebhookResource = fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events", integration.CalendarID)
+	integration.WebhookChannel = watchChannel.ID
+	integration.WebhookResource = created.ResourceUri
 	integration.UpdatedAt = time.Now()
 
 	err = s.repo.UpdateIntegration(ctx, integration)
@@ -400,37 +526,62 @@ func (s *GoogleCalendarSetupService) SetupWebhook(ctx context.Context, channelID
 		s.logger.Error("Error al actualizar integración con webhook", err, map[string]interface{}{
 			"channel_id": channelID,
 		})
-		return fmt.Errorf("error al actualizar integración: %w", err)
+	}
+
+	for _, old := range previous {
+		s.stopWatchChannel(ctx, calendarService, old)
 	}
 
 	s.logger.Info("Webhook configurado exitosamente", map[string]interface{}{
 		"channel_id":      channelID,
-		"webhook_id":      webhook.Id,
+		"webhook_id":      watchChannel.ID,
 		"webhook_address": webhook.Address,
-		"expiration":      webhook.Expiration,
+		"expiration":      watchChannel.Expiration,
 	})
 
-	return nil
+	return watchChannel, nil
 }
 
-// createOAuth2Client crea un cliente OAuth2 con refresh automático
-func (s *GoogleCalendarSetupService) createOAuth2Client(ctx context.Context, integration *domain.GoogleCalendarIntegration) (*http.Client, error) {
-	// Desencriptar access token
-	accessToken, err := s.encryption.Decrypt(integration.AccessToken)
+// oauth2Config arma la configuración OAuth2 con las credenciales de cliente vigentes
+func (s *GoogleCalendarSetupService) oauth2Config(ctx context.Context) (*oauth2.Config, error) {
+	clientID, clientSecret, err := s.config.OAuthClientCredentials(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error al desencriptar access token: %w", err)
+		return nil, fmt.Errorf("error al obtener credenciales OAuth2 de Google: %w", err)
 	}
 
-	// Configurar OAuth2
-	oauth2Config := &oauth2.Config{
-		ClientID:     s.config.ClientID,
-		ClientSecret: s.config.ClientSecret,
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
 		RedirectURL:  s.config.RedirectURL,
 		Scopes:       s.config.Scopes,
 		Endpoint: oauth2.Endpoint{
 			AuthURL:  s.config.AuthURL,
 			TokenURL: s.config.TokenURL,
 		},
+	}, nil
+}
+
+// newCalendarService crea el cliente de la API de Calendar sobre APIBaseURL
+func (s *GoogleCalendarSetupService) newCalendarService(ctx context.Context, client *http.Client) (*calendar.Service, error) {
+	opts := []option.ClientOption{option.WithHTTPClient(client)}
+	if s.config.APIBaseURL != "" {
+		opts = append(opts, option.WithEndpoint(strings.TrimRight(s.config.APIBaseURL, "/")+"/calendar/v3/"))
+	}
+	return calendar.NewService(ctx, opts...)
+}
+
+// createOAuth2Client crea un cliente OAuth2 con refresh automático
+func (s *GoogleCalendarSetupService) createOAuth2Client(ctx context.Context, integration *domain.GoogleCalendarIntegration) (*http.Client, error) {
+	// Desencriptar access token
+	accessToken, err := s.encryption.Decrypt(integration.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("error al desencriptar access token: %w", err)
+	}
+
+	// Configurar OAuth2
+	oauth2Config, err := s.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Crear token
@@ -479,7 +630,7 @@ func (s *GoogleCalendarSetupService) ValidateToken(ctx context.Context, channelI
 		return false, fmt.Errorf("error al crear cliente OAuth2: %w", err)
 	}
 
-	calendarService, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	calendarService, err := s.newCalendarService(ctx, client)
 	if err != nil {
 		return false, fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
 	}
@@ -505,6 +656,9 @@ func (s *GoogleCalendarSetupService) RevokeAccess(ctx context.Context, channelID
 		return fmt.Errorf("error al obtener integración: %w", err)
 	}
 
+	// Los canales push se detienen mientras los tokens todavía son válidos
+	s.StopWatchChannels(ctx, integration)
+
 	// Desencriptar access token
 	accessToken, err := s.encryption.Decrypt(integration.AccessToken)
 	if err != nil {
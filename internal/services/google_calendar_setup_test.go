@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/calendar/v3"
+)
+
+// memoryOAuthStateRepository consume cada state una sola vez como la sentencia UPDATE ... RETURNING
+type memoryOAuthStateRepository struct {
+	states map[string]*domain.OAuthState
+}
+
+func (r *memoryOAuthStateRepository) Create(ctx context.Context, state *domain.OAuthState) error {
+	copied := *state
+	r.states[state.StateToken] = &copied
+	return nil
+}
+
+func (r *memoryOAuthStateRepository) Consume(ctx context.Context, stateToken string) (*domain.OAuthState, error) {
+	state, ok := r.states[stateToken]
+	if !ok || state.ConsumedAt != nil {
+		return nil, fmt.Errorf("oauth state not found: %w", sql.ErrNoRows)
+	}
+	now := time.Now()
+	state.ConsumedAt = &now
+	copied := *state
+	return &copied, nil
+}
+
+func (r *memoryOAuthStateRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for token, state := range r.states {
+		if state.ExpiresAt.Before(before) {
+			delete(r.states, token)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// memoryCalendarRepository guarda las integraciones por canal y los eventos por ID
+type memoryCalendarRepository struct {
+	domain.GoogleCalendarRepository
+	integrations map[string]*domain.GoogleCalendarIntegration
+	events       map[string]*domain.CalendarEvent
+}
+
+func (r *memoryCalendarRepository) CreateIntegration(ctx context.Context, integration *domain.GoogleCalendarIntegration) error {
+	copied := *integration
+	r.integrations[integration.ChannelID] = &copied
+	return nil
+}
+
+func (r *memoryCalendarRepository) GetIntegration(ctx context.Context, channelID string) (*domain.GoogleCalendarIntegration, error) {
+	integration, ok := r.integrations[channelID]
+	if !ok {
+		return nil, fmt.Errorf("integration not found: %s", channelID)
+	}
+	copied := *integration
+	return &copied, nil
+}
+
+func (r *memoryCalendarRepository) UpdateIntegration(ctx context.Context, integration *domain.GoogleCalendarIntegration) error {
+	if _, ok := r.integrations[integration.ChannelID]; !ok {
+		return fmt.Errorf("integration not found: %s", integration.ChannelID)
+	}
+	copied := *integration
+	r.integrations[integration.ChannelID] = &copied
+	return nil
+}
+
+// fakeGoogleServer responde el intercambio de códigos, el calendario principal y, si events está
+// definido, el listado de eventos
+type fakeGoogleServer struct {
+	mu           sync.Mutex
+	verifiers    []string
+	events       func(query url.Values) (int, string)
+	eventQueries []url.Values
+	watches      []calendar.Channel
+	stopped      []calendar.Channel
+}
+
+func (g *fakeGoogleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/token":
+		_ = r.ParseForm()
+		g.mu.Lock()
+		g.verifiers = append(g.verifiers, r.PostForm.Get("code_verifier"))
+		g.mu.Unlock()
+		if r.PostForm.Get("code") != "valid-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"access-1","refresh_token":"refresh-1","token_type":"Bearer","expires_in":3600}`))
+	case "/calendar/v3/users/me/calendarList/primary":
+		_, _ = w.Write([]byte(`{"id":"ana@example.com","summary":"Agenda de Ana"}`))
+	case "/calendar/v3/calendars/primary/events":
+		if g.events == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		g.mu.Lock()
+		g.eventQueries = append(g.eventQueries, r.URL.Query())
+		g.mu.Unlock()
+		status, body := g.events(r.URL.Query())
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	case "/calendar/v3/calendars/primary/events/watch":
+		var channel calendar.Channel
+		_ = json.NewDecoder(r.Body).Decode(&channel)
+		g.mu.Lock()
+		g.watches = append(g.watches, channel)
+		g.mu.Unlock()
+		_, _ = fmt.Fprintf(w, `{"kind":"api#channel","id":%q,"resourceId":"resource-%d","resourceUri":"https://www.googleapis.com/calendar/v3/calendars/primary/events","expiration":"%d"}`,
+			channel.Id, len(g.watches), channel.Expiration)
+	case "/calendar/v3/channels/stop":
+		var channel calendar.Channel
+		_ = json.NewDecoder(r.Body).Decode(&channel)
+		g.mu.Lock()
+		g.stopped = append(g.stopped, channel)
+		g.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestSetupService(t *testing.T) (*GoogleCalendarSetupService, *memoryCalendarRepository, *memoryOAuthStateRepository, *fakeGoogleServer) {
+	google := &fakeGoogleServer{}
+	server := httptest.NewServer(google)
+	t.Cleanup(server.Close)
+
+	encryption, err := NewEncryptionService("0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+
+	repo := &memoryCalendarRepository{
+		integrations: make(map[string]*domain.GoogleCalendarIntegration),
+		events:       make(map[string]*domain.CalendarEvent),
+	}
+	stateRepo := &memoryOAuthStateRepository{states: make(map[string]*domain.OAuthState)}
+	service := NewGoogleCalendarSetupService(&config.GoogleCalendarConfig{
+		ClientID:       "client-id",
+		ClientSecret:   "client-secret",
+		RedirectURL:    "https://integrations.example.com/api/v1/integrations/google-calendar/callback",
+		Scopes:         []string{"https://www.googleapis.com/auth/calendar"},
+		APIBaseURL:     server.URL,
+		TokenURL:       server.URL + "/token",
+		AuthURL:        server.URL + "/auth",
+		OAuthStateTTL:  10 * time.Minute,
+		ReturnURLHosts: []string{"app.example.com"},
+		WebhookURL:     "https://integrations.example.com/api/v1/webhooks/google-calendar",
+	}, repo, stateRepo, &memoryWatchChannelRepository{channels: make(map[string]*domain.CalendarWatchChannel)}, logger.NewLogger("error"), encryption)
+	return service, repo, stateRepo, google
+}
+
+func TestGoogleCalendarOAuthFlowWithPKCE(t *testing.T) {
+	service, repo, stateRepo, google := newTestSetupService(t)
+	ctx := context.Background()
+
+	response, err := service.InitiateAuth(ctx, "tenant-1", domain.CalendarTypeWork, "https://app.example.com/settings/calendar?tab=1")
+	require.NoError(t, err)
+
+	require.Contains(t, stateRepo.states, response.StateToken)
+	state := stateRepo.states[response.StateToken]
+	assert.Equal(t, "tenant-1", state.TenantID)
+	assert.Equal(t, domain.CalendarTypeWork, state.CalendarType)
+	require.Contains(t, repo.integrations, state.ChannelID)
+
+	// La URL de autorización lleva el state y el desafío PKCE del verificador guardado
+	authURL, err := url.Parse(response.AuthURL)
+	require.NoError(t, err)
+	challenge := sha256.Sum256([]byte(state.CodeVerifier))
+	assert.Equal(t, response.StateToken, authURL.Query().Get("state"))
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(challenge[:]), authURL.Query().Get("code_challenge"))
+
+	result, err := service.HandleCallback(ctx, "valid-code", response.StateToken, "")
+	require.NoError(t, err)
+	assert.Equal(t, state.ChannelID, result.ChannelID)
+	assert.Equal(t, "https://app.example.com/settings/calendar?tab=1", result.ReturnURL)
+	assert.Equal(t, []string{state.CodeVerifier}, google.verifiers)
+
+	// Los tokens quedan cifrados en la integración creada al iniciar el flujo
+	integration := repo.integrations[state.ChannelID]
+	assert.Equal(t, domain.StatusActive, integration.Status)
+	assert.Equal(t, "Agenda de Ana", integration.CalendarName)
+	accessToken, err := service.encryption.Decrypt(integration.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", accessToken)
+
+	// El state es de un solo uso
+	_, err = service.HandleCallback(ctx, "valid-code", response.StateToken, "")
+	assert.ErrorIs(t, err, ErrInvalidOAuthState)
+}
+
+func TestGoogleCalendarOAuthCallbackFailures(t *testing.T) {
+	service, repo, stateRepo, google := newTestSetupService(t)
+	ctx := context.Background()
+
+	_, err := service.InitiateAuth(ctx, "tenant-1", domain.CalendarTypeWork, "https://evil.example.net/steal")
+	assert.ErrorIs(t, err, ErrInvalidReturnURL)
+	assert.Empty(t, repo.integrations)
+
+	// Un state vencido se consume y retorna la URL de retorno para informar el fallo
+	response, err := service.InitiateAuth(ctx, "tenant-1", domain.CalendarTypePersonal, "https://app.example.com/done")
+	require.NoError(t, err)
+	service.now = func() time.Time { return time.Now().Add(time.Hour) }
+	result, err := service.HandleCallback(ctx, "valid-code", response.StateToken, "")
+	assert.ErrorIs(t, err, ErrOAuthStateExpired)
+	require.NotNil(t, result)
+	assert.Equal(t, "https://app.example.com/done", result.ReturnURL)
+	assert.Empty(t, google.verifiers)
+	service.now = time.Now
+
+	// El rechazo del usuario no intercambia ningún código
+	response, err = service.InitiateAuth(ctx, "tenant-1", domain.CalendarTypePersonal, "")
+	require.NoError(t, err)
+	result, err = service.HandleCallback(ctx, "", response.StateToken, "access_denied")
+	assert.EqualError(t, err, "autorización rechazada: access_denied")
+	assert.Equal(t, domain.StatusDisabled, repo.integrations[result.ChannelID].Status)
+	assert.Empty(t, google.verifiers)
+
+	// Al iniciar otro flujo se eliminan los states vencidos
+	stateRepo.states["old"] = &domain.OAuthState{StateToken: "old", ExpiresAt: time.Now().Add(-time.Minute)}
+	_, err = service.InitiateAuth(ctx, "tenant-1", domain.CalendarTypePersonal, "")
+	require.NoError(t, err)
+	assert.NotContains(t, stateRepo.states, "old")
+
+	_, err = service.HandleCallback(ctx, "valid-code", "unknown", "")
+	assert.ErrorIs(t, err, ErrInvalidOAuthState)
+}
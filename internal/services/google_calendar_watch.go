@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+const (
+	// defaultWatchChannelTTL es la vigencia pedida para un canal push cuando no se configura otra
+	defaultWatchChannelTTL = 7 * 24 * time.Hour
+	// defaultWatchRenewBefore es la anticipación de la renovación cuando no se configura otra
+	defaultWatchRenewBefore = 24 * time.Hour
+	// watchSyncTimeout es el tiempo máximo de la sincronización disparada por una notificación push
+	watchSyncTimeout = 5 * time.Minute
+)
+
+var (
+	// ErrUnknownWatchChannel indica que la notificación llegó por un canal push no registrado o detenido
+	ErrUnknownWatchChannel = errors.New("unknown calendar watch channel")
+	// ErrInvalidWatchToken indica que el token o el recurso de la notificación no coinciden con los del canal
+	ErrInvalidWatchToken = errors.New("invalid calendar watch channel token")
+)
+
+// StopWatchChannels detiene los canales push abiertos de la integración
+func (s *GoogleCalendarSetupService) StopWatchChannels(ctx context.Context, integration *domain.GoogleCalendarIntegration) {
+	channels, err := s.watchRepo.ListActiveByChannel(ctx, integration.ChannelID)
+	if err != nil {
+		s.logger.Error("Error al obtener canales push", err, map[string]interface{}{
+			"channel_id": integration.ChannelID,
+		})
+		return
+	}
+	if len(channels) == 0 {
+		return
+	}
+
+	// Sin tokens válidos los canales solo se marcan detenidos y vencen solos en Google
+	calendarService, err := s.calendarServiceFor(ctx, integration)
+	if err != nil {
+		s.logger.Warn("No se pueden detener los canales push en Google", map[string]interface{}{
+			"channel_id": integration.ChannelID,
+			"error":      err.Error(),
+		})
+		calendarService = nil
+	}
+
+	for _, channel := range channels {
+		s.stopWatchChannel(ctx, calendarService, channel)
+	}
+}
+
+// RenewWatchChannels reabre los canales push que vencen dentro de WatchRenewBefore y detiene los de
+// integraciones que ya no están activas. Retorna cuántas integraciones renovó.
+func (s *GoogleCalendarSetupService) RenewWatchChannels(ctx context.Context) (int, error) {
+	renewBefore := s.config.WatchRenewBefore
+	if renewBefore <= 0 {
+		renewBefore = defaultWatchRenewBefore
+	}
+
+	expiring, err := s.watchRepo.ListExpiring(ctx, s.now().Add(renewBefore))
+	if err != nil {
+		return 0, fmt.Errorf("error al obtener canales push por vencer: %w", err)
+	}
+
+	renewed := 0
+	visited := make(map[string]bool)
+	for _, channel := range expiring {
+		if visited[channel.ChannelID] {
+			continue
+		}
+		visited[channel.ChannelID] = true
+
+		integration, err := s.repo.GetIntegration(ctx, channel.ChannelID)
+		if err != nil {
+			s.logger.Error("Error al obtener integración del canal push", err, map[string]interface{}{
+				"channel_id":       channel.ChannelID,
+				"watch_channel_id": channel.ID,
+			})
+			continue
+		}
+		if integration.Status != domain.StatusActive {
+			s.StopWatchChannels(ctx, integration)
+			continue
+		}
+
+		if _, err := s.SetupWebhook(ctx, channel.ChannelID); err != nil {
+			s.logger.Error("Error al renovar canal push", err, map[string]interface{}{
+				"channel_id":       channel.ChannelID,
+				"watch_channel_id": channel.ID,
+			})
+			continue
+		}
+		renewed++
+	}
+
+	return renewed, nil
+}
+
+// ResolveWatchChannel identifica el canal push de una notificación y verifica su token y su recurso
+func (s *GoogleCalendarSetupService) ResolveWatchChannel(ctx context.Context, notification *domain.WebhookNotification) (*domain.CalendarWatchChannel, error) {
+	if notification.WatchChannelID == "" {
+		return nil, ErrUnknownWatchChannel
+	}
+
+	channel, err := s.watchRepo.GetByID(ctx, notification.WatchChannelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUnknownWatchChannel
+		}
+		return nil, fmt.Errorf("error al obtener canal push: %w", err)
+	}
+	if channel.StoppedAt != nil {
+		return nil, ErrUnknownWatchChannel
+	}
+
+	if subtle.ConstantTimeCompare([]byte(channel.Token), []byte(notification.Token)) != 1 || channel.ResourceID != notification.ResourceID {
+		return nil, ErrInvalidWatchToken
+	}
+
+	return channel, nil
+}
+
+// stopWatchChannel detiene el canal en Google y lo marca detenido. Un fallo en Google solo se
+// registra: el canal vence solo y sus notificaciones ya no se enrutan.
+func (s *GoogleCalendarSetupService) stopWatchChannel(ctx context.Context, calendarService *calendar.Service, channel *domain.CalendarWatchChannel) {
+	if calendarService != nil {
+		err := calendarService.Channels.Stop(&calendar.Channel{Id: channel.ID, ResourceId: channel.ResourceID}).Context(ctx).Do()
+		if err != nil {
+			s.logger.Warn("No se pudo detener el canal push en Google", map[string]interface{}{
+				"channel_id":       channel.ChannelID,
+				"watch_channel_id": channel.ID,
+				"error":            err.Error(),
+			})
+		}
+	}
+
+	if err := s.watchRepo.MarkStopped(ctx, channel.ID, s.now()); err != nil {
+		s.logger.Error("Error al marcar canal push como detenido", err, map[string]interface{}{
+			"watch_channel_id": channel.ID,
+		})
+	}
+}
+
+// calendarServiceFor crea el cliente de Calendar con los tokens de la integración
+func (s *GoogleCalendarSetupService) calendarServiceFor(ctx context.Context, integration *domain.GoogleCalendarIntegration) (*calendar.Service, error) {
+	client, err := s.createOAuth2Client(ctx, integration)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear cliente OAuth2: %w", err)
+	}
+
+	calendarService, err := s.newCalendarService(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear servicio de Google Calendar: %w", err)
+	}
+
+	return calendarService, nil
+}
+
+// HandlePushNotification enruta una notificación push a su integración y, si el calendario cambió,
+// sincroniza en segundo plano. La notificación sync solo confirma la apertura del canal.
+func (s *GoogleCalendarService) HandlePushNotification(ctx context.Context, notification *domain.WebhookNotification) (*domain.CalendarWatchChannel, error) {
+	channel, err := s.setupSvc.ResolveWatchChannel(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	if notification.State != "sync" {
+		s.requestSync(channel.ChannelID)
+	}
+
+	return channel, nil
+}
+
+// requestSync sincroniza el canal en segundo plano. Las notificaciones que llegan mientras corre la
+// sincronización del mismo canal se juntan en una sola sincronización posterior.
+func (s *GoogleCalendarService) requestSync(channelID string) {
+	s.syncMu.Lock()
+	if _, running := s.syncing[channelID]; running {
+		s.syncing[channelID] = true
+		s.syncMu.Unlock()
+		return
+	}
+	s.syncing[channelID] = false
+	s.syncMu.Unlock()
+
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), watchSyncTimeout)
+			if _, err := s.SyncEvents(ctx, channelID); err != nil {
+				s.logger.Error("Error al sincronizar eventos por notificación push", err, map[string]interface{}{
+					"channel_id": channelID,
+				})
+			}
+			cancel()
+
+			s.syncMu.Lock()
+			if !s.syncing[channelID] {
+				delete(s.syncing, channelID)
+				s.syncMu.Unlock()
+				return
+			}
+			s.syncing[channelID] = false
+			s.syncMu.Unlock()
+		}
+	}()
+}
+
+// GoogleCalendarWatchRenewer renueva periódicamente los canales push antes de que venzan
+type GoogleCalendarWatchRenewer struct {
+	setupSvc *GoogleCalendarSetupService
+	config   *config.GoogleCalendarConfig
+	logger   logger.Logger
+}
+
+// NewGoogleCalendarWatchRenewer crea una nueva instancia del renovador de canales push
+func NewGoogleCalendarWatchRenewer(setupSvc *GoogleCalendarSetupService, cfg *config.GoogleCalendarConfig, logger logger.Logger) *GoogleCalendarWatchRenewer {
+	return &GoogleCalendarWatchRenewer{
+		setupSvc: setupSvc,
+		config:   cfg,
+		logger:   logger,
+	}
+}
+
+// Start renueva los canales al iniciar y luego cada WatchRenewInterval hasta que se cancele el contexto
+func (r *GoogleCalendarWatchRenewer) Start(ctx context.Context) {
+	if r.config.WebhookURL == "" || r.config.WatchRenewInterval <= 0 {
+		r.logger.Info("Google Calendar watch renewer is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.config.WatchRenewInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := r.setupSvc.RenewWatchChannels(ctx); err != nil {
+				r.logger.Error("Failed to renew Google Calendar watch channels", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	r.logger.Info("Google Calendar watch renewer started", map[string]interface{}{
+		"interval":     r.config.WatchRenewInterval,
+		"renew_before": r.config.WatchRenewBefore,
+	})
+}
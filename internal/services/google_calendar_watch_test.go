@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryWatchChannelRepository guarda los canales push por ID
+type memoryWatchChannelRepository struct {
+	mu       sync.Mutex
+	channels map[string]*domain.CalendarWatchChannel
+}
+
+func (r *memoryWatchChannelRepository) Create(ctx context.Context, channel *domain.CalendarWatchChannel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *channel
+	r.channels[channel.ID] = &copied
+	return nil
+}
+
+func (r *memoryWatchChannelRepository) GetByID(ctx context.Context, id string) (*domain.CalendarWatchChannel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	channel, ok := r.channels[id]
+	if !ok {
+		return nil, fmt.Errorf("calendar watch channel not found: %w", sql.ErrNoRows)
+	}
+	copied := *channel
+	return &copied, nil
+}
+
+func (r *memoryWatchChannelRepository) ListActiveByChannel(ctx context.Context, channelID string) ([]*domain.CalendarWatchChannel, error) {
+	return r.list(func(channel *domain.CalendarWatchChannel) bool { return channel.ChannelID == channelID }), nil
+}
+
+func (r *memoryWatchChannelRepository) ListExpiring(ctx context.Context, before time.Time) ([]*domain.CalendarWatchChannel, error) {
+	return r.list(func(channel *domain.CalendarWatchChannel) bool { return channel.Expiration.Before(before) }), nil
+}
+
+func (r *memoryWatchChannelRepository) MarkStopped(ctx context.Context, id string, stoppedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if channel, ok := r.channels[id]; ok && channel.StoppedAt == nil {
+		channel.StoppedAt = &stoppedAt
+	}
+	return nil
+}
+
+func (r *memoryWatchChannelRepository) list(match func(*domain.CalendarWatchChannel) bool) []*domain.CalendarWatchChannel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var channels []*domain.CalendarWatchChannel
+	for _, channel := range r.channels {
+		if channel.StoppedAt == nil && match(channel) {
+			copied := *channel
+			channels = append(channels, &copied)
+		}
+	}
+	return channels
+}
+
+func TestSetupWebhookRegistersAndRenewsWatchChannels(t *testing.T) {
+	service, repo, google := newTestCalendarService(t, "")
+	setupSvc := service.setupSvc
+	watchRepo := setupSvc.watchRepo.(*memoryWatchChannelRepository)
+	ctx := context.Background()
+
+	first, err := setupSvc.SetupWebhook(ctx, "channel-1")
+	require.NoError(t, err)
+	assert.Equal(t, "primary", first.CalendarID)
+	assert.Equal(t, "resource-1", first.ResourceID)
+	assert.NotEmpty(t, first.Token)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), first.Expiration, time.Minute)
+	assert.Equal(t, first.ID, repo.integrations["channel-1"].WebhookChannel)
+
+	// Google recibe la dirección del webhook y el token propio del canal
+	require.Len(t, google.watches, 1)
+	assert.Equal(t, "https://integrations.example.com/api/v1/webhooks/google-calendar", google.watches[0].Address)
+	assert.Equal(t, first.Token, google.watches[0].Token)
+
+	// Nada vence dentro de la ventana de renovación
+	renewed, err := setupSvc.RenewWatchChannels(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, renewed)
+
+	// Cerca del vencimiento se abre un canal nuevo y se detiene el anterior
+	setupSvc.now = func() time.Time { return time.Now().Add(6*24*time.Hour + time.Hour) }
+	renewed, err = setupSvc.RenewWatchChannels(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, renewed)
+	require.Len(t, google.watches, 2)
+	require.Len(t, google.stopped, 1)
+	assert.Equal(t, first.ID, google.stopped[0].Id)
+	assert.Equal(t, "resource-1", google.stopped[0].ResourceId)
+
+	active, _ := watchRepo.ListActiveByChannel(ctx, "channel-1")
+	require.Len(t, active, 1)
+	assert.Equal(t, google.watches[1].Id, active[0].ID)
+
+	// Los canales de una integración desactivada se detienen sin renovarse
+	repo.integrations["channel-1"].Status = domain.StatusDisabled
+	setupSvc.now = func() time.Time { return time.Now().Add(14 * 24 * time.Hour) }
+	renewed, err = setupSvc.RenewWatchChannels(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, renewed)
+	assert.Len(t, google.watches, 2)
+	assert.Len(t, google.stopped, 2)
+	active, _ = watchRepo.ListActiveByChannel(ctx, "channel-1")
+	assert.Empty(t, active)
+}
+
+func TestHandlePushNotificationRoutesToIntegration(t *testing.T) {
+	service, repo, google := newTestCalendarService(t, "")
+	google.events = func(query url.Values) (int, string) {
+		return http.StatusOK, fmt.Sprintf(`{"items":[%s],"nextSyncToken":"sync-1"}`, googleEventJSON("evt-1", "confirmed", "Demo"))
+	}
+	ctx := context.Background()
+
+	channel, err := service.setupSvc.SetupWebhook(ctx, "channel-1")
+	require.NoError(t, err)
+
+	notification := &domain.WebhookNotification{
+		WatchChannelID: channel.ID,
+		ResourceID:     channel.ResourceID,
+		State:          "sync",
+		Token:          channel.Token,
+	}
+
+	// La notificación sync confirma el canal sin sincronizar
+	routed, err := service.HandlePushNotification(ctx, notification)
+	require.NoError(t, err)
+	assert.Equal(t, "channel-1", routed.ChannelID)
+
+	notification.State = "exists"
+	_, err = service.HandlePushNotification(ctx, notification)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		service.syncMu.Lock()
+		defer service.syncMu.Unlock()
+		return len(service.syncing) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, google.eventQueries, 1)
+	assert.Equal(t, "sync-1", repo.integrations["channel-1"].SyncToken)
+
+	// Un token o un recurso distintos no se aceptan
+	_, err = service.HandlePushNotification(ctx, &domain.WebhookNotification{WatchChannelID: channel.ID, ResourceID: channel.ResourceID, State: "exists", Token: "other"})
+	assert.ErrorIs(t, err, ErrInvalidWatchToken)
+	_, err = service.HandlePushNotification(ctx, &domain.WebhookNotification{WatchChannelID: channel.ID, ResourceID: "other", State: "exists", Token: channel.Token})
+	assert.ErrorIs(t, err, ErrInvalidWatchToken)
+
+	// Un canal desconocido o detenido no se enruta
+	_, err = service.HandlePushNotification(ctx, &domain.WebhookNotification{WatchChannelID: "unknown", State: "exists"})
+	assert.ErrorIs(t, err, ErrUnknownWatchChannel)
+	service.setupSvc.StopWatchChannels(ctx, repo.integrations["channel-1"])
+	_, err = service.HandlePushNotification(ctx, notification)
+	assert.ErrorIs(t, err, ErrUnknownWatchChannel)
+	assert.Len(t, google.eventQueries, 1)
+}
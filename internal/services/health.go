@@ -1,26 +1,95 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
 	"it-integration-service/pkg/logger"
 )
 
+// Niveles de criticidad de una dependencia: una crítica caída deja el servicio no listo y una
+// opcional caída solo lo degrada
+const (
+	DependencyCritical = "critical"
+	DependencyOptional = "optional"
+)
+
+// Estados de una dependencia y del servicio
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusDegraded  = "degraded"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// databasePinger es lo que el health check necesita de la base de datos; *sql.DB lo implementa
+type databasePinger interface {
+	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+}
+
+// VaultHealthChecker consulta el estado de Vault; vault.Client lo implementa
+type VaultHealthChecker interface {
+	Health(ctx context.Context) error
+}
+
 // HealthService maneja los health checks del servicio
 type HealthService struct {
-	db     *sql.DB
-	logger logger.Logger
+	db                  databasePinger
+	channelRepo         domain.ChannelIntegrationRepository
+	vault               VaultHealthChecker
+	messagingServiceURL string
+	platformEndpoints   map[domain.Platform]string
+	cfg                 config.HealthConfig
+	client              *http.Client
+	now                 func() time.Time
+	logger              logger.Logger
+
+	mu            sync.Mutex
+	platformCache map[domain.Platform]*DependencyHealth
+}
+
+// NewHealthService crea una nueva instancia del servicio de health. Sin cliente de Vault no se
+// verifica Vault.
+func NewHealthService(db *sql.DB, channelRepo domain.ChannelIntegrationRepository, vault VaultHealthChecker, cfg *config.Config, logger logger.Logger) *HealthService {
+	return &HealthService{
+		db:                  db,
+		channelRepo:         channelRepo,
+		vault:               vault,
+		messagingServiceURL: cfg.Integration.MessagingServiceURL,
+		platformEndpoints:   defaultPlatformEndpoints(cfg),
+		cfg:                 cfg.Health,
+		client: &http.Client{
+			Timeout: cfg.Health.Timeout,
+			// Una redirección ya prueba que la API responde
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now:           time.Now,
+		logger:        logger,
+		platformCache: make(map[domain.Platform]*DependencyHealth),
+	}
 }
 
-// NewHealthService crea una nueva instancia del servicio de health
-func NewHealthService(db *sql.DB, logger logger.Logger) HealthService {
-	return HealthService{
-		db:     db,
-		logger: logger,
+// defaultPlatformEndpoints son las APIs cuya disponibilidad se verifica por plataforma
+func defaultPlatformEndpoints(cfg *config.Config) map[domain.Platform]string {
+	return map[domain.Platform]string{
+		domain.PlatformWhatsApp:       "https://graph.facebook.com",
+		domain.PlatformMessenger:      "https://graph.facebook.com",
+		domain.PlatformInstagram:      "https://graph.facebook.com",
+		domain.PlatformTelegram:       "https://api.telegram.org",
+		domain.PlatformMailchimp:      cfg.Mailchimp.BaseURL,
+		domain.PlatformGoogleCalendar: cfg.GoogleCalendar.APIBaseURL,
 	}
 }
 
@@ -51,8 +120,9 @@ type SystemInfo struct {
 
 // DatabaseHealth representa el estado de salud de la base de datos
 type DatabaseHealth struct {
-	Status    string        `json:"status"`
-	Latency   time.Duration `json:"latency"`
+	Status      string        `json:"status"`
+	Criticality string        `json:"criticality"`
+	Latency     time.Duration `json:"latency"`
 	Connections struct {
 		Open  int `json:"open"`
 		InUse int `json:"in_use"`
@@ -61,102 +131,180 @@ type DatabaseHealth struct {
 	Error string `json:"error,omitempty"`
 }
 
-// ExternalServiceHealth representa el estado de salud de servicios externos
-type ExternalServiceHealth struct {
-	MessagingService struct {
-		Status    string        `json:"status"`
-		Latency   time.Duration `json:"latency"`
-		Error     string        `json:"error,omitempty"`
-	} `json:"messaging_service"`
-	Vault struct {
-		Status    string        `json:"status"`
-		Latency   time.Duration `json:"latency"`
-		Error     string        `json:"error,omitempty"`
-	} `json:"vault"`
+// DependencyHealth representa el estado de una dependencia externa
+type DependencyHealth struct {
+	Status      string        `json:"status"`
+	Criticality string        `json:"criticality"`
+	Latency     time.Duration `json:"latency"`
+	Error       string        `json:"error,omitempty"`
+	CheckedAt   time.Time     `json:"checked_at"`
+}
+
+// IntegrationsHealth resume las integraciones registradas por plataforma y estado
+type IntegrationsHealth struct {
+	TotalIntegrations  int                       `json:"total_integrations"`
+	ActiveIntegrations int                       `json:"active_integrations"`
+	ErrorIntegrations  int                       `json:"error_integrations"`
+	Platforms          map[string]int            `json:"platforms"`
+	ByPlatformStatus   map[string]map[string]int `json:"by_platform_status"`
+	Error              string                    `json:"error,omitempty"`
 }
 
 var startTime = time.Now()
 
-// CheckHealth verifica el estado general del servicio
-func (s *HealthService) CheckHealth() *HealthStatus {
-	status := &HealthStatus{
-		Status:    "healthy",
-		Timestamp: time.Now(),
+// CheckHealth verifica el estado general del servicio: unhealthy si falla una dependencia
+// crítica y degraded si falla una opcional
+func (s *HealthService) CheckHealth(ctx context.Context) *HealthStatus {
+	status := s.newStatus()
+	status.Status, status.Checks = s.runChecks(ctx)
+	status.Checks["system"] = s.getSystemInfo()
+	return status
+}
+
+// CheckReadiness verifica si el servicio está listo para recibir tráfico. Una dependencia
+// opcional caída deja el servicio listo pero degradado.
+func (s *HealthService) CheckReadiness(ctx context.Context) *HealthStatus {
+	status := s.newStatus()
+	overall, checks := s.runChecks(ctx)
+	status.Checks = checks
+
+	switch overall {
+	case HealthStatusHealthy:
+		status.Status = "ready"
+	case HealthStatusDegraded:
+		status.Status = HealthStatusDegraded
+	default:
+		status.Status = "not_ready"
+	}
+	return status
+}
+
+func (s *HealthService) newStatus() *HealthStatus {
+	return &HealthStatus{
+		Timestamp: s.now(),
 		Uptime:    time.Since(startTime).String(),
 		Service:   "it-integration-service",
 		Version:   "1.0.0",
-		Checks:    make(map[string]interface{}),
 	}
+}
 
-	// Verificar base de datos
-	dbHealth := s.checkDatabaseHealth()
-	status.Checks["database"] = dbHealth
-	if dbHealth.Status != "healthy" {
-		status.Status = "degraded"
+// runChecks verifica en paralelo las dependencias y retorna el estado agregado. Las APIs se
+// verifican solo para las plataformas con integraciones activas.
+func (s *HealthService) runChecks(ctx context.Context) (string, map[string]interface{}) {
+	checks := make(map[string]interface{})
+	var levels []dependencyLevel
+
+	dbHealth := s.checkDatabaseHealth(ctx)
+	checks["database"] = dbHealth
+	levels = append(levels, dependencyLevel{dbHealth.Status, dbHealth.Criticality})
+
+	integrations := s.checkIntegrationsHealth(ctx)
+	checks["integrations"] = integrations
+
+	var wg sync.WaitGroup
+	var messaging, vault *DependencyHealth
+	platforms := make(map[string]*DependencyHealth)
+	var platformsMu sync.Mutex
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		messaging = s.checkMessagingService(ctx)
+	}()
+
+	if s.vault != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vault = s.checkVaultService(ctx)
+		}()
 	}
 
-	// Verificar servicios externos
-	externalHealth := s.checkExternalServicesHealth()
-	status.Checks["external_services"] = externalHealth
-	if externalHealth.MessagingService.Status != "healthy" || externalHealth.Vault.Status != "healthy" {
-		status.Status = "degraded"
+	for _, platform := range s.activePlatforms(integrations) {
+		wg.Add(1)
+		go func(platform domain.Platform) {
+			defer wg.Done()
+			health := s.checkPlatform(ctx, platform)
+			platformsMu.Lock()
+			platforms[string(platform)] = health
+			platformsMu.Unlock()
+		}(platform)
 	}
+	wg.Wait()
 
-	// Verificar sistema
-	systemInfo := s.getSystemInfo()
-	status.Checks["system"] = systemInfo
-
-	// Verificar integraciones activas
-	integrationsHealth := s.checkIntegrationsHealth()
-	status.Checks["integrations"] = integrationsHealth
-
-	// Si hay errores críticos, marcar como unhealthy
-	if dbHealth.Status == "unhealthy" {
-		status.Status = "unhealthy"
+	checks["messaging_service"] = messaging
+	levels = append(levels, dependencyLevel{messaging.Status, messaging.Criticality})
+	if vault != nil {
+		checks["vault"] = vault
+		levels = append(levels, dependencyLevel{vault.Status, vault.Criticality})
+	}
+	if len(platforms) > 0 {
+		checks["platforms"] = platforms
+		for _, health := range platforms {
+			levels = append(levels, dependencyLevel{health.Status, health.Criticality})
+		}
 	}
 
-	return status
+	return aggregateHealth(levels), checks
 }
 
-// CheckReadiness verifica si el servicio está listo para recibir tráfico
-func (s *HealthService) CheckReadiness() *HealthStatus {
-	status := &HealthStatus{
-		Status:    "ready",
-		Timestamp: time.Now(),
-		Uptime:    time.Since(startTime).String(),
-		Service:   "it-integration-service",
-		Version:   "1.0.0",
-		Checks:    make(map[string]interface{}),
-	}
+// dependencyLevel es el estado de una dependencia junto con su criticidad
+type dependencyLevel struct {
+	status      string
+	criticality string
+}
 
-	// Verificar que la base de datos esté disponible
-	dbHealth := s.checkDatabaseHealth()
-	status.Checks["database"] = dbHealth
-	if dbHealth.Status != "healthy" {
-		status.Status = "not_ready"
+// aggregateHealth calcula el estado del servicio a partir de sus dependencias
+func aggregateHealth(levels []dependencyLevel) string {
+	overall := HealthStatusHealthy
+	for _, level := range levels {
+		switch {
+		case level.status == HealthStatusHealthy:
+		case level.status == HealthStatusUnhealthy && level.criticality == DependencyCritical:
+			return HealthStatusUnhealthy
+		default:
+			overall = HealthStatusDegraded
+		}
 	}
+	return overall
+}
 
-	// Verificar que los servicios críticos estén disponibles
-	externalHealth := s.checkExternalServicesHealth()
-	status.Checks["external_services"] = externalHealth
-	if externalHealth.MessagingService.Status != "healthy" {
-		status.Status = "not_ready"
+// criticality retorna el nivel configurado de una dependencia; la base de datos y el servicio
+// de mensajería son críticos por defecto
+func (s *HealthService) criticality(name string) string {
+	if level, ok := s.cfg.Criticality[name]; ok && (level == DependencyCritical || level == DependencyOptional) {
+		return level
 	}
+	switch name {
+	case "database", "messaging_service":
+		return DependencyCritical
+	}
+	return DependencyOptional
+}
 
-	return status
+// platformCriticality retorna el nivel de la API de una plataforma, o el de "platforms" si no tiene uno propio
+func (s *HealthService) platformCriticality(platform domain.Platform) string {
+	if _, ok := s.cfg.Criticality[string(platform)]; ok {
+		return s.criticality(string(platform))
+	}
+	return s.criticality("platforms")
 }
 
 // checkDatabaseHealth verifica el estado de la base de datos
-func (s *HealthService) checkDatabaseHealth() *DatabaseHealth {
+func (s *HealthService) checkDatabaseHealth(ctx context.Context) *DatabaseHealth {
 	health := &DatabaseHealth{
-		Status: "healthy",
+		Status:      HealthStatusHealthy,
+		Criticality: s.criticality("database"),
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
 	start := time.Now()
-	
+
 	// Verificar conexión
-	if err := s.db.Ping(); err != nil {
-		health.Status = "unhealthy"
+	if err := s.db.PingContext(ctx); err != nil {
+		health.Status = HealthStatusUnhealthy
 		health.Error = err.Error()
 		health.Latency = time.Since(start)
 		return health
@@ -172,91 +320,121 @@ func (s *HealthService) checkDatabaseHealth() *DatabaseHealth {
 
 	// Verificar que no haya demasiadas conexiones abiertas
 	if stats.OpenConnections > 100 {
-		health.Status = "degraded"
+		health.Status = HealthStatusDegraded
 		health.Error = "too many open connections"
 	}
 
 	return health
 }
 
-// checkExternalServicesHealth verifica el estado de servicios externos
-func (s *HealthService) checkExternalServicesHealth() *ExternalServiceHealth {
-	health := &ExternalServiceHealth{}
+// checkMessagingService consulta el health del servicio de mensajería configurado
+func (s *HealthService) checkMessagingService(ctx context.Context) *DependencyHealth {
+	health := s.newDependency(s.criticality("messaging_service"))
+	start := time.Now()
+	statusCode, err := s.get(ctx, strings.TrimRight(s.messagingServiceURL, "/")+"/api/v1/health")
+	health.Latency = time.Since(start)
 
-	// Verificar servicio de mensajería
-	health.MessagingService = s.checkMessagingService()
+	switch {
+	case err != nil:
+		health.Status = HealthStatusUnhealthy
+		health.Error = err.Error()
+	case statusCode != http.StatusOK:
+		health.Status = HealthStatusDegraded
+		health.Error = fmt.Sprintf("unexpected status code: %d", statusCode)
+	}
+	return health
+}
+
+// checkVaultService consulta sys/health de Vault
+func (s *HealthService) checkVaultService(ctx context.Context) *DependencyHealth {
+	health := s.newDependency(s.criticality("vault"))
 
-	// Verificar Vault
-	health.Vault = s.checkVaultService()
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
 
+	start := time.Now()
+	err := s.vault.Health(ctx)
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Status = HealthStatusUnhealthy
+		health.Error = err.Error()
+	}
 	return health
 }
 
-// checkMessagingService verifica el estado del servicio de mensajería
-func (s *HealthService) checkMessagingService() struct {
-	Status  string        `json:"status"`
-	Latency time.Duration `json:"latency"`
-	Error   string        `json:"error,omitempty"`
-} {
-	result := struct {
-		Status  string        `json:"status"`
-		Latency time.Duration `json:"latency"`
-		Error   string        `json:"error,omitempty"`
-	}{
-		Status: "healthy",
+// checkPlatform verifica que la API de la plataforma responda. El resultado se reutiliza
+// durante PlatformCacheTTL para no consultar las APIs externas en cada probe.
+func (s *HealthService) checkPlatform(ctx context.Context, platform domain.Platform) *DependencyHealth {
+	s.mu.Lock()
+	cached, ok := s.platformCache[platform]
+	s.mu.Unlock()
+	if ok && s.now().Sub(cached.CheckedAt) < s.cfg.PlatformCacheTTL {
+		return cached
 	}
 
-	// En una implementación real, esto haría una llamada HTTP al servicio de mensajería
-	// Por ahora, simulamos la verificación
+	health := s.newDependency(s.platformCriticality(platform))
 	start := time.Now()
-	
-	// Simular llamada HTTP
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get("http://localhost:8081/api/v1/health")
-	
-	result.Latency = time.Since(start)
-	
-	if err != nil {
-		result.Status = "unhealthy"
-		result.Error = err.Error()
-	} else {
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			result.Status = "degraded"
-			result.Error = fmt.Sprintf("unexpected status code: %d", resp.StatusCode)
+	statusCode, err := s.get(ctx, s.platformEndpoints[platform])
+	health.Latency = time.Since(start)
+
+	// Cualquier respuesta que no sea un error del servidor prueba que la API es alcanzable
+	switch {
+	case err != nil:
+		health.Status = HealthStatusUnhealthy
+		health.Error = err.Error()
+	case statusCode >= http.StatusInternalServerError:
+		health.Status = HealthStatusDegraded
+		health.Error = fmt.Sprintf("unexpected status code: %d", statusCode)
+	}
+
+	s.mu.Lock()
+	s.platformCache[platform] = health
+	s.mu.Unlock()
+
+	return health
+}
+
+// activePlatforms retorna las plataformas con integraciones activas y una API que verificar
+func (s *HealthService) activePlatforms(integrations *IntegrationsHealth) []domain.Platform {
+	var platforms []domain.Platform
+	for platform, byStatus := range integrations.ByPlatformStatus {
+		if byStatus[string(domain.StatusActive)] == 0 {
+			continue
+		}
+		if endpoint := s.platformEndpoints[domain.Platform(platform)]; endpoint != "" {
+			platforms = append(platforms, domain.Platform(platform))
 		}
 	}
+	return platforms
+}
 
-	return result
+func (s *HealthService) newDependency(criticality string) *DependencyHealth {
+	return &DependencyHealth{
+		Status:      HealthStatusHealthy,
+		Criticality: criticality,
+		CheckedAt:   s.now(),
+	}
 }
 
-// checkVaultService verifica el estado del servicio Vault
-func (s *HealthService) checkVaultService() struct {
-	Status  string        `json:"status"`
-	Latency time.Duration `json:"latency"`
-	Error   string        `json:"error,omitempty"`
-} {
-	result := struct {
-		Status  string        `json:"status"`
-		Latency time.Duration `json:"latency"`
-		Error   string        `json:"error,omitempty"`
-	}{
-		Status: "healthy",
+// get hace un GET con el timeout de las verificaciones y retorna el código de respuesta
+func (s *HealthService) get(ctx context.Context, url string) (int, error) {
+	if url == "" {
+		return 0, errors.New("endpoint not configured")
 	}
 
-	// En una implementación real, esto verificaría la conexión a Vault
-	// Por ahora, simulamos la verificación
-	start := time.Now()
-	
-	// Simular verificación de Vault
-	time.Sleep(10 * time.Millisecond) // Simular latencia
-	
-	result.Latency = time.Since(start)
-	
-	// Por ahora, asumimos que Vault está disponible
-	// En producción, esto haría una llamada real a Vault
-
-	return result
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
 }
 
 // getSystemInfo obtiene información del sistema
@@ -280,20 +458,37 @@ func (s *HealthService) getSystemInfo() *SystemInfo {
 	return info
 }
 
-// checkIntegrationsHealth verifica el estado de las integraciones
-func (s *HealthService) checkIntegrationsHealth() map[string]interface{} {
-	health := make(map[string]interface{})
-
-	// En una implementación real, esto consultaría la base de datos
-	// para obtener estadísticas de las integraciones
-	health["total_integrations"] = 5
-	health["active_integrations"] = 4
-	health["error_integrations"] = 1
-	health["platforms"] = map[string]int{
-		"whatsapp":  2,
-		"telegram":  1,
-		"messenger": 1,
-		"instagram": 1,
+// checkIntegrationsHealth cuenta las integraciones registradas por plataforma y estado
+func (s *HealthService) checkIntegrationsHealth(ctx context.Context) *IntegrationsHealth {
+	health := &IntegrationsHealth{
+		Platforms:        make(map[string]int),
+		ByPlatformStatus: make(map[string]map[string]int),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	counts, err := s.channelRepo.CountByPlatformAndStatus(ctx)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	for _, count := range counts {
+		platform := string(count.Platform)
+		health.TotalIntegrations += count.Count
+		health.Platforms[platform] += count.Count
+		if health.ByPlatformStatus[platform] == nil {
+			health.ByPlatformStatus[platform] = make(map[string]int)
+		}
+		health.ByPlatformStatus[platform][string(count.Status)] += count.Count
+
+		switch count.Status {
+		case domain.StatusActive:
+			health.ActiveIntegrations += count.Count
+		case domain.StatusError:
+			health.ErrorIntegrations += count.Count
+		}
 	}
 
 	return health
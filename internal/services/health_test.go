@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePinger struct {
+	err error
+}
+
+func (p *fakePinger) PingContext(ctx context.Context) error { return p.err }
+func (p *fakePinger) Stats() sql.DBStats                    { return sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2} }
+
+type fakeVaultHealth struct {
+	err error
+}
+
+func (v *fakeVaultHealth) Health(ctx context.Context) error { return v.err }
+
+// countingRepository retorna los conteos configurados por plataforma y estado
+type countingRepository struct {
+	domain.ChannelIntegrationRepository
+	counts []*domain.IntegrationCount
+}
+
+func (r *countingRepository) CountByPlatformAndStatus(ctx context.Context) ([]*domain.IntegrationCount, error) {
+	return r.counts, nil
+}
+
+// newTestHealthService arma el servicio contra un servicio de mensajería y una API de plataforma locales
+func newTestHealthService(t *testing.T, messagingStatus int, criticality map[string]string) (*HealthService, *atomic.Int32) {
+	messaging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/health", r.URL.Path)
+		w.WriteHeader(messagingStatus)
+	}))
+	t.Cleanup(messaging.Close)
+
+	var platformHits atomic.Int32
+	platformAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		platformHits.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(platformAPI.Close)
+
+	repo := &countingRepository{counts: []*domain.IntegrationCount{
+		{Platform: domain.PlatformWhatsApp, Status: domain.StatusActive, Count: 2},
+		{Platform: domain.PlatformWhatsApp, Status: domain.StatusError, Count: 1},
+		{Platform: domain.PlatformTelegram, Status: domain.StatusDisabled, Count: 4},
+		{Platform: domain.PlatformWebchat, Status: domain.StatusActive, Count: 1},
+	}}
+	cfg := &config.Config{
+		Integration: config.IntegrationConfig{MessagingServiceURL: messaging.URL + "/"},
+		Health:      config.HealthConfig{Timeout: time.Second, PlatformCacheTTL: time.Minute, Criticality: criticality},
+	}
+
+	service := NewHealthService(nil, repo, nil, cfg, logger.NewLogger("error"))
+	service.db = &fakePinger{}
+	service.platformEndpoints = map[domain.Platform]string{
+		domain.PlatformWhatsApp: platformAPI.URL,
+		domain.PlatformTelegram: platformAPI.URL,
+	}
+	return service, &platformHits
+}
+
+func TestCheckHealthReportsIntegrationsAndDependencies(t *testing.T) {
+	service, platformHits := newTestHealthService(t, http.StatusOK, nil)
+
+	status := service.CheckHealth(context.Background())
+	assert.Equal(t, HealthStatusHealthy, status.Status)
+
+	integrations := status.Checks["integrations"].(*IntegrationsHealth)
+	assert.Equal(t, 8, integrations.TotalIntegrations)
+	assert.Equal(t, 3, integrations.ActiveIntegrations)
+	assert.Equal(t, 1, integrations.ErrorIntegrations)
+	assert.Equal(t, 3, integrations.Platforms["whatsapp"])
+	assert.Equal(t, map[string]int{"active": 2, "error": 1}, integrations.ByPlatformStatus["whatsapp"])
+
+	messaging := status.Checks["messaging_service"].(*DependencyHealth)
+	assert.Equal(t, HealthStatusHealthy, messaging.Status)
+	assert.Equal(t, DependencyCritical, messaging.Criticality)
+
+	// Solo se verifica la API de plataformas con integraciones activas; un 400 prueba que responde
+	platforms := status.Checks["platforms"].(map[string]*DependencyHealth)
+	require.Len(t, platforms, 1)
+	assert.Equal(t, HealthStatusHealthy, platforms["whatsapp"].Status)
+	assert.Equal(t, DependencyOptional, platforms["whatsapp"].Criticality)
+	assert.Equal(t, int32(1), platformHits.Load())
+
+	// Sin cliente de Vault no se verifica
+	assert.NotContains(t, status.Checks, "vault")
+}
+
+func TestCheckReadinessUsesCriticality(t *testing.T) {
+	// Vault opcional caído degrada pero deja el servicio listo
+	service, _ := newTestHealthService(t, http.StatusOK, nil)
+	service.vault = &fakeVaultHealth{err: errors.New("vault is sealed")}
+	status := service.CheckReadiness(context.Background())
+	assert.Equal(t, HealthStatusDegraded, status.Status)
+	assert.Equal(t, "vault is sealed", status.Checks["vault"].(*DependencyHealth).Error)
+
+	// Como dependencia crítica deja el servicio no listo
+	service, _ = newTestHealthService(t, http.StatusOK, map[string]string{"vault": DependencyCritical})
+	service.vault = &fakeVaultHealth{err: errors.New("vault is sealed")}
+	assert.Equal(t, "not_ready", service.CheckReadiness(context.Background()).Status)
+
+	// El servicio de mensajería es crítico salvo que se configure como opcional
+	service, _ = newTestHealthService(t, http.StatusOK, nil)
+	service.messagingServiceURL = "http://127.0.0.1:1"
+	assert.Equal(t, "not_ready", service.CheckReadiness(context.Background()).Status)
+
+	service, _ = newTestHealthService(t, http.StatusOK, map[string]string{"messaging_service": DependencyOptional})
+	service.messagingServiceURL = "http://127.0.0.1:1"
+	assert.Equal(t, HealthStatusDegraded, service.CheckReadiness(context.Background()).Status)
+
+	// La base de datos caída deja el servicio no listo
+	service, _ = newTestHealthService(t, http.StatusOK, nil)
+	service.db = &fakePinger{err: errors.New("connection refused")}
+	assert.Equal(t, "not_ready", service.CheckReadiness(context.Background()).Status)
+}
+
+func TestPlatformChecksAreCached(t *testing.T) {
+	service, platformHits := newTestHealthService(t, http.StatusOK, nil)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	service.CheckHealth(context.Background())
+	service.CheckReadiness(context.Background())
+	assert.Equal(t, int32(1), platformHits.Load())
+
+	now = now.Add(2 * time.Minute)
+	service.CheckReadiness(context.Background())
+	assert.Equal(t, int32(2), platformHits.Load())
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"it-integration-service/internal/domain"
 )
@@ -20,30 +21,70 @@ type IntegrationService interface {
 	ProcessWhatsAppWebhook(ctx context.Context, payload []byte, signature string) error
 	ProcessMessengerWebhook(ctx context.Context, payload []byte, signature string) error
 	ProcessInstagramWebhook(ctx context.Context, payload []byte, signature string) error
-	ProcessTelegramWebhook(ctx context.Context, payload []byte) error
+	ProcessTelegramWebhook(ctx context.Context, payload []byte, webhookKey string) error
 	ProcessWebchatWebhook(ctx context.Context, payload []byte) error
 	ProcessMailchimpWebhook(ctx context.Context, payload []byte, signature string) error
-
-	// Consulta de mensajes entrantes (solo para validación)
-	GetInboundMessages(ctx context.Context, platform string, limit, offset int) ([]*domain.InboundMessage, error)
+	// ProcessChannelWebhook procesa un webhook recibido en la ruta propia del canal
+	ProcessChannelWebhook(ctx context.Context, platform domain.Platform, channelID string, payload []byte) error
+	ResolveWebhookChannel(ctx context.Context, platform domain.Platform, channelID string, payload []byte) (*domain.ChannelIntegration, error)
+	// ResolveWebhookChannels retorna el canal de la ruta o, sin ella, el de cada entrada del payload
+	ResolveWebhookChannels(ctx context.Context, platform domain.Platform, channelID string, payload []byte) ([]*domain.ChannelIntegration, error)
 }
 
 // WebhookService define las operaciones para procesamiento de webhooks
 type WebhookService interface {
 	ValidateSignature(payload []byte, signature string, secret string) bool
-	NormalizeMessage(platform domain.Platform, payload []byte) (*NormalizedMessage, error)
+	NormalizeMessage(platform domain.Platform, payload []byte) ([]*NormalizedMessage, error)
+	ExtractStatusEvents(platform domain.Platform, payload []byte) ([]*StatusEvent, error)
+	ExtractChannelIdentifier(platform domain.Platform, payload []byte) (string, error)
+	// ExtractChannelIdentifiers obtiene los identificadores de canal de todas las entradas del payload, sin repetir
+	ExtractChannelIdentifiers(platform domain.Platform, payload []byte) ([]string, error)
 	ForwardToMessagingService(ctx context.Context, message *NormalizedMessage) error
+	ForwardStatusToMessagingService(ctx context.Context, event *StatusEvent) error
 }
 
 // NormalizedMessage representa un mensaje normalizado entre plataformas
 type NormalizedMessage struct {
-	Platform   domain.Platform        `json:"platform"`
-	Sender     string                 `json:"sender"`
-	Recipient  string                 `json:"recipient"`
-	Content    *domain.MessageContent `json:"content"`
-	Timestamp  int64                  `json:"timestamp"`
-	MessageID  string                 `json:"message_id"`
-	TenantID   string                 `json:"tenant_id"`
-	ChannelID  string                 `json:"channel_id"`
-	RawPayload json.RawMessage        `json:"raw_payload"`
+	Platform       domain.Platform        `json:"platform"`
+	Sender         string                 `json:"sender"`
+	Recipient      string                 `json:"recipient"`
+	Content        *domain.MessageContent `json:"content"`
+	Timestamp      int64                  `json:"timestamp"`
+	MessageID      string                 `json:"message_id"`
+	IdempotencyKey string                 `json:"idempotency_key"`
+	TenantID       string                 `json:"tenant_id"`
+	ChannelID      string                 `json:"channel_id"`
+	RawPayload     json.RawMessage        `json:"raw_payload"`
+	// Replay marca los mensajes reenviados por una reproducción y no por el webhook original
+	Replay *ReplayInfo `json:"replay,omitempty"`
+	// channelIdentifier identifica el canal de la entrada del webhook que trajo el mensaje
+	// (phone_number_id o entry.id en Meta); vacío si la plataforma no lo informa por entrada
+	channelIdentifier string
+}
+
+// ReplayInfo identifica la reproducción que reenvió un mensaje
+type ReplayInfo struct {
+	ReplayID           string    `json:"replay_id"`
+	InboundMessageID   string    `json:"inbound_message_id"`
+	OriginalReceivedAt time.Time `json:"original_received_at"`
+}
+
+// StatusEvent representa un recibo de estado (entregado, leído, fallido) de un mensaje saliente.
+// Messenger confirma por watermark: MessageID vacío y Watermark indica hasta cuándo aplica.
+type StatusEvent struct {
+	Platform       domain.Platform      `json:"platform"`
+	MessageID      string               `json:"message_id,omitempty"`
+	Recipient      string               `json:"recipient"`
+	Status         domain.MessageStatus `json:"status"`
+	Timestamp      int64                `json:"timestamp"`
+	Watermark      int64                `json:"watermark,omitempty"`
+	ErrorCode      string               `json:"error_code,omitempty"`
+	ErrorMessage   string               `json:"error_message,omitempty"`
+	IdempotencyKey string               `json:"idempotency_key"`
+	TenantID       string               `json:"tenant_id"`
+	ChannelID      string               `json:"channel_id"`
+	// ConversationCategory es la categoría de conversación que informa WhatsApp (service, marketing, utility...)
+	ConversationCategory string `json:"conversation_category,omitempty"`
+	// channelIdentifier identifica el canal de la entrada del webhook que trajo el recibo
+	channelIdentifier string
 }
@@ -2,7 +2,10 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"it-integration-service/internal/domain"
@@ -11,25 +14,55 @@ import (
 	"github.com/google/uuid"
 )
 
+// UnresolvedChannelQuarantine guarda los webhooks sin canal válido en lugar de rechazarlos
+const UnresolvedChannelQuarantine = "quarantine"
+
+// webhookRoute indica cómo identifica la URL del webhook al canal destino
+type webhookRoute struct {
+	channelKey string // identificador registrado para el canal, como la clave de Telegram
+	channelID  string // ID del canal en la ruta /webhooks/<plataforma>/:channel_id
+}
+
 type integrationService struct {
-	channelService ChannelService
-	inboundRepo    domain.InboundMessageRepository
-	webhookService WebhookService
-	logger         logger.Logger
+	channelService   ChannelService
+	inboundRepo      domain.InboundMessageRepository
+	outboxRepo       domain.MessageOutboxRepository
+	outboundRepo     domain.OutboundMessageLogRepository
+	dedupRepo        domain.ProcessedWebhookEventRepository
+	webhookService   WebhookService
+	channelResolver  ChannelResolver
+	sessions         ConversationSessionService
+	unresolvedPolicy string
+	dedupRetention   time.Duration
+	logger           logger.Logger
 }
 
 // NewIntegrationService crea una nueva instancia del servicio de integración
 func NewIntegrationService(
 	channelService ChannelService,
 	inboundRepo domain.InboundMessageRepository,
+	outboxRepo domain.MessageOutboxRepository,
+	outboundRepo domain.OutboundMessageLogRepository,
+	dedupRepo domain.ProcessedWebhookEventRepository,
 	webhookService WebhookService,
+	channelResolver ChannelResolver,
+	sessions ConversationSessionService,
+	unresolvedPolicy string,
+	dedupRetention time.Duration,
 	logger logger.Logger,
 ) IntegrationService {
 	return &integrationService{
-		channelService: channelService,
-		inboundRepo:    inboundRepo,
-		webhookService: webhookService,
-		logger:         logger,
+		channelService:   channelService,
+		inboundRepo:      inboundRepo,
+		outboxRepo:       outboxRepo,
+		outboundRepo:     outboundRepo,
+		dedupRepo:        dedupRepo,
+		webhookService:   webhookService,
+		channelResolver:  channelResolver,
+		sessions:         sessions,
+		unresolvedPolicy: unresolvedPolicy,
+		dedupRetention:   dedupRetention,
+		logger:           logger,
 	}
 }
 
@@ -47,56 +80,105 @@ func (s *integrationService) GetChannelsByTenant(ctx context.Context, tenantID s
 }
 
 func (s *integrationService) UpdateChannel(ctx context.Context, integration *domain.ChannelIntegration) error {
-	return s.channelService.UpdateChannel(ctx, integration)
+	if err := s.channelService.UpdateChannel(ctx, integration); err != nil {
+		return err
+	}
+	s.invalidateChannel(integration.ID)
+	return nil
 }
 
 func (s *integrationService) DeleteChannel(ctx context.Context, id string) error {
-	return s.channelService.DeleteChannel(ctx, id)
+	if err := s.channelService.DeleteChannel(ctx, id); err != nil {
+		return err
+	}
+	s.invalidateChannel(id)
+	return nil
 }
 
 // Procesamiento de webhooks
 func (s *integrationService) ProcessWhatsAppWebhook(ctx context.Context, payload []byte, signature string) error {
-	return s.processWebhook(ctx, domain.PlatformWhatsApp, payload, signature)
+	return s.processWebhook(ctx, domain.PlatformWhatsApp, payload, signature, webhookRoute{})
 }
 
 func (s *integrationService) ProcessMessengerWebhook(ctx context.Context, payload []byte, signature string) error {
-	return s.processWebhook(ctx, domain.PlatformMessenger, payload, signature)
+	return s.processWebhook(ctx, domain.PlatformMessenger, payload, signature, webhookRoute{})
 }
 
 func (s *integrationService) ProcessInstagramWebhook(ctx context.Context, payload []byte, signature string) error {
-	return s.processWebhook(ctx, domain.PlatformInstagram, payload, signature)
+	return s.processWebhook(ctx, domain.PlatformInstagram, payload, signature, webhookRoute{})
 }
 
-func (s *integrationService) ProcessTelegramWebhook(ctx context.Context, payload []byte) error {
-	return s.processWebhook(ctx, domain.PlatformTelegram, payload, "")
+func (s *integrationService) ProcessTelegramWebhook(ctx context.Context, payload []byte, webhookKey string) error {
+	// Telegram no identifica al bot en el update, el canal se resuelve por la clave de la URL
+	return s.processWebhook(ctx, domain.PlatformTelegram, payload, "", webhookRoute{channelKey: webhookKey})
 }
 
 func (s *integrationService) ProcessWebchatWebhook(ctx context.Context, payload []byte) error {
-	return s.processWebhook(ctx, domain.PlatformWebchat, payload, "")
+	return s.processWebhook(ctx, domain.PlatformWebchat, payload, "", webhookRoute{})
 }
 
 func (s *integrationService) ProcessMailchimpWebhook(ctx context.Context, payload []byte, signature string) error {
-	return s.processWebhook(ctx, domain.PlatformMailchimp, payload, signature)
+	return s.processWebhook(ctx, domain.PlatformMailchimp, payload, signature, webhookRoute{})
 }
 
-// Consulta de mensajes entrantes
-func (s *integrationService) GetInboundMessages(ctx context.Context, platform string, limit, offset int) ([]*domain.InboundMessage, error) {
-	if s.inboundRepo == nil {
-		s.logger.Warn("Inbound repository is nil, returning mock data")
-		return s.getMockInboundMessages(platform, limit)
+func (s *integrationService) ProcessChannelWebhook(ctx context.Context, platform domain.Platform, channelID string, payload []byte) error {
+	return s.processWebhook(ctx, platform, payload, "", webhookRoute{channelID: channelID})
+}
+
+// ResolveWebhookChannel identifica el canal de un webhook por el ID de la ruta o, sin él, por el
+// identificador del payload. Los canales deshabilitados se retornan junto con ErrChannelDisabled.
+func (s *integrationService) ResolveWebhookChannel(ctx context.Context, platform domain.Platform, channelID string, payload []byte) (*domain.ChannelIntegration, error) {
+	return s.resolveChannel(ctx, platform, payload, webhookRoute{channelID: channelID})
+}
+
+// ResolveWebhookChannels retorna los canales que reciben un webhook, sin repetir. Con ruta es el
+// canal de la ruta; sin ella, el de cada entrada del payload, incluidos los deshabilitados. Las
+// entradas de canales no registrados se omiten: el procesamiento les aplica la política de canales
+// no resueltos.
+func (s *integrationService) ResolveWebhookChannels(ctx context.Context, platform domain.Platform, channelID string, payload []byte) ([]*domain.ChannelIntegration, error) {
+	if channelID != "" {
+		channel, err := s.channelByID(ctx, platform, channelID, payload)
+		if channel == nil {
+			return nil, err
+		}
+		return []*domain.ChannelIntegration{channel}, err
+	}
+	if s.channelResolver == nil || len(payload) == 0 {
+		return nil, nil
+	}
+
+	identifiers, err := s.webhookService.ExtractChannelIdentifiers(platform, payload)
+	if err != nil {
+		s.logger.Warn("Failed to extract channel identifiers", map[string]interface{}{
+			"platform": platform,
+			"error":    err.Error(),
+		})
+		return nil, nil
 	}
-	// Por ahora solo retornamos mensajes no procesados
-	return s.inboundRepo.GetUnprocessed(ctx, limit)
+
+	var channels []*domain.ChannelIntegration
+	seen := make(map[string]bool, len(identifiers))
+	for _, identifier := range identifiers {
+		channel, err := s.channelResolver.Resolve(ctx, platform, identifier)
+		if err != nil && !isUnresolvedChannel(err) {
+			return nil, err
+		}
+		if channel == nil || seen[channel.ID] {
+			continue
+		}
+		seen[channel.ID] = true
+		channels = append(channels, channel)
+	}
+	return channels, nil
 }
 
 // Helper functions
-func (s *integrationService) processWebhook(ctx context.Context, platform domain.Platform, payload []byte, signature string) error {
+func (s *integrationService) processWebhook(ctx context.Context, platform domain.Platform, payload []byte, signature string, route webhookRoute) error {
 	s.logger.Info("Processing webhook", map[string]interface{}{
 		"platform":     platform,
 		"payload_size": len(payload),
 	})
 
-	// Guardar mensaje entrante
 	message := &domain.InboundMessage{
 		ID:         uuid.New().String(),
 		Platform:   platform,
@@ -105,54 +187,674 @@ func (s *integrationService) processWebhook(ctx context.Context, platform domain
 		Processed:  false,
 	}
 
-	if s.inboundRepo != nil {
-		if err := s.inboundRepo.Create(ctx, message); err != nil {
-			s.logger.Error("Failed to save inbound message", err)
+	// La ruta propia del canal y la URL de Telegram identifican un único canal para todo el webhook;
+	// en las rutas compartidas cada entrada puede ser de un canal (y un tenant) distinto
+	routed := route.channelID != "" || platform == domain.PlatformTelegram
+	var channel *domain.ChannelIntegration
+	if routed {
+		var err error
+		channel, err = s.resolveChannel(ctx, platform, payload, route)
+		if err != nil {
+			if isUnresolvedChannel(err) {
+				return s.handleUnresolvedChannel(ctx, message, channel, err)
+			}
+			s.logger.Error("Failed to resolve channel for webhook", err)
+			return err
+		}
+		if channel != nil {
+			message.TenantID = channel.TenantID
+			message.ChannelID = channel.ID
 		}
 	}
 
-	// Normalizar mensaje
-	normalizedMessage, err := s.webhookService.NormalizeMessage(platform, payload)
+	// Normalizar mensajes (un webhook de Meta puede traer varios)
+	normalizedMessages, err := s.webhookService.NormalizeMessage(platform, payload)
 	if err != nil {
 		s.logger.Error("Failed to normalize message", err)
+		// Conservar el payload crudo para poder reprocesarlo más adelante
+		s.saveInboundMessage(ctx, message)
 		return err
 	}
 
-	// Marcar como procesado
-	if s.inboundRepo != nil {
-		if err := s.inboundRepo.MarkAsProcessed(ctx, message.ID); err != nil {
-			s.logger.Error("Failed to mark message as processed", err)
+	// Los recibos de entrega y lectura llegan al mismo endpoint que los mensajes
+	statusEvents, err := s.webhookService.ExtractStatusEvents(platform, payload)
+	if err != nil {
+		s.logger.Error("Failed to extract status events", err)
+		s.saveInboundMessage(ctx, message)
+		return err
+	}
+
+	if len(normalizedMessages) == 0 && len(statusEvents) == 0 {
+		// Sin eventos no hay entradas que resolver por separado: vale el canal del payload
+		if !routed {
+			channel, err := s.resolveChannel(ctx, platform, payload, route)
+			if isUnresolvedChannel(err) {
+				return s.handleUnresolvedChannel(ctx, message, channel, err)
+			}
+			if channel != nil {
+				message.TenantID = channel.TenantID
+				message.ChannelID = channel.ID
+			}
 		}
+		s.saveInboundMessage(ctx, message)
+		return fmt.Errorf("no messages or status events found in %s payload", platform)
 	}
 
-	// Reenviar al servicio de mensajería
-	if err := s.webhookService.ForwardToMessagingService(ctx, normalizedMessage); err != nil {
-		s.logger.Error("Failed to forward message to messaging service", err)
+	items := s.buildInboundItems(message, normalizedMessages, statusEvents)
+
+	unresolved := 0
+	if routed {
+		for i := range items {
+			assignItemChannel(&items[i], &message.Results[i], channel)
+		}
+	} else {
+		resolution, err := s.resolveItemChannels(ctx, message, items)
+		if err != nil {
+			s.logger.Error("Failed to resolve channel for webhook", err)
+			return err
+		}
+		if resolution.unresolved == len(items) {
+			return s.handleUnresolvedChannel(ctx, message, resolution.channel, resolution.cause)
+		}
+		unresolved = resolution.unresolved
+	}
+
+	// Con outbox la entrega es asíncrona: los eventos quedan persistidos junto al registro entrante
+	// y el dispatcher se encarga de reenviarlos con reintentos. Meta y Telegram reenvían el webhook
+	// si no respondemos a tiempo; lo ya aceptado se descarta en la misma transacción.
+	if s.outboxRepo != nil {
+		duplicates, err := s.enqueueForDelivery(ctx, message, items)
+		if err != nil {
+			s.logger.Error("Failed to enqueue message for delivery", err)
+			return err
+		}
+		if s.acknowledgeDuplicates(message, len(items), duplicates, unresolved) {
+			return nil
+		}
+		s.applyInboundEvents(ctx, items)
+
+		s.logger.Info("Webhook enqueued for delivery", map[string]interface{}{
+			"platform":   platform,
+			"tenant_id":  message.TenantID,
+			"channel_id": message.ChannelID,
+			"messages":   len(normalizedMessages),
+			"statuses":   len(statusEvents),
+			"duplicates": duplicates,
+			"unresolved": unresolved,
+		})
+		return nil
+	}
+
+	// Sin outbox no hay transacción que compartir: el evento se registra antes de reenviarlo y se
+	// libera si no se pudo entregar
+	duplicates, err := s.claimItems(ctx, message, items)
+	if err != nil {
+		s.logger.Error("Failed to check webhook duplicates", err)
 		return err
 	}
+	if s.acknowledgeDuplicates(message, len(items), duplicates, unresolved) {
+		return nil
+	}
+	s.applyInboundEvents(ctx, items)
+
+	s.saveInboundMessage(ctx, message)
+
+	// Reenviar cada evento al servicio de mensajería; un fallo no impide entregar el resto
+	failed := 0
+	for i, item := range items {
+		if item.skipped() {
+			continue
+		}
+
+		result := &message.Results[i]
+		result.Attempts = 1
+		result.UpdatedAt = time.Now()
+
+		if err := s.forwardItem(ctx, item); err != nil {
+			s.logger.Error("Failed to forward event to messaging service", map[string]interface{}{
+				"platform":        platform,
+				"event_type":      item.eventType,
+				"message_id":      item.messageID,
+				"idempotency_key": item.idempotencyKey,
+				"error":           err.Error(),
+			})
+			result.Status = domain.InboundResultFailed
+			result.Error = err.Error()
+			failed++
+			// El proveedor reintentará el webhook; este evento no debe tomarse como duplicado
+			s.releaseClaim(ctx, item.claim)
+			continue
+		}
+
+		result.Status = domain.InboundResultDelivered
+	}
+
+	// Solo se marca como procesado cuando todos los eventos fueron entregados
+	if s.inboundRepo != nil {
+		if err := s.inboundRepo.UpdateResults(ctx, message.ID, message.Results, failed == 0); err != nil {
+			s.logger.Error("Failed to update inbound message results", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to forward %d of %d events to messaging service", failed, len(items)-duplicates-unresolved)
+	}
 
 	s.logger.Info("Webhook processed successfully", map[string]interface{}{
-		"platform":   platform,
-		"message_id": normalizedMessage.MessageID,
+		"platform": platform,
+		"messages": len(normalizedMessages),
+		"statuses": len(statusEvents),
+	})
+
+	return nil
+}
+
+// acknowledgeDuplicates indica si todos los eventos del webhook estaban repetidos o sin canal, en
+// cuyo caso se responde al proveedor sin reenviar nada
+func (s *integrationService) acknowledgeDuplicates(message *domain.InboundMessage, events, duplicates, unresolved int) bool {
+	if duplicates == 0 || duplicates+unresolved != events {
+		return false
+	}
+	s.logger.Info("Duplicate webhook acknowledged without forwarding", map[string]interface{}{
+		"platform":   message.Platform,
+		"channel_id": message.ChannelID,
+		"events":     events,
+	})
+	return true
+}
+
+// applyInboundEvents aplica los efectos de los eventos aceptados que no dependen del reenvío
+func (s *integrationService) applyInboundEvents(ctx context.Context, items []inboundItem) {
+	// El log de salida se actualiza al recibir el webhook; el reenvío sigue el camino de los mensajes
+	for _, item := range items {
+		if event, ok := item.payload.(*StatusEvent); ok && !item.skipped() {
+			s.applyStatusEvent(ctx, event)
+		}
+	}
+
+	// Cada mensaje del contacto abre o extiende su ventana de atención
+	s.recordSessions(ctx, items)
+}
+
+// recordSessions actualiza la ventana de atención con los mensajes y recibos del webhook.
+// Un fallo no detiene el reenvío: la ventana se corrige con el próximo mensaje del contacto.
+func (s *integrationService) recordSessions(ctx context.Context, items []inboundItem) {
+	if s.sessions == nil {
+		return
+	}
+
+	for _, item := range items {
+		if item.skipped() {
+			continue
+		}
+
+		var err error
+		switch payload := item.payload.(type) {
+		case *NormalizedMessage:
+			err = s.sessions.RecordInbound(ctx, payload)
+		case *StatusEvent:
+			err = s.sessions.RecordStatus(ctx, payload)
+		}
+		if err != nil {
+			s.logger.Error("Failed to record conversation session", map[string]interface{}{
+				"event_type": item.eventType,
+				"message_id": item.messageID,
+				"error":      err.Error(),
+			})
+		}
+	}
+}
+
+// inboundItem es un evento de un webhook que se reenvía al servicio de mensajería
+type inboundItem struct {
+	eventType      domain.EventType
+	messageID      string
+	sender         string
+	idempotencyKey string
+	// channelIdentifier identifica el canal de la entrada del evento; vacío si el payload no lo trae por entrada
+	channelIdentifier string
+	channelID         string
+	// eventKey identifica el evento en la plataforma para deduplicar; vacío si no tiene ID propio
+	eventKey   string
+	duplicate  bool
+	unresolved bool
+	claim      *domain.ProcessedWebhookEvent
+	payload    interface{}
+}
+
+// skipped indica si el evento no se reenvía por estar repetido o por no tener canal
+func (item *inboundItem) skipped() bool {
+	return item.duplicate || item.unresolved
+}
+
+// buildInboundItems asigna la idempotency key a cada mensaje y recibo del webhook y prepara sus
+// resultados en el registro entrante, primero los mensajes y luego los recibos. El tenant y el
+// canal se asignan después, según la entrada de cada evento.
+func (s *integrationService) buildInboundItems(message *domain.InboundMessage, normalizedMessages []*NormalizedMessage, statusEvents []*StatusEvent) []inboundItem {
+	items := make([]inboundItem, 0, len(normalizedMessages)+len(statusEvents))
+
+	for _, normalizedMessage := range normalizedMessages {
+		normalizedMessage.IdempotencyKey = idempotencyKey(message.Platform, normalizedMessage.MessageID, message.ID, len(items))
+
+		items = append(items, inboundItem{
+			eventType:         domain.EventTypeMessage,
+			messageID:         normalizedMessage.MessageID,
+			sender:            normalizedMessage.Sender,
+			idempotencyKey:    normalizedMessage.IdempotencyKey,
+			channelIdentifier: normalizedMessage.channelIdentifier,
+			eventKey:          messageEventKey(message.Platform, message.Payload, normalizedMessage),
+			payload:           normalizedMessage,
+		})
+	}
+
+	for _, event := range statusEvents {
+		event.IdempotencyKey = statusIdempotencyKey(event)
+
+		items = append(items, inboundItem{
+			eventType:         domain.EventTypeStatus,
+			messageID:         event.MessageID,
+			idempotencyKey:    event.IdempotencyKey,
+			channelIdentifier: event.channelIdentifier,
+			eventKey:          statusEventKey(event),
+			payload:           event,
+		})
+	}
+
+	now := time.Now()
+	message.Results = make([]domain.InboundResult, len(items))
+	for i, item := range items {
+		message.Results[i] = domain.InboundResult{
+			Index:          i,
+			EventType:      item.eventType,
+			MessageID:      item.messageID,
+			Sender:         item.sender,
+			IdempotencyKey: item.idempotencyKey,
+			Status:         domain.InboundResultPending,
+			UpdatedAt:      now,
+		}
+	}
+
+	return items
+}
+
+// itemResolution resume la resolución de canales de los eventos de un webhook
+type itemResolution struct {
+	unresolved int
+	// cause y channel son el motivo y el canal (si existe pero está deshabilitado) del primer evento sin resolver
+	cause   error
+	channel *domain.ChannelIntegration
+}
+
+// resolveItemChannels resuelve el canal de cada evento por el identificador de su entrada: Meta agrupa
+// en una entrega entradas de distintos números y páginas, que pueden ser de distintos tenants. Los
+// eventos cuyo canal no está registrado o está deshabilitado quedan como unresolved en sus
+// resultados y no se reenvían. El registro entrante toma el canal solo si todos los eventos
+// resueltos son del mismo.
+func (s *integrationService) resolveItemChannels(ctx context.Context, message *domain.InboundMessage, items []inboundItem) (itemResolution, error) {
+	var resolution itemResolution
+	if s.channelResolver == nil {
+		return resolution, nil
+	}
+
+	// Los webhooks que no identifican el canal por entrada (Webchat, Mailchimp) usan el del payload
+	var payloadIdentifier *string
+	identifierFor := func(item *inboundItem) string {
+		if item.channelIdentifier != "" {
+			return item.channelIdentifier
+		}
+		if payloadIdentifier == nil {
+			identifier, err := s.webhookService.ExtractChannelIdentifier(message.Platform, message.Payload)
+			if err != nil {
+				s.logger.Warn("Failed to extract channel identifier", map[string]interface{}{
+					"platform": message.Platform,
+					"error":    err.Error(),
+				})
+			}
+			payloadIdentifier = &identifier
+		}
+		return *payloadIdentifier
+	}
+
+	type resolved struct {
+		channel *domain.ChannelIntegration
+		err     error
+	}
+	byIdentifier := make(map[string]resolved)
+	var shared *domain.ChannelIntegration
+	mixed := false
+
+	for i := range items {
+		item := &items[i]
+		identifier := identifierFor(item)
+
+		entry, ok := byIdentifier[identifier]
+		if !ok {
+			entry.channel, entry.err = s.channelResolver.Resolve(ctx, message.Platform, identifier)
+			if entry.err != nil && !isUnresolvedChannel(entry.err) {
+				return resolution, entry.err
+			}
+			byIdentifier[identifier] = entry
+		}
+
+		if entry.err != nil {
+			item.unresolved = true
+			message.Results[i].Status = domain.InboundResultUnresolved
+			message.Results[i].Error = entry.err.Error()
+			if resolution.unresolved == 0 {
+				resolution.cause = entry.err
+				resolution.channel = entry.channel
+			}
+			resolution.unresolved++
+			continue
+		}
+
+		assignItemChannel(item, &message.Results[i], entry.channel)
+		if shared == nil {
+			shared = entry.channel
+		} else if shared.ID != entry.channel.ID {
+			mixed = true
+		}
+	}
+
+	if resolution.unresolved > 0 {
+		s.logger.Warn("Webhook events received for unresolved channels", map[string]interface{}{
+			"platform":   message.Platform,
+			"unresolved": resolution.unresolved,
+			"events":     len(items),
+			"reason":     resolution.cause.Error(),
+		})
+	}
+
+	if shared != nil && !mixed {
+		message.TenantID = shared.TenantID
+		message.ChannelID = shared.ID
+	}
+
+	return resolution, nil
+}
+
+// assignItemChannel asigna el tenant y el canal al evento, a su payload y a su resultado
+func assignItemChannel(item *inboundItem, result *domain.InboundResult, channel *domain.ChannelIntegration) {
+	if channel == nil {
+		return
+	}
+
+	item.channelID = channel.ID
+	result.TenantID = channel.TenantID
+	result.ChannelID = channel.ID
+
+	switch payload := item.payload.(type) {
+	case *NormalizedMessage:
+		payload.TenantID = channel.TenantID
+		payload.ChannelID = channel.ID
+	case *StatusEvent:
+		payload.TenantID = channel.TenantID
+		payload.ChannelID = channel.ID
+	}
+}
+
+// isUnresolvedChannel indica si el error corresponde a un canal inexistente o deshabilitado
+func isUnresolvedChannel(err error) bool {
+	return errors.Is(err, ErrChannelNotFound) || errors.Is(err, ErrChannelDisabled)
+}
+
+// forwardItem reenvía un mensaje o un recibo de estado según su tipo
+func (s *integrationService) forwardItem(ctx context.Context, item inboundItem) error {
+	switch payload := item.payload.(type) {
+	case *NormalizedMessage:
+		return s.webhookService.ForwardToMessagingService(ctx, payload)
+	case *StatusEvent:
+		return s.webhookService.ForwardStatusToMessagingService(ctx, payload)
+	default:
+		return fmt.Errorf("unsupported event type: %s", item.eventType)
+	}
+}
+
+// idempotencyKey genera la clave de un mensaje: el ID de la plataforma cuando existe,
+// o la posición dentro del webhook recibido cuando la plataforma no lo provee
+func idempotencyKey(platform domain.Platform, messageID, inboundID string, index int) string {
+	if messageID != "" {
+		return fmt.Sprintf("%s:%s", platform, messageID)
+	}
+	return fmt.Sprintf("%s:%s:%d", platform, inboundID, index)
+}
+
+// statusIdempotencyKey genera la clave de un recibo: un mismo mensaje recibe un recibo por estado
+func statusIdempotencyKey(event *StatusEvent) string {
+	return fmt.Sprintf("%s:%s", event.Platform, statusEventKey(event))
+}
+
+// statusEventKey identifica un recibo dentro de la plataforma
+func statusEventKey(event *StatusEvent) string {
+	if event.MessageID != "" {
+		return fmt.Sprintf("%s:%s", event.MessageID, event.Status)
+	}
+	return fmt.Sprintf("%s:%s:%d", event.Recipient, event.Status, event.Watermark)
+}
+
+// applyStatusEvent actualiza el log de mensajes salientes con un recibo. Los recibos de mensajes
+// que no se enviaron desde este servicio se ignoran y los fallos no detienen el webhook.
+func (s *integrationService) applyStatusEvent(ctx context.Context, event *StatusEvent) {
+	if s.outboundRepo == nil {
+		return
+	}
+
+	var logs []*domain.OutboundMessageLog
+	if event.MessageID != "" {
+		log, err := s.outboundRepo.GetByPlatformMessageID(ctx, event.MessageID)
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("Status event for unknown outbound message", map[string]interface{}{
+				"platform":   event.Platform,
+				"message_id": event.MessageID,
+			})
+			return
+		}
+		if err != nil {
+			s.logger.Error("Failed to get outbound message log", err)
+			return
+		}
+		logs = append(logs, log)
+	} else if event.ChannelID != "" {
+		var err error
+		logs, err = s.outboundRepo.GetUnreadByRecipient(ctx, event.ChannelID, event.Recipient, time.UnixMilli(event.Watermark))
+		if err != nil {
+			s.logger.Error("Failed to get outbound message logs by recipient", err)
+			return
+		}
+	}
+
+	var response []byte
+	if event.Status == domain.MessageStatusFailed {
+		response, _ = json.Marshal(event)
+	}
+
+	for _, log := range logs {
+		if !statusAdvances(log.Status, event.Status) {
+			continue
+		}
+		if err := s.outboundRepo.UpdateStatus(ctx, log.ID, event.Status, response); err != nil {
+			s.logger.Error("Failed to update outbound message status", map[string]interface{}{
+				"log_id": log.ID,
+				"status": event.Status,
+				"error":  err.Error(),
+			})
+		}
+	}
+}
+
+// statusAdvances indica si un recibo hace avanzar el estado del mensaje. Meta no garantiza el
+// orden de los recibos, así que un "delivered" tardío no debe pisar un "read".
+func statusAdvances(current, next domain.MessageStatus) bool {
+	rank := map[domain.MessageStatus]int{
+		domain.MessageStatusQueued:    0,
+		domain.MessageStatusSent:      1,
+		domain.MessageStatusDelivered: 2,
+		domain.MessageStatusRead:      3,
+	}
+
+	if next == domain.MessageStatusFailed {
+		return current == domain.MessageStatusQueued || current == domain.MessageStatusSent
+	}
+	if current == domain.MessageStatusFailed {
+		return false
+	}
+	return rank[next] > rank[current]
+}
+
+// resolveChannel identifica el canal destino del webhook. Sin resolvedor configurado solo se
+// resuelven los webhooks cuya ruta incluye el ID del canal.
+func (s *integrationService) resolveChannel(ctx context.Context, platform domain.Platform, payload []byte, route webhookRoute) (*domain.ChannelIntegration, error) {
+	if route.channelID != "" {
+		return s.channelByID(ctx, platform, route.channelID, payload)
+	}
+	if s.channelResolver == nil {
+		return nil, nil
+	}
+
+	identifier := route.channelKey
+	if identifier == "" {
+		var err error
+		identifier, err = s.webhookService.ExtractChannelIdentifier(platform, payload)
+		if err != nil {
+			s.logger.Warn("Failed to extract channel identifier", map[string]interface{}{
+				"platform": platform,
+				"error":    err.Error(),
+			})
+			return nil, ErrChannelNotFound
+		}
+	}
+
+	// Los bots configurados antes de la clave de webhook siguen usando /webhooks/telegram
+	if identifier == "" && platform == domain.PlatformTelegram {
+		return s.channelResolver.ResolveSole(ctx, platform)
+	}
+
+	return s.channelResolver.Resolve(ctx, platform, identifier)
+}
+
+// channelByID obtiene el canal indicado en la ruta del webhook. El payload no puede pertenecer a
+// otro canal registrado: la firma se validó con el secreto del canal de la ruta.
+func (s *integrationService) channelByID(ctx context.Context, platform domain.Platform, channelID string, payload []byte) (*domain.ChannelIntegration, error) {
+	channel, err := s.channelService.GetChannel(ctx, channelID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChannelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if channel.Platform != platform {
+		return nil, ErrChannelNotFound
+	}
+
+	if len(payload) > 0 && s.channelResolver != nil {
+		// Ninguna entrada puede ser de otro canal registrado
+		identifiers, _ := s.webhookService.ExtractChannelIdentifiers(platform, payload)
+		for _, identifier := range identifiers {
+			owner, err := s.channelResolver.Resolve(ctx, platform, identifier)
+			if err != nil && !isUnresolvedChannel(err) {
+				return nil, err
+			}
+			if owner != nil && owner.ID != channel.ID {
+				s.logger.Warn("Webhook payload belongs to another channel", map[string]interface{}{
+					"platform":   platform,
+					"channel_id": channel.ID,
+					"owner_id":   owner.ID,
+				})
+				return channel, ErrChannelMismatch
+			}
+		}
+	}
+
+	if channel.Status != domain.StatusActive {
+		return channel, ErrChannelDisabled
+	}
+	return channel, nil
+}
+
+// handleUnresolvedChannel aplica la política configurada a un webhook sin canal activo
+func (s *integrationService) handleUnresolvedChannel(ctx context.Context, message *domain.InboundMessage, channel *domain.ChannelIntegration, cause error) error {
+	if channel != nil {
+		message.TenantID = channel.TenantID
+		message.ChannelID = channel.ID
+	}
+
+	s.logger.Warn("Webhook received for unresolved channel", map[string]interface{}{
+		"platform":   message.Platform,
+		"channel_id": message.ChannelID,
+		"policy":     s.unresolvedPolicy,
+		"reason":     cause.Error(),
 	})
 
+	if s.unresolvedPolicy != UnresolvedChannelQuarantine {
+		return cause
+	}
+
+	// En cuarentena el mensaje se guarda sin reenviarlo y el proveedor recibe un 200
+	message.QuarantineReason = cause.Error()
+	s.saveInboundMessage(ctx, message)
 	return nil
 }
 
-// Mock data para desarrollo
-func (s *integrationService) getMockInboundMessages(platform string, limit int) ([]*domain.InboundMessage, error) {
-	mockMessages := []*domain.InboundMessage{
-		{
-			ID:         "mock-1",
-			Platform:   domain.Platform(platform),
-			Payload:    json.RawMessage(`{"text": "Mensaje de prueba"}`),
-			ReceivedAt: time.Now().Add(-time.Hour),
-			Processed:  true,
-		},
+// invalidateChannel descarta la resolución cacheada de un canal modificado
+func (s *integrationService) invalidateChannel(channelID string) {
+	if s.channelResolver != nil {
+		s.channelResolver.InvalidateChannel(channelID)
 	}
+}
+
+// enqueueForDelivery guarda el mensaje entrante, una entrada de outbox por evento y sus registros
+// de deduplicación en una misma transacción. Retorna cuántos eventos estaban repetidos.
+func (s *integrationService) enqueueForDelivery(ctx context.Context, message *domain.InboundMessage, items []inboundItem) (int, error) {
+	now := time.Now()
+	entries := make([]*domain.OutboxMessage, 0, len(items))
+
+	for i, item := range items {
+		if item.skipped() {
+			continue
+		}
 
-	if limit > len(mockMessages) {
-		return mockMessages, nil
+		outboxPayload, err := json.Marshal(item.payload)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal %s event: %w", item.eventType, err)
+		}
+
+		entries = append(entries, &domain.OutboxMessage{
+			ID:               uuid.New().String(),
+			InboundMessageID: message.ID,
+			ItemIndex:        i,
+			EventType:        item.eventType,
+			Platform:         message.Platform,
+			MessageID:        item.messageID,
+			IdempotencyKey:   item.idempotencyKey,
+			Payload:          outboxPayload,
+			Status:           domain.OutboxStatusPending,
+			NextAttemptAt:    now,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+			DedupEvent:       s.dedupEvent(message, item, now),
+		})
+	}
+
+	if err := s.outboxRepo.EnqueueWithInbound(ctx, message, entries); err != nil {
+		return 0, err
+	}
+
+	duplicates := 0
+	for _, entry := range entries {
+		if entry.Duplicate {
+			s.markDuplicate(message, items, entry.ItemIndex)
+			duplicates++
+		}
+	}
+	return duplicates, nil
+}
+
+// saveInboundMessage guarda el mensaje entrante sin bloquear el procesamiento si falla
+func (s *integrationService) saveInboundMessage(ctx context.Context, message *domain.InboundMessage) {
+	if s.inboundRepo == nil {
+		return
+	}
+	if err := s.inboundRepo.Create(ctx, message); err != nil {
+		s.logger.Error("Failed to save inbound message", err)
 	}
-	return mockMessages[:limit], nil
 }
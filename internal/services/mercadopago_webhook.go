@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -11,11 +12,14 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"it-integration-service/internal/config"
 )
 
 // MercadoPagoWebhookService maneja la validación de webhooks de Mercado Pago
 type MercadoPagoWebhookService struct {
 	secretKey string
+	config    *config.MercadoPagoConfig
 }
 
 // NewMercadoPagoWebhookService crea una nueva instancia del servicio de webhooks
@@ -25,6 +29,23 @@ func NewMercadoPagoWebhookService(secretKey string) *MercadoPagoWebhookService {
 	}
 }
 
+// NewMercadoPagoWebhookServiceFromConfig crea el servicio resolviendo la clave de webhooks en
+// cada validación, de modo que rotarla no requiera reiniciar
+func NewMercadoPagoWebhookServiceFromConfig(cfg *config.MercadoPagoConfig) *MercadoPagoWebhookService {
+	return &MercadoPagoWebhookService{
+		secretKey: cfg.SecretKey,
+		config:    cfg,
+	}
+}
+
+// webhookSecret retorna la clave vigente para validar la firma
+func (s *MercadoPagoWebhookService) webhookSecret(ctx context.Context) (string, error) {
+	if s.config == nil {
+		return s.secretKey, nil
+	}
+	return s.config.ResolveWebhookSecret(ctx)
+}
+
 // ValidateWebhookSignature valida la firma del webhook según la documentación de Mercado Pago
 func (s *MercadoPagoWebhookService) ValidateWebhookSignature(r *http.Request, body []byte) (bool, error) {
 	// Obtener headers necesarios
@@ -48,8 +69,12 @@ func (s *MercadoPagoWebhookService) ValidateWebhookSignature(r *http.Request, bo
 	// Generar el template de firma
 	manifest := s.generateManifest(dataID, xRequestId, ts)
 
-	// Calcular HMAC
-	expectedHash := s.calculateHMAC(manifest)
+	// Calcular HMAC con la clave vigente
+	secretKey, err := s.webhookSecret(r.Context())
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve webhook secret: %w", err)
+	}
+	expectedHash := s.calculateHMAC(secretKey, manifest)
 
 	// Comparar hashes
 	if expectedHash != hash {
@@ -99,8 +124,8 @@ func (s *MercadoPagoWebhookService) generateManifest(dataID, xRequestId, ts stri
 }
 
 // calculateHMAC calcula el HMAC SHA256
-func (s *MercadoPagoWebhookService) calculateHMAC(manifest string) string {
-	h := hmac.New(sha256.New, []byte(s.secretKey))
+func (s *MercadoPagoWebhookService) calculateHMAC(secretKey, manifest string) string {
+	h := hmac.New(sha256.New, []byte(secretKey))
 	h.Write([]byte(manifest))
 	return hex.EncodeToString(h.Sum(nil))
 }
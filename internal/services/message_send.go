@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// MessageSendService envía mensajes salientes por el canal indicado y los registra en outbound_message_logs
+type MessageSendService interface {
+	SendMessage(ctx context.Context, req *domain.SendMessageRequest) (*domain.OutboundMessageLog, error)
+}
+
+type messageSendService struct {
+	channelRepo  domain.ChannelIntegrationRepository
+	outboundRepo domain.OutboundMessageLogRepository
+	templates    *WhatsAppTemplateService
+	sessions     ConversationSessionService
+	senders      map[domain.Platform]Sender
+	logger       logger.Logger
+}
+
+// NewMessageSendService crea una nueva instancia del servicio de envío. Sin templates los
+// envíos de plantilla se entregan a Meta sin validar sus parámetros, y sin sessions no se
+// verifica la ventana de atención de los mensajes libres.
+func NewMessageSendService(
+	channelRepo domain.ChannelIntegrationRepository,
+	outboundRepo domain.OutboundMessageLogRepository,
+	templates *WhatsAppTemplateService,
+	sessions ConversationSessionService,
+	senders []Sender,
+	logger logger.Logger,
+) MessageSendService {
+	senderMap := make(map[domain.Platform]Sender, len(senders))
+	for _, sender := range senders {
+		senderMap[sender.Platform()] = sender
+	}
+
+	return &messageSendService{
+		channelRepo:  channelRepo,
+		outboundRepo: outboundRepo,
+		templates:    templates,
+		sessions:     sessions,
+		senders:      senderMap,
+		logger:       logger,
+	}
+}
+
+// DefaultSenders retorna un sender por cada plataforma con API de envío
+func DefaultSenders(logger logger.Logger) []Sender {
+	return []Sender{
+		NewWhatsAppSender(logger),
+		NewMessengerSender(logger),
+		NewInstagramSender(logger),
+		NewTelegramSender(logger),
+		NewWebchatSender(logger),
+	}
+}
+
+func (s *messageSendService) SendMessage(ctx context.Context, req *domain.SendMessageRequest) (*domain.OutboundMessageLog, error) {
+	if err := validateContent(&req.Content); err != nil {
+		return nil, err
+	}
+
+	channel, err := s.channelRepo.GetByID(ctx, req.ChannelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, fmt.Errorf("failed to get channel: %w", err)
+	}
+	if channel.Status != domain.StatusActive {
+		return nil, ErrChannelDisabled
+	}
+
+	sender, ok := s.senders[channel.Platform]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPlatform, channel.Platform)
+	}
+
+	token, err := channelAccessToken(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fuera de la ventana de atención Meta solo acepta plantillas
+	if req.Content.Type != domain.ContentTypeTemplate && s.sessions != nil {
+		if err := s.sessions.CheckWindow(ctx, channel, req.Recipient); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Content.Type == domain.ContentTypeTemplate {
+		if channel.Platform != domain.PlatformWhatsApp {
+			return nil, invalidContent("template messages are only supported by whatsapp")
+		}
+		// Se valida antes de crear el log para no registrar envíos que Meta rechazaría
+		if s.templates != nil {
+			if err := s.templates.ValidateTemplateContent(ctx, channel, token, req.Content.Template); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	contentJSON, err := json.Marshal(req.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message content: %w", err)
+	}
+
+	// El log se crea antes de enviar para que los recibos de estado encuentren el mensaje
+	log := &domain.OutboundMessageLog{
+		ID:        uuid.New().String(),
+		ChannelID: channel.ID,
+		Recipient: req.Recipient,
+		Content:   contentJSON,
+		Status:    domain.MessageStatusQueued,
+		Timestamp: time.Now(),
+	}
+	if err := s.outboundRepo.Create(ctx, log); err != nil {
+		return nil, fmt.Errorf("failed to create outbound message log: %w", err)
+	}
+
+	result, sendErr := sender.Send(ctx, channel, token, req.Recipient, &req.Content)
+	if sendErr != nil {
+		log.Status = domain.MessageStatusFailed
+		log.Response = failedSendResponse(sendErr)
+		if err := s.outboundRepo.UpdateSendResult(ctx, log.ID, log.Status, "", log.Response); err != nil {
+			s.logger.Error("Failed to record failed send", map[string]interface{}{
+				"log_id": log.ID,
+				"error":  err.Error(),
+			})
+		}
+
+		s.logger.Error("Failed to send message", map[string]interface{}{
+			"channel_id": channel.ID,
+			"platform":   channel.Platform,
+			"log_id":     log.ID,
+			"error":      sendErr.Error(),
+		})
+		return log, sendErr
+	}
+
+	log.Status = domain.MessageStatusSent
+	log.PlatformMessageID = result.PlatformMessageID
+	if json.Valid(result.Response) {
+		log.Response = result.Response
+	}
+	if err := s.outboundRepo.UpdateSendResult(ctx, log.ID, log.Status, log.PlatformMessageID, log.Response); err != nil {
+		// El mensaje ya salió; no se reporta como fallo para evitar reenvíos duplicados
+		s.logger.Error("Failed to record send result", map[string]interface{}{
+			"log_id":     log.ID,
+			"message_id": log.PlatformMessageID,
+			"error":      err.Error(),
+		})
+	}
+
+	return log, nil
+}
+
+// channelAccessToken obtiene el token del canal; el repositorio ya lo entrega descifrado
+func channelAccessToken(channel *domain.ChannelIntegration) (string, error) {
+	if channel.AccessToken == "" {
+		return "", fmt.Errorf("channel %s has no access token", channel.ID)
+	}
+	return channel.AccessToken, nil
+}
+
+// failedSendResponse conserva la respuesta de la plataforma o, si no hubo, el mensaje de error
+func failedSendResponse(err error) json.RawMessage {
+	var platformErr *sendError
+	if errors.As(err, &platformErr) && json.Valid(platformErr.response) {
+		return platformErr.response
+	}
+	response, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return response
+}
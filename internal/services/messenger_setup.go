@@ -64,29 +64,23 @@ func (s *MessengerSetupService) GetPageInfo(ctx context.Context, pageAccessToken
 	}
 	defer resp.Body.Close()
 
-	var pageInfo MessengerPageInfo
-	if err := json.NewDecoder(resp.Body).Decode(&pageInfo); err != nil {
+	// Graph API responde la página en la raíz y los errores en "error"
+	var pageResp struct {
+		MessengerPageInfo
+		Error *MetaAPIError `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pageResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Verificar si hay error en la respuesta
-	if pageInfo.ID == "" {
-		var errorResp MetaAPIResponse
-		resp.Body.Close()
-		
-		// Hacer la petición de nuevo para obtener el error
-		resp2, _ := client.Do(req)
-		if resp2 != nil {
-			defer resp2.Body.Close()
-			json.NewDecoder(resp2.Body).Decode(&errorResp)
-			if errorResp.Error != nil {
-				return nil, fmt.Errorf("facebook API error: %s", errorResp.Error.Message)
-			}
-		}
+	if pageResp.Error != nil {
+		return nil, fmt.Errorf("facebook API error: %s", pageResp.Error.Message)
+	}
+	if pageResp.ID == "" {
 		return nil, fmt.Errorf("invalid page response")
 	}
 
-	return &pageInfo, nil
+	return &pageResp.MessengerPageInfo, nil
 }
 
 // SubscribeToWebhooks suscribe la página a webhooks de Messenger
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/events"
+	"it-integration-service/pkg/secrets"
+)
+
+// NotificationSignatureHeader lleva la firma HMAC-SHA256 del cuerpo enviado a un webhook
+const NotificationSignatureHeader = "X-Signature-256"
+
+// NotificationSender entrega los avisos a un tipo de destino
+type NotificationSender interface {
+	Type() domain.NotificationDestinationType
+	Send(ctx context.Context, destination domain.NotificationDestination, notification *TokenNotification) error
+}
+
+// DefaultNotificationSenders retorna los senders disponibles: email solo con SMTP configurado
+// y evento solo con bus de eventos
+func DefaultNotificationSenders(cfg config.NotificationConfig, secretProvider secrets.Provider, bus events.EventBus) []NotificationSender {
+	senders := []NotificationSender{NewWebhookNotificationSender(cfg.WebhookTimeout)}
+	if cfg.SMTPHost != "" {
+		senders = append(senders, NewSMTPNotificationSender(cfg, secretProvider))
+	}
+	if bus != nil {
+		senders = append(senders, NewEventNotificationSender(bus))
+	}
+	return senders
+}
+
+// WebhookNotificationSender envía el aviso por POST a un endpoint HTTPS del tenant
+type WebhookNotificationSender struct {
+	client *http.Client
+}
+
+// NewWebhookNotificationSender crea el sender de webhooks
+func NewWebhookNotificationSender(timeout time.Duration) *WebhookNotificationSender {
+	return &WebhookNotificationSender{client: &http.Client{Timeout: timeout}}
+}
+
+func (s *WebhookNotificationSender) Type() domain.NotificationDestinationType {
+	return domain.NotificationDestinationWebhook
+}
+
+// Send envía el aviso en JSON con la firma "sha256=<hex>" del cuerpo con el secreto del destino
+func (s *WebhookNotificationSender) Send(ctx context.Context, destination domain.NotificationDestination, notification *TokenNotification) error {
+	target, err := url.Parse(destination.URL)
+	if err != nil || target.Scheme != "https" {
+		return errors.New("webhook url must use https")
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Notification-Event", string(notification.Event))
+	req.Header.Set("X-Notification-ID", notification.ID)
+	req.Header.Set(NotificationSignatureHeader, SignNotification(destination.Secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignNotification retorna el valor de la cabecera de firma para el cuerpo dado
+func SignNotification(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SMTPNotificationSender envía el aviso por email con el servidor SMTP configurado
+type SMTPNotificationSender struct {
+	cfg     config.NotificationConfig
+	secrets secrets.Provider
+}
+
+// NewSMTPNotificationSender crea el sender de emails; la contraseña se resuelve como smtp_password
+func NewSMTPNotificationSender(cfg config.NotificationConfig, secretProvider secrets.Provider) *SMTPNotificationSender {
+	return &SMTPNotificationSender{cfg: cfg, secrets: secretProvider}
+}
+
+func (s *SMTPNotificationSender) Type() domain.NotificationDestinationType {
+	return domain.NotificationDestinationEmail
+}
+
+// Send envía un email de texto plano a los destinatarios del destino. Usa STARTTLS si el
+// servidor lo ofrece y autenticación PLAIN solo con usuario configurado.
+func (s *SMTPNotificationSender) Send(ctx context.Context, destination domain.NotificationDestination, notification *TokenNotification) error {
+	if len(destination.Emails) == 0 {
+		return errors.New("email destination has no recipients")
+	}
+
+	var auth smtp.Auth
+	if s.cfg.SMTPUsername != "" {
+		password, err := secrets.Resolve(ctx, s.secrets, "smtp_password", s.cfg.SMTPPassword)
+		if err != nil {
+			return fmt.Errorf("failed to resolve smtp password: %w", err)
+		}
+		auth = smtp.PlainAuth("", s.cfg.SMTPUsername, password, s.cfg.SMTPHost)
+	}
+
+	addr := net.JoinHostPort(s.cfg.SMTPHost, strconv.Itoa(s.cfg.SMTPPort))
+	message := notificationEmail(s.cfg.SMTPFrom, destination.Emails, notification)
+	if err := smtp.SendMail(addr, auth, s.cfg.SMTPFrom, destination.Emails, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// notificationEmail arma el mensaje con cabeceras y líneas terminadas en CRLF
+func notificationEmail(from string, to []string, notification *TokenNotification) []byte {
+	subject := fmt.Sprintf("[%s] Channel token expires in %d days", notification.Platform, notification.DaysUntilExpiry)
+	if notification.Event == domain.NotificationTokenExpired {
+		subject = fmt.Sprintf("[%s] Channel token expired", notification.Platform)
+	}
+
+	expiresAt := "unknown"
+	if notification.ExpiresAt != nil {
+		expiresAt = notification.ExpiresAt.Format(time.RFC3339)
+	}
+
+	lines := []string{
+		"From: " + from,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + subject,
+		"Date: " + notification.OccurredAt.Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		subject + ".",
+		"",
+		"Tenant: " + notification.TenantID,
+		"Channel: " + notification.ChannelID,
+		"Platform: " + notification.Platform,
+		"Expires at: " + expiresAt,
+		"",
+	}
+	if notification.Event == domain.NotificationTokenExpired {
+		lines = append(lines, "The channel was deactivated. Rotate its token to resume sending and receiving messages.")
+	} else {
+		lines = append(lines, "Rotate the channel token before it expires to avoid interrupting the channel.")
+	}
+
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// EventNotificationSender publica el aviso en el bus de eventos como integration.<evento>
+type EventNotificationSender struct {
+	bus     events.EventBus
+	factory *events.EventFactory
+}
+
+// NewEventNotificationSender crea el sender del bus de eventos
+func NewEventNotificationSender(bus events.EventBus) *EventNotificationSender {
+	return &EventNotificationSender{
+		bus:     bus,
+		factory: events.NewEventFactory("it-integration-service"),
+	}
+}
+
+func (s *EventNotificationSender) Type() domain.NotificationDestinationType {
+	return domain.NotificationDestinationEvent
+}
+
+func (s *EventNotificationSender) Send(ctx context.Context, destination domain.NotificationDestination, notification *TokenNotification) error {
+	data := map[string]interface{}{
+		"notification_id":   notification.ID,
+		"tenant_id":         notification.TenantID,
+		"channel_id":        notification.ChannelID,
+		"platform":          notification.Platform,
+		"days_until_expiry": notification.DaysUntilExpiry,
+	}
+	if notification.ExpiresAt != nil {
+		data["expires_at"] = *notification.ExpiresAt
+	}
+
+	event := s.factory.CreateSystemEvent("integration."+string(notification.Event), data)
+	if err := s.bus.Publish(ctx, event); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+)
+
+// OutboxDispatcher entrega en segundo plano los mensajes del outbox al servicio de mensajería
+type OutboxDispatcher struct {
+	outboxRepo     domain.MessageOutboxRepository
+	webhookService WebhookService
+	config         config.OutboxConfig
+	logger         logger.Logger
+}
+
+// NewOutboxDispatcher crea una nueva instancia del dispatcher del outbox
+func NewOutboxDispatcher(outboxRepo domain.MessageOutboxRepository, webhookService WebhookService, cfg config.OutboxConfig, logger logger.Logger) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		outboxRepo:     outboxRepo,
+		webhookService: webhookService,
+		config:         cfg,
+		logger:         logger,
+	}
+}
+
+// Start inicia el loop de entrega hasta que se cancele el contexto
+func (d *OutboxDispatcher) Start(ctx context.Context) {
+	if !d.config.Enabled {
+		d.logger.Info("Outbox dispatcher is disabled")
+		return
+	}
+
+	if d.config.LeaseDuration < 2*d.config.RequestTimeout {
+		d.logger.Warn("Outbox lease shorter than two request timeouts, a slow delivery may outlive its lease", map[string]interface{}{
+			"lease":           d.config.LeaseDuration,
+			"request_timeout": d.config.RequestTimeout,
+		})
+	}
+
+	go d.run(ctx)
+
+	d.logger.Info("Outbox dispatcher started", map[string]interface{}{
+		"poll_interval": d.config.PollInterval,
+		"batch_size":    d.claimLimit(),
+		"max_attempts":  d.config.MaxAttempts,
+	})
+}
+
+// run ejecuta el polling del outbox
+func (d *OutboxDispatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info("Outbox dispatcher stopped")
+			return
+		case <-ticker.C:
+			// Mientras haya lotes completos seguimos drenando sin esperar al siguiente tick
+			for {
+				claimed, err := d.DispatchBatch(ctx)
+				if err != nil {
+					d.logger.Error("Failed to dispatch outbox batch", err)
+					break
+				}
+				if claimed < d.claimLimit() || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// claimLimit calcula cuántos mensajes reclamar por lote. Las entregas son secuenciales y cada una
+// puede tardar hasta RequestTimeout, así que el lote se limita para que termine antes de que venza
+// el lease, con un timeout de margen para las actualizaciones del outbox. De lo contrario otra
+// réplica volvería a reclamar y entregar los mensajes que todavía no se enviaron.
+func (d *OutboxDispatcher) claimLimit() int {
+	limit := d.config.BatchSize
+	if d.config.RequestTimeout > 0 {
+		if fit := int(d.config.LeaseDuration/d.config.RequestTimeout) - 1; fit < limit {
+			limit = fit
+		}
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
+// DispatchBatch reclama y entrega un lote de mensajes, retornando cuántos se reclamaron
+func (d *OutboxDispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	messages, err := d.outboxRepo.ClaimDue(ctx, d.claimLimit(), d.config.LeaseDuration)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	for _, message := range messages {
+		d.deliver(ctx, message)
+	}
+
+	return len(messages), nil
+}
+
+// deliver intenta entregar un mensaje y registra el resultado en el outbox. message.Attempts ya
+// incluye el intento actual, que se contó al reclamarlo.
+func (d *OutboxDispatcher) deliver(ctx context.Context, message *domain.OutboxMessage) {
+	// Solo se llega aquí con más intentos que el máximo si el último reclamo venció sin registrar
+	// resultado (p. ej. el worker se cayó al entregarlo); no se vuelve a intentar
+	if message.Attempts > d.config.MaxAttempts {
+		d.deadLetter(ctx, message, fmt.Errorf("lease expired without result after %d attempts", message.Attempts-1))
+		return
+	}
+
+	forwardCtx, cancel := context.WithTimeout(ctx, d.config.RequestTimeout)
+	err := d.forward(forwardCtx, message)
+	cancel()
+
+	var payloadErr *outboxPayloadError
+	if errors.As(err, &payloadErr) {
+		// Un payload corrupto nunca se entregará, no tiene sentido reintentar
+		d.deadLetter(ctx, message, err)
+		return
+	}
+
+	if err == nil {
+		if err := d.outboxRepo.MarkDelivered(ctx, message.ID); err != nil {
+			d.logger.Error("Failed to mark outbox message as delivered", map[string]interface{}{
+				"outbox_id": message.ID,
+				"error":     err.Error(),
+			})
+		}
+		return
+	}
+
+	if message.Attempts >= d.config.MaxAttempts {
+		d.deadLetter(ctx, message, err)
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(d.backoff(message.Attempts))
+	if markErr := d.outboxRepo.MarkRetry(ctx, message.ID, nextAttemptAt, err.Error()); markErr != nil {
+		d.logger.Error("Failed to schedule outbox retry", map[string]interface{}{
+			"outbox_id": message.ID,
+			"error":     markErr.Error(),
+		})
+		return
+	}
+
+	d.logger.Warn("Outbox delivery failed, retry scheduled", map[string]interface{}{
+		"outbox_id":       message.ID,
+		"message_id":      message.MessageID,
+		"attempts":        message.Attempts,
+		"next_attempt_at": nextAttemptAt,
+		"error":           err.Error(),
+	})
+}
+
+// outboxPayloadError indica que el payload de una entrada del outbox no se puede decodificar
+type outboxPayloadError struct {
+	err error
+}
+
+func (e *outboxPayloadError) Error() string {
+	return fmt.Sprintf("failed to unmarshal outbox payload: %v", e.err)
+}
+
+// forward reenvía la entrada según su tipo de evento
+func (d *OutboxDispatcher) forward(ctx context.Context, message *domain.OutboxMessage) error {
+	if message.EventType == domain.EventTypeStatus {
+		var event StatusEvent
+		if err := json.Unmarshal(message.Payload, &event); err != nil {
+			return &outboxPayloadError{err: err}
+		}
+		return d.webhookService.ForwardStatusToMessagingService(ctx, &event)
+	}
+
+	var normalized NormalizedMessage
+	if err := json.Unmarshal(message.Payload, &normalized); err != nil {
+		return &outboxPayloadError{err: err}
+	}
+	return d.webhookService.ForwardToMessagingService(ctx, &normalized)
+}
+
+// deadLetter mueve un mensaje al estado dead_letter
+func (d *OutboxDispatcher) deadLetter(ctx context.Context, message *domain.OutboxMessage, cause error) {
+	if err := d.outboxRepo.MarkDeadLetter(ctx, message.ID, cause.Error()); err != nil {
+		d.logger.Error("Failed to move outbox message to dead letter", map[string]interface{}{
+			"outbox_id": message.ID,
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	d.logger.Error("Outbox message moved to dead letter", map[string]interface{}{
+		"outbox_id":  message.ID,
+		"message_id": message.MessageID,
+		"platform":   message.Platform,
+		"attempts":   message.Attempts,
+		"error":      cause.Error(),
+	})
+}
+
+// backoff calcula el retraso exponencial con jitter para el intento dado
+func (d *OutboxDispatcher) backoff(attempt int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < attempt && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.config.MaxBackoff {
+		delay = d.config.MaxBackoff
+	}
+
+	// Jitter entre el 50% y el 100% del retraso para evitar reintentos sincronizados
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOutboxRepository reproduce los reclamos con lease y las transiciones de la tabla message_outbox
+type memoryOutboxRepository struct {
+	mu          sync.Mutex
+	messages    map[string]*domain.OutboxMessage
+	lockedUntil map[string]time.Time
+	claimLimits []int
+	now         time.Time
+}
+
+func newMemoryOutboxRepository(messages ...*domain.OutboxMessage) *memoryOutboxRepository {
+	r := &memoryOutboxRepository{
+		messages:    make(map[string]*domain.OutboxMessage),
+		lockedUntil: make(map[string]time.Time),
+		now:         time.Now(),
+	}
+	for _, message := range messages {
+		r.messages[message.ID] = message
+	}
+	return r
+}
+
+func (r *memoryOutboxRepository) EnqueueWithInbound(ctx context.Context, inbound *domain.InboundMessage, messages []*domain.OutboxMessage) error {
+	return errors.New("not implemented")
+}
+
+func (r *memoryOutboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.claimLimits = append(r.claimLimits, limit)
+
+	var due []*domain.OutboxMessage
+	for _, message := range r.messages {
+		pending := message.Status == domain.OutboxStatusPending && !message.NextAttemptAt.After(r.now)
+		abandoned := message.Status == domain.OutboxStatusProcessing && r.lockedUntil[message.ID].Before(r.now)
+		if pending || abandoned {
+			due = append(due, message)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*domain.OutboxMessage, 0, len(due))
+	for _, message := range due {
+		message.Status = domain.OutboxStatusProcessing
+		message.Attempts++
+		r.lockedUntil[message.ID] = r.now.Add(lease)
+		copied := *message
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *memoryOutboxRepository) MarkDelivered(ctx context.Context, id string) error {
+	return r.transition(id, func(message *domain.OutboxMessage) {
+		message.Status = domain.OutboxStatusDelivered
+	})
+}
+
+func (r *memoryOutboxRepository) MarkRetry(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	return r.transition(id, func(message *domain.OutboxMessage) {
+		message.Status = domain.OutboxStatusPending
+		message.NextAttemptAt = nextAttemptAt
+		message.LastError = lastError
+	})
+}
+
+func (r *memoryOutboxRepository) MarkDeadLetter(ctx context.Context, id string, lastError string) error {
+	return r.transition(id, func(message *domain.OutboxMessage) {
+		message.Status = domain.OutboxStatusDeadLetter
+		message.LastError = lastError
+	})
+}
+
+func (r *memoryOutboxRepository) transition(id string, apply func(message *domain.OutboxMessage)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	message, ok := r.messages[id]
+	if !ok || message.Status != domain.OutboxStatusProcessing {
+		return fmt.Errorf("outbox message not claimed: %w", sql.ErrNoRows)
+	}
+	apply(message)
+	delete(r.lockedUntil, id)
+	return nil
+}
+
+func (r *memoryOutboxRepository) get(id string) domain.OutboxMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.messages[id]
+}
+
+func (r *memoryOutboxRepository) advance(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.now = r.now.Add(d)
+}
+
+// scriptedForwarder responde a cada reenvío con el error configurado y cuenta las entregas
+type scriptedForwarder struct {
+	WebhookService
+	mu        sync.Mutex
+	err       error
+	forwarded map[string]int
+}
+
+func (f *scriptedForwarder) ForwardToMessagingService(ctx context.Context, message *NormalizedMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.forwarded == nil {
+		f.forwarded = make(map[string]int)
+	}
+	f.forwarded[message.MessageID]++
+	return f.err
+}
+
+func outboxEntry(t *testing.T, id string) *domain.OutboxMessage {
+	payload, err := json.Marshal(&NormalizedMessage{Platform: domain.PlatformWhatsApp, MessageID: id})
+	require.NoError(t, err)
+	return &domain.OutboxMessage{
+		ID:            id,
+		EventType:     domain.EventTypeMessage,
+		Platform:      domain.PlatformWhatsApp,
+		MessageID:     id,
+		Payload:       payload,
+		Status:        domain.OutboxStatusPending,
+		NextAttemptAt: time.Now().Add(-time.Second),
+	}
+}
+
+func outboxTestConfig() config.OutboxConfig {
+	return config.OutboxConfig{
+		Enabled:        true,
+		PollInterval:   time.Second,
+		BatchSize:      50,
+		MaxAttempts:    3,
+		BaseBackoff:    10 * time.Second,
+		MaxBackoff:     15 * time.Second,
+		LeaseDuration:  time.Minute,
+		RequestTimeout: time.Second,
+	}
+}
+
+func TestOutboxDispatcherRetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryOutboxRepository(outboxEntry(t, "msg-1"))
+	forwarder := &scriptedForwarder{err: errors.New("messaging service unavailable")}
+	cfg := outboxTestConfig()
+	dispatcher := NewOutboxDispatcher(repo, forwarder, cfg, logger.NewLogger("error"))
+
+	// Primer fallo: se reintenta entre el 50% y el 100% del backoff base
+	before := time.Now()
+	claimed, err := dispatcher.DispatchBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+
+	message := repo.get("msg-1")
+	assert.Equal(t, domain.OutboxStatusPending, message.Status)
+	assert.Equal(t, 1, message.Attempts)
+	assert.Equal(t, "messaging service unavailable", message.LastError)
+	assert.False(t, message.NextAttemptAt.Before(before.Add(cfg.BaseBackoff/2)))
+	assert.False(t, message.NextAttemptAt.After(time.Now().Add(cfg.BaseBackoff)))
+
+	// Un reintento que no venció no se reclama
+	claimed, err = dispatcher.DispatchBatch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, claimed)
+
+	// Segundo fallo: el backoff se duplica pero no supera MaxBackoff
+	repo.advance(cfg.BaseBackoff)
+	before = time.Now()
+	claimed, err = dispatcher.DispatchBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+
+	message = repo.get("msg-1")
+	assert.Equal(t, 2, message.Attempts)
+	assert.False(t, message.NextAttemptAt.Before(before.Add(cfg.MaxBackoff/2)))
+	assert.False(t, message.NextAttemptAt.After(time.Now().Add(cfg.MaxBackoff)))
+	assert.Equal(t, 2, forwarder.forwarded["msg-1"])
+}
+
+func TestOutboxDispatcherDeadLetters(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("al agotar los intentos", func(t *testing.T) {
+		repo := newMemoryOutboxRepository(outboxEntry(t, "msg-1"))
+		forwarder := &scriptedForwarder{err: errors.New("messaging service unavailable")}
+		cfg := outboxTestConfig()
+		dispatcher := NewOutboxDispatcher(repo, forwarder, cfg, logger.NewLogger("error"))
+
+		for i := 0; i < cfg.MaxAttempts; i++ {
+			_, err := dispatcher.DispatchBatch(ctx)
+			require.NoError(t, err)
+			repo.advance(cfg.MaxBackoff)
+		}
+
+		message := repo.get("msg-1")
+		assert.Equal(t, domain.OutboxStatusDeadLetter, message.Status)
+		assert.Equal(t, cfg.MaxAttempts, message.Attempts)
+		assert.Equal(t, cfg.MaxAttempts, forwarder.forwarded["msg-1"])
+
+		claimed, err := dispatcher.DispatchBatch(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, claimed)
+	})
+
+	t.Run("payload corrupto sin reintentar", func(t *testing.T) {
+		entry := outboxEntry(t, "msg-1")
+		entry.Payload = []byte(`{"platform":`)
+		repo := newMemoryOutboxRepository(entry)
+		forwarder := &scriptedForwarder{}
+		dispatcher := NewOutboxDispatcher(repo, forwarder, outboxTestConfig(), logger.NewLogger("error"))
+
+		_, err := dispatcher.DispatchBatch(ctx)
+		require.NoError(t, err)
+
+		message := repo.get("msg-1")
+		assert.Equal(t, domain.OutboxStatusDeadLetter, message.Status)
+		assert.Equal(t, 1, message.Attempts)
+		assert.Contains(t, message.LastError, "failed to unmarshal outbox payload")
+		assert.Empty(t, forwarder.forwarded)
+	})
+}
+
+func TestOutboxDispatcherReclaimsExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryOutboxRepository(outboxEntry(t, "msg-1"))
+	forwarder := &scriptedForwarder{}
+	cfg := outboxTestConfig()
+	dispatcher := NewOutboxDispatcher(repo, forwarder, cfg, logger.NewLogger("error"))
+
+	// Una réplica que se cae tras reclamar deja el mensaje en processing sin resultado
+	for i := 1; i <= cfg.MaxAttempts; i++ {
+		claimed, err := repo.ClaimDue(ctx, cfg.BatchSize, cfg.LeaseDuration)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, i, claimed[0].Attempts)
+
+		// Mientras el lease está vigente nadie más lo reclama
+		claimed, err = repo.ClaimDue(ctx, cfg.BatchSize, cfg.LeaseDuration)
+		require.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		repo.advance(cfg.LeaseDuration + time.Second)
+	}
+
+	// El reclamo tras el último intento abandonado no vuelve a entregar el mensaje
+	claimed, err := dispatcher.DispatchBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+
+	message := repo.get("msg-1")
+	assert.Equal(t, domain.OutboxStatusDeadLetter, message.Status)
+	assert.Equal(t, cfg.MaxAttempts+1, message.Attempts)
+	assert.Contains(t, message.LastError, "lease expired")
+	assert.Empty(t, forwarder.forwarded)
+
+	// Un lease vencido con intentos disponibles se reclama y se entrega normalmente
+	repo = newMemoryOutboxRepository(outboxEntry(t, "msg-2"))
+	dispatcher = NewOutboxDispatcher(repo, forwarder, cfg, logger.NewLogger("error"))
+	_, err = repo.ClaimDue(ctx, cfg.BatchSize, cfg.LeaseDuration)
+	require.NoError(t, err)
+	repo.advance(cfg.LeaseDuration + time.Second)
+
+	claimed, err = dispatcher.DispatchBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+	message = repo.get("msg-2")
+	assert.Equal(t, domain.OutboxStatusDelivered, message.Status)
+	assert.Equal(t, 2, message.Attempts)
+	assert.Equal(t, 1, forwarder.forwarded["msg-2"])
+}
+
+func TestOutboxDispatcherClaimsWhatFitsInTheLease(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+		lease     time.Duration
+		timeout   time.Duration
+		want      int
+	}{
+		{name: "lease holgado usa el batch configurado", batchSize: 5, lease: time.Minute, timeout: time.Second, want: 5},
+		{name: "entregas lentas reducen el lote", batchSize: 50, lease: time.Minute, timeout: 10 * time.Second, want: 5},
+		{name: "lease menor al timeout reclama de a uno", batchSize: 50, lease: 5 * time.Second, timeout: 10 * time.Second, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []*domain.OutboxMessage
+			for i := 0; i < 60; i++ {
+				entries = append(entries, outboxEntry(t, fmt.Sprintf("msg-%02d", i)))
+			}
+			repo := newMemoryOutboxRepository(entries...)
+			cfg := outboxTestConfig()
+			cfg.BatchSize = tt.batchSize
+			cfg.LeaseDuration = tt.lease
+			cfg.RequestTimeout = tt.timeout
+			dispatcher := NewOutboxDispatcher(repo, &scriptedForwarder{}, cfg, logger.NewLogger("error"))
+
+			claimed, err := dispatcher.DispatchBatch(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, claimed)
+			assert.Equal(t, []int{tt.want}, repo.claimLimits)
+		})
+	}
+}
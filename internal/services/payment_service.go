@@ -82,7 +82,9 @@ func (s *PaymentService) CreatePayment(request *models.PaymentRequest) (*models.
 
 	// Configurar headers
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.config.AccessToken)
+	if err := s.authorize(req); err != nil {
+		return nil, err
+	}
 
 	// Ejecutar la solicitud
 	resp, err := s.client.Do(req)
@@ -124,7 +126,9 @@ func (s *PaymentService) GetPayment(paymentID int64) (*models.PaymentResponse, e
 		return nil, fmt.Errorf("error al crear la solicitud HTTP: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+s.config.AccessToken)
+	if err := s.authorize(req); err != nil {
+		return nil, err
+	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -167,7 +171,9 @@ func (s *PaymentService) RefundPayment(paymentID int64, amount float64) error {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.config.AccessToken)
+	if err := s.authorize(req); err != nil {
+		return err
+	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -191,6 +197,16 @@ func (s *PaymentService) ValidateWebhookSignature(signature, body string) bool {
 	return true
 }
 
+// authorize agrega a la solicitud el access token vigente de Mercado Pago
+func (s *PaymentService) authorize(req *http.Request) error {
+	token, err := s.config.ResolveAccessToken(req.Context())
+	if err != nil {
+		return fmt.Errorf("error al obtener el access token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
 // getAPIURL retorna la URL base de la API según el entorno
 func (s *PaymentService) getAPIURL() string {
 	if s.config.Environment == "production" {
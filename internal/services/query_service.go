@@ -2,19 +2,56 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"it-integration-service/internal/domain"
 	"it-integration-service/pkg/logger"
 )
 
+const (
+	// defaultMessageQueryLimit es la cantidad de mensajes por página si no se indica otra
+	defaultMessageQueryLimit = 50
+	// maxMessageQueryLimit es la cantidad máxima de mensajes por página
+	maxMessageQueryLimit = 500
+	// redactedValue reemplaza los valores ocultos del contenido de los mensajes
+	redactedValue = "[REDACTED]"
+)
+
+// ErrInvalidMessageQuery indica que los filtros o el cursor de una consulta de mensajes no son válidos
+var ErrInvalidMessageQuery = errors.New("invalid message query")
+
+// redactionSafeKeys son los campos técnicos del contenido de los mensajes que se conservan al
+// ocultar el contenido: tipos, estados, IDs de mensaje y fechas, sin datos de los contactos
+var redactionSafeKeys = map[string]bool{
+	"object":            true,
+	"field":             true,
+	"messaging_product": true,
+	"type":              true,
+	"status":            true,
+	"event":             true,
+	"message_id":        true,
+	"update_id":         true,
+	"mid":               true,
+	"seq":               true,
+	"watermark":         true,
+	"timestamp":         true,
+	"date":              true,
+	"time":              true,
+	"mime_type":         true,
+	"is_echo":           true,
+	"code":              true,
+}
+
 // QueryService define las operaciones para consultas de mensajes
 type QueryService interface {
-	GetInboundMessages(ctx context.Context, platform string, limit, offset int) ([]*domain.InboundMessage, error)
-	GetOutboundMessages(ctx context.Context, platform string, limit, offset int) ([]*domain.OutboundMessageLog, error)
+	GetInboundMessages(ctx context.Context, query domain.InboundMessageQuery) (*domain.InboundMessagePage, error)
+	GetOutboundMessages(ctx context.Context, query domain.OutboundMessageQuery) (*domain.OutboundMessagePage, error)
 	GetChatHistory(ctx context.Context, platform, userID string) (*domain.ChatHistory, error)
 }
 
@@ -40,88 +77,198 @@ func NewQueryService(
 	}
 }
 
-// GetInboundMessages obtiene mensajes entrantes con filtros
-func (s *queryService) GetInboundMessages(ctx context.Context, platform string, limit, offset int) ([]*domain.InboundMessage, error) {
-	if s.channelRepo == nil {
-		// Mock response for development
-		return []*domain.InboundMessage{
-			{
-				ID:         "mock-inbound-1",
-				Platform:   domain.Platform(platform),
-				Payload:    []byte(`{"message": {"text": "Mensaje de prueba mock"}}`),
-				ReceivedAt: time.Now().Add(-2 * time.Hour),
-				Processed:  true,
-			},
-		}, nil
-	}
-
-	// Construir query con filtros opcionales
-	query := `SELECT id, platform, payload, received_at, processed 
-			  FROM inbound_messages 
-			  WHERE ($1 = '' OR platform = $1) 
-			  ORDER BY received_at DESC 
-			  LIMIT $2 OFFSET $3`
-
-	rows, err := s.channelRepo.DB().QueryContext(ctx, query, platform, limit, offset)
+// GetInboundMessages obtiene una página de mensajes entrantes con filtros, los más recientes primero
+func (s *queryService) GetInboundMessages(ctx context.Context, query domain.InboundMessageQuery) (*domain.InboundMessagePage, error) {
+	limit, err := validateMessageQuery(query.From, query.To, query.Limit, query.Payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query inbound messages: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	var messages []*domain.InboundMessage
-	for rows.Next() {
-		var msg domain.InboundMessage
-		if err := rows.Scan(&msg.ID, &msg.Platform, &msg.Payload, &msg.ReceivedAt, &msg.Processed); err != nil {
-			s.logger.Error("Failed to scan inbound message", err)
-			continue
+	filter := domain.InboundMessageFilter{
+		Platform:   query.Platform,
+		TenantID:   query.TenantID,
+		ChannelID:  query.ChannelID,
+		Sender:     query.Sender,
+		From:       query.From,
+		To:         query.To,
+		Processed:  query.Processed,
+		Descending: true,
+		Limit:      limit,
+	}
+	if query.Cursor != "" {
+		receivedAt, id, err := decodeMessageCursor(query.Cursor)
+		if err != nil {
+			return nil, err
 		}
-		messages = append(messages, &msg)
+		filter.AfterReceivedAt = &receivedAt
+		filter.AfterID = id
+	}
+
+	messages, err := s.inboundRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.InboundMessagePage{Messages: messages}
+	if page.Messages == nil {
+		page.Messages = []*domain.InboundMessage{}
+	}
+	for _, message := range page.Messages {
+		message.Payload = applyPayloadMode(message.Payload, query.Payload)
+	}
+	if len(messages) == limit {
+		last := messages[len(messages)-1]
+		page.NextCursor = encodeMessageCursor(last.ReceivedAt, last.ID)
 	}
 
-	return messages, nil
+	return page, nil
 }
 
-// GetOutboundMessages obtiene mensajes salientes con filtros
-func (s *queryService) GetOutboundMessages(ctx context.Context, platform string, limit, offset int) ([]*domain.OutboundMessageLog, error) {
-	if s.outboundRepo == nil {
-		// Mock response for development
-		return []*domain.OutboundMessageLog{
-			{
-				ID:        "mock-outbound-1",
-				ChannelID: "mock-channel-1",
-				Recipient: "573001234567",
-				Status:    domain.MessageStatusSent,
-				Timestamp: time.Now().Add(-1 * time.Hour),
-			},
-		}, nil
-	}
-
-	// Construir query con filtros opcionales
-	query := `SELECT id, channel_id, recipient, content, status, response, timestamp 
-			  FROM outbound_message_logs 
-			  WHERE ($1 = '' OR channel_id IN (
-				  SELECT id FROM channel_integrations WHERE platform = $1
-			  ))
-			  ORDER BY timestamp DESC 
-			  LIMIT $2 OFFSET $3`
-
-	rows, err := s.channelRepo.DB().QueryContext(ctx, query, platform, limit, offset)
+// GetOutboundMessages obtiene una página del log de mensajes salientes con filtros, los más recientes primero
+func (s *queryService) GetOutboundMessages(ctx context.Context, query domain.OutboundMessageQuery) (*domain.OutboundMessagePage, error) {
+	limit, err := validateMessageQuery(query.From, query.To, query.Limit, query.Payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query outbound messages: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	var messages []*domain.OutboundMessageLog
-	for rows.Next() {
-		var msg domain.OutboundMessageLog
-		if err := rows.Scan(&msg.ID, &msg.ChannelID, &msg.Recipient, &msg.Content, &msg.Status, &msg.Response, &msg.Timestamp); err != nil {
-			s.logger.Error("Failed to scan outbound message", err)
-			continue
+	filter := domain.OutboundMessageFilter{
+		TenantID:  query.TenantID,
+		ChannelID: query.ChannelID,
+		Platform:  query.Platform,
+		Status:    query.Status,
+		Recipient: query.Recipient,
+		From:      query.From,
+		To:        query.To,
+		Limit:     limit,
+	}
+	if query.Cursor != "" {
+		timestamp, id, err := decodeMessageCursor(query.Cursor)
+		if err != nil {
+			return nil, err
 		}
-		messages = append(messages, &msg)
+		filter.BeforeTimestamp = &timestamp
+		filter.BeforeID = id
+	}
+
+	messages, err := s.outboundRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.OutboundMessagePage{Messages: messages}
+	if page.Messages == nil {
+		page.Messages = []*domain.OutboundMessageLog{}
+	}
+	for _, message := range page.Messages {
+		message.Content = applyPayloadMode(message.Content, query.Payload)
+		message.Response = applyPayloadMode(message.Response, query.Payload)
+	}
+	if len(messages) == limit {
+		last := messages[len(messages)-1]
+		page.NextCursor = encodeMessageCursor(last.Timestamp, last.ID)
+	}
+
+	return page, nil
+}
+
+// validateMessageQuery valida el rango de fechas y el modo de contenido y retorna el límite de la página
+func validateMessageQuery(from, to *time.Time, limit int, payload domain.PayloadMode) (int, error) {
+	if from != nil && to != nil && !to.After(*from) {
+		return 0, fmt.Errorf("%w: to must be after from", ErrInvalidMessageQuery)
+	}
+	switch payload {
+	case "", domain.PayloadModeFull, domain.PayloadModeRedacted, domain.PayloadModeNone:
+	default:
+		return 0, fmt.Errorf("%w: payload must be full, redacted or none", ErrInvalidMessageQuery)
+	}
+
+	if limit <= 0 {
+		return defaultMessageQueryLimit, nil
+	}
+	if limit > maxMessageQueryLimit {
+		return maxMessageQueryLimit, nil
+	}
+	return limit, nil
+}
+
+// encodeMessageCursor arma el cursor opaco con la fecha y el ID del último mensaje de la página
+func encodeMessageCursor(at time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+// decodeMessageCursor obtiene la fecha y el ID del último mensaje leído a partir del cursor
+func decodeMessageCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: malformed cursor", ErrInvalidMessageQuery)
+	}
+	at, id, found := strings.Cut(string(raw), "|")
+	if !found || id == "" {
+		return time.Time{}, "", fmt.Errorf("%w: malformed cursor", ErrInvalidMessageQuery)
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: malformed cursor", ErrInvalidMessageQuery)
+	}
+	return parsed, id, nil
+}
+
+// applyPayloadMode retorna el contenido completo, oculto o vacío según el modo de la consulta
+func applyPayloadMode(payload json.RawMessage, mode domain.PayloadMode) json.RawMessage {
+	switch mode {
+	case domain.PayloadModeNone:
+		return nil
+	case domain.PayloadModeRedacted:
+		return redactPayload(payload)
+	default:
+		return payload
+	}
+}
+
+// redactPayload conserva la estructura del JSON y los campos de redactionSafeKeys y reemplaza los
+// demás textos y números; un contenido que no es JSON se oculta completo
+func redactPayload(payload json.RawMessage) json.RawMessage {
+	if len(payload) == 0 {
+		return payload
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		redacted, _ := json.Marshal(redactedValue)
+		return redacted
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		redacted, _ = json.Marshal(redactedValue)
 	}
+	return redacted
+}
 
-	return messages, nil
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			switch field.(type) {
+			case map[string]interface{}, []interface{}:
+			default:
+				if redactionSafeKeys[key] {
+					continue
+				}
+			}
+			v[key] = redactValue(field)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+		return v
+	case string, float64:
+		return redactedValue
+	default:
+		return v
+	}
 }
 
 // GetChatHistory obtiene el historial de conversación con un usuario específico
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOutboundLogRepository retorna el log en el orden guardado, que los tests arman del más reciente al más antiguo
+type memoryOutboundLogRepository struct {
+	domain.OutboundMessageLogRepository
+	logs    []*domain.OutboundMessageLog
+	filters []domain.OutboundMessageFilter
+}
+
+func (r *memoryOutboundLogRepository) List(ctx context.Context, filter domain.OutboundMessageFilter) ([]*domain.OutboundMessageLog, error) {
+	r.filters = append(r.filters, filter)
+
+	var result []*domain.OutboundMessageLog
+	for _, log := range r.logs {
+		if filter.Recipient != "" && log.Recipient != filter.Recipient {
+			continue
+		}
+		if filter.BeforeTimestamp != nil && !log.Timestamp.Before(*filter.BeforeTimestamp) {
+			continue
+		}
+		copied := *log
+		result = append(result, &copied)
+		if len(result) == filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func TestGetInboundMessagesPaginatesNewestFirst(t *testing.T) {
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &memoryInboundRepository{}
+	for i := 0; i < 5; i++ {
+		sender := "42"
+		if i == 3 {
+			sender = "99"
+		}
+		repo.messages = append(repo.messages, &domain.InboundMessage{
+			ID:         fmt.Sprintf("inbound-%d", i),
+			Platform:   domain.PlatformTelegram,
+			TenantID:   "tenant-1",
+			Payload:    telegramTextPayload(i, 100+i),
+			ReceivedAt: receivedAt.Add(time.Duration(i) * time.Minute),
+			Results:    []domain.InboundResult{{EventType: domain.EventTypeMessage, Sender: sender}},
+		})
+	}
+	service := NewQueryService(nil, repo, nil, logger.NewLogger("error"))
+
+	query := domain.InboundMessageQuery{TenantID: "tenant-1", Sender: "42", Limit: 2}
+	page, err := service.GetInboundMessages(context.Background(), query)
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 2)
+	assert.Equal(t, "inbound-4", page.Messages[0].ID)
+	assert.Equal(t, "inbound-2", page.Messages[1].ID)
+	require.NotEmpty(t, page.NextCursor)
+
+	query.Cursor = page.NextCursor
+	page, err = service.GetInboundMessages(context.Background(), query)
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 2)
+	assert.Equal(t, "inbound-1", page.Messages[0].ID)
+	assert.Equal(t, "inbound-0", page.Messages[1].ID)
+
+	// La página siguiente está vacía y ya no tiene cursor
+	query.Cursor = page.NextCursor
+	page, err = service.GetInboundMessages(context.Background(), query)
+	require.NoError(t, err)
+	assert.Empty(t, page.Messages)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestGetOutboundMessagesAppliesPayloadMode(t *testing.T) {
+	timestamp := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &memoryOutboundLogRepository{logs: []*domain.OutboundMessageLog{
+		{
+			ID:        "outbound-2",
+			Recipient: "573001234567",
+			Content:   json.RawMessage(`{"type":"text","text":"hola Ana"}`),
+			Response:  json.RawMessage(`{"messaging_product":"whatsapp","contacts":[{"input":"573001234567","wa_id":"573001234567"}]}`),
+			Status:    domain.MessageStatusSent,
+			Timestamp: timestamp.Add(time.Minute),
+		},
+		{ID: "outbound-1", Recipient: "573001234567", Content: json.RawMessage(`{"type":"text","text":"hola"}`), Timestamp: timestamp},
+	}}
+	service := NewQueryService(nil, nil, repo, logger.NewLogger("error"))
+
+	page, err := service.GetOutboundMessages(context.Background(), domain.OutboundMessageQuery{Recipient: "573001234567", Limit: 1, Payload: domain.PayloadModeRedacted})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.JSONEq(t, `{"type":"text","text":"[REDACTED]"}`, string(page.Messages[0].Content))
+	assert.JSONEq(t, `{"messaging_product":"whatsapp","contacts":[{"input":"[REDACTED]","wa_id":"[REDACTED]"}]}`, string(page.Messages[0].Response))
+
+	page, err = service.GetOutboundMessages(context.Background(), domain.OutboundMessageQuery{Cursor: page.NextCursor, Payload: domain.PayloadModeNone})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.Equal(t, "outbound-1", page.Messages[0].ID)
+	assert.Nil(t, page.Messages[0].Content)
+
+	// El cursor continúa desde el último mensaje y el límite toma el valor por defecto
+	last := repo.filters[len(repo.filters)-1]
+	assert.Equal(t, "outbound-2", last.BeforeID)
+	assert.Equal(t, timestamp.Add(time.Minute), *last.BeforeTimestamp)
+	assert.Equal(t, defaultMessageQueryLimit, last.Limit)
+}
+
+func TestMessageQueryValidation(t *testing.T) {
+	service := NewQueryService(nil, &memoryInboundRepository{}, nil, logger.NewLogger("error"))
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+	_, err := service.GetInboundMessages(context.Background(), domain.InboundMessageQuery{From: &from, To: &to})
+	assert.ErrorIs(t, err, ErrInvalidMessageQuery)
+
+	_, err = service.GetInboundMessages(context.Background(), domain.InboundMessageQuery{Payload: "partial"})
+	assert.ErrorIs(t, err, ErrInvalidMessageQuery)
+
+	_, err = service.GetInboundMessages(context.Background(), domain.InboundMessageQuery{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidMessageQuery)
+
+	at, id, err := decodeMessageCursor(encodeMessageCursor(from.Add(123*time.Nanosecond), "inbound-1"))
+	require.NoError(t, err)
+	assert.Equal(t, from.Add(123*time.Nanosecond), at)
+	assert.Equal(t, "inbound-1", id)
+}
+
+func TestRedactPayloadKeepsStructureAndTechnicalFields(t *testing.T) {
+	redacted := redactPayload(telegramTextPayload(7, 70))
+	assert.JSONEq(t, `{"update_id":7,"message":{"message_id":70,"from":{"id":"[REDACTED]","first_name":"[REDACTED]"},"chat":{"id":"[REDACTED]","type":"private"},"date":1767225600,"text":"[REDACTED]"}}`, string(redacted))
+
+	assert.JSONEq(t, `"[REDACTED]"`, string(redactPayload(json.RawMessage(`not json`))))
+	assert.Nil(t, redactPayload(nil))
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/pkg/logger"
+	"it-integration-service/pkg/secrets"
+	"it-integration-service/pkg/vault"
+)
+
+// NewSecretProvider arma el proveedor de secretos configurado, con las variables de entorno
+// como respaldo y un cache con TTL. Con el origen vault renueva el token hasta que se cancele ctx.
+func NewSecretProvider(ctx context.Context, cfg config.SecretsConfig, vaultClient vault.Client, logger logger.Logger) (secrets.Provider, error) {
+	var chain secrets.Chain
+
+	switch cfg.Source {
+	case "", "env":
+	case "file":
+		chain = append(chain, secrets.NewFileProvider(cfg.FileDir))
+	case "vault":
+		if vaultClient == nil {
+			return nil, fmt.Errorf("vault client is required for vault secrets")
+		}
+		vaultProvider := secrets.NewVaultProvider(vaultClient, cfg.VaultMount, cfg.VaultPath, func(err error) bool {
+			return errors.Is(err, vault.ErrSecretNotFound)
+		})
+		vaultProvider.StartTokenRenewal(ctx, logger)
+		chain = append(chain, vaultProvider)
+	default:
+		return nil, fmt.Errorf("unknown secrets source %q", cfg.Source)
+	}
+	chain = append(chain, secrets.NewEnvProvider())
+
+	logger.Info("Secret provider configured", map[string]interface{}{
+		"source":    cfg.Source,
+		"cache_ttl": cfg.CacheTTL,
+	})
+
+	return secrets.NewCachedProvider(chain, cfg.CacheTTL), nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"it-integration-service/internal/domain"
+)
+
+var (
+	// ErrUnsupportedPlatform indica que la plataforma del canal no admite mensajes salientes
+	ErrUnsupportedPlatform = errors.New("platform does not support outbound messages")
+	// ErrInvalidMessageContent indica que el contenido no puede enviarse por la plataforma
+	ErrInvalidMessageContent = errors.New("invalid message content")
+	// ErrMessageSendFailed indica que la plataforma rechazó el mensaje o no respondió
+	ErrMessageSendFailed = errors.New("message send failed")
+)
+
+// Sender envía un mensaje saliente a través de una plataforma
+type Sender interface {
+	Platform() domain.Platform
+	// Send envía el contenido al destinatario usando el token ya desencriptado del canal
+	Send(ctx context.Context, channel *domain.ChannelIntegration, token, recipient string, content *domain.MessageContent) (*SendResult, error)
+}
+
+// SendResult es la respuesta de la plataforma a un envío
+type SendResult struct {
+	PlatformMessageID string
+	Response          json.RawMessage
+}
+
+// sendError envuelve un error de la plataforma conservando su respuesta para el log de salida
+type sendError struct {
+	response json.RawMessage
+	err      error
+}
+
+func (e *sendError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sendError) Unwrap() []error {
+	return []error{ErrMessageSendFailed, e.err}
+}
+
+func newSendError(response json.RawMessage, format string, args ...interface{}) error {
+	return &sendError{response: response, err: fmt.Errorf(format, args...)}
+}
+
+// invalidContent construye un error de validación de contenido con el detalle del problema
+func invalidContent(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidMessageContent, fmt.Sprintf(format, args...))
+}
+
+// validateContent verifica los campos mínimos del contenido antes de elegir el formato de la plataforma
+func validateContent(content *domain.MessageContent) error {
+	switch content.Type {
+	case domain.ContentTypeText:
+		if content.Text == "" {
+			return invalidContent("text is required for text messages")
+		}
+	case domain.ContentTypeImage, domain.ContentTypeVideo, domain.ContentTypeAudio, domain.ContentTypeDocument:
+		if content.Media == nil || (content.Media.URL == "" && content.Media.ID == "") {
+			return invalidContent("media url or id is required for %s messages", content.Type)
+		}
+	case domain.ContentTypeTemplate:
+		if content.Template == nil || content.Template.Name == "" || content.Template.Language == "" {
+			return invalidContent("template name and language are required for template messages")
+		}
+	default:
+		return invalidContent("content type %q is not supported for sending", content.Type)
+	}
+	return nil
+}
+
+// mediaCaption obtiene el texto que acompaña a un archivo
+func mediaCaption(content *domain.MessageContent) string {
+	if content.Media != nil && content.Media.Caption != "" {
+		return content.Media.Caption
+	}
+	return content.Text
+}
+
+// channelConfigValue lee un valor de texto de la configuración del canal
+func channelConfigValue(channel *domain.ChannelIntegration, key string) string {
+	var config map[string]interface{}
+	if err := json.Unmarshal(channel.Config, &config); err != nil {
+		return ""
+	}
+	value, _ := config[key].(string)
+	return value
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+)
+
+// metaGraphURL es la versión de la Graph API usada por los servicios de configuración
+const metaGraphURL = "https://graph.facebook.com/v18.0"
+
+// metaGraphPost envía un POST a la Graph API y retorna el cuerpo de la respuesta
+func metaGraphPost(ctx context.Context, client *http.Client, endpoint, accessToken string, payload interface{}) (json.RawMessage, error) {
+	return metaGraphRequest(ctx, client, http.MethodPost, endpoint, accessToken, payload)
+}
+
+// metaGraphRequest llama a la Graph API; payload nil envía la solicitud sin cuerpo
+func metaGraphRequest(ctx context.Context, client *http.Client, method, endpoint, accessToken string, payload interface{}) (json.RawMessage, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		reqBody = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, newSendError(nil, "failed to call meta API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newSendError(nil, "failed to read response: %w", err)
+	}
+
+	var apiResp MetaAPIResponse
+	if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Error != nil {
+		return nil, newSendError(body, "meta API error: %s", apiResp.Error.Message)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newSendError(body, "meta API returned status %d", resp.StatusCode)
+	}
+
+	return body, nil
+}
+
+// WhatsAppSender envía mensajes mediante la WhatsApp Cloud API
+type WhatsAppSender struct {
+	baseURL string
+	client  *http.Client
+	logger  logger.Logger
+}
+
+// NewWhatsAppSender crea una nueva instancia del sender de WhatsApp
+func NewWhatsAppSender(logger logger.Logger) *WhatsAppSender {
+	return &WhatsAppSender{
+		baseURL: metaGraphURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		logger:  logger,
+	}
+}
+
+func (s *WhatsAppSender) Platform() domain.Platform {
+	return domain.PlatformWhatsApp
+}
+
+func (s *WhatsAppSender) Send(ctx context.Context, channel *domain.ChannelIntegration, token, recipient string, content *domain.MessageContent) (*SendResult, error) {
+	phoneNumberID := channelConfigValue(channel, "phone_number_id")
+	if phoneNumberID == "" {
+		return nil, fmt.Errorf("whatsapp channel %s has no phone_number_id", channel.ID)
+	}
+
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                recipient,
+		"type":              content.Type,
+	}
+
+	switch content.Type {
+	case domain.ContentTypeText:
+		payload["text"] = map[string]interface{}{"body": content.Text}
+	case domain.ContentTypeTemplate:
+		payload["template"] = whatsappTemplatePayload(content.Template)
+	default:
+		// La Cloud API acepta un media_id subido previamente o un link público
+		media := map[string]interface{}{}
+		if content.Media.ID != "" {
+			media["id"] = content.Media.ID
+		} else {
+			media["link"] = content.Media.URL
+		}
+		if caption := mediaCaption(content); caption != "" && content.Type != domain.ContentTypeAudio {
+			media["caption"] = caption
+		}
+		if content.Type == domain.ContentTypeDocument && content.Media.Filename != "" {
+			media["filename"] = content.Media.Filename
+		}
+		payload[content.Type] = media
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/messages", s.baseURL, phoneNumberID)
+	body, err := metaGraphPost(ctx, s.client, endpoint, token, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var sendResp struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &sendResp); err != nil {
+		return nil, newSendError(body, "failed to decode response: %w", err)
+	}
+
+	result := &SendResult{Response: body}
+	if len(sendResp.Messages) > 0 {
+		result.PlatformMessageID = sendResp.Messages[0].ID
+	}
+
+	s.logger.Info("WhatsApp message sent successfully", map[string]interface{}{
+		"channel_id": channel.ID,
+		"message_id": result.PlatformMessageID,
+		"type":       content.Type,
+	})
+
+	return result, nil
+}
+
+// MetaMessagingSender envía mensajes mediante la Send API de Messenger, que Instagram comparte
+type MetaMessagingSender struct {
+	platform domain.Platform
+	baseURL  string
+	client   *http.Client
+	logger   logger.Logger
+}
+
+// NewMessengerSender crea una nueva instancia del sender de Messenger
+func NewMessengerSender(logger logger.Logger) *MetaMessagingSender {
+	return newMetaMessagingSender(domain.PlatformMessenger, logger)
+}
+
+// NewInstagramSender crea una nueva instancia del sender de Instagram
+func NewInstagramSender(logger logger.Logger) *MetaMessagingSender {
+	return newMetaMessagingSender(domain.PlatformInstagram, logger)
+}
+
+func newMetaMessagingSender(platform domain.Platform, logger logger.Logger) *MetaMessagingSender {
+	return &MetaMessagingSender{
+		platform: platform,
+		baseURL:  metaGraphURL,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger,
+	}
+}
+
+func (s *MetaMessagingSender) Platform() domain.Platform {
+	return s.platform
+}
+
+func (s *MetaMessagingSender) Send(ctx context.Context, channel *domain.ChannelIntegration, token, recipient string, content *domain.MessageContent) (*SendResult, error) {
+	var message map[string]interface{}
+
+	if content.Type == domain.ContentTypeText {
+		message = map[string]interface{}{"text": content.Text}
+	} else {
+		attachmentType, err := s.attachmentType(content.Type)
+		if err != nil {
+			return nil, err
+		}
+		if content.Media.URL == "" {
+			return nil, invalidContent("%s attachments require a media url", s.platform)
+		}
+		message = map[string]interface{}{
+			"attachment": map[string]interface{}{
+				"type": attachmentType,
+				"payload": map[string]interface{}{
+					"url":         content.Media.URL,
+					"is_reusable": true,
+				},
+			},
+		}
+	}
+
+	result, err := s.sendMessage(ctx, token, recipient, message)
+	if err != nil {
+		return nil, err
+	}
+
+	// Los adjuntos no admiten texto; la leyenda se envía como un segundo mensaje
+	if content.Type != domain.ContentTypeText {
+		if caption := mediaCaption(content); caption != "" {
+			if _, err := s.sendMessage(ctx, token, recipient, map[string]interface{}{"text": caption}); err != nil {
+				s.logger.Warn("Failed to send attachment caption", map[string]interface{}{
+					"channel_id": channel.ID,
+					"platform":   s.platform,
+					"error":      err.Error(),
+				})
+			}
+		}
+	}
+
+	s.logger.Info("Meta message sent successfully", map[string]interface{}{
+		"channel_id": channel.ID,
+		"platform":   s.platform,
+		"message_id": result.PlatformMessageID,
+		"type":       content.Type,
+	})
+
+	return result, nil
+}
+
+func (s *MetaMessagingSender) sendMessage(ctx context.Context, token, recipient string, message map[string]interface{}) (*SendResult, error) {
+	payload := map[string]interface{}{
+		"recipient":      map[string]string{"id": recipient},
+		"messaging_type": "RESPONSE",
+		"message":        message,
+	}
+
+	endpoint := s.baseURL + "/me/messages"
+	body, err := metaGraphPost(ctx, s.client, endpoint, token, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var sendResp struct {
+		MessageID string `json:"message_id"`
+	}
+	if err := json.Unmarshal(body, &sendResp); err != nil {
+		return nil, newSendError(body, "failed to decode response: %w", err)
+	}
+
+	return &SendResult{PlatformMessageID: sendResp.MessageID, Response: body}, nil
+}
+
+// attachmentType traduce el tipo de contenido al tipo de adjunto de la Send API
+func (s *MetaMessagingSender) attachmentType(contentType string) (string, error) {
+	switch contentType {
+	case domain.ContentTypeImage, domain.ContentTypeVideo, domain.ContentTypeAudio:
+		return contentType, nil
+	case domain.ContentTypeDocument:
+		// Instagram no admite archivos genéricos
+		if s.platform == domain.PlatformInstagram {
+			return "", invalidContent("instagram does not support document attachments")
+		}
+		return "file", nil
+	default:
+		return "", invalidContent("content type %q is not supported by %s", contentType, s.platform)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+)
+
+// TelegramSender envía mensajes mediante la Bot API de Telegram
+type TelegramSender struct {
+	baseURL string
+	client  *http.Client
+	logger  logger.Logger
+}
+
+// NewTelegramSender crea una nueva instancia del sender de Telegram
+func NewTelegramSender(logger logger.Logger) *TelegramSender {
+	return &TelegramSender{
+		baseURL: "https://api.telegram.org",
+		client:  &http.Client{Timeout: 10 * time.Second},
+		logger:  logger,
+	}
+}
+
+func (s *TelegramSender) Platform() domain.Platform {
+	return domain.PlatformTelegram
+}
+
+// telegramMediaMethods asocia cada tipo de archivo con su método y campo en la Bot API
+var telegramMediaMethods = map[string][2]string{
+	domain.ContentTypeImage:    {"sendPhoto", "photo"},
+	domain.ContentTypeVideo:    {"sendVideo", "video"},
+	domain.ContentTypeAudio:    {"sendAudio", "audio"},
+	domain.ContentTypeDocument: {"sendDocument", "document"},
+}
+
+func (s *TelegramSender) Send(ctx context.Context, channel *domain.ChannelIntegration, token, recipient string, content *domain.MessageContent) (*SendResult, error) {
+	method := "sendMessage"
+	payload := map[string]interface{}{"chat_id": recipient}
+
+	if content.Type == domain.ContentTypeText {
+		payload["text"] = content.Text
+	} else {
+		mediaMethod, ok := telegramMediaMethods[content.Type]
+		if !ok {
+			return nil, invalidContent("content type %q is not supported by telegram", content.Type)
+		}
+		method = mediaMethod[0]
+		// Telegram acepta un file_id ya subido o una URL pública
+		if content.Media.ID != "" {
+			payload[mediaMethod[1]] = content.Media.ID
+		} else {
+			payload[mediaMethod[1]] = content.Media.URL
+		}
+		if caption := mediaCaption(content); caption != "" {
+			payload["caption"] = caption
+		}
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/%s", s.baseURL, token, method)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// La URL contiene el token del bot, no se incluye en el error
+		return nil, newSendError(nil, "failed to send message: %s request failed", method)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newSendError(nil, "failed to read response: %w", err)
+	}
+
+	var apiResp TelegramAPIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, newSendError(body, "failed to decode response: %w", err)
+	}
+	if !apiResp.OK {
+		return nil, newSendError(body, "telegram API error: %s", apiResp.Description)
+	}
+
+	var sentMessage struct {
+		MessageID int64 `json:"message_id"`
+	}
+	if err := json.Unmarshal(apiResp.Result, &sentMessage); err != nil {
+		return nil, newSendError(body, "failed to decode sent message: %w", err)
+	}
+
+	result := &SendResult{
+		PlatformMessageID: strconv.FormatInt(sentMessage.MessageID, 10),
+		Response:          body,
+	}
+
+	s.logger.Info("Telegram message sent successfully", map[string]interface{}{
+		"channel_id": channel.ID,
+		"message_id": result.PlatformMessageID,
+		"method":     method,
+	})
+
+	return result, nil
+}
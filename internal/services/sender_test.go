@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingServer responde con body y guarda la ruta y el JSON recibidos
+func recordingServer(t *testing.T, status int, body string) (*httptest.Server, *string, *map[string]interface{}) {
+	var path string
+	var received map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &received))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &path, &received
+}
+
+func TestWhatsAppSenderSendsMedia(t *testing.T) {
+	server, path, received := recordingServer(t, http.StatusOK,
+		`{"messaging_product":"whatsapp","contacts":[{"input":"5491155550000","wa_id":"5491155550000"}],"messages":[{"id":"wamid.HBgLNTQ5MTE1NTU1MDAwMBUCABEYEjQ1"}]}`)
+
+	sender := NewWhatsAppSender(logger.NewLogger("error"))
+	sender.baseURL = server.URL
+
+	channel := &domain.ChannelIntegration{ID: "channel-1", Config: json.RawMessage(`{"phone_number_id":"106540352242922"}`)}
+	content := &domain.MessageContent{
+		Type:  domain.ContentTypeDocument,
+		Media: &domain.MediaContent{URL: "https://example.com/invoice.pdf", Caption: "Factura", Filename: "invoice.pdf"},
+	}
+
+	result, err := sender.Send(context.Background(), channel, "token", "5491155550000", content)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/106540352242922/messages", *path)
+	assert.Equal(t, "wamid.HBgLNTQ5MTE1NTU1MDAwMBUCABEYEjQ1", result.PlatformMessageID)
+	assert.Equal(t, "document", (*received)["type"])
+	assert.Equal(t, map[string]interface{}{
+		"link":     "https://example.com/invoice.pdf",
+		"caption":  "Factura",
+		"filename": "invoice.pdf",
+	}, (*received)["document"])
+}
+
+func TestWhatsAppSenderReportsPlatformError(t *testing.T) {
+	server, _, _ := recordingServer(t, http.StatusBadRequest,
+		`{"error":{"message":"(#131030) Recipient phone number not in allowed list","type":"OAuthException","code":131030}}`)
+
+	sender := NewWhatsAppSender(logger.NewLogger("error"))
+	sender.baseURL = server.URL
+
+	channel := &domain.ChannelIntegration{ID: "channel-1", Config: json.RawMessage(`{"phone_number_id":"106540352242922"}`)}
+	_, err := sender.Send(context.Background(), channel, "token", "5491155550000", &domain.MessageContent{Type: domain.ContentTypeText, Text: "hola"})
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrMessageSendFailed))
+	assert.JSONEq(t,
+		`{"error":{"message":"(#131030) Recipient phone number not in allowed list","type":"OAuthException","code":131030}}`,
+		string(failedSendResponse(err)))
+}
+
+func TestTelegramSenderSendsPhoto(t *testing.T) {
+	server, path, received := recordingServer(t, http.StatusOK,
+		`{"ok":true,"result":{"message_id":4521,"chat":{"id":99},"date":1749416800}}`)
+
+	sender := NewTelegramSender(logger.NewLogger("error"))
+	sender.baseURL = server.URL
+
+	content := &domain.MessageContent{
+		Type:  domain.ContentTypeImage,
+		Text:  "Tu pedido",
+		Media: &domain.MediaContent{URL: "https://example.com/order.jpg"},
+	}
+
+	result, err := sender.Send(context.Background(), &domain.ChannelIntegration{ID: "channel-2"}, "123:ABC", "99", content)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/bot123:ABC/sendPhoto", *path)
+	assert.Equal(t, "4521", result.PlatformMessageID)
+	assert.Equal(t, "https://example.com/order.jpg", (*received)["photo"])
+	assert.Equal(t, "Tu pedido", (*received)["caption"])
+}
+
+func TestInstagramSenderRejectsDocuments(t *testing.T) {
+	sender := NewInstagramSender(logger.NewLogger("error"))
+	content := &domain.MessageContent{
+		Type:  domain.ContentTypeDocument,
+		Media: &domain.MediaContent{URL: "https://example.com/invoice.pdf"},
+	}
+
+	_, err := sender.Send(context.Background(), &domain.ChannelIntegration{ID: "channel-3"}, "token", "1146720579373962", content)
+	assert.True(t, errors.Is(err, ErrInvalidMessageContent))
+}
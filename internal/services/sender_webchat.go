@@ -0,0 +1,93 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// WebchatSender entrega los mensajes del agente al backend del widget mediante su webhook de notificaciones
+type WebchatSender struct {
+	client *http.Client
+	logger logger.Logger
+}
+
+// NewWebchatSender crea una nueva instancia del sender de webchat
+func NewWebchatSender(logger logger.Logger) *WebchatSender {
+	return &WebchatSender{
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+	}
+}
+
+func (s *WebchatSender) Platform() domain.Platform {
+	return domain.PlatformWebchat
+}
+
+func (s *WebchatSender) Send(ctx context.Context, channel *domain.ChannelIntegration, token, recipient string, content *domain.MessageContent) (*SendResult, error) {
+	var config struct {
+		WebchatConfig *WebchatConfig `json:"webchat_config"`
+	}
+	if err := json.Unmarshal(channel.Config, &config); err != nil || config.WebchatConfig == nil {
+		return nil, fmt.Errorf("webchat channel %s has no webchat_config", channel.ID)
+	}
+
+	webhookURL := config.WebchatConfig.Settings.Notifications.WebhookURL
+	if webhookURL == "" {
+		return nil, fmt.Errorf("webchat channel %s has no notifications webhook_url", channel.ID)
+	}
+
+	message := map[string]interface{}{
+		"id":         uuid.New().String(),
+		"webchat_id": config.WebchatConfig.ID,
+		"session_id": recipient,
+		"type":       "agent",
+		"content":    content,
+		"timestamp":  time.Now().Unix(),
+	}
+
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "it-integration-service/1.0")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, newSendError(nil, "failed to deliver webchat message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newSendError(nil, "webchat webhook returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	result := &SendResult{PlatformMessageID: message["id"].(string)}
+	if json.Valid(body) {
+		result.Response = body
+	}
+
+	s.logger.Info("Webchat message sent successfully", map[string]interface{}{
+		"channel_id": channel.ID,
+		"session_id": recipient,
+		"message_id": result.PlatformMessageID,
+	})
+
+	return result, nil
+}
@@ -6,10 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"it-integration-service/internal/domain"
 	"it-integration-service/pkg/logger"
+
+	"github.com/google/uuid"
 )
 
 // TelegramSetupService maneja la configuración específica de Telegram
@@ -255,6 +258,11 @@ func (s *TelegramSetupService) CreateTelegramIntegration(ctx context.Context, bo
 		"bot_name":     botInfo.FirstName,
 	})
 
+	// Los updates de Telegram no identifican al bot, así que cada canal recibe
+	// una URL propia con una clave que permite resolverlo al llegar el webhook
+	webhookKey := uuid.New().String()
+	webhookURL = strings.TrimSuffix(webhookURL, "/") + "/" + webhookKey
+
 	// Configurar webhook
 	if err := s.SetWebhook(ctx, botToken, webhookURL); err != nil {
 		return nil, fmt.Errorf("failed to set webhook: %w", err)
@@ -267,6 +275,7 @@ func (s *TelegramSetupService) CreateTelegramIntegration(ctx context.Context, bo
 		"bot_username": botInfo.Username,
 		"bot_name":     botInfo.FirstName,
 		"webhook_url":  webhookURL,
+		"webhook_key":  webhookKey,
 	}
 
 	configJSON, err := json.Marshal(config)
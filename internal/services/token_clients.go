@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/secrets"
+)
+
+// ErrTokenRefreshUnsupported indica que la plataforma no permite renovar el token sin intervención del usuario
+var ErrTokenRefreshUnsupported = errors.New("token refresh not supported for platform")
+
+// TokenInfo es lo que la plataforma informa sobre un token
+type TokenInfo struct {
+	Valid     bool
+	ExpiresAt *time.Time // nil si el token no vence
+	IssuedAt  *time.Time
+}
+
+// RefreshedToken es un token nuevo emitido por la plataforma
+type RefreshedToken struct {
+	AccessToken string
+	ExpiresAt   *time.Time
+	IssuedAt    time.Time
+}
+
+// TokenClient valida y renueva los tokens de las plataformas que atiende
+type TokenClient interface {
+	Platforms() []domain.Platform
+	// Inspect consulta a la plataforma por el token ya desencriptado
+	Inspect(ctx context.Context, channel *domain.ChannelIntegration, token string) (*TokenInfo, error)
+	// Refresh emite un token nuevo para el canal o retorna ErrTokenRefreshUnsupported
+	Refresh(ctx context.Context, channel *domain.ChannelIntegration) (*RefreshedToken, error)
+}
+
+// DefaultTokenClients retorna los clientes de tokens de las plataformas soportadas
+func DefaultTokenClients(secretProvider secrets.Provider, googleConfig *config.GoogleCalendarConfig) []TokenClient {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return []TokenClient{
+		&MetaTokenClient{baseURL: metaGraphURL, client: client, secrets: secretProvider},
+		&TelegramTokenClient{baseURL: "https://api.telegram.org", client: client},
+		&MailchimpTokenClient{client: client},
+		&GoogleTokenClient{infoURL: "https://oauth2.googleapis.com/tokeninfo", config: googleConfig, client: client},
+	}
+}
+
+// getJSON hace un GET y decodifica la respuesta en out; los códigos 4xx se reportan con el cuerpo
+func getJSON(ctx context.Context, client *http.Client, endpoint string, header http.Header, out interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 500 {
+		return resp.StatusCode, fmt.Errorf("platform returned status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// unixTime convierte un timestamp de la plataforma; 0 significa sin valor
+func unixTime(seconds int64) *time.Time {
+	if seconds <= 0 {
+		return nil
+	}
+	t := time.Unix(seconds, 0).UTC()
+	return &t
+}
+
+// MetaTokenClient valida tokens con debug_token y los canjea por tokens de larga duración.
+// Usa el app_id y app_secret del canal o, si no los tiene, meta_app_id y meta_app_secret.
+type MetaTokenClient struct {
+	baseURL string
+	client  *http.Client
+	secrets secrets.Provider
+}
+
+func (c *MetaTokenClient) Platforms() []domain.Platform {
+	return []domain.Platform{domain.PlatformWhatsApp, domain.PlatformMessenger, domain.PlatformInstagram}
+}
+
+// appCredentials resuelve la app de Meta del canal
+func (c *MetaTokenClient) appCredentials(ctx context.Context, channel *domain.ChannelIntegration) (string, string, error) {
+	appID := channelConfigValue(channel, "app_id")
+	appSecret := channelConfigValue(channel, "app_secret")
+	if appID != "" && appSecret != "" {
+		return appID, appSecret, nil
+	}
+
+	appID, err := secrets.Resolve(ctx, c.secrets, "meta_app_id", appID)
+	if err != nil {
+		return "", "", err
+	}
+	appSecret, err = secrets.Resolve(ctx, c.secrets, "meta_app_secret", appSecret)
+	if err != nil {
+		return "", "", err
+	}
+	if appID == "" || appSecret == "" {
+		return "", "", fmt.Errorf("meta app credentials not configured for channel %s", channel.ID)
+	}
+	return appID, appSecret, nil
+}
+
+func (c *MetaTokenClient) Inspect(ctx context.Context, channel *domain.ChannelIntegration, token string) (*TokenInfo, error) {
+	appID, appSecret, err := c.appCredentials(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{
+		"input_token":  {token},
+		"access_token": {appID + "|" + appSecret},
+	}
+
+	var resp struct {
+		Data struct {
+			IsValid   bool  `json:"is_valid"`
+			ExpiresAt int64 `json:"expires_at"`
+			IssuedAt  int64 `json:"issued_at"`
+		} `json:"data"`
+		Error *MetaAPIError `json:"error"`
+	}
+	if _, err := getJSON(ctx, c.client, c.baseURL+"/debug_token?"+query.Encode(), nil, &resp); err != nil {
+		return nil, fmt.Errorf("meta debug_token failed: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("meta debug_token error: %s", resp.Error.Message)
+	}
+
+	// expires_at en 0 indica un token que no vence, como los de página obtenidos con un token de larga duración
+	return &TokenInfo{
+		Valid:     resp.Data.IsValid,
+		ExpiresAt: unixTime(resp.Data.ExpiresAt),
+		IssuedAt:  unixTime(resp.Data.IssuedAt),
+	}, nil
+}
+
+func (c *MetaTokenClient) Refresh(ctx context.Context, channel *domain.ChannelIntegration) (*RefreshedToken, error) {
+	appID, appSecret, err := c.appCredentials(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{
+		"grant_type":        {"fb_exchange_token"},
+		"client_id":         {appID},
+		"client_secret":     {appSecret},
+		"fb_exchange_token": {channel.AccessToken},
+	}
+
+	var resp struct {
+		AccessToken string        `json:"access_token"`
+		ExpiresIn   int64         `json:"expires_in"`
+		Error       *MetaAPIError `json:"error"`
+	}
+	if _, err := getJSON(ctx, c.client, c.baseURL+"/oauth/access_token?"+query.Encode(), nil, &resp); err != nil {
+		return nil, fmt.Errorf("meta token exchange failed: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("meta token exchange error: %s", resp.Error.Message)
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("meta token exchange returned no token")
+	}
+
+	now := time.Now().UTC()
+	refreshed := &RefreshedToken{AccessToken: resp.AccessToken, IssuedAt: now}
+	if resp.ExpiresIn > 0 {
+		expiresAt := now.Add(time.Duration(resp.ExpiresIn) * time.Second)
+		refreshed.ExpiresAt = &expiresAt
+	}
+	return refreshed, nil
+}
+
+// TelegramTokenClient valida el token del bot con getMe; los tokens de bot no vencen
+type TelegramTokenClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (c *TelegramTokenClient) Platforms() []domain.Platform {
+	return []domain.Platform{domain.PlatformTelegram}
+}
+
+func (c *TelegramTokenClient) Inspect(ctx context.Context, channel *domain.ChannelIntegration, token string) (*TokenInfo, error) {
+	var resp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	status, err := getJSON(ctx, c.client, fmt.Sprintf("%s/bot%s/getMe", c.baseURL, token), nil, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("telegram getMe failed: %w", err)
+	}
+
+	// Telegram responde 401 (o 404 con un token mal formado) cuando el token fue revocado
+	if !resp.OK && status != http.StatusUnauthorized && status != http.StatusNotFound {
+		return nil, fmt.Errorf("telegram getMe error: %s", resp.Description)
+	}
+	return &TokenInfo{Valid: resp.OK}, nil
+}
+
+func (c *TelegramTokenClient) Refresh(ctx context.Context, channel *domain.ChannelIntegration) (*RefreshedToken, error) {
+	return nil, ErrTokenRefreshUnsupported
+}
+
+// MailchimpTokenClient valida la API key con el endpoint ping; las API keys no vencen
+type MailchimpTokenClient struct {
+	baseURL string // solo para pruebas; normalmente se deriva del data center de la key
+	client  *http.Client
+}
+
+func (c *MailchimpTokenClient) Platforms() []domain.Platform {
+	return []domain.Platform{domain.PlatformMailchimp}
+}
+
+// apiURL retorna la URL de la API para la key: el data center va al final, p. ej. "xxxx-us6"
+func (c *MailchimpTokenClient) apiURL(channel *domain.ChannelIntegration, apiKey string) (string, error) {
+	if c.baseURL != "" {
+		return c.baseURL, nil
+	}
+	if baseURL := channelConfigValue(channel, "base_url"); baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/"), nil
+	}
+
+	prefix := channelConfigValue(channel, "server_prefix")
+	if i := strings.LastIndex(apiKey, "-"); prefix == "" && i >= 0 {
+		prefix = apiKey[i+1:]
+	}
+	if prefix == "" {
+		return "", fmt.Errorf("mailchimp data center unknown for channel %s", channel.ID)
+	}
+	return fmt.Sprintf("https://%s.api.mailchimp.com", prefix), nil
+}
+
+func (c *MailchimpTokenClient) Inspect(ctx context.Context, channel *domain.ChannelIntegration, token string) (*TokenInfo, error) {
+	baseURL, err := c.apiURL(channel, token)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "apikey "+token)
+
+	var resp struct {
+		HealthStatus string `json:"health_status"`
+		Detail       string `json:"detail"`
+	}
+	status, err := getJSON(ctx, c.client, baseURL+"/3.0/ping", header, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("mailchimp ping failed: %w", err)
+	}
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return &TokenInfo{Valid: false}, nil
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("mailchimp ping returned status %d: %s", status, resp.Detail)
+	}
+	return &TokenInfo{Valid: true}, nil
+}
+
+func (c *MailchimpTokenClient) Refresh(ctx context.Context, channel *domain.ChannelIntegration) (*RefreshedToken, error) {
+	return nil, ErrTokenRefreshUnsupported
+}
+
+// GoogleTokenClient valida el access token con tokeninfo y lo renueva con el refresh_token del canal
+type GoogleTokenClient struct {
+	infoURL string
+	config  *config.GoogleCalendarConfig
+	client  *http.Client
+}
+
+func (c *GoogleTokenClient) Platforms() []domain.Platform {
+	return []domain.Platform{domain.PlatformGoogleCalendar}
+}
+
+func (c *GoogleTokenClient) Inspect(ctx context.Context, channel *domain.ChannelIntegration, token string) (*TokenInfo, error) {
+	var resp struct {
+		ExpiresIn        string `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := getJSON(ctx, c.client, c.infoURL+"?"+url.Values{"access_token": {token}}.Encode(), nil, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("google tokeninfo failed: %w", err)
+	}
+	if status == http.StatusBadRequest || resp.Error != "" {
+		return &TokenInfo{Valid: false}, nil
+	}
+
+	info := &TokenInfo{Valid: true}
+	if seconds, err := strconv.ParseInt(resp.ExpiresIn, 10, 64); err == nil && seconds > 0 {
+		expiresAt := time.Now().UTC().Add(time.Duration(seconds) * time.Second)
+		info.ExpiresAt = &expiresAt
+	}
+	return info, nil
+}
+
+func (c *GoogleTokenClient) Refresh(ctx context.Context, channel *domain.ChannelIntegration) (*RefreshedToken, error) {
+	refreshToken := channelConfigValue(channel, "refresh_token")
+	if refreshToken == "" {
+		return nil, fmt.Errorf("%w: channel %s has no refresh token", ErrTokenRefreshUnsupported, channel.ID)
+	}
+	if c.config == nil {
+		return nil, fmt.Errorf("google oauth not configured")
+	}
+
+	clientID, clientSecret, err := c.config.OAuthClientCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("google token refresh failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokenResp struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode google token response: %w", err)
+	}
+	if tokenResp.Error != "" {
+		return nil, fmt.Errorf("google token refresh error: %s: %s", tokenResp.Error, tokenResp.ErrorDescription)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("google token refresh returned no token")
+	}
+
+	now := time.Now().UTC()
+	refreshed := &RefreshedToken{AccessToken: tokenResp.AccessToken, IssuedAt: now}
+	if tokenResp.ExpiresIn > 0 {
+		expiresAt := now.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+		refreshed.ExpiresAt = &expiresAt
+	}
+	return refreshed, nil
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotificationSettingsNotFound indica que el tenant no configuró destinos de aviso
+	ErrNotificationSettingsNotFound = errors.New("notification settings not found")
+	// ErrInvalidNotificationSettings indica que algún destino de aviso no es válido
+	ErrInvalidNotificationSettings = errors.New("invalid notification settings")
+)
+
+// TokenNotifier avisa al tenant que el token de un canal vence pronto o ya venció
+type TokenNotifier interface {
+	NotifyTokenExpiry(ctx context.Context, token *TokenStatus, event domain.NotificationEventType) error
+}
+
+// TokenNotification es el contenido de un aviso de vencimiento de token
+type TokenNotification struct {
+	ID              string                       `json:"id"`
+	Event           domain.NotificationEventType `json:"event"`
+	TenantID        string                       `json:"tenant_id"`
+	ChannelID       string                       `json:"channel_id"`
+	Platform        string                       `json:"platform"`
+	ExpiresAt       *time.Time                   `json:"expires_at,omitempty"`
+	DaysUntilExpiry int                          `json:"days_until_expiry"`
+	OccurredAt      time.Time                    `json:"occurred_at"`
+}
+
+// TokenNotificationService entrega los avisos de vencimiento a los destinos de cada tenant.
+// Un mismo vencimiento se avisa una sola vez por destino; las entregas fallidas se reintentan
+// en la siguiente revisión de tokens.
+type TokenNotificationService struct {
+	repo    domain.NotificationRepository
+	senders map[domain.NotificationDestinationType]NotificationSender
+	now     func() time.Time
+	logger  logger.Logger
+}
+
+// NewTokenNotificationService crea el servicio de avisos con los senders disponibles
+func NewTokenNotificationService(repo domain.NotificationRepository, senders []NotificationSender, logger logger.Logger) *TokenNotificationService {
+	byType := make(map[domain.NotificationDestinationType]NotificationSender)
+	for _, sender := range senders {
+		byType[sender.Type()] = sender
+	}
+
+	return &TokenNotificationService{
+		repo:    repo,
+		senders: byType,
+		now:     time.Now,
+		logger:  logger,
+	}
+}
+
+// NotifyTokenExpiry avisa a los destinos del tenant del canal. Sin destinos configurados el
+// aviso queda solo en el log.
+func (s *TokenNotificationService) NotifyTokenExpiry(ctx context.Context, token *TokenStatus, event domain.NotificationEventType) error {
+	settings, err := s.repo.GetSettings(ctx, token.TenantID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get notification settings: %w", err)
+	}
+	if settings == nil || !settings.Enabled || len(settings.Destinations) == 0 {
+		s.logger.Warn("Token expiry without notification destinations", map[string]interface{}{
+			"event":             event,
+			"channel_id":        token.ChannelID,
+			"platform":          token.Platform,
+			"tenant_id":         token.TenantID,
+			"days_until_expiry": token.DaysUntilExpiry,
+		})
+		return nil
+	}
+
+	notification := &TokenNotification{
+		ID:              uuid.New().String(),
+		Event:           event,
+		TenantID:        token.TenantID,
+		ChannelID:       token.ChannelID,
+		Platform:        token.Platform,
+		DaysUntilExpiry: token.DaysUntilExpiry,
+		OccurredAt:      s.now().UTC(),
+	}
+	if !token.TokenExpiry.IsZero() {
+		expiresAt := token.TokenExpiry.UTC()
+		notification.ExpiresAt = &expiresAt
+	}
+
+	var errs []error
+	for _, destination := range settings.Destinations {
+		if err := s.deliver(ctx, destination, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver envía el aviso a un destino si no se le entregó antes y registra el resultado
+func (s *TokenNotificationService) deliver(ctx context.Context, destination domain.NotificationDestination, notification *TokenNotification) error {
+	dedupKey := notificationDedupKey(notification, destination)
+	sent, err := s.repo.WasSent(ctx, dedupKey)
+	if err != nil {
+		return err
+	}
+	if sent {
+		return nil
+	}
+
+	var sendErr error
+	if sender, ok := s.senders[destination.Type]; ok {
+		sendErr = sender.Send(ctx, destination, notification)
+	} else {
+		sendErr = fmt.Errorf("%s destinations are not available", destination.Type)
+	}
+
+	record := &domain.NotificationRecord{
+		ID:              uuid.New().String(),
+		TenantID:        notification.TenantID,
+		ChannelID:       notification.ChannelID,
+		Platform:        domain.Platform(notification.Platform),
+		EventType:       notification.Event,
+		DestinationType: destination.Type,
+		Destination:     destinationLabel(destination),
+		Status:          domain.NotificationStatusSent,
+		DedupKey:        dedupKey,
+		TokenExpiresAt:  notification.ExpiresAt,
+		CreatedAt:       s.now().UTC(),
+	}
+	if sendErr != nil {
+		record.Status = domain.NotificationStatusFailed
+		record.Error = sendErr.Error()
+	}
+	if err := s.repo.CreateRecord(ctx, record); err != nil {
+		// Sin registro el aviso se repetirá en la siguiente revisión
+		s.logger.Error("Failed to record token notification", map[string]interface{}{
+			"channel_id":  notification.ChannelID,
+			"destination": record.Destination,
+			"error":       err.Error(),
+		})
+	}
+
+	if sendErr != nil {
+		return fmt.Errorf("failed to notify %s destination %s: %w", destination.Type, record.Destination, sendErr)
+	}
+
+	s.logger.Info("Token expiry notification sent", map[string]interface{}{
+		"event":       notification.Event,
+		"channel_id":  notification.ChannelID,
+		"tenant_id":   notification.TenantID,
+		"destination": record.Destination,
+	})
+	return nil
+}
+
+// GetSettings retorna los destinos de aviso del tenant sin los secretos de los webhooks
+func (s *TokenNotificationService) GetSettings(ctx context.Context, tenantID string) (*domain.TenantNotificationSettings, error) {
+	settings, err := s.repo.GetSettings(ctx, tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotificationSettingsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return redactNotificationSettings(settings), nil
+}
+
+// UpdateSettings valida y reemplaza los destinos de aviso del tenant. Un webhook sin secreto
+// conserva el que ya tenía la misma URL.
+func (s *TokenNotificationService) UpdateSettings(ctx context.Context, settings *domain.TenantNotificationSettings) (*domain.TenantNotificationSettings, error) {
+	if settings.TenantID == "" {
+		return nil, fmt.Errorf("%w: tenant_id is required", ErrInvalidNotificationSettings)
+	}
+
+	current, err := s.repo.GetSettings(ctx, settings.TenantID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	for i := range settings.Destinations {
+		destination := &settings.Destinations[i]
+		if destination.Type == domain.NotificationDestinationWebhook && destination.Secret == "" && current != nil {
+			destination.Secret = webhookSecretFor(current, destination.URL)
+		}
+		if err := s.validateDestination(destination); err != nil {
+			return nil, fmt.Errorf("%w: destination %d: %v", ErrInvalidNotificationSettings, i, err)
+		}
+	}
+
+	now := s.now().UTC()
+	settings.CreatedAt = now
+	settings.UpdatedAt = now
+	if err := s.repo.UpsertSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	return redactNotificationSettings(settings), nil
+}
+
+// ListHistory retorna los avisos entregados o fallidos del tenant, los más recientes primero
+func (s *TokenNotificationService) ListHistory(ctx context.Context, tenantID, channelID string, limit, offset int) ([]*domain.NotificationRecord, error) {
+	return s.repo.ListRecords(ctx, tenantID, channelID, limit, offset)
+}
+
+// validateDestination verifica un destino y normaliza sus emails
+func (s *TokenNotificationService) validateDestination(destination *domain.NotificationDestination) error {
+	if _, ok := s.senders[destination.Type]; !ok {
+		return fmt.Errorf("%q destinations are not available", destination.Type)
+	}
+
+	switch destination.Type {
+	case domain.NotificationDestinationWebhook:
+		target, err := url.Parse(destination.URL)
+		if err != nil || target.Scheme != "https" || target.Host == "" {
+			return errors.New("webhook url must be an absolute https URL")
+		}
+		if destination.Secret == "" {
+			return errors.New("webhook secret is required")
+		}
+	case domain.NotificationDestinationEmail:
+		if len(destination.Emails) == 0 {
+			return errors.New("at least one email is required")
+		}
+		for i, email := range destination.Emails {
+			address, err := mail.ParseAddress(email)
+			if err != nil {
+				return fmt.Errorf("invalid email %q", email)
+			}
+			destination.Emails[i] = address.Address
+		}
+	}
+	return nil
+}
+
+// webhookSecretFor retorna el secreto guardado del webhook con la URL dada
+func webhookSecretFor(settings *domain.TenantNotificationSettings, targetURL string) string {
+	for _, destination := range settings.Destinations {
+		if destination.Type == domain.NotificationDestinationWebhook && destination.URL == targetURL {
+			return destination.Secret
+		}
+	}
+	return ""
+}
+
+// redactNotificationSettings retorna una copia sin los secretos de los webhooks
+func redactNotificationSettings(settings *domain.TenantNotificationSettings) *domain.TenantNotificationSettings {
+	redacted := *settings
+	redacted.Destinations = make([]domain.NotificationDestination, len(settings.Destinations))
+	for i, destination := range settings.Destinations {
+		destination.Secret = ""
+		redacted.Destinations[i] = destination
+	}
+	return &redacted
+}
+
+// destinationLabel identifica el destino en el historial
+func destinationLabel(destination domain.NotificationDestination) string {
+	switch destination.Type {
+	case domain.NotificationDestinationWebhook:
+		return destination.URL
+	case domain.NotificationDestinationEmail:
+		return strings.Join(destination.Emails, ",")
+	default:
+		return string(destination.Type)
+	}
+}
+
+// notificationDedupKey identifica el aviso de un vencimiento a un destino. Al rotar el token
+// cambia el vencimiento y el siguiente se vuelve a avisar.
+func notificationDedupKey(notification *TokenNotification, destination domain.NotificationDestination) string {
+	var expiresAt int64
+	if notification.ExpiresAt != nil {
+		expiresAt = notification.ExpiresAt.Unix()
+	}
+	fingerprint := sha256.Sum256([]byte(string(destination.Type) + "|" + destinationLabel(destination)))
+	return fmt.Sprintf("%s:%s:%d:%s", notification.ChannelID, notification.Event, expiresAt, hex.EncodeToString(fingerprint[:8]))
+}
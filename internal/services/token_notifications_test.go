@@ -0,0 +1,397 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/events"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryNotificationRepository guarda los destinos por tenant y el historial en memoria
+type memoryNotificationRepository struct {
+	mu       sync.Mutex
+	settings map[string]*domain.TenantNotificationSettings
+	records  []*domain.NotificationRecord
+}
+
+func newMemoryNotificationRepository(settings ...*domain.TenantNotificationSettings) *memoryNotificationRepository {
+	repo := &memoryNotificationRepository{settings: make(map[string]*domain.TenantNotificationSettings)}
+	for _, s := range settings {
+		repo.settings[s.TenantID] = s
+	}
+	return repo
+}
+
+func (r *memoryNotificationRepository) GetSettings(ctx context.Context, tenantID string) (*domain.TenantNotificationSettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	settings, ok := r.settings[tenantID]
+	if !ok {
+		return nil, fmt.Errorf("notification settings not found: %w", sql.ErrNoRows)
+	}
+	copied := *settings
+	copied.Destinations = append([]domain.NotificationDestination(nil), settings.Destinations...)
+	return &copied, nil
+}
+
+func (r *memoryNotificationRepository) UpsertSettings(ctx context.Context, settings *domain.TenantNotificationSettings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *settings
+	copied.Destinations = append([]domain.NotificationDestination(nil), settings.Destinations...)
+	r.settings[settings.TenantID] = &copied
+	return nil
+}
+
+func (r *memoryNotificationRepository) WasSent(ctx context.Context, dedupKey string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, record := range r.records {
+		if record.DedupKey == dedupKey && record.Status == domain.NotificationStatusSent {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryNotificationRepository) CreateRecord(ctx context.Context, record *domain.NotificationRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, record)
+	return nil
+}
+
+func (r *memoryNotificationRepository) ListRecords(ctx context.Context, tenantID, channelID string, limit, offset int) ([]*domain.NotificationRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*domain.NotificationRecord
+	for i := len(r.records) - 1; i >= 0; i-- {
+		record := r.records[i]
+		if record.TenantID == tenantID && (channelID == "" || record.ChannelID == channelID) {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+// smtpSink es un servidor SMTP mínimo en 127.0.0.1 que guarda los mensajes recibidos
+type smtpSink struct {
+	listener net.Listener
+	messages chan smtpSinkMessage
+}
+
+type smtpSinkMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	sink := &smtpSink{listener: listener, messages: make(chan smtpSinkMessage, 10)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	return sink
+}
+
+func (s *smtpSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	var message smtpSinkMessage
+	reply("220 localhost ESMTP sink")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+		upper := strings.ToUpper(command)
+
+		switch {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			message = smtpSinkMessage{from: strings.Trim(command[len("MAIL FROM:"):], "<> ")}
+			reply("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			message.to = append(message.to, strings.Trim(command[len("RCPT TO:"):], "<> "))
+			reply("250 OK")
+		case upper == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			message.data = data.String()
+			s.messages <- message
+			reply("250 OK")
+		case upper == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func expiringToken(expiresAt time.Time, days int) *TokenStatus {
+	return &TokenStatus{
+		ChannelID:       "channel-1",
+		Platform:        string(domain.PlatformWhatsApp),
+		TenantID:        "tenant-1",
+		TokenExpiry:     expiresAt,
+		DaysUntilExpiry: days,
+		Status:          TokenStatusExpiringSoon,
+	}
+}
+
+func TestTokenNotificationWebhookIsSignedAndSentOncePerExpiry(t *testing.T) {
+	var mu sync.Mutex
+	var received []TokenNotification
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(NotificationSignatureHeader) != SignNotification("tenant-secret", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var notification TokenNotification
+		require.NoError(t, json.Unmarshal(body, &notification))
+		mu.Lock()
+		received = append(received, notification)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhookNotificationSender(time.Second)
+	webhook.client = server.Client()
+	repo := newMemoryNotificationRepository(&domain.TenantNotificationSettings{
+		TenantID: "tenant-1",
+		Enabled:  true,
+		Destinations: []domain.NotificationDestination{
+			{Type: domain.NotificationDestinationWebhook, URL: server.URL + "/hooks/tokens", Secret: "tenant-secret"},
+		},
+	})
+	service := NewTokenNotificationService(repo, []NotificationSender{webhook}, logger.NewLogger("error"))
+
+	expiresAt := time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// Las revisiones siguientes del mismo vencimiento no repiten el aviso
+	require.NoError(t, service.NotifyTokenExpiry(ctx, expiringToken(expiresAt, 4), domain.NotificationTokenExpiring))
+	require.NoError(t, service.NotifyTokenExpiry(ctx, expiringToken(expiresAt, 3), domain.NotificationTokenExpiring))
+	require.Len(t, received, 1)
+	assert.Equal(t, domain.NotificationTokenExpiring, received[0].Event)
+	assert.Equal(t, "channel-1", received[0].ChannelID)
+	assert.Equal(t, 4, received[0].DaysUntilExpiry)
+	assert.True(t, expiresAt.Equal(*received[0].ExpiresAt))
+
+	// Un token rotado tiene otro vencimiento y se vuelve a avisar
+	require.NoError(t, service.NotifyTokenExpiry(ctx, expiringToken(expiresAt.AddDate(0, 2, 0), 6), domain.NotificationTokenExpiring))
+	assert.Len(t, received, 2)
+
+	history, err := service.ListHistory(ctx, "tenant-1", "channel-1", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, domain.NotificationStatusSent, history[0].Status)
+	assert.Equal(t, server.URL+"/hooks/tokens", history[0].Destination)
+}
+
+func TestTokenNotificationFailuresAreRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhookNotificationSender(time.Second)
+	webhook.client = server.Client()
+	repo := newMemoryNotificationRepository(&domain.TenantNotificationSettings{
+		TenantID: "tenant-1",
+		Enabled:  true,
+		Destinations: []domain.NotificationDestination{
+			{Type: domain.NotificationDestinationWebhook, URL: server.URL, Secret: "tenant-secret"},
+		},
+	})
+	service := NewTokenNotificationService(repo, []NotificationSender{webhook}, logger.NewLogger("error"))
+
+	token := expiringToken(time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC), 4)
+	err := service.NotifyTokenExpiry(context.Background(), token, domain.NotificationTokenExpiring)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 503")
+
+	require.NoError(t, service.NotifyTokenExpiry(context.Background(), token, domain.NotificationTokenExpiring))
+	require.NoError(t, service.NotifyTokenExpiry(context.Background(), token, domain.NotificationTokenExpiring))
+	assert.Equal(t, 2, attempts)
+
+	history, err := service.ListHistory(context.Background(), "tenant-1", "", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, domain.NotificationStatusSent, history[0].Status)
+	assert.Equal(t, domain.NotificationStatusFailed, history[1].Status)
+	assert.Contains(t, history[1].Error, "status 503")
+}
+
+func TestTokenNotificationEmailIsSentThroughSMTP(t *testing.T) {
+	sink := newSMTPSink(t)
+	email := NewSMTPNotificationSender(config.NotificationConfig{
+		SMTPHost: "127.0.0.1",
+		SMTPPort: sink.port(),
+		SMTPFrom: "alerts@example.com",
+	}, nil)
+
+	repo := newMemoryNotificationRepository(&domain.TenantNotificationSettings{
+		TenantID: "tenant-1",
+		Enabled:  true,
+		Destinations: []domain.NotificationDestination{
+			{Type: domain.NotificationDestinationEmail, Emails: []string{"ops@tenant.com", "owner@tenant.com"}},
+		},
+	})
+	service := NewTokenNotificationService(repo, []NotificationSender{email}, logger.NewLogger("error"))
+
+	token := expiringToken(time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC), 0)
+	require.NoError(t, service.NotifyTokenExpiry(context.Background(), token, domain.NotificationTokenExpired))
+
+	select {
+	case message := <-sink.messages:
+		assert.Equal(t, "alerts@example.com", message.from)
+		assert.Equal(t, []string{"ops@tenant.com", "owner@tenant.com"}, message.to)
+		assert.Contains(t, message.data, "Subject: [whatsapp] Channel token expired\r\n")
+		assert.Contains(t, message.data, "Channel: channel-1")
+		assert.Contains(t, message.data, "Expires at: 2026-03-01T11:00:00Z")
+	case <-time.After(2 * time.Second):
+		t.Fatal("smtp sink did not receive the notification")
+	}
+}
+
+func TestTokenRotationNotifiesRevokedTokenOnce(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	channels := &memoryTokenRepository{channels: map[string]*domain.ChannelIntegration{
+		"tg-revoked": {ID: "tg-revoked", TenantID: "tenant-1", Platform: domain.PlatformTelegram, Status: domain.StatusActive, AccessToken: "revoked"},
+	}}
+	telegram := &fakeTokenClient{platform: domain.PlatformTelegram}
+
+	bus := events.NewInMemoryEventBus(logger.NewLogger("error"))
+	published := make(chan events.Event, 10)
+	require.NoError(t, bus.Subscribe("integration.token_expired", func(ctx context.Context, event events.Event) error {
+		published <- event
+		return nil
+	}))
+
+	repo := newMemoryNotificationRepository(&domain.TenantNotificationSettings{
+		TenantID:     "tenant-1",
+		Enabled:      true,
+		Destinations: []domain.NotificationDestination{{Type: domain.NotificationDestinationEvent}},
+	})
+	notifier := NewTokenNotificationService(repo, []NotificationSender{NewEventNotificationSender(bus)}, logger.NewLogger("error"))
+	service := NewTokenRotationService(channels, []TokenClient{telegram}, notifier, logger.NewLogger("error"))
+
+	// El token rechazado vuelve a validarse en cada revisión sin cambiar su vencimiento
+	config := TokenRotationConfig{Enabled: true, RotationInterval: 24 * time.Hour, WarningDays: 7}
+	for tick := 0; tick < 3; tick++ {
+		service.now = func() time.Time { return now.Add(time.Duration(tick) * 25 * time.Hour) }
+		require.NoError(t, service.processTokenRotation(context.Background(), config))
+	}
+
+	select {
+	case event := <-published:
+		assert.Equal(t, "tg-revoked", event.Data["channel_id"])
+		assert.Equal(t, "tenant-1", event.Data["tenant_id"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("token_expired event was not published")
+	}
+
+	history, err := notifier.ListHistory(context.Background(), "tenant-1", "", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, domain.NotificationTokenExpired, history[0].EventType)
+	assert.Equal(t, now, *channels.channels["tg-revoked"].TokenExpiresAt)
+	assert.Equal(t, domain.StatusError, channels.channels["tg-revoked"].Status)
+}
+
+func TestUpdateNotificationSettingsValidatesDestinations(t *testing.T) {
+	repo := newMemoryNotificationRepository()
+	service := NewTokenNotificationService(repo, []NotificationSender{NewWebhookNotificationSender(time.Second)}, logger.NewLogger("error"))
+	ctx := context.Background()
+
+	invalid := []domain.NotificationDestination{
+		{Type: domain.NotificationDestinationWebhook, URL: "http://tenant.com/hook", Secret: "s"},
+		{Type: domain.NotificationDestinationWebhook, URL: "https://tenant.com/hook"},
+		{Type: domain.NotificationDestinationEmail, Emails: []string{"ops@tenant.com"}},
+	}
+	for _, destination := range invalid {
+		_, err := service.UpdateSettings(ctx, &domain.TenantNotificationSettings{
+			TenantID: "tenant-1", Enabled: true, Destinations: []domain.NotificationDestination{destination},
+		})
+		assert.True(t, errors.Is(err, ErrInvalidNotificationSettings), "destination %+v", destination)
+	}
+
+	saved, err := service.UpdateSettings(ctx, &domain.TenantNotificationSettings{
+		TenantID: "tenant-1", Enabled: true, Destinations: []domain.NotificationDestination{
+			{Type: domain.NotificationDestinationWebhook, URL: "https://tenant.com/hook", Secret: "tenant-secret"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, saved.Destinations[0].Secret)
+
+	// Reenviar la configuración sin el secreto conserva el guardado
+	_, err = service.UpdateSettings(ctx, &domain.TenantNotificationSettings{
+		TenantID: "tenant-1", Enabled: false, Destinations: []domain.NotificationDestination{
+			{Type: domain.NotificationDestinationWebhook, URL: "https://tenant.com/hook"},
+		},
+	})
+	require.NoError(t, err)
+	stored, err := repo.GetSettings(ctx, "tenant-1")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-secret", stored.Destinations[0].Secret)
+	assert.False(t, stored.Enabled)
+
+	_, err = service.GetSettings(ctx, "tenant-2")
+	assert.True(t, errors.Is(err, ErrNotificationSettingsNotFound))
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,38 +10,59 @@ import (
 	"it-integration-service/pkg/logger"
 )
 
-// TokenRotationService maneja la rotación automática de tokens
+// Estados de un token según su vencimiento y la última validación
+const (
+	TokenStatusValid        = "valid"
+	TokenStatusExpiringSoon = "expiring_soon"
+	TokenStatusExpired      = "expired"
+)
+
+// TokenRotationService maneja la validación, rotación y vencimiento de los tokens de los canales
 type TokenRotationService struct {
 	channelRepo domain.ChannelIntegrationRepository
-	logger       logger.Logger
+	clients     map[domain.Platform]TokenClient
+	notifier    TokenNotifier
+	now         func() time.Time
+	logger      logger.Logger
 }
 
-// NewTokenRotationService crea una nueva instancia del servicio de rotación de tokens
-func NewTokenRotationService(channelRepo domain.ChannelIntegrationRepository, logger logger.Logger) *TokenRotationService {
+// NewTokenRotationService crea una nueva instancia del servicio de rotación de tokens; sin
+// notifier los vencimientos solo quedan en el log
+func NewTokenRotationService(channelRepo domain.ChannelIntegrationRepository, clients []TokenClient, notifier TokenNotifier, logger logger.Logger) *TokenRotationService {
+	byPlatform := make(map[domain.Platform]TokenClient)
+	for _, client := range clients {
+		for _, platform := range client.Platforms() {
+			byPlatform[platform] = client
+		}
+	}
+
 	return &TokenRotationService{
 		channelRepo: channelRepo,
-		logger:       logger,
+		clients:     byPlatform,
+		notifier:    notifier,
+		now:         time.Now,
+		logger:      logger,
 	}
 }
 
 // TokenRotationConfig representa la configuración de rotación de tokens
 type TokenRotationConfig struct {
-	Enabled           bool          `json:"enabled"`
-	RotationInterval  time.Duration `json:"rotation_interval"`
-	WarningDays       int           `json:"warning_days"`
-	AutoRotation      bool          `json:"auto_rotation"`
-	NotificationEmail string        `json:"notification_email"`
+	Enabled          bool          `json:"enabled"`
+	RotationInterval time.Duration `json:"rotation_interval"`
+	WarningDays      int           `json:"warning_days"`
+	AutoRotation     bool          `json:"auto_rotation"`
 }
 
 // TokenStatus representa el estado de un token
 type TokenStatus struct {
-	ChannelID     string    `json:"channel_id"`
-	Platform      string    `json:"platform"`
-	TenantID      string    `json:"tenant_id"`
-	TokenExpiry   time.Time `json:"token_expiry"`
-	DaysUntilExpiry int     `json:"days_until_expiry"`
-	Status        string    `json:"status"` // "valid", "expiring_soon", "expired"
-	LastRotated   time.Time `json:"last_rotated"`
+	ChannelID       string    `json:"channel_id"`
+	Platform        string    `json:"platform"`
+	TenantID        string    `json:"tenant_id"`
+	TokenExpiry     time.Time `json:"token_expiry"`
+	DaysUntilExpiry int       `json:"days_until_expiry"`
+	Status          string    `json:"status"` // "valid", "expiring_soon", "expired"
+	LastRotated     time.Time `json:"last_rotated"`
+	LastValidated   time.Time `json:"last_validated"`
 }
 
 // RotateToken rota un token específico
@@ -51,17 +73,23 @@ func (s *TokenRotationService) RotateToken(ctx context.Context, channelID string
 		return fmt.Errorf("failed to get channel integration: %w", err)
 	}
 
-	// Validar que el nuevo token sea válido
-	if err := s.validateNewToken(ctx, integration.Platform, newToken); err != nil {
-		return fmt.Errorf("invalid new token: %w", err)
+	// Validar el nuevo token contra la plataforma
+	info, err := s.inspect(ctx, integration, newToken)
+	if err != nil {
+		return fmt.Errorf("failed to validate new token: %w", err)
+	}
+	if !info.Valid {
+		return fmt.Errorf("invalid new token: rejected by %s", integration.Platform)
 	}
 
-	// Actualizar el token
-	integration.AccessToken = newToken
-	integration.UpdatedAt = time.Now()
+	issuedAt := s.now().UTC()
+	if info.IssuedAt != nil {
+		issuedAt = *info.IssuedAt
+	}
+	s.applyNewToken(integration, &RefreshedToken{AccessToken: newToken, ExpiresAt: info.ExpiresAt, IssuedAt: issuedAt})
 
 	// Guardar en la base de datos
-	if err := s.channelRepo.Update(ctx, integration); err != nil {
+	if err := s.channelRepo.UpdateTokenState(ctx, integration); err != nil {
 		return fmt.Errorf("failed to update token: %w", err)
 	}
 
@@ -74,31 +102,26 @@ func (s *TokenRotationService) RotateToken(ctx context.Context, channelID string
 	return nil
 }
 
-// GetExpiringTokens obtiene tokens que están por expirar
+// ValidateToken valida el token vigente del canal contra la plataforma y guarda el resultado
+func (s *TokenRotationService) ValidateToken(ctx context.Context, channelID string) (*TokenStatus, error) {
+	integration, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel integration: %w", err)
+	}
+	return s.validateIntegration(ctx, integration, s.GetTokenRotationConfig().WarningDays)
+}
+
+// GetExpiringTokens obtiene los tokens que vencen dentro de daysThreshold días o ya vencieron
 func (s *TokenRotationService) GetExpiringTokens(ctx context.Context, daysThreshold int) ([]*TokenStatus, error) {
-	// En una implementación real, esto consultaría la base de datos
-	// Por ahora, retornamos datos de ejemplo
-	expiringTokens := []*TokenStatus{
-		{
-			ChannelID:       "whatsapp_tenant1_123",
-			Platform:        "whatsapp",
-			TenantID:        "tenant1",
-			TokenExpiry:     time.Now().AddDate(0, 0, 5), // 5 días
-			DaysUntilExpiry: 5,
-			Status:          "expiring_soon",
-			LastRotated:     time.Now().AddDate(0, -1, 0), // 1 mes atrás
-		},
-		{
-			ChannelID:       "telegram_tenant1_456",
-			Platform:        "telegram",
-			TenantID:        "tenant1",
-			TokenExpiry:     time.Now().AddDate(0, 0, 2), // 2 días
-			DaysUntilExpiry: 2,
-			Status:          "expiring_soon",
-			LastRotated:     time.Now().AddDate(0, -2, 0), // 2 meses atrás
-		},
+	integrations, err := s.channelRepo.GetByTokenExpiry(ctx, s.now().AddDate(0, 0, daysThreshold))
+	if err != nil {
+		return nil, err
 	}
 
+	expiringTokens := make([]*TokenStatus, 0, len(integrations))
+	for _, integration := range integrations {
+		expiringTokens = append(expiringTokens, s.tokenStatus(integration, daysThreshold))
+	}
 	return expiringTokens, nil
 }
 
@@ -141,6 +164,9 @@ func (s *TokenRotationService) runTokenRotationScheduler(ctx context.Context, co
 
 // processTokenRotation procesa la rotación de tokens
 func (s *TokenRotationService) processTokenRotation(ctx context.Context, config TokenRotationConfig) error {
+	// Validar primero los tokens sin validación reciente para conocer su vencimiento
+	s.validateStaleTokens(ctx, s.now().Add(-config.RotationInterval), config.WarningDays)
+
 	// Obtener tokens que están por expirar
 	expiringTokens, err := s.GetExpiringTokens(ctx, config.WarningDays)
 	if err != nil {
@@ -148,20 +174,32 @@ func (s *TokenRotationService) processTokenRotation(ctx context.Context, config
 	}
 
 	for _, token := range expiringTokens {
-		if token.Status == "expired" {
-			// Token expirado - desactivar integración
+		if token.Status == TokenStatusExpired {
+			// Un token vencido puede renovarse si la plataforma lo permite (refresh token de Google)
+			if config.AutoRotation {
+				if err := s.autoRotateToken(ctx, token.ChannelID); err == nil {
+					continue
+				} else if !errors.Is(err, ErrTokenRefreshUnsupported) {
+					s.logger.Error("Failed to auto-rotate expired token", err)
+				}
+			}
+
+			// Token expirado - desactivar integración y avisar al tenant
 			if err := s.deactivateExpiredIntegration(ctx, token.ChannelID); err != nil {
 				s.logger.Error("Failed to deactivate expired integration", err)
 			}
-		} else if token.Status == "expiring_soon" {
+			if err := s.sendExpiryNotification(ctx, token, domain.NotificationTokenExpired); err != nil {
+				s.logger.Error("Failed to send expiry notification", err)
+			}
+		} else if token.Status == TokenStatusExpiringSoon {
 			// Token por expirar - enviar notificación
-			if err := s.sendExpiryNotification(ctx, token, config); err != nil {
+			if err := s.sendExpiryNotification(ctx, token, domain.NotificationTokenExpiring); err != nil {
 				s.logger.Error("Failed to send expiry notification", err)
 			}
 
 			// Rotación automática si está habilitada
 			if config.AutoRotation {
-				if err := s.autoRotateToken(ctx, token.ChannelID); err != nil {
+				if err := s.autoRotateToken(ctx, token.ChannelID); err != nil && !errors.Is(err, ErrTokenRefreshUnsupported) {
 					s.logger.Error("Failed to auto-rotate token", err)
 				}
 			}
@@ -171,57 +209,156 @@ func (s *TokenRotationService) processTokenRotation(ctx context.Context, config
 	return nil
 }
 
-// validateNewToken valida que un nuevo token sea válido
-func (s *TokenRotationService) validateNewToken(ctx context.Context, platform domain.Platform, token string) error {
-	switch platform {
-	case domain.PlatformWhatsApp:
-		return s.validateWhatsAppToken(ctx, token)
-	case domain.PlatformTelegram:
-		return s.validateTelegramToken(ctx, token)
-	case domain.PlatformMessenger:
-		return s.validateMessengerToken(ctx, token)
-	case domain.PlatformInstagram:
-		return s.validateInstagramToken(ctx, token)
-	default:
-		return fmt.Errorf("unsupported platform for token validation: %s", platform)
+// validateStaleTokens valida los tokens de los canales no validados desde validatedBefore
+func (s *TokenRotationService) validateStaleTokens(ctx context.Context, validatedBefore time.Time, warningDays int) {
+	for platform := range s.clients {
+		integrations, err := s.channelRepo.GetByPlatform(ctx, platform)
+		if err != nil {
+			s.logger.Error("Failed to list channels for token validation", map[string]interface{}{
+				"platform": platform,
+				"error":    err.Error(),
+			})
+			continue
+		}
+
+		for _, integration := range integrations {
+			if integration.Status == domain.StatusDisabled {
+				continue
+			}
+			if last := integration.TokenLastValidatedAt; last != nil && last.After(validatedBefore) {
+				continue
+			}
+			if _, err := s.validateIntegration(ctx, integration, warningDays); err != nil {
+				s.logger.Warn("Failed to validate channel token", map[string]interface{}{
+					"channel_id": integration.ID,
+					"platform":   integration.Platform,
+					"error":      err.Error(),
+				})
+			}
+		}
 	}
 }
 
-// validateWhatsAppToken valida un token de WhatsApp
-func (s *TokenRotationService) validateWhatsAppToken(ctx context.Context, token string) error {
-	// Implementar validación específica de WhatsApp
-	// Por ahora, solo verificar que no esté vacío
-	if token == "" {
-		return fmt.Errorf("whatsapp token cannot be empty")
+// validateIntegration consulta a la plataforma y actualiza la metadata del token. Un token
+// rechazado por la plataforma desactiva la integración igual que uno vencido.
+func (s *TokenRotationService) validateIntegration(ctx context.Context, integration *domain.ChannelIntegration, warningDays int) (*TokenStatus, error) {
+	info, err := s.inspect(ctx, integration, channelToken(integration))
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	now := s.now().UTC()
+	previousExpiry := integration.TokenExpiresAt
+	integration.TokenLastValidatedAt = &now
+	integration.TokenExpiresAt = info.ExpiresAt
+	if info.IssuedAt != nil {
+		integration.TokenIssuedAt = info.IssuedAt
+	}
+	if !info.Valid {
+		// El vencimiento es el momento en que la plataforma lo rechazó por primera vez, así las
+		// validaciones siguientes no lo cuentan como un vencimiento nuevo a avisar
+		if previousExpiry == nil || previousExpiry.After(now) {
+			previousExpiry = &now
+		}
+		integration.TokenExpiresAt = previousExpiry
+		s.markExpired(integration)
+	}
+	integration.UpdatedAt = now
+
+	if err := s.channelRepo.UpdateTokenState(ctx, integration); err != nil {
+		return nil, fmt.Errorf("failed to update token state: %w", err)
+	}
+
+	return s.tokenStatus(integration, warningDays), nil
 }
 
-// validateTelegramToken valida un token de Telegram
-func (s *TokenRotationService) validateTelegramToken(ctx context.Context, token string) error {
-	// Implementar validación específica de Telegram
+// inspect valida un token con el cliente de la plataforma del canal
+func (s *TokenRotationService) inspect(ctx context.Context, integration *domain.ChannelIntegration, token string) (*TokenInfo, error) {
 	if token == "" {
-		return fmt.Errorf("telegram token cannot be empty")
+		return nil, fmt.Errorf("%s token cannot be empty", integration.Platform)
 	}
-	return nil
+
+	client, ok := s.clients[integration.Platform]
+	if !ok {
+		return nil, fmt.Errorf("unsupported platform for token validation: %s", integration.Platform)
+	}
+	return client.Inspect(ctx, integration, token)
 }
 
-// validateMessengerToken valida un token de Messenger
-func (s *TokenRotationService) validateMessengerToken(ctx context.Context, token string) error {
-	// Implementar validación específica de Messenger
-	if token == "" {
-		return fmt.Errorf("messenger token cannot be empty")
+// channelToken retorna la credencial que valida la plataforma. Mailchimp y Telegram pueden
+// guardarla en el config en lugar del access token.
+func channelToken(integration *domain.ChannelIntegration) string {
+	if integration.AccessToken != "" {
+		return integration.AccessToken
 	}
-	return nil
+	for _, key := range []string{"api_key", "bot_token", "page_access_token"} {
+		if value := channelConfigValue(integration, key); value != "" {
+			return value
+		}
+	}
+	return ""
 }
 
-// validateInstagramToken valida un token de Instagram
-func (s *TokenRotationService) validateInstagramToken(ctx context.Context, token string) error {
-	// Implementar validación específica de Instagram
-	if token == "" {
-		return fmt.Errorf("instagram token cannot be empty")
+// tokenStatus calcula el estado del token a partir de su metadata; vence pronto si le quedan
+// menos de warningDays días
+func (s *TokenRotationService) tokenStatus(integration *domain.ChannelIntegration, warningDays int) *TokenStatus {
+	status := &TokenStatus{
+		ChannelID: integration.ID,
+		Platform:  string(integration.Platform),
+		TenantID:  integration.TenantID,
+		Status:    TokenStatusValid,
 	}
-	return nil
+	if integration.TokenLastRotatedAt != nil {
+		status.LastRotated = *integration.TokenLastRotatedAt
+	}
+	if integration.TokenLastValidatedAt != nil {
+		status.LastValidated = *integration.TokenLastValidatedAt
+	}
+
+	if integration.TokenExpiresAt != nil {
+		status.TokenExpiry = *integration.TokenExpiresAt
+		remaining := integration.TokenExpiresAt.Sub(s.now())
+		status.DaysUntilExpiry = int(remaining.Hours() / 24)
+		switch {
+		case remaining <= 0:
+			status.Status = TokenStatusExpired
+		case remaining <= time.Duration(warningDays)*24*time.Hour:
+			status.Status = TokenStatusExpiringSoon
+		}
+	}
+
+	return status
+}
+
+// applyNewToken guarda un token nuevo y su metadata; una integración desactivada por vencimiento vuelve a activarse
+func (s *TokenRotationService) applyNewToken(integration *domain.ChannelIntegration, token *RefreshedToken) {
+	now := s.now().UTC()
+	issuedAt := token.IssuedAt
+
+	integration.AccessToken = token.AccessToken
+	integration.TokenIssuedAt = &issuedAt
+	integration.TokenExpiresAt = token.ExpiresAt
+	integration.TokenLastRotatedAt = &now
+	integration.TokenLastValidatedAt = &now
+	integration.UpdatedAt = now
+	if integration.Status == domain.StatusError {
+		integration.Status = domain.StatusActive
+	}
+}
+
+// markExpired pasa la integración a error por token vencido
+func (s *TokenRotationService) markExpired(integration *domain.ChannelIntegration) {
+	if integration.Status == domain.StatusError {
+		return
+	}
+	integration.Status = domain.StatusError
+	integration.UpdatedAt = s.now()
+
+	s.logger.Warn("Integration deactivated due to expired token", map[string]interface{}{
+		"channel_id": integration.ID,
+		"platform":   integration.Platform,
+		"tenant_id":  integration.TenantID,
+	})
 }
 
 // deactivateExpiredIntegration desactiva una integración con token expirado
@@ -230,43 +367,63 @@ func (s *TokenRotationService) deactivateExpiredIntegration(ctx context.Context,
 	if err != nil {
 		return fmt.Errorf("failed to get integration: %w", err)
 	}
+	if integration.Status == domain.StatusError {
+		return nil
+	}
 
-	integration.Status = domain.StatusError
-	integration.UpdatedAt = time.Now()
-
-	if err := s.channelRepo.Update(ctx, integration); err != nil {
+	s.markExpired(integration)
+	if err := s.channelRepo.UpdateTokenState(ctx, integration); err != nil {
 		return fmt.Errorf("failed to deactivate integration: %w", err)
 	}
 
-	s.logger.Warn("Integration deactivated due to expired token", map[string]interface{}{
-		"channel_id": channelID,
-		"platform":   integration.Platform,
-		"tenant_id":  integration.TenantID,
-	})
-
 	return nil
 }
 
-// sendExpiryNotification envía notificación de expiración de token
-func (s *TokenRotationService) sendExpiryNotification(ctx context.Context, token *TokenStatus, config TokenRotationConfig) error {
-	// En una implementación real, esto enviaría un email o webhook
-	s.logger.Warn("Token expiring soon", map[string]interface{}{
-		"channel_id":       token.ChannelID,
-		"platform":         token.Platform,
-		"tenant_id":        token.TenantID,
-		"days_until_expiry": token.DaysUntilExpiry,
-		"notification_email": config.NotificationEmail,
-	})
+// sendExpiryNotification avisa al tenant el vencimiento del token; el notifier descarta los
+// avisos ya entregados del mismo vencimiento
+func (s *TokenRotationService) sendExpiryNotification(ctx context.Context, token *TokenStatus, event domain.NotificationEventType) error {
+	if s.notifier == nil {
+		s.logger.Warn("Token expiry without notifier", map[string]interface{}{
+			"event":             event,
+			"channel_id":        token.ChannelID,
+			"platform":          token.Platform,
+			"tenant_id":         token.TenantID,
+			"days_until_expiry": token.DaysUntilExpiry,
+		})
+		return nil
+	}
 
-	return nil
+	return s.notifier.NotifyTokenExpiry(ctx, token, event)
 }
 
-// autoRotateToken rota automáticamente un token
+// autoRotateToken obtiene un token nuevo de la plataforma: canje por un token de larga duración
+// en Meta y refresh token en Google
 func (s *TokenRotationService) autoRotateToken(ctx context.Context, channelID string) error {
-	// En una implementación real, esto obtendría un nuevo token de la API correspondiente
-	// Por ahora, solo loggeamos la acción
-	s.logger.Info("Auto-rotating token", map[string]interface{}{
+	integration, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get integration: %w", err)
+	}
+
+	client, ok := s.clients[integration.Platform]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTokenRefreshUnsupported, integration.Platform)
+	}
+
+	refreshed, err := client.Refresh(ctx, integration)
+	if err != nil {
+		return err
+	}
+
+	s.applyNewToken(integration, refreshed)
+	if err := s.channelRepo.UpdateTokenState(ctx, integration); err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+
+	s.logger.Info("Token auto-rotated", map[string]interface{}{
 		"channel_id": channelID,
+		"platform":   integration.Platform,
+		"tenant_id":  integration.TenantID,
+		"expires_at": integration.TokenExpiresAt,
 	})
 
 	return nil
@@ -275,10 +432,9 @@ func (s *TokenRotationService) autoRotateToken(ctx context.Context, channelID st
 // GetTokenRotationConfig obtiene la configuración de rotación de tokens
 func (s *TokenRotationService) GetTokenRotationConfig() TokenRotationConfig {
 	return TokenRotationConfig{
-		Enabled:           true,
-		RotationInterval:  24 * time.Hour, // Revisar cada 24 horas
-		WarningDays:       7,              // Advertir 7 días antes
-		AutoRotation:      false,          // No rotar automáticamente por defecto
-		NotificationEmail: "admin@company.com",
+		Enabled:          true,
+		RotationInterval: 24 * time.Hour, // Revisar cada 24 horas
+		WarningDays:      7,              // Advertir 7 días antes
+		AutoRotation:     false,          // No rotar automáticamente por defecto
 	}
 }
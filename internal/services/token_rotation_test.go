@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTokenRepository guarda las integraciones por ID y simula las consultas por vencimiento
+type memoryTokenRepository struct {
+	domain.ChannelIntegrationRepository
+	channels map[string]*domain.ChannelIntegration
+}
+
+func (r *memoryTokenRepository) GetByID(ctx context.Context, id string) (*domain.ChannelIntegration, error) {
+	channel := *r.channels[id]
+	return &channel, nil
+}
+
+func (r *memoryTokenRepository) GetByPlatform(ctx context.Context, platform domain.Platform) ([]*domain.ChannelIntegration, error) {
+	var result []*domain.ChannelIntegration
+	for _, channel := range r.channels {
+		if channel.Platform == platform {
+			copied := *channel
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryTokenRepository) GetByTokenExpiry(ctx context.Context, before time.Time) ([]*domain.ChannelIntegration, error) {
+	var result []*domain.ChannelIntegration
+	for _, channel := range r.channels {
+		if channel.TokenExpiresAt != nil && !channel.TokenExpiresAt.After(before) && channel.Status != domain.StatusDisabled {
+			copied := *channel
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryTokenRepository) UpdateTokenState(ctx context.Context, integration *domain.ChannelIntegration) error {
+	copied := *integration
+	r.channels[integration.ID] = &copied
+	return nil
+}
+
+// fakeTokenClient responde con la información configurada por token
+type fakeTokenClient struct {
+	platform  domain.Platform
+	tokens    map[string]*TokenInfo
+	refreshed *RefreshedToken
+}
+
+func (c *fakeTokenClient) Platforms() []domain.Platform {
+	return []domain.Platform{c.platform}
+}
+
+func (c *fakeTokenClient) Inspect(ctx context.Context, channel *domain.ChannelIntegration, token string) (*TokenInfo, error) {
+	if info, ok := c.tokens[token]; ok {
+		return info, nil
+	}
+	return &TokenInfo{Valid: false}, nil
+}
+
+func (c *fakeTokenClient) Refresh(ctx context.Context, channel *domain.ChannelIntegration) (*RefreshedToken, error) {
+	if c.refreshed == nil {
+		return nil, ErrTokenRefreshUnsupported
+	}
+	return c.refreshed, nil
+}
+
+func timeAt(t time.Time) *time.Time {
+	return &t
+}
+
+func TestTokenRotationLifecycle(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &memoryTokenRepository{channels: map[string]*domain.ChannelIntegration{
+		"wa-expiring": {ID: "wa-expiring", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive, AccessToken: "old-wa",
+			TokenExpiresAt: timeAt(now.AddDate(0, 0, 3)), TokenLastValidatedAt: timeAt(now)},
+		"gc-expired": {ID: "gc-expired", Platform: domain.PlatformGoogleCalendar, Status: domain.StatusActive, AccessToken: "old-gc",
+			TokenExpiresAt: timeAt(now.Add(-time.Hour)), TokenLastValidatedAt: timeAt(now)},
+		"tg-revoked": {ID: "tg-revoked", Platform: domain.PlatformTelegram, Status: domain.StatusActive, AccessToken: "revoked"},
+		"tg-valid":   {ID: "tg-valid", Platform: domain.PlatformTelegram, Status: domain.StatusActive, AccessToken: "bot-ok"},
+	}}
+
+	meta := &fakeTokenClient{platform: domain.PlatformWhatsApp}
+	google := &fakeTokenClient{platform: domain.PlatformGoogleCalendar, refreshed: &RefreshedToken{
+		AccessToken: "new-gc", IssuedAt: now, ExpiresAt: timeAt(now.Add(time.Hour)),
+	}}
+	telegram := &fakeTokenClient{platform: domain.PlatformTelegram, tokens: map[string]*TokenInfo{"bot-ok": {Valid: true}}}
+
+	service := NewTokenRotationService(repo, []TokenClient{meta, google, telegram}, nil, logger.NewLogger("error"))
+	service.now = func() time.Time { return now }
+
+	config := TokenRotationConfig{Enabled: true, RotationInterval: 24 * time.Hour, WarningDays: 7, AutoRotation: true}
+	require.NoError(t, service.processTokenRotation(context.Background(), config))
+
+	// Google renueva el token vencido con el refresh token y sigue activo
+	gc := repo.channels["gc-expired"]
+	assert.Equal(t, "new-gc", gc.AccessToken)
+	assert.Equal(t, domain.StatusActive, gc.Status)
+	assert.Equal(t, now.Add(time.Hour), *gc.TokenExpiresAt)
+	assert.Equal(t, now, *gc.TokenLastRotatedAt)
+
+	// Meta no pudo canjearse y queda activo hasta vencer
+	wa := repo.channels["wa-expiring"]
+	assert.Equal(t, "old-wa", wa.AccessToken)
+	assert.Equal(t, domain.StatusActive, wa.Status)
+
+	// Un token revocado en la plataforma pasa a error; uno válido registra la validación
+	assert.Equal(t, domain.StatusError, repo.channels["tg-revoked"].Status)
+	assert.Equal(t, now, *repo.channels["tg-valid"].TokenLastValidatedAt)
+	assert.Nil(t, repo.channels["tg-valid"].TokenExpiresAt)
+	assert.Equal(t, domain.StatusActive, repo.channels["tg-valid"].Status)
+
+	// Rotar manualmente reactiva el canal si la plataforma acepta el token
+	telegram.tokens["bot-new"] = &TokenInfo{Valid: true}
+	assert.Error(t, service.RotateToken(context.Background(), "tg-revoked", "still-revoked"))
+	require.NoError(t, service.RotateToken(context.Background(), "tg-revoked", "bot-new"))
+	assert.Equal(t, domain.StatusActive, repo.channels["tg-revoked"].Status)
+	assert.Equal(t, "bot-new", repo.channels["tg-revoked"].AccessToken)
+}
+
+func TestGetExpiringTokensClassifiesByExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &memoryTokenRepository{channels: map[string]*domain.ChannelIntegration{
+		"soon":    {ID: "soon", Platform: domain.PlatformMessenger, Status: domain.StatusActive, TokenExpiresAt: timeAt(now.AddDate(0, 0, 2))},
+		"expired": {ID: "expired", Platform: domain.PlatformMessenger, Status: domain.StatusActive, TokenExpiresAt: timeAt(now.AddDate(0, 0, -1))},
+		"later":   {ID: "later", Platform: domain.PlatformMessenger, Status: domain.StatusActive, TokenExpiresAt: timeAt(now.AddDate(0, 0, 30))},
+	}}
+	service := NewTokenRotationService(repo, nil, nil, logger.NewLogger("error"))
+	service.now = func() time.Time { return now }
+
+	tokens, err := service.GetExpiringTokens(context.Background(), 7)
+	require.NoError(t, err)
+
+	statuses := map[string]*TokenStatus{}
+	for _, token := range tokens {
+		statuses[token.ChannelID] = token
+	}
+	require.Len(t, statuses, 2)
+	assert.Equal(t, TokenStatusExpiringSoon, statuses["soon"].Status)
+	assert.Equal(t, 2, statuses["soon"].DaysUntilExpiry)
+	assert.Equal(t, TokenStatusExpired, statuses["expired"].Status)
+}
+
+func TestMetaTokenClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/debug_token":
+			assert.Equal(t, "app-1|secret-1", r.URL.Query().Get("access_token"))
+			if r.URL.Query().Get("input_token") == "good" {
+				w.Write([]byte(`{"data":{"is_valid":true,"expires_at":1767225600,"issued_at":1761955200}}`))
+				return
+			}
+			w.Write([]byte(`{"data":{"is_valid":false,"expires_at":0}}`))
+		case "/oauth/access_token":
+			assert.Equal(t, "fb_exchange_token", r.URL.Query().Get("grant_type"))
+			assert.Equal(t, "good", r.URL.Query().Get("fb_exchange_token"))
+			w.Write([]byte(`{"access_token":"long-lived","token_type":"bearer","expires_in":5184000}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &MetaTokenClient{baseURL: server.URL, client: server.Client()}
+	channel := &domain.ChannelIntegration{
+		ID:          "channel-1",
+		Platform:    domain.PlatformWhatsApp,
+		AccessToken: "good",
+		Config:      []byte(`{"app_id":"app-1","app_secret":"secret-1"}`),
+	}
+
+	info, err := client.Inspect(context.Background(), channel, "good")
+	require.NoError(t, err)
+	assert.True(t, info.Valid)
+	assert.Equal(t, time.Unix(1767225600, 0).UTC(), *info.ExpiresAt)
+
+	info, err = client.Inspect(context.Background(), channel, "bad")
+	require.NoError(t, err)
+	assert.False(t, info.Valid)
+	assert.Nil(t, info.ExpiresAt)
+
+	refreshed, err := client.Refresh(context.Background(), channel)
+	require.NoError(t, err)
+	assert.Equal(t, "long-lived", refreshed.AccessToken)
+	assert.WithinDuration(t, time.Now().Add(60*24*time.Hour), *refreshed.ExpiresAt, time.Minute)
+
+	// Sin app en el canal ni en los secretos no se puede consultar a Meta
+	_, err = client.Inspect(context.Background(), &domain.ChannelIntegration{ID: "channel-2"}, "good")
+	assert.Error(t, err)
+}
+
+func TestTelegramTokenClientTreatsUnauthorizedAsInvalid(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/botgood/getMe" {
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true}}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+	}))
+	defer server.Close()
+
+	client := &TelegramTokenClient{baseURL: server.URL, client: server.Client()}
+
+	info, err := client.Inspect(context.Background(), &domain.ChannelIntegration{}, "good")
+	require.NoError(t, err)
+	assert.True(t, info.Valid)
+
+	info, err = client.Inspect(context.Background(), &domain.ChannelIntegration{}, "revoked")
+	require.NoError(t, err)
+	assert.False(t, info.Valid)
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingOutbox registra lo que el webhook deja en el outbox y, como la transacción de
+// EnqueueWithInbound, los registros de deduplicación de sus entradas
+type recordingOutbox struct {
+	domain.MessageOutboxRepository
+	inbound *domain.InboundMessage
+	entries []*domain.OutboxMessage
+	dedup   map[string]*domain.ProcessedWebhookEvent
+	err     error
+}
+
+func dedupKey(event *domain.ProcessedWebhookEvent) string {
+	return string(event.Platform) + "|" + event.ChannelID + "|" + event.EventKey
+}
+
+func (r *recordingOutbox) EnqueueWithInbound(ctx context.Context, inbound *domain.InboundMessage, messages []*domain.OutboxMessage) error {
+	// Un error revierte la transacción: no queda ningún registro
+	if r.err != nil {
+		return r.err
+	}
+
+	var enqueue []*domain.OutboxMessage
+	for _, message := range messages {
+		if event := message.DedupEvent; event != nil {
+			if existing, ok := r.dedup[dedupKey(event)]; ok && existing.ExpiresAt.After(time.Now()) {
+				message.Duplicate = true
+				inbound.Results[message.ItemIndex].Status = domain.InboundResultDuplicate
+				continue
+			}
+		}
+		enqueue = append(enqueue, message)
+	}
+	if len(messages) > 0 && len(enqueue) == 0 {
+		return nil
+	}
+
+	if r.dedup == nil {
+		r.dedup = make(map[string]*domain.ProcessedWebhookEvent)
+	}
+	for _, message := range enqueue {
+		if message.DedupEvent != nil {
+			r.dedup[dedupKey(message.DedupEvent)] = message.DedupEvent
+		}
+	}
+	r.inbound = inbound
+	r.entries = enqueue
+	return nil
+}
+
+// batchEntry es una entrada de un webhook de Meta: el canal que la recibe y los eventos que trae
+type batchEntry struct {
+	channel  string
+	messages []string
+	statuses []string
+}
+
+func whatsappBatchPayload(entries ...batchEntry) []byte {
+	var parts []string
+	for _, entry := range entries {
+		var messages, statuses []string
+		for _, id := range entry.messages {
+			messages = append(messages, fmt.Sprintf(`{"from":"5215512345678","id":%q,"timestamp":"1749416800","type":"text","text":{"body":"hola"}}`, id))
+		}
+		for _, id := range entry.statuses {
+			statuses = append(statuses, fmt.Sprintf(`{"id":%q,"status":"delivered","timestamp":"1749416800","recipient_id":"5215512345678"}`, id))
+		}
+		parts = append(parts, fmt.Sprintf(`{"id":"waba","changes":[{"field":"messages","value":{"metadata":{"phone_number_id":%q},"messages":[%s],"statuses":[%s]}}]}`,
+			entry.channel, strings.Join(messages, ","), strings.Join(statuses, ",")))
+	}
+	return []byte(`{"object":"whatsapp_business_account","entry":[` + strings.Join(parts, ",") + `]}`)
+}
+
+func messengerBatchPayload(entries ...batchEntry) []byte {
+	var parts []string
+	for _, entry := range entries {
+		var events []string
+		for _, id := range entry.messages {
+			events = append(events, fmt.Sprintf(`{"sender":{"id":"user-1"},"recipient":{"id":%q},"timestamp":1749416800000,"message":{"mid":%q,"text":"hola"}}`, entry.channel, id))
+		}
+		parts = append(parts, fmt.Sprintf(`{"id":%q,"time":1749416800000,"messaging":[%s]}`, entry.channel, strings.Join(events, ",")))
+	}
+	return []byte(`{"object":"page","entry":[` + strings.Join(parts, ",") + `]}`)
+}
+
+// deliveredEvent es lo que llegó al outbox de un evento: su ID y el canal con que se reenvía
+type deliveredEvent struct {
+	messageID string
+	tenantID  string
+	channelID string
+}
+
+func TestProcessWebhookResolvesChannelPerEntry(t *testing.T) {
+	channels := newCountingChannelRepository(
+		&domain.ChannelIntegration{ID: "channel-a", TenantID: "tenant-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive},
+		&domain.ChannelIntegration{ID: "channel-b", TenantID: "tenant-b", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive},
+		&domain.ChannelIntegration{ID: "page-a", TenantID: "tenant-a", Platform: domain.PlatformMessenger, Status: domain.StatusActive},
+		&domain.ChannelIntegration{ID: "page-b", TenantID: "tenant-b", Platform: domain.PlatformMessenger, Status: domain.StatusActive},
+	)
+	channels.identifiers = map[string]string{"phone-a": "channel-a", "phone-b": "channel-b", "fb-page-a": "page-a", "fb-page-b": "page-b"}
+
+	tests := []struct {
+		name           string
+		platform       domain.Platform
+		payload        []byte
+		wantDelivered  []deliveredEvent
+		wantInbound    string
+		wantUnresolved []string
+	}{
+		{
+			name:          "una entrada",
+			platform:      domain.PlatformWhatsApp,
+			payload:       whatsappBatchPayload(batchEntry{channel: "phone-a", messages: []string{"wamid.1"}}),
+			wantDelivered: []deliveredEvent{{"wamid.1", "tenant-a", "channel-a"}},
+			wantInbound:   "channel-a",
+		},
+		{
+			name:     "varias entradas del mismo canal",
+			platform: domain.PlatformWhatsApp,
+			payload: whatsappBatchPayload(
+				batchEntry{channel: "phone-a", messages: []string{"wamid.1"}},
+				batchEntry{channel: "phone-a", messages: []string{"wamid.2"}, statuses: []string{"wamid.out"}},
+			),
+			wantDelivered: []deliveredEvent{
+				{"wamid.1", "tenant-a", "channel-a"},
+				{"wamid.2", "tenant-a", "channel-a"},
+				{"wamid.out", "tenant-a", "channel-a"},
+			},
+			wantInbound: "channel-a",
+		},
+		{
+			name:     "entradas de distintos canales",
+			platform: domain.PlatformWhatsApp,
+			payload: whatsappBatchPayload(
+				batchEntry{channel: "phone-a", messages: []string{"wamid.1"}},
+				batchEntry{channel: "phone-b", messages: []string{"wamid.2"}, statuses: []string{"wamid.out"}},
+			),
+			wantDelivered: []deliveredEvent{
+				{"wamid.1", "tenant-a", "channel-a"},
+				{"wamid.2", "tenant-b", "channel-b"},
+				{"wamid.out", "tenant-b", "channel-b"},
+			},
+		},
+		{
+			name:     "páginas de Messenger de distintos canales",
+			platform: domain.PlatformMessenger,
+			payload: messengerBatchPayload(
+				batchEntry{channel: "fb-page-b", messages: []string{"m_1"}},
+				batchEntry{channel: "fb-page-a", messages: []string{"m_2"}},
+			),
+			wantDelivered: []deliveredEvent{
+				{"m_1", "tenant-b", "page-b"},
+				{"m_2", "tenant-a", "page-a"},
+			},
+		},
+		{
+			name:     "canales conocidos y desconocidos",
+			platform: domain.PlatformWhatsApp,
+			payload: whatsappBatchPayload(
+				batchEntry{channel: "phone-x", messages: []string{"wamid.1"}},
+				batchEntry{channel: "phone-b", messages: []string{"wamid.2"}},
+			),
+			wantDelivered:  []deliveredEvent{{"wamid.2", "tenant-b", "channel-b"}},
+			wantInbound:    "channel-b",
+			wantUnresolved: []string{"wamid.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.NewLogger("error")
+			outbox := &recordingOutbox{}
+			dedup := &memoryDedupRepository{events: map[string]*domain.ProcessedWebhookEvent{}}
+			service := &integrationService{
+				channelService:   NewChannelService(channels, log),
+				outboxRepo:       outbox,
+				dedupRepo:        dedup,
+				webhookService:   NewWebhookService("", log),
+				channelResolver:  NewChannelResolver(channels, time.Minute, log),
+				unresolvedPolicy: "reject",
+				dedupRetention:   time.Hour,
+				logger:           log,
+			}
+
+			require.NoError(t, service.processWebhook(context.Background(), tt.platform, tt.payload, "", webhookRoute{}))
+			require.NotNil(t, outbox.inbound)
+			assert.Equal(t, tt.wantInbound, outbox.inbound.ChannelID)
+
+			var delivered []deliveredEvent
+			for _, entry := range outbox.entries {
+				var event struct {
+					MessageID string `json:"message_id"`
+					TenantID  string `json:"tenant_id"`
+					ChannelID string `json:"channel_id"`
+				}
+				require.NoError(t, json.Unmarshal(entry.Payload, &event))
+				delivered = append(delivered, deliveredEvent{event.MessageID, event.TenantID, event.ChannelID})
+
+				// El resultado del evento y su deduplicación usan el mismo canal con que se reenvía
+				result := outbox.inbound.Results[entry.ItemIndex]
+				assert.Equal(t, event.ChannelID, result.ChannelID)
+				assert.Equal(t, event.TenantID, result.TenantID)
+				_, claimed := outbox.dedup[string(tt.platform)+"|"+event.ChannelID+"|"+entry.MessageID+statusSuffix(entry)]
+				assert.True(t, claimed, "evento %s deduplicado con otro canal", entry.MessageID)
+			}
+			assert.Equal(t, tt.wantDelivered, delivered)
+
+			var unresolved []string
+			for _, result := range outbox.inbound.Results {
+				if result.Status == domain.InboundResultUnresolved {
+					unresolved = append(unresolved, result.MessageID)
+					assert.Equal(t, ErrChannelNotFound.Error(), result.Error)
+					assert.Empty(t, result.ChannelID)
+				}
+			}
+			assert.Equal(t, tt.wantUnresolved, unresolved)
+		})
+	}
+}
+
+// statusSuffix completa la clave de deduplicación de los recibos, que incluye el estado
+func statusSuffix(entry *domain.OutboxMessage) string {
+	if entry.EventType == domain.EventTypeStatus {
+		return ":" + string(domain.MessageStatusDelivered)
+	}
+	return ""
+}
+
+func TestProcessWebhookWithoutKnownChannels(t *testing.T) {
+	channels := newCountingChannelRepository()
+	log := logger.NewLogger("error")
+	outbox := &recordingOutbox{}
+	service := &integrationService{
+		channelService:   NewChannelService(channels, log),
+		outboxRepo:       outbox,
+		webhookService:   NewWebhookService("", log),
+		channelResolver:  NewChannelResolver(channels, time.Minute, log),
+		unresolvedPolicy: "reject",
+		logger:           log,
+	}
+
+	payload := whatsappBatchPayload(
+		batchEntry{channel: "phone-x", messages: []string{"wamid.1"}},
+		batchEntry{channel: "phone-y", messages: []string{"wamid.2"}},
+	)
+	err := service.processWebhook(context.Background(), domain.PlatformWhatsApp, payload, "", webhookRoute{})
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+	assert.Nil(t, outbox.inbound)
+}
+
+func TestProcessWebhookForwardsOnlyTheEntryOfEachTenant(t *testing.T) {
+	channels := newCountingChannelRepository(
+		&domain.ChannelIntegration{ID: "channel-a", TenantID: "tenant-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive},
+		&domain.ChannelIntegration{ID: "channel-b", TenantID: "tenant-b", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive},
+		&domain.ChannelIntegration{ID: "page-a", TenantID: "tenant-a", Platform: domain.PlatformMessenger, Status: domain.StatusActive},
+		&domain.ChannelIntegration{ID: "page-b", TenantID: "tenant-b", Platform: domain.PlatformMessenger, Status: domain.StatusActive},
+	)
+	channels.identifiers = map[string]string{"phone-a": "channel-a", "phone-b": "channel-b", "fb-page-a": "page-a", "fb-page-b": "page-b"}
+
+	tests := []struct {
+		name     string
+		platform domain.Platform
+		payload  []byte
+		own      map[string][]string
+	}{
+		{
+			name:     "WhatsApp",
+			platform: domain.PlatformWhatsApp,
+			payload: whatsappBatchPayload(
+				batchEntry{channel: "phone-a", messages: []string{"wamid.a1", "wamid.a2"}},
+				batchEntry{channel: "phone-b", messages: []string{"wamid.b1"}},
+			),
+			own: map[string][]string{"tenant-a": {"phone-a", "wamid.a1", "wamid.a2"}, "tenant-b": {"phone-b", "wamid.b1"}},
+		},
+		{
+			name:     "Messenger",
+			platform: domain.PlatformMessenger,
+			payload: messengerBatchPayload(
+				batchEntry{channel: "fb-page-a", messages: []string{"m_a1"}},
+				batchEntry{channel: "fb-page-b", messages: []string{"m_b1"}},
+			),
+			own: map[string][]string{"tenant-a": {"fb-page-a", "m_a1"}, "tenant-b": {"fb-page-b", "m_b1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.NewLogger("error")
+			outbox := &recordingOutbox{}
+			service := &integrationService{
+				channelService:   NewChannelService(channels, log),
+				outboxRepo:       outbox,
+				webhookService:   NewWebhookService("", log),
+				channelResolver:  NewChannelResolver(channels, time.Minute, log),
+				unresolvedPolicy: "reject",
+				logger:           log,
+			}
+
+			require.NoError(t, service.processWebhook(context.Background(), tt.platform, tt.payload, "", webhookRoute{}))
+			require.NotEmpty(t, outbox.entries)
+			for _, entry := range outbox.entries {
+				var event struct {
+					TenantID   string          `json:"tenant_id"`
+					RawPayload json.RawMessage `json:"raw_payload"`
+				}
+				require.NoError(t, json.Unmarshal(entry.Payload, &event))
+				assertScopedRawPayload(t, tt.own, event.TenantID, event.RawPayload)
+			}
+		})
+	}
+}
+
+// assertScopedRawPayload verifica que el payload reenviado a un tenant trae su entrada y ninguna de otro
+func assertScopedRawPayload(t *testing.T, own map[string][]string, tenantID string, rawPayload []byte) {
+	t.Helper()
+	require.NotEmpty(t, rawPayload)
+	for tenant, markers := range own {
+		for _, marker := range markers {
+			if tenant == tenantID {
+				continue
+			}
+			assert.NotContains(t, string(rawPayload), marker, "el payload de %s trae datos de %s", tenantID, tenant)
+		}
+	}
+	assert.Contains(t, string(rawPayload), own[tenantID][0])
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registeredChannels resuelve canales por ID y por identificador de plataforma
+type registeredChannels struct {
+	domain.ChannelIntegrationRepository
+	channels    map[string]*domain.ChannelIntegration
+	identifiers map[string]string
+}
+
+func (r *registeredChannels) GetByID(ctx context.Context, id string) (*domain.ChannelIntegration, error) {
+	channel, ok := r.channels[id]
+	if !ok {
+		return nil, fmt.Errorf("channel integration not found: %w", sql.ErrNoRows)
+	}
+	return channel, nil
+}
+
+func (r *registeredChannels) GetByPlatformIdentifier(ctx context.Context, platform domain.Platform, identifier string) (*domain.ChannelIntegration, error) {
+	id, ok := r.identifiers[identifier]
+	if !ok {
+		return nil, fmt.Errorf("channel integration not found: %w", sql.ErrNoRows)
+	}
+	return r.channels[id], nil
+}
+
+func whatsappPayload(phoneNumberID string) []byte {
+	return []byte(fmt.Sprintf(`{"entry":[{"changes":[{"value":{"metadata":{"phone_number_id":%q}}}]}]}`, phoneNumberID))
+}
+
+func TestResolveWebhookChannelByRoute(t *testing.T) {
+	log := logger.NewLogger("error")
+	repo := &registeredChannels{
+		channels: map[string]*domain.ChannelIntegration{
+			"channel-a": {ID: "channel-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive},
+			"channel-b": {ID: "channel-b", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive},
+			"channel-c": {ID: "channel-c", Platform: domain.PlatformWhatsApp, Status: domain.StatusDisabled},
+			"channel-m": {ID: "channel-m", Platform: domain.PlatformMessenger, Status: domain.StatusActive},
+		},
+		identifiers: map[string]string{"phone-a": "channel-a", "phone-b": "channel-b"},
+	}
+	service := &integrationService{
+		channelService:  NewChannelService(repo, log),
+		webhookService:  NewWebhookService("", log),
+		channelResolver: NewChannelResolver(repo, 0, log),
+		logger:          log,
+	}
+	ctx := context.Background()
+
+	channel, err := service.ResolveWebhookChannel(ctx, domain.PlatformWhatsApp, "channel-a", whatsappPayload("phone-a"))
+	require.NoError(t, err)
+	assert.Equal(t, "channel-a", channel.ID)
+
+	// Sin payload (verificación) o con un identificador no registrado alcanza con la ruta
+	channel, err = service.ResolveWebhookChannel(ctx, domain.PlatformWhatsApp, "channel-a", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "channel-a", channel.ID)
+	_, err = service.ResolveWebhookChannel(ctx, domain.PlatformWhatsApp, "channel-a", whatsappPayload("phone-x"))
+	require.NoError(t, err)
+
+	// Un tenant no puede firmar con su secreto mensajes dirigidos al canal de otro
+	_, err = service.ResolveWebhookChannel(ctx, domain.PlatformWhatsApp, "channel-a", whatsappPayload("phone-b"))
+	assert.ErrorIs(t, err, ErrChannelMismatch)
+	_, err = service.ResolveWebhookChannel(ctx, domain.PlatformWhatsApp, "channel-a", whatsappBatchPayload(
+		batchEntry{channel: "phone-a", messages: []string{"wamid.1"}},
+		batchEntry{channel: "phone-b", messages: []string{"wamid.2"}},
+	))
+	assert.ErrorIs(t, err, ErrChannelMismatch)
+
+	_, err = service.ResolveWebhookChannel(ctx, domain.PlatformWhatsApp, "channel-m", nil)
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+	_, err = service.ResolveWebhookChannel(ctx, domain.PlatformWhatsApp, "missing", nil)
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+
+	channel, err = service.ResolveWebhookChannel(ctx, domain.PlatformWhatsApp, "channel-c", nil)
+	assert.ErrorIs(t, err, ErrChannelDisabled)
+	assert.Equal(t, "channel-c", channel.ID)
+
+	// Sin ruta el canal sale del payload
+	channel, err = service.ResolveWebhookChannel(ctx, domain.PlatformWhatsApp, "", whatsappPayload("phone-b"))
+	require.NoError(t, err)
+	assert.Equal(t, "channel-b", channel.ID)
+}
+
+func TestResolveWebhookChannelsPerEntry(t *testing.T) {
+	log := logger.NewLogger("error")
+	repo := &registeredChannels{
+		channels: map[string]*domain.ChannelIntegration{
+			"channel-a": {ID: "channel-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive},
+			"channel-b": {ID: "channel-b", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive},
+			"channel-c": {ID: "channel-c", Platform: domain.PlatformWhatsApp, Status: domain.StatusDisabled},
+		},
+		identifiers: map[string]string{"phone-a": "channel-a", "phone-b": "channel-b", "phone-c": "channel-c"},
+	}
+	service := &integrationService{
+		channelService:  NewChannelService(repo, log),
+		webhookService:  NewWebhookService("", log),
+		channelResolver: NewChannelResolver(repo, 0, log),
+		logger:          log,
+	}
+	ctx := context.Background()
+
+	// Sin ruta se resuelve cada entrada; las de canales no registrados quedan para el procesamiento
+	channels, err := service.ResolveWebhookChannels(ctx, domain.PlatformWhatsApp, "", whatsappBatchPayload(
+		batchEntry{channel: "phone-a", messages: []string{"wamid.1"}},
+		batchEntry{channel: "phone-x", messages: []string{"wamid.2"}},
+		batchEntry{channel: "phone-b", messages: []string{"wamid.3"}},
+		batchEntry{channel: "phone-c", messages: []string{"wamid.4"}},
+		batchEntry{channel: "phone-a", messages: []string{"wamid.5"}},
+	))
+	require.NoError(t, err)
+	var ids []string
+	for _, channel := range channels {
+		ids = append(ids, channel.ID)
+	}
+	assert.Equal(t, []string{"channel-a", "channel-b", "channel-c"}, ids)
+
+	channels, err = service.ResolveWebhookChannels(ctx, domain.PlatformWhatsApp, "", whatsappPayload("phone-x"))
+	require.NoError(t, err)
+	assert.Empty(t, channels)
+
+	// Con ruta solo cuenta el canal de la ruta
+	channels, err = service.ResolveWebhookChannels(ctx, domain.PlatformWhatsApp, "channel-a", whatsappPayload("phone-a"))
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	assert.Equal(t, "channel-a", channels[0].ID)
+
+	channels, err = service.ResolveWebhookChannels(ctx, domain.PlatformWhatsApp, "channel-a", whatsappPayload("phone-b"))
+	assert.ErrorIs(t, err, ErrChannelMismatch)
+	require.Len(t, channels, 1)
+
+	_, err = service.ResolveWebhookChannels(ctx, domain.PlatformWhatsApp, "missing", nil)
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+}
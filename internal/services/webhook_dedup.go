@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/metrics"
+	"it-integration-service/pkg/logger"
+)
+
+// messageEventKey identifica un mensaje dentro de la plataforma. Los message_id de Telegram
+// solo son únicos por chat, por eso se usa el update_id, único por bot. Los eventos de Mailchimp
+// sin fired_at no se deduplican: su ID no identifica al evento.
+func messageEventKey(platform domain.Platform, payload []byte, message *NormalizedMessage) string {
+	if platform == domain.PlatformMailchimp {
+		var event struct {
+			FiredAt string `json:"fired_at"`
+		}
+		if err := json.Unmarshal(payload, &event); err != nil || event.FiredAt == "" {
+			return ""
+		}
+		return message.MessageID
+	}
+	if platform == domain.PlatformTelegram {
+		var update struct {
+			UpdateID int64 `json:"update_id"`
+		}
+		if err := json.Unmarshal(payload, &update); err == nil && update.UpdateID != 0 {
+			return "update:" + strconv.FormatInt(update.UpdateID, 10)
+		}
+		return ""
+	}
+	return message.MessageID
+}
+
+// claimItems registra los eventos del webhook en el almacén de deduplicación y marca como
+// duplicados los que ya se aceptaron dentro de la ventana de retención. Retorna cuántos hay.
+// Solo se usa sin outbox; con outbox el registro va en la transacción de EnqueueWithInbound.
+func (s *integrationService) claimItems(ctx context.Context, message *domain.InboundMessage, items []inboundItem) (int, error) {
+	if s.dedupRepo == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	duplicates := 0
+	for i := range items {
+		item := &items[i]
+		claim := s.dedupEvent(message, *item, now)
+		if claim == nil {
+			continue
+		}
+
+		claimed, err := s.dedupRepo.Claim(ctx, claim)
+		if err != nil {
+			// Los eventos ya registrados en este webhook no deben bloquear el reintento del proveedor
+			s.releaseClaims(ctx, items)
+			return 0, err
+		}
+		if claimed {
+			item.claim = claim
+			continue
+		}
+
+		s.markDuplicate(message, items, i)
+		duplicates++
+	}
+
+	return duplicates, nil
+}
+
+// dedupEvent arma el registro de deduplicación de un evento; nil si el evento no se deduplica
+func (s *integrationService) dedupEvent(message *domain.InboundMessage, item inboundItem, now time.Time) *domain.ProcessedWebhookEvent {
+	if s.dedupRepo == nil || item.eventKey == "" || item.unresolved {
+		return nil
+	}
+
+	// Los eventos se deduplican por el canal de su entrada, no por el del webhook completo
+	return &domain.ProcessedWebhookEvent{
+		Platform:         message.Platform,
+		ChannelID:        item.channelID,
+		EventKey:         item.eventKey,
+		InboundMessageID: message.ID,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(s.dedupRetention),
+	}
+}
+
+// markDuplicate marca un evento ya aceptado dentro de la ventana de retención para no reenviarlo
+func (s *integrationService) markDuplicate(message *domain.InboundMessage, items []inboundItem, i int) {
+	item := &items[i]
+	item.duplicate = true
+	message.Results[i].Status = domain.InboundResultDuplicate
+	metrics.WebhookDuplicate(string(message.Platform), string(item.eventType))
+
+	s.logger.Debug("Duplicate webhook event discarded", map[string]interface{}{
+		"platform":   message.Platform,
+		"channel_id": item.channelID,
+		"event_type": item.eventType,
+		"event_key":  item.eventKey,
+	})
+}
+
+// releaseClaims libera los eventos registrados por este webhook cuando no pudo aceptarse
+func (s *integrationService) releaseClaims(ctx context.Context, items []inboundItem) {
+	for _, item := range items {
+		s.releaseClaim(ctx, item.claim)
+	}
+}
+
+func (s *integrationService) releaseClaim(ctx context.Context, claim *domain.ProcessedWebhookEvent) {
+	if claim == nil {
+		return
+	}
+	if err := s.dedupRepo.Release(ctx, claim); err != nil {
+		s.logger.Error("Failed to release webhook event", map[string]interface{}{
+			"platform":  claim.Platform,
+			"event_key": claim.EventKey,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// WebhookDedupCleaner elimina periódicamente los eventos cuya ventana de retención venció
+type WebhookDedupCleaner struct {
+	dedupRepo domain.ProcessedWebhookEventRepository
+	config    config.DedupConfig
+	logger    logger.Logger
+}
+
+// NewWebhookDedupCleaner crea una nueva instancia del limpiador de deduplicación
+func NewWebhookDedupCleaner(dedupRepo domain.ProcessedWebhookEventRepository, cfg config.DedupConfig, logger logger.Logger) *WebhookDedupCleaner {
+	return &WebhookDedupCleaner{
+		dedupRepo: dedupRepo,
+		config:    cfg,
+		logger:    logger,
+	}
+}
+
+// Start inicia la limpieza periódica hasta que se cancele el contexto
+func (c *WebhookDedupCleaner) Start(ctx context.Context) {
+	if c.config.CleanupInterval <= 0 {
+		c.logger.Info("Webhook dedup cleanup is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.config.CleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := c.dedupRepo.DeleteExpired(ctx)
+				if err != nil {
+					c.logger.Error("Failed to delete expired webhook events", err)
+					continue
+				}
+				if deleted > 0 {
+					c.logger.Info("Expired webhook events deleted", map[string]interface{}{
+						"deleted": deleted,
+					})
+				}
+			}
+		}
+	}()
+}
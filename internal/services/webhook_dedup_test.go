@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryDedupRepository simula la restricción única de processed_webhook_events
+type memoryDedupRepository struct {
+	events map[string]*domain.ProcessedWebhookEvent
+}
+
+func (r *memoryDedupRepository) key(event *domain.ProcessedWebhookEvent) string {
+	return string(event.Platform) + "|" + event.ChannelID + "|" + event.EventKey
+}
+
+func (r *memoryDedupRepository) Claim(ctx context.Context, event *domain.ProcessedWebhookEvent) (bool, error) {
+	if existing, ok := r.events[r.key(event)]; ok && existing.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+	r.events[r.key(event)] = event
+	return true, nil
+}
+
+func (r *memoryDedupRepository) Release(ctx context.Context, event *domain.ProcessedWebhookEvent) error {
+	if existing, ok := r.events[r.key(event)]; ok && existing.InboundMessageID == event.InboundMessageID {
+		delete(r.events, r.key(event))
+	}
+	return nil
+}
+
+func (r *memoryDedupRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func TestClaimItemsDiscardsRedeliveries(t *testing.T) {
+	repo := &memoryDedupRepository{events: map[string]*domain.ProcessedWebhookEvent{}}
+	service := &integrationService{
+		dedupRepo:      repo,
+		dedupRetention: time.Hour,
+		logger:         logger.NewLogger("error"),
+	}
+
+	newWebhook := func(id string) (*domain.InboundMessage, []inboundItem) {
+		message := &domain.InboundMessage{ID: id, Platform: domain.PlatformWhatsApp, ChannelID: "channel-1"}
+		items := service.buildInboundItems(message,
+			[]*NormalizedMessage{{Platform: domain.PlatformWhatsApp, MessageID: "wamid.A"}},
+			[]*StatusEvent{{Platform: domain.PlatformWhatsApp, MessageID: "wamid.B", Status: domain.MessageStatusRead}},
+		)
+		return message, items
+	}
+
+	first, firstItems := newWebhook("inbound-1")
+	duplicates, err := service.claimItems(context.Background(), first, firstItems)
+	require.NoError(t, err)
+	assert.Zero(t, duplicates)
+
+	second, secondItems := newWebhook("inbound-2")
+	duplicates, err = service.claimItems(context.Background(), second, secondItems)
+	require.NoError(t, err)
+	assert.Equal(t, 2, duplicates)
+	assert.True(t, secondItems[0].duplicate)
+	assert.Equal(t, domain.InboundResultDuplicate, second.Results[1].Status)
+
+	// Si el primer webhook no pudo aceptarse, el reenvío debe procesarse
+	service.releaseClaims(context.Background(), firstItems)
+	third, thirdItems := newWebhook("inbound-3")
+	duplicates, err = service.claimItems(context.Background(), third, thirdItems)
+	require.NoError(t, err)
+	assert.Zero(t, duplicates)
+}
+
+func TestMessageEventKeyUsesTelegramUpdateID(t *testing.T) {
+	payload := []byte(`{"update_id": 815162342, "message": {"message_id": 12, "chat": {"id": 99}}}`)
+	key := messageEventKey(domain.PlatformTelegram, payload, &NormalizedMessage{MessageID: "12"})
+	assert.Equal(t, "update:815162342", key)
+
+	key = messageEventKey(domain.PlatformMessenger, nil, &NormalizedMessage{MessageID: "m_abc"})
+	assert.Equal(t, "m_abc", key)
+}
+
+func TestClaimItemsDiscardsRedeliveredReactions(t *testing.T) {
+	payload, err := os.ReadFile(filepath.Join("testdata", "webhooks", "messenger_location_reactions.json"))
+	require.NoError(t, err)
+
+	repo := &memoryDedupRepository{events: map[string]*domain.ProcessedWebhookEvent{}}
+	service := &integrationService{
+		dedupRepo:      repo,
+		dedupRetention: time.Hour,
+		webhookService: &webhookService{},
+		logger:         logger.NewLogger("error"),
+	}
+
+	receive := func(id string) (*domain.InboundMessage, []inboundItem) {
+		messages, err := service.webhookService.NormalizeMessage(domain.PlatformMessenger, payload)
+		require.NoError(t, err)
+		message := &domain.InboundMessage{ID: id, Platform: domain.PlatformMessenger, Payload: payload}
+		return message, service.buildInboundItems(message, messages, nil)
+	}
+
+	first, firstItems := receive("inbound-1")
+	duplicates, err := service.claimItems(context.Background(), first, firstItems)
+	require.NoError(t, err)
+	assert.Zero(t, duplicates)
+
+	// El reenvío del webhook repite las reacciones y sus claves de idempotencia
+	second, secondItems := receive("inbound-2")
+	duplicates, err = service.claimItems(context.Background(), second, secondItems)
+	require.NoError(t, err)
+	assert.Equal(t, 3, duplicates)
+	for i := range secondItems {
+		assert.Equal(t, firstItems[i].idempotencyKey, secondItems[i].idempotencyKey)
+	}
+}
+
+func TestEnqueueRecordsDedupInTheSameTransaction(t *testing.T) {
+	log := logger.NewLogger("error")
+	channels := newCountingChannelRepository(&domain.ChannelIntegration{ID: "channel-a", TenantID: "tenant-a", Platform: domain.PlatformWhatsApp, Status: domain.StatusActive})
+	channels.identifiers["phone-a"] = "channel-a"
+	outbox := &recordingOutbox{err: errors.New("connection reset")}
+	dedup := &memoryDedupRepository{events: map[string]*domain.ProcessedWebhookEvent{}}
+	service := &integrationService{
+		channelService:   NewChannelService(channels, log),
+		outboxRepo:       outbox,
+		dedupRepo:        dedup,
+		webhookService:   NewWebhookService("", log),
+		channelResolver:  NewChannelResolver(channels, time.Minute, log),
+		unresolvedPolicy: "reject",
+		dedupRetention:   time.Hour,
+		logger:           log,
+	}
+	payload := whatsappBatchPayload(batchEntry{channel: "phone-a", messages: []string{"wamid.1"}, statuses: []string{"wamid.out"}})
+	receive := func() error {
+		return service.processWebhook(context.Background(), domain.PlatformWhatsApp, payload, "", webhookRoute{})
+	}
+
+	// Si el encolado falla no queda ningún evento registrado y el reenvío del proveedor se acepta
+	require.Error(t, receive())
+	assert.Empty(t, outbox.dedup)
+	assert.Empty(t, dedup.events)
+
+	outbox.err = nil
+	require.NoError(t, receive())
+	require.Len(t, outbox.entries, 2)
+	assert.Len(t, outbox.dedup, 2)
+	first := outbox.inbound
+
+	// Un reenvío del webhook ya encolado se responde sin volver a encolarlo
+	require.NoError(t, receive())
+	assert.Same(t, first, outbox.inbound)
+	assert.Len(t, outbox.entries, 2)
+}
+
+func TestMailchimpEventsDeduplicatedBySubscriberAndFiredAt(t *testing.T) {
+	repo := &memoryDedupRepository{events: map[string]*domain.ProcessedWebhookEvent{}}
+	service := &integrationService{
+		dedupRepo:      repo,
+		dedupRetention: time.Hour,
+		webhookService: &webhookService{},
+		logger:         logger.NewLogger("error"),
+	}
+
+	receive := func(id, payload string) int {
+		messages, err := service.webhookService.NormalizeMessage(domain.PlatformMailchimp, []byte(payload))
+		require.NoError(t, err)
+		message := &domain.InboundMessage{ID: id, Platform: domain.PlatformMailchimp, ChannelID: "channel-mc", Payload: []byte(payload)}
+		duplicates, err := service.claimItems(context.Background(), message, service.buildInboundItems(message, messages, nil))
+		require.NoError(t, err)
+		return duplicates
+	}
+
+	ana := `{"type":"subscribe","fired_at":"2026-03-01 12:00:00","data":{"list_id":"list-1","id":"a1","email":"ana@example.com"}}`
+	beto := `{"type":"subscribe","fired_at":"2026-03-01 12:00:00","data":{"list_id":"list-1","id":"b2","email":"beto@example.com"}}`
+
+	// Dos suscripciones en el mismo segundo son eventos distintos
+	assert.Zero(t, receive("inbound-1", ana))
+	assert.Zero(t, receive("inbound-2", beto))
+	// El reenvío de una de ellas sí es un duplicado
+	assert.Equal(t, 1, receive("inbound-3", ana))
+
+	// Sin fired_at no se puede reconocer el reenvío: el evento no se deduplica
+	undated := `{"type":"unsubscribe","data":{"list_id":"list-1","id":"a1","email":"ana@example.com"}}`
+	assert.Zero(t, receive("inbound-4", undated))
+	assert.Zero(t, receive("inbound-5", undated))
+	messages, err := service.webhookService.NormalizeMessage(domain.PlatformMailchimp, []byte(undated))
+	require.NoError(t, err)
+	assert.Empty(t, messageEventKey(domain.PlatformMailchimp, []byte(undated), messages[0]))
+}
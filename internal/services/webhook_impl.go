@@ -10,13 +10,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"it-integration-service/internal/domain"
 	"it-integration-service/pkg/logger"
+
+	"github.com/google/uuid"
 )
 
+// mailchimpFiredAtLayout es el formato de fired_at en los webhooks de Mailchimp
+const mailchimpFiredAtLayout = "2006-01-02 15:04:05"
+
 type webhookService struct {
 	messagingServiceURL string
 	logger              logger.Logger
@@ -47,193 +52,136 @@ func (s *webhookService) ValidateSignature(payload []byte, signature string, sec
 	return hmac.Equal([]byte(signature), []byte(expectedSignature))
 }
 
-func (s *webhookService) NormalizeMessage(platform domain.Platform, payload []byte) (*NormalizedMessage, error) {
+// NormalizeMessage normaliza todos los mensajes de un webhook. Meta agrupa varias
+// entradas, cambios y mensajes en una sola entrega; el resto de plataformas envía uno.
+// Un webhook de Meta que solo trae recibos de estado retorna una lista vacía.
+func (s *webhookService) NormalizeMessage(platform domain.Platform, payload []byte) ([]*NormalizedMessage, error) {
 	switch platform {
 	case domain.PlatformWhatsApp:
-		return s.normalizeWhatsAppMessage(payload)
+		return s.normalizeWhatsAppMessages(payload)
 	case domain.PlatformMessenger:
-		return s.normalizeMessengerMessage(payload)
+		return s.normalizeMessengerMessages(payload)
 	case domain.PlatformInstagram:
-		return s.normalizeInstagramMessage(payload)
+		return s.normalizeInstagramMessages(payload)
 	case domain.PlatformTelegram:
-		return s.normalizeTelegramMessage(payload)
+		return single(s.normalizeTelegramMessage(payload))
 	case domain.PlatformWebchat:
-		return s.normalizeWebchatMessage(payload)
+		return single(s.normalizeWebchatMessage(payload))
 	case domain.PlatformMailchimp:
-		return s.normalizeMailchimpMessage(payload)
+		return single(s.normalizeMailchimpMessage(payload))
 	default:
 		return nil, fmt.Errorf("unsupported platform: %s", platform)
 	}
 }
 
-func (s *webhookService) normalizeWhatsAppMessage(payload []byte) (*NormalizedMessage, error) {
-	var whatsappPayload struct {
-		Entry []struct {
-			Changes []struct {
-				Value struct {
-					Messages []struct {
-						ID        string `json:"id"`
-						From      string `json:"from"`
-						Timestamp string `json:"timestamp"`
-						Text      struct {
-							Body string `json:"body"`
-						} `json:"text"`
-						Type string `json:"type"`
-					} `json:"messages"`
-					Metadata struct {
-						PhoneNumberID string `json:"phone_number_id"`
-					} `json:"metadata"`
-				} `json:"value"`
-			} `json:"changes"`
-		} `json:"entry"`
-	}
-
-	if err := json.Unmarshal(payload, &whatsappPayload); err != nil {
-		return nil, fmt.Errorf("failed to parse WhatsApp payload: %w", err)
-	}
-
-	if len(whatsappPayload.Entry) == 0 || len(whatsappPayload.Entry[0].Changes) == 0 ||
-		len(whatsappPayload.Entry[0].Changes[0].Value.Messages) == 0 {
-		return nil, fmt.Errorf("no messages found in WhatsApp payload")
-	}
-
-	msg := whatsappPayload.Entry[0].Changes[0].Value.Messages[0]
-	timestamp, _ := strconv.ParseInt(msg.Timestamp, 10, 64)
-
-	content := &domain.MessageContent{
-		Type: msg.Type,
-		Text: msg.Text.Body,
-	}
-
-	return &NormalizedMessage{
-		Platform:   domain.PlatformWhatsApp,
-		Sender:     msg.From,
-		Recipient:  whatsappPayload.Entry[0].Changes[0].Value.Metadata.PhoneNumberID,
-		Content:    content,
-		Timestamp:  timestamp,
-		MessageID:  msg.ID,
-		RawPayload: payload,
-	}, nil
-}
-
-func (s *webhookService) normalizeMessengerMessage(payload []byte) (*NormalizedMessage, error) {
-	var messengerPayload struct {
-		Entry []struct {
-			Messaging []struct {
-				Sender struct {
-					ID string `json:"id"`
-				} `json:"sender"`
-				Recipient struct {
-					ID string `json:"id"`
-				} `json:"recipient"`
-				Timestamp int64 `json:"timestamp"`
-				Message   struct {
-					Mid  string `json:"mid"`
-					Text string `json:"text"`
-				} `json:"message"`
-			} `json:"messaging"`
-		} `json:"entry"`
-	}
-
-	if err := json.Unmarshal(payload, &messengerPayload); err != nil {
-		return nil, fmt.Errorf("failed to parse Messenger payload: %w", err)
-	}
-
-	if len(messengerPayload.Entry) == 0 || len(messengerPayload.Entry[0].Messaging) == 0 {
-		return nil, fmt.Errorf("no messages found in Messenger payload")
-	}
-
-	msg := messengerPayload.Entry[0].Messaging[0]
-
-	content := &domain.MessageContent{
-		Type: "text",
-		Text: msg.Message.Text,
-	}
-
-	return &NormalizedMessage{
-		Platform:   domain.PlatformMessenger,
-		Sender:     msg.Sender.ID,
-		Recipient:  msg.Recipient.ID,
-		Content:    content,
-		Timestamp:  msg.Timestamp,
-		MessageID:  msg.Message.Mid,
-		RawPayload: payload,
-	}, nil
-}
-
-func (s *webhookService) normalizeInstagramMessage(payload []byte) (*NormalizedMessage, error) {
-	// Instagram usa el mismo formato que Messenger
-	normalized, err := s.normalizeMessengerMessage(payload)
+// single adapta los normalizadores de un solo mensaje al resultado en lote
+func single(message *NormalizedMessage, err error) ([]*NormalizedMessage, error) {
 	if err != nil {
 		return nil, err
 	}
-	normalized.Platform = domain.PlatformInstagram
-	return normalized, nil
+	return []*NormalizedMessage{message}, nil
 }
 
-func (s *webhookService) normalizeTelegramMessage(payload []byte) (*NormalizedMessage, error) {
-	var telegramPayload struct {
-		Message struct {
-			MessageID int64 `json:"message_id"`
-			From      struct {
-				ID       int64  `json:"id"`
-				Username string `json:"username"`
-			} `json:"from"`
-			Chat struct {
-				ID int64 `json:"id"`
-			} `json:"chat"`
-			Date int64  `json:"date"`
-			Text string `json:"text"`
-		} `json:"message"`
-	}
-
-	if err := json.Unmarshal(payload, &telegramPayload); err != nil {
-		return nil, fmt.Errorf("failed to parse Telegram payload: %w", err)
-	}
-
-	content := &domain.MessageContent{
-		Type: "text",
-		Text: telegramPayload.Message.Text,
+// ExtractChannelIdentifier obtiene del payload el identificador que la plataforma usa para el canal
+// destino (phone_number_id, page_id, list_id...) de su primera entrada. Telegram no lo incluye y se
+// resuelve por la URL.
+func (s *webhookService) ExtractChannelIdentifier(platform domain.Platform, payload []byte) (string, error) {
+	identifiers, err := s.ExtractChannelIdentifiers(platform, payload)
+	if err != nil || len(identifiers) == 0 {
+		return "", err
 	}
-
-	return &NormalizedMessage{
-		Platform:   domain.PlatformTelegram,
-		Sender:     strconv.FormatInt(telegramPayload.Message.From.ID, 10),
-		Recipient:  strconv.FormatInt(telegramPayload.Message.Chat.ID, 10),
-		Content:    content,
-		Timestamp:  telegramPayload.Message.Date,
-		MessageID:  strconv.FormatInt(telegramPayload.Message.MessageID, 10),
-		RawPayload: payload,
-	}, nil
+	return identifiers[0], nil
 }
 
-func (s *webhookService) normalizeWebchatMessage(payload []byte) (*NormalizedMessage, error) {
-	var webchatPayload struct {
-		MessageID string `json:"message_id"`
-		UserID    string `json:"user_id"`
-		SessionID string `json:"session_id"`
-		Text      string `json:"text"`
-		Timestamp int64  `json:"timestamp"`
-	}
-
-	if err := json.Unmarshal(payload, &webchatPayload); err != nil {
-		return nil, fmt.Errorf("failed to parse Webchat payload: %w", err)
+// ExtractChannelIdentifiers obtiene los identificadores de canal de todas las entradas del payload,
+// en orden y sin repetir. Meta agrupa en una misma entrega entradas de distintos números y páginas.
+func (s *webhookService) ExtractChannelIdentifiers(platform domain.Platform, payload []byte) ([]string, error) {
+	var identifiers []string
+	add := func(identifier string) {
+		if identifier == "" {
+			return
+		}
+		for _, existing := range identifiers {
+			if existing == identifier {
+				return
+			}
+		}
+		identifiers = append(identifiers, identifier)
 	}
 
-	content := &domain.MessageContent{
-		Type: "text",
-		Text: webchatPayload.Text,
+	switch platform {
+	case domain.PlatformWhatsApp:
+		var whatsappPayload struct {
+			Entry []struct {
+				Changes []struct {
+					Value struct {
+						Metadata struct {
+							PhoneNumberID string `json:"phone_number_id"`
+						} `json:"metadata"`
+					} `json:"value"`
+				} `json:"changes"`
+			} `json:"entry"`
+		}
+		if err := json.Unmarshal(payload, &whatsappPayload); err != nil {
+			return nil, fmt.Errorf("failed to parse WhatsApp payload: %w", err)
+		}
+		for _, entry := range whatsappPayload.Entry {
+			for _, change := range entry.Changes {
+				add(change.Value.Metadata.PhoneNumberID)
+			}
+		}
+	case domain.PlatformMessenger, domain.PlatformInstagram:
+		var metaPayload struct {
+			Entry []struct {
+				ID        string `json:"id"`
+				Messaging []struct {
+					Recipient struct {
+						ID string `json:"id"`
+					} `json:"recipient"`
+				} `json:"messaging"`
+			} `json:"entry"`
+		}
+		if err := json.Unmarshal(payload, &metaPayload); err != nil {
+			return nil, fmt.Errorf("failed to parse %s payload: %w", platform, err)
+		}
+		for _, entry := range metaPayload.Entry {
+			if entry.ID != "" {
+				add(entry.ID)
+				continue
+			}
+			for _, messaging := range entry.Messaging {
+				add(messaging.Recipient.ID)
+			}
+		}
+	case domain.PlatformWebchat:
+		var webchatPayload struct {
+			WebchatID string `json:"webchat_id"`
+		}
+		if err := json.Unmarshal(payload, &webchatPayload); err != nil {
+			return nil, fmt.Errorf("failed to parse Webchat payload: %w", err)
+		}
+		add(webchatPayload.WebchatID)
+	case domain.PlatformMailchimp:
+		var mailchimpPayload struct {
+			ListID string `json:"list_id"`
+			Data   struct {
+				ListID string `json:"list_id"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(payload, &mailchimpPayload); err != nil {
+			return nil, fmt.Errorf("failed to parse mailchimp payload: %w", err)
+		}
+		add(mailchimpPayload.ListID)
+		if len(identifiers) == 0 {
+			add(mailchimpPayload.Data.ListID)
+		}
+	case domain.PlatformTelegram:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported platform: %s", platform)
 	}
 
-	return &NormalizedMessage{
-		Platform:   domain.PlatformWebchat,
-		Sender:     webchatPayload.UserID,
-		Recipient:  webchatPayload.SessionID,
-		Content:    content,
-		Timestamp:  webchatPayload.Timestamp,
-		MessageID:  webchatPayload.MessageID,
-		RawPayload: payload,
-	}, nil
+	return identifiers, nil
 }
 
 func (s *webhookService) ForwardToMessagingService(ctx context.Context, message *NormalizedMessage) error {
@@ -244,13 +192,19 @@ func (s *webhookService) ForwardToMessagingService(ctx context.Context, message
 
 	// Preparar el payload para el servicio de mensajería
 	payload := map[string]interface{}{
-		"platform":    message.Platform,
-		"sender":      message.Sender,
-		"recipient":   message.Recipient,
-		"content":     message.Content,
-		"timestamp":   message.Timestamp,
-		"message_id":  message.MessageID,
-		"raw_payload": message.RawPayload,
+		"platform":        message.Platform,
+		"sender":          message.Sender,
+		"recipient":       message.Recipient,
+		"content":         message.Content,
+		"timestamp":       message.Timestamp,
+		"message_id":      message.MessageID,
+		"idempotency_key": message.IdempotencyKey,
+		"tenant_id":       message.TenantID,
+		"channel_id":      message.ChannelID,
+		"raw_payload":     message.RawPayload,
+	}
+	if message.Replay != nil {
+		payload["replay"] = message.Replay
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -267,6 +221,12 @@ func (s *webhookService) ForwardToMessagingService(ctx context.Context, message
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "it-integration-service/1.0")
+	if message.IdempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", message.IdempotencyKey)
+	}
+	if message.Replay != nil {
+		req.Header.Set("X-Replay-ID", message.Replay.ReplayID)
+	}
 
 	// Realizar la llamada HTTP
 	client := &http.Client{Timeout: 10 * time.Second}
@@ -357,14 +317,16 @@ func (s *webhookService) normalizeMailchimpMessage(payload []byte) (*NormalizedM
 	// Parsear timestamp
 	timestamp := time.Now().Unix()
 	if mailchimpPayload.FiredAt != "" {
-		if ts, err := time.Parse(time.RFC3339, mailchimpPayload.FiredAt); err == nil {
+		if ts, err := time.Parse(mailchimpFiredAtLayout, mailchimpPayload.FiredAt); err == nil {
+			timestamp = ts.Unix()
+		} else if ts, err := time.Parse(time.RFC3339, mailchimpPayload.FiredAt); err == nil {
 			timestamp = ts.Unix()
 		}
 	}
 
 	return &NormalizedMessage{
 		Platform:  domain.PlatformMailchimp,
-		MessageID: fmt.Sprintf("mailchimp_%s_%d", mailchimpPayload.Type, timestamp),
+		MessageID: mailchimpEventID(mailchimpPayload.Type, mailchimpPayload.ListID, mailchimpPayload.Data, mailchimpPayload.FiredAt),
 		Sender:    sender,
 		Recipient: recipient,
 		Content: &domain.MessageContent{
@@ -375,3 +337,27 @@ func (s *webhookService) normalizeMailchimpMessage(payload []byte) (*NormalizedM
 		RawPayload: payload,
 	}, nil
 }
+
+// mailchimpEventID identifica un evento de Mailchimp, que no trae ID propio, por su tipo, la lista,
+// el suscriptor o la campaña y fired_at. Sin fired_at no se puede reconocer un reenvío, así que el
+// ID es único y el evento no se deduplica.
+func mailchimpEventID(eventType, listID string, data map[string]interface{}, firedAt string) string {
+	if firedAt == "" {
+		return fmt.Sprintf("mailchimp_%s_%s", eventType, uuid.New().String())
+	}
+
+	field := func(keys ...string) string {
+		for _, key := range keys {
+			if value, ok := data[key].(string); ok && value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+	if listID == "" {
+		listID = field("list_id")
+	}
+	subject := field("id", "new_id", "email", "new_email", "campaign_id")
+
+	return strings.Join([]string{"mailchimp", eventType, listID, subject, firedAt}, "_")
+}
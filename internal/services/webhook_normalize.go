@@ -0,0 +1,711 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"it-integration-service/internal/domain"
+)
+
+// Estructuras de los payloads entrantes de cada plataforma, limitadas a los campos que se normalizan
+
+type whatsAppInboundMessage struct {
+	ID        string `json:"id"`
+	From      string `json:"from"`
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Text      struct {
+		Body string `json:"body"`
+	} `json:"text"`
+	Image    *whatsAppMedia `json:"image"`
+	Audio    *whatsAppMedia `json:"audio"`
+	Video    *whatsAppMedia `json:"video"`
+	Document *whatsAppMedia `json:"document"`
+	Sticker  *whatsAppMedia `json:"sticker"`
+	Location *struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Name      string  `json:"name"`
+		Address   string  `json:"address"`
+	} `json:"location"`
+	Contacts []struct {
+		Name struct {
+			FormattedName string `json:"formatted_name"`
+			FirstName     string `json:"first_name"`
+			LastName      string `json:"last_name"`
+		} `json:"name"`
+		Phones []struct {
+			Phone string `json:"phone"`
+			WaID  string `json:"wa_id"`
+		} `json:"phones"`
+		Emails []struct {
+			Email string `json:"email"`
+		} `json:"emails"`
+		Org struct {
+			Company string `json:"company"`
+		} `json:"org"`
+	} `json:"contacts"`
+	Interactive *struct {
+		Type        string `json:"type"`
+		ButtonReply *struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"button_reply"`
+		ListReply *struct {
+			ID          string `json:"id"`
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		} `json:"list_reply"`
+	} `json:"interactive"`
+	Button *struct {
+		Payload string `json:"payload"`
+		Text    string `json:"text"`
+	} `json:"button"`
+	Reaction *struct {
+		MessageID string `json:"message_id"`
+		Emoji     string `json:"emoji"`
+	} `json:"reaction"`
+	Context *struct {
+		ID   string `json:"id"`
+		From string `json:"from"`
+	} `json:"context"`
+}
+
+type whatsAppMedia struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	Caption  string `json:"caption"`
+	Filename string `json:"filename"`
+}
+
+type metaMessagingEvent struct {
+	Sender struct {
+		ID string `json:"id"`
+	} `json:"sender"`
+	Recipient struct {
+		ID string `json:"id"`
+	} `json:"recipient"`
+	Timestamp int64 `json:"timestamp"`
+	Message   *struct {
+		Mid        string `json:"mid"`
+		Text       string `json:"text"`
+		QuickReply *struct {
+			Payload string `json:"payload"`
+		} `json:"quick_reply"`
+		ReplyTo *struct {
+			Mid string `json:"mid"`
+		} `json:"reply_to"`
+		Attachments []struct {
+			Type    string `json:"type"`
+			Payload struct {
+				URL         string `json:"url"`
+				Title       string `json:"title"`
+				StickerID   int64  `json:"sticker_id"`
+				Coordinates *struct {
+					Lat  float64 `json:"lat"`
+					Long float64 `json:"long"`
+				} `json:"coordinates"`
+			} `json:"payload"`
+		} `json:"attachments"`
+	} `json:"message"`
+	Postback *struct {
+		Mid     string `json:"mid"`
+		Title   string `json:"title"`
+		Payload string `json:"payload"`
+	} `json:"postback"`
+	Reaction *struct {
+		Mid    string `json:"mid"`
+		Action string `json:"action"`
+		Emoji  string `json:"emoji"`
+	} `json:"reaction"`
+}
+
+type telegramUpdate struct {
+	UpdateID      int64            `json:"update_id"`
+	Message       *telegramMessage `json:"message"`
+	CallbackQuery *struct {
+		ID      string           `json:"id"`
+		From    telegramUser     `json:"from"`
+		Message *telegramMessage `json:"message"`
+		Data    string           `json:"data"`
+	} `json:"callback_query"`
+	MessageReaction *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		MessageID   int64         `json:"message_id"`
+		User        *telegramUser `json:"user"`
+		Date        int64         `json:"date"`
+		NewReaction []struct {
+			Type  string `json:"type"`
+			Emoji string `json:"emoji"`
+		} `json:"new_reaction"`
+	} `json:"message_reaction"`
+}
+
+type telegramMessage struct {
+	MessageID int64        `json:"message_id"`
+	From      telegramUser `json:"from"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Date     int64          `json:"date"`
+	Text     string         `json:"text"`
+	Caption  string         `json:"caption"`
+	Photo    []telegramFile `json:"photo"`
+	Voice    *telegramFile  `json:"voice"`
+	Audio    *telegramFile  `json:"audio"`
+	Video    *telegramFile  `json:"video"`
+	Document *telegramFile  `json:"document"`
+	Sticker  *telegramFile  `json:"sticker"`
+	Location *struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"location"`
+	Venue *struct {
+		Title   string `json:"title"`
+		Address string `json:"address"`
+	} `json:"venue"`
+	Contact *struct {
+		PhoneNumber string `json:"phone_number"`
+		FirstName   string `json:"first_name"`
+		LastName    string `json:"last_name"`
+		UserID      int64  `json:"user_id"`
+	} `json:"contact"`
+	ReplyToMessage *struct {
+		MessageID int64        `json:"message_id"`
+		From      telegramUser `json:"from"`
+	} `json:"reply_to_message"`
+}
+
+type telegramUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type telegramFile struct {
+	FileID   string `json:"file_id"`
+	MimeType string `json:"mime_type"`
+	FileName string `json:"file_name"`
+	Emoji    string `json:"emoji"`
+}
+
+// whatsAppEntryChanges es una entrada de WhatsApp con sus cambios sin decodificar
+type whatsAppEntryChanges struct {
+	ID      string            `json:"id"`
+	Changes []json.RawMessage `json:"changes"`
+}
+
+// metaWebhookPayload arma el cuerpo de un webhook de Meta con una sola entrada. Meta agrupa en una
+// entrega entradas de distintos canales (y tenants), así que cada mensaje se reenvía solo con la suya.
+func metaWebhookPayload(object string, entry interface{}) json.RawMessage {
+	payload, err := json.Marshal(struct {
+		Object string        `json:"object"`
+		Entry  []interface{} `json:"entry"`
+	}{Object: object, Entry: []interface{}{entry}})
+	if err != nil {
+		return nil
+	}
+	return payload
+}
+
+func (s *webhookService) normalizeWhatsAppMessages(payload []byte) ([]*NormalizedMessage, error) {
+	var whatsappPayload struct {
+		Object string                 `json:"object"`
+		Entry  []whatsAppEntryChanges `json:"entry"`
+	}
+
+	if err := json.Unmarshal(payload, &whatsappPayload); err != nil {
+		return nil, fmt.Errorf("failed to parse WhatsApp payload: %w", err)
+	}
+
+	var messages []*NormalizedMessage
+	for _, entry := range whatsappPayload.Entry {
+		for _, rawChange := range entry.Changes {
+			var change struct {
+				Value struct {
+					Messages []whatsAppInboundMessage `json:"messages"`
+					Metadata struct {
+						PhoneNumberID string `json:"phone_number_id"`
+					} `json:"metadata"`
+				} `json:"value"`
+			}
+			if err := json.Unmarshal(rawChange, &change); err != nil {
+				return nil, fmt.Errorf("failed to parse WhatsApp payload: %w", err)
+			}
+			if len(change.Value.Messages) == 0 {
+				continue
+			}
+
+			// Cada cambio trae los mensajes de un número; el payload reenviado se limita a ese cambio
+			rawPayload := metaWebhookPayload(whatsappPayload.Object, whatsAppEntryChanges{ID: entry.ID, Changes: []json.RawMessage{rawChange}})
+
+			for _, msg := range change.Value.Messages {
+				timestamp, _ := strconv.ParseInt(msg.Timestamp, 10, 64)
+
+				messages = append(messages, &NormalizedMessage{
+					Platform:          domain.PlatformWhatsApp,
+					Sender:            msg.From,
+					Recipient:         change.Value.Metadata.PhoneNumberID,
+					Content:           whatsAppContent(&msg),
+					Timestamp:         timestamp,
+					MessageID:         msg.ID,
+					RawPayload:        rawPayload,
+					channelIdentifier: change.Value.Metadata.PhoneNumberID,
+				})
+			}
+		}
+	}
+
+	return messages, nil
+}
+
+// whatsAppContent convierte un mensaje de la Cloud API en contenido normalizado
+func whatsAppContent(msg *whatsAppInboundMessage) *domain.MessageContent {
+	content := &domain.MessageContent{Type: msg.Type}
+
+	switch msg.Type {
+	case "text":
+		content.Text = msg.Text.Body
+	case "image", "audio", "video", "document", "sticker":
+		media := map[string]*whatsAppMedia{
+			"image":    msg.Image,
+			"audio":    msg.Audio,
+			"video":    msg.Video,
+			"document": msg.Document,
+			"sticker":  msg.Sticker,
+		}[msg.Type]
+		if media != nil {
+			content.Text = media.Caption
+			content.Media = &domain.MediaContent{
+				ID:       media.ID,
+				Type:     msg.Type,
+				Caption:  media.Caption,
+				MimeType: media.MimeType,
+				Filename: media.Filename,
+			}
+		}
+	case "location":
+		if msg.Location != nil {
+			content.Location = &domain.LocationContent{
+				Latitude:  msg.Location.Latitude,
+				Longitude: msg.Location.Longitude,
+				Name:      msg.Location.Name,
+				Address:   msg.Location.Address,
+			}
+		}
+	case "contacts":
+		for _, contact := range msg.Contacts {
+			card := domain.ContactCard{
+				Name:         contact.Name.FormattedName,
+				Organization: contact.Org.Company,
+			}
+			if card.Name == "" {
+				card.Name = strings.TrimSpace(contact.Name.FirstName + " " + contact.Name.LastName)
+			}
+			for _, phone := range contact.Phones {
+				card.Phones = append(card.Phones, phone.Phone)
+				if card.PlatformUserID == "" {
+					card.PlatformUserID = phone.WaID
+				}
+			}
+			for _, email := range contact.Emails {
+				card.Emails = append(card.Emails, email.Email)
+			}
+			content.Contacts = append(content.Contacts, card)
+		}
+	case "interactive":
+		if msg.Interactive != nil {
+			interactive := &domain.InteractiveContent{Type: msg.Interactive.Type}
+			switch {
+			case msg.Interactive.ButtonReply != nil:
+				interactive.ID = msg.Interactive.ButtonReply.ID
+				interactive.Title = msg.Interactive.ButtonReply.Title
+			case msg.Interactive.ListReply != nil:
+				interactive.ID = msg.Interactive.ListReply.ID
+				interactive.Title = msg.Interactive.ListReply.Title
+				interactive.Description = msg.Interactive.ListReply.Description
+			}
+			content.Interactive = interactive
+			content.Text = interactive.Title
+		}
+	case "button":
+		// Respuesta a un botón de quick reply de una plantilla
+		content.Type = domain.ContentTypeInteractive
+		if msg.Button != nil {
+			content.Interactive = &domain.InteractiveContent{
+				Type:  "button",
+				ID:    msg.Button.Payload,
+				Title: msg.Button.Text,
+			}
+			content.Text = msg.Button.Text
+		}
+	case "reaction":
+		if msg.Reaction != nil {
+			content.Reaction = &domain.ReactionContent{
+				MessageID: msg.Reaction.MessageID,
+				Emoji:     msg.Reaction.Emoji,
+			}
+		}
+	default:
+		content.Type = domain.ContentTypeUnsupported
+	}
+
+	if msg.Context != nil && msg.Context.ID != "" {
+		content.ReplyTo = &domain.ReplyReference{
+			MessageID: msg.Context.ID,
+			Sender:    msg.Context.From,
+		}
+	}
+
+	return content
+}
+
+func (s *webhookService) normalizeMessengerMessages(payload []byte) ([]*NormalizedMessage, error) {
+	var messengerPayload struct {
+		Object string            `json:"object"`
+		Entry  []json.RawMessage `json:"entry"`
+	}
+
+	if err := json.Unmarshal(payload, &messengerPayload); err != nil {
+		return nil, fmt.Errorf("failed to parse Messenger payload: %w", err)
+	}
+
+	var messages []*NormalizedMessage
+	for _, rawEntry := range messengerPayload.Entry {
+		var entry struct {
+			ID        string               `json:"id"`
+			Messaging []metaMessagingEvent `json:"messaging"`
+		}
+		if err := json.Unmarshal(rawEntry, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse Messenger payload: %w", err)
+		}
+
+		// Cada entrada es de una página; el payload reenviado se limita a esa entrada
+		rawPayload := metaWebhookPayload(messengerPayload.Object, rawEntry)
+		for _, event := range entry.Messaging {
+			content, messageID := metaMessagingContent(&event)
+			// Las entregas y lecturas se procesan como recibos de estado, no como mensajes
+			if content == nil {
+				continue
+			}
+
+			messages = append(messages, &NormalizedMessage{
+				Platform:          domain.PlatformMessenger,
+				Sender:            event.Sender.ID,
+				Recipient:         event.Recipient.ID,
+				Content:           content,
+				Timestamp:         event.Timestamp,
+				MessageID:         messageID,
+				RawPayload:        rawPayload,
+				channelIdentifier: metaEntryChannel(entry.ID, event.Recipient.ID),
+			})
+		}
+	}
+
+	return messages, nil
+}
+
+func (s *webhookService) normalizeInstagramMessages(payload []byte) ([]*NormalizedMessage, error) {
+	// Instagram usa el mismo formato que Messenger
+	messages, err := s.normalizeMessengerMessages(payload)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		message.Platform = domain.PlatformInstagram
+	}
+	return messages, nil
+}
+
+// metaEntryChannel retorna el identificador del canal de una entrada de Messenger o Instagram:
+// entry.id es la página (o cuenta de Instagram) que recibe el evento y, si falta, el destinatario
+func metaEntryChannel(entryID, recipientID string) string {
+	if entryID != "" {
+		return entryID
+	}
+	return recipientID
+}
+
+// metaMessagingContent convierte un evento de Messenger/Instagram en contenido normalizado
+// junto con el ID del mensaje; retorna nil si el evento no representa un mensaje del usuario
+func metaMessagingContent(event *metaMessagingEvent) (*domain.MessageContent, string) {
+	switch {
+	case event.Message != nil:
+		msg := event.Message
+		content := &domain.MessageContent{Type: domain.ContentTypeText, Text: msg.Text}
+
+		for _, attachment := range msg.Attachments {
+			switch attachment.Type {
+			case "location":
+				content.Type = domain.ContentTypeLocation
+				if coordinates := attachment.Payload.Coordinates; coordinates != nil {
+					content.Location = &domain.LocationContent{
+						Latitude:  coordinates.Lat,
+						Longitude: coordinates.Long,
+						Name:      attachment.Payload.Title,
+					}
+				}
+				continue
+			}
+
+			media := domain.MediaContent{Type: metaAttachmentType(attachment.Type), URL: attachment.Payload.URL}
+			if attachment.Payload.StickerID != 0 {
+				media.Type = domain.ContentTypeSticker
+				media.ID = strconv.FormatInt(attachment.Payload.StickerID, 10)
+			}
+			content.Attachments = append(content.Attachments, media)
+		}
+
+		// Media es el primer adjunto; Attachments solo se conserva cuando hay varios
+		if len(content.Attachments) > 0 {
+			media := content.Attachments[0]
+			content.Media = &media
+			content.Type = media.Type
+			if len(content.Attachments) == 1 {
+				content.Attachments = nil
+			}
+		}
+
+		if msg.QuickReply != nil {
+			content.Type = domain.ContentTypeInteractive
+			content.Interactive = &domain.InteractiveContent{
+				Type:  "quick_reply",
+				ID:    msg.QuickReply.Payload,
+				Title: msg.Text,
+			}
+		}
+
+		if msg.ReplyTo != nil && msg.ReplyTo.Mid != "" {
+			content.ReplyTo = &domain.ReplyReference{MessageID: msg.ReplyTo.Mid}
+		}
+
+		return content, msg.Mid
+	case event.Postback != nil:
+		return &domain.MessageContent{
+			Type: domain.ContentTypeInteractive,
+			Text: event.Postback.Title,
+			Interactive: &domain.InteractiveContent{
+				Type:  "postback",
+				ID:    event.Postback.Payload,
+				Title: event.Postback.Title,
+			},
+		}, event.Postback.Mid
+	case event.Reaction != nil:
+		reaction := &domain.ReactionContent{MessageID: event.Reaction.Mid}
+		if event.Reaction.Action != "unreact" {
+			reaction.Emoji = event.Reaction.Emoji
+		}
+		return &domain.MessageContent{Type: domain.ContentTypeReaction, Reaction: reaction}, metaReactionID(event)
+	default:
+		return nil, ""
+	}
+}
+
+// metaReactionID arma un ID estable para una reacción, que Meta envía sin mid propio: el mensaje
+// reaccionado, quien reacciona y la acción con su emoji. Un reenvío del webhook repite el mismo
+// timestamp, que distingue volver a poner la misma reacción después de quitarla.
+func metaReactionID(event *metaMessagingEvent) string {
+	parts := []string{"reaction", event.Reaction.Mid, event.Sender.ID, event.Reaction.Action}
+	if event.Reaction.Action != "unreact" {
+		parts = append(parts, event.Reaction.Emoji)
+	}
+	parts = append(parts, strconv.FormatInt(event.Timestamp, 10))
+	return strings.Join(parts, ":")
+}
+
+// metaAttachmentType traduce los tipos de adjunto de Meta a los tipos normalizados
+func metaAttachmentType(attachmentType string) string {
+	switch attachmentType {
+	case "image", "audio", "video":
+		return attachmentType
+	case "file":
+		return domain.ContentTypeDocument
+	default:
+		return domain.ContentTypeUnsupported
+	}
+}
+
+func (s *webhookService) normalizeTelegramMessage(payload []byte) (*NormalizedMessage, error) {
+	var update telegramUpdate
+	if err := json.Unmarshal(payload, &update); err != nil {
+		return nil, fmt.Errorf("failed to parse Telegram payload: %w", err)
+	}
+
+	switch {
+	case update.Message != nil:
+		msg := update.Message
+		return &NormalizedMessage{
+			Platform:   domain.PlatformTelegram,
+			Sender:     strconv.FormatInt(msg.From.ID, 10),
+			Recipient:  strconv.FormatInt(msg.Chat.ID, 10),
+			Content:    telegramContent(msg),
+			Timestamp:  msg.Date,
+			MessageID:  strconv.FormatInt(msg.MessageID, 10),
+			RawPayload: payload,
+		}, nil
+	case update.CallbackQuery != nil:
+		query := update.CallbackQuery
+		normalized := &NormalizedMessage{
+			Platform: domain.PlatformTelegram,
+			Sender:   strconv.FormatInt(query.From.ID, 10),
+			Content: &domain.MessageContent{
+				Type: domain.ContentTypeInteractive,
+				Interactive: &domain.InteractiveContent{
+					Type: "callback_query",
+					ID:   query.Data,
+				},
+			},
+			MessageID:  "callback_" + query.ID,
+			RawPayload: payload,
+		}
+		if query.Message != nil {
+			normalized.Recipient = strconv.FormatInt(query.Message.Chat.ID, 10)
+			normalized.Timestamp = query.Message.Date
+			normalized.Content.ReplyTo = &domain.ReplyReference{
+				MessageID: strconv.FormatInt(query.Message.MessageID, 10),
+			}
+		}
+		return normalized, nil
+	case update.MessageReaction != nil:
+		reaction := update.MessageReaction
+		content := &domain.MessageContent{
+			Type:     domain.ContentTypeReaction,
+			Reaction: &domain.ReactionContent{MessageID: strconv.FormatInt(reaction.MessageID, 10)},
+		}
+		// Telegram envía la lista completa de reacciones vigentes; vacía significa que se retiraron
+		for _, newReaction := range reaction.NewReaction {
+			if newReaction.Type == "emoji" {
+				content.Reaction.Emoji = newReaction.Emoji
+				break
+			}
+		}
+
+		normalized := &NormalizedMessage{
+			Platform:   domain.PlatformTelegram,
+			Recipient:  strconv.FormatInt(reaction.Chat.ID, 10),
+			Content:    content,
+			Timestamp:  reaction.Date,
+			MessageID:  fmt.Sprintf("reaction_%d", update.UpdateID),
+			RawPayload: payload,
+		}
+		if reaction.User != nil {
+			normalized.Sender = strconv.FormatInt(reaction.User.ID, 10)
+		}
+		return normalized, nil
+	default:
+		return &NormalizedMessage{
+			Platform:   domain.PlatformTelegram,
+			Content:    &domain.MessageContent{Type: domain.ContentTypeUnsupported},
+			MessageID:  fmt.Sprintf("update_%d", update.UpdateID),
+			RawPayload: payload,
+		}, nil
+	}
+}
+
+// telegramContent convierte un mensaje de Telegram en contenido normalizado
+func telegramContent(msg *telegramMessage) *domain.MessageContent {
+	content := &domain.MessageContent{Type: domain.ContentTypeText, Text: msg.Text}
+
+	setMedia := func(contentType string, file *telegramFile) {
+		content.Type = contentType
+		content.Text = msg.Caption
+		content.Media = &domain.MediaContent{
+			ID:       file.FileID,
+			Type:     contentType,
+			Caption:  msg.Caption,
+			MimeType: file.MimeType,
+			Filename: file.FileName,
+		}
+	}
+
+	switch {
+	case len(msg.Photo) > 0:
+		// Telegram envía varias resoluciones de la foto, la última es la más grande
+		setMedia(domain.ContentTypeImage, &msg.Photo[len(msg.Photo)-1])
+	case msg.Voice != nil:
+		setMedia(domain.ContentTypeAudio, msg.Voice)
+	case msg.Audio != nil:
+		setMedia(domain.ContentTypeAudio, msg.Audio)
+	case msg.Video != nil:
+		setMedia(domain.ContentTypeVideo, msg.Video)
+	case msg.Document != nil:
+		setMedia(domain.ContentTypeDocument, msg.Document)
+	case msg.Sticker != nil:
+		setMedia(domain.ContentTypeSticker, msg.Sticker)
+		content.Text = msg.Sticker.Emoji
+	case msg.Location != nil:
+		content.Type = domain.ContentTypeLocation
+		content.Location = &domain.LocationContent{
+			Latitude:  msg.Location.Latitude,
+			Longitude: msg.Location.Longitude,
+		}
+		if msg.Venue != nil {
+			content.Location.Name = msg.Venue.Title
+			content.Location.Address = msg.Venue.Address
+		}
+	case msg.Contact != nil:
+		content.Type = domain.ContentTypeContacts
+		card := domain.ContactCard{
+			Name:   strings.TrimSpace(msg.Contact.FirstName + " " + msg.Contact.LastName),
+			Phones: []string{msg.Contact.PhoneNumber},
+		}
+		if msg.Contact.UserID != 0 {
+			card.PlatformUserID = strconv.FormatInt(msg.Contact.UserID, 10)
+		}
+		content.Contacts = []domain.ContactCard{card}
+	}
+
+	if msg.ReplyToMessage != nil {
+		content.ReplyTo = &domain.ReplyReference{
+			MessageID: strconv.FormatInt(msg.ReplyToMessage.MessageID, 10),
+			Sender:    strconv.FormatInt(msg.ReplyToMessage.From.ID, 10),
+		}
+	}
+
+	return content
+}
+
+func (s *webhookService) normalizeWebchatMessage(payload []byte) (*NormalizedMessage, error) {
+	var webchatPayload struct {
+		MessageID string                  `json:"message_id"`
+		UserID    string                  `json:"user_id"`
+		SessionID string                  `json:"session_id"`
+		Type      string                  `json:"type"`
+		Text      string                  `json:"text"`
+		Media     *domain.MediaContent    `json:"media"`
+		Location  *domain.LocationContent `json:"location"`
+		ReplyTo   string                  `json:"reply_to"`
+		Timestamp int64                   `json:"timestamp"`
+	}
+
+	if err := json.Unmarshal(payload, &webchatPayload); err != nil {
+		return nil, fmt.Errorf("failed to parse Webchat payload: %w", err)
+	}
+
+	// El widget ya envía el contenido con la forma normalizada
+	content := &domain.MessageContent{
+		Type:     webchatPayload.Type,
+		Text:     webchatPayload.Text,
+		Media:    webchatPayload.Media,
+		Location: webchatPayload.Location,
+	}
+	if content.Type == "" {
+		content.Type = domain.ContentTypeText
+	}
+	if webchatPayload.ReplyTo != "" {
+		content.ReplyTo = &domain.ReplyReference{MessageID: webchatPayload.ReplyTo}
+	}
+
+	return &NormalizedMessage{
+		Platform:   domain.PlatformWebchat,
+		Sender:     webchatPayload.UserID,
+		Recipient:  webchatPayload.SessionID,
+		Content:    content,
+		Timestamp:  webchatPayload.Timestamp,
+		MessageID:  webchatPayload.MessageID,
+		RawPayload: payload,
+	}, nil
+}
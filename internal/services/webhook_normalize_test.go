@@ -0,0 +1,62 @@
+package services
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"it-integration-service/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "regenerar los archivos golden de testdata/webhooks")
+
+// TestNormalizeMessageGolden normaliza cada payload de ejemplo de testdata/webhooks y
+// compara el resultado con su archivo .golden. El prefijo del archivo indica la plataforma.
+func TestNormalizeMessageGolden(t *testing.T) {
+	payloads, err := filepath.Glob(filepath.Join("testdata", "webhooks", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, payloads)
+
+	service := &webhookService{}
+
+	for _, payloadPath := range payloads {
+		if strings.HasSuffix(payloadPath, ".golden.json") {
+			continue
+		}
+
+		name := strings.TrimSuffix(filepath.Base(payloadPath), ".json")
+		platform := domain.Platform(strings.SplitN(name, "_", 2)[0])
+
+		t.Run(name, func(t *testing.T) {
+			payload, err := os.ReadFile(payloadPath)
+			require.NoError(t, err)
+
+			messages, err := service.NormalizeMessage(platform, payload)
+			require.NoError(t, err)
+
+			// El payload crudo ya está en el archivo de entrada, no se repite en el golden
+			for _, message := range messages {
+				message.RawPayload = nil
+			}
+
+			got, err := json.MarshalIndent(messages, "", "  ")
+			require.NoError(t, err)
+			got = append(got, '\n')
+
+			goldenPath := strings.TrimSuffix(payloadPath, ".json") + ".golden.json"
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(goldenPath, got, 0o644))
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			require.NoError(t, err, "regenerate with: go test ./internal/services -run TestNormalizeMessageGolden -args -update")
+			assert.JSONEq(t, string(want), string(got))
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+)
+
+// webhookReplayBatchSize es la cantidad de mensajes entrantes leídos por consulta
+const webhookReplayBatchSize = 100
+
+var (
+	// ErrReplayRunning indica que ya hay una reproducción en curso
+	ErrReplayRunning = errors.New("webhook replay is already running")
+	// ErrInvalidReplayRequest indica que la solicitud de reproducción no es válida
+	ErrInvalidReplayRequest = errors.New("invalid webhook replay request")
+)
+
+// WebhookReplayService vuelve a normalizar los payloads guardados en inbound_messages y los
+// reenvía al servicio de mensajería, por ejemplo después de corregir un error de normalización o
+// de una caída del servicio de mensajería. Los mensajes reenviados llevan la marca de la reproducción.
+type WebhookReplayService struct {
+	inboundRepo    domain.InboundMessageRepository
+	webhookService WebhookService
+	config         config.ReplayConfig
+	logger         logger.Logger
+
+	mu      sync.Mutex
+	baseCtx context.Context
+	report  domain.WebhookReplayReport
+}
+
+// NewWebhookReplayService crea una nueva instancia del servicio de reproducción de webhooks
+func NewWebhookReplayService(inboundRepo domain.InboundMessageRepository, webhookService WebhookService, cfg config.ReplayConfig, logger logger.Logger) *WebhookReplayService {
+	return &WebhookReplayService{
+		inboundRepo:    inboundRepo,
+		webhookService: webhookService,
+		config:         cfg,
+		logger:         logger,
+		baseCtx:        context.Background(),
+		report:         domain.WebhookReplayReport{State: domain.WebhookReplayIdle},
+	}
+}
+
+// Start asocia las reproducciones disparadas por API al contexto de los procesos en segundo
+// plano; al cancelarse se detiene la reproducción en curso
+func (s *WebhookReplayService) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.baseCtx = ctx
+	s.mu.Unlock()
+}
+
+// Trigger inicia una reproducción en segundo plano y retorna su estado inicial
+func (s *WebhookReplayService) Trigger(req domain.WebhookReplayRequest) (domain.WebhookReplayReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report.State == domain.WebhookReplayRunning {
+		return s.snapshot(), ErrReplayRunning
+	}
+
+	req, err := s.prepare(req)
+	if err != nil {
+		return domain.WebhookReplayReport{}, err
+	}
+
+	replayID := uuid.New().String()
+	now := time.Now()
+	s.report = domain.WebhookReplayReport{
+		ReplayID:  replayID,
+		State:     domain.WebhookReplayRunning,
+		Request:   req,
+		Items:     []domain.WebhookReplayItem{},
+		StartedAt: &now,
+	}
+
+	go func(ctx context.Context) {
+		s.execute(ctx, replayID, req, func(report *domain.WebhookReplayReport) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.report = *report
+			s.report.Items = append([]domain.WebhookReplayItem{}, report.Items...)
+		})
+	}(s.baseCtx)
+
+	return s.snapshot(), nil
+}
+
+// Status retorna el avance de la última reproducción disparada por API
+func (s *WebhookReplayService) Status() domain.WebhookReplayReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot()
+}
+
+// Run ejecuta una reproducción y espera a que termine; onProgress, si no es nil, recibe el
+// reporte después de cada mensaje entrante
+func (s *WebhookReplayService) Run(ctx context.Context, req domain.WebhookReplayRequest, onProgress func(*domain.WebhookReplayReport)) (*domain.WebhookReplayReport, error) {
+	req, err := s.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.execute(ctx, uuid.New().String(), req, onProgress), nil
+}
+
+// prepare valida la solicitud y completa el límite y la tasa con la configuración
+func (s *WebhookReplayService) prepare(req domain.WebhookReplayRequest) (domain.WebhookReplayRequest, error) {
+	if req.Platform == "" && len(req.IDs) == 0 && req.From == nil && req.To == nil && req.Processed == nil {
+		return req, fmt.Errorf("%w: at least one of platform, ids, from, to or processed is required", ErrInvalidReplayRequest)
+	}
+	if req.From != nil && req.To != nil && !req.To.After(*req.From) {
+		return req, fmt.Errorf("%w: to must be after from", ErrInvalidReplayRequest)
+	}
+	if req.RatePerSecond < 0 {
+		return req, fmt.Errorf("%w: rate_per_second must be positive", ErrInvalidReplayRequest)
+	}
+
+	if req.Limit <= 0 || (s.config.MaxMessages > 0 && req.Limit > s.config.MaxMessages) {
+		req.Limit = s.config.MaxMessages
+	}
+	if req.RatePerSecond == 0 {
+		req.RatePerSecond = s.config.RatePerSecond
+	}
+	return req, nil
+}
+
+// execute recorre los mensajes entrantes seleccionados en orden de recepción y los reproduce
+// respetando la tasa de la solicitud
+func (s *WebhookReplayService) execute(ctx context.Context, replayID string, req domain.WebhookReplayRequest, onProgress func(*domain.WebhookReplayReport)) *domain.WebhookReplayReport {
+	startedAt := time.Now()
+	report := &domain.WebhookReplayReport{
+		ReplayID:  replayID,
+		State:     domain.WebhookReplayRunning,
+		Request:   req,
+		Items:     []domain.WebhookReplayItem{},
+		StartedAt: &startedAt,
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if req.RatePerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(req.RatePerSecond), 1)
+	}
+
+	filter := domain.InboundMessageFilter{
+		Platform:  req.Platform,
+		IDs:       req.IDs,
+		From:      req.From,
+		To:        req.To,
+		Processed: req.Processed,
+	}
+
+	var runErr error
+	for req.Limit <= 0 || report.Selected < req.Limit {
+		filter.Limit = webhookReplayBatchSize
+		if req.Limit > 0 && req.Limit-report.Selected < filter.Limit {
+			filter.Limit = req.Limit - report.Selected
+		}
+
+		messages, err := s.inboundRepo.List(ctx, filter)
+		if err != nil {
+			runErr = err
+			break
+		}
+
+		for _, inbound := range messages {
+			if ctx.Err() != nil {
+				break
+			}
+			item := s.replayMessage(ctx, limiter, replayID, req.DryRun, inbound)
+			report.Selected++
+			switch item.Status {
+			case domain.WebhookReplayItemReplayed, domain.WebhookReplayItemWouldReplay:
+				report.Replayed++
+			case domain.WebhookReplayItemSkipped:
+				report.Skipped++
+			default:
+				report.Failed++
+			}
+			report.MessagesForwarded += item.Forwarded
+			report.Items = append(report.Items, item)
+
+			if onProgress != nil {
+				onProgress(report)
+			}
+		}
+
+		if ctx.Err() != nil {
+			runErr = ctx.Err()
+			break
+		}
+		if len(messages) < filter.Limit {
+			break
+		}
+		last := messages[len(messages)-1]
+		filter.AfterReceivedAt = &last.ReceivedAt
+		filter.AfterID = last.ID
+	}
+
+	completedAt := time.Now()
+	report.CompletedAt = &completedAt
+	report.State = domain.WebhookReplayCompleted
+	if runErr != nil {
+		report.State = domain.WebhookReplayFailed
+		report.Error = runErr.Error()
+		s.logger.Error("Webhook replay failed", map[string]interface{}{
+			"replay_id": replayID,
+			"selected":  report.Selected,
+			"error":     runErr.Error(),
+		})
+	} else {
+		s.logger.Info("Webhook replay completed", map[string]interface{}{
+			"replay_id":          replayID,
+			"dry_run":            req.DryRun,
+			"selected":           report.Selected,
+			"replayed":           report.Replayed,
+			"skipped":            report.Skipped,
+			"failed":             report.Failed,
+			"messages_forwarded": report.MessagesForwarded,
+		})
+	}
+
+	if onProgress != nil {
+		onProgress(report)
+	}
+	return report
+}
+
+// replayMessage normaliza un mensaje entrante y reenvía sus mensajes con la misma idempotency
+// key del webhook original; los recibos de estado no se reproducen
+func (s *WebhookReplayService) replayMessage(ctx context.Context, limiter *rate.Limiter, replayID string, dryRun bool, inbound *domain.InboundMessage) domain.WebhookReplayItem {
+	item := domain.WebhookReplayItem{
+		InboundMessageID: inbound.ID,
+		Platform:         inbound.Platform,
+		ReceivedAt:       inbound.ReceivedAt,
+	}
+
+	if inbound.QuarantineReason != "" {
+		item.Status = domain.WebhookReplayItemSkipped
+		item.Error = "quarantined: " + inbound.QuarantineReason
+		return item
+	}
+
+	normalizedMessages, err := s.webhookService.NormalizeMessage(inbound.Platform, inbound.Payload)
+	if err != nil {
+		item.Status = domain.WebhookReplayItemFailed
+		item.Error = fmt.Sprintf("failed to normalize message: %v", err)
+		return item
+	}
+	item.Messages = len(normalizedMessages)
+	if item.Messages == 0 {
+		item.Status = domain.WebhookReplayItemSkipped
+		item.Error = "no messages found in payload"
+		return item
+	}
+	if dryRun {
+		item.Status = domain.WebhookReplayItemWouldReplay
+		return item
+	}
+
+	var delivered []*NormalizedMessage
+	var lastErr error
+	for i, message := range normalizedMessages {
+		message.TenantID = inbound.TenantID
+		message.ChannelID = inbound.ChannelID
+		// Un webhook de Meta puede agrupar mensajes de varios canales: vale el canal de cada resultado
+		if i < len(inbound.Results) && inbound.Results[i].EventType == domain.EventTypeMessage {
+			result := inbound.Results[i]
+			if result.Status == domain.InboundResultUnresolved {
+				continue
+			}
+			if result.ChannelID != "" {
+				message.TenantID = result.TenantID
+				message.ChannelID = result.ChannelID
+			}
+		}
+		// Los mensajes van primero entre los eventos del webhook, su posición coincide con la original
+		message.IdempotencyKey = idempotencyKey(inbound.Platform, message.MessageID, inbound.ID, i)
+		message.Replay = &ReplayInfo{
+			ReplayID:           replayID,
+			InboundMessageID:   inbound.ID,
+			OriginalReceivedAt: inbound.ReceivedAt,
+		}
+
+		if err := limiter.Wait(ctx); err != nil {
+			lastErr = err
+			break
+		}
+		if err := s.webhookService.ForwardToMessagingService(ctx, message); err != nil {
+			lastErr = err
+			continue
+		}
+		delivered = append(delivered, message)
+	}
+
+	item.Forwarded = len(delivered)
+	item.Status = domain.WebhookReplayItemReplayed
+	if lastErr != nil {
+		item.Status = domain.WebhookReplayItemFailed
+		item.Error = lastErr.Error()
+	}
+
+	s.recordDelivery(ctx, inbound, delivered)
+	return item
+}
+
+// recordDelivery marca como entregados en el registro entrante los mensajes reenviados; el
+// registro queda procesado cuando todos sus eventos están entregados
+func (s *WebhookReplayService) recordDelivery(ctx context.Context, inbound *domain.InboundMessage, delivered []*NormalizedMessage) {
+	if len(delivered) == 0 {
+		return
+	}
+
+	now := time.Now()
+	results := append([]domain.InboundResult(nil), inbound.Results...)
+	for _, message := range delivered {
+		index := -1
+		for i := range results {
+			if results[i].EventType == domain.EventTypeMessage && results[i].IdempotencyKey == message.IdempotencyKey {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			// Un webhook que no se pudo normalizar al recibirlo no tiene resultados
+			results = append(results, domain.InboundResult{
+				Index:          len(results),
+				EventType:      domain.EventTypeMessage,
+				MessageID:      message.MessageID,
+				Sender:         message.Sender,
+				IdempotencyKey: message.IdempotencyKey,
+			})
+			index = len(results) - 1
+		}
+
+		results[index].Status = domain.InboundResultDelivered
+		results[index].Error = ""
+		results[index].Attempts++
+		results[index].UpdatedAt = now
+	}
+
+	processed := true
+	for _, result := range results {
+		if result.Status != domain.InboundResultDelivered && result.Status != domain.InboundResultDuplicate {
+			processed = false
+			break
+		}
+	}
+
+	if err := s.inboundRepo.UpdateResults(ctx, inbound.ID, results, processed); err != nil {
+		s.logger.Error("Failed to update replayed inbound message results", map[string]interface{}{
+			"inbound_message_id": inbound.ID,
+			"error":              err.Error(),
+		})
+	}
+}
+
+// snapshot copia el reporte para que quien llama no comparta el slice de resultados
+func (s *WebhookReplayService) snapshot() domain.WebhookReplayReport {
+	report := s.report
+	report.Items = append([]domain.WebhookReplayItem{}, s.report.Items...)
+	return report
+}
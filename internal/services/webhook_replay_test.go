@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryInboundRepository filtra los mensajes entrantes en memoria como la consulta de List
+type memoryInboundRepository struct {
+	domain.InboundMessageRepository
+	messages []*domain.InboundMessage
+	updated  map[string][]domain.InboundResult
+}
+
+func (r *memoryInboundRepository) List(ctx context.Context, filter domain.InboundMessageFilter) ([]*domain.InboundMessage, error) {
+	sorted := append([]*domain.InboundMessage(nil), r.messages...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].ReceivedAt.Equal(sorted[j].ReceivedAt) {
+			return (sorted[i].ID < sorted[j].ID) != filter.Descending
+		}
+		return sorted[i].ReceivedAt.Before(sorted[j].ReceivedAt) != filter.Descending
+	})
+
+	var result []*domain.InboundMessage
+	for _, message := range sorted {
+		if filter.Platform != "" && message.Platform != filter.Platform {
+			continue
+		}
+		if filter.TenantID != "" && message.TenantID != filter.TenantID {
+			continue
+		}
+		if filter.Sender != "" && !hasSender(message, filter.Sender) {
+			continue
+		}
+		if filter.Processed != nil && message.Processed != *filter.Processed {
+			continue
+		}
+		if filter.From != nil && message.ReceivedAt.Before(*filter.From) {
+			continue
+		}
+		if filter.AfterReceivedAt != nil && !afterCursor(message, *filter.AfterReceivedAt, filter.AfterID, filter.Descending) {
+			continue
+		}
+		copied := *message
+		result = append(result, &copied)
+		if len(result) == filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func hasSender(message *domain.InboundMessage, sender string) bool {
+	for _, result := range message.Results {
+		if result.Sender == sender {
+			return true
+		}
+	}
+	return false
+}
+
+// afterCursor compara por fecha de recepción e ID como la condición (received_at, id) de List
+func afterCursor(message *domain.InboundMessage, receivedAt time.Time, id string, descending bool) bool {
+	if message.ReceivedAt.Equal(receivedAt) {
+		return (message.ID > id) != descending && message.ID != id
+	}
+	return message.ReceivedAt.After(receivedAt) != descending
+}
+
+func (r *memoryInboundRepository) UpdateResults(ctx context.Context, id string, results []domain.InboundResult, processed bool) error {
+	r.updated[id] = results
+	for _, message := range r.messages {
+		if message.ID == id {
+			message.Processed = processed
+			message.Results = results
+		}
+	}
+	return nil
+}
+
+// messagingRecorder registra los mensajes recibidos por el servicio de mensajería
+type messagingRecorder struct {
+	mu       sync.Mutex
+	bodies   []map[string]interface{}
+	replayID []string
+	fail     bool
+}
+
+func (m *messagingRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	m.bodies = append(m.bodies, body)
+	m.replayID = append(m.replayID, r.Header.Get("X-Replay-ID"))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func telegramTextPayload(updateID, messageID int) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"update_id":%d,"message":{"message_id":%d,"from":{"id":42,"first_name":"Ana"},"chat":{"id":42,"type":"private"},"date":1767225600,"text":"hola"}}`, updateID, messageID))
+}
+
+func newTestReplayService(t *testing.T, messages ...*domain.InboundMessage) (*WebhookReplayService, *memoryInboundRepository, *messagingRecorder) {
+	recorder := &messagingRecorder{}
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+
+	repo := &memoryInboundRepository{messages: messages, updated: make(map[string][]domain.InboundResult)}
+	log := logger.NewLogger("error")
+	service := NewWebhookReplayService(repo, NewWebhookService(server.URL, log), config.ReplayConfig{RatePerSecond: 1000, MaxMessages: 100}, log)
+	return service, repo, recorder
+}
+
+func TestWebhookReplayForwardsMarkedMessages(t *testing.T) {
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	failed := &domain.InboundMessage{
+		ID:         "inbound-1",
+		Platform:   domain.PlatformTelegram,
+		TenantID:   "tenant-1",
+		ChannelID:  "channel-1",
+		Payload:    telegramTextPayload(1, 10),
+		ReceivedAt: receivedAt,
+		Results: []domain.InboundResult{{
+			Index: 0, EventType: domain.EventTypeMessage, MessageID: "10",
+			IdempotencyKey: "telegram:10", Status: domain.InboundResultFailed, Attempts: 1,
+		}},
+	}
+	quarantined := &domain.InboundMessage{
+		ID:               "inbound-2",
+		Platform:         domain.PlatformTelegram,
+		Payload:          telegramTextPayload(2, 11),
+		ReceivedAt:       receivedAt.Add(time.Minute),
+		QuarantineReason: "channel_not_found",
+	}
+	other := &domain.InboundMessage{ID: "inbound-3", Platform: domain.PlatformWhatsApp, Payload: json.RawMessage(`{}`), ReceivedAt: receivedAt}
+
+	service, repo, recorder := newTestReplayService(t, failed, quarantined, other)
+	processed := false
+	report, err := service.Run(context.Background(), domain.WebhookReplayRequest{Platform: domain.PlatformTelegram, Processed: &processed}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, domain.WebhookReplayCompleted, report.State)
+	assert.Equal(t, 2, report.Selected)
+	assert.Equal(t, 1, report.Replayed)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 1, report.MessagesForwarded)
+	require.Len(t, report.Items, 2)
+	assert.Equal(t, domain.WebhookReplayItemReplayed, report.Items[0].Status)
+	assert.Equal(t, "quarantined: channel_not_found", report.Items[1].Error)
+
+	// El mensaje llega con la idempotency key original y la marca de la reproducción
+	require.Len(t, recorder.bodies, 1)
+	body := recorder.bodies[0]
+	assert.Equal(t, "telegram:10", body["idempotency_key"])
+	assert.Equal(t, "channel-1", body["channel_id"])
+	assert.Equal(t, report.ReplayID, recorder.replayID[0])
+	replay := body["replay"].(map[string]interface{})
+	assert.Equal(t, report.ReplayID, replay["replay_id"])
+	assert.Equal(t, "inbound-1", replay["inbound_message_id"])
+
+	// El registro entrante queda procesado
+	require.Contains(t, repo.updated, "inbound-1")
+	assert.Equal(t, domain.InboundResultDelivered, repo.updated["inbound-1"][0].Status)
+	assert.Equal(t, 2, repo.updated["inbound-1"][0].Attempts)
+	assert.True(t, failed.Processed)
+}
+
+func TestWebhookReplayDryRunAndFailures(t *testing.T) {
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var messages []*domain.InboundMessage
+	for i := 0; i < 3; i++ {
+		messages = append(messages, &domain.InboundMessage{
+			ID:         fmt.Sprintf("inbound-%d", i),
+			Platform:   domain.PlatformTelegram,
+			Payload:    telegramTextPayload(i, 100+i),
+			ReceivedAt: receivedAt.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	service, repo, recorder := newTestReplayService(t, messages...)
+
+	report, err := service.Run(context.Background(), domain.WebhookReplayRequest{Platform: domain.PlatformTelegram, DryRun: true, Limit: 2}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Selected)
+	assert.Equal(t, 2, report.Replayed)
+	assert.Equal(t, domain.WebhookReplayItemWouldReplay, report.Items[0].Status)
+	assert.Empty(t, recorder.bodies)
+	assert.Empty(t, repo.updated)
+
+	// Un reenvío fallido queda en el reporte y el registro entrante sin cambios
+	recorder.fail = true
+	report, err = service.Run(context.Background(), domain.WebhookReplayRequest{IDs: []string{"inbound-0"}, Platform: domain.PlatformTelegram, Limit: 1}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Failed)
+	assert.Contains(t, report.Items[0].Error, "status 503")
+	assert.Empty(t, repo.updated)
+}
+
+func TestWebhookReplayValidatesRequest(t *testing.T) {
+	service, _, _ := newTestReplayService(t)
+
+	_, err := service.Trigger(domain.WebhookReplayRequest{})
+	assert.ErrorIs(t, err, ErrInvalidReplayRequest)
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+	_, err = service.Run(context.Background(), domain.WebhookReplayRequest{From: &from, To: &to}, nil)
+	assert.ErrorIs(t, err, ErrInvalidReplayRequest)
+
+	// El límite se recorta al máximo configurado y la tasa toma el valor por defecto
+	req, err := service.prepare(domain.WebhookReplayRequest{Platform: domain.PlatformTelegram, Limit: 5000})
+	require.NoError(t, err)
+	assert.Equal(t, 100, req.Limit)
+	assert.Equal(t, float64(1000), req.RatePerSecond)
+}
+
+func TestWebhookReplayForwardsOnlyTheEntryOfEachTenant(t *testing.T) {
+	batch := &domain.InboundMessage{
+		ID:         "inbound-batch",
+		Platform:   domain.PlatformWhatsApp,
+		Payload:    whatsappBatchPayload(batchEntry{channel: "phone-a", messages: []string{"wamid.a1"}}, batchEntry{channel: "phone-b", messages: []string{"wamid.b1"}}),
+		ReceivedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Results: []domain.InboundResult{
+			{Index: 0, EventType: domain.EventTypeMessage, MessageID: "wamid.a1", TenantID: "tenant-a", ChannelID: "channel-a", Status: domain.InboundResultFailed},
+			{Index: 1, EventType: domain.EventTypeMessage, MessageID: "wamid.b1", TenantID: "tenant-b", ChannelID: "channel-b", Status: domain.InboundResultFailed},
+		},
+	}
+
+	service, _, recorder := newTestReplayService(t, batch)
+	processed := false
+	report, err := service.Run(context.Background(), domain.WebhookReplayRequest{Platform: domain.PlatformWhatsApp, Processed: &processed}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.MessagesForwarded)
+
+	require.Len(t, recorder.bodies, 2)
+	own := map[string][]string{"tenant-a": {"phone-a", "wamid.a1"}, "tenant-b": {"phone-b", "wamid.b1"}}
+	for _, body := range recorder.bodies {
+		rawPayload, err := json.Marshal(body["raw_payload"])
+		require.NoError(t, err)
+		assertScopedRawPayload(t, own, body["tenant_id"].(string), rawPayload)
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"it-integration-service/internal/domain"
+)
+
+// ExtractStatusEvents obtiene los recibos de entrega y lectura de un webhook. WhatsApp los envía
+// en statuses[]; Messenger e Instagram como eventos delivery/read dentro de messaging[].
+func (s *webhookService) ExtractStatusEvents(platform domain.Platform, payload []byte) ([]*StatusEvent, error) {
+	switch platform {
+	case domain.PlatformWhatsApp:
+		return s.extractWhatsAppStatuses(payload)
+	case domain.PlatformMessenger, domain.PlatformInstagram:
+		return s.extractMetaMessagingStatuses(platform, payload)
+	default:
+		return nil, nil
+	}
+}
+
+func (s *webhookService) extractWhatsAppStatuses(payload []byte) ([]*StatusEvent, error) {
+	var whatsappPayload struct {
+		Entry []struct {
+			Changes []struct {
+				Value struct {
+					Statuses []struct {
+						ID           string `json:"id"`
+						Status       string `json:"status"`
+						Timestamp    string `json:"timestamp"`
+						RecipientID  string `json:"recipient_id"`
+						Conversation *struct {
+							Origin struct {
+								Type string `json:"type"`
+							} `json:"origin"`
+						} `json:"conversation"`
+						Pricing *struct {
+							Category string `json:"category"`
+						} `json:"pricing"`
+						Errors []struct {
+							Code    int    `json:"code"`
+							Title   string `json:"title"`
+							Message string `json:"message"`
+						} `json:"errors"`
+					} `json:"statuses"`
+					Metadata struct {
+						PhoneNumberID string `json:"phone_number_id"`
+					} `json:"metadata"`
+				} `json:"value"`
+			} `json:"changes"`
+		} `json:"entry"`
+	}
+
+	if err := json.Unmarshal(payload, &whatsappPayload); err != nil {
+		return nil, fmt.Errorf("failed to parse WhatsApp payload: %w", err)
+	}
+
+	var events []*StatusEvent
+	for _, entry := range whatsappPayload.Entry {
+		for _, change := range entry.Changes {
+			for _, status := range change.Value.Statuses {
+				messageStatus, ok := whatsAppMessageStatus(status.Status)
+				if !ok {
+					continue
+				}
+
+				timestamp, _ := strconv.ParseInt(status.Timestamp, 10, 64)
+				event := &StatusEvent{
+					Platform:          domain.PlatformWhatsApp,
+					MessageID:         status.ID,
+					Recipient:         status.RecipientID,
+					Status:            messageStatus,
+					Timestamp:         timestamp,
+					channelIdentifier: change.Value.Metadata.PhoneNumberID,
+				}
+				if status.Pricing != nil && status.Pricing.Category != "" {
+					event.ConversationCategory = status.Pricing.Category
+				} else if status.Conversation != nil {
+					event.ConversationCategory = status.Conversation.Origin.Type
+				}
+				if len(status.Errors) > 0 {
+					event.ErrorCode = strconv.Itoa(status.Errors[0].Code)
+					event.ErrorMessage = status.Errors[0].Title
+					if status.Errors[0].Message != "" {
+						event.ErrorMessage = status.Errors[0].Message
+					}
+				}
+
+				events = append(events, event)
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// whatsAppMessageStatus traduce los estados de la Cloud API; "deleted" y otros no se registran
+func whatsAppMessageStatus(status string) (domain.MessageStatus, bool) {
+	switch status {
+	case "sent":
+		return domain.MessageStatusSent, true
+	case "delivered":
+		return domain.MessageStatusDelivered, true
+	case "read":
+		return domain.MessageStatusRead, true
+	case "failed":
+		return domain.MessageStatusFailed, true
+	default:
+		return "", false
+	}
+}
+
+func (s *webhookService) extractMetaMessagingStatuses(platform domain.Platform, payload []byte) ([]*StatusEvent, error) {
+	var metaPayload struct {
+		Entry []struct {
+			ID        string `json:"id"`
+			Messaging []struct {
+				Sender struct {
+					ID string `json:"id"`
+				} `json:"sender"`
+				Recipient struct {
+					ID string `json:"id"`
+				} `json:"recipient"`
+				Timestamp int64 `json:"timestamp"`
+				Delivery  *struct {
+					Mids      []string `json:"mids"`
+					Watermark int64    `json:"watermark"`
+				} `json:"delivery"`
+				Read *struct {
+					Mid       string `json:"mid"`
+					Watermark int64  `json:"watermark"`
+				} `json:"read"`
+			} `json:"messaging"`
+		} `json:"entry"`
+	}
+
+	if err := json.Unmarshal(payload, &metaPayload); err != nil {
+		return nil, fmt.Errorf("failed to parse %s payload: %w", platform, err)
+	}
+
+	var events []*StatusEvent
+	for _, entry := range metaPayload.Entry {
+		for _, messaging := range entry.Messaging {
+			// El usuario que recibió nuestro mensaje es quien envía el recibo
+			newEvent := func(status domain.MessageStatus, messageID string, watermark int64) *StatusEvent {
+				return &StatusEvent{
+					Platform:          platform,
+					MessageID:         messageID,
+					Recipient:         messaging.Sender.ID,
+					Status:            status,
+					Timestamp:         messaging.Timestamp,
+					Watermark:         watermark,
+					channelIdentifier: metaEntryChannel(entry.ID, messaging.Recipient.ID),
+				}
+			}
+
+			if delivery := messaging.Delivery; delivery != nil {
+				if len(delivery.Mids) == 0 {
+					events = append(events, newEvent(domain.MessageStatusDelivered, "", delivery.Watermark))
+				}
+				for _, mid := range delivery.Mids {
+					events = append(events, newEvent(domain.MessageStatusDelivered, mid, 0))
+				}
+			}
+
+			// Instagram envía "seen" con el mid del mensaje; Messenger solo con watermark
+			if read := messaging.Read; read != nil {
+				if read.Mid != "" {
+					events = append(events, newEvent(domain.MessageStatusRead, read.Mid, 0))
+				} else {
+					events = append(events, newEvent(domain.MessageStatusRead, "", read.Watermark))
+				}
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// ForwardStatusToMessagingService envía un recibo de estado al servicio de mensajería
+func (s *webhookService) ForwardStatusToMessagingService(ctx context.Context, event *StatusEvent) error {
+	if s.messagingServiceURL == "" {
+		s.logger.Warn("Messaging service URL not configured, skipping status forward")
+		return nil
+	}
+
+	jsonData, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status event: %w", err)
+	}
+
+	url := s.messagingServiceURL + "/api/v1/webhooks/status"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "it-integration-service/1.0")
+	if event.IdempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", event.IdempotencyKey)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to forward status to messaging service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("messaging service returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	s.logger.Debug("Status event forwarded to messaging service", map[string]interface{}{
+		"message_id": event.MessageID,
+		"platform":   event.Platform,
+		"status":     event.Status,
+	})
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"testing"
+
+	"it-integration-service/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractStatusEvents(t *testing.T) {
+	service := &webhookService{}
+
+	t.Run("whatsapp statuses", func(t *testing.T) {
+		payload := []byte(`{
+			"object": "whatsapp_business_account",
+			"entry": [{
+				"id": "102290129340398",
+				"changes": [{
+					"field": "messages",
+					"value": {
+						"messaging_product": "whatsapp",
+						"metadata": {"display_phone_number": "15550783881", "phone_number_id": "106540352242922"},
+						"statuses": [
+							{"id": "wamid.A", "status": "delivered", "timestamp": "1749416800", "recipient_id": "16505551234"},
+							{"id": "wamid.A", "status": "read", "timestamp": "1749416810", "recipient_id": "16505551234",
+							 "conversation": {"id": "c1", "origin": {"type": "service"}}, "pricing": {"billable": true, "category": "utility"}},
+							{"id": "wamid.B", "status": "failed", "timestamp": "1749416820", "recipient_id": "16505551234",
+							 "errors": [{"code": 131047, "title": "Re-engagement message", "message": "More than 24 hours have passed since the recipient last replied"}]},
+							{"id": "wamid.C", "status": "deleted", "timestamp": "1749416830", "recipient_id": "16505551234"}
+						]
+					}
+				}]
+			}]
+		}`)
+
+		events, err := service.ExtractStatusEvents(domain.PlatformWhatsApp, payload)
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+
+		assert.Equal(t, &StatusEvent{
+			Platform:          domain.PlatformWhatsApp,
+			MessageID:         "wamid.A",
+			Recipient:         "16505551234",
+			Status:            domain.MessageStatusDelivered,
+			Timestamp:         1749416800,
+			channelIdentifier: "106540352242922",
+		}, events[0])
+		assert.Equal(t, domain.MessageStatusRead, events[1].Status)
+		assert.Equal(t, "utility", events[1].ConversationCategory)
+		assert.Equal(t, domain.MessageStatusFailed, events[2].Status)
+		assert.Equal(t, "131047", events[2].ErrorCode)
+		assert.Equal(t, "More than 24 hours have passed since the recipient last replied", events[2].ErrorMessage)
+
+		messages, err := service.NormalizeMessage(domain.PlatformWhatsApp, payload)
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+	})
+
+	t.Run("messenger delivery and read", func(t *testing.T) {
+		payload := []byte(`{
+			"object": "page",
+			"entry": [{
+				"id": "107315378463519",
+				"time": 1749416900000,
+				"messaging": [
+					{"sender": {"id": "6912348723456789"}, "recipient": {"id": "107315378463519"}, "timestamp": 1749416900001,
+					 "delivery": {"mids": ["m_one", "m_two"], "watermark": 1749416899000}},
+					{"sender": {"id": "6912348723456789"}, "recipient": {"id": "107315378463519"}, "timestamp": 1749416900002,
+					 "read": {"watermark": 1749416899500}}
+				]
+			}]
+		}`)
+
+		events, err := service.ExtractStatusEvents(domain.PlatformMessenger, payload)
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+
+		assert.Equal(t, "m_one", events[0].MessageID)
+		assert.Equal(t, "m_two", events[1].MessageID)
+		assert.Equal(t, domain.MessageStatusDelivered, events[1].Status)
+		assert.Equal(t, "6912348723456789", events[1].Recipient)
+
+		assert.Empty(t, events[2].MessageID)
+		assert.Equal(t, domain.MessageStatusRead, events[2].Status)
+		assert.Equal(t, int64(1749416899500), events[2].Watermark)
+	})
+
+	t.Run("instagram seen", func(t *testing.T) {
+		payload := []byte(`{
+			"object": "instagram",
+			"entry": [{
+				"id": "17841405822304914",
+				"time": 1749417000000,
+				"messaging": [
+					{"sender": {"id": "1146720579373962"}, "recipient": {"id": "17841405822304914"}, "timestamp": 1749417000001,
+					 "read": {"mid": "aWdfZAG1faXRlbToxOklHTWVzc2FnZAUlE"}}
+				]
+			}]
+		}`)
+
+		events, err := service.ExtractStatusEvents(domain.PlatformInstagram, payload)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "aWdfZAG1faXRlbToxOklHTWVzc2FnZAUlE", events[0].MessageID)
+		assert.Equal(t, domain.MessageStatusRead, events[0].Status)
+	})
+}
+
+func TestStatusAdvances(t *testing.T) {
+	assert.True(t, statusAdvances(domain.MessageStatusSent, domain.MessageStatusDelivered))
+	assert.True(t, statusAdvances(domain.MessageStatusSent, domain.MessageStatusRead))
+	assert.True(t, statusAdvances(domain.MessageStatusSent, domain.MessageStatusFailed))
+	assert.False(t, statusAdvances(domain.MessageStatusRead, domain.MessageStatusDelivered))
+	assert.False(t, statusAdvances(domain.MessageStatusDelivered, domain.MessageStatusFailed))
+	assert.False(t, statusAdvances(domain.MessageStatusFailed, domain.MessageStatusDelivered))
+}
@@ -109,12 +109,23 @@ func (s *WhatsAppSetupService) GetPhoneNumberInfo(ctx context.Context, accessTok
 	}
 	defer resp.Body.Close()
 
-	var phoneInfo WhatsAppPhoneNumberInfo
-	if err := json.NewDecoder(resp.Body).Decode(&phoneInfo); err != nil {
+	// Graph API responde el número en la raíz y los errores en "error"
+	var phoneResp struct {
+		WhatsAppPhoneNumberInfo
+		Error *MetaAPIError `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&phoneResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return &phoneInfo, nil
+	if phoneResp.Error != nil {
+		return nil, fmt.Errorf("meta API error: %s", phoneResp.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("meta API returned status %d", resp.StatusCode)
+	}
+
+	return &phoneResp.WhatsAppPhoneNumberInfo, nil
 }
 
 // SubscribeToWebhooks suscribe la aplicación a webhooks de WhatsApp
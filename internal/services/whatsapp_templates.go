@@ -0,0 +1,471 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+)
+
+var (
+	// ErrTemplateNotFound indica que la plantilla no existe en la cuenta de WhatsApp Business
+	ErrTemplateNotFound = errors.New("whatsapp template not found")
+	// ErrTemplateRequestFailed indica que Meta rechazó la operación sobre las plantillas
+	ErrTemplateRequestFailed = errors.New("whatsapp template request failed")
+	// ErrMissingBusinessAccount indica que el canal no tiene configurada la WABA dueña de las plantillas
+	ErrMissingBusinessAccount = errors.New("whatsapp channel has no business_account_id")
+)
+
+// whatsappTemplatePageLimit es la cantidad de plantillas pedidas por página a la Graph API
+const whatsappTemplatePageLimit = 100
+
+// templatePlaceholder reconoce los parámetros posicionales {{1}}, {{2}}, ... de una plantilla
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*(\d+)\s*\}\}`)
+
+// WhatsAppTemplate representa una plantilla de mensaje de la cuenta de WhatsApp Business
+type WhatsAppTemplate struct {
+	ID         string                      `json:"id"`
+	Name       string                      `json:"name"`
+	Language   string                      `json:"language"`
+	Status     string                      `json:"status"`
+	Category   string                      `json:"category"`
+	Components []WhatsAppTemplateComponent `json:"components"`
+}
+
+// WhatsAppTemplateComponent es un bloque de la plantilla (HEADER, BODY, FOOTER o BUTTONS)
+type WhatsAppTemplateComponent struct {
+	Type    string                   `json:"type"`
+	Format  string                   `json:"format,omitempty"`
+	Text    string                   `json:"text,omitempty"`
+	Buttons []WhatsAppTemplateButton `json:"buttons,omitempty"`
+	Example json.RawMessage          `json:"example,omitempty"`
+}
+
+// WhatsAppTemplateButton es un botón de la plantilla
+type WhatsAppTemplateButton struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	URL         string `json:"url,omitempty"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+}
+
+// WhatsAppTemplateDefinition es la solicitud de creación de una plantilla
+type WhatsAppTemplateDefinition struct {
+	Name       string                      `json:"name" binding:"required"`
+	Language   string                      `json:"language" binding:"required"`
+	Category   string                      `json:"category" binding:"required"`
+	Components []WhatsAppTemplateComponent `json:"components" binding:"required"`
+}
+
+type cachedTemplates struct {
+	templates []WhatsAppTemplate
+	expiresAt time.Time
+}
+
+// WhatsAppTemplateService administra las plantillas de mensaje de la WABA de cada canal
+// y valida los envíos de plantilla antes de llamar a Meta
+type WhatsAppTemplateService struct {
+	channelRepo domain.ChannelIntegrationRepository
+	baseURL     string
+	client      *http.Client
+	ttl         time.Duration
+	logger      logger.Logger
+
+	mu    sync.RWMutex
+	cache map[string]cachedTemplates
+}
+
+// NewWhatsAppTemplateService crea una nueva instancia del servicio de plantillas de WhatsApp
+func NewWhatsAppTemplateService(channelRepo domain.ChannelIntegrationRepository, ttl time.Duration, logger logger.Logger) *WhatsAppTemplateService {
+	return &WhatsAppTemplateService{
+		channelRepo: channelRepo,
+		baseURL:     metaGraphURL,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		ttl:         ttl,
+		logger:      logger,
+		cache:       make(map[string]cachedTemplates),
+	}
+}
+
+// ListTemplates retorna las plantillas del canal; refresh ignora la cache
+func (s *WhatsAppTemplateService) ListTemplates(ctx context.Context, channelID string, refresh bool) ([]WhatsAppTemplate, error) {
+	channel, token, err := s.loadChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if refresh {
+		s.invalidate(channel.ID)
+	}
+	return s.templates(ctx, channel, token)
+}
+
+// CreateTemplate envía la plantilla a revisión de Meta
+func (s *WhatsAppTemplateService) CreateTemplate(ctx context.Context, channelID string, definition *WhatsAppTemplateDefinition) (*WhatsAppTemplate, error) {
+	channel, token, err := s.loadChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	wabaID, err := s.businessAccountID(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/message_templates", s.baseURL, wabaID)
+	body, err := metaGraphRequest(ctx, s.client, http.MethodPost, endpoint, token, definition)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateRequestFailed, err)
+	}
+
+	var created struct {
+		ID       string `json:"id"`
+		Status   string `json:"status"`
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		return nil, fmt.Errorf("failed to decode template response: %w", err)
+	}
+
+	s.invalidate(channel.ID)
+
+	s.logger.Info("WhatsApp template created", map[string]interface{}{
+		"channel_id": channel.ID,
+		"template":   definition.Name,
+		"language":   definition.Language,
+		"status":     created.Status,
+	})
+
+	category := created.Category
+	if category == "" {
+		category = definition.Category
+	}
+	return &WhatsAppTemplate{
+		ID:         created.ID,
+		Name:       definition.Name,
+		Language:   definition.Language,
+		Status:     created.Status,
+		Category:   category,
+		Components: definition.Components,
+	}, nil
+}
+
+// DeleteTemplate elimina la plantilla en todos sus idiomas
+func (s *WhatsAppTemplateService) DeleteTemplate(ctx context.Context, channelID, name string) error {
+	channel, token, err := s.loadChannel(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	wabaID, err := s.businessAccountID(channel)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/message_templates?name=%s", s.baseURL, wabaID, url.QueryEscape(name))
+	if _, err := metaGraphRequest(ctx, s.client, http.MethodDelete, endpoint, token, nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateRequestFailed, err)
+	}
+
+	s.invalidate(channel.ID)
+
+	s.logger.Info("WhatsApp template deleted", map[string]interface{}{
+		"channel_id": channel.ID,
+		"template":   name,
+	})
+	return nil
+}
+
+// ValidateTemplateContent verifica que la plantilla esté aprobada y que los parámetros
+// coincidan con sus placeholders
+func (s *WhatsAppTemplateService) ValidateTemplateContent(ctx context.Context, channel *domain.ChannelIntegration, token string, content *domain.TemplateContent) error {
+	templates, err := s.templates(ctx, channel, token)
+	if err != nil {
+		return err
+	}
+
+	for i := range templates {
+		if templates[i].Name == content.Name && templates[i].Language == content.Language {
+			return validateTemplateParams(&templates[i], content)
+		}
+	}
+	return fmt.Errorf("%w: %s (%s)", ErrTemplateNotFound, content.Name, content.Language)
+}
+
+// validateTemplateParams compara los parámetros del envío con los componentes de la plantilla
+func validateTemplateParams(template *WhatsAppTemplate, content *domain.TemplateContent) error {
+	if template.Status != "APPROVED" {
+		return invalidContent("template %s is %s and cannot be sent", template.Name, template.Status)
+	}
+
+	var header, body, buttons *WhatsAppTemplateComponent
+	for i := range template.Components {
+		switch strings.ToUpper(template.Components[i].Type) {
+		case "HEADER":
+			header = &template.Components[i]
+		case "BODY":
+			body = &template.Components[i]
+		case "BUTTONS":
+			buttons = &template.Components[i]
+		}
+	}
+
+	// Encabezado
+	switch {
+	case header == nil:
+		if len(content.HeaderParams) > 0 || content.HeaderMedia != nil {
+			return invalidContent("template %s has no header", template.Name)
+		}
+	case strings.EqualFold(header.Format, "TEXT") || header.Format == "":
+		if err := checkParamCount("header", header.Text, content.HeaderParams); err != nil {
+			return err
+		}
+	case strings.EqualFold(header.Format, "LOCATION"):
+		// Los encabezados de ubicación no se validan
+	default:
+		if content.HeaderMedia == nil || (content.HeaderMedia.URL == "" && content.HeaderMedia.ID == "") {
+			return invalidContent("template %s requires a %s header media", template.Name, strings.ToLower(header.Format))
+		}
+	}
+
+	// Cuerpo
+	bodyText := ""
+	if body != nil {
+		bodyText = body.Text
+	}
+	if err := checkParamCount("body", bodyText, content.BodyParams); err != nil {
+		return err
+	}
+
+	// Botones: cada botón URL dinámico necesita su parámetro
+	var templateButtons []WhatsAppTemplateButton
+	if buttons != nil {
+		templateButtons = buttons.Buttons
+	}
+	provided := make(map[int]bool, len(content.Buttons))
+	for _, button := range content.Buttons {
+		if button.Index < 0 || button.Index >= len(templateButtons) {
+			return invalidContent("template %s has no button at index %d", template.Name, button.Index)
+		}
+		if provided[button.Index] {
+			return invalidContent("button %d has more than one parameter", button.Index)
+		}
+		provided[button.Index] = true
+
+		templateButton := templateButtons[button.Index]
+		switch strings.ToUpper(templateButton.Type) {
+		case "QUICK_REPLY":
+			if button.SubType != "quick_reply" {
+				return invalidContent("button %d is a quick_reply button", button.Index)
+			}
+		case "URL":
+			if button.SubType != "url" || !templatePlaceholder.MatchString(templateButton.URL) {
+				return invalidContent("button %d does not take a url parameter", button.Index)
+			}
+		default:
+			return invalidContent("button %d of type %s does not take parameters", button.Index, strings.ToLower(templateButton.Type))
+		}
+		if button.Param == "" {
+			return invalidContent("button %d parameter is empty", button.Index)
+		}
+	}
+	for i, templateButton := range templateButtons {
+		if strings.EqualFold(templateButton.Type, "URL") && templatePlaceholder.MatchString(templateButton.URL) && !provided[i] {
+			return invalidContent("button %d requires a url parameter", i)
+		}
+	}
+
+	return nil
+}
+
+// checkParamCount verifica que haya un parámetro no vacío por cada placeholder del texto
+func checkParamCount(component, text string, params []string) error {
+	expected := countPlaceholders(text)
+	if len(params) != expected {
+		return invalidContent("%s expects %d parameters, got %d", component, expected, len(params))
+	}
+	for i, param := range params {
+		if strings.TrimSpace(param) == "" {
+			return invalidContent("%s parameter %d is empty", component, i+1)
+		}
+	}
+	return nil
+}
+
+// countPlaceholders cuenta los placeholders distintos del texto
+func countPlaceholders(text string) int {
+	seen := make(map[string]bool)
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(text, -1) {
+		seen[match[1]] = true
+	}
+	return len(seen)
+}
+
+// whatsappTemplatePayload arma el objeto template de la Cloud API
+func whatsappTemplatePayload(template *domain.TemplateContent) map[string]interface{} {
+	var components []map[string]interface{}
+
+	if template.HeaderMedia != nil {
+		mediaType := template.HeaderMedia.Type
+		if mediaType == "" {
+			mediaType = domain.ContentTypeImage
+		}
+		media := map[string]interface{}{}
+		if template.HeaderMedia.ID != "" {
+			media["id"] = template.HeaderMedia.ID
+		} else {
+			media["link"] = template.HeaderMedia.URL
+		}
+		if mediaType == domain.ContentTypeDocument && template.HeaderMedia.Filename != "" {
+			media["filename"] = template.HeaderMedia.Filename
+		}
+		components = append(components, map[string]interface{}{
+			"type":       "header",
+			"parameters": []map[string]interface{}{{"type": mediaType, mediaType: media}},
+		})
+	} else if len(template.HeaderParams) > 0 {
+		components = append(components, map[string]interface{}{
+			"type":       "header",
+			"parameters": textParameters(template.HeaderParams),
+		})
+	}
+
+	if len(template.BodyParams) > 0 {
+		components = append(components, map[string]interface{}{
+			"type":       "body",
+			"parameters": textParameters(template.BodyParams),
+		})
+	}
+
+	for _, button := range template.Buttons {
+		parameter := map[string]interface{}{"type": "text", "text": button.Param}
+		if button.SubType == "quick_reply" {
+			parameter = map[string]interface{}{"type": "payload", "payload": button.Param}
+		}
+		components = append(components, map[string]interface{}{
+			"type":       "button",
+			"sub_type":   button.SubType,
+			"index":      fmt.Sprintf("%d", button.Index),
+			"parameters": []map[string]interface{}{parameter},
+		})
+	}
+
+	payload := map[string]interface{}{
+		"name":     template.Name,
+		"language": map[string]interface{}{"code": template.Language},
+	}
+	if len(components) > 0 {
+		payload["components"] = components
+	}
+	return payload
+}
+
+func textParameters(values []string) []map[string]interface{} {
+	parameters := make([]map[string]interface{}, 0, len(values))
+	for _, value := range values {
+		parameters = append(parameters, map[string]interface{}{"type": "text", "text": value})
+	}
+	return parameters
+}
+
+// templates retorna las plantillas del canal desde la cache o desde la Graph API
+func (s *WhatsAppTemplateService) templates(ctx context.Context, channel *domain.ChannelIntegration, token string) ([]WhatsAppTemplate, error) {
+	s.mu.RLock()
+	entry, ok := s.cache[channel.ID]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.templates, nil
+	}
+
+	templates, err := s.fetchTemplates(ctx, channel, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.ttl > 0 {
+		s.mu.Lock()
+		s.cache[channel.ID] = cachedTemplates{templates: templates, expiresAt: time.Now().Add(s.ttl)}
+		s.mu.Unlock()
+	}
+	return templates, nil
+}
+
+// fetchTemplates recorre todas las páginas del edge message_templates
+func (s *WhatsAppTemplateService) fetchTemplates(ctx context.Context, channel *domain.ChannelIntegration, token string) ([]WhatsAppTemplate, error) {
+	wabaID, err := s.businessAccountID(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/message_templates?fields=id,name,language,status,category,components&limit=%d",
+		s.baseURL, wabaID, whatsappTemplatePageLimit)
+
+	templates := []WhatsAppTemplate{}
+	for endpoint != "" {
+		body, err := metaGraphRequest(ctx, s.client, http.MethodGet, endpoint, token, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrTemplateRequestFailed, err)
+		}
+
+		var page struct {
+			Data   []WhatsAppTemplate `json:"data"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode templates: %w", err)
+		}
+
+		templates = append(templates, page.Data...)
+		endpoint = page.Paging.Next
+	}
+
+	s.logger.Debug("WhatsApp templates loaded", map[string]interface{}{
+		"channel_id": channel.ID,
+		"count":      len(templates),
+	})
+	return templates, nil
+}
+
+func (s *WhatsAppTemplateService) invalidate(channelID string) {
+	s.mu.Lock()
+	delete(s.cache, channelID)
+	s.mu.Unlock()
+}
+
+// loadChannel obtiene el canal de WhatsApp y su token
+func (s *WhatsAppTemplateService) loadChannel(ctx context.Context, channelID string) (*domain.ChannelIntegration, string, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrChannelNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get channel: %w", err)
+	}
+	if channel.Platform != domain.PlatformWhatsApp {
+		return nil, "", fmt.Errorf("%w: templates are only available for whatsapp channels", ErrUnsupportedPlatform)
+	}
+
+	token, err := channelAccessToken(channel)
+	if err != nil {
+		return nil, "", err
+	}
+	return channel, token, nil
+}
+
+// businessAccountID obtiene la WABA guardada en la configuración del canal
+func (s *WhatsAppTemplateService) businessAccountID(channel *domain.ChannelIntegration) (string, error) {
+	wabaID := channelConfigValue(channel, "business_account_id")
+	if wabaID == "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingBusinessAccount, channel.ID)
+	}
+	return wabaID, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/domain"
+	"it-integration-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func orderShippedTemplate() *WhatsAppTemplate {
+	return &WhatsAppTemplate{
+		Name:     "order_shipped",
+		Language: "es_AR",
+		Status:   "APPROVED",
+		Category: "UTILITY",
+		Components: []WhatsAppTemplateComponent{
+			{Type: "HEADER", Format: "TEXT", Text: "Pedido {{1}}"},
+			{Type: "BODY", Text: "Hola {{1}}, tu pedido {{2}} llega el {{3}}. Seguilo con el código {{2}}."},
+			{Type: "BUTTONS", Buttons: []WhatsAppTemplateButton{
+				{Type: "URL", Text: "Seguir envío", URL: "https://example.com/track/{{1}}"},
+				{Type: "QUICK_REPLY", Text: "No lo recibí"},
+			}},
+		},
+	}
+}
+
+func TestValidateTemplateParams(t *testing.T) {
+	valid := domain.TemplateContent{
+		Name:         "order_shipped",
+		Language:     "es_AR",
+		HeaderParams: []string{"#1234"},
+		BodyParams:   []string{"Ana", "#1234", "lunes"},
+		Buttons: []domain.TemplateButtonParam{
+			{Index: 0, SubType: "url", Param: "1234"},
+			{Index: 1, SubType: "quick_reply", Param: "NOT_RECEIVED"},
+		},
+	}
+	require.NoError(t, validateTemplateParams(orderShippedTemplate(), &valid))
+
+	tests := []struct {
+		name   string
+		mutate func(content *domain.TemplateContent)
+	}{
+		{"missing body param", func(c *domain.TemplateContent) { c.BodyParams = c.BodyParams[:2] }},
+		{"empty header param", func(c *domain.TemplateContent) { c.HeaderParams = []string{" "} }},
+		{"missing url button param", func(c *domain.TemplateContent) { c.Buttons = c.Buttons[1:] }},
+		{"wrong button sub type", func(c *domain.TemplateContent) { c.Buttons[1].SubType = "url" }},
+		{"unknown button index", func(c *domain.TemplateContent) { c.Buttons[1].Index = 5 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := valid
+			content.HeaderParams = append([]string(nil), valid.HeaderParams...)
+			content.BodyParams = append([]string(nil), valid.BodyParams...)
+			content.Buttons = append([]domain.TemplateButtonParam(nil), valid.Buttons...)
+			tt.mutate(&content)
+
+			err := validateTemplateParams(orderShippedTemplate(), &content)
+			assert.True(t, errors.Is(err, ErrInvalidMessageContent), "got %v", err)
+		})
+	}
+}
+
+func TestValidateTemplateParamsRejectsUnapproved(t *testing.T) {
+	template := orderShippedTemplate()
+	template.Status = "PENDING"
+
+	err := validateTemplateParams(template, &domain.TemplateContent{Name: "order_shipped", Language: "es_AR"})
+	assert.True(t, errors.Is(err, ErrInvalidMessageContent))
+}
+
+func TestWhatsAppSenderSendsTemplate(t *testing.T) {
+	server, _, received := recordingServer(t, http.StatusOK, `{"messages":[{"id":"wamid.template"}]}`)
+
+	sender := NewWhatsAppSender(logger.NewLogger("error"))
+	sender.baseURL = server.URL
+
+	channel := &domain.ChannelIntegration{ID: "channel-1", Config: json.RawMessage(`{"phone_number_id":"106540352242922"}`)}
+	content := &domain.MessageContent{
+		Type: domain.ContentTypeTemplate,
+		Template: &domain.TemplateContent{
+			Name:       "order_shipped",
+			Language:   "es_AR",
+			BodyParams: []string{"Ana"},
+			Buttons:    []domain.TemplateButtonParam{{Index: 1, SubType: "quick_reply", Param: "NOT_RECEIVED"}},
+		},
+	}
+
+	_, err := sender.Send(context.Background(), channel, "token", "5491155550000", content)
+	require.NoError(t, err)
+
+	template, err := json.Marshal((*received)["template"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "order_shipped",
+		"language": {"code": "es_AR"},
+		"components": [
+			{"type": "body", "parameters": [{"type": "text", "text": "Ana"}]},
+			{"type": "button", "sub_type": "quick_reply", "index": "1", "parameters": [{"type": "payload", "payload": "NOT_RECEIVED"}]}
+		]
+	}`, string(template))
+}
+
+func TestListTemplatesFollowsPagingAndCaches(t *testing.T) {
+	requests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/102290129340398/message_templates", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("after") == "" {
+			_, _ = w.Write([]byte(`{"data":[{"id":"1","name":"welcome","language":"es","status":"APPROVED"}],"paging":{"next":"` +
+				server.URL + `/102290129340398/message_templates?after=abc"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"2","name":"order_shipped","language":"es_AR","status":"APPROVED"}],"paging":{}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	channels := &channelsByID{channels: map[string]*domain.ChannelIntegration{
+		"channel-1": {
+			ID:          "channel-1",
+			Platform:    domain.PlatformWhatsApp,
+			AccessToken: "token",
+			Config:      json.RawMessage(`{"business_account_id":"102290129340398"}`),
+		},
+	}}
+	service := NewWhatsAppTemplateService(channels, time.Minute, logger.NewLogger("error"))
+	service.baseURL = server.URL
+
+	templates, err := service.ListTemplates(context.Background(), "channel-1", false)
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	assert.Equal(t, "order_shipped", templates[1].Name)
+
+	_, err = service.ListTemplates(context.Background(), "channel-1", false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests, "second listing should be served from cache")
+
+	_, err = service.ListTemplates(context.Background(), "channel-1", true)
+	require.NoError(t, err)
+	assert.Equal(t, 4, requests)
+}
+
+// channelsByID resuelve canales por ID desde un mapa
+type channelsByID struct {
+	domain.ChannelIntegrationRepository
+	channels map[string]*domain.ChannelIntegration
+}
+
+func (r *channelsByID) GetByID(ctx context.Context, id string) (*domain.ChannelIntegration, error) {
+	channel, ok := r.channels[id]
+	if !ok {
+		return nil, errors.New("channel not found")
+	}
+	return channel, nil
+}
@@ -19,6 +19,60 @@ func SetupTestContainers(ctx context.Context) (*TestContainers, error) {
 	containers := &TestContainers{}
 
 	// PostgreSQL Container
+	postgresContainer, err := startPostgresContainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containers.PostgresContainer = postgresContainer
+
+	// Vault Container
+	vaultContainer, err := startVaultContainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containers.VaultContainer = vaultContainer
+
+	// Redis Container
+	redisReq := testcontainers.ContainerRequest{
+		Image:        "redis:7-alpine",
+		ExposedPorts: []string{"6379/tcp"},
+		WaitingFor:   wait.ForListeningPort("6379/tcp").WithStartupTimeout(30 * time.Second),
+	}
+
+	redisContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: redisReq,
+		Started:          true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start redis container: %w", err)
+	}
+	containers.RedisContainer = redisContainer
+
+	return containers, nil
+}
+
+// VaultDevRootToken es el root token del Vault en modo dev de las pruebas
+const VaultDevRootToken = "test-token"
+
+// SetupVaultContainer inicia solo un Vault en modo dev, con el motor KV v2 montado en "secret"
+func SetupVaultContainer(ctx context.Context) (*TestContainers, error) {
+	vaultContainer, err := startVaultContainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &TestContainers{VaultContainer: vaultContainer}, nil
+}
+
+// SetupPostgresContainer inicia solo un PostgreSQL vacío
+func SetupPostgresContainer(ctx context.Context) (*TestContainers, error) {
+	postgresContainer, err := startPostgresContainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &TestContainers{PostgresContainer: postgresContainer}, nil
+}
+
+func startPostgresContainer(ctx context.Context) (testcontainers.Container, error) {
 	postgresReq := testcontainers.ContainerRequest{
 		Image:        "postgres:15-alpine",
 		ExposedPorts: []string{"5432/tcp"},
@@ -37,17 +91,18 @@ func SetupTestContainers(ctx context.Context) (*TestContainers, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to start postgres container: %w", err)
 	}
-	containers.PostgresContainer = postgresContainer
+	return postgresContainer, nil
+}
 
-	// Vault Container
+func startVaultContainer(ctx context.Context) (testcontainers.Container, error) {
 	vaultReq := testcontainers.ContainerRequest{
-		Image:        "vault:latest",
+		Image:        "hashicorp/vault:1.15",
 		ExposedPorts: []string{"8200/tcp"},
 		Env: map[string]string{
-			"VAULT_DEV_ROOT_TOKEN_ID":      "test-token",
-			"VAULT_DEV_LISTEN_ADDRESS":     "0.0.0.0:8200",
+			"VAULT_DEV_ROOT_TOKEN_ID":  VaultDevRootToken,
+			"VAULT_DEV_LISTEN_ADDRESS": "0.0.0.0:8200",
 		},
-		Cmd: []string{"vault", "server", "-dev"},
+		Cmd:        []string{"server", "-dev"},
 		WaitingFor: wait.ForHTTP("/v1/sys/health").WithPort("8200/tcp").WithStartupTimeout(30 * time.Second),
 	}
 
@@ -58,25 +113,7 @@ func SetupTestContainers(ctx context.Context) (*TestContainers, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to start vault container: %w", err)
 	}
-	containers.VaultContainer = vaultContainer
-
-	// Redis Container
-	redisReq := testcontainers.ContainerRequest{
-		Image:        "redis:7-alpine",
-		ExposedPorts: []string{"6379/tcp"},
-		WaitingFor:   wait.ForListeningPort("6379/tcp").WithStartupTimeout(30 * time.Second),
-	}
-
-	redisContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: redisReq,
-		Started:          true,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to start redis container: %w", err)
-	}
-	containers.RedisContainer = redisContainer
-
-	return containers, nil
+	return vaultContainer, nil
 }
 
 func (tc *TestContainers) GetPostgresConnectionString(ctx context.Context) (string, error) {
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"embed"
+	"io/fs"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,11 +17,18 @@ import (
 	"it-integration-service/internal/repository"
 	"it-integration-service/internal/routes"
 	"it-integration-service/internal/services"
+	"it-integration-service/pkg/events"
 	"it-integration-service/pkg/logger"
+	"it-integration-service/pkg/vault"
 
 	"github.com/gin-gonic/gin"
 )
 
+// migrationFiles son las migraciones SQL incluidas en el binario
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
 // @title Microservice Template API
 // @version 1.0
 // @description Template para microservicios en Go
@@ -32,11 +41,26 @@ func main() {
 	// Inicializar logger
 	logger := logger.NewLogger(cfg.LogLevel)
 
-	// Inicializar cliente de Vault (comentado para testing)
-	// vaultClient, err := vault.NewClient(cfg.VaultConfig)
-	// if err != nil {
-	// 	logger.Fatal("Failed to initialize Vault client", err)
-	// }
+	// Contexto de los procesos en segundo plano, se cancela al apagar el servidor
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Inicializar cliente de Vault solo si los secretos o las claves de encriptación se leen de ahí
+	var vaultClient vault.Client
+	if cfg.Secrets.Source == "vault" || cfg.Encryption.KeySource == "vault" {
+		client, err := vault.NewClient(cfg.VaultConfig)
+		if err != nil {
+			logger.Fatal("Failed to initialize Vault client", err)
+		}
+		vaultClient = client
+	}
+
+	// Secretos resueltos en cada uso, para rotarlos sin redeploy
+	secretProvider, err := services.NewSecretProvider(workersCtx, cfg.Secrets, vaultClient, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize secret provider", err)
+	}
+	cfg.GoogleCalendar.Secrets = secretProvider
 
 	// Inicializar conexión a base de datos
 	db, err := repository.NewPostgresDB(
@@ -52,41 +76,130 @@ func main() {
 	}
 	defer db.Close()
 
-	// Inicializar repositorios
-	channelRepo := repository.NewChannelIntegrationRepository(db)
+	// Inicializar keyring de encriptación; sin claves válidas el servidor no arranca, salvo que
+	// ENCRYPTION_ALLOW_PLAINTEXT permita guardar las credenciales en texto plano
+	var credentialSealer repository.CredentialSealer
+	encryptionService, err := services.LoadEncryptionKeyring(cfg.Encryption, cfg.Integration.EncryptionKey, vaultClient)
+	if err != nil {
+		if !cfg.Encryption.AllowPlaintext {
+			logger.Fatal("Failed to load encryption keyring", map[string]interface{}{
+				"error":  err.Error(),
+				"source": cfg.Encryption.KeySource,
+			})
+		}
+		logger.Warn("Encryption service not configured, channel credentials will be stored in plaintext", map[string]interface{}{
+			"error":  err.Error(),
+			"source": cfg.Encryption.KeySource,
+		})
+		encryptionService = nil
+	} else {
+		credentialSealer = encryptionService
+	}
+
+	// Inicializar repositorios. Las escrituras de canales pasan por el resolvedor de webhooks para
+	// que su cache no siga resolviendo un canal deshabilitado o con credenciales viejas
+	channelStore := repository.NewChannelIntegrationRepository(db, credentialSealer)
+	channelResolver := services.NewChannelResolver(channelStore, cfg.Integration.ChannelCacheTTL, logger)
+	channelRepo := services.NewInvalidatingChannelRepository(channelStore, channelResolver)
 	inboundRepo := repository.NewInboundMessageRepository(db)
+	outboxRepo := repository.NewMessageOutboxRepository(db)
+	outboundRepo := repository.NewOutboundMessageLogRepository(db)
+	dedupRepo := repository.NewProcessedWebhookEventRepository(db)
+	sessionRepo := repository.NewConversationSessionRepository(db)
+	channelHealthRepo := repository.NewChannelHealthCheckRepository(db)
 
 	// Inicializar servicios
-	healthService := services.NewHealthService(db.DB, logger)
+	healthService := services.NewHealthService(db.DB, channelRepo, vaultClient, cfg, logger)
 	webhookService := services.NewWebhookService(cfg.Integration.MessagingServiceURL, logger)
 	channelService := services.NewChannelService(channelRepo, logger)
 
-	// Inicializar servicio de encriptación
-	// encryptionService, err := services.NewEncryptionService(cfg.Integration.EncryptionKey)
-	// if err != nil {
-	// 	logger.Fatal("Failed to initialize encryption service", err)
-	// }
+	// Avisos de vencimiento de tokens a los destinos de cada tenant
+	eventBus := events.NewInMemoryEventBus(logger)
+	defer eventBus.Close()
+	notificationRepo := repository.NewNotificationRepository(db, credentialSealer)
+	notificationService := services.NewTokenNotificationService(
+		notificationRepo,
+		services.DefaultNotificationSenders(cfg.Notifications, secretProvider, eventBus),
+		logger,
+	)
 
-	// Inicializar servicio de rotación de tokens
-	tokenRotationService := services.NewTokenRotationService(channelRepo, logger)
+	// Inicializar servicio de rotación de tokens, que valida y renueva contra cada plataforma
+	tokenRotationService := services.NewTokenRotationService(channelRepo, services.DefaultTokenClients(secretProvider, &cfg.GoogleCalendar), notificationService, logger)
+
+	// Ventanas de atención por contacto, actualizadas con cada mensaje entrante
+	sessionService := services.NewConversationSessionService(sessionRepo, cfg.Integration.SessionWindow, logger)
 
 	// Servicio de integración (solo para integraciones, no envío de mensajes)
 	integrationService := services.NewIntegrationService(
 		channelService,
 		inboundRepo,
+		outboxRepo,
+		outboundRepo,
+		dedupRepo,
 		webhookService,
+		channelResolver,
+		sessionService,
+		cfg.Integration.UnresolvedChannelPolicy,
+		cfg.Dedup.Retention,
+		logger,
+	)
+
+	// Consulta de mensajes entrantes y salientes
+	queryService := services.NewQueryService(channelRepo, inboundRepo, outboundRepo, logger)
+
+	// Plantillas de WhatsApp, cacheadas por canal
+	templateService := services.NewWhatsAppTemplateService(channelRepo, cfg.Integration.TemplateCacheTTL, logger)
+
+	// Envío de mensajes salientes por canal
+	messageSendService := services.NewMessageSendService(
+		channelRepo,
+		outboundRepo,
+		templateService,
+		sessionService,
+		services.DefaultSenders(logger),
 		logger,
 	)
 
+	// Envíos masivos
+	broadcastRepo := repository.NewBroadcastRepository(db)
+	broadcastService := services.NewBroadcastService(broadcastRepo, channelRepo, logger)
+
+	// Dispatcher del outbox hacia el servicio de mensajería
+	outboxDispatcher := services.NewOutboxDispatcher(outboxRepo, webhookService, cfg.Outbox, logger)
+	outboxDispatcher.Start(workersCtx)
+
+	// Limpieza del registro de deduplicación de webhooks
+	dedupCleaner := services.NewWebhookDedupCleaner(dedupRepo, cfg.Dedup, logger)
+	dedupCleaner.Start(workersCtx)
+
+	// Motor de envíos masivos
+	broadcastEngine := services.NewBroadcastEngine(broadcastRepo, messageSendService, cfg.Broadcast, logger)
+	broadcastEngine.Start(workersCtx)
+
+	// Verificación periódica de la conectividad de cada canal
+	channelProber := services.NewChannelProber(channelRepo, channelHealthRepo, services.DefaultChannelProbes(cfg.ChannelProbe, logger), cfg.ChannelProbe, logger)
+	channelProber.Start(workersCtx)
+
+	// Reproducción de webhooks guardados, disparada por API
+	replayService := services.NewWebhookReplayService(inboundRepo, webhookService, cfg.Replay, logger)
+	replayService.Start(workersCtx)
+
+	// Re-encriptación de credenciales con la clave activa, disparada por API
+	var reencryptionJob *services.CredentialReencryptionJob
+	if encryptionService != nil {
+		reencryptionJob = services.NewCredentialReencryptionJob(db, encryptionService, cfg.Encryption.ReencryptBatchSize, logger)
+		reencryptionJob.Start(workersCtx)
+	}
+
 	// Inicializar configuración de Mercado Pago
-	mpConfig, err := config.NewMercadoPagoConfig()
+	mpConfig, err := config.NewMercadoPagoConfig(secretProvider)
 	if err != nil {
 		logger.Fatal("Failed to initialize Mercado Pago configuration", err)
 	}
 
 	// Inicializar servicios de pago
 	paymentService := services.NewPaymentService(mpConfig)
-	mpWebhookService := services.NewMercadoPagoWebhookService(mpConfig.SecretKey)
+	mpWebhookService := services.NewMercadoPagoWebhookServiceFromConfig(mpConfig)
 	paymentController := controllers.NewPaymentController(paymentService, mpWebhookService)
 
 	// Configurar Gin
@@ -103,16 +216,68 @@ func main() {
 
 	// Programar rotación automática de tokens
 	tokenConfig := tokenRotationService.GetTokenRotationConfig()
-	if err := tokenRotationService.ScheduleTokenRotation(context.Background(), tokenConfig); err != nil {
+	if err := tokenRotationService.ScheduleTokenRotation(workersCtx, tokenConfig); err != nil {
 		logger.Error("Failed to schedule token rotation", err)
 	}
 
 	// Rutas
-	handlers.SetupRoutes(router, healthService, integrationService, logger, cfg, db)
+	handlers.SetupRoutes(router, healthService, integrationService, queryService, messageSendService, broadcastService, templateService, sessionService, notificationService, channelProber, replayService, reencryptionJob, secretProvider, logger, cfg, channelRepo)
 
 	// Rutas de pagos
 	routes.SetupPaymentRoutes(router, paymentController)
 
+	// Módulo de Google Calendar, montado solo si está habilitado y su configuración está completa
+	if cfg.GoogleCalendar.Enabled {
+		if err := cfg.GoogleCalendar.Validate(workersCtx); err != nil {
+			logger.Fatal("Invalid Google Calendar configuration", err)
+		}
+		// Los tokens de Google se guardan encriptados, así que sin keyring el módulo no puede iniciar
+		if encryptionService == nil {
+			logger.Fatal("Google Calendar requires a valid encryption key", map[string]interface{}{
+				"source": cfg.Encryption.KeySource,
+			})
+		}
+
+		if cfg.GoogleCalendar.AutoMigrate {
+			migrations, err := fs.Sub(migrationFiles, "migrations")
+			if err != nil {
+				logger.Fatal("Failed to read embedded migrations", err)
+			}
+			applied, err := repository.ApplyMigrations(workersCtx, db, migrations, repository.GoogleCalendarMigrations)
+			if err != nil {
+				logger.Fatal("Failed to apply Google Calendar migrations", err)
+			}
+			if len(applied) > 0 {
+				logger.Info("Google Calendar migrations applied", map[string]interface{}{
+					"migrations": applied,
+				})
+			}
+		}
+
+		calendarRepo := repository.NewGoogleCalendarRepository(db.DB, logger)
+		calendarSetupService := services.NewGoogleCalendarSetupService(
+			&cfg.GoogleCalendar,
+			calendarRepo,
+			repository.NewOAuthStateRepository(db),
+			repository.NewCalendarWatchChannelRepository(db),
+			logger,
+			encryptionService,
+		)
+		scheduledNotificationRepo := repository.NewScheduledNotificationRepository(db)
+		calendarNotificationService := services.NewNotificationService(scheduledNotificationRepo, logger)
+		calendarEventService := services.NewGoogleCalendarService(&cfg.GoogleCalendar, calendarSetupService, calendarRepo, calendarNotificationService, logger, encryptionService)
+
+		// Renovación de los canales push antes de que venzan
+		watchRenewer := services.NewGoogleCalendarWatchRenewer(calendarSetupService, &cfg.GoogleCalendar, logger)
+		watchRenewer.Start(workersCtx)
+
+		// Envío de los recordatorios programados de los eventos
+		reminderScheduler := services.NewGoogleCalendarReminderScheduler(scheduledNotificationRepo, calendarRepo, calendarNotificationService, &cfg.GoogleCalendar, logger)
+		reminderScheduler.Start(workersCtx)
+
+		routes.SetupGoogleCalendarRoutes(router, cfg, logger, calendarSetupService, calendarEventService, calendarNotificationService)
+	}
+
 	// Servidor HTTP
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
@@ -133,6 +298,7 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
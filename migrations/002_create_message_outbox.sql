@@ -0,0 +1,28 @@
+-- Migración para crear el outbox de entrega al servicio de mensajería
+-- Ejecutar: psql -d your_database -f 002_create_message_outbox.sql
+
+-- Tabla de mensajes normalizados pendientes de entrega
+CREATE TABLE IF NOT EXISTS message_outbox (
+    id UUID PRIMARY KEY,
+    inbound_message_id VARCHAR(255) NOT NULL,
+    platform VARCHAR(50) NOT NULL,
+    message_id VARCHAR(255),
+    payload JSONB NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'delivered', 'dead_letter')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Índice parcial para el polling del dispatcher
+CREATE INDEX IF NOT EXISTS idx_message_outbox_due ON message_outbox(next_attempt_at) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_message_outbox_inbound_message_id ON message_outbox(inbound_message_id);
+CREATE INDEX IF NOT EXISTS idx_message_outbox_status ON message_outbox(status);
+
+COMMENT ON TABLE message_outbox IS 'Outbox transaccional de mensajes normalizados hacia el servicio de mensajería';
+COMMENT ON COLUMN message_outbox.locked_until IS 'Lease del dispatcher que reclamó el mensaje; vencido se vuelve a reclamar';
+COMMENT ON COLUMN message_outbox.status IS 'pending, processing, delivered o dead_letter al agotar OUTBOX_MAX_ATTEMPTS';
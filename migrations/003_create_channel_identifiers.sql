@@ -0,0 +1,61 @@
+-- Migración para resolver el tenant y canal de cada webhook entrante
+-- Ejecutar: psql -d your_database -f 003_create_channel_identifiers.sql
+
+-- Tabla de identificadores de plataforma por canal (phone_number_id, page_id, list_id, etc.)
+CREATE TABLE IF NOT EXISTS channel_identifiers (
+    platform VARCHAR(50) NOT NULL,
+    identifier_type VARCHAR(50) NOT NULL,
+    identifier VARCHAR(255) NOT NULL,
+    channel_id VARCHAR(255) NOT NULL,
+    tenant_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (platform, identifier)
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_identifiers_channel_id ON channel_identifiers(channel_id);
+
+-- Tenant y canal resueltos para cada mensaje entrante
+ALTER TABLE inbound_messages ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(255);
+ALTER TABLE inbound_messages ADD COLUMN IF NOT EXISTS channel_id VARCHAR(255);
+ALTER TABLE inbound_messages ADD COLUMN IF NOT EXISTS quarantine_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_inbound_messages_tenant_id ON inbound_messages(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_inbound_messages_channel_id ON inbound_messages(channel_id);
+
+-- Poblar identificadores de las integraciones existentes
+INSERT INTO channel_identifiers (platform, identifier_type, identifier, channel_id, tenant_id)
+SELECT platform, 'phone_number_id', config->>'phone_number_id', id::text, tenant_id
+FROM channel_integrations
+WHERE platform = 'whatsapp' AND COALESCE(config->>'phone_number_id', '') <> ''
+ON CONFLICT DO NOTHING;
+
+INSERT INTO channel_identifiers (platform, identifier_type, identifier, channel_id, tenant_id)
+SELECT platform, 'page_id', config->>'page_id', id::text, tenant_id
+FROM channel_integrations
+WHERE platform IN ('messenger', 'instagram') AND COALESCE(config->>'page_id', '') <> ''
+ON CONFLICT DO NOTHING;
+
+INSERT INTO channel_identifiers (platform, identifier_type, identifier, channel_id, tenant_id)
+SELECT platform, 'instagram_id', config->>'instagram_id', id::text, tenant_id
+FROM channel_integrations
+WHERE platform = 'instagram' AND COALESCE(config->>'instagram_id', '') <> ''
+ON CONFLICT DO NOTHING;
+
+-- Los bots de Telegram existentes no tienen webhook_key: siguen en la ruta legacy /webhooks/telegram,
+-- que se resuelve mientras haya un único bot activo
+
+INSERT INTO channel_identifiers (platform, identifier_type, identifier, channel_id, tenant_id)
+SELECT platform, 'webchat_id', config->'webchat_config'->>'id', id::text, tenant_id
+FROM channel_integrations
+WHERE platform = 'webchat' AND COALESCE(config->'webchat_config'->>'id', '') <> ''
+ON CONFLICT DO NOTHING;
+
+INSERT INTO channel_identifiers (platform, identifier_type, identifier, channel_id, tenant_id)
+SELECT platform, 'list_id', config->>'audience_id', id::text, tenant_id
+FROM channel_integrations
+WHERE platform = 'mailchimp' AND COALESCE(config->>'audience_id', '') <> ''
+ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE channel_identifiers IS 'Identificadores de plataforma usados para resolver el canal de un webhook';
+COMMENT ON COLUMN channel_identifiers.identifier_type IS 'phone_number_id, page_id, instagram_id, webhook_key, webchat_id o list_id';
+COMMENT ON COLUMN inbound_messages.quarantine_reason IS 'Motivo por el que el mensaje no se reenvió (canal desconocido o deshabilitado)';
@@ -0,0 +1,15 @@
+-- Migración para registrar el resultado de cada mensaje de un webhook en lote
+-- Ejecutar: psql -d your_database -f 004_add_inbound_message_results.sql
+
+-- Resultado por mensaje (índice, message_id, idempotency_key, estado, error)
+ALTER TABLE inbound_messages ADD COLUMN IF NOT EXISTS results JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- Cada entrada del outbox corresponde a un mensaje del webhook
+ALTER TABLE message_outbox ADD COLUMN IF NOT EXISTS item_index INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE message_outbox ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_message_outbox_idempotency_key ON message_outbox(idempotency_key);
+
+COMMENT ON COLUMN inbound_messages.results IS 'Resultado de entrega de cada mensaje contenido en el webhook, en orden';
+COMMENT ON COLUMN message_outbox.item_index IS 'Posición del mensaje dentro de inbound_messages.results';
+COMMENT ON COLUMN message_outbox.idempotency_key IS 'Clave enviada al servicio de mensajería en el header Idempotency-Key';
@@ -0,0 +1,25 @@
+-- Migración para procesar recibos de estado (entregado, leído, fallido)
+-- Ejecutar: psql -d your_database -f 005_add_message_status_receipts.sql
+
+-- Log de mensajes salientes
+CREATE TABLE IF NOT EXISTS outbound_message_logs (
+    id VARCHAR(255) PRIMARY KEY,
+    channel_id VARCHAR(255) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    content JSONB,
+    status VARCHAR(50) NOT NULL,
+    response JSONB,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- ID del mensaje en la plataforma para correlacionar los recibos
+ALTER TABLE outbound_message_logs ADD COLUMN IF NOT EXISTS platform_message_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_outbound_message_logs_platform_message_id ON outbound_message_logs(platform_message_id);
+CREATE INDEX IF NOT EXISTS idx_outbound_message_logs_recipient ON outbound_message_logs(channel_id, recipient, timestamp);
+
+-- El outbox transporta mensajes y eventos de estado
+ALTER TABLE message_outbox ADD COLUMN IF NOT EXISTS event_type VARCHAR(20) NOT NULL DEFAULT 'message';
+
+COMMENT ON COLUMN outbound_message_logs.status IS 'queued, sent, delivered, read o failed';
+COMMENT ON COLUMN message_outbox.event_type IS 'message (mensaje de usuario) o status (recibo de entrega/lectura)';
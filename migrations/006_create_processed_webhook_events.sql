@@ -0,0 +1,19 @@
+-- Migración para descartar webhooks reenviados por Meta y Telegram
+-- Ejecutar: psql -d your_database -f 006_create_processed_webhook_events.sql
+
+-- Eventos ya aceptados, por plataforma, canal e ID del evento en la plataforma
+CREATE TABLE IF NOT EXISTS processed_webhook_events (
+    platform VARCHAR(50) NOT NULL,
+    channel_id VARCHAR(255) NOT NULL DEFAULT '',
+    event_key VARCHAR(255) NOT NULL,
+    inbound_message_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CONSTRAINT uq_processed_webhook_events UNIQUE (platform, channel_id, event_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_webhook_events_expires_at ON processed_webhook_events(expires_at);
+
+COMMENT ON TABLE processed_webhook_events IS 'Registro de deduplicación de eventos de webhooks durante la ventana de retención';
+COMMENT ON COLUMN processed_webhook_events.event_key IS 'wamid, mid o update_id; los recibos agregan el estado (mid:delivered)';
+COMMENT ON COLUMN processed_webhook_events.channel_id IS 'Vacío si el canal no se resolvió; el update_id de Telegram solo es único por bot';
@@ -0,0 +1,46 @@
+-- Migración para crear los envíos masivos (broadcast) y su resultado por destinatario
+-- Ejecutar: psql -d your_database -f 007_create_broadcast_jobs.sql
+
+-- Trabajos de envío masivo
+CREATE TABLE IF NOT EXISTS broadcast_jobs (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    platforms JSONB NOT NULL,
+    channels JSONB NOT NULL,
+    content JSONB NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'loading' CHECK (status IN ('loading', 'queued', 'running', 'paused', 'completed', 'cancelled', 'failed')),
+    total_recipients INTEGER NOT NULL DEFAULT 0,
+    rejected_recipients INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcast_jobs_tenant_id ON broadcast_jobs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_broadcast_jobs_active ON broadcast_jobs(created_at) WHERE status IN ('queued', 'running');
+
+-- Destinatarios de cada trabajo con su resultado
+CREATE TABLE IF NOT EXISTS broadcast_recipients (
+    id BIGSERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES broadcast_jobs(id) ON DELETE CASCADE,
+    platform VARCHAR(50) NOT NULL,
+    channel_id VARCHAR(255) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'sent', 'failed', 'cancelled')),
+    message_id VARCHAR(255),
+    outbound_log_id VARCHAR(255),
+    error TEXT,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_broadcast_recipients UNIQUE (job_id, platform, recipient)
+);
+
+-- Índices para el reclamo de lotes y el conteo de progreso
+CREATE INDEX IF NOT EXISTS idx_broadcast_recipients_job_status ON broadcast_recipients(job_id, status, id);
+
+COMMENT ON TABLE broadcast_jobs IS 'Envíos masivos; el progreso se calcula desde broadcast_recipients';
+COMMENT ON COLUMN broadcast_jobs.channels IS 'Canal del tenant usado para cada plataforma destino';
+COMMENT ON COLUMN broadcast_jobs.rejected_recipients IS 'Filas de la carga descartadas por plataforma inválida o destinatario vacío';
+COMMENT ON COLUMN broadcast_recipients.locked_until IS 'Lease del worker que reclamó el destinatario; vencido se vuelve a reclamar';
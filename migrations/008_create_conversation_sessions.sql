@@ -0,0 +1,23 @@
+-- Migración para registrar la ventana de atención de 24 horas por contacto
+-- Ejecutar: psql -d your_database -f 008_create_conversation_sessions.sql
+
+-- Una fila por canal y contacto, actualizada con cada mensaje entrante
+CREATE TABLE IF NOT EXISTS conversation_sessions (
+    channel_id VARCHAR(255) NOT NULL,
+    contact_id VARCHAR(255) NOT NULL,
+    tenant_id VARCHAR(255) NOT NULL,
+    platform VARCHAR(50) NOT NULL,
+    last_inbound_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    window_expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT 'service',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (channel_id, contact_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_sessions_tenant_expires ON conversation_sessions(tenant_id, window_expires_at);
+
+COMMENT ON TABLE conversation_sessions IS 'Ventana de atención por contacto: fuera de ella Meta solo acepta plantillas';
+COMMENT ON COLUMN conversation_sessions.contact_id IS 'ID del contacto en la plataforma (wa_id, PSID, IGSID, chat_id)';
+COMMENT ON COLUMN conversation_sessions.window_expires_at IS 'last_inbound_at más la duración de la ventana';
+COMMENT ON COLUMN conversation_sessions.category IS 'service al abrirse por un mensaje del contacto; WhatsApp informa la categoría de la conversación en los recibos';
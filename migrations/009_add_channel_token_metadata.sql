@@ -0,0 +1,14 @@
+-- Migración para registrar el ciclo de vida del access token de cada canal
+-- Ejecutar: psql -d your_database -f 009_add_channel_token_metadata.sql
+
+ALTER TABLE channel_integrations ADD COLUMN IF NOT EXISTS token_expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE channel_integrations ADD COLUMN IF NOT EXISTS token_issued_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE channel_integrations ADD COLUMN IF NOT EXISTS token_last_validated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE channel_integrations ADD COLUMN IF NOT EXISTS token_last_rotated_at TIMESTAMP WITH TIME ZONE;
+
+-- El scheduler de rotación busca los tokens por vencer
+CREATE INDEX IF NOT EXISTS idx_channel_integrations_token_expires ON channel_integrations(token_expires_at)
+    WHERE token_expires_at IS NOT NULL;
+
+COMMENT ON COLUMN channel_integrations.token_expires_at IS 'Vencimiento informado por la plataforma; NULL si el token no vence (Telegram, Mailchimp, páginas de Meta)';
+COMMENT ON COLUMN channel_integrations.token_last_validated_at IS 'Última validación contra la plataforma (debug_token, getMe, ping)';
@@ -0,0 +1,35 @@
+-- Migración para crear los destinos de aviso por tenant y el historial de avisos de vencimiento de tokens
+-- Ejecutar: psql -d your_database -f 010_create_token_notifications.sql
+
+-- Destinos de aviso de cada tenant (webhook, email o evento)
+CREATE TABLE IF NOT EXISTS tenant_notification_settings (
+    tenant_id VARCHAR(255) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    destinations JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON COLUMN tenant_notification_settings.destinations IS 'Lista de destinos; el secreto de los webhooks se guarda sellado';
+
+-- Una fila por intento de entrega de un aviso a un destino
+CREATE TABLE IF NOT EXISTS notification_history (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    channel_id VARCHAR(255) NOT NULL,
+    platform VARCHAR(50) NOT NULL,
+    event_type VARCHAR(50) NOT NULL CHECK (event_type IN ('token_expiring', 'token_expired')),
+    destination_type VARCHAR(50) NOT NULL CHECK (destination_type IN ('webhook', 'email', 'event')),
+    destination TEXT NOT NULL,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('sent', 'failed')),
+    error TEXT,
+    dedup_key VARCHAR(255) NOT NULL,
+    token_expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_history_tenant_created ON notification_history(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notification_history_dedup_sent ON notification_history(dedup_key) WHERE status = 'sent';
+
+COMMENT ON TABLE notification_history IS 'Avisos de vencimiento de tokens entregados o fallidos; los fallidos se reintentan en la siguiente revisión';
+COMMENT ON COLUMN notification_history.dedup_key IS 'Canal, motivo, vencimiento y destino: un mismo vencimiento se avisa una sola vez por destino';
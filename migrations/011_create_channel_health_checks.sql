@@ -0,0 +1,26 @@
+-- Migración para crear el historial de verificaciones de conectividad de los canales
+-- Ejecutar: psql -d your_database -f 011_create_channel_health_checks.sql
+
+-- Una fila por verificación de un canal contra su plataforma
+CREATE TABLE IF NOT EXISTS channel_health_checks (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id VARCHAR(255) NOT NULL,
+    tenant_id VARCHAR(255) NOT NULL,
+    platform VARCHAR(50) NOT NULL,
+    healthy BOOLEAN NOT NULL,
+    error TEXT,
+    details JSONB,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Historial de un canal, las verificaciones más recientes primero
+CREATE INDEX IF NOT EXISTS idx_channel_health_checks_channel ON channel_health_checks(channel_id, checked_at DESC);
+
+-- Limpieza de las verificaciones fuera del período de retención
+CREATE INDEX IF NOT EXISTS idx_channel_health_checks_checked_at ON channel_health_checks(checked_at);
+
+COMMENT ON TABLE channel_health_checks IS 'Resultados de las verificaciones periódicas de conectividad de cada canal';
+COMMENT ON COLUMN channel_health_checks.details IS 'Datos informados por la plataforma: webhook de Telegram, número de WhatsApp o página de Messenger';
+COMMENT ON COLUMN channel_health_checks.consecutive_failures IS 'Fallos seguidos del canal incluyendo esta verificación; al llegar al umbral el canal pasa a error';
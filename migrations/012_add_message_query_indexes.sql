@@ -0,0 +1,16 @@
+-- Migración para la consulta de mensajes entrantes y salientes con filtros y paginación por cursor
+-- Ejecutar: psql -d your_database -f 012_add_message_query_indexes.sql
+
+-- Páginas de mensajes entrantes, los más recientes primero, por tenant y por canal
+CREATE INDEX IF NOT EXISTS idx_inbound_messages_received_at ON inbound_messages(received_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_inbound_messages_tenant_received_at ON inbound_messages(tenant_id, received_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_inbound_messages_channel_received_at ON inbound_messages(channel_id, received_at DESC, id DESC);
+
+-- Filtro por remitente: results guarda el remitente de cada mensaje del webhook
+CREATE INDEX IF NOT EXISTS idx_inbound_messages_results ON inbound_messages USING GIN (results jsonb_path_ops);
+
+-- Páginas del log de mensajes salientes, los más recientes primero
+CREATE INDEX IF NOT EXISTS idx_outbound_message_logs_timestamp ON outbound_message_logs(timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_outbound_message_logs_channel_timestamp ON outbound_message_logs(channel_id, timestamp DESC, id DESC);
+
+COMMENT ON COLUMN inbound_messages.results IS 'Resultado de entrega de cada mensaje contenido en el webhook, en orden, con su remitente';
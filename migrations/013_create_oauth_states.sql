@@ -0,0 +1,24 @@
+-- Migración para guardar el state de los flujos OAuth2 con su verificador PKCE
+-- Ejecutar: psql -d your_database -f 013_create_oauth_states.sql
+
+-- Un state por autorización iniciada; se consume una sola vez en el callback
+CREATE TABLE IF NOT EXISTS oauth_states (
+    state_token VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    channel_id VARCHAR(255) NOT NULL,
+    calendar_type VARCHAR(50) NOT NULL,
+    code_verifier VARCHAR(255) NOT NULL,
+    return_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    consumed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Limpieza de los states vencidos
+CREATE INDEX IF NOT EXISTS idx_oauth_states_expires_at ON oauth_states(expires_at);
+
+COMMENT ON TABLE oauth_states IS 'State de las autorizaciones OAuth2 de Google Calendar en curso';
+COMMENT ON COLUMN oauth_states.channel_id IS 'Canal de la integración creada al iniciar la autorización';
+COMMENT ON COLUMN oauth_states.code_verifier IS 'Verificador PKCE enviado al intercambiar el código';
+COMMENT ON COLUMN oauth_states.return_url IS 'URL del tenant a la que vuelve el navegador al terminar';
+COMMENT ON COLUMN oauth_states.consumed_at IS 'Momento en que el callback usó el state; no se acepta de nuevo';
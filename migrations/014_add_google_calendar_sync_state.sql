@@ -0,0 +1,17 @@
+-- Migración para la sincronización incremental de Google Calendar
+-- Ejecutar: psql -d your_database -f 014_add_google_calendar_sync_state.sql
+
+-- Soft delete de integraciones y eventos, usado por las consultas del repositorio
+ALTER TABLE google_calendar_integrations ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE calendar_events ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Estado de sincronización de cada integración
+ALTER TABLE google_calendar_integrations ADD COLUMN IF NOT EXISTS sync_token TEXT;
+ALTER TABLE google_calendar_integrations ADD COLUMN IF NOT EXISTS last_sync_at TIMESTAMP WITH TIME ZONE;
+
+-- Un solo evento local vigente por evento de Google en cada canal
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_events_channel_google_id
+    ON calendar_events(channel_id, google_id) WHERE deleted_at IS NULL;
+
+COMMENT ON COLUMN google_calendar_integrations.sync_token IS 'nextSyncToken de la última sincronización completa, vacío para forzar una sincronización completa';
+COMMENT ON COLUMN google_calendar_integrations.last_sync_at IS 'Fecha de la última sincronización exitosa';
@@ -0,0 +1,24 @@
+-- Migración para el registro de canales push de Google Calendar
+-- Ejecutar: psql -d your_database -f 015_create_calendar_watch_channels.sql
+
+-- Un registro por canal abierto con events.watch; se detiene al renovarlo o al revocar el acceso
+CREATE TABLE IF NOT EXISTS calendar_watch_channels (
+    id VARCHAR(255) PRIMARY KEY,
+    channel_id VARCHAR(255) NOT NULL,
+    calendar_id VARCHAR(255) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+    token VARCHAR(255) NOT NULL,
+    expiration TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    stopped_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Canales vigentes de cada integración y próximos a vencer
+CREATE INDEX IF NOT EXISTS idx_calendar_watch_channels_channel_id ON calendar_watch_channels(channel_id) WHERE stopped_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_calendar_watch_channels_expiration ON calendar_watch_channels(expiration) WHERE stopped_at IS NULL;
+
+COMMENT ON TABLE calendar_watch_channels IS 'Canales de notificaciones push abiertos en Google Calendar';
+COMMENT ON COLUMN calendar_watch_channels.id IS 'ID del canal, recibido en el header X-Goog-Channel-ID';
+COMMENT ON COLUMN calendar_watch_channels.channel_id IS 'Canal de la integración de Google Calendar';
+COMMENT ON COLUMN calendar_watch_channels.resource_id IS 'ID del recurso observado, recibido en el header X-Goog-Resource-ID';
+COMMENT ON COLUMN calendar_watch_channels.token IS 'Token del canal, recibido en el header X-Goog-Channel-Token';
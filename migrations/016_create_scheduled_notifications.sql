@@ -0,0 +1,31 @@
+-- Migración para los recordatorios programados de eventos de Google Calendar
+-- Ejecutar: psql -d your_database -f 016_create_scheduled_notifications.sql
+
+-- Un registro por recordatorio de cada evento; el scheduler de cada réplica los reclama con SKIP LOCKED
+CREATE TABLE IF NOT EXISTS scheduled_notifications (
+    id UUID PRIMARY KEY,
+    event_id UUID NOT NULL REFERENCES calendar_events(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(255) NOT NULL,
+    channel_id VARCHAR(255) NOT NULL,
+    reminder_minutes INTEGER NOT NULL,
+    fire_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'sent', 'cancelled', 'skipped', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Un mismo recordatorio a la misma hora existe una sola vez, así reprogramar no lo vuelve a enviar
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_notifications_reminder ON scheduled_notifications(event_id, reminder_minutes, fire_at);
+-- Índice parcial para el polling del scheduler
+CREATE INDEX IF NOT EXISTS idx_scheduled_notifications_due ON scheduled_notifications(next_attempt_at) WHERE status IN ('pending', 'processing');
+
+COMMENT ON TABLE scheduled_notifications IS 'Recordatorios programados de eventos de Google Calendar';
+COMMENT ON COLUMN scheduled_notifications.fire_at IS 'Inicio del evento menos reminder_minutes';
+COMMENT ON COLUMN scheduled_notifications.next_attempt_at IS 'fire_at hasta el primer fallo y luego la hora del siguiente reintento';
+COMMENT ON COLUMN scheduled_notifications.locked_until IS 'Lease del scheduler que reclamó el recordatorio; vencido se vuelve a reclamar';
+COMMENT ON COLUMN scheduled_notifications.status IS 'pending, processing, sent, cancelled (evento eliminado o movido), skipped (misfire) o failed';
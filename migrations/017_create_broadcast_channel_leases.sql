@@ -0,0 +1,14 @@
+-- Migración para que cada canal de los envíos masivos lo atienda una sola réplica a la vez
+-- Ejecutar: psql -d your_database -f 017_create_broadcast_channel_leases.sql
+
+-- El límite de mensajes por segundo se aplica en la réplica que tiene el canal; las demás no
+-- reclaman sus destinatarios hasta que lo libere o venza el lease
+CREATE TABLE IF NOT EXISTS broadcast_channel_leases (
+    channel_id VARCHAR(255) PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+COMMENT ON TABLE broadcast_channel_leases IS 'Réplica que envía los destinatarios de cada canal';
+COMMENT ON COLUMN broadcast_channel_leases.holder IS 'Identificador del motor de envíos de la réplica';
+COMMENT ON COLUMN broadcast_channel_leases.locked_until IS 'Vencido, otra réplica puede tomar el canal';
@@ -0,0 +1,235 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"it-integration-service/pkg/logger"
+)
+
+// ErrNotFound indica que el proveedor no tiene el secreto
+var ErrNotFound = errors.New("secret not found")
+
+// Provider resuelve secretos por nombre al momento de usarlos, de modo que rotarlos no
+// requiera reiniciar el servicio. Los nombres son en minúsculas con guiones bajos, p. ej.
+// "whatsapp_webhook_secret".
+type Provider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// Resolve retorna el secreto o fallback si el proveedor es nil o no lo tiene
+func Resolve(ctx context.Context, provider Provider, name, fallback string) (string, error) {
+	if provider == nil {
+		return fallback, nil
+	}
+
+	value, err := provider.GetSecret(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return fallback, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// EnvProvider lee los secretos de variables de entorno con el nombre en mayúsculas
+type EnvProvider struct{}
+
+// NewEnvProvider crea un proveedor de variables de entorno
+func NewEnvProvider() *EnvProvider {
+	return &EnvProvider{}
+}
+
+func (p *EnvProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	value := os.Getenv(strings.ToUpper(name))
+	if value == "" {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return value, nil
+}
+
+// FileProvider lee cada secreto de un archivo con su nombre dentro de un directorio, como los
+// secretos montados por Docker o Kubernetes
+type FileProvider struct {
+	dir string
+}
+
+// NewFileProvider crea un proveedor que lee los secretos de dir
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{dir: dir}
+}
+
+func (p *FileProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	if strings.ContainsAny(name, `/\`) || name == ".." {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %s: %w", name, err)
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return value, nil
+}
+
+// VaultKV es lo que VaultProvider necesita de Vault; vault.Client lo implementa
+type VaultKV interface {
+	GetKVSecret(ctx context.Context, mount, path string) (map[string]interface{}, error)
+	RenewToken(ctx context.Context) (time.Duration, error)
+}
+
+// VaultProvider lee los secretos como claves de un secreto del motor KV v2
+type VaultProvider struct {
+	client     VaultKV
+	mount      string
+	path       string
+	isNotFound func(error) bool
+}
+
+// NewVaultProvider crea un proveedor que lee las claves del secreto mount/path. isNotFound
+// reconoce el error del cliente cuando el secreto no existe.
+func NewVaultProvider(client VaultKV, mount, path string, isNotFound func(error) bool) *VaultProvider {
+	return &VaultProvider{
+		client:     client,
+		mount:      mount,
+		path:       path,
+		isNotFound: isNotFound,
+	}
+}
+
+func (p *VaultProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	data, err := p.client.GetKVSecret(ctx, p.mount, p.path)
+	if err != nil {
+		if p.isNotFound != nil && p.isNotFound(err) {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return "", err
+	}
+
+	raw, ok := data[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s in vault is not a string", name)
+	}
+	return value, nil
+}
+
+// StartTokenRenewal renueva el token de Vault a la mitad de su TTL hasta que se cancele el
+// contexto. Los tokens no renovables, como el root token de desarrollo, no se renuevan.
+func (p *VaultProvider) StartTokenRenewal(ctx context.Context, logger logger.Logger) {
+	go func() {
+		for {
+			ttl, err := p.client.RenewToken(ctx)
+			wait := ttl / 2
+			switch {
+			case err != nil:
+				logger.Error("Failed to renew vault token", map[string]interface{}{
+					"error": err.Error(),
+				})
+				wait = 30 * time.Second
+			case ttl == 0:
+				logger.Info("Vault token is not renewable, skipping renewal")
+				return
+			case wait < time.Minute:
+				wait = time.Minute
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// Chain consulta los proveedores en orden y usa el primero que tenga el secreto
+type Chain []Provider
+
+func (c Chain) GetSecret(ctx context.Context, name string) (string, error) {
+	for _, provider := range c {
+		value, err := provider.GetSecret(ctx, name)
+		if err == nil {
+			return value, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+type cachedSecret struct {
+	value     string
+	err       error
+	expiresAt time.Time
+}
+
+// CachedProvider guarda los secretos resueltos durante ttl. Si el proveedor falla al refrescar
+// un secreto vencido se sigue usando el último valor conocido.
+type CachedProvider struct {
+	provider Provider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedSecret
+}
+
+// NewCachedProvider envuelve provider con un cache de ttl
+func NewCachedProvider(provider Provider, ttl time.Duration) *CachedProvider {
+	return &CachedProvider{
+		provider: provider,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]cachedSecret),
+	}
+}
+
+func (p *CachedProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	p.mu.Lock()
+	entry, ok := p.entries[name]
+	p.mu.Unlock()
+	if ok && p.now().Before(entry.expiresAt) {
+		return entry.value, entry.err
+	}
+
+	value, err := p.provider.GetSecret(ctx, name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		if ok && entry.err == nil {
+			return entry.value, nil
+		}
+		return "", err
+	}
+
+	// Los secretos inexistentes también se cachean para no consultar al proveedor en cada request
+	p.mu.Lock()
+	p.entries[name] = cachedSecret{value: value, err: err, expiresAt: p.now().Add(p.ttl)}
+	p.mu.Unlock()
+
+	return value, err
+}
+
+// Invalidate descarta el valor cacheado del secreto
+func (p *CachedProvider) Invalidate(name string) {
+	p.mu.Lock()
+	delete(p.entries, name)
+	p.mu.Unlock()
+}
@@ -0,0 +1,133 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVault simula un secreto KV v2 que se puede rotar entre lecturas
+type fakeVault struct {
+	data  map[string]interface{}
+	err   error
+	reads int
+}
+
+var errFakeNotFound = errors.New("fake: secret not found")
+
+func (v *fakeVault) GetKVSecret(ctx context.Context, mount, path string) (map[string]interface{}, error) {
+	v.reads++
+	if v.err != nil {
+		return nil, v.err
+	}
+	return v.data, nil
+}
+
+func (v *fakeVault) RenewToken(ctx context.Context) (time.Duration, error) {
+	return 0, nil
+}
+
+func TestEnvProviderUsesUppercaseName(t *testing.T) {
+	t.Setenv("WHATSAPP_WEBHOOK_SECRET", "from-env")
+
+	value, err := NewEnvProvider().GetSecret(context.Background(), "whatsapp_webhook_secret")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+
+	_, err = NewEnvProvider().GetSecret(context.Background(), "missing_secret")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFileProviderReadsTrimmedFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mp_access_token"), []byte("APP_USR-123\n"), 0o600))
+	provider := NewFileProvider(dir)
+
+	value, err := provider.GetSecret(context.Background(), "mp_access_token")
+	require.NoError(t, err)
+	assert.Equal(t, "APP_USR-123", value)
+
+	_, err = provider.GetSecret(context.Background(), "mp_webhook_secret")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = provider.GetSecret(context.Background(), "../etc/passwd")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
+
+func TestVaultProviderReadsKeysOfSecret(t *testing.T) {
+	client := &fakeVault{data: map[string]interface{}{"google_client_secret": "GOCSPX-1"}}
+	provider := NewVaultProvider(client, "secret", "integration-service", func(err error) bool {
+		return errors.Is(err, errFakeNotFound)
+	})
+
+	value, err := provider.GetSecret(context.Background(), "google_client_secret")
+	require.NoError(t, err)
+	assert.Equal(t, "GOCSPX-1", value)
+
+	_, err = provider.GetSecret(context.Background(), "google_client_id")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	client.err = errFakeNotFound
+	_, err = provider.GetSecret(context.Background(), "google_client_secret")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestChainFallsBackOnlyWhenNotFound(t *testing.T) {
+	t.Setenv("MESSENGER_VERIFY_TOKEN", "env-token")
+	client := &fakeVault{data: map[string]interface{}{}}
+	chain := Chain{NewVaultProvider(client, "secret", "app", nil), NewEnvProvider()}
+
+	value, err := chain.GetSecret(context.Background(), "messenger_verify_token")
+	require.NoError(t, err)
+	assert.Equal(t, "env-token", value)
+
+	// Si Vault falla no se usa silenciosamente el valor de entorno
+	client.err = errors.New("connection refused")
+	_, err = chain.GetSecret(context.Background(), "messenger_verify_token")
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestCachedProviderRefreshesAfterTTL(t *testing.T) {
+	client := &fakeVault{data: map[string]interface{}{"whatsapp_webhook_secret": "v1"}}
+	cached := NewCachedProvider(NewVaultProvider(client, "secret", "app", nil), time.Minute)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cached.now = func() time.Time { return now }
+
+	value, err := cached.GetSecret(context.Background(), "whatsapp_webhook_secret")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", value)
+
+	// Rotado en Vault: se sigue usando v1 hasta que vence el cache
+	client.data = map[string]interface{}{"whatsapp_webhook_secret": "v2"}
+	value, _ = cached.GetSecret(context.Background(), "whatsapp_webhook_secret")
+	assert.Equal(t, "v1", value)
+	assert.Equal(t, 1, client.reads)
+
+	now = now.Add(2 * time.Minute)
+	value, _ = cached.GetSecret(context.Background(), "whatsapp_webhook_secret")
+	assert.Equal(t, "v2", value)
+
+	// Con Vault caído se conserva el último valor conocido
+	client.err = errors.New("connection refused")
+	now = now.Add(2 * time.Minute)
+	value, err = cached.GetSecret(context.Background(), "whatsapp_webhook_secret")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", value)
+}
+
+func TestResolveUsesFallbackWhenMissing(t *testing.T) {
+	value, err := Resolve(context.Background(), nil, "mp_webhook_secret", "static")
+	require.NoError(t, err)
+	assert.Equal(t, "static", value)
+
+	value, err = Resolve(context.Background(), Chain{}, "mp_webhook_secret", "static")
+	require.NoError(t, err)
+	assert.Equal(t, "static", value)
+}
@@ -1,15 +1,27 @@
 package vault
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"it-integration-service/internal/config"
 	"github.com/hashicorp/vault/api"
 )
 
+// ErrSecretNotFound indica que el secreto no existe en Vault
+var ErrSecretNotFound = errors.New("secret not found")
+
 type Client interface {
 	GetSecret(path string) (map[string]interface{}, error)
 	GetSecretValue(path, key string) (string, error)
+	// GetKVSecret lee la última versión de un secreto del motor KV v2 montado en mount
+	GetKVSecret(ctx context.Context, mount, path string) (map[string]interface{}, error)
+	// RenewToken renueva el token del cliente y retorna su nuevo TTL; 0 si no es renovable
+	RenewToken(ctx context.Context) (time.Duration, error)
+	// Health consulta sys/health y falla si Vault no está inicializado o está sellado
+	Health(ctx context.Context) error
 }
 
 type vaultClient struct {
@@ -68,6 +80,48 @@ func (v *vaultClient) GetSecretValue(path, key string) (string, error) {
 	return strValue, nil
 }
 
+func (v *vaultClient) GetKVSecret(ctx context.Context, mount, path string) (map[string]interface{}, error) {
+	secret, err := v.client.KVv2(mount).Get(ctx, path)
+	if err != nil {
+		if errors.Is(err, api.ErrSecretNotFound) {
+			return nil, fmt.Errorf("%w: %s/%s", ErrSecretNotFound, mount, path)
+		}
+		return nil, fmt.Errorf("failed to read kv secret from vault: %w", err)
+	}
+
+	return secret.Data, nil
+}
+
+func (v *vaultClient) RenewToken(ctx context.Context) (time.Duration, error) {
+	secret, err := v.client.Auth().Token().RenewSelfWithContext(ctx, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to renew vault token: %w", err)
+	}
+
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil || !renewable {
+		return 0, err
+	}
+
+	return secret.TokenTTL()
+}
+
+func (v *vaultClient) Health(ctx context.Context) error {
+	health, err := v.client.Sys().HealthWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check vault health: %w", err)
+	}
+
+	if !health.Initialized {
+		return errors.New("vault is not initialized")
+	}
+	if health.Sealed {
+		return errors.New("vault is sealed")
+	}
+
+	return nil
+}
+
 // Ejemplo de uso comentado:
 /*
 // Para obtener un secreto completo:
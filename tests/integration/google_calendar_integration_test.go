@@ -0,0 +1,316 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/domain"
+	"it-integration-service/internal/repository"
+	"it-integration-service/internal/routes"
+	"it-integration-service/internal/services"
+	testingPkg "it-integration-service/internal/testing"
+	"it-integration-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/calendar/v3"
+)
+
+// fakeGoogleAPI simula los endpoints OAuth2 y de Calendar que usa el módulo
+type fakeGoogleAPI struct {
+	mu      sync.Mutex
+	watches []calendar.Channel
+}
+
+func (g *fakeGoogleAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/token":
+		_ = r.ParseForm()
+		if r.PostForm.Get("code") != "valid-code" || r.PostForm.Get("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"access-1","refresh_token":"refresh-1","token_type":"Bearer","expires_in":3600}`))
+	case "/calendar/v3/users/me/calendarList/primary":
+		_, _ = w.Write([]byte(`{"id":"ana@example.com","summary":"Agenda de Ana"}`))
+	case "/calendar/v3/calendars/primary/events":
+		_, _ = w.Write([]byte(`{"items":[{"id":"evt-1","status":"confirmed","summary":"Demo","start":{"dateTime":"2026-03-02T10:00:00Z"},"end":{"dateTime":"2026-03-02T11:00:00Z"}}],"nextSyncToken":"sync-1"}`))
+	case "/calendar/v3/calendars/primary/events/watch":
+		var channel calendar.Channel
+		_ = json.NewDecoder(r.Body).Decode(&channel)
+		g.mu.Lock()
+		g.watches = append(g.watches, channel)
+		g.mu.Unlock()
+		_, _ = fmt.Fprintf(w, `{"kind":"api#channel","id":%q,"resourceId":"resource-%d","resourceUri":"https://www.googleapis.com/calendar/v3/calendars/primary/events","expiration":"%d"}`,
+			channel.Id, len(g.watches), channel.Expiration)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (g *fakeGoogleAPI) lastWatch() *calendar.Channel {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.watches) == 0 {
+		return nil
+	}
+	return &g.watches[len(g.watches)-1]
+}
+
+// calendarRequest envía una request al router y decodifica el campo data de la respuesta
+func calendarRequest(t *testing.T, router *gin.Engine, req *http.Request, wantStatus int, data interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, wantStatus, w.Code, w.Body.String())
+	if data != nil {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &struct {
+			Data interface{} `json:"data"`
+		}{Data: data}))
+	}
+}
+
+func TestGoogleCalendarModuleEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	containers, err := testingPkg.SetupPostgresContainer(ctx)
+	require.NoError(t, err)
+	defer containers.Cleanup(ctx)
+
+	pgConn, err := containers.GetPostgresConnectionString(ctx)
+	require.NoError(t, err)
+	pgURL, err := url.Parse(pgConn)
+	require.NoError(t, err)
+	password, _ := pgURL.User.Password()
+	db, err := repository.NewPostgresDB(pgURL.Hostname(), pgURL.Port(), pgURL.User.Username(), password, "test_db", "disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Las migraciones se aplican una sola vez aunque el servidor inicie de nuevo
+	migrations := os.DirFS("../../migrations")
+	applied, err := repository.ApplyMigrations(ctx, db, migrations, repository.GoogleCalendarMigrations)
+	require.NoError(t, err)
+	assert.Equal(t, repository.GoogleCalendarMigrations, applied)
+	applied, err = repository.ApplyMigrations(ctx, db, migrations, repository.GoogleCalendarMigrations)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	google := &fakeGoogleAPI{}
+	server := httptest.NewServer(google)
+	defer server.Close()
+
+	cfg := &config.Config{GoogleCalendar: config.GoogleCalendarConfig{
+		Enabled:          true,
+		ClientID:         "client-id",
+		ClientSecret:     "client-secret",
+		RedirectURL:      "https://integrations.example.com/api/v1/integrations/google-calendar/callback",
+		Scopes:           []string{"https://www.googleapis.com/auth/calendar"},
+		APIBaseURL:       server.URL,
+		TokenURL:         server.URL + "/token",
+		AuthURL:          server.URL + "/auth",
+		WebhookURL:       "https://integrations.example.com/api/v1/webhooks/google-calendar",
+		DefaultTimeZone:  "America/Mexico_City",
+		OAuthStateTTL:    10 * time.Minute,
+		WatchChannelTTL:  7 * 24 * time.Hour,
+		WatchRenewBefore: 24 * time.Hour,
+
+		ReminderPollInterval:  time.Second,
+		ReminderBatchSize:     100,
+		ReminderLease:         time.Minute,
+		ReminderMaxAttempts:   5,
+		ReminderRetryBackoff:  time.Second,
+		ReminderMisfireGrace:  5 * time.Minute,
+		ReminderMisfirePolicy: "fire_before_start",
+	}}
+	require.NoError(t, cfg.GoogleCalendar.Validate(ctx))
+
+	// Una configuración incompleta se rechaza al iniciar con todos sus problemas
+	incomplete := cfg.GoogleCalendar
+	incomplete.ClientSecret = ""
+	incomplete.RedirectURL = "/callback"
+	err = incomplete.Validate(ctx)
+	assert.ErrorIs(t, err, config.ErrIncompleteGoogleCalendarConfig)
+	assert.Contains(t, err.Error(), "GOOGLE_CLIENT_SECRET")
+	assert.Contains(t, err.Error(), "GOOGLE_REDIRECT_URL")
+
+	log := logger.NewLogger("error")
+	encryption, err := services.NewEncryptionService("0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+	calendarRepo := repository.NewGoogleCalendarRepository(db.DB, log)
+	setupService := services.NewGoogleCalendarSetupService(
+		&cfg.GoogleCalendar,
+		calendarRepo,
+		repository.NewOAuthStateRepository(db),
+		repository.NewCalendarWatchChannelRepository(db),
+		log,
+		encryption,
+	)
+	notificationService := services.NewNotificationService(repository.NewScheduledNotificationRepository(db), log)
+	eventService := services.NewGoogleCalendarService(&cfg.GoogleCalendar, setupService, calendarRepo, notificationService, log, encryption)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	routes.SetupGoogleCalendarRoutes(router, cfg, log, setupService, eventService, notificationService)
+
+	// Inicio del flujo OAuth2
+	var auth services.AuthURLResponse
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/integrations/google-calendar/auth", strings.NewReader(`{"tenant_id":"tenant-1","calendar_type":"work"}`))
+	req.Header.Set("Content-Type", "application/json")
+	calendarRequest(t, router, req, http.StatusOK, &auth)
+	assert.True(t, strings.HasPrefix(auth.AuthURL, server.URL+"/auth"))
+
+	// Callback: el código se intercambia en Google y los tokens quedan encriptados
+	callbackPath := "/api/v1/integrations/google-calendar/callback?code=valid-code&state=" + url.QueryEscape(auth.StateToken)
+	var callback map[string]interface{}
+	calendarRequest(t, router, httptest.NewRequest(http.MethodGet, callbackPath, nil), http.StatusOK, &callback)
+	channelID, _ := callback["channel_id"].(string)
+	require.NotEmpty(t, channelID)
+	assert.Equal(t, "Agenda de Ana", callback["calendar_name"])
+
+	var storedAccessToken string
+	require.NoError(t, db.DB.QueryRowContext(ctx, `SELECT access_token FROM google_calendar_integrations WHERE channel_id = $1`, channelID).Scan(&storedAccessToken))
+	assert.NotEqual(t, "access-1", storedAccessToken)
+	accessToken, err := encryption.Decrypt(storedAccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", accessToken)
+
+	// El state es de un solo uso
+	calendarRequest(t, router, httptest.NewRequest(http.MethodGet, callbackPath, nil), http.StatusBadRequest, nil)
+
+	// Canal push sobre el calendario de la integración
+	var setup map[string]interface{}
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/integrations/google-calendar/webhook/setup", strings.NewReader(fmt.Sprintf(`{"tenant_id":"tenant-1","channel_id":%q,"calendar_id":"primary"}`, channelID)))
+	req.Header.Set("Content-Type", "application/json")
+	calendarRequest(t, router, req, http.StatusOK, &setup)
+	watch := google.lastWatch()
+	require.NotNil(t, watch)
+	assert.Equal(t, watch.Id, setup["watch_channel_id"])
+	assert.Equal(t, cfg.GoogleCalendar.WebhookURL, watch.Address)
+
+	notification := func(token string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/google-calendar", nil)
+		req.Header.Set("X-Goog-Channel-ID", watch.Id)
+		req.Header.Set("X-Goog-Resource-ID", "resource-1")
+		req.Header.Set("X-Goog-Resource-State", "exists")
+		req.Header.Set("X-Goog-Message-Number", "2")
+		req.Header.Set("X-Goog-Channel-Token", token)
+		return req
+	}
+
+	// Una notificación con otro token no dispara la sincronización
+	calendarRequest(t, router, notification("other-token"), http.StatusUnauthorized, nil)
+
+	// La notificación se enruta a la integración y sincroniza sus eventos
+	var routed map[string]interface{}
+	calendarRequest(t, router, notification(watch.Token), http.StatusOK, &routed)
+	assert.Equal(t, channelID, routed["channel_id"])
+
+	assert.Eventually(t, func() bool {
+		var syncToken string
+		err := db.DB.QueryRowContext(ctx, `SELECT COALESCE(sync_token, '') FROM google_calendar_integrations WHERE channel_id = $1`, channelID).Scan(&syncToken)
+		return err == nil && syncToken == "sync-1"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	var tenantEvents map[string]interface{}
+	calendarRequest(t, router, httptest.NewRequest(http.MethodGet, "/api/v1/integrations/google-calendar/events/tenant/tenant-1", nil), http.StatusOK, &tenantEvents)
+	assert.EqualValues(t, 1, tenantEvents["total_events"])
+}
+
+func TestScheduledNotificationsClaimOnceAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+
+	containers, err := testingPkg.SetupPostgresContainer(ctx)
+	require.NoError(t, err)
+	defer containers.Cleanup(ctx)
+
+	pgConn, err := containers.GetPostgresConnectionString(ctx)
+	require.NoError(t, err)
+	pgURL, err := url.Parse(pgConn)
+	require.NoError(t, err)
+	password, _ := pgURL.User.Password()
+	db, err := repository.NewPostgresDB(pgURL.Hostname(), pgURL.Port(), pgURL.User.Username(), password, "test_db", "disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = repository.ApplyMigrations(ctx, db, os.DirFS("../../migrations"), repository.GoogleCalendarMigrations)
+	require.NoError(t, err)
+
+	log := logger.NewLogger("error")
+	calendarRepo := repository.NewGoogleCalendarRepository(db.DB, log)
+	scheduleRepo := repository.NewScheduledNotificationRepository(db)
+	notificationService := services.NewNotificationService(scheduleRepo, log)
+
+	// Recordatorios que vencen en un segundo para 20 eventos
+	start := time.Now().Add(time.Minute + time.Second).UTC().Truncate(time.Second)
+	for i := 0; i < 20; i++ {
+		event := &domain.CalendarEvent{
+			ID:         uuid.New().String(),
+			TenantID:   "tenant-1",
+			ChannelID:  "channel-1",
+			GoogleID:   fmt.Sprintf("evt-%d", i),
+			CalendarID: "primary",
+			Summary:    "Demo",
+			StartTime:  start,
+			EndTime:    start.Add(time.Hour),
+			Status:     domain.EventStatusConfirmed,
+			Visibility: domain.EventVisibilityDefault,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		}
+		require.NoError(t, calendarRepo.CreateEvent(ctx, event))
+		require.NoError(t, notificationService.ScheduleReminders(ctx, event, []int{1}))
+		// Reprogramar a la misma hora no duplica el recordatorio
+		require.NoError(t, notificationService.ScheduleReminders(ctx, event, []int{1}))
+	}
+	time.Sleep(1500 * time.Millisecond)
+
+	// Cuatro réplicas reclaman a la vez: cada recordatorio lo obtiene una sola
+	var mu sync.Mutex
+	claimed := make(map[string]int)
+	var wg sync.WaitGroup
+	for replica := 0; replica < 4; replica++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				notifications, err := scheduleRepo.ClaimDue(ctx, 3, time.Minute)
+				if !assert.NoError(t, err) || len(notifications) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, notification := range notifications {
+					claimed[notification.ID]++
+				}
+				mu.Unlock()
+				for _, notification := range notifications {
+					assert.NoError(t, scheduleRepo.MarkSent(ctx, notification.ID, time.Now()))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, claimed, 20)
+	for id, times := range claimed {
+		assert.Equal(t, 1, times, id)
+	}
+
+	// Cada recordatorio quedó enviado una vez, sin duplicados por reprogramar a la misma hora
+	var sent int
+	require.NoError(t, db.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM scheduled_notifications WHERE status = 'sent'`).Scan(&sent))
+	assert.Equal(t, 20, sent)
+}
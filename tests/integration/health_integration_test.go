@@ -4,10 +4,16 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
+	"it-integration-service/internal/config"
+	"it-integration-service/internal/repository"
 	"it-integration-service/internal/services"
 	testingPkg "it-integration-service/internal/testing"
+	"it-integration-service/pkg/logger"
+	"it-integration-service/pkg/vault"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -17,6 +23,8 @@ import (
 type IntegrationTestSuite struct {
 	suite.Suite
 	containers *testingPkg.TestContainers
+	messaging  *httptest.Server
+	db         *repository.PostgresDB
 	router     *gin.Engine
 }
 
@@ -32,23 +40,28 @@ func (suite *IntegrationTestSuite) SetupSuite() {
 	gin.SetMode(gin.TestMode)
 	suite.router = gin.New()
 
+	// Servicio de mensajería simulado
+	suite.messaging = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
 	// Setup basic routes for testing
-	healthService := services.NewHealthService(nil, nil)
+	healthService := suite.newHealthService(ctx, "", nil)
 
 	// Setup only health endpoints for testing
 	api := suite.router.Group("/api/v1")
 	{
 		api.GET("/health", func(c *gin.Context) {
-			status := healthService.CheckHealth()
+			status := healthService.CheckHealth(c.Request.Context())
 			c.JSON(http.StatusOK, gin.H{
 				"status": "healthy",
 				"data":   status,
 			})
 		})
 		api.GET("/ready", func(c *gin.Context) {
-			status := healthService.CheckReadiness()
-			if status.Status == "ready" {
-				c.JSON(http.StatusOK, gin.H{"status": "ready"})
+			status := healthService.CheckReadiness(c.Request.Context())
+			if status.Status == "ready" || status.Status == services.HealthStatusDegraded {
+				c.JSON(http.StatusOK, gin.H{"status": status.Status})
 			} else {
 				c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready"})
 			}
@@ -56,8 +69,42 @@ func (suite *IntegrationTestSuite) SetupSuite() {
 	}
 }
 
+// newHealthService crea el servicio de health contra los contenedores; vaultAddress vacío usa
+// el Vault del contenedor
+func (suite *IntegrationTestSuite) newHealthService(ctx context.Context, vaultAddress string, criticality map[string]string) *services.HealthService {
+	if suite.db == nil {
+		pgConn, err := suite.containers.GetPostgresConnectionString(ctx)
+		suite.Require().NoError(err)
+		pgURL, err := url.Parse(pgConn)
+		suite.Require().NoError(err)
+		password, _ := pgURL.User.Password()
+		suite.db, err = repository.NewPostgresDB(pgURL.Hostname(), pgURL.Port(), pgURL.User.Username(), password, "test_db", "disable")
+		suite.Require().NoError(err)
+	}
+
+	if vaultAddress == "" {
+		address, err := suite.containers.GetVaultAddress(ctx)
+		suite.Require().NoError(err)
+		vaultAddress = address
+	}
+	vaultClient, err := vault.NewClient(config.VaultConfig{Address: vaultAddress, Token: testingPkg.VaultDevRootToken})
+	suite.Require().NoError(err)
+
+	cfg := &config.Config{
+		Integration: config.IntegrationConfig{MessagingServiceURL: suite.messaging.URL},
+		Health:      config.HealthConfig{Timeout: 2 * time.Second, PlatformCacheTTL: time.Minute, Criticality: criticality},
+	}
+	return services.NewHealthService(suite.db.DB, repository.NewChannelIntegrationRepository(suite.db, nil), vaultClient, cfg, logger.NewLogger("error"))
+}
+
 func (suite *IntegrationTestSuite) TearDownSuite() {
 	ctx := context.Background()
+	if suite.messaging != nil {
+		suite.messaging.Close()
+	}
+	if suite.db != nil {
+		suite.db.Close()
+	}
 	if suite.containers != nil {
 		suite.containers.Cleanup(ctx)
 	}
@@ -81,6 +128,20 @@ func (suite *IntegrationTestSuite) TestReadinessEndpoint() {
 	assert.Contains(suite.T(), w.Body.String(), "ready")
 }
 
+func (suite *IntegrationTestSuite) TestReadinessIsDegradedWhenOptionalDependencyIsDown() {
+	ctx := context.Background()
+
+	// Vault es opcional por defecto: sin respuesta el servicio sigue listo pero degradado
+	healthService := suite.newHealthService(ctx, "http://127.0.0.1:1", nil)
+	status := healthService.CheckReadiness(ctx)
+	assert.Equal(suite.T(), services.HealthStatusDegraded, status.Status)
+
+	// Como dependencia crítica deja el servicio no listo
+	healthService = suite.newHealthService(ctx, "http://127.0.0.1:1", map[string]string{"vault": services.DependencyCritical})
+	status = healthService.CheckReadiness(ctx)
+	assert.Equal(suite.T(), "not_ready", status.Status)
+}
+
 func (suite *IntegrationTestSuite) TestContainersAreRunning() {
 	ctx := context.Background()
 
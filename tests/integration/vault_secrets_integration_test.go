@@ -0,0 +1,74 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"it-integration-service/internal/config"
+	testingPkg "it-integration-service/internal/testing"
+	"it-integration-service/pkg/secrets"
+	"it-integration-service/pkg/vault"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultSecretProviderResolvesRotatedSecrets(t *testing.T) {
+	ctx := context.Background()
+
+	containers, err := testingPkg.SetupVaultContainer(ctx)
+	require.NoError(t, err)
+	defer containers.Cleanup(ctx)
+
+	address, err := containers.GetVaultAddress(ctx)
+	require.NoError(t, err)
+
+	// Cliente directo para escribir el secreto como lo haría un operador
+	adminConfig := api.DefaultConfig()
+	adminConfig.Address = address
+	admin, err := api.NewClient(adminConfig)
+	require.NoError(t, err)
+	admin.SetToken(testingPkg.VaultDevRootToken)
+
+	_, err = admin.KVv2("secret").Put(ctx, "integration-service", map[string]interface{}{
+		"whatsapp_webhook_secret": "app-secret-v1",
+	})
+	require.NoError(t, err)
+
+	client, err := vault.NewClient(config.VaultConfig{Address: address, Token: testingPkg.VaultDevRootToken})
+	require.NoError(t, err)
+
+	vaultProvider := secrets.NewVaultProvider(client, "secret", "integration-service", func(err error) bool {
+		return errors.Is(err, vault.ErrSecretNotFound)
+	})
+	cached := secrets.NewCachedProvider(secrets.Chain{vaultProvider, secrets.NewEnvProvider()}, time.Minute)
+
+	value, err := cached.GetSecret(ctx, "whatsapp_webhook_secret")
+	require.NoError(t, err)
+	assert.Equal(t, "app-secret-v1", value)
+
+	// Rotar en Vault y descartar el cache tiene efecto sin reiniciar
+	_, err = admin.KVv2("secret").Put(ctx, "integration-service", map[string]interface{}{
+		"whatsapp_webhook_secret": "app-secret-v2",
+	})
+	require.NoError(t, err)
+	cached.Invalidate("whatsapp_webhook_secret")
+
+	value, err = cached.GetSecret(ctx, "whatsapp_webhook_secret")
+	require.NoError(t, err)
+	assert.Equal(t, "app-secret-v2", value)
+
+	// Lo que no está en Vault cae a las variables de entorno
+	t.Setenv("MESSENGER_VERIFY_TOKEN", "env-verify-token")
+	value, err = cached.GetSecret(ctx, "messenger_verify_token")
+	require.NoError(t, err)
+	assert.Equal(t, "env-verify-token", value)
+
+	// El root token de desarrollo no es renovable
+	ttl, err := client.RenewToken(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+}